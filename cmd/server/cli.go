@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"golang.org/x/term"
+)
+
+// backupPasswordEnv can be used to pass the backup password non-interactively
+const backupPasswordEnv = "SUBVAULT_BACKUP_PASSWORD"
+
+const cliUsage = `Usage:
+  subvault [flags]                                     start the web server
+  subvault export [--format json|csv] [--out FILE]     export subscriptions (default: JSON to stdout)
+         [--status S,...] [--category C] [--purpose P] [--from DATE] [--to DATE]
+                                                       export only the matching subscriptions
+         [--columns KEY,...]                           CSV columns in this order (default: saved preference)
+  subvault backup [--encrypt] [--password PW] --out FILE
+                                                       write a full backup (.json or encrypted .stbk)
+  subvault import [--format wallos|subvault|svbundle] [--password PW] [--payments] [--dry-run] FILE
+                                                       import subscriptions (.stbk files are decrypted)
+  subvault config export [--format yaml|json] [--out FILE]
+                                                       export non-secret settings and categories
+  subvault config import FILE                          apply a YAML or JSON configuration
+  subvault seed [--count N] [--seed S] [--force]       add N synthetic subscriptions for testing
+                                                       and benchmarking (default: 5000)
+  subvault reminders simulate [--date DATE] [--format text|json]
+                                                       show the reminders that would be sent on DATE
+                                                       (YYYY-MM-DD, default: today) without sending
+
+The backup password can also be set via SUBVAULT_BACKUP_PASSWORD; otherwise it is prompted for.
+`
+
+// handleCommand runs a CLI subcommand against the configured database and exits.
+// The HTTP server is not started.
+func handleCommand(args []string, exportService *service.ExportService, importService *service.ImportService, configService *service.ConfigService, seedService *service.SeedService, reminderSimulationService *service.ReminderSimulationService) {
+	var err error
+	switch args[0] {
+	case "export":
+		err = runExport(args[1:], exportService)
+	case "backup":
+		err = runBackup(args[1:], exportService)
+	case "import":
+		err = runImport(args[1:], importService)
+	case "config":
+		err = runConfig(args[1:], configService)
+	case "seed":
+		err = runSeed(args[1:], seedService)
+	case "reminders":
+		err = runReminders(args[1:], reminderSimulationService)
+	case "help", "-h", "--help":
+		fmt.Print(cliUsage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n%s", args[0], cliUsage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("%s failed: %v", args[0], err)
+	}
+}
+
+func runExport(args []string, exportService *service.ExportService) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "json", "Export format: json or csv")
+	out := fs.String("out", "", "Output file (default: stdout)")
+	status := fs.String("status", "", "Only these statuses, comma-separated (e.g. Active,Trial)")
+	category := fs.String("category", "", "Only this category (ID or name)")
+	purpose := fs.String("purpose", "", "Only this purpose: personal, business or shared")
+	from := fs.String("from", "", "Only renewals on or after this date (YYYY-MM-DD)")
+	to := fs.String("to", "", "Only renewals on or before this date (YYYY-MM-DD)")
+	columnList := fs.String("columns", "", "CSV columns in this order, comma-separated (e.g. name,cost,renewal_date)")
+	fs.Parse(args)
+
+	filter, err := models.ParseSubscriptionFilter(*status, *category, *purpose, *from, *to)
+	if err != nil {
+		return err
+	}
+	var columns []string
+	if *columnList != "" {
+		if columns, err = service.ParseCSVColumns(strings.Split(*columnList, ",")); err != nil {
+			return err
+		}
+	}
+
+	w, closeFn, err := openOutput(*out)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	switch strings.ToLower(*format) {
+	case "json":
+		export, err := exportService.BuildJSONExport(context.Background(), filter)
+		if err != nil {
+			return err
+		}
+		if err := writeJSON(w, export); err != nil {
+			return err
+		}
+	case "csv":
+		if err := exportService.WriteAllCSV(context.Background(), w, filter, columns); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format %q (use json or csv)", *format)
+	}
+
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "✓ Exported subscriptions to %s\n", *out)
+	}
+	return nil
+}
+
+func runBackup(args []string, exportService *service.ExportService) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	encrypt := fs.Bool("encrypt", false, "Encrypt the backup with AES-256-GCM (.stbk)")
+	password := fs.String("password", "", "Encryption password (or set "+backupPasswordEnv+")")
+	out := fs.String("out", "", "Output file")
+	fs.Parse(args)
+
+	if *out == "" {
+		return errors.New("--out is required")
+	}
+
+	var data []byte
+	if *encrypt {
+		pw, err := resolvePassword(*password, true)
+		if err != nil {
+			return err
+		}
+		data, err = exportService.BuildEncryptedBackup(context.Background(), pw)
+		if err != nil {
+			return err
+		}
+	} else {
+		backup, err := exportService.BuildBackup(context.Background())
+		if err != nil {
+			return err
+		}
+		data, err = json.MarshalIndent(backup, "", "  ")
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(*out, data, 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Backup written to %s\n", *out)
+	return nil
+}
+
+func runSeed(args []string, seedService *service.SeedService) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	count := fs.Int("count", 5000, "Number of subscriptions to create")
+	seed := fs.Uint64("seed", 1, "Random seed; the same seed creates the same subscriptions")
+	force := fs.Bool("force", false, "Seed even if the database already has subscriptions")
+	fs.Parse(args)
+
+	start := time.Now()
+	batch, err := seedService.Seed(context.Background(), *count, *seed, *force)
+	if errors.Is(err, service.ErrSeedNotEmpty) {
+		return fmt.Errorf("%w; use --force to add the synthetic subscriptions anyway", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Created %d synthetic subscriptions in %s\n", batch.SubscriptionCount, time.Since(start).Round(time.Millisecond))
+	fmt.Printf("  Import batch #%d removes them again from Settings > Data\n", batch.ID)
+	return nil
+}
+
+func runReminders(args []string, simulationService *service.ReminderSimulationService) error {
+	if len(args) == 0 || args[0] != "simulate" {
+		return errors.New("usage: subvault reminders simulate [--date DATE] [--format text|json]")
+	}
+	fs := flag.NewFlagSet("reminders simulate", flag.ExitOnError)
+	date := fs.String("date", "", "Date to simulate, YYYY-MM-DD or YYYY-MM-DDTHH:MM (default: now)")
+	format := fs.String("format", "text", "Output format: text or json")
+	fs.Parse(args[1:])
+
+	at, err := service.ParseSimulationTime(*date, time.Now())
+	if err != nil {
+		return err
+	}
+	simulation, err := simulationService.Simulate(context.Background(), at)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(simulation)
+	case "text":
+		printReminderSimulation(simulation)
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q (use text or json)", *format)
+	}
+}
+
+func printReminderSimulation(simulation *service.ReminderSimulation) {
+	fmt.Printf("Reminders on %s (dry run, nothing is sent):\n", simulation.At.Format("2006-01-02 15:04"))
+	for _, reminder := range simulation.Reminders {
+		fmt.Printf("  [%s] %s, due %s (in %d days)\n", reminder.Kind, reminder.Name, reminder.DueDate.Format(time.DateOnly), reminder.DaysUntil)
+		if reminder.PendingRetry {
+			fmt.Println("      skipped, a retry of this reminder is pending")
+			continue
+		}
+		if len(reminder.Channels) == 0 {
+			fmt.Println("      no notification channel selected")
+		}
+		for _, delivery := range reminder.Channels {
+			line := fmt.Sprintf("      %s: %s", delivery.Channel, strings.ReplaceAll(delivery.Status, "_", " "))
+			if len(delivery.Recipients) > 0 {
+				line += " to " + strings.Join(delivery.Recipients, ", ")
+			}
+			fmt.Println(line)
+		}
+	}
+	fmt.Printf("✓ %d reminders due\n", len(simulation.Reminders))
+}
+
+func runImport(args []string, importService *service.ImportService) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", "", "Import format: wallos, subvault or svbundle (default: auto-detect)")
+	password := fs.String("password", "", "Password for encrypted .stbk backups (or set "+backupPasswordEnv+")")
+	dryRun := fs.Bool("dry-run", false, "Show what would be imported without writing anything")
+	payments := fs.Bool("payments", false, "Add the payment history of Wallos exports to the payment ledger")
+	fs.Parse(args)
+
+	// Allow flags after the file argument as well
+	if fs.NArg() == 0 {
+		return errors.New("missing file to import")
+	}
+	file := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	encrypted := strings.EqualFold(filepath.Ext(file), ".stbk")
+	pw := ""
+	if encrypted {
+		if pw, err = resolvePassword(*password, false); err != nil {
+			return err
+		}
+	}
+
+	if *dryRun {
+		var preview *service.ImportPreview
+		if encrypted {
+			preview, err = importService.PreviewEncrypted(context.Background(), data, pw)
+		} else {
+			preview, err = importService.Preview(context.Background(), data, *format)
+		}
+		if err != nil {
+			return err
+		}
+		printImportPreview(preview)
+		importService.Discard(preview.Token)
+		return nil
+	}
+
+	var result service.ImportResult
+	if encrypted {
+		result, err = importService.ImportEncrypted(context.Background(), data, pw)
+	} else {
+		result, err = importService.Import(context.Background(), data, *format, service.ImportOptions{Payments: *payments})
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, detail := range result.Details {
+		fmt.Println("  " + detail)
+	}
+	fmt.Printf("✓ Imported: %d, skipped: %d, errors: %d\n", result.Imported, result.Skipped, result.Errors)
+	if result.Payments > 0 {
+		fmt.Printf("  Added %d past payments to the payment ledger\n", result.Payments)
+	}
+	if result.BatchID != 0 {
+		fmt.Printf("  Import batch #%d can be undone from Settings > Data\n", result.BatchID)
+	}
+	if result.Errors > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runConfig(args []string, configService *service.ConfigService) error {
+	if len(args) == 0 {
+		return errors.New("missing subcommand (export or import)")
+	}
+
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("config export", flag.ExitOnError)
+		format := fs.String("format", "yaml", "Export format: yaml or json")
+		out := fs.String("out", "", "Output file (default: stdout)")
+		fs.Parse(args[1:])
+
+		cfg, err := configService.Export()
+		if err != nil {
+			return err
+		}
+		data, err := configService.Marshal(cfg, *format)
+		if err != nil {
+			return err
+		}
+		w, closeFn, err := openOutput(*out)
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		if *out != "" {
+			fmt.Fprintf(os.Stderr, "✓ Exported configuration to %s\n", *out)
+		}
+		return nil
+
+	case "import":
+		if len(args) != 2 {
+			return errors.New("usage: subvault config import FILE")
+		}
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			return err
+		}
+		cfg, err := configService.Parse(data)
+		if err != nil {
+			return err
+		}
+		result, err := configService.Import(cfg)
+		if err != nil {
+			return err
+		}
+		for _, name := range result.CategoriesCreated {
+			fmt.Println("  created category " + name)
+		}
+		fmt.Printf("✓ Applied %d settings, created %d categories\n", result.SettingsApplied, len(result.CategoriesCreated))
+		return nil
+
+	default:
+		return fmt.Errorf("unknown config subcommand %q (use export or import)", args[0])
+	}
+}
+
+func printImportPreview(preview *service.ImportPreview) {
+	for _, msg := range preview.ParseErrors {
+		fmt.Println("  " + msg)
+	}
+	for _, item := range preview.Items {
+		line := fmt.Sprintf("  [%s] %s (%.2f %s, %s)", item.Action, item.Name, item.Cost, item.Currency, item.Schedule)
+		if item.Category != "" {
+			line += " -> " + item.Category
+			if item.CategoryMapping == service.CategoryMappingNew {
+				line += " (new category)"
+			}
+		}
+		fmt.Println(line)
+	}
+	if preview.Payments > 0 {
+		fmt.Printf("  %d past payments found; add them to the payment ledger with --payments\n", preview.Payments)
+	}
+	fmt.Printf("Dry run: %d would be created, %d skipped; nothing was written\n", preview.ToCreate, preview.ToSkip)
+}
+
+// resolvePassword returns the password from the flag, the environment or an interactive prompt
+func resolvePassword(password string, confirm bool) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+	if env := os.Getenv(backupPasswordEnv); env != "" {
+		return env, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Backup password: ")
+	passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "Confirm password: ")
+		confirmBytes, err := term.ReadPassword(int(syscall.Stdin))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		if string(passwordBytes) != string(confirmBytes) {
+			return "", errors.New("passwords do not match")
+		}
+	}
+
+	if len(passwordBytes) == 0 {
+		return "", errors.New("password required")
+	}
+	return string(passwordBytes), nil
+}
+
+// openOutput opens the given file for writing, or stdout when path is empty
+func openOutput(path string) (io.Writer, func(), error) {
+	if path == "" {
+		return os.Stdout, func() {}, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"crypto/subtle"
+	"errors"
+	"expvar"
 	"flag"
 	"fmt"
 	"html/template"
@@ -10,19 +13,23 @@ import (
 	"math"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"subvault/internal/config"
 	"subvault/internal/database"
 	"subvault/internal/handlers"
 	"subvault/internal/i18n"
 	"subvault/internal/middleware"
+	"subvault/internal/models"
 	"subvault/internal/repository"
+	"subvault/internal/scheduler"
 	"subvault/internal/service"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/term"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -37,9 +44,27 @@ func main() {
 
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.EnsureDataDirs(); err != nil {
+		log.Fatal("Failed to create data directory:", err)
+	}
+	if err := cfg.MigrateLegacyData(); err != nil {
+		log.Fatal("Failed to migrate data directory:", err)
+	}
+	slog.Info("using data directory", "path", cfg.DataDir, "database", cfg.DatabasePath)
 
 	// Initialize database
-	db, err := database.Initialize(cfg.DatabasePath)
+	databaseKey, err := cfg.DatabaseKey()
+	if err != nil {
+		log.Fatal("Failed to load database key:", err)
+	}
+	db, err := database.Initialize(cfg.DatabasePath, database.Options{
+		BusyTimeoutMs: cfg.DBBusyTimeoutMs,
+		JournalMode:   cfg.DBJournalMode,
+		Synchronous:   cfg.DBSynchronous,
+		MaxOpenConns:  cfg.DBMaxOpenConns,
+		MaxIdleConns:  cfg.DBMaxIdleConns,
+		Key:           databaseKey,
+	})
 	if err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
@@ -50,18 +75,50 @@ func main() {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
+	// Time queries for the performance report and the slow query log
+	requestMetrics := service.NewRequestMetrics(time.Duration(cfg.SlowQueryMs) * time.Millisecond)
+	if err := requestMetrics.InstrumentDB(db); err != nil {
+		log.Fatal("Failed to instrument database:", err)
+	}
+	expvar.Publish("requests", requestMetrics.Var())
+
 	// Initialize repositories
 	subscriptionRepo := repository.NewSubscriptionRepository(db)
 	settingsRepo := repository.NewSettingsRepository(db)
 	categoryRepo := repository.NewCategoryRepository(db)
 	exchangeRateRepo := repository.NewExchangeRateRepository(db)
-
-	// Initialize i18n service
+	importBatchRepo := repository.NewImportBatchRepository(db)
+	usageEventRepo := repository.NewUsageEventRepository(db)
+	reminderRetryRepo := repository.NewReminderRetryRepository(db)
+	subscriptionShareRepo := repository.NewSubscriptionShareRepository(db)
+	paymentRepo := repository.NewPaymentRepository(db)
+	statsHistoryRepo := repository.NewStatsHistoryRepository(db)
+	categoryRuleRepo := repository.NewCategoryRuleRepository(db)
+	vendorRepo := repository.NewVendorRepository(db)
+	inboundEmailRepo := repository.NewInboundEmailRepository(db)
+	loginEventRepo := repository.NewLoginEventRepository(db)
+	changeProposalRepo := repository.NewChangeProposalRepository(db)
+	fireflyRecurrenceRepo := repository.NewFireflyRecurrenceRepository(db)
+
+	// Initialize i18n service and add the currencies from the currencies file (if present)
 	i18nService := i18n.NewI18nService(cfg.LocaleDir)
+	if err := i18n.LoadCurrencies(cfg.CurrenciesFile); err != nil {
+		log.Fatal("Failed to load currencies:", err)
+	}
 
 	// Initialize services
 	categoryService := service.NewCategoryService(categoryRepo)
+	categoryRuleService := service.NewCategoryRuleService(categoryRuleRepo, categoryService)
+	vendorService := service.NewVendorService(vendorRepo)
+	searchService := service.NewSearchService(subscriptionRepo, categoryRepo)
 	settingsService := service.NewSettingsService(settingsRepo)
+	// Outbound requests, Shoutrrr's included, go through the configured proxy
+	proxyService := service.NewProxyService(settingsService)
+	service.UseOutboundProxyByDefault()
+	if cfg.OfflineMode {
+		service.EnableOfflineMode()
+		slog.Info("offline mode is on, outbound network calls are disabled")
+	}
 	currencyService := service.NewCurrencyService(exchangeRateRepo, settingsService)
 	preferencesService := service.NewPreferencesService(settingsService, i18nService)
 	authService := service.NewAuthService(settingsService, settingsRepo)
@@ -70,16 +127,56 @@ func main() {
 	calendarService := service.NewCalendarService(settingsService, settingsRepo)
 	renewalService := service.NewRenewalService()
 	subscriptionService := service.NewSubscriptionService(subscriptionRepo, categoryService, currencyService, preferencesService, settingsService, renewalService)
+	undoService := service.NewUndoService(subscriptionService, cfg.LogosDir(), cfg.AttachmentsDir())
 	emailService := service.NewEmailService(preferencesService, notifConfigService, i18nService)
 	shoutrrrService := service.NewShoutrrrService(preferencesService, notifConfigService, i18nService)
+	notifier := service.NewNotificationDispatcher(emailService, shoutrrrService)
+
+	// Deletions whose undo token was lost with the last shutdown become permanent
+	if _, err := undoService.PurgeExpired(context.Background()); err != nil {
+		slog.Warn("failed to purge deleted subscriptions", "error", err)
+	}
 
 	// Migrate existing Pushover config to Shoutrrr format (one-time migration)
 	if err := notifConfigService.MigratePushoverToShoutrrr(); err != nil {
 		slog.Warn("pushover to shoutrrr migration failed", "error", err)
 	}
-	logoService := service.NewLogoService()
+	// Warn early about sender setups whose reminders are likely to be rejected or filed as spam
+	if smtpConfig, err := notifConfigService.GetSMTPConfig(); err == nil {
+		go service.WarnSender(context.Background(), smtpConfig, !cfg.OfflineMode)
+	}
+	logoService := service.NewLogoService(settingsService, service.LogoFetchPolicy{
+		AllowedSchemes:       cfg.LogoAllowedSchemes,
+		AllowPrivateNetworks: cfg.LogoAllowPrivateNetworks,
+	})
+	logoQueueService := service.NewLogoQueueService(subscriptionRepo, logoService, cfg.LogosDir())
+	exportService := service.NewExportService(subscriptionService, preferencesService)
+	usageService := service.NewUsageService(usageEventRepo, subscriptionService, currencyService, preferencesService)
+	paymentService := service.NewPaymentService(paymentRepo, subscriptionService)
+	changeProposalService := service.NewChangeProposalService(changeProposalRepo, subscriptionService)
+	reconcileService := service.NewReconcileService(paymentRepo, subscriptionService, currencyService, preferencesService)
+	openBankingService := service.NewOpenBankingService(settingsService, reconcileService, preferencesService)
+	inboundEmailService := service.NewInboundEmailService(settingsService, inboundEmailRepo, reconcileService, subscriptionService, vendorService, preferencesService)
+	loginAuditService := service.NewLoginAuditService(loginEventRepo, settingsService, notifier)
+	splitService := service.NewSplitService(subscriptionShareRepo, subscriptionService, currencyService, preferencesService)
+	importService := service.NewImportService(subscriptionService, categoryService, categoryRuleService, renewalService, importBatchRepo, cfg.LogosDir())
+	budgetImportService := service.NewBudgetImportService(settingsService, importService, subscriptionService)
+	fireflyExportService := service.NewFireflyExportService(budgetImportService, fireflyRecurrenceRepo, subscriptionService)
+	configService := service.NewConfigService(settingsService, preferencesService, categoryService, categoryRuleService)
+	bundleService := service.NewBundleService(subscriptionService, logoService, cfg.LogosDir())
 
 	// Handle CLI commands (run before starting HTTP server)
+	if flag.NArg() > 0 {
+		seedService := service.NewSeedService(subscriptionService, categoryService, renewalService, importBatchRepo)
+		hookService, err := service.LoadHookService(cfg.HooksFile)
+		if err != nil {
+			log.Fatal("Failed to load hooks:", err)
+		}
+		reminderSimulationService := service.NewReminderSimulationService(subscriptionService, notifier, notifConfigService, hookService, service.NewReminderRetryService(reminderRetryRepo))
+		handleCommand(flag.Args(), exportService, importService, configService, seedService, reminderSimulationService)
+		return
+	}
+
 	if *disableAuth {
 		handleDisableAuth(authService)
 		return
@@ -95,7 +192,7 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to initialize session secret:", err)
 	}
-	sessionService := service.NewSessionService(sessionSecret)
+	sessionService := service.NewSessionService(sessionSecret, authService.GetSessionLifetimes)
 
 	// Initialize CSRF secret
 	csrfSecret, err := authService.GetOrGenerateCSRFSecret()
@@ -103,12 +200,117 @@ func main() {
 		log.Fatal("Failed to initialize CSRF secret:", err)
 	}
 
+	// Load hooks from the hooks file (if present)
+	hookService, err := service.LoadHookService(cfg.HooksFile)
+	if err != nil {
+		log.Fatal("Failed to load hooks:", err)
+	}
+
+	// Register background jobs (scheduled below, can also be run from Settings > Jobs)
+	rateAlertService := service.NewRateAlertService(subscriptionService, currencyService, preferencesService, settingsService)
+	weeklySummaryService := service.NewWeeklySummaryService(subscriptionService, currencyService, preferencesService, settingsService)
+	monthlyReportService := service.NewMonthlyReportService(exportService, subscriptionService, emailService, notifConfigService, settingsService)
+	housekeepingService := service.NewHousekeepingService(authService, exchangeRateRepo, subscriptionService, cfg.LogosDir())
+	reminderRetryService := service.NewReminderRetryService(reminderRetryRepo)
+	reminders := service.NewReminderJobs(subscriptionService, notifier, hookService, reminderRetryService)
+	reminderSimulationService := service.NewReminderSimulationService(subscriptionService, notifier, notifConfigService, hookService, reminderRetryService)
+	updateService := service.NewUpdateService(settingsService)
+	statsHistoryService := service.NewStatsHistoryService(statsHistoryRepo, subscriptionService, preferencesService)
+	jobService := scheduler.New(scheduler.SystemClock, repository.NewJobRunRepository(db))
+	for _, job := range []scheduler.Job{
+		scheduler.NewJob(scheduler.JobRenewalReminders, 24*time.Hour, reminders.SendRenewalReminders),
+		scheduler.NewJob(scheduler.JobCancellationReminders, 24*time.Hour, reminders.SendCancellationReminders),
+		scheduler.NewJob(scheduler.JobGracePeriodReminders, 24*time.Hour, reminders.SendGracePeriodReminders),
+		scheduler.NewJob(scheduler.JobContractReminders, 24*time.Hour, reminders.SendContractReminders),
+		scheduler.NewJob(scheduler.JobPaidThroughReminders, 24*time.Hour, reminders.SendPaidThroughReminders),
+		scheduler.NewJob(scheduler.JobPromoReminders, 24*time.Hour, reminders.SendPromoReminders),
+		scheduler.NewJob(scheduler.JobReminderRetries, reminderRetryInterval, reminders.RetryFailed),
+		scheduler.NewJob(scheduler.JobUnusedNudge, 24*time.Hour, func(ctx context.Context, now time.Time) error {
+			return checkAndSendUnusedNudge(ctx, usageService, notifier, settingsService, now)
+		}),
+		scheduler.NewJob(scheduler.JobRateAlerts, 24*time.Hour, func(ctx context.Context, now time.Time) error {
+			return checkAndSendRateAlerts(ctx, rateAlertService, notifier, settingsService, now)
+		}),
+		scheduler.NewJob(scheduler.JobWeeklySummary, 24*time.Hour, func(ctx context.Context, now time.Time) error {
+			return checkAndSendWeeklySummary(ctx, weeklySummaryService, notifier, settingsService, now)
+		}),
+		// Hourly, so a report held back by the email delivery window goes out once it opens
+		scheduler.NewJob(scheduler.JobMonthlyReport, time.Hour, func(ctx context.Context, now time.Time) error {
+			return checkAndSendMonthlyReport(ctx, monthlyReportService, settingsService, now)
+		}),
+		scheduler.NewJob(scheduler.JobRenewalConfirmations, 24*time.Hour, func(ctx context.Context, now time.Time) error {
+			return checkRenewalConfirmations(ctx, paymentService, notifier, settingsService, now)
+		}),
+		scheduler.NewJob(scheduler.JobBankSync, 24*time.Hour, func(ctx context.Context, _ time.Time) error {
+			return syncBankTransactions(ctx, openBankingService)
+		}),
+		scheduler.NewJob(scheduler.JobBudgetSync, 24*time.Hour, func(ctx context.Context, _ time.Time) error {
+			return syncBudgetAmounts(ctx, budgetImportService)
+		}),
+		scheduler.NewJob(scheduler.JobLogoQueue, logoQueueInterval, func(ctx context.Context, _ time.Time) error {
+			return processLogoQueue(ctx, logoQueueService)
+		}),
+		// Manual only, rates are fetched when a conversion finds them older than the refresh interval
+		scheduler.NewJob(scheduler.JobCurrencyRefresh, 0, func(ctx context.Context, _ time.Time) error {
+			return currencyService.RefreshRates(ctx)
+		}),
+		scheduler.NewJob(scheduler.JobBackup, time.Duration(cfg.BackupIntervalHours)*time.Hour, func(ctx context.Context, _ time.Time) error {
+			_, err := exportService.WriteBackupFile(ctx, cfg.BackupsDir(), cfg.BackupKeep)
+			return err
+		}),
+		scheduler.NewJob(scheduler.JobHousekeeping, time.Duration(cfg.HousekeepingIntervalHours)*time.Hour, func(ctx context.Context, _ time.Time) error {
+			if result := housekeepingService.Run(ctx); result.Errors > 0 {
+				return fmt.Errorf("%d housekeeping tasks failed", result.Errors)
+			}
+			return nil
+		}),
+		scheduler.NewJob(scheduler.JobNotificationQueue, notificationQueueInterval, func(ctx context.Context, now time.Time) error {
+			_, err := notifier.FlushQueued(now)
+			return err
+		}),
+		// Hourly, so each day's snapshot holds the figures at the end of the day
+		scheduler.NewJob(scheduler.JobStatsSnapshot, time.Hour, func(ctx context.Context, now time.Time) error {
+			_, err := statsHistoryService.Record(ctx, now)
+			return err
+		}),
+		scheduler.NewJob(scheduler.JobUpdateCheck, 24*time.Hour, func(ctx context.Context, _ time.Time) error {
+			return updateService.Check(ctx)
+		}),
+	} {
+		jobService.Register(job)
+	}
+
 	// Initialize handlers
-	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService, preferencesService, settingsService, calendarService, currencyService, emailService, shoutrrrService, logoService)
-	settingsHandler := handlers.NewSettingsHandler(settingsService, authService, apiKeyService, preferencesService, notifConfigService, calendarService, currencyService, i18nService)
+	defaultsService := service.NewSubscriptionDefaultsService(settingsService, preferencesService, categoryService)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService, preferencesService, settingsService, calendarService, currencyService, notifier, logoService, exportService, hookService, defaultsService, logoQueueService, vendorService, undoService)
+	settingsHandler := handlers.NewSettingsHandler(settingsService, authService, apiKeyService, preferencesService, notifConfigService, calendarService, currencyService, defaultsService, categoryService, i18nService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
-	authHandler := handlers.NewAuthHandler(authService, sessionService, emailService, notifConfigService)
-	importHandler := handlers.NewImportHandler(subscriptionService, categoryService, settingsService)
+	categoryRuleHandler := handlers.NewCategoryRuleHandler(categoryRuleService)
+	vendorHandler := handlers.NewVendorHandler(vendorService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	inboundEmailHandler := handlers.NewInboundEmailHandler(inboundEmailService)
+	authHandler := handlers.NewAuthHandler(authService, sessionService, emailService, notifConfigService, loginAuditService)
+	importHandler := handlers.NewImportHandler(importService, budgetImportService, fireflyExportService, settingsService)
+	configHandler := handlers.NewConfigHandler(configService)
+	jobsHandler := handlers.NewJobsHandler(jobService)
+	bundleHandler := handlers.NewBundleHandler(bundleService)
+	usageHandler := handlers.NewUsageHandler(usageService, preferencesService)
+	paymentHandler := handlers.NewPaymentHandler(paymentService, reconcileService, openBankingService, settingsService)
+	changeProposalHandler := handlers.NewChangeProposalHandler(changeProposalService, subscriptionHandler, authService)
+	splitHandler := handlers.NewSplitHandler(splitService, subscriptionService, preferencesService, notifier)
+	erasureService := service.NewErasureService(authService, sessionService, func() error { return database.Erase(db) },
+		cfg.LogosDir(), cfg.AttachmentsDir(), cfg.BackupsDir())
+	erasureHandler := handlers.NewErasureHandler(erasureService, undoService, sessionService)
+	undoHandler := handlers.NewUndoHandler(undoService, subscriptionService, hookService)
+	notificationTestHandler := handlers.NewNotificationTestHandler(service.NewNotificationTestService(notifier, notifConfigService, preferencesService), emailService)
+	updateHandler := handlers.NewUpdateHandler(updateService)
+	statsHistoryHandler := handlers.NewStatsHistoryHandler(statsHistoryService)
+	performanceHandler := handlers.NewPerformanceHandler(requestMetrics)
+	integrityService := service.NewIntegrityService(subscriptionRepo, categoryRepo, defaultsService, preferencesService)
+	integrityHandler := handlers.NewIntegrityHandler(integrityService)
+	proxyHandler := handlers.NewProxyHandler(proxyService)
+	reminderSimulationHandler := handlers.NewReminderSimulationHandler(reminderSimulationService)
+	notificationPreviewHandler := handlers.NewNotificationPreviewHandler(service.NewNotificationPreviewService(reminderSimulationService, subscriptionService, weeklySummaryService, monthlyReportService, settingsService))
 
 	// Setup Gin router
 	if cfg.Environment == "production" {
@@ -116,19 +318,28 @@ func main() {
 	}
 
 	router := gin.Default()
+	// Without trusted proxies the client IP is the peer address, so a client
+	// cannot pick the IP of the login history and rate limits via X-Forwarded-For
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	router.Use(middleware.RequestMetrics(requestMetrics))
+	// Bound each request so slow queries and outbound calls made for it are cancelled
+	router.Use(middleware.RequestTimeout(time.Duration(cfg.RequestTimeoutSeconds) * time.Second))
 
 	// Load HTML templates with error handling
-	tmpl := loadTemplates()
+	tmpl := loadTemplates(cfg)
 	if tmpl != nil && len(tmpl.Templates()) > 0 {
 		router.SetHTMLTemplate(tmpl)
 	} else {
 		slog.Warn("template loading failed, using fallback")
 		// Fallback to LoadHTMLGlob for compatibility
-		router.LoadHTMLGlob("web/templates/**/*")
+		router.LoadHTMLGlob(filepath.Join(cfg.WebDir, "templates", "**", "*"))
 	}
+	router.HTMLRender = middleware.TimedHTMLRender(router.HTMLRender)
 
 	// Serve static files with cache headers
-	staticFS := http.Dir("./web/static")
+	staticFS := http.Dir(cfg.StaticDir())
 	staticHandler := http.StripPrefix("/static/", http.FileServer(staticFS))
 	router.GET("/static/*filepath", func(c *gin.Context) {
 		c.Header("Cache-Control", "public, max-age=86400")
@@ -138,8 +349,22 @@ func main() {
 		c.Header("Cache-Control", "public, max-age=86400")
 		staticHandler.ServeHTTP(c.Writer, c.Request)
 	})
-	router.StaticFile("/favicon.ico", "./web/static/favicon.ico")
-	router.StaticFile("/manifest.json", "./web/static/manifest.json")
+	// Serve locally stored logos (e.g. from imported bundles). Logos may be SVG,
+	// so the CSP keeps them from running scripts when opened directly.
+	logosHandler := http.StripPrefix("/logos/", http.FileServer(http.Dir(cfg.LogosDir())))
+	router.GET("/logos/*filepath", func(c *gin.Context) {
+		if strings.HasSuffix(c.Param("filepath"), "/") {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+		logosHandler.ServeHTTP(c.Writer, c.Request)
+	})
+	router.StaticFile("/favicon.ico", filepath.Join(cfg.StaticDir(), "favicon.ico"))
+	router.StaticFile("/manifest.json", filepath.Join(cfg.StaticDir(), "manifest.json"))
+	router.GET("/theme.css", settingsHandler.ThemeCSS)
 
 	// Health check endpoint with database connectivity check
 	router.GET("/healthz", func(c *gin.Context) {
@@ -167,6 +392,11 @@ func main() {
 		})
 	})
 
+	// Readiness endpoint with per-check status
+	healthService := newHealthService(db, tmpl, currencyService, emailService)
+	healthHandler := handlers.NewHealthHandler(healthService, cfg.ReadyzOptionalChecks)
+	router.GET("/readyz", healthHandler.Readiness)
+
 	// Apply CSRF middleware (before auth - login page needs CSRF too)
 	csrfSecure := os.Getenv("HTTPS_ENABLED") == "true"
 	router.Use(middleware.CSRFMiddleware(csrfSecret, csrfSecure))
@@ -176,9 +406,10 @@ func main() {
 
 	// Apply i18n middleware
 	router.Use(middleware.I18nMiddleware(i18nService, preferencesService))
+	router.Use(middleware.UpdateStatusMiddleware(updateService))
 
 	// Routes
-	setupRoutes(router, subscriptionHandler, settingsHandler, apiKeyService, categoryHandler, authHandler, importHandler)
+	setupRoutes(router, subscriptionHandler, settingsHandler, apiKeyService, categoryHandler, authHandler, importHandler, usageHandler, splitHandler, configHandler, jobsHandler, bundleHandler, paymentHandler, erasureHandler, updateHandler, statsHistoryHandler, categoryRuleHandler, vendorHandler, searchHandler, inboundEmailHandler, notificationTestHandler, performanceHandler, undoHandler, integrityHandler, reminderSimulationHandler, notificationPreviewHandler, proxyHandler, changeProposalHandler)
 
 	// Seed sample data if database is empty
 	// Commented out - no sample data by default
@@ -186,11 +417,12 @@ func main() {
 	// 	seedSampleData(subscriptionService)
 	// }
 
-	// Start renewal reminder scheduler
-	go startRenewalReminderScheduler(subscriptionService, emailService, shoutrrrService, settingsService)
+	// Point out data the forms would not let through, like leftovers of old versions
+	integrityService.LogIssues(context.Background())
 
-	// Start cancellation reminder scheduler
-	go startCancellationReminderScheduler(subscriptionService, emailService, shoutrrrService, settingsService)
+	// Run the background jobs when due, catching up on runs missed while the server was down
+	go jobService.Start(context.Background(), scheduler.DefaultTick)
+	go jobService.RunOnWakeup(scheduler.JobLogoQueue, logoQueueService.Wakeups())
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -202,8 +434,78 @@ func main() {
 	log.Fatal(router.Run(":" + port))
 }
 
-// loadTemplates loads HTML templates with better error handling for arm64 compatibility
-func loadTemplates() *template.Template {
+// notificationQueueInterval is how often notifications deferred by a channel's
+// delivery window are checked for delivery
+const notificationQueueInterval = 5 * time.Minute
+
+// reminderRetryInterval is how often failed reminders are checked for a due retry
+const reminderRetryInterval = 15 * time.Minute
+
+// logoQueueInterval is how often logos left pending, e.g. by a restart, are looked up.
+// Newly queued logos are looked up right away.
+const logoQueueInterval = 15 * time.Minute
+
+// criticalTemplates are required for basic functionality
+var criticalTemplates = []string{
+	"web/templates/subscription/dashboard.html",
+	"web/templates/subscription/subscriptions.html",
+	"web/templates/error.html",
+}
+
+// newHealthService registers the readiness checks served on /readyz
+func newHealthService(db *gorm.DB, tmpl *template.Template, currencyService *service.CurrencyService, emailService *service.EmailService) *service.HealthService {
+	health := service.NewHealthService()
+
+	health.Register("database", false, func() error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return fmt.Errorf("database connection unavailable")
+		}
+		if err := sqlDB.Ping(); err != nil {
+			return fmt.Errorf("database ping failed")
+		}
+		return nil
+	})
+
+	health.Register("migrations", false, func() error {
+		pending, err := database.PendingMigrations(db)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("pending migrations: %s", strings.Join(pending, ", "))
+		}
+		return nil
+	})
+
+	health.Register("templates", false, func() error {
+		if tmpl == nil {
+			return fmt.Errorf("templates not loaded")
+		}
+		var missing []string
+		for _, file := range criticalTemplates {
+			if tmpl.Lookup(filepath.Base(file)) == nil {
+				missing = append(missing, filepath.Base(file))
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("missing templates: %s", strings.Join(missing, ", "))
+		}
+		return nil
+	})
+
+	health.Register("exchange_rates", true, currencyService.CheckFreshness)
+
+	health.Register("smtp", true, func() error {
+		return emailService.CheckSMTPReachable(3 * time.Second)
+	})
+
+	return health
+}
+
+// loadTemplates loads HTML templates with better error handling for arm64 compatibility.
+// Files are resolved against the web directory; overrides in the data directory take precedence.
+func loadTemplates(cfg *config.Config) *template.Template {
 	tmpl := template.New("")
 
 	// Add template functions
@@ -252,40 +554,63 @@ func loadTemplates() *template.Template {
 	// Load partials first (they define reusable template blocks)
 	partialFiles := []string{
 		"web/templates/partials/sidebar.html",
+		"web/templates/partials/quick-actions.html",
+		"web/templates/partials/command-palette.html",
+		"web/templates/partials/rate-warning.html",
 	}
 	for _, file := range partialFiles {
-		if _, err := tmpl.ParseFiles(file); err != nil {
+		if _, err := tmpl.ParseFiles(templatePath(cfg, file)); err != nil {
 			slog.Error("failed to parse partial", "file", file, "error", err)
 		}
 	}
 
-	// Critical templates required for basic functionality
-	criticalTemplates := []string{
-		"web/templates/subscription/dashboard.html",
-		"web/templates/subscription/subscriptions.html",
-		"web/templates/error.html",
-	}
-
 	// All template files to load
 	templateFiles := []string{
 		// Subscription pages
 		"web/templates/subscription/dashboard.html",
+		"web/templates/subscription/category-breakdown.html",
 		"web/templates/subscription/subscriptions.html",
 		"web/templates/subscription/calendar.html",
+		"web/templates/subscription/tax-report.html",
+		"web/templates/subscription/renewals.html",
+		"web/templates/subscription/proposals.html",
+		"web/templates/subscription/quick-add.html",
+		"web/templates/subscription/reconcile-preview.html",
+		"web/templates/subscription/bank-connection.html",
+		"web/templates/subscription/inbound-email.html",
 		"web/templates/subscription/subscription-form.html",
+		"web/templates/subscription/logo-status.html",
+		"web/templates/subscription/logo-candidates.html",
 		"web/templates/subscription/subscription-list.html",
+		"web/templates/subscription/inline-cell.html",
 		"web/templates/subscription/form-errors.html",
+		"web/templates/subscription/undo-toast.html",
 		"web/templates/subscription/import-result.html",
+		"web/templates/subscription/import-preview.html",
+		"web/templates/subscription/usage-insights.html",
+		"web/templates/subscription/split-form.html",
+		"web/templates/subscription/split-settlement.html",
+		"web/templates/settings/import-batches.html",
+		"web/templates/settings/budget-connection.html",
 		// Settings pages
 		"web/templates/settings/settings-general.html",
 		"web/templates/settings/settings-notifications.html",
+		"web/templates/settings/settings-notification-preview.html",
 		"web/templates/settings/settings-data.html",
 		"web/templates/settings/settings-security.html",
 		"web/templates/settings/settings-appearance.html",
 		"web/templates/settings/api-docs.html",
 		"web/templates/settings/api-keys-list.html",
+		"web/templates/settings/login-history.html",
 		"web/templates/settings/smtp-message.html",
+		"web/templates/settings/notification-test-results.html",
 		"web/templates/settings/exchange-rate-status.html",
+		"web/templates/settings/jobs-list.html",
+		"web/templates/settings/performance.html",
+		"web/templates/settings/integrity-report.html",
+		"web/templates/settings/proxy-settings.html",
+		"web/templates/settings/reminder-simulation.html",
+		"web/templates/settings/settings-jobs.html",
 		// Auth pages
 		"web/templates/auth/login.html",
 		"web/templates/auth/login-error.html",
@@ -296,6 +621,7 @@ func loadTemplates() *template.Template {
 		"web/templates/auth/reset-password-error.html",
 		"web/templates/auth/reset-password-success.html",
 		"web/templates/auth/auth-message.html",
+		"web/templates/auth/password-strength.html",
 		// Shared
 		"web/templates/error.html",
 	}
@@ -306,7 +632,8 @@ func loadTemplates() *template.Template {
 
 	// Load templates individually to catch arm64-specific issues
 	for _, file := range templateFiles {
-		if _, err := os.Stat(file); err != nil {
+		path := templatePath(cfg, file)
+		if _, err := os.Stat(path); err != nil {
 			slog.Warn("template file not found", "file", file)
 			// Check if this is a critical template
 			for _, critical := range criticalTemplates {
@@ -317,8 +644,8 @@ func loadTemplates() *template.Template {
 			continue
 		}
 
-		if _, err := tmpl.ParseFiles(file); err != nil {
-			slog.Error("failed to parse template", "file", file, "error", err)
+		if _, err := tmpl.ParseFiles(path); err != nil {
+			slog.Error("failed to parse template", "file", path, "error", err)
 			failedCount++
 			// Check if this is a critical template
 			for _, critical := range criticalTemplates {
@@ -347,10 +674,18 @@ func loadTemplates() *template.Template {
 	return tmpl
 }
 
-func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, settingsHandler *handlers.SettingsHandler, apiKeyService *service.APIKeyService, categoryHandler *handlers.CategoryHandler, authHandler *handlers.AuthHandler, importHandler *handlers.ImportHandler) {
+// templatePath maps a "web/templates/..." entry to the configured web directory or a custom override
+func templatePath(cfg *config.Config, file string) string {
+	return cfg.TemplatePath(strings.TrimPrefix(file, "web/templates/"))
+}
+
+func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, settingsHandler *handlers.SettingsHandler, apiKeyService *service.APIKeyService, categoryHandler *handlers.CategoryHandler, authHandler *handlers.AuthHandler, importHandler *handlers.ImportHandler, usageHandler *handlers.UsageHandler, splitHandler *handlers.SplitHandler, configHandler *handlers.ConfigHandler, jobsHandler *handlers.JobsHandler, bundleHandler *handlers.BundleHandler, paymentHandler *handlers.PaymentHandler, erasureHandler *handlers.ErasureHandler, updateHandler *handlers.UpdateHandler, statsHistoryHandler *handlers.StatsHistoryHandler, categoryRuleHandler *handlers.CategoryRuleHandler, vendorHandler *handlers.VendorHandler, searchHandler *handlers.SearchHandler, inboundEmailHandler *handlers.InboundEmailHandler, notificationTestHandler *handlers.NotificationTestHandler, performanceHandler *handlers.PerformanceHandler, undoHandler *handlers.UndoHandler, integrityHandler *handlers.IntegrityHandler, reminderSimulationHandler *handlers.ReminderSimulationHandler, notificationPreviewHandler *handlers.NotificationPreviewHandler, proxyHandler *handlers.ProxyHandler, changeProposalHandler *handlers.ChangeProposalHandler) {
 	// Calendar feed (public, token-based auth)
 	router.GET("/cal/:token/subscriptions.ics", handler.ServeCalendarFeed)
 
+	// Inbound email webhook (public, token-based auth)
+	router.POST("/inbound/email/:token", inboundEmailHandler.Receive)
+
 	// Auth routes (public)
 	router.GET("/login", authHandler.ShowLoginPage)
 	router.GET("/forgot-password", authHandler.ShowForgotPasswordPage)
@@ -364,10 +699,16 @@ func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, sett
 		c.Redirect(http.StatusMovedPermanently, "/dashboard")
 	})
 	router.GET("/calendar", handler.Calendar)
+	router.GET("/tax-report", handler.TaxReport)
+	router.GET("/renewals", paymentHandler.Renewals)
+	router.GET("/proposals", changeProposalHandler.Proposals)
+	router.GET("/quick-add", handler.QuickAdd)
 	router.GET("/settings", settingsHandler.SettingsGeneral)
 	router.GET("/settings/notifications", settingsHandler.SettingsNotifications)
+	router.GET("/settings/notifications/preview", notificationPreviewHandler.SettingsNotificationPreview)
 	router.GET("/settings/data", settingsHandler.SettingsData)
 	router.GET("/settings/security", settingsHandler.SettingsSecurity)
+	router.GET("/settings/jobs", jobsHandler.SettingsJobs)
 	router.GET("/settings/appearance", settingsHandler.SettingsAppearance)
 	router.GET("/api-docs", settingsHandler.APIDocs)
 
@@ -376,6 +717,7 @@ func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, sett
 	{
 		form.GET("/subscription", handler.GetSubscriptionForm)
 		form.GET("/subscription/:id", handler.GetSubscriptionForm)
+		form.GET("/subscription/:id/split", splitHandler.SplitForm)
 	}
 
 	// API routes for HTMX
@@ -386,18 +728,77 @@ func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, sett
 		api.GET("/subscriptions/:id", handler.GetSubscription)
 		api.PUT("/subscriptions/:id", handler.UpdateSubscription)
 		api.DELETE("/subscriptions/:id", handler.DeleteSubscription)
+		api.POST("/subscriptions/:id/cancel", handler.CancelSubscription)
+		api.POST("/subscriptions/:id/pause", handler.PauseSubscription)
+		api.POST("/subscriptions/:id/resume", handler.ResumeSubscription)
+		api.GET("/subscriptions/:id/inline/:field", handler.InlineCell)
+		api.PATCH("/subscriptions/:id/cost", handler.PatchCost)
+		api.PATCH("/subscriptions/:id/renewal-date", handler.PatchRenewalDate)
+		api.PATCH("/subscriptions/:id/status", handler.PatchStatus)
+		api.POST("/subscriptions/:id/logo", handler.RetryLogo)
+		api.POST("/subscriptions/:id/icon", handler.UploadLogo)
+		api.GET("/logos", handler.LogoCandidates)
 		api.GET("/stats", handler.GetStats)
+		api.GET("/stats/history", statsHistoryHandler.GetHistory)
+		api.GET("/version", updateHandler.GetVersion)
+		api.GET("/stats/categories/:id/subscriptions", handler.GetCategorySubscriptions)
+		api.GET("/reports/tax", handler.GetTaxReport)
+		api.GET("/reports/tax/csv", handler.ExportTaxReportCSV)
+
+		// Payment ledger and renewal confirmation routes
+		api.GET("/payments", paymentHandler.GetPayments)
+		api.GET("/payments/unconfirmed", paymentHandler.GetUnconfirmedPayments)
+		api.POST("/payments/:id/confirm", paymentHandler.ConfirmPayment)
+		api.POST("/payments/:id/reject", paymentHandler.RejectPayment)
+		api.POST("/reconcile/preview", paymentHandler.PreviewReconcile)
+		api.POST("/reconcile/confirm", paymentHandler.ConfirmReconcile)
+		api.GET("/bank", paymentHandler.BankConnection)
+		api.POST("/bank/credentials", paymentHandler.SaveBankCredentials)
+		api.POST("/bank/connect", paymentHandler.ConnectBank)
+		api.POST("/bank/sync", paymentHandler.SyncBank)
+		api.POST("/bank/disconnect", paymentHandler.DisconnectBank)
+		api.GET("/inbound-email", inboundEmailHandler.InboundEmailCard)
+		api.POST("/inbound-email/token", inboundEmailHandler.GenerateInboundToken)
+		api.DELETE("/inbound-email/token", inboundEmailHandler.RevokeInboundToken)
+		api.DELETE("/inbound-email/:id", inboundEmailHandler.DismissInboundEmail)
+
+		// Change proposals of viewers, decided by the admin
+		api.GET("/proposals", changeProposalHandler.GetProposals)
+		api.GET("/proposals/:id", changeProposalHandler.GetProposal)
+		api.POST("/proposals", changeProposalHandler.ProposeChange)
+		api.POST("/proposals/:id/approve", changeProposalHandler.ApproveProposal)
+		api.POST("/proposals/:id/reject", changeProposalHandler.RejectProposal)
+
+		// Usage tracking routes
+		api.POST("/subscriptions/:id/usage-event", usageHandler.LogUsageEvent)
+		api.GET("/subscriptions/:id/usage-events", usageHandler.GetUsageEvents)
+		api.GET("/usage/cost-per-use", usageHandler.GetCostPerUse)
+		api.GET("/usage/insights", usageHandler.UsageInsights)
+
+		// Shared expense splitting routes
+		api.GET("/subscriptions/:id/shares", splitHandler.GetShares)
+		api.PUT("/subscriptions/:id/shares", splitHandler.SaveShares)
+		api.GET("/splits/settlement", splitHandler.GetSettlement)
+		api.GET("/splits/settlement/csv", splitHandler.ExportSettlementCSV)
+		api.GET("/splits/settlement/card", splitHandler.SettlementCard)
+		api.POST("/splits/settlement/send", splitHandler.SendSettlement)
 
 		// Export and data management routes
 		api.GET("/export/csv", handler.ExportCSV)
 		api.GET("/export/json", handler.ExportJSON)
 		api.GET("/export/ical", handler.ExportICal)
+		api.GET("/export/bundle", bundleHandler.ExportBundle)
 		api.GET("/backup", handler.BackupData)
-		api.DELETE("/clear-all", handler.ClearAllData)
+		api.DELETE("/clear-all", erasureHandler.ClearAllData)
+		api.POST("/undo/:token", undoHandler.Undo)
+		api.POST("/erase", erasureHandler.EraseAll)
 
 		// Calendar token management
 		api.POST("/calendar/generate", settingsHandler.GenerateCalendarToken)
 		api.POST("/calendar/revoke", settingsHandler.RevokeCalendarToken)
+		api.GET("/settings/calendar/url", settingsHandler.GetCalendarAPI)
+		api.POST("/settings/calendar", settingsHandler.SaveCalendarFeedOptions)
+		api.POST("/settings/csv-columns", settingsHandler.SaveCSVColumns)
 
 		// Settings routes
 		api.POST("/settings/smtp", settingsHandler.SaveSMTPSettings)
@@ -405,9 +806,17 @@ func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, sett
 		api.POST("/settings/shoutrrr", settingsHandler.SaveShoutrrrSettings)
 		api.POST("/settings/shoutrrr/test", settingsHandler.TestShoutrrrConnection)
 		api.GET("/settings/shoutrrr", settingsHandler.GetShoutrrrConfig)
+		api.POST("/settings/delivery-windows", settingsHandler.SaveDeliveryWindows)
+		api.POST("/settings/notification-languages", settingsHandler.SaveNotificationLanguages)
+		api.POST("/settings/defaults", settingsHandler.SaveSubscriptionDefaults)
+		api.POST("/settings/budgets/:purpose", settingsHandler.SavePurposeBudget)
 		api.POST("/settings/notifications/:setting", settingsHandler.UpdateNotificationSetting)
 		api.GET("/settings/notifications", settingsHandler.GetNotificationSettings)
+		api.POST("/settings/notifications/test-all", notificationTestHandler.TestAllNotifications)
+		api.GET("/settings/notifications/preview/:type", notificationTestHandler.PreviewEmail)
+		api.GET("/settings/notifications/simulate", reminderSimulationHandler.Simulate)
 		api.GET("/settings/smtp", settingsHandler.GetSMTPConfig)
+		api.GET("/settings/logins", authHandler.LoginHistory)
 
 		// API Key management routes
 		api.GET("/settings/apikeys", settingsHandler.ListAPIKeys)
@@ -419,7 +828,12 @@ func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, sett
 
 		// Exchange rate management
 		api.POST("/settings/exchange-rates/refresh", settingsHandler.RefreshExchangeRates)
+		api.POST("/settings/exchange-rates/manual", settingsHandler.SetManualRates)
 		api.POST("/settings/currency-refresh", settingsHandler.UpdateCurrencyRefreshInterval)
+		api.POST("/settings/logo-privacy", settingsHandler.ToggleLogoPrivacy)
+		api.POST("/settings/update-check", updateHandler.ToggleUpdateCheck)
+		api.GET("/settings/proxy", proxyHandler.GetProxy)
+		api.POST("/settings/proxy", proxyHandler.SaveProxy)
 
 		// Language setting
 		api.POST("/settings/language", settingsHandler.UpdateLanguage)
@@ -430,6 +844,26 @@ func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, sett
 		// Import routes
 		api.POST("/import/subscriptions", importHandler.ImportSubscriptions)
 		api.POST("/import/encrypted", importHandler.ImportEncrypted)
+		api.POST("/import/preview", importHandler.PreviewImport)
+		api.POST("/import/confirm", importHandler.ConfirmImport)
+		api.POST("/import/discard", importHandler.DiscardImport)
+		api.GET("/import/batches", importHandler.ListBatches)
+		api.DELETE("/import/batches/:id", importHandler.UndoBatch)
+		api.GET("/budget", importHandler.BudgetConnection)
+		api.POST("/budget/connection", importHandler.SaveBudgetConnection)
+		api.POST("/budget/preview", importHandler.PreviewBudget)
+		api.POST("/budget/sync", importHandler.SyncBudget)
+		api.POST("/budget/disconnect", importHandler.DisconnectBudget)
+		api.GET("/budget/export", importHandler.FireflyExportForm)
+		api.POST("/budget/export", importHandler.ExportToFirefly)
+		api.GET("/settings/config", configHandler.ExportConfig)
+		api.GET("/settings/jobs", jobsHandler.ListJobs)
+		api.GET("/settings/performance", performanceHandler.Performance)
+		api.GET("/settings/integrity", integrityHandler.Report)
+		api.POST("/settings/integrity/fix", integrityHandler.Fix)
+		api.POST("/settings/integrity/fix-all", integrityHandler.FixAll)
+		api.POST("/settings/jobs/:name/run", jobsHandler.RunJob)
+		api.POST("/settings/config", configHandler.ImportConfig)
 
 		// Encrypted export route
 		api.POST("/export/encrypted", handler.ExportEncrypted)
@@ -439,17 +873,37 @@ func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, sett
 		api.POST("/categories", categoryHandler.CreateCategory)
 		api.PUT("/categories/:id", categoryHandler.UpdateCategory)
 		api.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+		api.GET("/category-rules", categoryRuleHandler.ListRules)
+		api.POST("/category-rules", categoryRuleHandler.SetRule)
+		api.DELETE("/category-rules/:id", categoryRuleHandler.DeleteRule)
+
+		// Vendor routes
+		api.GET("/vendors", vendorHandler.ListVendors)
+		api.POST("/vendors", vendorHandler.CreateVendor)
+		api.PUT("/vendors/:id", vendorHandler.UpdateVendor)
+		api.DELETE("/vendors/:id", vendorHandler.DeleteVendor)
+
+		// Command palette search
+		api.GET("/search", searchHandler.Search)
+
+		// Quick add from free text
+		api.POST("/quickparse", handler.QuickParse)
 
 		// Auth routes
 		api.POST("/auth/login", authHandler.Login)
 		api.GET("/auth/logout", authHandler.Logout)
 		api.POST("/auth/forgot-password", authHandler.ForgotPassword)
 		api.POST("/auth/reset-password", authHandler.ResetPassword)
+		api.POST("/auth/password-strength", authHandler.PasswordStrength)
 
 		// Auth settings routes
 		api.POST("/settings/auth/setup", settingsHandler.SetupAuth)
 		api.POST("/settings/auth/disable", settingsHandler.DisableAuth)
 		api.GET("/settings/auth/status", settingsHandler.GetAuthStatus)
+		api.POST("/settings/auth/viewer", settingsHandler.SetupViewer)
+		api.POST("/settings/auth/viewer/remove", settingsHandler.RemoveViewer)
+		api.POST("/settings/auth/sessions", settingsHandler.SaveSessionLifetimes)
+		api.POST("/settings/auth/password-policy", settingsHandler.SavePasswordPolicy)
 
 		// Theme settings routes
 		api.GET("/settings/theme", settingsHandler.GetTheme)
@@ -458,6 +912,11 @@ func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, sett
 		// Date format settings routes
 		api.GET("/settings/date-format", settingsHandler.GetDateFormat)
 		api.POST("/settings/date-format", settingsHandler.SetDateFormat)
+		api.POST("/settings/display-rounding", settingsHandler.SetDisplayRounding)
+		api.POST("/settings/dashboard", settingsHandler.SaveDashboardPreferences)
+		api.POST("/settings/chart-tables", settingsHandler.ToggleChartTables)
+		api.POST("/settings/accent-color", settingsHandler.SaveAccentColor)
+		api.POST("/settings/custom-css", settingsHandler.SaveCustomCSS)
 	}
 
 	// Public API routes (require API key authentication)
@@ -466,6 +925,8 @@ func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, sett
 	v1.Use(middleware.CORS())
 	v1.Use(apiRateLimiter.Middleware())
 	v1.Use(middleware.APIKeyAuth(apiKeyService))
+	// Endpoints that change instance configuration or hand out secrets
+	requireAdmin := middleware.RequireAPIKeyScope(models.APIKeyScopeAdmin)
 	{
 		// Subscription endpoints
 		v1.GET("/subscriptions", handler.GetSubscriptionsAPI)
@@ -473,189 +934,338 @@ func setupRoutes(router *gin.Engine, handler *handlers.SubscriptionHandler, sett
 		v1.GET("/subscriptions/:id", handler.GetSubscription)
 		v1.PUT("/subscriptions/:id", handler.UpdateSubscriptionAPI)
 		v1.DELETE("/subscriptions/:id", handler.DeleteSubscriptionAPI)
+		v1.POST("/subscriptions/:id/cancel", handler.CancelSubscription)
+		v1.POST("/subscriptions/:id/pause", handler.PauseSubscription)
+		v1.POST("/subscriptions/:id/resume", handler.ResumeSubscription)
+		v1.GET("/subscriptions/:id/occurrences", handler.GetSubscriptionOccurrencesAPI)
+		v1.POST("/subscriptions/bulk", handler.BulkCreateSubscriptionsAPI)
+		v1.POST("/subscriptions/:id/logo", handler.RetryLogoAPI)
+		v1.PUT("/subscriptions/:id/icon", handler.UploadLogoAPI)
+		v1.GET("/logos", handler.LogoCandidatesAPI)
+		v1.POST("/quickparse", handler.QuickParse)
+
+		// Usage tracking endpoints
+		v1.POST("/subscriptions/:id/usage-event", usageHandler.LogUsageEvent)
+		v1.GET("/subscriptions/:id/usage-events", usageHandler.GetUsageEvents)
+		v1.GET("/usage/cost-per-use", usageHandler.GetCostPerUse)
+
+		// Shared expense splitting endpoints
+		v1.GET("/subscriptions/:id/shares", splitHandler.GetShares)
+		v1.PUT("/subscriptions/:id/shares", splitHandler.SaveShares)
+		v1.GET("/splits/settlement", splitHandler.GetSettlement)
+		v1.GET("/splits/settlement/csv", splitHandler.ExportSettlementCSV)
+
+		// Payment ledger and renewal confirmation endpoints
+		v1.GET("/payments", paymentHandler.GetPayments)
+		v1.GET("/payments/unconfirmed", paymentHandler.GetUnconfirmedPayments)
+		v1.POST("/payments/:id/confirm", paymentHandler.ConfirmPayment)
+		v1.POST("/payments/:id/reject", paymentHandler.RejectPayment)
+		v1.POST("/reconcile", paymentHandler.ReconcileAPI)
+		v1.POST("/reconcile/confirm", paymentHandler.ConfirmReconcileAPI)
+		v1.GET("/bank", paymentHandler.GetBankStatusAPI)
+		v1.POST("/bank/sync", paymentHandler.SyncBankAPI)
+		v1.GET("/inbound-email", requireAdmin, inboundEmailHandler.GetInboundEmailAPI)
+		v1.POST("/inbound-email/token", requireAdmin, inboundEmailHandler.GenerateInboundTokenAPI)
+		v1.DELETE("/inbound-email/token", requireAdmin, inboundEmailHandler.RevokeInboundTokenAPI)
+		v1.DELETE("/inbound-email/:id", inboundEmailHandler.DismissInboundEmail)
+
+		// Change proposal endpoints
+		v1.GET("/proposals", changeProposalHandler.GetProposals)
+		v1.GET("/proposals/:id", changeProposalHandler.GetProposal)
+		v1.POST("/proposals/:id/approve", changeProposalHandler.ApproveProposal)
+		v1.POST("/proposals/:id/reject", changeProposalHandler.RejectProposal)
 
 		// Stats and export endpoints
 		v1.GET("/stats", handler.GetStats)
+		v1.GET("/stats/history", statsHistoryHandler.GetHistory)
+		v1.GET("/version", updateHandler.GetVersion)
+		v1.GET("/stats/categories/:id/subscriptions", handler.GetCategorySubscriptions)
+		v1.GET("/reports/tax", handler.GetTaxReport)
+		v1.GET("/reports/tax/csv", handler.ExportTaxReportCSV)
 		v1.GET("/export/csv", handler.ExportCSV)
 		v1.GET("/export/json", handler.ExportJSON)
 		v1.GET("/export/ical", handler.ExportICal)
+		v1.GET("/export/bundle", bundleHandler.ExportBundle)
+		v1.GET("/backup", handler.BackupData)
+		v1.POST("/export/encrypted", handler.ExportEncrypted)
+
+		// Home Assistant RESTful sensors
+		v1.GET("/homeassistant/sensors", handler.HomeAssistantSensors)
+		v1.GET("/homeassistant/config", handler.HomeAssistantConfig)
+
+		// Import endpoints
+		v1.POST("/import", importHandler.ImportAPI)
+		v1.POST("/import/confirm", importHandler.ConfirmImportAPI)
+		v1.GET("/import/batches", importHandler.ListBatchesAPI)
+		v1.DELETE("/import/batches/:id", importHandler.UndoBatchAPI)
+		v1.GET("/budget", importHandler.GetBudgetStatusAPI)
+		v1.POST("/budget/preview", importHandler.PreviewBudgetAPI)
+		v1.POST("/budget/sync", importHandler.SyncBudgetAPI)
+		v1.GET("/budget/firefly/accounts", importHandler.GetFireflyAccountsAPI)
+		v1.POST("/budget/firefly/export", importHandler.ExportToFireflyAPI)
+
+		// Settings endpoints
+		v1.GET("/settings", settingsHandler.GetSettingsAPI)
+		v1.PATCH("/settings", requireAdmin, settingsHandler.UpdateSettingsAPI)
+		v1.GET("/settings/proxy", proxyHandler.GetProxy)
+		v1.PUT("/settings/proxy", requireAdmin, proxyHandler.SaveProxy)
+		v1.GET("/settings/notifications", settingsHandler.GetNotificationSettings)
+		v1.PATCH("/settings/notifications", requireAdmin, settingsHandler.UpdateNotificationSettingsAPI)
+		v1.PUT("/settings/notifications", requireAdmin, settingsHandler.ReplaceNotificationSettingsAPI)
+		v1.POST("/settings/notifications/test-all", notificationTestHandler.TestAllNotifications)
+		v1.GET("/reminders/simulate", reminderSimulationHandler.Simulate)
+		v1.GET("/notifications/preview", notificationPreviewHandler.Preview)
+		v1.GET("/settings/smtp", settingsHandler.GetSMTPConfig)
+		v1.PUT("/settings/smtp", requireAdmin, settingsHandler.SaveSMTPConfigAPI)
+		v1.GET("/settings/logins", authHandler.LoginHistory)
+		v1.GET("/settings/sessions", settingsHandler.GetSessionLifetimesAPI)
+		v1.PUT("/settings/sessions", requireAdmin, settingsHandler.SaveSessionLifetimesAPI)
+		v1.GET("/settings/password-policy", settingsHandler.GetPasswordPolicyAPI)
+		v1.PUT("/settings/password-policy", requireAdmin, settingsHandler.SavePasswordPolicyAPI)
+		v1.GET("/settings/shoutrrr", settingsHandler.GetShoutrrrConfig)
+		v1.PUT("/settings/shoutrrr", requireAdmin, settingsHandler.SaveShoutrrrConfigAPI)
+		v1.GET("/settings/defaults", settingsHandler.GetSubscriptionDefaultsAPI)
+		v1.PUT("/settings/defaults", requireAdmin, settingsHandler.SaveSubscriptionDefaultsAPI)
+		v1.GET("/settings/csv-columns", settingsHandler.GetCSVColumnsAPI)
+		v1.PUT("/settings/csv-columns", requireAdmin, settingsHandler.SaveCSVColumnsAPI)
+		v1.GET("/settings/config", configHandler.ExportConfig)
+		v1.PUT("/settings/config", requireAdmin, configHandler.ImportConfig)
+		v1.POST("/erase", requireAdmin, erasureHandler.EraseAll)
+		v1.GET("/settings/exchange-rates", settingsHandler.GetExchangeRateStatusAPI)
+		v1.POST("/settings/exchange-rates/refresh", settingsHandler.RefreshExchangeRatesAPI)
+		v1.PUT("/settings/exchange-rates", requireAdmin, settingsHandler.SetManualRatesAPI)
+
+		// Calendar feed endpoints
+		v1.GET("/calendar", requireAdmin, settingsHandler.GetCalendarAPI)
+		v1.POST("/calendar/token", requireAdmin, settingsHandler.GenerateCalendarToken)
+		v1.DELETE("/calendar/token", requireAdmin, settingsHandler.RevokeCalendarToken)
+		v1.GET("/calendar/options", settingsHandler.GetCalendarFeedOptionsAPI)
+		v1.PUT("/calendar/options", requireAdmin, settingsHandler.SaveCalendarFeedOptionsAPI)
+
+		// Background job endpoints
+		v1.GET("/jobs", jobsHandler.ListJobsAPI)
+		v1.POST("/jobs/:name/run", jobsHandler.RunJobAPI)
+
+		// Runtime counters (housekeeping, memory) as published via expvar
+		v1.GET("/metrics", gin.WrapH(expvar.Handler()))
+		v1.GET("/performance", performanceHandler.Performance)
+
+		// Data integrity check
+		v1.GET("/integrity", integrityHandler.Report)
+		v1.POST("/integrity/fix", integrityHandler.Fix)
+		v1.POST("/integrity/fix-all", integrityHandler.FixAll)
 
 		// Category endpoints
 		v1.GET("/categories", categoryHandler.ListCategories)
 		v1.POST("/categories", categoryHandler.CreateCategory)
 		v1.PUT("/categories/:id", categoryHandler.UpdateCategory)
 		v1.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+
+		// Import category mapping rules
+		v1.GET("/category-rules", categoryRuleHandler.ListRules)
+		v1.POST("/category-rules", categoryRuleHandler.SetRule)
+		v1.DELETE("/category-rules/:id", categoryRuleHandler.DeleteRule)
+
+		// Vendor routes
+		v1.GET("/vendors", vendorHandler.ListVendors)
+		v1.POST("/vendors", vendorHandler.CreateVendor)
+		v1.PUT("/vendors/:id", vendorHandler.UpdateVendor)
+		v1.DELETE("/vendors/:id", vendorHandler.DeleteVendor)
 	}
-}
 
-// startRenewalReminderScheduler starts a background goroutine that checks for
-// upcoming renewals and sends reminder emails and Shoutrrr notifications daily
-func startRenewalReminderScheduler(subscriptionService *service.SubscriptionService, emailService *service.EmailService, shoutrrrService *service.ShoutrrrService, settingsService *service.SettingsService) {
-	// Run immediately on startup (after a short delay to let server initialize)
-	go func() {
-		time.Sleep(30 * time.Second) // Wait 30 seconds for server to fully start
-		checkAndSendRenewalReminders(subscriptionService, emailService, shoutrrrService, settingsService)
-	}()
-
-	// Then run daily at midnight
-	// Note: Ticker is intentionally not stopped as this is a long-running server process.
-	// The ticker will run for the lifetime of the application, which is the desired behavior.
-	ticker := time.NewTicker(24 * time.Hour)
-	go func() {
-		defer ticker.Stop() // Clean up ticker if goroutine exits (defensive programming)
-		for range ticker.C {
-			// Recover from any panics in the reminder check to keep the scheduler running
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						slog.Error("panic in renewal reminder check", "panic", r)
-					}
-				}()
-				checkAndSendRenewalReminders(subscriptionService, emailService, shoutrrrService, settingsService)
-			}()
-		}
-	}()
+	// Minimal endpoints for Shortcuts/Tasker, which also take the API key as
+	// api_key query parameter. The widget endpoints only read, so like the
+	// calendar feed they only take read-only keys there.
+	shortcuts := router.Group("/api/v1/shortcuts")
+	shortcuts.Use(middleware.CORS())
+	shortcuts.Use(apiRateLimiter.Middleware())
+	{
+		shortcuts.GET("/next-renewal", middleware.APIKeyFeedAuth(apiKeyService), handler.ShortcutNextRenewal)
+		shortcuts.GET("/monthly-total", middleware.APIKeyFeedAuth(apiKeyService), handler.ShortcutMonthlyTotal)
+		shortcuts.POST("/add", middleware.APIKeyQueryAuth(apiKeyService), handler.ShortcutAddSubscription)
+	}
+
+	// The calendar feed for an API key instead of the calendar token; calendar
+	// apps can only pass a read-only key as api_key query parameter
+	router.GET("/api/v1/calendar/subscriptions.ics", apiRateLimiter.Middleware(), middleware.APIKeyFeedAuth(apiKeyService), handler.ServeCalendarFeedAPI)
 }
 
-// checkAndSendRenewalReminders checks for subscriptions needing reminders and sends emails and Shoutrrr notifications
-func checkAndSendRenewalReminders(subscriptionService *service.SubscriptionService, emailService *service.EmailService, shoutrrrService *service.ShoutrrrService, settingsService *service.SettingsService) {
-	// Get subscriptions needing reminders (per-subscription settings)
-	subscriptions, err := subscriptionService.GetSubscriptionsNeedingReminders()
-	if err != nil {
-		slog.Error("failed to get subscriptions for renewal reminders", "error", err)
-		return
+// checkAndSendUnusedNudge sends the unused subscription summary through all notification channels
+// at most once per calendar month
+func checkAndSendUnusedNudge(ctx context.Context, usageService *service.UsageService, notifier *service.NotificationDispatcher, settingsService *service.SettingsService, now time.Time) error {
+	if !settingsService.GetBoolSettingWithDefault("unused_nudges", false) {
+		return nil
 	}
 
-	if len(subscriptions) == 0 {
-		slog.Info("no subscriptions need renewal reminders today")
-		return
+	month := now.Year()*100 + int(now.Month())
+	if settingsService.GetIntSettingWithDefault("unused_nudge_last_month", 0) == month {
+		return nil
 	}
 
-	slog.Info("checking subscriptions for renewal reminders", "count", len(subscriptions))
+	threshold := settingsService.GetFloatSettingWithDefault("unused_nudge_threshold", 10.0)
+	nudge, err := usageService.GetUnusedNudge(ctx, threshold)
+	if err != nil {
+		slog.Error("failed to get unused subscriptions", "error", err)
+		return err
+	}
 
-	// Send reminder for each subscription (both email and Shoutrrr)
-	sentCount := 0
-	failedCount := 0
-	for sub, daysUntil := range subscriptions {
-		emailErr := emailService.SendRenewalReminder(sub, daysUntil)
-		shoutrrrErr := shoutrrrService.SendRenewalReminder(sub, daysUntil)
+	if len(nudge.Subscriptions) == 0 {
+		slog.Info("no unused subscriptions above threshold", "threshold", threshold)
+		return nil
+	}
 
-		// If both fail, count as failed; otherwise consider it sent
-		if emailErr != nil && shoutrrrErr != nil {
-			slog.Error("failed to send renewal reminder", "subscription", sub.Name, "id", sub.ID, "emailError", emailErr, "shoutrrrError", shoutrrrErr)
-			failedCount++
-		} else {
-			// Mark reminder as sent for this renewal date
-			now := time.Now()
-			sub.LastReminderSent = &now
-			if sub.RenewalDate != nil {
-				renewalDateCopy := *sub.RenewalDate
-				sub.LastReminderRenewalDate = &renewalDateCopy
-			}
+	if err := notifier.SendUnusedSubscriptionsNudge(nudge); err != nil {
+		slog.Error("failed to send unused subscription nudge", "error", err)
+		return err
+	}
 
-			// Update the subscription in the database
-			_, updateErr := subscriptionService.Update(sub.ID, sub)
-			if updateErr != nil {
-				slog.Warn("failed to update last reminder sent", "subscription", sub.Name, "id", sub.ID, "error", updateErr)
-			}
+	if err := settingsService.SetIntSetting("unused_nudge_last_month", month); err != nil {
+		slog.Warn("failed to record unused subscription nudge", "error", err)
+	}
+	slog.Info("sent unused subscription nudge", "count", len(nudge.Subscriptions), "savings", nudge.MonthlySavings)
+	return nil
+}
 
-			if emailErr != nil {
-				slog.Info("sent shoutrrr renewal reminder", "subscription", sub.Name, "daysUntil", daysUntil, "emailError", emailErr)
-			} else if shoutrrrErr != nil {
-				slog.Info("sent email renewal reminder", "subscription", sub.Name, "daysUntil", daysUntil, "shoutrrrError", shoutrrrErr)
-			} else {
-				slog.Info("sent renewal reminders", "subscription", sub.Name, "daysUntil", daysUntil)
-			}
-			sentCount++
-		}
+// checkAndSendRateAlerts notifies about exchange rate moves that changed the cost of
+// foreign-currency subscriptions since last month
+func checkAndSendRateAlerts(ctx context.Context, rateAlertService *service.RateAlertService, notifier *service.NotificationDispatcher, settingsService *service.SettingsService, now time.Time) error {
+	if !settingsService.GetBoolSettingWithDefault("rate_alerts", false) {
+		return nil
 	}
 
-	slog.Info("renewal reminder check complete", "sent", sentCount, "failed", failedCount)
-}
+	threshold := settingsService.GetFloatSettingWithDefault("rate_alert_threshold", service.DefaultRateAlertThreshold)
+	alert, err := rateAlertService.Check(ctx, threshold, now)
+	if err != nil {
+		slog.Error("failed to check exchange rate changes", "error", err)
+		return err
+	}
+	if alert == nil {
+		return nil
+	}
 
-// startCancellationReminderScheduler starts a background goroutine that checks for
-// upcoming cancellations and sends reminder emails and Shoutrrr notifications daily
-func startCancellationReminderScheduler(subscriptionService *service.SubscriptionService, emailService *service.EmailService, shoutrrrService *service.ShoutrrrService, settingsService *service.SettingsService) {
-	// Run immediately on startup (after a short delay to let server initialize)
-	go func() {
-		time.Sleep(30 * time.Second) // Wait 30 seconds for server to fully start
-		checkAndSendCancellationReminders(subscriptionService, emailService, shoutrrrService, settingsService)
-	}()
-
-	// Then run daily at midnight
-	// Note: Ticker is intentionally not stopped as this is a long-running server process.
-	// The ticker will run for the lifetime of the application, which is the desired behavior.
-	ticker := time.NewTicker(24 * time.Hour)
-	go func() {
-		defer ticker.Stop() // Clean up ticker if goroutine exits (defensive programming)
-		for range ticker.C {
-			// Recover from any panics in the reminder check to keep the scheduler running
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						slog.Error("panic in cancellation reminder check", "panic", r)
-					}
-				}()
-				checkAndSendCancellationReminders(subscriptionService, emailService, shoutrrrService, settingsService)
-			}()
-		}
-	}()
+	if err := notifier.SendExchangeRateAlert(alert); err != nil {
+		slog.Error("failed to send exchange rate alert", "error", err)
+		return err
+	}
+	slog.Info("sent exchange rate alert", "currencies", len(alert.Changes))
+	return nil
 }
 
-// checkAndSendCancellationReminders checks for subscriptions needing cancellation reminders and sends emails and Shoutrrr notifications
-func checkAndSendCancellationReminders(subscriptionService *service.SubscriptionService, emailService *service.EmailService, shoutrrrService *service.ShoutrrrService, settingsService *service.SettingsService) {
-	// Get subscriptions needing cancellation reminders (per-subscription settings)
-	subscriptions, err := subscriptionService.GetSubscriptionsNeedingCancellationReminders()
+// checkAndSendWeeklySummary sends the summary of the week's subscription changes,
+// the coming week's renewals and the budget status once every seven days
+func checkAndSendWeeklySummary(ctx context.Context, weeklySummaryService *service.WeeklySummaryService, notifier *service.NotificationDispatcher, settingsService *service.SettingsService, now time.Time) error {
+	if !settingsService.GetBoolSettingWithDefault("weekly_summary", false) {
+		return nil
+	}
+
+	summary, err := weeklySummaryService.Check(ctx, now)
 	if err != nil {
-		slog.Error("failed to get subscriptions for cancellation reminders", "error", err)
-		return
+		slog.Error("failed to build weekly summary", "error", err)
+		return err
+	}
+	if summary == nil {
+		return nil
 	}
 
-	if len(subscriptions) == 0 {
-		slog.Info("no subscriptions need cancellation reminders today")
-		return
+	if err := notifier.SendWeeklySummary(summary); err != nil {
+		slog.Error("failed to send weekly summary", "error", err)
+		return err
 	}
+	slog.Info("sent weekly summary", "changes", len(summary.Added)+len(summary.Edited)+len(summary.Cancelled)+len(summary.Deleted)+len(summary.PriceChanges), "renewals", len(summary.Renewals))
+	return nil
+}
 
-	slog.Info("checking subscriptions for cancellation reminders", "count", len(subscriptions))
+// checkAndSendMonthlyReport emails the CSV export once a month, starting on the 1st
+func checkAndSendMonthlyReport(ctx context.Context, monthlyReportService *service.MonthlyReportService, settingsService *service.SettingsService, now time.Time) error {
+	if !settingsService.GetBoolSettingWithDefault("monthly_report", false) {
+		return nil
+	}
 
-	// Send reminder for each subscription (both email and Shoutrrr)
-	sentCount := 0
-	failedCount := 0
-	for sub, daysUntil := range subscriptions {
-		emailErr := emailService.SendCancellationReminder(sub, daysUntil)
-		shoutrrrErr := shoutrrrService.SendCancellationReminder(sub, daysUntil)
+	report, err := monthlyReportService.Send(ctx, now)
+	if err != nil {
+		slog.Error("failed to send monthly report", "error", err)
+		return err
+	}
+	if report != nil {
+		slog.Info("sent monthly report", "month", report.Month.Format("2006-01"), "subscriptions", report.Subscriptions)
+	}
+	return nil
+}
 
-		// If both fail, count as failed; otherwise consider it sent
-		if emailErr != nil && shoutrrrErr != nil {
-			slog.Error("failed to send cancellation reminder", "subscription", sub.Name, "id", sub.ID, "emailError", emailErr, "shoutrrrError", shoutrrrErr)
-			failedCount++
-		} else {
-			// Mark reminder as sent for this cancellation date
-			now := time.Now()
-			sub.LastCancellationReminderSent = &now
-			if sub.CancellationDate != nil {
-				cancellationDateCopy := *sub.CancellationDate
-				sub.LastCancellationReminderDate = &cancellationDateCopy
-			}
+// checkRenewalConfirmations records the renewals that passed as pending payments
+// and asks through the notification channels to confirm their charges. Without a configured
+// channel they are only listed under Renewals.
+func checkRenewalConfirmations(ctx context.Context, paymentService *service.PaymentService, notifier *service.NotificationDispatcher, settingsService *service.SettingsService, now time.Time) error {
+	if !settingsService.GetBoolSettingWithDefault("renewal_confirmations", false) {
+		return nil
+	}
 
-			// Update the subscription in the database
-			_, updateErr := subscriptionService.Update(sub.ID, sub)
-			if updateErr != nil {
-				slog.Warn("failed to update last cancellation reminder sent", "subscription", sub.Name, "id", sub.ID, "error", updateErr)
-			}
+	payments, err := paymentService.RecordRenewals(ctx, now)
+	if err != nil {
+		slog.Error("failed to record renewals for confirmation", "error", err)
+		return err
+	}
+	if len(payments) == 0 {
+		return nil
+	}
 
-			if emailErr != nil {
-				slog.Info("sent shoutrrr cancellation reminder", "subscription", sub.Name, "daysUntil", daysUntil, "emailError", emailErr)
-			} else if shoutrrrErr != nil {
-				slog.Info("sent email cancellation reminder", "subscription", sub.Name, "daysUntil", daysUntil, "shoutrrrError", shoutrrrErr)
-			} else {
-				slog.Info("sent cancellation reminders", "subscription", sub.Name, "daysUntil", daysUntil)
-			}
-			sentCount++
+	if err := notifier.SendRenewalConfirmations(payments); err != nil {
+		if errors.Is(err, service.ErrChannelNotConfigured) {
+			slog.Info("renewals awaiting confirmation, no notification channel configured", "count", len(payments))
+			return nil
 		}
+		slog.Error("failed to send renewal confirmations", "error", err)
+		return err
+	}
+	slog.Info("sent renewal confirmations", "count", len(payments))
+	return nil
+}
+
+// syncBankTransactions reads the linked bank accounts, confirming the renewals
+// they pay and proposing unmatched recurring charges. Does nothing until a
+// bank is connected, and in offline mode.
+func syncBankTransactions(ctx context.Context, openBankingService *service.OpenBankingService) error {
+	if !openBankingService.Connection().Configured() || service.OfflineMode() {
+		return nil
 	}
 
-	slog.Info("cancellation reminder check complete", "sent", sentCount, "failed", failedCount)
+	result, err := openBankingService.Sync(ctx)
+	if errors.Is(err, service.ErrBankNotLinked) {
+		return nil
+	}
+	if err != nil {
+		slog.Error("failed to sync bank transactions", "error", err)
+		return err
+	}
+	slog.Info("synced bank transactions", "transactions", result.Transactions, "recorded", result.Recorded, "candidates", result.Candidates)
+	return nil
+}
+
+// syncBudgetAmounts updates the cost of subscriptions from the recurring
+// expenses of YNAB or Firefly III. Does nothing unless a budgeting tool is
+// connected with amount sync on, and in offline mode.
+func syncBudgetAmounts(ctx context.Context, budgetImportService *service.BudgetImportService) error {
+	connection := budgetImportService.Connection()
+	if !connection.Configured() || !connection.SyncAmounts || service.OfflineMode() {
+		return nil
+	}
+
+	result, err := budgetImportService.SyncAmounts(ctx)
+	if err != nil {
+		slog.Error("failed to sync amounts from budgeting tool", "provider", connection.Provider, "error", err)
+		return err
+	}
+	slog.Info("synced amounts from budgeting tool", "provider", connection.Provider, "expenses", result.Expenses, "matched", result.Matched, "updated", result.Updated)
+	return nil
+}
+
+// processLogoQueue looks up the logos of saved subscriptions in the background
+func processLogoQueue(ctx context.Context, logoQueueService *service.LogoQueueService) error {
+	result, err := logoQueueService.Process(ctx)
+	if result.Fetched > 0 || result.Failed > 0 {
+		slog.Info("processed logo queue", "fetched", result.Fetched, "failed", result.Failed)
+	}
+	return err
 }
 
 // handleResetPassword handles the --reset-password CLI command
@@ -689,12 +1299,7 @@ func handleResetPassword(authService *service.AuthService, newPassword string) {
 		password = string(passwordBytes)
 	}
 
-	// Validate password length
-	if len(password) < 8 {
-		log.Fatal("Password must be at least 8 characters long")
-	}
-
-	// Update password
+	// Update password; the password policy is checked here
 	if err := authService.SetAuthPassword(password); err != nil {
 		log.Fatal("Failed to update password:", err)
 	}
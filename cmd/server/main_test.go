@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestRoutes registers the routes with only the API key service; the
+// tests must not reach a handler
+func setupTestRoutes(t *testing.T) (*gin.Engine, *service.APIKeyService) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.APIKey{}))
+	apiKeys := service.NewAPIKeyService(repository.NewSettingsRepository(db))
+
+	router := gin.New()
+	setupRoutes(router, nil, nil, apiKeys, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	return router, apiKeys
+}
+
+func TestSetupRoutes_AdminScope(t *testing.T) {
+	router, apiKeys := setupTestRoutes(t)
+	key, err := apiKeys.CreateAPIKey("Automation", nil)
+	require.NoError(t, err)
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPatch, "/api/v1/settings/notifications"},
+		{http.MethodPut, "/api/v1/settings/notifications"},
+		{http.MethodPut, "/api/v1/settings/config"},
+		{http.MethodPut, "/api/v1/settings/smtp"},
+		{http.MethodPut, "/api/v1/settings/shoutrrr"},
+		{http.MethodPut, "/api/v1/settings/sessions"},
+		{http.MethodPut, "/api/v1/settings/password-policy"},
+		{http.MethodPut, "/api/v1/settings/proxy"},
+		{http.MethodPut, "/api/v1/settings/exchange-rates"},
+		{http.MethodPost, "/api/v1/erase"},
+		{http.MethodPost, "/api/v1/calendar/token"},
+		{http.MethodPost, "/api/v1/inbound-email/token"},
+		{http.MethodDelete, "/api/v1/inbound-email/token"},
+		{http.MethodDelete, "/api/v1/calendar/token"},
+		{http.MethodPut, "/api/v1/calendar/options"},
+		{http.MethodPatch, "/api/v1/settings"},
+		{http.MethodPut, "/api/v1/settings/defaults"},
+		{http.MethodPut, "/api/v1/settings/csv-columns"},
+		// These return the inbound webhook URL and the calendar token
+		{http.MethodGet, "/api/v1/inbound-email"},
+		{http.MethodGet, "/api/v1/calendar"},
+	} {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			req.Header.Set("X-API-Key", key.Key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Contains(t, w.Body.String(), "admin scope")
+		})
+	}
+}
+
+func TestSetupRoutes_ReadOnlyKeySecrets(t *testing.T) {
+	router, apiKeys := setupTestRoutes(t)
+	key, err := apiKeys.CreateAPIKey("Widget", []string{models.APIKeyScopeRead})
+	require.NoError(t, err)
+
+	// A read-only key in a widget or calendar URL must not lead to a secret
+	for _, path := range []string{"/api/v1/inbound-email", "/api/v1/calendar", "/api/v1/calendar?category=1"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("X-API-Key", key.Key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Contains(t, w.Body.String(), "admin scope")
+		})
+	}
+}
+
+func TestSetupRoutes_ShortcutQueryKeys(t *testing.T) {
+	router, apiKeys := setupTestRoutes(t)
+	key, err := apiKeys.CreateAPIKey("Shortcuts", nil)
+	require.NoError(t, err)
+	readOnly, err := apiKeys.CreateAPIKey("Widget", []string{models.APIKeyScopeRead})
+	require.NoError(t, err)
+
+	for _, tc := range []struct{ method, path, key, want string }{
+		{http.MethodGet, "/api/v1/shortcuts/next-renewal", key.Key, "Only read-only API keys"},
+		{http.MethodGet, "/api/v1/shortcuts/monthly-total", key.Key, "Only read-only API keys"},
+		{http.MethodPost, "/api/v1/shortcuts/add", readOnly.Key, "API key is read-only"},
+	} {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path+"?api_key="+tc.key, nil))
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Contains(t, w.Body.String(), tc.want)
+		})
+	}
+}
@@ -1,28 +1,159 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 type Config struct {
+	// DataDir holds the database, logos, attachments, backups, custom locales and templates
+	DataDir      string
 	DatabasePath string
-	Port         string
-	Environment  string
-	LocaleDir    string
+	// WebDir holds the bundled templates and static assets
+	WebDir      string
+	Port        string
+	Environment string
+	LocaleDir   string
+	// HooksFile configures command and HTTP hooks (see docs/configuration.md)
+	HooksFile string
+	// CurrenciesFile adds or overrides currencies (see docs/configuration.md)
+	CurrenciesFile string
+	// HousekeepingIntervalHours is how often orphaned data is pruned; 0 disables it
+	HousekeepingIntervalHours int
+	// BackupIntervalHours is how often a backup is written to the backups directory; 0 disables it
+	BackupIntervalHours int
+	// BackupKeep is how many scheduled backups are kept
+	BackupKeep int
+	// RequestTimeoutSeconds bounds how long a request may run; 0 disables the limit
+	RequestTimeoutSeconds int
+	// SlowQueryMs is the duration from which database queries are logged; 0 disables the log
+	SlowQueryMs int
+
+	// LogoAllowedSchemes are the URL schemes logos are fetched from (http, https)
+	LogoAllowedSchemes []string
+	// LogoAllowPrivateNetworks lets logo lookups reach loopback and private addresses
+	LogoAllowPrivateNetworks bool
+
+	// OfflineMode blocks all outbound network calls: exchange rates, logos,
+	// Shoutrrr, HTTP hooks, bank sync and update checks
+	OfflineMode bool
+
+	// TrustedProxies are the reverse proxies, as addresses or CIDR ranges, whose
+	// X-Forwarded-For header gives the client IP; empty trusts none
+	TrustedProxies []string
+
+	// ReadyzOptionalChecks lets /readyz run the optional exchange rate and SMTP
+	// checks on request; they are off because the endpoint needs no login
+	ReadyzOptionalChecks bool
+
+	// SQLite tuning (see database.Options)
+	DBBusyTimeoutMs int
+	DBJournalMode   string
+	DBSynchronous   string
+	DBMaxOpenConns  int
+	DBMaxIdleConns  int
+
+	// DatabaseKeyFile holds the SQLCipher key when DATABASE_KEY is not set
+	DatabaseKeyFile string
+
+	databaseKey          string
+	explicitDatabasePath bool
 }
 
 func Load() *Config {
+	dataDir := resolveDataDir()
+
+	// Custom locales are picked up from the data directory unless LOCALE_DIR says otherwise
+	localeDir := getEnv("LOCALE_DIR", "")
+	if localeDir == "" && isDir(filepath.Join(dataDir, localesDir)) {
+		localeDir = filepath.Join(dataDir, localesDir)
+	}
+
 	return &Config{
-		DatabasePath: getEnv("DATABASE_PATH", "./data/subvault.db"),
-		Port:         getEnv("PORT", "8080"),
-		Environment:  getEnv("GIN_MODE", "debug"),
-		LocaleDir:    getEnv("LOCALE_DIR", ""),
+		DataDir:                   dataDir,
+		DatabasePath:              getEnv("DATABASE_PATH", filepath.Join(dataDir, databaseFile)),
+		WebDir:                    resolveWebDir(),
+		Port:                      getEnv("PORT", "8080"),
+		Environment:               getEnv("GIN_MODE", "debug"),
+		LocaleDir:                 localeDir,
+		HooksFile:                 getEnv("HOOKS_FILE", filepath.Join(dataDir, "hooks.yaml")),
+		CurrenciesFile:            getEnv("CURRENCIES_FILE", filepath.Join(dataDir, "currencies.yaml")),
+		HousekeepingIntervalHours: getEnvInt("HOUSEKEEPING_INTERVAL_HOURS", 24),
+		BackupIntervalHours:       getEnvInt("BACKUP_INTERVAL_HOURS", 0),
+		BackupKeep:                getEnvInt("BACKUP_KEEP", 7),
+		RequestTimeoutSeconds:     getEnvInt("REQUEST_TIMEOUT_SECONDS", 30),
+		SlowQueryMs:               getEnvInt("SLOW_QUERY_MS", 200),
+		LogoAllowedSchemes:        getEnvList("LOGO_ALLOWED_SCHEMES", []string{"https", "http"}),
+		LogoAllowPrivateNetworks:  os.Getenv("LOGO_ALLOW_PRIVATE_NETWORKS") == "true",
+		OfflineMode:               os.Getenv("OFFLINE_MODE") == "true",
+		ReadyzOptionalChecks:      os.Getenv("READYZ_OPTIONAL_CHECKS") == "true",
+		TrustedProxies:            getEnvList("TRUSTED_PROXIES", nil),
+		explicitDatabasePath:      os.Getenv("DATABASE_PATH") != "",
+		DBBusyTimeoutMs:           getEnvInt("DB_BUSY_TIMEOUT_MS", 5000),
+		DBJournalMode:             getEnv("DB_JOURNAL_MODE", "WAL"),
+		DBSynchronous:             getEnv("DB_SYNCHRONOUS", "NORMAL"),
+		DBMaxOpenConns:            getEnvInt("DB_MAX_OPEN_CONNS", 4),
+		DBMaxIdleConns:            getEnvInt("DB_MAX_IDLE_CONNS", 2),
+		DatabaseKeyFile:           os.Getenv("DATABASE_KEY_FILE"),
+		databaseKey:               os.Getenv("DATABASE_KEY"),
 	}
 }
 
+// DatabaseKey returns the key the database is encrypted with, from
+// DATABASE_KEY or the first line of DATABASE_KEY_FILE. An empty key leaves the
+// database unencrypted.
+func (c *Config) DatabaseKey() (string, error) {
+	if c.databaseKey != "" || c.DatabaseKeyFile == "" {
+		return c.databaseKey, nil
+	}
+	data, err := os.ReadFile(c.DatabaseKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read database key file: %w", err)
+	}
+	key, _, _ := strings.Cut(string(data), "\n")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", errors.New("database key file is empty")
+	}
+	return key, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+// getEnvInt reads an integer environment variable, falling back to the default
+// when unset or not a valid integer
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvList reads a comma separated environment variable, falling back to the
+// default when unset or empty
+func getEnvList(key string, defaultValue []string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseKey(t *testing.T) {
+	t.Setenv("DATABASE_KEY", "")
+	t.Setenv("DATABASE_KEY_FILE", "")
+	key, err := Load().DatabaseKey()
+	require.NoError(t, err)
+	assert.Empty(t, key)
+
+	keyFile := filepath.Join(t.TempDir(), "db.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("  correct horse battery staple \nignored\n"), 0o600))
+	t.Setenv("DATABASE_KEY_FILE", keyFile)
+	key, err = Load().DatabaseKey()
+	require.NoError(t, err)
+	assert.Equal(t, "correct horse battery staple", key)
+
+	// The environment variable wins over the file
+	t.Setenv("DATABASE_KEY", "from-env")
+	key, err = Load().DatabaseKey()
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", key)
+
+	t.Setenv("DATABASE_KEY", "")
+	require.NoError(t, os.WriteFile(keyFile, []byte("\n"), 0o600))
+	_, err = Load().DatabaseKey()
+	assert.Error(t, err)
+
+	t.Setenv("DATABASE_KEY_FILE", filepath.Join(t.TempDir(), "missing.key"))
+	_, err = Load().DatabaseKey()
+	assert.Error(t, err)
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// legacyDataDir is where the database lived before DATA_DIR existed (relative to the working directory)
+const legacyDataDir = "./data"
+
+// containerDataDir is used when a /data volume is mounted and DATA_DIR is not set
+const containerDataDir = "/data"
+
+// databaseFile is the SQLite file name inside the data directory
+const databaseFile = "subvault.db"
+
+// Subdirectories of the data directory
+const (
+	logosDir       = "logos"
+	attachmentsDir = "attachments"
+	backupsDir     = "backups"
+	localesDir     = "locales"
+	templatesDir   = "templates"
+)
+
+// LogosDir holds locally cached subscription logos
+func (c *Config) LogosDir() string { return filepath.Join(c.DataDir, logosDir) }
+
+// AttachmentsDir holds uploaded files such as invoices
+func (c *Config) AttachmentsDir() string { return filepath.Join(c.DataDir, attachmentsDir) }
+
+// BackupsDir holds backups written by the server
+func (c *Config) BackupsDir() string { return filepath.Join(c.DataDir, backupsDir) }
+
+// CustomTemplatesDir holds template overrides, mirroring the layout of web/templates
+func (c *Config) CustomTemplatesDir() string { return filepath.Join(c.DataDir, templatesDir) }
+
+// EnsureDataDirs creates the data directory and its standard subdirectories
+func (c *Config) EnsureDataDirs() error {
+	dirs := []string{c.DataDir, filepath.Dir(c.DatabasePath), c.LogosDir(), c.AttachmentsDir(), c.BackupsDir(),
+		filepath.Join(c.DataDir, localesDir), c.CustomTemplatesDir()}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// TemplatePath returns the path of a template below web/templates, preferring an
+// override with the same relative path in the custom templates directory
+func (c *Config) TemplatePath(name string) string {
+	if custom := filepath.Join(c.CustomTemplatesDir(), name); fileExists(custom) {
+		return custom
+	}
+	return filepath.Join(c.WebDir, "templates", name)
+}
+
+// StaticDir is the directory of the bundled CSS, JS and images
+func (c *Config) StaticDir() string { return filepath.Join(c.WebDir, "static") }
+
+// resolveDataDir picks the data directory: DATA_DIR, then the directory of an
+// explicit DATABASE_PATH, then a mounted /data volume, then the XDG data home
+func resolveDataDir() string {
+	if dir := os.Getenv("DATA_DIR"); dir != "" {
+		return dir
+	}
+	if dbPath := os.Getenv("DATABASE_PATH"); dbPath != "" {
+		return filepath.Dir(dbPath)
+	}
+	if isDir(containerDataDir) {
+		return containerDataDir
+	}
+	return xdgDataDir()
+}
+
+// xdgDataDir returns $XDG_DATA_HOME/subvault, falling back to ~/.local/share/subvault
+func xdgDataDir() string {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return legacyDataDir
+		}
+		base = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(base, "subvault")
+}
+
+// resolveWebDir finds the web assets: WEB_DIR, then ./web, then next to the binary
+func resolveWebDir() string {
+	if dir := os.Getenv("WEB_DIR"); dir != "" {
+		return dir
+	}
+	if isDir("web") {
+		return "web"
+	}
+	if exe, err := os.Executable(); err == nil {
+		if dir := filepath.Join(filepath.Dir(exe), "web"); isDir(dir) {
+			return dir
+		}
+	}
+	return "web"
+}
+
+// MigrateLegacyData moves a database from the old ./data location into the
+// data directory when the data directory has none yet. It is a no-op when
+// DATABASE_PATH points somewhere explicitly.
+func (c *Config) MigrateLegacyData() error {
+	if c.explicitDatabasePath {
+		return nil
+	}
+	legacyDB := filepath.Join(legacyDataDir, databaseFile)
+	if !fileExists(legacyDB) || fileExists(c.DatabasePath) || samePath(legacyDataDir, c.DataDir) {
+		return nil
+	}
+
+	// The WAL and shared-memory files belong to the database and must move with it
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		src := legacyDB + suffix
+		if !fileExists(src) {
+			continue
+		}
+		if err := moveFile(src, c.DatabasePath+suffix); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", src, err)
+		}
+	}
+	slog.Info("migrated database to data directory", "from", legacyDB, "to", c.DatabasePath)
+	return nil
+}
+
+// moveFile renames a file, copying it when source and target are on different filesystems
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_DataDirResolution(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("DATA_DIR", "")
+	t.Setenv("DATABASE_PATH", "")
+	t.Setenv("LOCALE_DIR", "")
+	xdg := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", xdg)
+
+	cfg := Load()
+	if !isDir(containerDataDir) {
+		assert.Equal(t, filepath.Join(xdg, "subvault"), cfg.DataDir)
+		assert.Equal(t, filepath.Join(xdg, "subvault", "subvault.db"), cfg.DatabasePath)
+	}
+
+	t.Setenv("DATABASE_PATH", "/srv/subvault/app.db")
+	cfg = Load()
+	assert.Equal(t, "/srv/subvault", cfg.DataDir)
+	assert.Equal(t, "/srv/subvault/app.db", cfg.DatabasePath)
+
+	t.Setenv("DATA_DIR", "/var/lib/subvault")
+	t.Setenv("DATABASE_PATH", "")
+	cfg = Load()
+	assert.Equal(t, "/var/lib/subvault", cfg.DataDir)
+	assert.Equal(t, "/var/lib/subvault/subvault.db", cfg.DatabasePath)
+	assert.Equal(t, "/var/lib/subvault/logos", cfg.LogosDir())
+}
+
+func TestMigrateLegacyData(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("DATABASE_PATH", "")
+	t.Setenv("DATA_DIR", filepath.Join(t.TempDir(), "subvault"))
+
+	require.NoError(t, os.MkdirAll(legacyDataDir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(legacyDataDir, databaseFile), []byte("db"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(legacyDataDir, databaseFile+"-wal"), []byte("wal"), 0600))
+
+	cfg := Load()
+	require.NoError(t, cfg.EnsureDataDirs())
+	require.NoError(t, cfg.MigrateLegacyData())
+
+	data, err := os.ReadFile(cfg.DatabasePath)
+	require.NoError(t, err)
+	assert.Equal(t, "db", string(data))
+	assert.FileExists(t, cfg.DatabasePath+"-wal")
+	assert.NoFileExists(t, filepath.Join(legacyDataDir, databaseFile))
+
+	// A second run finds nothing to migrate
+	require.NoError(t, cfg.MigrateLegacyData())
+}
+
+func TestTemplatePath_Override(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir(), WebDir: "web"}
+	assert.Equal(t, filepath.Join("web", "templates", "error.html"), cfg.TemplatePath("error.html"))
+
+	override := filepath.Join(cfg.CustomTemplatesDir(), "error.html")
+	require.NoError(t, os.MkdirAll(filepath.Dir(override), 0750))
+	require.NoError(t, os.WriteFile(override, []byte("custom"), 0600))
+	assert.Equal(t, override, cfg.TemplatePath("error.html"))
+}
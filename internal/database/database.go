@@ -1,16 +1,140 @@
 package database
 
 import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-func Initialize(dbPath string) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+// Options controls SQLite connection tuning
+type Options struct {
+	BusyTimeoutMs int    // How long a connection waits on a locked database before failing
+	JournalMode   string // WAL, DELETE, TRUNCATE, PERSIST, MEMORY or OFF
+	Synchronous   string // OFF, NORMAL, FULL or EXTRA
+	MaxOpenConns  int
+	MaxIdleConns  int
+	Key           string // Encrypts the database with SQLCipher; empty leaves it unencrypted
+}
+
+// DefaultOptions returns the tuning used when no configuration is provided
+func DefaultOptions() Options {
+	return Options{
+		BusyTimeoutMs: 5000,
+		JournalMode:   "WAL",
+		Synchronous:   "NORMAL",
+		MaxOpenConns:  4,
+		MaxIdleConns:  2,
+	}
+}
+
+var validJournalModes = map[string]bool{
+	"WAL": true, "DELETE": true, "TRUNCATE": true, "PERSIST": true, "MEMORY": true, "OFF": true,
+}
+
+var validSynchronousModes = map[string]bool{
+	"OFF": true, "NORMAL": true, "FULL": true, "EXTRA": true,
+}
+
+// normalize replaces invalid values with defaults
+func (o Options) normalize(dbPath string) Options {
+	defaults := DefaultOptions()
+
+	o.JournalMode = strings.ToUpper(strings.TrimSpace(o.JournalMode))
+	if !validJournalModes[o.JournalMode] {
+		o.JournalMode = defaults.JournalMode
+	}
+	o.Synchronous = strings.ToUpper(strings.TrimSpace(o.Synchronous))
+	if !validSynchronousModes[o.Synchronous] {
+		o.Synchronous = defaults.Synchronous
+	}
+	if o.BusyTimeoutMs < 0 {
+		o.BusyTimeoutMs = defaults.BusyTimeoutMs
+	}
+	if o.MaxOpenConns < 1 {
+		o.MaxOpenConns = defaults.MaxOpenConns
+	}
+	if o.MaxIdleConns < 0 || o.MaxIdleConns > o.MaxOpenConns {
+		o.MaxIdleConns = o.MaxOpenConns
+	}
+
+	// Every connection to an in-memory database gets its own empty database
+	if strings.Contains(dbPath, ":memory:") || strings.Contains(dbPath, "mode=memory") {
+		o.MaxOpenConns = 1
+		o.MaxIdleConns = 1
+	}
+
+	return o
+}
+
+// driverName is the SQLite driver that applies the settings without a DSN
+// parameter to every new connection
+const driverName = "sqlite3_subvault"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{ConnectHook: connectHook})
+}
+
+// connectHook applies the per-connection settings that have no DSN parameter
+func connectHook(conn *sqlite3.SQLiteConn) error {
+	_, err := conn.Exec("PRAGMA temp_store = MEMORY", nil)
+	return err
+}
+
+// buildDSN appends connection parameters to the database path so that they are
+// applied to every connection in the pool, not just the first one
+func buildDSN(dbPath string, opts Options) string {
+	params := url.Values{}
+	params.Set("_busy_timeout", fmt.Sprintf("%d", opts.BusyTimeoutMs))
+	// An encrypted database cannot be read before the key is set, see registerKeyedDriver
+	if opts.Key == "" {
+		params.Set("_journal_mode", opts.JournalMode)
+	}
+	params.Set("_synchronous", opts.Synchronous)
+	params.Set("_foreign_keys", "1")
+	params.Set("_cache_size", "-20000")
+	// Take the write lock when a transaction starts instead of on the first write,
+	// so concurrent transactions wait on busy_timeout rather than fail on upgrade
+	params.Set("_txlock", "immediate")
+
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	return dbPath + separator + params.Encode()
+}
+
+func Initialize(dbPath string, opts Options) (*gorm.DB, error) {
+	opts = opts.normalize(dbPath)
+
+	dialector := sqlite.Dialector{DriverName: driverName, DSN: buildDSN(dbPath, opts)}
+	if opts.Key != "" {
+		file, _, _ := strings.Cut(strings.TrimPrefix(dbPath, "file:"), "?")
+		plaintext, err := isPlaintextDatabase(file)
+		if err != nil {
+			return nil, err
+		}
+		if plaintext {
+			if err := encryptExisting(file, opts.Key); err != nil {
+				return nil, err
+			}
+		}
+		dialector.DriverName = registerKeyedDriver(opts.Key, opts.JournalMode)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
+		if opts.Key != "" {
+			return nil, fmt.Errorf("%w: %v", ErrWrongKey, err)
+		}
 		return nil, err
 	}
 
@@ -18,26 +142,26 @@ func Initialize(dbPath string) (*gorm.DB, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	// SQLite performance tuning
-	pragmas := []string{
-		"PRAGMA journal_mode = WAL",
-		"PRAGMA synchronous = NORMAL",
-		"PRAGMA busy_timeout = 5000",
-		"PRAGMA cache_size = -20000",
-		"PRAGMA temp_store = MEMORY",
-		"PRAGMA foreign_keys = ON",
-	}
-	for _, pragma := range pragmas {
-		if _, err := sqlDB.Exec(pragma); err != nil {
+	if opts.Key != "" {
+		if err := checkEncryption(sqlDB); err != nil {
+			sqlDB.Close()
 			return nil, err
 		}
 	}
 
-	// Connection pool for SQLite (single writer)
-	sqlDB.SetMaxOpenConns(1)
-	sqlDB.SetMaxIdleConns(1)
+	// Connection pool: WAL allows concurrent readers alongside a single writer;
+	// writers queue on busy_timeout
+	sqlDB.SetMaxOpenConns(opts.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(opts.MaxIdleConns)
 	sqlDB.SetConnMaxLifetime(0)
 
+	slog.Info("database initialized",
+		"journal_mode", opts.JournalMode,
+		"synchronous", opts.Synchronous,
+		"busy_timeout_ms", opts.BusyTimeoutMs,
+		"max_open_conns", opts.MaxOpenConns,
+		"max_idle_conns", opts.MaxIdleConns,
+		"encrypted", opts.Key != "")
+
 	return db, nil
 }
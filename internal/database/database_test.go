@@ -0,0 +1,201 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsNormalize(t *testing.T) {
+	opts := Options{
+		BusyTimeoutMs: -1,
+		JournalMode:   "bogus",
+		Synchronous:   "full",
+		MaxOpenConns:  0,
+		MaxIdleConns:  10,
+	}.normalize("./data/subvault.db")
+
+	assert.Equal(t, 5000, opts.BusyTimeoutMs)
+	assert.Equal(t, "WAL", opts.JournalMode)
+	assert.Equal(t, "FULL", opts.Synchronous)
+	assert.Equal(t, 4, opts.MaxOpenConns)
+	assert.Equal(t, 4, opts.MaxIdleConns)
+}
+
+func TestOptionsNormalize_InMemoryUsesSingleConnection(t *testing.T) {
+	opts := DefaultOptions().normalize(":memory:")
+	assert.Equal(t, 1, opts.MaxOpenConns)
+	assert.Equal(t, 1, opts.MaxIdleConns)
+}
+
+func TestBuildDSN(t *testing.T) {
+	dsn := buildDSN("./data/subvault.db", DefaultOptions())
+	assert.Contains(t, dsn, "./data/subvault.db?")
+	assert.Contains(t, dsn, "_busy_timeout=5000")
+	assert.Contains(t, dsn, "_journal_mode=WAL")
+	assert.Contains(t, dsn, "_txlock=immediate")
+
+	dsn = buildDSN("file:test.db?cache=shared", DefaultOptions())
+	assert.Contains(t, dsn, "file:test.db?cache=shared&")
+}
+
+func TestInitialize(t *testing.T) {
+	db, err := Initialize(t.TempDir()+"/test.db", DefaultOptions())
+	assert.NoError(t, err)
+
+	var mode string
+	db.Raw("PRAGMA journal_mode").Scan(&mode)
+	assert.Equal(t, "wal", mode)
+
+	var timeout int
+	db.Raw("PRAGMA busy_timeout").Scan(&timeout)
+	assert.Equal(t, 5000, timeout)
+}
+
+func TestInitialize_TempStoreOnEveryConnection(t *testing.T) {
+	db, err := Initialize(t.TempDir()+"/test.db", DefaultOptions())
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+
+	// Hold several connections at once so the pool has to open new ones
+	var conns []*sql.Conn
+	for range DefaultOptions().MaxOpenConns {
+		conn, err := sqlDB.Conn(t.Context())
+		require.NoError(t, err)
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		var store int
+		require.NoError(t, conn.QueryRowContext(t.Context(), "PRAGMA temp_store").Scan(&store))
+		assert.Equal(t, 2, store, "temp_store = MEMORY")
+		require.NoError(t, conn.Close())
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	db, err := Initialize(":memory:", DefaultOptions())
+	assert.NoError(t, err)
+
+	pending, err := PendingMigrations(db)
+	assert.NoError(t, err)
+	assert.Contains(t, pending, "table subscriptions")
+
+	assert.NoError(t, RunMigrations(db))
+	pending, err = PendingMigrations(db)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestMigrateHashedAPIKeys(t *testing.T) {
+	db, err := Initialize(":memory:", DefaultOptions())
+	require.NoError(t, err)
+	require.NoError(t, RunMigrations(db))
+
+	// A key stored in plain text before hashing, and one created since
+	plain := "sk_0123456789abcdef"
+	require.NoError(t, db.Exec("INSERT INTO api_keys (name, key, prefix) VALUES (?, ?, '')", "Old", plain).Error)
+	require.NoError(t, db.Create(&models.APIKey{Name: "New", KeyHash: models.HashAPIKey("sk_fedcba"), Prefix: "sk_fedcba"}).Error)
+
+	require.NoError(t, migrateHashedAPIKeys(db))
+	require.NoError(t, migrateHashedAPIKeys(db))
+
+	var keys []models.APIKey
+	require.NoError(t, db.Order("id").Find(&keys).Error)
+	require.Len(t, keys, 2)
+	assert.Equal(t, models.HashAPIKey(plain), keys[0].KeyHash)
+	assert.Equal(t, "sk_01234567", keys[0].Prefix)
+	assert.Equal(t, models.HashAPIKey("sk_fedcba"), keys[1].KeyHash)
+}
+
+func TestErase(t *testing.T) {
+	db, err := Initialize(t.TempDir()+"/test.db", DefaultOptions())
+	require.NoError(t, err)
+	require.NoError(t, RunMigrations(db))
+
+	require.NoError(t, db.Create(&models.Subscription{Name: "Netflix", Cost: 15, Schedule: "Monthly", Status: "Active", CategoryID: 1}).Error)
+	require.NoError(t, db.Create(&models.Settings{Key: "auth_password_hash", Value: "secret"}).Error)
+	require.NoError(t, db.Create(&models.APIKey{Name: "Home", KeyHash: models.HashAPIKey("sk_secret"), Prefix: "sk_secret"}).Error)
+	require.NoError(t, db.Create(&models.Category{Name: "Custom"}).Error)
+
+	require.NoError(t, Erase(db))
+
+	var count int64
+	db.Model(&models.Subscription{}).Count(&count)
+	assert.Zero(t, count)
+	db.Model(&models.Settings{}).Count(&count)
+	assert.Zero(t, count)
+	db.Model(&models.APIKey{}).Count(&count)
+	assert.Zero(t, count)
+
+	// Only the default category of a fresh installation is left
+	var categories []models.Category
+	require.NoError(t, db.Find(&categories).Error)
+	require.Len(t, categories, 1)
+	assert.Equal(t, uint(1), categories[0].ID)
+	assert.True(t, categories[0].IsDefault)
+
+	pending, err := PendingMigrations(db)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestKeyLiteral(t *testing.T) {
+	assert.Equal(t, "'secret'", keyLiteral("secret"))
+	assert.Equal(t, "'it''s secret'", keyLiteral("it's secret"))
+
+	raw := "2DD29CA851E7B56E4697B0E1F08507293D761A05CE4D1B628663F411A8086D99"
+	assert.Equal(t, `"x'`+raw+`'"`, keyLiteral(raw))
+	// Not 64 hex digits, so a passphrase
+	assert.Equal(t, "'"+raw[:63]+"g'", keyLiteral(raw[:63]+"g"))
+}
+
+func TestIsPlaintextDatabase(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/test.db"
+
+	plaintext, err := isPlaintextDatabase(path)
+	require.NoError(t, err)
+	assert.False(t, plaintext, "missing file")
+
+	_, err = Initialize(path, DefaultOptions())
+	require.NoError(t, err)
+	plaintext, err = isPlaintextDatabase(path)
+	require.NoError(t, err)
+	assert.True(t, plaintext)
+
+	require.NoError(t, os.WriteFile(dir+"/encrypted.db", []byte("\x8f\x1c random bytes of an encrypted page"), 0o600))
+	plaintext, err = isPlaintextDatabase(dir + "/encrypted.db")
+	require.NoError(t, err)
+	assert.False(t, plaintext)
+}
+
+func TestInitialize_KeyWithoutSQLCipher(t *testing.T) {
+	db, err := Initialize(":memory:", DefaultOptions())
+	require.NoError(t, err)
+	var version string
+	if db.Raw("PRAGMA cipher_version").Scan(&version); version != "" {
+		t.Skip("linked against SQLCipher")
+	}
+
+	// A key must never silently leave the database unencrypted
+	opts := DefaultOptions()
+	opts.Key = "secret"
+	_, err = Initialize(t.TempDir()+"/test.db", opts)
+	assert.ErrorIs(t, err, ErrEncryptionUnsupported)
+
+	// An existing plaintext database is left untouched
+	path := t.TempDir() + "/existing.db"
+	_, err = Initialize(path, DefaultOptions())
+	require.NoError(t, err)
+	_, err = Initialize(path, opts)
+	assert.ErrorIs(t, err, ErrEncryptionUnsupported)
+	plaintext, err := isPlaintextDatabase(path)
+	require.NoError(t, err)
+	assert.True(t, plaintext)
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrEncryptionUnsupported is returned when a key is configured but the
+	// binary is linked against plain SQLite instead of SQLCipher
+	ErrEncryptionUnsupported = errors.New("database encryption requires a build linked against SQLCipher")
+	// ErrWrongKey is returned when the database cannot be read with the configured key
+	ErrWrongKey = errors.New("database key is wrong or the file is not a database")
+)
+
+// plaintextHeader starts every unencrypted SQLite file
+var plaintextHeader = []byte("SQLite format 3\x00")
+
+var keyedDrivers atomic.Int64
+
+// registerKeyedDriver registers a SQLite driver that unlocks every new
+// connection with key. SQLCipher requires the key before anything reads the
+// file, so the journal mode is set here rather than through the DSN.
+func registerKeyedDriver(key, journalMode string) string {
+	name := fmt.Sprintf("sqlite3_keyed_%d", keyedDrivers.Add(1))
+	sql.Register(name, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if _, err := conn.Exec("PRAGMA key = "+keyLiteral(key), nil); err != nil {
+				return err
+			}
+			if _, err := conn.Exec("PRAGMA journal_mode = "+journalMode, nil); err != nil {
+				return err
+			}
+			return connectHook(conn)
+		},
+	})
+	return name
+}
+
+// keyLiteral quotes a key for PRAGMA key and ATTACH ... KEY. 64 hex digits are
+// used as the raw 256-bit key; anything else is a passphrase.
+func keyLiteral(key string) string {
+	if len(key) == 64 && strings.Trim(strings.ToLower(key), "0123456789abcdef") == "" {
+		return `"x'` + key + `'"`
+	}
+	return "'" + strings.ReplaceAll(key, "'", "''") + "'"
+}
+
+// checkEncryption verifies that SQLCipher is linked in and the key opens the database
+func checkEncryption(db *sql.DB) error {
+	var version string
+	if err := db.QueryRow("PRAGMA cipher_version").Scan(&version); err != nil || version == "" {
+		return ErrEncryptionUnsupported
+	}
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master").Scan(&tables); err != nil {
+		return fmt.Errorf("%w: %v", ErrWrongKey, err)
+	}
+	return nil
+}
+
+// isPlaintextDatabase reports whether path is an existing unencrypted SQLite file
+func isPlaintextDatabase(path string) (bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(plaintextHeader))
+	if _, err := io.ReadFull(f, header); err != nil {
+		// Empty or truncated files have nothing to migrate
+		return false, nil
+	}
+	return bytes.Equal(header, plaintextHeader), nil
+}
+
+// encryptExisting replaces the unencrypted database at path with an encrypted
+// copy, so enabling encryption on an existing installation keeps its data
+func encryptExisting(path, key string) error {
+	plain, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer plain.Close()
+	plain.SetMaxOpenConns(1)
+
+	var version string
+	if err := plain.QueryRow("PRAGMA cipher_version").Scan(&version); err != nil || version == "" {
+		return ErrEncryptionUnsupported
+	}
+
+	encryptedPath := path + ".encrypting"
+	os.Remove(encryptedPath)
+	quotedPath := "'" + strings.ReplaceAll(encryptedPath, "'", "''") + "'"
+	steps := []string{
+		"ATTACH DATABASE " + quotedPath + " AS encrypted KEY " + keyLiteral(key),
+		"SELECT sqlcipher_export('encrypted')",
+		"DETACH DATABASE encrypted",
+	}
+	for _, step := range steps {
+		if _, err := plain.Exec(step); err != nil {
+			os.Remove(encryptedPath)
+			return fmt.Errorf("failed to encrypt database: %w", err)
+		}
+	}
+	plain.Close()
+
+	if err := os.Rename(encryptedPath, path); err != nil {
+		os.Remove(encryptedPath)
+		return fmt.Errorf("failed to replace database: %w", err)
+	}
+	// The journal of the plaintext file would still hold unencrypted pages
+	os.Remove(path + "-wal")
+	os.Remove(path + "-shm")
+	slog.Warn("encrypted the existing database; older copies and backups of it remain unencrypted", "path", path)
+	return nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Erase deletes the rows of every table, including settings, secrets and API
+// keys, and recreates the defaults a fresh installation starts with. The file
+// is vacuumed and the WAL truncated afterwards, so deleted data does not linger
+// in free pages or the journal.
+func Erase(db *gorm.DB) error {
+	var tables []string
+	if err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&tables).Error; err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Check foreign keys at commit, when every table is empty, so the tables
+		// can be cleared in any order
+		if err := tx.Exec("PRAGMA defer_foreign_keys = ON").Error; err != nil {
+			return err
+		}
+		for _, table := range tables {
+			if err := tx.Exec(fmt.Sprintf("DELETE FROM %q", table)).Error; err != nil {
+				return fmt.Errorf("failed to clear table %s: %w", table, err)
+			}
+		}
+		// Restart the IDs, so they do not reveal how many records existed
+		var sequences int64
+		tx.Raw("SELECT COUNT(*) FROM sqlite_master WHERE name = 'sqlite_sequence'").Scan(&sequences)
+		if sequences > 0 {
+			return tx.Exec("DELETE FROM sqlite_sequence").Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := RunMigrations(db); err != nil {
+		return fmt.Errorf("failed to recreate defaults: %w", err)
+	}
+	if err := db.Exec("VACUUM").Error; err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	return db.Exec("PRAGMA wal_checkpoint(TRUNCATE)").Error
+}
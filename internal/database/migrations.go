@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"log/slog"
 	"strconv"
 	"subvault/internal/models"
@@ -8,10 +9,52 @@ import (
 	"gorm.io/gorm"
 )
 
+// baseModels are auto-migrated before the hand-written migrations run
+func baseModels() []interface{} {
+	return []interface{}{&models.Category{}, &models.Settings{}, &models.APIKey{}, &models.ExchangeRate{}, &models.ImportBatch{}, &models.UsageEvent{}, &models.SubscriptionShare{}, &models.ReminderRetry{}, &models.Payment{}, &models.StatsSnapshot{}, &models.CategoryRule{}, &models.Vendor{}, &models.InboundEmail{}, &models.JobRun{}, &models.LoginEvent{}, &models.ChangeProposal{}, &models.FireflyRecurrence{}}
+}
+
+// schemaModels lists every model whose table is managed by RunMigrations
+func schemaModels() []interface{} {
+	return append(baseModels(), &models.Subscription{})
+}
+
+// PendingMigrations compares the database schema against the models and returns
+// a description of every missing table or column. An empty result means the
+// schema is up to date.
+func PendingMigrations(db *gorm.DB) ([]string, error) {
+	var pending []string
+	migrator := db.Migrator()
+
+	for _, model := range schemaModels() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("failed to parse model schema: %w", err)
+		}
+		table := stmt.Schema.Table
+
+		if !migrator.HasTable(model) {
+			pending = append(pending, fmt.Sprintf("table %s", table))
+			continue
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !migrator.HasColumn(model, field.DBName) {
+				pending = append(pending, fmt.Sprintf("column %s.%s", table, field.DBName))
+			}
+		}
+	}
+
+	return pending, nil
+}
+
 // RunMigrations executes all database migrations
 func RunMigrations(db *gorm.DB) error {
 	// Auto-migrate non-problematic models first
-	err := db.AutoMigrate(&models.Category{}, &models.Settings{}, &models.APIKey{}, &models.ExchangeRate{})
+	err := db.AutoMigrate(baseModels()...)
 	if err != nil {
 		return err
 	}
@@ -28,6 +71,14 @@ func RunMigrations(db *gorm.DB) error {
 		migrateTaxFields,
 		migrateContractFields,
 		migratePerSubscriptionNotifications,
+		migrateImportBatchTracking,
+		migrateVendorGrouping,
+		migrateContractTerms,
+		migratePaidThroughDate,
+		migratePromoPricing,
+		migrateSeats,
+		migrateCancelInstructions,
+		migrateHashedAPIKeys,
 	}
 
 	for _, migration := range migrations {
@@ -347,3 +398,146 @@ func migratePerSubscriptionNotifications(db *gorm.DB) error {
 
 	return nil
 }
+
+// migrateImportBatchTracking adds import_batch_id to subscriptions so imports can be undone
+func migrateImportBatchTracking(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Subscription{}) {
+		return nil
+	}
+	if !db.Migrator().HasColumn(&models.Subscription{}, "import_batch_id") {
+		if err := db.Migrator().AddColumn(&models.Subscription{}, "ImportBatchID"); err != nil {
+			return err
+		}
+		db.Exec("CREATE INDEX IF NOT EXISTS idx_subscriptions_import_batch_id ON subscriptions(import_batch_id)")
+	}
+	return nil
+}
+
+// migrateVendorGrouping adds the optional vendor of a subscription
+func migrateVendorGrouping(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Subscription{}) {
+		return nil
+	}
+	if !db.Migrator().HasColumn(&models.Subscription{}, "vendor_id") {
+		if err := db.Migrator().AddColumn(&models.Subscription{}, "VendorID"); err != nil {
+			return err
+		}
+		db.Exec("CREATE INDEX IF NOT EXISTS idx_subscriptions_vendor_id ON subscriptions(vendor_id)")
+	}
+	return nil
+}
+
+// migrateContractTerms adds the contract term columns to subscriptions
+func migrateContractTerms(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Subscription{}) {
+		return nil
+	}
+	columns := map[string]string{
+		"contract_start_date":         "ContractStartDate",
+		"contract_end_date":           "ContractEndDate",
+		"minimum_term_months":         "MinimumTermMonths",
+		"notice_period_days":          "NoticePeriodDays",
+		"auto_renew":                  "AutoRenew",
+		"last_contract_reminder_date": "LastContractReminderDate",
+	}
+	for col, field := range columns {
+		if !db.Migrator().HasColumn(&models.Subscription{}, col) {
+			if err := db.Migrator().AddColumn(&models.Subscription{}, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migratePaidThroughDate adds the paid-through date of cancelled subscriptions
+// and its reminder tracking column
+func migratePaidThroughDate(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Subscription{}) {
+		return nil
+	}
+	columns := map[string]string{
+		"paid_through_date":               "PaidThroughDate",
+		"last_paid_through_reminder_date": "LastPaidThroughReminderDate",
+	}
+	for col, field := range columns {
+		if !db.Migrator().HasColumn(&models.Subscription{}, col) {
+			if err := db.Migrator().AddColumn(&models.Subscription{}, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migratePromoPricing adds the promotional price of subscriptions and its
+// reminder tracking columns
+func migratePromoPricing(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Subscription{}) {
+		return nil
+	}
+	columns := map[string]string{
+		"promo_cost":               "PromoCost",
+		"promo_end_date":           "PromoEndDate",
+		"promo_end_reminder":       "PromoEndReminder",
+		"last_promo_reminder_date": "LastPromoReminderDate",
+	}
+	for col, field := range columns {
+		if !db.Migrator().HasColumn(&models.Subscription{}, col) {
+			if err := db.Migrator().AddColumn(&models.Subscription{}, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateSeats adds the seat count of family and team plans
+func migrateSeats(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Subscription{}) || db.Migrator().HasColumn(&models.Subscription{}, "seats") {
+		return nil
+	}
+	return db.Migrator().AddColumn(&models.Subscription{}, "Seats")
+}
+
+// migrateCancelInstructions adds the cancellation page and steps of
+// subscriptions
+func migrateCancelInstructions(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Subscription{}) {
+		return nil
+	}
+	columns := map[string]string{
+		"cancel_url":   "CancelURL",
+		"cancel_notes": "CancelNotes",
+	}
+	for col, field := range columns {
+		if !db.Migrator().HasColumn(&models.Subscription{}, col) {
+			if err := db.Migrator().AddColumn(&models.Subscription{}, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateHashedAPIKeys replaces API keys stored in plain text by their hash.
+// Keys without a prefix predate hashing; their key column holds the key itself.
+func migrateHashedAPIKeys(db *gorm.DB) error {
+	var keys []models.APIKey
+	if err := db.Where("prefix = '' OR prefix IS NULL").Find(&keys).Error; err != nil {
+		return err
+	}
+	for _, key := range keys {
+		plain := key.KeyHash
+		if err := db.Model(&models.APIKey{}).Where("id = ?", key.ID).Updates(map[string]interface{}{
+			"key":    models.HashAPIKey(plain),
+			"prefix": models.APIKeyPrefix(plain),
+		}).Error; err != nil {
+			return err
+		}
+	}
+	if len(keys) > 0 {
+		slog.Info("hashed stored API keys", "count", len(keys))
+	}
+	return nil
+}
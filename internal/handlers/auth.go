@@ -1,8 +1,9 @@
 package handlers
 
 import (
-	"crypto/subtle"
+	"errors"
 	"fmt"
+	"html"
 	"log/slog"
 	"net/http"
 	"net/url"
@@ -12,19 +13,24 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// loginHistoryLimit is how many login attempts the login history shows
+const loginHistoryLimit = 25
+
 type AuthHandler struct {
 	authService    service.AuthServiceInterface
 	sessionService *service.SessionService
 	emailService   service.EmailServiceInterface
 	notifConfig    service.NotificationConfigServiceInterface
+	loginAudit     service.LoginAuditServiceInterface
 }
 
-func NewAuthHandler(authService service.AuthServiceInterface, sessionService *service.SessionService, emailService service.EmailServiceInterface, notifConfig service.NotificationConfigServiceInterface) *AuthHandler {
+func NewAuthHandler(authService service.AuthServiceInterface, sessionService *service.SessionService, emailService service.EmailServiceInterface, notifConfig service.NotificationConfigServiceInterface, loginAudit service.LoginAuditServiceInterface) *AuthHandler {
 	return &AuthHandler{
 		authService:    authService,
 		sessionService: sessionService,
 		emailService:   emailService,
 		notifConfig:    notifConfig,
+		loginAudit:     loginAudit,
 	}
 }
 
@@ -70,39 +76,75 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		redirect = "/"
 	}
 
-	storedUsername, err := h.authService.GetAuthUsername()
-	if err != nil {
-		c.HTML(http.StatusInternalServerError, "login-error.html", gin.H{
-			"Error": tr(c, "auth_error_system", "Authentication system error"),
+	role, err := h.authService.Authenticate(username, password)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		h.recordLogin(c, username, "", false)
+		c.HTML(http.StatusUnauthorized, "login-error.html", gin.H{
+			"Error": tr(c, "auth_error_invalid_credentials", "Invalid username or password"),
 		})
 		return
 	}
-
-	validUsername := subtle.ConstantTimeCompare([]byte(storedUsername), []byte(username)) == 1
-
-	var validPassword bool
-	if err := h.authService.ValidatePassword(password); err == nil {
-		validPassword = true
-	}
-
-	if !validUsername || !validPassword {
-		c.HTML(http.StatusUnauthorized, "login-error.html", gin.H{
-			"Error": tr(c, "auth_error_invalid_credentials", "Invalid username or password"),
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "login-error.html", gin.H{
+			"Error": tr(c, "auth_error_system", "Authentication system error"),
 		})
 		return
 	}
 
-	if err := h.sessionService.CreateSession(c.Writer, c.Request, rememberMe); err != nil {
+	if err := h.sessionService.CreateSession(c.Writer, c.Request, rememberMe, role); err != nil {
 		c.HTML(http.StatusInternalServerError, "login-error.html", gin.H{
 			"Error": tr(c, "auth_error_session", "Failed to create session"),
 		})
 		return
 	}
+	h.recordLogin(c, username, role, true)
 
 	c.Header("HX-Redirect", redirect)
 	c.Status(http.StatusOK)
 }
 
+// recordLogin adds a login attempt to the login history and alerts of
+// successful logins from new devices in the background. Failing to record
+// does not fail the login.
+func (h *AuthHandler) recordLogin(c *gin.Context, username, role string, success bool) {
+	event, err := h.loginAudit.Record(username, role, c.ClientIP(), c.Request.UserAgent(), success)
+	if err != nil {
+		slog.Error("failed to record login", "error", err)
+		return
+	}
+	if event.NewDevice {
+		slog.Info("login from new device", "username", event.Username, "ip", event.IP)
+		go func() {
+			if err := h.loginAudit.SendAlert(event); err != nil {
+				slog.Error("failed to send login alert", "error", err)
+			}
+		}()
+	}
+}
+
+// LoginHistory returns the recent login attempts, as the login history card
+// for htmx requests and as JSON otherwise
+func (h *AuthHandler) LoginHistory(c *gin.Context) {
+	events, err := h.loginAudit.Recent(loginHistoryLimit)
+	if err != nil {
+		slog.Error("failed to load login history", "error", err)
+		if c.GetHeader("HX-Request") != "" {
+			c.String(http.StatusInternalServerError, tr(c, "error_something_wrong", "Something went wrong"))
+			return
+		}
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	if c.GetHeader("HX-Request") != "" {
+		c.HTML(http.StatusOK, "login-history.html", mergeTemplateData(baseTemplateData(c), gin.H{
+			"Events": events,
+		}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events, "alerts_enabled": h.loginAudit.AlertsEnabled()})
+}
+
 // Logout handles logout
 func (h *AuthHandler) Logout(c *gin.Context) {
 	if err := h.sessionService.DestroySession(c.Writer, c.Request); err != nil {
@@ -150,9 +192,9 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 		<p><a href="%s">Reset Password</a></p>
 		<p>This link will expire in 1 hour.</p>
 		<p>If you did not request this reset, please ignore this email.</p>
-	`, resetURL)
+	`, html.EscapeString(resetURL))
 
-	err = h.emailService.SendEmail(subject, body)
+	err = h.emailService.SendEmail(c.Request.Context(), subject, body)
 	if err != nil {
 		slog.Error("failed to send reset email", "error", err)
 		c.HTML(http.StatusInternalServerError, "forgot-password-error.html", mergeTemplateData(baseTemplateData(c), gin.H{
@@ -180,7 +222,7 @@ func (h *AuthHandler) ShowResetPasswordPage(c *gin.Context) {
 	}
 
 	data := baseTemplateData(c)
-	mergeTemplateData(data, gin.H{"Token": token})
+	mergeTemplateData(data, gin.H{"Token": token, "PasswordPolicy": h.authService.GetPasswordPolicy()})
 	c.HTML(http.StatusOK, "reset-password.html", data)
 }
 
@@ -190,13 +232,6 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	newPassword := c.PostForm("new_password")
 	confirmPassword := c.PostForm("confirm_password")
 
-	if len(newPassword) < 8 {
-		c.HTML(http.StatusBadRequest, "reset-password-error.html", mergeTemplateData(baseTemplateData(c), gin.H{
-			"Error": tr(c, "auth_error_password_short", "Password must be at least 8 characters long"),
-		}))
-		return
-	}
-
 	if newPassword != confirmPassword {
 		c.HTML(http.StatusBadRequest, "reset-password-error.html", mergeTemplateData(baseTemplateData(c), gin.H{
 			"Error": tr(c, "auth_error_password_mismatch", ErrPasswordsDoNotMatch),
@@ -212,6 +247,10 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	}
 
 	if err := h.authService.SetAuthPassword(newPassword); err != nil {
+		if msg, ok := passwordPolicyMessage(c, err); ok {
+			c.HTML(http.StatusBadRequest, "reset-password-error.html", mergeTemplateData(baseTemplateData(c), gin.H{"Error": msg}))
+			return
+		}
 		c.HTML(http.StatusInternalServerError, "reset-password-error.html", mergeTemplateData(baseTemplateData(c), gin.H{
 			"Error": tr(c, "auth_error_update_password", "Failed to update password"),
 		}))
@@ -224,3 +263,41 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		"Message": tr(c, "auth_success_password_reset", "Password reset successfully. You can now login with your new password."),
 	}))
 }
+
+// passwordStrengthLabels are the English names of the strength scores
+var passwordStrengthLabels = []string{"Very weak", "Weak", "Fair", "Good", "Strong"}
+
+// PasswordStrength renders the strength meter and the unmet password policy
+// rules for the password being typed
+func (h *AuthHandler) PasswordStrength(c *gin.Context) {
+	password := c.PostForm("password")
+	if password == "" {
+		password = c.PostForm("new_password")
+	}
+	if password == "" {
+		c.String(http.StatusOK, "")
+		return
+	}
+	// The endpoint is public for the reset page; only signed in users have
+	// their password compared with the admin username, which would otherwise
+	// leak through the score
+	username := c.PostForm("username")
+	if username == "" && h.sessionService.IsAuthenticated(c.Request) {
+		username, _ = h.authService.GetAuthUsername()
+	}
+
+	policy := h.authService.GetPasswordPolicy()
+	check := h.authService.EvaluatePassword(password, username)
+
+	unmet := make([]string, 0, len(check.Failed))
+	for _, rule := range check.Failed {
+		msg, _ := passwordPolicyMessage(c, &service.PasswordPolicyError{Rule: rule, Policy: policy})
+		unmet = append(unmet, msg)
+	}
+	c.HTML(http.StatusOK, "password-strength.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Strength":      check.Strength,
+		"StrengthLabel": tr(c, fmt.Sprintf("password_strength_%d", check.Strength), passwordStrengthLabels[check.Strength]),
+		"Segments":      []int{1, 2, 3, 4},
+		"Unmet":         unmet,
+	}))
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BankStatus is the API view of the bank connection, without credentials
+type BankStatus struct {
+	Configured    bool                      `json:"configured"`
+	Linked        bool                      `json:"linked"`
+	InstitutionID string                    `json:"institution_id,omitempty"`
+	Accounts      int                       `json:"accounts"`
+	LastSync      *time.Time                `json:"last_sync,omitempty"`
+	LastError     string                    `json:"last_error,omitempty"`
+	Candidates    []service.RecurringCharge `json:"candidates"`
+}
+
+// BankConnection renders the bank connection card. Accounts are picked up once
+// the bank consent is given; with ?country= the banks of that country are listed.
+func (h *PaymentHandler) BankConnection(c *gin.Context) {
+	data := gin.H{}
+	if _, err := h.bank.RefreshAccounts(c.Request.Context()); err != nil {
+		data["Error"] = bankErrorMessage(err)
+	}
+	if country := strings.TrimSpace(c.Query("country")); country != "" {
+		institutions, err := h.bank.Institutions(c.Request.Context(), country)
+		if err != nil {
+			data["Error"] = bankErrorMessage(err)
+		}
+		data["Country"] = strings.ToUpper(country)
+		data["Institutions"] = institutions
+	}
+	h.renderBank(c, http.StatusOK, data)
+}
+
+// SaveBankCredentials checks and stores the GoCardless secret ID and key
+func (h *PaymentHandler) SaveBankCredentials(c *gin.Context) {
+	if err := h.bank.SaveCredentials(c.Request.Context(), c.PostForm("secret_id"), c.PostForm("secret_key")); err != nil {
+		h.renderBank(c, http.StatusBadRequest, gin.H{"Error": bankErrorMessage(err)})
+		return
+	}
+	h.renderBank(c, http.StatusOK, gin.H{})
+}
+
+// ConnectBank starts the bank consent and redirects to the bank
+func (h *PaymentHandler) ConnectBank(c *gin.Context) {
+	institutionID := c.PostForm("institution_id")
+	if institutionID == "" {
+		h.renderBank(c, http.StatusBadRequest, gin.H{"Error": "Select a bank"})
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	link, err := h.bank.Connect(c.Request.Context(), institutionID, scheme+"://"+c.Request.Host+"/renewals")
+	if err != nil {
+		h.renderBank(c, http.StatusBadGateway, gin.H{"Error": bankErrorMessage(err)})
+		return
+	}
+	c.Header("HX-Redirect", link)
+	c.Status(http.StatusOK)
+}
+
+// SyncBank reads the latest bank transactions right away
+func (h *PaymentHandler) SyncBank(c *gin.Context) {
+	result, err := h.bank.Sync(c.Request.Context())
+	if err != nil {
+		h.renderBank(c, http.StatusOK, gin.H{"Error": bankErrorMessage(err)})
+		return
+	}
+	h.renderBank(c, http.StatusOK, gin.H{"SyncResult": result})
+}
+
+// DisconnectBank revokes the bank consent and removes the credentials
+func (h *PaymentHandler) DisconnectBank(c *gin.Context) {
+	if err := h.bank.Disconnect(c.Request.Context()); err != nil {
+		slog.Error("failed to disconnect bank", "error", err)
+		h.renderBank(c, http.StatusInternalServerError, gin.H{"Error": "An internal error occurred"})
+		return
+	}
+	h.renderBank(c, http.StatusOK, gin.H{})
+}
+
+// GetBankStatusAPI returns the bank connection and the proposed subscriptions of the last sync
+func (h *PaymentHandler) GetBankStatusAPI(c *gin.Context) {
+	connection, err := h.bank.RefreshAccounts(c.Request.Context())
+	if err != nil {
+		slog.Warn("failed to refresh bank accounts", "error", err)
+	}
+	c.JSON(http.StatusOK, BankStatus{
+		Configured:    connection.Configured(),
+		Linked:        connection.Linked(),
+		InstitutionID: connection.InstitutionID,
+		Accounts:      len(connection.Accounts),
+		LastSync:      connection.LastSync,
+		LastError:     connection.LastError,
+		Candidates:    h.bank.Candidates(),
+	})
+}
+
+// SyncBankAPI reads the latest bank transactions and returns what was recorded
+func (h *PaymentHandler) SyncBankAPI(c *gin.Context) {
+	result, err := h.bank.Sync(c.Request.Context())
+	switch {
+	case errors.Is(err, service.ErrBankNotConfigured), errors.Is(err, service.ErrBankNotLinked):
+		apiError(c, http.StatusConflict, bankErrorMessage(err))
+		return
+	case errors.Is(err, service.ErrBankAPI):
+		apiError(c, http.StatusBadGateway, bankErrorMessage(err))
+		return
+	case err != nil:
+		slog.Error("failed to sync bank transactions", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *PaymentHandler) renderBank(c *gin.Context, status int, data gin.H) {
+	connection := h.bank.Connection()
+	configured := connection.Configured()
+	// Never send the secret key back
+	connection.SecretKey = ""
+	c.HTML(status, "bank-connection.html", mergeTemplateData(baseTemplateData(c), mergeTemplateData(gin.H{
+		"Configured": configured,
+		"Connection": connection,
+		"Candidates": h.bank.Candidates(),
+	}, data)))
+}
+
+// bankErrorMessage returns the client message for a bank connection error
+func bankErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, service.ErrBankNotConfigured):
+		return "Enter the secret ID and key of your GoCardless Bank Account Data account"
+	case errors.Is(err, service.ErrBankNotLinked):
+		return "Connect a bank first"
+	case errors.Is(err, service.ErrBankAPI):
+		slog.Warn("bank data provider rejected request", "error", err)
+		return "The bank data provider rejected the request, check the credentials and the bank consent"
+	default:
+		slog.Error("bank connection failed", "error", err)
+		return "The bank data provider could not be reached"
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BudgetStatus is the API view of the budgeting tool connection, without the token
+type BudgetStatus struct {
+	Configured      bool       `json:"configured"`
+	Provider        string     `json:"provider,omitempty"`
+	URL             string     `json:"url,omitempty"`
+	BudgetID        string     `json:"budget_id,omitempty"`
+	SyncAmounts     bool       `json:"sync_amounts"`
+	LastSync        *time.Time `json:"last_sync,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	ExportAccountID string     `json:"export_account_id,omitempty"`
+	LastExport      *time.Time `json:"last_export,omitempty"`
+}
+
+// FireflyExportRequest is the DTO for exporting to Firefly III. Without an
+// account ID the account of the last export is used.
+type FireflyExportRequest struct {
+	AccountID string `json:"account_id"`
+}
+
+// BudgetConnection renders the YNAB / Firefly III connection card
+func (h *ImportHandler) BudgetConnection(c *gin.Context) {
+	h.renderBudget(c, http.StatusOK, gin.H{})
+}
+
+// SaveBudgetConnection checks and stores the budgeting tool and its API token.
+// An empty token keeps the stored one.
+func (h *ImportHandler) SaveBudgetConnection(c *gin.Context) {
+	connection := models.BudgetConnection{
+		Provider:    c.PostForm("provider"),
+		Token:       c.PostForm("token"),
+		URL:         c.PostForm("url"),
+		BudgetID:    c.PostForm("budget_id"),
+		SyncAmounts: c.PostForm("sync_amounts") == "true",
+	}
+	if err := h.budget.Save(c.Request.Context(), connection); err != nil {
+		h.renderBudget(c, http.StatusBadRequest, gin.H{"Error": budgetErrorMessage(err)})
+		return
+	}
+	h.renderBudget(c, http.StatusOK, gin.H{})
+}
+
+// PreviewBudget reads the recurring expenses and renders the import preview of
+// those without a subscription, confirmed like a file import
+func (h *ImportHandler) PreviewBudget(c *gin.Context) {
+	preview, err := h.budget.Preview(c.Request.Context())
+	if err != nil {
+		h.renderBudget(c, http.StatusOK, gin.H{"Error": budgetErrorMessage(err)})
+		return
+	}
+	h.renderBudget(c, http.StatusOK, gin.H{"Preview": preview.ImportPreview, "Tracked": preview.Tracked, "Unsupported": preview.Unsupported})
+}
+
+// SyncBudget updates the cost of matching subscriptions right away
+func (h *ImportHandler) SyncBudget(c *gin.Context) {
+	result, err := h.budget.SyncAmounts(c.Request.Context())
+	if err != nil {
+		h.renderBudget(c, http.StatusOK, gin.H{"Error": budgetErrorMessage(err)})
+		return
+	}
+	h.renderBudget(c, http.StatusOK, gin.H{"SyncResult": result})
+}
+
+// DisconnectBudget removes the budgeting tool connection and its token
+func (h *ImportHandler) DisconnectBudget(c *gin.Context) {
+	if err := h.budget.Disconnect(); err != nil {
+		slog.Error("failed to disconnect budgeting tool", "error", err)
+		h.renderBudget(c, http.StatusInternalServerError, gin.H{"Error": "An internal error occurred"})
+		return
+	}
+	h.renderBudget(c, http.StatusOK, gin.H{})
+}
+
+// FireflyExportForm renders the connection card with the Firefly III asset
+// accounts to pay exported subscriptions from
+func (h *ImportHandler) FireflyExportForm(c *gin.Context) {
+	accounts, err := h.firefly.Accounts(c.Request.Context())
+	if err != nil {
+		h.renderBudget(c, http.StatusOK, gin.H{"Error": budgetErrorMessage(err)})
+		return
+	}
+	h.renderBudget(c, http.StatusOK, gin.H{"Accounts": accounts})
+}
+
+// ExportToFirefly pushes the subscriptions to Firefly III as recurring transactions
+func (h *ImportHandler) ExportToFirefly(c *gin.Context) {
+	result, err := h.firefly.Export(c.Request.Context(), c.PostForm("account_id"))
+	if err != nil {
+		h.renderBudget(c, http.StatusOK, gin.H{"Error": budgetErrorMessage(err)})
+		return
+	}
+	h.renderBudget(c, http.StatusOK, gin.H{"ExportResult": result})
+}
+
+// GetBudgetStatusAPI returns the budgeting tool connection
+func (h *ImportHandler) GetBudgetStatusAPI(c *gin.Context) {
+	connection := h.budget.Connection()
+	c.JSON(http.StatusOK, BudgetStatus{
+		Configured:      connection.Configured(),
+		Provider:        connection.Provider,
+		URL:             connection.URL,
+		BudgetID:        connection.BudgetID,
+		SyncAmounts:     connection.SyncAmounts,
+		LastSync:        connection.LastSync,
+		LastError:       connection.LastError,
+		ExportAccountID: connection.ExportAccountID,
+		LastExport:      connection.LastExport,
+	})
+}
+
+// PreviewBudgetAPI stages the recurring expenses without a subscription. The
+// token is confirmed with POST /api/v1/import/confirm.
+func (h *ImportHandler) PreviewBudgetAPI(c *gin.Context) {
+	preview, err := h.budget.Preview(c.Request.Context())
+	if err != nil {
+		budgetAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// GetFireflyAccountsAPI lists the Firefly III asset accounts exported subscriptions can be paid from
+func (h *ImportHandler) GetFireflyAccountsAPI(c *gin.Context) {
+	accounts, err := h.firefly.Accounts(c.Request.Context())
+	if err != nil {
+		budgetAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, accounts)
+}
+
+// ExportToFireflyAPI pushes the subscriptions to Firefly III and returns what changed
+func (h *ImportHandler) ExportToFireflyAPI(c *gin.Context) {
+	var req FireflyExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+	result, err := h.firefly.Export(c.Request.Context(), req.AccountID)
+	if err != nil {
+		budgetAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// SyncBudgetAPI updates the cost of matching subscriptions and returns what changed
+func (h *ImportHandler) SyncBudgetAPI(c *gin.Context) {
+	result, err := h.budget.SyncAmounts(c.Request.Context())
+	if err != nil {
+		budgetAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *ImportHandler) renderBudget(c *gin.Context, status int, data gin.H) {
+	connection := h.budget.Connection()
+	configured := connection.Configured()
+	// Never send the token back
+	connection.Token = ""
+	c.HTML(status, "budget-connection.html", mergeTemplateData(baseTemplateData(c), mergeTemplateData(gin.H{
+		"Configured": configured,
+		"Connection": connection,
+	}, data)))
+}
+
+// budgetAPIError maps budgeting tool errors to API responses
+func budgetAPIError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrBudgetNotConfigured), errors.Is(err, service.ErrFireflyNotConnected):
+		apiError(c, http.StatusConflict, budgetErrorMessage(err))
+	case errors.Is(err, service.ErrInvalidBudgetConnection), errors.Is(err, service.ErrFireflyAccountRequired):
+		apiBadRequest(c, budgetErrorMessage(err))
+	case errors.Is(err, service.ErrOfflineMode):
+		apiError(c, http.StatusServiceUnavailable, budgetErrorMessage(err))
+	case errors.Is(err, service.ErrBudgetAPI):
+		apiError(c, http.StatusBadGateway, budgetErrorMessage(err))
+	default:
+		slog.Error("failed to read budgeting tool", "error", err)
+		apiInternalError(c, ErrInternalServer)
+	}
+}
+
+// budgetErrorMessage returns the client message for a budgeting tool error
+func budgetErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, service.ErrBudgetNotConfigured):
+		return "Connect YNAB or Firefly III with an API token first"
+	case errors.Is(err, service.ErrFireflyNotConnected):
+		return "Connect Firefly III with an API token first"
+	case errors.Is(err, service.ErrFireflyAccountRequired):
+		return "Choose the Firefly III account subscriptions are paid from"
+	case errors.Is(err, service.ErrInvalidBudgetConnection):
+		return "Choose YNAB or Firefly III and enter the http:// or https:// address of Firefly III"
+	case errors.Is(err, service.ErrOfflineMode):
+		return "Offline mode is on, the budgeting tool cannot be reached"
+	case errors.Is(err, service.ErrBudgetAPI):
+		slog.Warn("budgeting tool rejected request", "error", err)
+		return "The budgeting tool rejected the request, check the API token and the budget"
+	default:
+		slog.Error("budgeting tool connection failed", "error", err)
+		return "The budgeting tool could not be reached"
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bundleFileNameChars matches characters that are replaced in bundle file names
+var bundleFileNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// BundleHandler exports subscriptions as .svbundle archives for sharing with
+// other SubVault instances. Bundles are imported through the regular import.
+type BundleHandler struct {
+	bundles *service.BundleService
+}
+
+func NewBundleHandler(bundles *service.BundleService) *BundleHandler {
+	return &BundleHandler{bundles: bundles}
+}
+
+// ExportBundle downloads a bundle. Optional query parameters: category_id,
+// ids (comma-separated subscription IDs) and name.
+func (h *BundleHandler) ExportBundle(c *gin.Context) {
+	filter := service.BundleFilter{Name: strings.TrimSpace(c.Query("name"))}
+	if raw := c.Query("category_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			apiBadRequest(c, "Invalid category_id")
+			return
+		}
+		filter.CategoryID = uint(id)
+	}
+	if raw := c.Query("ids"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+			if err != nil {
+				apiBadRequest(c, "Invalid ids")
+				return
+			}
+			filter.SubscriptionIDs = append(filter.SubscriptionIDs, uint(id))
+		}
+	}
+
+	var buf bytes.Buffer
+	count, err := h.bundles.Export(c.Request.Context(), &buf, filter)
+	if err != nil {
+		slog.Error("failed to export bundle", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	if count == 0 {
+		apiNotFound(c, "No subscriptions match the selection")
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+bundleFileName(filter.Name)+`"`)
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// bundleFileName derives a safe download file name from the bundle name
+func bundleFileName(name string) string {
+	slug := strings.Trim(bundleFileNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		slug = "subscriptions"
+	}
+	return slug + ".svbundle"
+}
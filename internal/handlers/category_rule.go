@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CategoryRuleHandler manages the category mapping rules applied by imports
+type CategoryRuleHandler struct {
+	rules service.CategoryRuleServiceInterface
+}
+
+func NewCategoryRuleHandler(rules service.CategoryRuleServiceInterface) *CategoryRuleHandler {
+	return &CategoryRuleHandler{rules: rules}
+}
+
+// categoryRuleRequest maps a category name of an import source to a category
+type categoryRuleRequest struct {
+	Source     string `json:"source"`
+	Match      string `json:"match" binding:"required"`
+	CategoryID uint   `json:"category_id" binding:"required"`
+}
+
+// ListRules returns all category rules with their target category
+func (h *CategoryRuleHandler) ListRules(c *gin.Context) {
+	rules, err := h.rules.List()
+	if err != nil {
+		slog.Error("failed to list category rules", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rules})
+}
+
+// SetRule creates a rule, or retargets the rule with the same source and name
+func (h *CategoryRuleHandler) SetRule(c *gin.Context) {
+	var req categoryRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+	rule, err := h.rules.Set(req.Source, req.Match, req.CategoryID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCategoryRule) {
+			apiBadRequest(c, err.Error())
+			return
+		}
+		slog.Error("failed to save category rule", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule removes a category rule
+func (h *CategoryRuleHandler) DeleteRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+	if err := h.rules.Delete(uint(id)); err != nil {
+		if errors.Is(err, service.ErrCategoryRuleNotFound) {
+			apiNotFound(c, "Category rule not found")
+			return
+		}
+		slog.Error("failed to delete category rule", "error", err, "id", id)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
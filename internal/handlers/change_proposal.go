@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"subvault/internal/i18n"
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxDecidedProposals limits the decided proposals shown on the proposals page
+const maxDecidedProposals = 20
+
+// maxProposalNote limits the length of a proposal's note, like the API
+const maxProposalNote = 1000
+
+// errProposalTargetGone is returned when approving a change to a subscription
+// that was deleted in the meantime
+var errProposalTargetGone = errors.New("subscription no longer exists")
+
+// ChangeProposalHandler lets viewers propose subscription changes and the
+// admin approve or reject them. Approved changes go through the same code as
+// the subscription API.
+type ChangeProposalHandler struct {
+	proposals     service.ChangeProposalServiceInterface
+	subscriptions *SubscriptionHandler
+	auth          service.AuthServiceInterface
+}
+
+func NewChangeProposalHandler(proposals service.ChangeProposalServiceInterface, subscriptions *SubscriptionHandler, auth service.AuthServiceInterface) *ChangeProposalHandler {
+	return &ChangeProposalHandler{proposals: proposals, subscriptions: subscriptions, auth: auth}
+}
+
+// ProposeChangeRequest is the body of a change proposal. Changes takes the
+// fields of the create or update subscription API and is left out for delete.
+type ProposeChangeRequest struct {
+	Action         string          `json:"action" binding:"required,oneof=create update delete"`
+	SubscriptionID uint            `json:"subscription_id"`
+	Changes        json.RawMessage `json:"changes"`
+	Note           string          `json:"note" binding:"omitempty,max=1000"`
+}
+
+// Proposals renders the queue of proposed changes. Viewers propose changes
+// there; the admin approves or rejects them.
+func (h *ChangeProposalHandler) Proposals(c *gin.Context) {
+	ctx := c.Request.Context()
+	pending, err := h.proposals.List(ctx, 0, models.ProposalPending)
+	if err != nil {
+		slog.Error("failed to list change proposals", "error", err)
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": "An internal error occurred"})
+		return
+	}
+	decided, err := h.proposals.List(ctx, maxDecidedProposals, models.ProposalApproved, models.ProposalRejected)
+	if err != nil {
+		slog.Error("failed to list change proposals", "error", err)
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": "An internal error occurred"})
+		return
+	}
+	subscriptions, err := h.subscriptions.service.GetAllSorted(ctx, "name", "asc")
+	if err != nil {
+		slog.Error("failed to list subscriptions", "error", err)
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": "An internal error occurred"})
+		return
+	}
+
+	c.HTML(http.StatusOK, "proposals.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Title":           "Proposed changes",
+		"CurrentPage":     "proposals",
+		"Pending":         pending,
+		"Decided":         decided,
+		"Subscriptions":   subscriptions,
+		"CurrencyOptions": i18n.Currencies(),
+	}))
+}
+
+// GetProposals returns the change proposals with their changes. Query
+// parameter: status (pending, approved or rejected; default all).
+func (h *ChangeProposalHandler) GetProposals(c *gin.Context) {
+	var statuses []string
+	switch status := c.Query("status"); status {
+	case "":
+	case models.ProposalPending, models.ProposalApproved, models.ProposalRejected:
+		statuses = append(statuses, status)
+	default:
+		apiBadRequest(c, "Invalid status, use pending, approved or rejected")
+		return
+	}
+	proposals, err := h.proposals.List(c.Request.Context(), 0, statuses...)
+	if err != nil {
+		slog.Error("failed to list change proposals", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, proposals)
+}
+
+// GetProposal returns a change proposal with its changes
+func (h *ChangeProposalHandler) GetProposal(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+	proposal, err := h.proposals.Get(c.Request.Context(), uint(id))
+	if errors.Is(err, service.ErrProposalNotFound) {
+		apiNotFound(c, "Change proposal not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to get change proposal", "error", err, "id", id)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, proposal)
+}
+
+// ProposeChange queues a subscription change for the admin. It takes a JSON
+// ProposeChangeRequest, or the form of the proposals page.
+func (h *ChangeProposalHandler) ProposeChange(c *gin.Context) {
+	var req ProposeChangeRequest
+	if c.ContentType() == binding.MIMEJSON {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apiBadRequest(c, ErrInvalidRequestBody)
+			return
+		}
+	} else {
+		var ok bool
+		if req, ok = proposalFromForm(c); !ok {
+			h.proposeError(c, http.StatusBadRequest, tr(c, "proposal_error_invalid", "The proposed values are invalid"))
+			return
+		}
+	}
+
+	if err := h.checkChanges(c.Request.Context(), &req); err != nil {
+		h.proposeError(c, http.StatusBadRequest, tr(c, "proposal_error_invalid", "The proposed values are invalid"))
+		return
+	}
+
+	proposal, err := h.proposals.Propose(c.Request.Context(), req.Action, req.SubscriptionID, req.Changes, req.Note, h.proposer(c))
+	switch {
+	case errors.Is(err, service.ErrInvalidProposal):
+		h.proposeError(c, http.StatusBadRequest, tr(c, "proposal_error_invalid", "The proposed values are invalid"))
+		return
+	case errors.Is(err, service.ErrTooManyProposals):
+		h.proposeError(c, http.StatusConflict, tr(c, "proposal_error_too_many", "Too many changes are waiting for a decision"))
+		return
+	case err != nil:
+		slog.Error("failed to save change proposal", "error", err)
+		h.proposeError(c, http.StatusInternalServerError, ErrInternalServer)
+		return
+	}
+
+	slog.Info("change proposed", "id", proposal.ID, "action", proposal.Action, "by", proposal.ProposedBy)
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Refresh", "true")
+		c.Status(http.StatusCreated)
+		return
+	}
+	c.JSON(http.StatusCreated, proposal)
+}
+
+func (h *ChangeProposalHandler) proposeError(c *gin.Context, status int, message string) {
+	if c.GetHeader("HX-Request") != "" {
+		c.HTML(status, "form-errors.html", mergeTemplateData(baseTemplateData(c), gin.H{"Error": message}))
+		return
+	}
+	apiError(c, status, message)
+}
+
+// proposalFromForm reads the proposal form. Empty fields are left unchanged;
+// without a subscription the form proposes a new one.
+func proposalFromForm(c *gin.Context) (ProposeChangeRequest, bool) {
+	req := ProposeChangeRequest{Action: models.ProposalCreate, Note: c.PostForm("note")}
+	if len(req.Note) > maxProposalNote {
+		return req, false
+	}
+	if id := c.PostForm("subscription_id"); id != "" {
+		subscriptionID, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return req, false
+		}
+		req.SubscriptionID = uint(subscriptionID)
+		req.Action = models.ProposalUpdate
+		if c.PostForm("action") == models.ProposalDelete {
+			req.Action = models.ProposalDelete
+			return req, true
+		}
+	}
+
+	changes := map[string]interface{}{}
+	for _, field := range []string{"name", "original_currency", "schedule", "status", "notes"} {
+		if value := strings.TrimSpace(c.PostForm(field)); value != "" {
+			changes[field] = value
+		}
+	}
+	if value := strings.TrimSpace(c.PostForm("cost")); value != "" {
+		cost, err := parseNumber(c, value)
+		if err != nil || math.Abs(cost) > models.MaxCost {
+			return req, false
+		}
+		changes["cost"] = cost
+	}
+	if value := c.PostForm("renewal_date"); value != "" {
+		date := parseDatePtr(value)
+		if date == nil {
+			return req, false
+		}
+		changes["renewal_date"] = date
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return req, false
+	}
+	req.Changes = data
+	return req, true
+}
+
+// checkChanges checks the proposed fields like the subscription API would
+// when the change is applied
+func (h *ChangeProposalHandler) checkChanges(ctx context.Context, req *ProposeChangeRequest) error {
+	switch req.Action {
+	case models.ProposalCreate:
+		var create CreateSubscriptionRequest
+		if err := decodeChanges(req.Changes, &create); err != nil {
+			return err
+		}
+		if _, err := h.subscriptions.subscriptionFromRequest(&create); err != nil {
+			return err
+		}
+		if _, ok := h.subscriptions.knownVendor(create.VendorID); !ok {
+			return errUnknownVendor
+		}
+	case models.ProposalUpdate:
+		var update UpdateSubscriptionRequest
+		if err := decodeChanges(req.Changes, &update); err != nil {
+			return err
+		}
+		original, err := h.subscriptions.service.GetByID(ctx, req.SubscriptionID)
+		if err != nil {
+			return errProposalTargetGone
+		}
+		if _, err := h.subscriptions.mergeUpdate(original, &update); err != nil {
+			return err
+		}
+		if update.Status != nil && !models.CanChangeStatus(original.Status, *update.Status) {
+			return service.ErrInvalidStatusChange
+		}
+	}
+	return nil
+}
+
+// decodeChanges decodes proposed fields into a subscription request, which
+// must know all of them, and validates it
+func decodeChanges(changes json.RawMessage, req interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(changes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(req)
+}
+
+// proposer returns who is logged in, for the proposal's author
+func (h *ChangeProposalHandler) proposer(c *gin.Context) string {
+	if isReadOnly(c) {
+		if username, ok := h.auth.GetViewerUsername(); ok {
+			return username
+		}
+		return service.RoleViewer
+	}
+	if username, err := h.auth.GetAuthUsername(); err == nil {
+		return username
+	}
+	return "admin"
+}
+
+// ApproveProposal applies a pending change
+func (h *ChangeProposalHandler) ApproveProposal(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+
+	ctx := c.Request.Context()
+	proposal, err := h.proposals.Approve(ctx, uint(id), func(proposal *models.ChangeProposal) error {
+		return h.apply(ctx, proposal)
+	})
+	if errors.Is(err, errUnknownVendor) || errors.Is(err, errBadNotifyChannels) || errors.Is(err, errProposalTargetGone) || errors.Is(err, service.ErrInvalidStatusChange) {
+		slog.Warn("proposed change no longer applies", "proposal", id, "error", err)
+		apiError(c, http.StatusConflict, tr(c, "proposal_error_stale", "The change no longer applies to the subscription, reject it instead"))
+		return
+	}
+	h.respondProposal(c, proposal, err, id)
+}
+
+// RejectProposal dismisses a pending change
+func (h *ChangeProposalHandler) RejectProposal(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+
+	proposal, err := h.proposals.Reject(c.Request.Context(), uint(id))
+	h.respondProposal(c, proposal, err, id)
+}
+
+// apply makes a proposed change through the subscription API's code, so that
+// logos, alerts and hooks follow as usual
+func (h *ChangeProposalHandler) apply(ctx context.Context, proposal *models.ChangeProposal) error {
+	if proposal.Action == models.ProposalCreate {
+		var req CreateSubscriptionRequest
+		if err := decodeChanges(json.RawMessage(proposal.Changes), &req); err != nil {
+			return err
+		}
+		_, err := h.subscriptions.createSubscription(ctx, &req)
+		return err
+	}
+
+	if proposal.SubscriptionID == nil {
+		return errProposalTargetGone
+	}
+	original, err := h.subscriptions.service.GetByID(ctx, *proposal.SubscriptionID)
+	if err != nil {
+		return errProposalTargetGone
+	}
+	if proposal.Action == models.ProposalDelete {
+		return h.subscriptions.deleteSubscription(ctx, original)
+	}
+	var req UpdateSubscriptionRequest
+	if err := decodeChanges(json.RawMessage(proposal.Changes), &req); err != nil {
+		return err
+	}
+	_, err = h.subscriptions.updateSubscription(ctx, original, &req)
+	return err
+}
+
+func (h *ChangeProposalHandler) respondProposal(c *gin.Context, proposal *models.ChangeProposal, err error, id uint64) {
+	switch {
+	case errors.Is(err, service.ErrProposalNotFound):
+		apiNotFound(c, "Change proposal not found")
+		return
+	case errors.Is(err, service.ErrProposalDecided):
+		apiError(c, http.StatusConflict, "Change proposal was already decided")
+		return
+	case err != nil:
+		slog.Error("failed to decide on change proposal", "error", err, "id", id)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	slog.Info("change proposal decided", "id", proposal.ID, "status", proposal.Status)
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Refresh", "true")
+		c.Status(http.StatusOK)
+		return
+	}
+	c.JSON(http.StatusOK, proposal)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestProposalRouter routes the proposal API, with requests made by a
+// viewer using German number formats
+func newTestProposalRouter(h *ChangeProposalHandler) *gin.Engine {
+	router := gin.New()
+	viewer := func(c *gin.Context) {
+		c.Set("auth_role", service.RoleViewer)
+		c.Set("lang", "de")
+	}
+	router.POST("/proposals", viewer, h.ProposeChange)
+	router.POST("/proposals/:id/approve", h.ApproveProposal)
+	router.POST("/proposals/:id/reject", h.RejectProposal)
+	return router
+}
+
+func postForm(router *gin.Engine, path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestProposeChange_Form(t *testing.T) {
+	h, _ := newTestChangeProposalHandler(t)
+	router := newTestProposalRouter(h)
+	form := url.Values{"name": {"Netflix"}, "cost": {"1.234,50"}, "schedule": {"Monthly"}, "status": {"Active"}, "note": {"Family plan"}}
+
+	rec := postForm(router, "/proposals", form)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var proposal models.ChangeProposal
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &proposal))
+	assert.Equal(t, models.ProposalCreate, proposal.Action)
+	assert.Equal(t, models.ProposalPending, proposal.Status)
+	assert.Equal(t, service.RoleViewer, proposal.ProposedBy)
+	stored, err := h.proposals.Get(t.Context(), proposal.ID)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Netflix","cost":1234.5,"schedule":"Monthly","status":"Active"}`, stored.Changes, "the cost is read in the viewer's format")
+
+	for _, cost := range []string{"zwölf", "1000000,01", "-2000000"} {
+		t.Run(cost, func(t *testing.T) {
+			form.Set("cost", cost)
+			rec := postForm(router, "/proposals", form)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), "The proposed values are invalid")
+		})
+	}
+}
+
+func TestApproveAndRejectProposal(t *testing.T) {
+	h, subscriptions := newTestChangeProposalHandler(t)
+	router := newTestProposalRouter(h)
+	propose := func(name string) string {
+		rec := postForm(router, "/proposals", url.Values{"name": {name}, "cost": {"5"}, "schedule": {"Monthly"}, "status": {"Active"}})
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var proposal models.ChangeProposal
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &proposal))
+		return strconv.FormatUint(uint64(proposal.ID), 10)
+	}
+	approved, rejected := propose("Spotify"), propose("Tidal")
+
+	rec := postForm(router, "/proposals/"+approved+"/approve", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"status":"approved"`)
+	rec = postForm(router, "/proposals/"+rejected+"/reject", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"status":"rejected"`)
+
+	// Only the approved proposal is applied
+	subs, err := subscriptions.GetAll(t.Context())
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "Spotify", subs[0].Name)
+	assert.Equal(t, 5.0, subs[0].Cost)
+
+	for _, tc := range []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/proposals/" + approved + "/approve", http.StatusConflict, "already decided"},
+		{"/proposals/" + rejected + "/approve", http.StatusConflict, "already decided"},
+		{"/proposals/99/reject", http.StatusNotFound, "Change proposal not found"},
+		{"/proposals/abc/approve", http.StatusBadRequest, ErrInvalidID},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			rec := postForm(router, tc.path, nil)
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.want)
+		})
+	}
+}
+
+func TestApproveProposal_Stale(t *testing.T) {
+	h, subscriptions := newTestChangeProposalHandler(t)
+	router := newTestProposalRouter(h)
+	sub, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 12.99, Schedule: "Monthly", Status: "Active"})
+	require.NoError(t, err)
+
+	rec := postForm(router, "/proposals", url.Values{"subscription_id": {strconv.FormatUint(uint64(sub.ID), 10)}, "cost": {"15,49"}})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var proposal models.ChangeProposal
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &proposal))
+	require.NoError(t, subscriptions.Delete(t.Context(), sub.ID))
+
+	// The subscription is gone, so the change is answered with a fixed message
+	// and stays pending to be rejected
+	rec = postForm(router, "/proposals/"+strconv.FormatUint(uint64(proposal.ID), 10)+"/approve", nil)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.JSONEq(t, `{"error":"The change no longer applies to the subscription, reject it instead"}`, rec.Body.String())
+	stored, err := h.proposals.Get(t.Context(), proposal.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProposalPending, stored.Status)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigHandler serves the configuration export and import ("configuration as code")
+type ConfigHandler struct {
+	config service.ConfigServiceInterface
+}
+
+func NewConfigHandler(config service.ConfigServiceInterface) *ConfigHandler {
+	return &ConfigHandler{config: config}
+}
+
+// ExportConfig downloads all non-secret settings and categories as YAML (default) or JSON
+func (h *ConfigHandler) ExportConfig(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", "yaml"))
+	if format != "yaml" && format != "json" {
+		apiBadRequest(c, "Unsupported format (use yaml or json)")
+		return
+	}
+
+	cfg, err := h.config.Export()
+	if err != nil {
+		slog.Error("failed to export configuration", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	data, err := h.config.Marshal(cfg, format)
+	if err != nil {
+		slog.Error("failed to encode configuration", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	contentType := "application/yaml"
+	if format == "json" {
+		contentType = "application/json"
+	}
+	if c.Query("download") != "false" {
+		c.Header("Content-Disposition", "attachment; filename=subvault-config."+format)
+	}
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ImportConfig applies a YAML or JSON configuration sent as multipart "file" field or raw body
+func (h *ConfigHandler) ImportConfig(c *gin.Context) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, _, ferr := c.Request.FormFile("file")
+		if ferr != nil {
+			apiBadRequest(c, ErrNoFileUploaded)
+			return
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, 1<<20))
+	}
+	if err != nil || len(data) == 0 {
+		apiBadRequest(c, ErrFailedReadFile)
+		return
+	}
+
+	cfg, err := h.config.Parse(data)
+	if err != nil {
+		apiBadRequest(c, err.Error())
+		return
+	}
+	result, err := h.config.Import(cfg)
+	switch {
+	case errors.Is(err, service.ErrInvalidConfig):
+		apiBadRequest(c, err.Error())
+		return
+	case err != nil:
+		slog.Error("failed to import configuration", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	slog.Info("configuration imported", "settings", result.SettingsApplied, "categories_created", len(result.CategoriesCreated))
+
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Refresh", "true")
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -14,15 +16,19 @@ const (
 
 // Common error messages used across handlers
 const (
-	ErrInvalidID            = "Invalid ID"
-	ErrSubscriptionNotFound = "Subscription not found"
-	ErrCategoryNotFound     = "Category not found"
-	ErrPasswordRequired     = "Password required"
-	ErrNoFileUploaded       = "No file uploaded"
-	ErrFailedReadFile       = "Failed to read file"
-	ErrPasswordsDoNotMatch  = "Passwords do not match"
-	ErrInvalidRequestBody   = "Invalid request body"
-	ErrInternalServer       = "Internal server error"
+	ErrInvalidID             = "Invalid ID"
+	ErrSubscriptionNotFound  = "Subscription not found"
+	ErrCategoryNotFound      = "Category not found"
+	ErrPasswordRequired      = "Password required"
+	ErrNoFileUploaded        = "No file uploaded"
+	ErrFailedReadFile        = "Failed to read file"
+	ErrPasswordsDoNotMatch   = "Passwords do not match"
+	ErrInvalidRequestBody    = "Invalid request body"
+	ErrInternalServer        = "Internal server error"
+	ErrInvalidPurpose        = "Invalid purpose: use personal, business or shared"
+	ErrInvalidNotifyChannels = "Invalid notify_channels: use a comma-separated list of email, shoutrrr and webhook"
+	ErrVendorNotFound        = "Vendor not found"
+	ErrRequestTimeout        = "Request timed out"
 )
 
 // APIErrorResponse is the standard error format for all API v1 endpoints.
@@ -45,8 +51,13 @@ func apiNotFound(c *gin.Context, message string) {
 	apiError(c, http.StatusNotFound, message)
 }
 
-// apiInternalError sends a 500 Internal Server Error.
+// apiInternalError sends a 500 Internal Server Error, or 504 Gateway Timeout
+// when the request ran out of time.
 func apiInternalError(c *gin.Context, message string) {
+	if errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		apiError(c, http.StatusGatewayTimeout, ErrRequestTimeout)
+		return
+	}
 	apiError(c, http.StatusInternalServerError, message)
 }
 
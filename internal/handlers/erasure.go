@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErasureHandler serves the endpoints that clear the subscriptions and erase everything
+type ErasureHandler struct {
+	erasure  service.ErasureServiceInterface
+	undo     service.UndoServiceInterface
+	sessions *service.SessionService
+}
+
+func NewErasureHandler(erasure service.ErasureServiceInterface, undo service.UndoServiceInterface, sessions *service.SessionService) *ErasureHandler {
+	return &ErasureHandler{erasure: erasure, undo: undo, sessions: sessions}
+}
+
+// eraseRequest confirms an erasure with the admin password and the confirmation text
+type eraseRequest struct {
+	Password string `json:"password" form:"password"`
+	Confirm  string `json:"confirm" form:"confirm"`
+}
+
+// ClearAllData removes all subscriptions together with their usage, shares,
+// payments, cached logos and attachments. Until the undo window has passed the
+// toast in the response can bring them back.
+func (h *ErasureHandler) ClearAllData(c *gin.Context) {
+	action, err := h.undo.ClearSubscriptions(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to clear subscription data", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	renderUndoToast(c, action, trCount(c, "undo_subscriptions_cleared", action.Count, fmt.Sprintf("Cleared %d subscriptions", action.Count)))
+}
+
+// EraseAll deletes all data including settings, secrets, API keys, logos,
+// attachments and backups. Unlike ClearAllData it leaves a fresh installation.
+func (h *ErasureHandler) EraseAll(c *gin.Context) {
+	var req eraseRequest
+	if err := c.ShouldBind(&req); err != nil {
+		apiBadRequest(c, "Invalid request body")
+		return
+	}
+
+	result, err := h.erasure.EraseAll(req.Password, req.Confirm)
+	switch {
+	case errors.Is(err, service.ErrEraseNotConfirmed):
+		apiBadRequest(c, tr(c, "settings_erase_not_confirmed", "Type ERASE to confirm"))
+		return
+	case errors.Is(err, service.ErrInvalidCredentials):
+		apiError(c, http.StatusForbidden, tr(c, "settings_erase_wrong_password", "Incorrect password"))
+		return
+	case err != nil && result == nil:
+		slog.Error("failed to erase data", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	case err != nil:
+		// The database is already gone; report leftovers without failing the request
+		slog.Error("erasure left files behind", "error", err)
+	}
+
+	// The session secret was replaced, so the current cookie is useless anyway
+	if err := h.sessions.DestroySession(c.Writer, c.Request); err != nil {
+		slog.Warn("failed to clear session cookie after erasure", "error", err)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "All data has been erased",
+		"files":   result.Files,
+	})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	health *service.HealthService
+	// optionalChecks lets the "include" query parameter run the optional
+	// checks, which contact the ECB and the SMTP server
+	optionalChecks bool
+}
+
+func NewHealthHandler(health *service.HealthService, optionalChecks bool) *HealthHandler {
+	return &HealthHandler{health: health, optionalChecks: optionalChecks}
+}
+
+// Readiness runs all required readiness checks plus any optional checks named in
+// the "include" query parameter (comma-separated, or "all") when optional
+// checks are enabled. Returns 200 when every executed check passed, 503 otherwise.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	include := make(map[string]bool)
+	query := c.Query("include")
+	if !h.optionalChecks {
+		query = ""
+	}
+	for _, name := range strings.Split(query, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == "all" {
+			for _, optional := range h.health.OptionalChecks() {
+				include[optional] = true
+			}
+			continue
+		}
+		include[name] = true
+	}
+
+	results, ready := h.health.Run(include)
+
+	status := "ready"
+	httpStatus := http.StatusOK
+	if !ready {
+		status = "not_ready"
+		httpStatus = http.StatusServiceUnavailable
+	}
+
+	c.JSON(httpStatus, gin.H{
+		"status": status,
+		"checks": results,
+	})
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	health := service.NewHealthService()
+	health.Register("database", false, func() error { return nil })
+	health.Register("smtp", true, func() error { return errors.New("smtp server mail.internal:25 unreachable") })
+
+	readyz := func(optionalChecks bool) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/readyz", NewHealthHandler(health, optionalChecks).Readiness)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz?include=all", nil))
+		return rec
+	}
+
+	rec := readyz(false)
+	assert.Equal(t, http.StatusOK, rec.Code, "optional checks only run when enabled")
+	assert.NotContains(t, rec.Body.String(), "smtp")
+
+	rec = readyz(true)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"fail"`)
+	assert.NotContains(t, rec.Body.String(), "mail.internal", "the failure reason is only logged")
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"subvault/internal/i18n"
+	"subvault/internal/service"
 	"subvault/internal/version"
 
 	"github.com/gin-gonic/gin"
@@ -22,14 +23,27 @@ func baseTemplateData(c *gin.Context) gin.H {
 
 	data["CurrentPath"] = c.Request.URL.Path
 	data["Version"] = version.GetVersion()
+	if status, exists := c.Get("update_status"); exists {
+		data["Update"] = status.(service.UpdateStatus)
+	} else {
+		data["Update"] = service.UpdateStatus{}
+	}
 
 	if token, exists := c.Get("csrf_token"); exists {
 		data["CSRFToken"] = token.(string)
 	}
 
+	data["ReadOnly"] = isReadOnly(c)
+	data["OfflineMode"] = service.OfflineMode()
+
 	return data
 }
 
+// isReadOnly reports whether the request comes from a read-only viewer session
+func isReadOnly(c *gin.Context) bool {
+	return c.GetString("auth_role") == service.RoleViewer
+}
+
 // mergeTemplateData merges additional data into the base template data
 func mergeTemplateData(base gin.H, extra gin.H) gin.H {
 	for k, v := range extra {
@@ -46,6 +60,12 @@ func getTranslator(c *gin.Context) *i18n.TranslationHelper {
 	return nil
 }
 
+// parseNumber reads a number typed by the user, accepting the decimal comma
+// and thousands groups of the request language, see i18n.ParseNumber
+func parseNumber(c *gin.Context, text string) (float64, error) {
+	return i18n.ParseNumber(text, c.GetString("lang"))
+}
+
 // tr translates a message ID using the context's translator, with English fallback
 func tr(c *gin.Context, messageID string, fallback string) string {
 	if t := getTranslator(c); t != nil {
@@ -55,3 +75,25 @@ func tr(c *gin.Context, messageID string, fallback string) string {
 	}
 	return fallback
 }
+
+// trData translates a message ID with template data, with an English fallback
+// that the caller formats
+func trData(c *gin.Context, messageID string, data map[string]interface{}, fallback string) string {
+	if t := getTranslator(c); t != nil {
+		if translated := t.TrData(messageID, data); translated != messageID {
+			return translated
+		}
+	}
+	return fallback
+}
+
+// trCount translates a message ID with plural forms for count, with an English
+// fallback that the caller formats
+func trCount(c *gin.Context, messageID string, count int, fallback string) string {
+	if t := getTranslator(c); t != nil {
+		if translated := t.TrCount(messageID, count); translated != messageID {
+			return translated
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"subvault/internal/i18n"
+	"subvault/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// homeAssistantScanInterval is how often, in seconds, Home Assistant polls the sensors
+const homeAssistantScanInterval = 900
+
+// HomeAssistantSensors is the flat state the Home Assistant sensors read their
+// values from. The next renewal fields are null without an upcoming renewal.
+type HomeAssistantSensors struct {
+	MonthlySpend         float64  `json:"monthly_spend"`
+	Currency             string   `json:"currency"`
+	Active               int      `json:"active"`
+	NextRenewal          *string  `json:"next_renewal"`
+	NextRenewalName      *string  `json:"next_renewal_name"`
+	NextRenewalCost      *float64 `json:"next_renewal_cost"`
+	NextRenewalCurrency  *string  `json:"next_renewal_currency"`
+	DaysUntilNextRenewal *int     `json:"days_until_next_renewal"`
+}
+
+// homeAssistantPackage is the configuration of the Home Assistant RESTful integration
+type homeAssistantPackage struct {
+	Rest []homeAssistantResource `yaml:"rest"`
+}
+
+type homeAssistantResource struct {
+	Resource     string                `yaml:"resource"`
+	Headers      map[string]*yaml.Node `yaml:"headers"`
+	ScanInterval int                   `yaml:"scan_interval"`
+	Sensor       []homeAssistantSensor `yaml:"sensor"`
+}
+
+type homeAssistantSensor struct {
+	Name              string   `yaml:"name"`
+	UniqueID          string   `yaml:"unique_id"`
+	ValueTemplate     string   `yaml:"value_template"`
+	UnitOfMeasurement string   `yaml:"unit_of_measurement,omitempty"`
+	DeviceClass       string   `yaml:"device_class"`
+	StateClass        string   `yaml:"state_class,omitempty"`
+	Icon              string   `yaml:"icon"`
+	JSONAttributes    []string `yaml:"json_attributes,omitempty"`
+}
+
+// HomeAssistantSensors returns the monthly spend and the next renewal for the
+// Home Assistant RESTful integration
+func (h *SubscriptionHandler) HomeAssistantSensors(c *gin.Context) {
+	stats, err := h.service.GetStats(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to load stats for Home Assistant sensors", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	subscriptions, err := h.service.GetAll(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to load subscriptions for Home Assistant sensors", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	currency := h.preferences.GetCurrency()
+	sensors := HomeAssistantSensors{
+		MonthlySpend: i18n.RoundAmount(stats.TotalMonthlySpend, currency),
+		Currency:     currency,
+		Active:       stats.ActiveSubscriptions,
+	}
+	now := time.Now()
+	if next := models.NextRenewal(subscriptions, now); next != nil {
+		date := next.RenewalDate.Format("2006-01-02")
+		days := daysUntilRenewal(next, now)
+		cost := i18n.RoundAmount(next.CostAt(*next.RenewalDate), next.OriginalCurrency)
+		sensors.NextRenewal = &date
+		sensors.NextRenewalName = &next.Name
+		sensors.NextRenewalCost = &cost
+		sensors.NextRenewalCurrency = &next.OriginalCurrency
+		sensors.DaysUntilNextRenewal = &days
+	}
+	c.JSON(http.StatusOK, sensors)
+}
+
+// HomeAssistantConfig returns the Home Assistant configuration for the sensors,
+// to paste into configuration.yaml or save as a package. The API key is read
+// from the subvault_api_key entry of secrets.yaml.
+func (h *SubscriptionHandler) HomeAssistantConfig(c *gin.Context) {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	config := homeAssistantPackage{Rest: []homeAssistantResource{{
+		Resource:     scheme + "://" + c.Request.Host + "/api/v1/homeassistant/sensors",
+		Headers:      map[string]*yaml.Node{"X-API-Key": {Kind: yaml.ScalarNode, Tag: "!secret", Value: "subvault_api_key"}},
+		ScanInterval: homeAssistantScanInterval,
+		Sensor: []homeAssistantSensor{
+			{
+				Name:              tr(c, "ha_sensor_monthly_spend", "SubVault monthly spend"),
+				UniqueID:          "subvault_monthly_spend",
+				ValueTemplate:     "{{ value_json.monthly_spend }}",
+				UnitOfMeasurement: h.preferences.GetCurrency(),
+				DeviceClass:       "monetary",
+				StateClass:        "total",
+				Icon:              "mdi:cash-multiple",
+				JSONAttributes:    []string{"active"},
+			},
+			{
+				Name:           tr(c, "ha_sensor_next_renewal", "SubVault next renewal"),
+				UniqueID:       "subvault_next_renewal",
+				ValueTemplate:  "{{ value_json.next_renewal }}",
+				DeviceClass:    "date",
+				Icon:           "mdi:calendar-refresh",
+				JSONAttributes: []string{"next_renewal_name", "next_renewal_cost", "next_renewal_currency"},
+			},
+			{
+				Name:              tr(c, "ha_sensor_days_until_renewal", "SubVault days until next renewal"),
+				UniqueID:          "subvault_days_until_next_renewal",
+				ValueTemplate:     "{{ value_json.days_until_next_renewal }}",
+				UnitOfMeasurement: "d",
+				DeviceClass:       "duration",
+				StateClass:        "measurement",
+				Icon:              "mdi:timer-sand",
+				JSONAttributes:    []string{"next_renewal_name"},
+			},
+		},
+	}}}
+
+	// Home Assistant configurations are indented by two spaces
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(config); err != nil {
+		slog.Error("failed to encode Home Assistant configuration", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.Data(http.StatusOK, "application/yaml", buf.Bytes())
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestHomeAssistantSensors(t *testing.T) {
+	h, subscriptions := newTestSubscriptionHandler(t)
+	require.NoError(t, h.preferences.SetCurrency("JPY"))
+	router := gin.New()
+	router.GET("/homeassistant/sensors", h.HomeAssistantSensors)
+	sensors := func() map[string]any {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/homeassistant/sensors", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return body
+	}
+
+	// Without an upcoming renewal its fields are null
+	assert.Equal(t, map[string]any{
+		"monthly_spend": 0.0, "currency": "JPY", "active": 0.0,
+		"next_renewal": nil, "next_renewal_name": nil, "next_renewal_cost": nil, "next_renewal_currency": nil, "days_until_next_renewal": nil,
+	}, sensors())
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	soon, later := today.AddDate(0, 0, 5), today.AddDate(0, 0, 40)
+	for _, sub := range []*models.Subscription{
+		{Name: "Music", Cost: 980, Schedule: "Monthly", Status: "Active", OriginalCurrency: "JPY", RenewalDate: &soon},
+		{Name: "Cloud", Cost: 10000, Schedule: "Annual", Status: "Active", OriginalCurrency: "JPY", RenewalDate: &later},
+	} {
+		_, err := subscriptions.Create(t.Context(), sub)
+		require.NoError(t, err)
+	}
+
+	// 980 + 10000/12 is rounded to whole yen
+	assert.Equal(t, map[string]any{
+		"monthly_spend": 1813.0, "currency": "JPY", "active": 2.0,
+		"next_renewal": soon.Format("2006-01-02"), "next_renewal_name": "Music", "next_renewal_cost": 980.0, "next_renewal_currency": "JPY", "days_until_next_renewal": 5.0,
+	}, sensors())
+}
+
+func TestHomeAssistantConfig(t *testing.T) {
+	h, _ := newTestSubscriptionHandler(t)
+	require.NoError(t, h.preferences.SetCurrency("EUR"))
+	router := gin.New()
+	router.GET("/homeassistant/config", h.HomeAssistantConfig)
+
+	req := httptest.NewRequest(http.MethodGet, "/homeassistant/config", nil)
+	req.Host = "subvault.example.com"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/yaml", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "rest:\n  - resource: https://subvault.example.com/api/v1/homeassistant/sensors\n")
+	assert.Contains(t, body, "X-API-Key: !secret subvault_api_key\n", "the key stays in secrets.yaml")
+
+	// The headers are checked above; a !secret tag does not decode into a node
+	var config struct {
+		Rest []struct {
+			ScanInterval int                   `yaml:"scan_interval"`
+			Sensor       []homeAssistantSensor `yaml:"sensor"`
+		} `yaml:"rest"`
+	}
+	require.NoError(t, yaml.Unmarshal(rec.Body.Bytes(), &config))
+	require.Len(t, config.Rest, 1)
+	assert.Equal(t, homeAssistantScanInterval, config.Rest[0].ScanInterval)
+	sensors := config.Rest[0].Sensor
+	require.Len(t, sensors, 3)
+	assert.Equal(t, homeAssistantSensor{
+		Name: "SubVault monthly spend", UniqueID: "subvault_monthly_spend", ValueTemplate: "{{ value_json.monthly_spend }}",
+		UnitOfMeasurement: "EUR", DeviceClass: "monetary", StateClass: "total", Icon: "mdi:cash-multiple", JSONAttributes: []string{"active"},
+	}, sensors[0])
+	assert.Equal(t, "subvault_next_renewal", sensors[1].UniqueID)
+	assert.Equal(t, "date", sensors[1].DeviceClass)
+	assert.Equal(t, []string{"next_renewal_name", "next_renewal_cost", "next_renewal_currency"}, sensors[1].JSONAttributes)
+	assert.Equal(t, "{{ value_json.days_until_next_renewal }}", sensors[2].ValueTemplate)
+	assert.Equal(t, "d", sensors[2].UnitOfMeasurement)
+}
@@ -1,112 +1,33 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
-	"strings"
-	"time"
+	"strconv"
 
-	"subvault/internal/crypto"
-	"subvault/internal/models"
 	"subvault/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ImportHandler struct {
-	subscriptionService service.SubscriptionServiceInterface
-	categoryService     service.CategoryServiceInterface
-	settingsService     service.SettingsServiceInterface
+	importService   *service.ImportService
+	budget          service.BudgetImportServiceInterface
+	firefly         service.FireflyExportServiceInterface
+	settingsService service.SettingsServiceInterface
 }
 
-func NewImportHandler(subscriptionService service.SubscriptionServiceInterface, categoryService service.CategoryServiceInterface, settingsService service.SettingsServiceInterface) *ImportHandler {
+func NewImportHandler(importService *service.ImportService, budget service.BudgetImportServiceInterface, firefly service.FireflyExportServiceInterface, settingsService service.SettingsServiceInterface) *ImportHandler {
 	return &ImportHandler{
-		subscriptionService: subscriptionService,
-		categoryService:     categoryService,
-		settingsService:     settingsService,
+		importService:   importService,
+		budget:          budget,
+		firefly:         firefly,
+		settingsService: settingsService,
 	}
 }
 
-type ImportResult struct {
-	Imported int      `json:"imported"`
-	Skipped  int      `json:"skipped"`
-	Errors   int      `json:"errors"`
-	Details  []string `json:"details"`
-}
-
-// wallosNameObj represents a nested Wallos object with a name field
-type wallosNameObj struct {
-	Name string `json:"name"`
-}
-
-// wallosSubscription represents a subscription from Wallos export
-// Supports both real Wallos format (nested objects) and flat format
-type wallosSubscription struct {
-	Name              string          `json:"name"`
-	Price             json.RawMessage `json:"price"`
-	CurrencyCode      string          `json:"currency_code"`
-	Currency          wallosNameObj   `json:"currency"`
-	Cycle             int             `json:"cycle"`
-	Frequency         int             `json:"frequency"`
-	NextPayment       string          `json:"next_payment"`
-	StartDate         string          `json:"start_date"`
-	CategoryName      string          `json:"category_name"`
-	Category          wallosNameObj   `json:"category"`
-	URL               string          `json:"url"`
-	Notes             string          `json:"notes"`
-	PaymentMethodName string          `json:"payment_method_name"`
-	PaymentMethod     wallosNameObj   `json:"payment_method"`
-}
-
-// GetPrice returns the price as a string, handling both float and string JSON values
-func (ws *wallosSubscription) GetPrice() string {
-	if ws.Price == nil {
-		return "0"
-	}
-	s := strings.TrimSpace(string(ws.Price))
-	// Remove quotes if it's a JSON string
-	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
-		return s[1 : len(s)-1]
-	}
-	return s
-}
-
-// GetCurrencyCode returns the currency code from either flat or nested format
-func (ws *wallosSubscription) GetCurrencyCode() string {
-	if ws.CurrencyCode != "" {
-		return ws.CurrencyCode
-	}
-	return ws.Currency.Name
-}
-
-// GetCategoryName returns the category name from either flat or nested format
-func (ws *wallosSubscription) GetCategoryName() string {
-	if ws.CategoryName != "" {
-		return ws.CategoryName
-	}
-	return ws.Category.Name
-}
-
-// GetPaymentMethodName returns the payment method from either flat or nested format
-func (ws *wallosSubscription) GetPaymentMethodName() string {
-	if ws.PaymentMethodName != "" {
-		return ws.PaymentMethodName
-	}
-	return ws.PaymentMethod.Name
-}
-
-type wallosExport struct {
-	Subscriptions []wallosSubscription `json:"subscriptions"`
-}
-
-// subtrackrExport represents the SubTrackr JSON export format
-type subtrackrExport struct {
-	Subscriptions []models.Subscription `json:"subscriptions"`
-}
-
 func (h *ImportHandler) ImportSubscriptions(c *gin.Context) {
 	file, _, err := c.Request.FormFile("file")
 	if err != nil {
@@ -121,266 +42,151 @@ func (h *ImportHandler) ImportSubscriptions(c *gin.Context) {
 		return
 	}
 
-	format := c.PostForm("format")
-	if format == "" {
-		format = h.detectFormat(data)
-	}
-
-	var result ImportResult
-	switch format {
-	case "wallos":
-		result = h.importWallos(data)
-	case "subvault", "subtrackr":
-		result = h.importSubTrackr(data)
-	default:
+	result, err := h.importService.Import(c.Request.Context(), data, c.PostForm("format"), importOptions(c.PostForm("payments")))
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown format"})
 		return
 	}
 
-	c.HTML(http.StatusOK, "import-result.html", gin.H{
+	c.HTML(http.StatusOK, "import-result.html", mergeTemplateData(baseTemplateData(c), gin.H{
 		"Result": result,
-	})
+	}))
 }
 
-func (h *ImportHandler) detectFormat(data []byte) string {
-	var raw map[string]json.RawMessage
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return ""
-	}
-
-	// SubTrackr exports have "exported_at" and "total_count"
-	if _, ok := raw["exported_at"]; ok {
-		return "subtrackr"
-	}
-
-	// Check if subscriptions array contains Wallos-specific fields
-	if subsData, ok := raw["subscriptions"]; ok {
-		var subs []map[string]interface{}
-		if err := json.Unmarshal(subsData, &subs); err == nil && len(subs) > 0 {
-			if _, hasCycle := subs[0]["cycle"]; hasCycle {
-				return "wallos"
-			}
-			if _, hasSchedule := subs[0]["schedule"]; hasSchedule {
-				return "subtrackr"
-			}
-		}
+// ImportEncrypted handles importing from an AES-256-GCM encrypted backup file (.stbk)
+func (h *ImportHandler) ImportEncrypted(c *gin.Context) {
+	password := c.PostForm("password")
+	if password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrPasswordRequired})
+		return
 	}
 
-	return ""
-}
-
-func (h *ImportHandler) importWallos(data []byte) ImportResult {
-	result := ImportResult{}
-
-	var export wallosExport
-	if err := json.Unmarshal(data, &export); err != nil {
-		result.Errors++
-		result.Details = append(result.Details, fmt.Sprintf("Parse error: %s", err.Error()))
-		return result
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrNoFileUploaded})
+		return
 	}
+	defer file.Close()
 
-	if len(export.Subscriptions) == 0 {
-		result.Details = append(result.Details, "No subscriptions found in file")
-		return result
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrFailedReadFile})
+		return
 	}
 
-	existing, _ := h.subscriptionService.GetAll()
-
-	for _, ws := range export.Subscriptions {
-		priceStr := ws.GetPrice()
-
-		// Duplicate check
-		if h.isDuplicate(existing, ws.Name, priceStr) {
-			result.Skipped++
-			result.Details = append(result.Details, fmt.Sprintf("Skipped (duplicate): %s", ws.Name))
-			continue
-		}
-
-		sub := models.Subscription{
-			Name:                   ws.Name,
-			OriginalCurrency:       ws.GetCurrencyCode(),
-			Status:                 "Active",
-			URL:                    ws.URL,
-			Notes:                  ws.Notes,
-			PaymentMethod:          ws.GetPaymentMethodName(),
-			DateCalculationVersion: 2,
-		}
-
-		// Parse price
-		var price float64
-		fmt.Sscanf(priceStr, "%f", &price)
-		sub.Cost = price
-
-		// Map cycle to schedule
-		schedule := "Monthly"
-		switch ws.Cycle {
-		case 1:
-			schedule = "Daily"
-		case 2:
-			schedule = "Weekly"
-		case 3:
-			schedule = "Monthly"
-		case 4:
-			schedule = "Annual"
-		}
-		// Handle frequency multiplier
-		if ws.Frequency > 1 && ws.Cycle == 3 && ws.Frequency == 3 {
-			schedule = "Quarterly"
-		}
-		sub.Schedule = schedule
-
-		// Parse next_payment as renewal date
-		if ws.NextPayment != "" {
-			if t, err := time.Parse("2006-01-02", ws.NextPayment); err == nil {
-				sub.RenewalDate = &t
-			}
-		}
-
-		// Parse start_date if available
-		if ws.StartDate != "" {
-			if t, err := time.Parse("2006-01-02", ws.StartDate); err == nil {
-				sub.StartDate = &t
-			}
-		}
-
-		// Map category
-		catName := ws.GetCategoryName()
-		if catName != "" {
-			cat := h.getOrCreateCategory(catName)
-			if cat != nil {
-				sub.CategoryID = cat.ID
-			}
-		}
-
-		if _, err := h.subscriptionService.Create(&sub); err != nil {
-			result.Errors++
-			result.Details = append(result.Details, fmt.Sprintf("Error importing %s: %s", ws.Name, err.Error()))
-		} else {
-			result.Imported++
-		}
+	result, err := h.importService.ImportEncrypted(c.Request.Context(), data, password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Decryption failed: wrong password or corrupted file"})
+		return
 	}
 
-	return result
+	c.HTML(http.StatusOK, "import-result.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Result": result,
+	}))
 }
 
-func (h *ImportHandler) importSubTrackr(data []byte) ImportResult {
-	result := ImportResult{}
-
-	var export subtrackrExport
-	if err := json.Unmarshal(data, &export); err != nil {
-		result.Errors++
-		result.Details = append(result.Details, fmt.Sprintf("Parse error: %s", err.Error()))
-		return result
+// PreviewImport parses an uploaded file without writing anything and renders
+// what would be created or skipped. Encrypted backups are handled when a password is sent.
+func (h *ImportHandler) PreviewImport(c *gin.Context) {
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrNoFileUploaded})
+		return
 	}
+	defer file.Close()
 
-	if len(export.Subscriptions) == 0 {
-		result.Details = append(result.Details, "No subscriptions found in file")
-		return result
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrFailedReadFile})
+		return
 	}
 
-	existing, _ := h.subscriptionService.GetAll()
-
-	for _, sub := range export.Subscriptions {
-		// Duplicate check
-		priceStr := fmt.Sprintf("%.2f", sub.Cost)
-		if h.isDuplicate(existing, sub.Name, priceStr) {
-			result.Skipped++
-			result.Details = append(result.Details, fmt.Sprintf("Skipped (duplicate): %s", sub.Name))
-			continue
-		}
-
-		// Reset ID and timestamps for re-import
-		newSub := sub
-		newSub.ID = 0
-		newSub.Category = models.Category{}
-		newSub.CategoryID = 0
-		newSub.CreatedAt = time.Time{}
-		newSub.UpdatedAt = time.Time{}
-		newSub.LastReminderSent = nil
-		newSub.LastReminderRenewalDate = nil
-		newSub.LastCancellationReminderSent = nil
-		newSub.LastCancellationReminderDate = nil
-
-		// Map category by name if possible
-		if sub.Category.Name != "" {
-			cat := h.getOrCreateCategory(sub.Category.Name)
-			if cat != nil {
-				newSub.CategoryID = cat.ID
-			}
-		}
-
-		if _, err := h.subscriptionService.Create(&newSub); err != nil {
-			result.Errors++
-			result.Details = append(result.Details, fmt.Sprintf("Error importing %s: %s", sub.Name, err.Error()))
-		} else {
-			result.Imported++
-		}
+	var preview *service.ImportPreview
+	if password := c.PostForm("password"); password != "" {
+		preview, err = h.importService.PreviewEncrypted(c.Request.Context(), data, password)
+	} else {
+		preview, err = h.importService.Preview(c.Request.Context(), data, c.PostForm("format"))
 	}
-
-	return result
-}
-
-func (h *ImportHandler) isDuplicate(existing []models.Subscription, name string, price string) bool {
-	for _, sub := range existing {
-		if strings.EqualFold(sub.Name, name) && fmt.Sprintf("%.2f", sub.Cost) == price {
-			return true
-		}
+	switch {
+	case errors.Is(err, service.ErrUnknownImportFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown format"})
+		return
+	case errors.Is(err, service.ErrDecryptionFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Decryption failed: wrong password or corrupted file"})
+		return
+	case err != nil:
+		slog.Error("failed to preview import", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
 	}
-	return false
+
+	c.HTML(http.StatusOK, "import-preview.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Preview": preview,
+	}))
 }
 
-func (h *ImportHandler) getOrCreateCategory(name string) *models.Category {
-	categories, err := h.categoryService.GetAll()
+// ConfirmImport writes a previously previewed import. The "payments" field adds
+// the payment history of Wallos exports to the payment ledger.
+func (h *ImportHandler) ConfirmImport(c *gin.Context) {
+	result, err := h.importService.Confirm(c.Request.Context(), c.PostForm("token"), importOptions(c.PostForm("payments")))
 	if err != nil {
-		return nil
-	}
-
-	for _, cat := range categories {
-		if strings.EqualFold(cat.Name, name) {
-			return &cat
-		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import preview expired, please upload the file again"})
+		return
 	}
 
-	newCat := &models.Category{Name: name}
-	created, err := h.categoryService.Create(newCat)
-	if err != nil {
-		slog.Error("failed to create category", "category", name, "error", err)
-		return nil
-	}
-	return created
+	c.HTML(http.StatusOK, "import-result.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Result": result,
+	}))
 }
 
-// ImportEncrypted handles importing from an AES-256-GCM encrypted backup file (.stbk)
-func (h *ImportHandler) ImportEncrypted(c *gin.Context) {
-	password := c.PostForm("password")
-	if password == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": ErrPasswordRequired})
-		return
-	}
+// DiscardImport drops a previewed import without writing anything
+func (h *ImportHandler) DiscardImport(c *gin.Context) {
+	h.importService.Discard(c.PostForm("token"))
+	c.Status(http.StatusNoContent)
+}
 
-	file, _, err := c.Request.FormFile("file")
+// ListBatches renders the import history with undo buttons
+func (h *ImportHandler) ListBatches(c *gin.Context) {
+	batches, err := h.importService.ListBatches()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": ErrNoFileUploaded})
+		slog.Error("failed to list import batches", "error", err)
+		c.HTML(http.StatusInternalServerError, "import-batches.html", mergeTemplateData(baseTemplateData(c), gin.H{
+			"Error": "An internal error occurred",
+		}))
 		return
 	}
-	defer file.Close()
 
-	data, err := io.ReadAll(file)
+	c.HTML(http.StatusOK, "import-batches.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Batches": batches,
+	}))
+}
+
+// UndoBatch removes all subscriptions created by an import batch
+func (h *ImportHandler) UndoBatch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": ErrFailedReadFile})
+		c.HTML(http.StatusBadRequest, "import-batches.html", mergeTemplateData(baseTemplateData(c), gin.H{
+			"Error": ErrInvalidID,
+		}))
 		return
 	}
 
-	decrypted, err := crypto.Decrypt(data, password)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Decryption failed: wrong password or corrupted file"})
+	deleted, err := h.importService.UndoBatch(uint(id))
+	if err != nil && !errors.Is(err, service.ErrImportBatchNotFound) {
+		slog.Error("failed to undo import batch", "error", err, "id", id)
+		c.HTML(http.StatusInternalServerError, "import-batches.html", mergeTemplateData(baseTemplateData(c), gin.H{
+			"Error": "An internal error occurred",
+		}))
 		return
 	}
+	slog.Info("import batch undone", "id", id, "deleted_subscriptions", deleted)
 
-	// Re-import using the SubTrackr format
-	result := h.importSubTrackr(decrypted)
+	// Return updated list
+	h.ListBatches(c)
+}
 
-	c.HTML(http.StatusOK, "import-result.html", gin.H{
-		"Result": result,
-	})
+// importOptions builds the import options from the "payments" parameter
+func importOptions(payments string) service.ImportOptions {
+	include, _ := strconv.ParseBool(payments)
+	return service.ImportOptions{Payments: include}
 }
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBodySize limits raw import uploads via the API
+const maxImportBodySize = 10 << 20
+
+// ConfirmImportRequest is the DTO for confirming a previewed import
+type ConfirmImportRequest struct {
+	Token string `json:"token" binding:"required"`
+	// Payments adds the payment history of Wallos exports to the payment ledger
+	Payments bool `json:"payments"`
+}
+
+// ImportAPI imports subscriptions via JSON API. The file is sent as multipart
+// "file" field or as the raw request body. With ?dry_run=true only a preview is
+// returned, which can be written later with POST /api/v1/import/confirm.
+// With ?payments=true the payment history of Wallos exports is imported too.
+// Encrypted backups need the password in the X-Backup-Password header or the
+// "password" form field.
+func (h *ImportHandler) ImportAPI(c *gin.Context) {
+	data, err := readImportBody(c)
+	if err != nil {
+		apiBadRequest(c, err.Error())
+		return
+	}
+
+	password := c.GetHeader("X-Backup-Password")
+	if password == "" {
+		password = c.PostForm("password")
+	}
+	format := c.Query("format")
+	if format == "" {
+		format = c.PostForm("format")
+	}
+
+	if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+		var preview *service.ImportPreview
+		if password != "" {
+			preview, err = h.importService.PreviewEncrypted(c.Request.Context(), data, password)
+		} else {
+			preview, err = h.importService.Preview(c.Request.Context(), data, format)
+		}
+		if err != nil {
+			h.importError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, preview)
+		return
+	}
+
+	var result service.ImportResult
+	if password != "" {
+		result, err = h.importService.ImportEncrypted(c.Request.Context(), data, password)
+	} else {
+		result, err = h.importService.Import(c.Request.Context(), data, format, importOptions(c.Query("payments")))
+	}
+	if err != nil {
+		h.importError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ConfirmImportAPI writes a previously previewed import
+func (h *ImportHandler) ConfirmImportAPI(c *gin.Context) {
+	var req ConfirmImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+
+	result, err := h.importService.Confirm(c.Request.Context(), req.Token, service.ImportOptions{Payments: req.Payments})
+	if err != nil {
+		apiNotFound(c, "Import preview expired, please upload the file again")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ListBatchesAPI returns the import history
+func (h *ImportHandler) ListBatchesAPI(c *gin.Context) {
+	batches, err := h.importService.ListBatches()
+	if err != nil {
+		slog.Error("failed to list import batches", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, batches)
+}
+
+// UndoBatchAPI removes all subscriptions created by an import batch
+func (h *ImportHandler) UndoBatchAPI(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+
+	deleted, err := h.importService.UndoBatch(uint(id))
+	switch {
+	case errors.Is(err, service.ErrImportBatchNotFound):
+		apiNotFound(c, "Import batch not found")
+		return
+	case err != nil:
+		slog.Error("failed to undo import batch", "error", err, "id", id)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	slog.Info("import batch undone", "id", id, "deleted_subscriptions", deleted)
+
+	c.JSON(http.StatusOK, gin.H{"deleted_subscriptions": deleted})
+}
+
+// importError maps import service errors to API responses
+func (h *ImportHandler) importError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownImportFormat):
+		apiBadRequest(c, "Unknown format")
+	case errors.Is(err, service.ErrDecryptionFailed):
+		apiBadRequest(c, "Decryption failed: wrong password or corrupted file")
+	default:
+		slog.Error("failed to import", "error", err)
+		apiInternalError(c, ErrInternalServer)
+	}
+}
+
+// readImportBody reads the uploaded file from a multipart form or the raw body
+func readImportBody(c *gin.Context) ([]byte, error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, _, err := c.Request.FormFile("file")
+		if err != nil {
+			return nil, errors.New(ErrNoFileUploaded)
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, errors.New(ErrFailedReadFile)
+		}
+		return data, nil
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodySize))
+	if err != nil {
+		return nil, errors.New(ErrFailedReadFile)
+	}
+	if len(data) == 0 {
+		return nil, errors.New(ErrNoFileUploaded)
+	}
+	return data, nil
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportAPI_PreviewAndConfirm(t *testing.T) {
+	h, subscriptions := newTestImportHandler(t)
+	router := gin.New()
+	router.POST("/import", h.ImportAPI)
+	router.POST("/import/confirm", h.ConfirmImportAPI)
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post("/import?dry_run=true", `{"subscriptions":[
+		{"name":"Netflix","price":"12.99","cycle":3,"category_name":"Streaming"},
+		{"name":"Notion","price":"10","cycle":4,"category_name":"Productivity"}
+	]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var preview service.ImportPreview
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &preview))
+	assert.Equal(t, "wallos", preview.Format)
+	assert.Equal(t, 2, preview.ToCreate)
+	require.NotEmpty(t, preview.Token)
+
+	// The preview writes nothing
+	subs, err := subscriptions.GetAll(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+
+	rec = post("/import/confirm", `{"token":"`+preview.Token+`"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var result service.ImportResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, 2, result.Imported)
+	subs, err = subscriptions.GetAll(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, subs, 2)
+
+	// A token is used up by its confirmation
+	rec = post("/import/confirm", `{"token":"`+preview.Token+`"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Import preview expired")
+}
+
+func TestImportAPI_Errors(t *testing.T) {
+	h, _ := newTestImportHandler(t)
+	router := gin.New()
+	router.POST("/import", h.ImportAPI)
+	router.POST("/import/confirm", h.ConfirmImportAPI)
+
+	for _, tc := range []struct {
+		name, path, body string
+		status           int
+		want             string
+	}{
+		{"unknown format", "/import?dry_run=true", `{"foo":"bar"}`, http.StatusBadRequest, "Unknown format"},
+		{"unknown format without dry run", "/import", `name;price`, http.StatusBadRequest, "Unknown format"},
+		{"unknown token", "/import/confirm", `{"token":"nope"}`, http.StatusNotFound, "Import preview expired"},
+		{"missing token", "/import/confirm", `{}`, http.StatusBadRequest, ErrInvalidRequestBody},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body)))
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.want)
+		})
+	}
+}
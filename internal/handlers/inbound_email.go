@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// inboundMaxBody limits inbound webhook payloads. Mailgun posts attachments
+// along with the email, so this is larger than a receipt needs.
+const inboundMaxBody = 10 << 20
+
+// InboundEmailHandler serves the inbound email webhook and its card on the
+// renewals page
+type InboundEmailHandler struct {
+	inbound service.InboundEmailServiceInterface
+}
+
+func NewInboundEmailHandler(inbound service.InboundEmailServiceInterface) *InboundEmailHandler {
+	return &InboundEmailHandler{inbound: inbound}
+}
+
+// inboundJSONPayload is the generic JSON payload for providers other than
+// Mailgun and SES, and for scripts. Date accepts RFC 3339 and RFC 5322 dates.
+type inboundJSONPayload struct {
+	From    string `json:"from"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+	Date    string `json:"date"`
+}
+
+// Receive accepts an email posted by a Mailgun route, an SES receipt rule
+// through SNS or any sender of the generic JSON payload. The token in the path
+// must match the webhook token.
+func (h *InboundEmailHandler) Receive(c *gin.Context) {
+	if !h.inbound.ValidToken(c.Param("token")) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, inboundMaxBody)
+
+	var msg service.InboundMessage
+	var err error
+	if c.GetHeader("X-Amz-Sns-Message-Type") != "" {
+		var subscribeURL string
+		subscribeURL, msg, err = h.readSNS(c)
+		if err == nil && subscribeURL != "" {
+			if err := h.inbound.ConfirmSNSSubscription(subscribeURL); err != nil {
+				slog.Warn("failed to confirm SNS subscription", "error", err)
+				apiError(c, http.StatusBadGateway, "Failed to confirm the SNS subscription")
+				return
+			}
+			slog.Info("confirmed SNS subscription for inbound email")
+			c.JSON(http.StatusOK, gin.H{"status": "subscribed"})
+			return
+		}
+	} else {
+		msg, err = readInboundPayload(c)
+	}
+	if err != nil {
+		slog.Warn("rejected inbound email", "error", err)
+		apiBadRequest(c, "Invalid inbound email payload")
+		return
+	}
+
+	result, err := h.inbound.Receive(c.Request.Context(), msg)
+	if err != nil {
+		slog.Error("failed to record inbound email", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	slog.Info("received inbound email", "status", result.Status, "merchant", result.Merchant)
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *InboundEmailHandler) readSNS(c *gin.Context) (string, service.InboundMessage, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", service.InboundMessage{}, err
+	}
+	return service.ParseSNSEnvelope(body)
+}
+
+// readInboundPayload reads a Mailgun form post or the generic JSON payload.
+// Mailgun routes forwarding to a URL ending in "mime" post the raw email.
+func readInboundPayload(c *gin.Context) (service.InboundMessage, error) {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType == "application/json" {
+		var payload inboundJSONPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			return service.InboundMessage{}, err
+		}
+		msg := service.InboundMessage{From: payload.From, Subject: payload.Subject, Text: payload.Text, Date: parseInboundDate(payload.Date)}
+		if strings.TrimSpace(msg.Text) == "" {
+			msg.Text = service.HTMLToText(payload.HTML)
+		}
+		return msg, nil
+	}
+
+	if raw := c.PostForm("body-mime"); raw != "" {
+		return service.ParseRawEmail([]byte(raw))
+	}
+	msg := service.InboundMessage{
+		From:    c.PostForm("from"),
+		Subject: c.PostForm("subject"),
+		Text:    c.PostForm("body-plain"),
+		Date:    parseInboundDate(c.PostForm("Date")),
+	}
+	if msg.Date.IsZero() {
+		if seconds, err := strconv.ParseInt(c.PostForm("timestamp"), 10, 64); err == nil {
+			msg.Date = time.Unix(seconds, 0)
+		}
+	}
+	if strings.TrimSpace(msg.Text) == "" {
+		msg.Text = service.HTMLToText(c.PostForm("body-html"))
+	}
+	if msg.From == "" {
+		msg.From = c.PostForm("sender")
+	}
+	if msg.From == "" && msg.Subject == "" && msg.Text == "" {
+		return msg, service.ErrInvalidInboundEmail
+	}
+	return msg, nil
+}
+
+// parseInboundDate reads an RFC 3339 or RFC 5322 date, returning the zero time otherwise
+func parseInboundDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date
+	}
+	if date, err := mail.ParseDate(value); err == nil {
+		return date
+	}
+	return time.Time{}
+}
+
+// InboundEmailCard renders the webhook card of the renewals page
+func (h *InboundEmailHandler) InboundEmailCard(c *gin.Context) {
+	h.render(c, http.StatusOK, gin.H{})
+}
+
+// GenerateInboundToken enables the webhook with a new token
+func (h *InboundEmailHandler) GenerateInboundToken(c *gin.Context) {
+	if _, err := h.inbound.GenerateToken(); err != nil {
+		slog.Error("failed to generate inbound email token", "error", err)
+		h.render(c, http.StatusInternalServerError, gin.H{"Error": ErrInternalServer})
+		return
+	}
+	h.render(c, http.StatusOK, gin.H{})
+}
+
+// RevokeInboundToken disables the webhook
+func (h *InboundEmailHandler) RevokeInboundToken(c *gin.Context) {
+	if err := h.inbound.RevokeToken(); err != nil {
+		slog.Error("failed to revoke inbound email token", "error", err)
+		h.render(c, http.StatusInternalServerError, gin.H{"Error": ErrInternalServer})
+		return
+	}
+	h.render(c, http.StatusOK, gin.H{})
+}
+
+// DismissInboundEmail removes a pending receipt
+func (h *InboundEmailHandler) DismissInboundEmail(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+	err = h.inbound.Dismiss(uint(id))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		apiNotFound(c, "Pending email not found")
+		return
+	case err != nil:
+		slog.Error("failed to dismiss inbound email", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	if c.GetHeader("HX-Request") != "" {
+		h.render(c, http.StatusOK, gin.H{})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetInboundEmailAPI returns the webhook URL, if enabled, and the pending receipts
+func (h *InboundEmailHandler) GetInboundEmailAPI(c *gin.Context) {
+	pending, err := h.inbound.Pending()
+	if err != nil {
+		slog.Error("failed to load pending inbound emails", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	response := gin.H{"enabled": false, "pending": pending}
+	if token := h.inbound.Token(); token != "" {
+		response["enabled"] = true
+		response["webhook_url"] = inboundWebhookURL(c, token)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GenerateInboundTokenAPI enables the webhook with a new token and returns its URL
+func (h *InboundEmailHandler) GenerateInboundTokenAPI(c *gin.Context) {
+	token, err := h.inbound.GenerateToken()
+	if err != nil {
+		slog.Error("failed to generate inbound email token", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "webhook_url": inboundWebhookURL(c, token)})
+}
+
+// RevokeInboundTokenAPI disables the webhook
+func (h *InboundEmailHandler) RevokeInboundTokenAPI(c *gin.Context) {
+	if err := h.inbound.RevokeToken(); err != nil {
+		slog.Error("failed to revoke inbound email token", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": false})
+}
+
+func (h *InboundEmailHandler) render(c *gin.Context, status int, data gin.H) {
+	pending, err := h.inbound.Pending()
+	if err != nil {
+		slog.Error("failed to load pending inbound emails", "error", err)
+		data["Error"] = ErrInternalServer
+	}
+	webhookURL := ""
+	if token := h.inbound.Token(); token != "" {
+		webhookURL = inboundWebhookURL(c, token)
+	}
+	c.HTML(status, "inbound-email.html", mergeTemplateData(baseTemplateData(c), mergeTemplateData(gin.H{
+		"WebhookURL": webhookURL,
+		"Pending":    pending,
+	}, data)))
+}
+
+// inboundWebhookURL is the address email providers post to
+func inboundWebhookURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/inbound/email/" + token
+}
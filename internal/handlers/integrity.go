@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntegrityHandler reports and fixes data integrity issues
+type IntegrityHandler struct {
+	integrity service.IntegrityServiceInterface
+}
+
+func NewIntegrityHandler(integrity service.IntegrityServiceInterface) *IntegrityHandler {
+	return &IntegrityHandler{integrity: integrity}
+}
+
+// Report checks the subscriptions and returns the issues found. htmx requests
+// get them as a list with fix buttons.
+func (h *IntegrityHandler) Report(c *gin.Context) {
+	h.renderReport(c)
+}
+
+// Fix corrects a single issue, named by its kind and subscription
+func (h *IntegrityHandler) Fix(c *gin.Context) {
+	var req struct {
+		Kind           string `form:"kind" json:"kind" binding:"required"`
+		SubscriptionID uint   `form:"subscription_id" json:"subscription_id" binding:"required"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+
+	err := h.integrity.Fix(c.Request.Context(), req.Kind, req.SubscriptionID)
+	switch {
+	case errors.Is(err, service.ErrIntegrityIssueNotFound):
+		apiNotFound(c, tr(c, "integrity_not_found", "This issue no longer exists"))
+		return
+	case errors.Is(err, service.ErrIntegrityNotFixable):
+		apiError(c, http.StatusUnprocessableEntity, tr(c, "integrity_not_fixable", "This issue has to be fixed by editing the subscription"))
+		return
+	case err != nil:
+		slog.Error("failed to fix integrity issue", "kind", req.Kind, "subscription_id", req.SubscriptionID, "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	h.renderReport(c)
+}
+
+// FixAll corrects every issue that can be fixed automatically
+func (h *IntegrityHandler) FixAll(c *gin.Context) {
+	fixed, err := h.integrity.FixAll(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to fix integrity issues", "fixed", fixed, "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	h.renderReport(c)
+}
+
+// renderReport answers with the current report, after a fix as well
+func (h *IntegrityHandler) renderReport(c *gin.Context) {
+	report, err := h.integrity.Check(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to check data integrity", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	if c.GetHeader("HX-Request") != "" {
+		c.HTML(http.StatusOK, "integrity-report.html", mergeTemplateData(baseTemplateData(c), gin.H{
+			"Report": report,
+		}))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
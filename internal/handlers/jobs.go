@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"subvault/internal/scheduler"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobsHandler shows the background jobs and lets the user run them on demand
+type JobsHandler struct {
+	jobs service.JobServiceInterface
+}
+
+func NewJobsHandler(jobs service.JobServiceInterface) *JobsHandler {
+	return &JobsHandler{jobs: jobs}
+}
+
+// SettingsJobs renders the Jobs settings page
+func (h *JobsHandler) SettingsJobs(c *gin.Context) {
+	data := baseTemplateData(c)
+	mergeTemplateData(data, gin.H{
+		"Title":       "Jobs",
+		"CurrentPage": "settings",
+		"CurrentTab":  "jobs",
+		"Jobs":        h.jobs.List(),
+		"AnyRunning":  h.jobs.AnyRunning(),
+	})
+	c.HTML(http.StatusOK, "settings-jobs.html", data)
+}
+
+// ListJobs renders the job list (polled while a job is running)
+func (h *JobsHandler) ListJobs(c *gin.Context) {
+	h.renderJobs(c, http.StatusOK, "")
+}
+
+// RunJob starts a job in the background and renders the updated list
+func (h *JobsHandler) RunJob(c *gin.Context) {
+	switch err := h.jobs.Trigger(c.Param("name")); {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		h.renderJobs(c, http.StatusNotFound, tr(c, "jobs_not_found", "Unknown job"))
+	case errors.Is(err, scheduler.ErrJobRunning):
+		h.renderJobs(c, http.StatusConflict, tr(c, "jobs_already_running", "This job is already running"))
+	default:
+		h.renderJobs(c, http.StatusOK, "")
+	}
+}
+
+// ListJobsAPI returns the status of all background jobs as JSON
+func (h *JobsHandler) ListJobsAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.jobs.List())
+}
+
+// RunJobAPI starts a job in the background and returns 202 Accepted
+func (h *JobsHandler) RunJobAPI(c *gin.Context) {
+	name := c.Param("name")
+	switch err := h.jobs.Trigger(name); {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		apiNotFound(c, "Job not found")
+	case errors.Is(err, scheduler.ErrJobRunning):
+		apiError(c, http.StatusConflict, err.Error())
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"job": name, "status": "started"})
+	}
+}
+
+func (h *JobsHandler) renderJobs(c *gin.Context, status int, errMsg string) {
+	c.HTML(status, "jobs-list.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Jobs":       h.jobs.List(),
+		"AnyRunning": h.jobs.AnyRunning(),
+		"Error":      errMsg,
+	}))
+}
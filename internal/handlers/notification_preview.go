@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxNotificationPreviewDays caps the days query parameter of the preview API
+const maxNotificationPreviewDays = 90
+
+// NotificationPreviewHandler shows the notifications of the coming days
+type NotificationPreviewHandler struct {
+	preview service.NotificationPreviewServiceInterface
+}
+
+func NewNotificationPreviewHandler(preview service.NotificationPreviewServiceInterface) *NotificationPreviewHandler {
+	return &NotificationPreviewHandler{preview: preview}
+}
+
+// notificationPreviewDay holds the notifications expected on one day
+type notificationPreviewDay struct {
+	Date    time.Time
+	Entries []service.NotificationPreviewEntry
+}
+
+// SettingsNotificationPreview renders the 30-day notification preview page
+func (h *NotificationPreviewHandler) SettingsNotificationPreview(c *gin.Context) {
+	preview, err := h.preview.Preview(c.Request.Context(), time.Now(), service.NotificationPreviewDays)
+	if err != nil {
+		slog.Error("failed to preview notifications", "error", err)
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": ErrInternalServer})
+		return
+	}
+
+	var days []notificationPreviewDay
+	for _, entry := range preview.Entries {
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(entry.Date) {
+			days = append(days, notificationPreviewDay{Date: entry.Date})
+		}
+		days[len(days)-1].Entries = append(days[len(days)-1].Entries, entry)
+	}
+
+	data := baseTemplateData(c)
+	mergeTemplateData(data, gin.H{
+		"Title":       "Notification Preview",
+		"CurrentPage": "settings",
+		"CurrentTab":  "notifications",
+		"Preview":     preview,
+		"Days":        days,
+		"PreviewDays": service.NotificationPreviewDays,
+	})
+	c.HTML(http.StatusOK, "settings-notification-preview.html", data)
+}
+
+// Preview returns the notifications of the next days query parameter days,
+// 30 by default
+func (h *NotificationPreviewHandler) Preview(c *gin.Context) {
+	days := service.NotificationPreviewDays
+	if raw := c.Query("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxNotificationPreviewDays {
+			apiBadRequest(c, tr(c, "notification_preview_invalid_days", "Days must be between 1 and 90"))
+			return
+		}
+		days = n
+	}
+
+	preview, err := h.preview.Preview(c.Request.Context(), time.Now(), days)
+	if err != nil {
+		slog.Error("failed to preview notifications", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
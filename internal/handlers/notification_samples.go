@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// emailPreviewPolicy keeps scripts and remote content out of a previewed
+// email, which is shown in a frame of the settings page
+const emailPreviewPolicy = "sandbox; default-src 'none'; style-src 'unsafe-inline'; img-src https: data:"
+
+// NotificationTestHandler sends sample notifications to check all channels at once
+type NotificationTestHandler struct {
+	tests  service.NotificationTestServiceInterface
+	emails service.EmailServiceInterface
+}
+
+func NewNotificationTestHandler(tests service.NotificationTestServiceInterface, emails service.EmailServiceInterface) *NotificationTestHandler {
+	return &NotificationTestHandler{tests: tests, emails: emails}
+}
+
+// notificationTestRow holds the results of one notification type, one per channel
+type notificationTestRow struct {
+	Type    string
+	Results []service.NotificationTestResult
+}
+
+// TestAllNotifications sends a sample of every notification type through every
+// channel and reports the outcome per type and channel. htmx requests get the
+// results as a table.
+func (h *NotificationTestHandler) TestAllNotifications(c *gin.Context) {
+	results := h.tests.TestAll(time.Now())
+	failed := 0
+	for _, result := range results {
+		if result.Status == service.NotificationTestFailed {
+			failed++
+		}
+	}
+
+	if c.GetHeader("HX-Request") != "" {
+		var channels []string
+		var rows []notificationTestRow
+		for _, result := range results {
+			if !slices.Contains(channels, result.Channel) {
+				channels = append(channels, result.Channel)
+			}
+			if len(rows) == 0 || rows[len(rows)-1].Type != result.Type {
+				rows = append(rows, notificationTestRow{Type: result.Type})
+			}
+			rows[len(rows)-1].Results = append(rows[len(rows)-1].Results, result)
+		}
+		c.HTML(http.StatusOK, "notification-test-results.html", mergeTemplateData(baseTemplateData(c), gin.H{
+			"Channels": channels,
+			"Rows":     rows,
+			"Failed":   failed,
+		}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results, "failed": failed})
+}
+
+// PreviewEmail returns the email of a notification type rendered for the
+// sample subscription as HTML, in the language of the lang parameter or else
+// the language of email notifications
+func (h *NotificationTestHandler) PreviewEmail(c *gin.Context) {
+	email, err := h.emails.Preview(c.Param("type"), c.Query("lang"), time.Now())
+	if errors.Is(err, service.ErrUnknownNotificationType) {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrUnsupportedLanguage) {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		slog.Error("failed to render email preview", "type", c.Param("type"), "error", err)
+		c.String(http.StatusInternalServerError, ErrInternalServer)
+		return
+	}
+
+	c.Header("Content-Security-Policy", emailPreviewPolicy)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(email.Body))
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLedgerRows limits the confirmed payments shown on the renewals page
+const maxLedgerRows = 50
+
+type PaymentHandler struct {
+	payments  service.PaymentServiceInterface
+	reconcile service.ReconcileServiceInterface
+	bank      service.OpenBankingServiceInterface
+	settings  service.SettingsServiceInterface
+}
+
+func NewPaymentHandler(payments service.PaymentServiceInterface, reconcile service.ReconcileServiceInterface, bank service.OpenBankingServiceInterface, settings service.SettingsServiceInterface) *PaymentHandler {
+	return &PaymentHandler{payments: payments, reconcile: reconcile, bank: bank, settings: settings}
+}
+
+// ConfirmPaymentRequest is the optional body for confirming a renewal. Amount
+// overrides the expected amount; PaidAt accepts RFC 3339 or YYYY-MM-DD and
+// defaults to the renewal date.
+type ConfirmPaymentRequest struct {
+	Amount *float64 `json:"amount" form:"amount" binding:"omitempty,min=0"`
+	PaidAt string   `json:"paid_at" form:"paid_at"`
+}
+
+// UnconfirmedPayment is a renewal awaiting confirmation or reported as not charged
+type UnconfirmedPayment struct {
+	models.Payment
+	PossiblyCancelled bool `json:"possibly_cancelled"`
+}
+
+// Renewals renders the page with the renewals awaiting confirmation, those that
+// point to an involuntary cancellation and the latest confirmed payments
+func (h *PaymentHandler) Renewals(c *gin.Context) {
+	unconfirmed, err := h.payments.Unconfirmed(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to list unconfirmed renewals", "error", err)
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": "An internal error occurred"})
+		return
+	}
+	ledger, err := h.payments.List(c.Request.Context(), 0, models.PaymentConfirmed)
+	if err != nil {
+		slog.Error("failed to list payments", "error", err)
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": "An internal error occurred"})
+		return
+	}
+	if len(ledger) > maxLedgerRows {
+		ledger = ledger[:maxLedgerRows]
+	}
+
+	now := time.Now()
+	var pending, flagged []models.Payment
+	for _, payment := range unconfirmed {
+		if payment.PossiblyCancelled(now) {
+			flagged = append(flagged, payment)
+		} else {
+			pending = append(pending, payment)
+		}
+	}
+
+	c.HTML(http.StatusOK, "renewals.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Title":       "Renewals",
+		"CurrentPage": "renewals",
+		"Enabled":     h.settings.GetBoolSettingWithDefault("renewal_confirmations", false),
+		"Pending":     pending,
+		"Flagged":     flagged,
+		"Ledger":      ledger,
+		"OverdueDays": models.PaymentOverdueDays,
+	}))
+}
+
+// GetPayments returns the payment ledger. Query parameters: subscription_id,
+// status (pending, confirmed or missed; default confirmed).
+func (h *PaymentHandler) GetPayments(c *gin.Context) {
+	var subscriptionID uint64
+	if id := c.Query("subscription_id"); id != "" {
+		var err error
+		if subscriptionID, err = strconv.ParseUint(id, 10, 32); err != nil {
+			apiBadRequest(c, ErrInvalidID)
+			return
+		}
+	}
+	status := c.DefaultQuery("status", models.PaymentConfirmed)
+	if status != models.PaymentPending && status != models.PaymentConfirmed && status != models.PaymentMissed {
+		apiBadRequest(c, "Invalid status, use pending, confirmed or missed")
+		return
+	}
+
+	payments, err := h.payments.List(c.Request.Context(), uint(subscriptionID), status)
+	if err != nil {
+		slog.Error("failed to list payments", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, payments)
+}
+
+// GetUnconfirmedPayments returns the renewals awaiting confirmation and those
+// reported as not charged, flagging possible involuntary cancellations
+func (h *PaymentHandler) GetUnconfirmedPayments(c *gin.Context) {
+	payments, err := h.payments.Unconfirmed(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to list unconfirmed renewals", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	now := time.Now()
+	result := make([]UnconfirmedPayment, len(payments))
+	for i, payment := range payments {
+		result[i] = UnconfirmedPayment{Payment: payment, PossiblyCancelled: payment.PossiblyCancelled(now)}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ConfirmPayment confirms that a renewal was charged, adding it to the ledger
+func (h *PaymentHandler) ConfirmPayment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+
+	var req ConfirmPaymentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBind(&req); err != nil {
+			apiBadRequest(c, ErrInvalidRequestBody)
+			return
+		}
+	}
+
+	var paidAt *time.Time
+	if req.PaidAt != "" {
+		t, err := parseUsageTime(req.PaidAt)
+		if err != nil {
+			apiBadRequest(c, "Invalid paid_at, use RFC 3339 or YYYY-MM-DD")
+			return
+		}
+		paidAt = &t
+	}
+
+	payment, err := h.payments.Confirm(c.Request.Context(), uint(id), req.Amount, paidAt)
+	h.respondPayment(c, payment, err, id)
+}
+
+// RejectPayment reports that a renewal was not charged
+func (h *PaymentHandler) RejectPayment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+
+	payment, err := h.payments.Reject(c.Request.Context(), uint(id))
+	h.respondPayment(c, payment, err, id)
+}
+
+func (h *PaymentHandler) respondPayment(c *gin.Context, payment *models.Payment, err error, id uint64) {
+	switch {
+	case errors.Is(err, service.ErrPaymentNotFound):
+		apiNotFound(c, "Payment not found")
+		return
+	case errors.Is(err, service.ErrPaymentConfirmed):
+		apiError(c, http.StatusConflict, "Payment is already confirmed")
+		return
+	case err != nil:
+		slog.Error("failed to update payment", "error", err, "id", id)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Refresh", "true")
+		c.Status(http.StatusOK)
+		return
+	}
+	c.JSON(http.StatusOK, payment)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// performanceLimit is how many routes and requests the performance report shows
+const performanceLimit = 20
+
+// PerformanceHandler shows where request time goes, to find slow pages
+type PerformanceHandler struct {
+	metrics service.RequestMetricsInterface
+}
+
+func NewPerformanceHandler(metrics service.RequestMetricsInterface) *PerformanceHandler {
+	return &PerformanceHandler{metrics: metrics}
+}
+
+// Performance returns the slowest routes, the slowest recent requests and
+// the recent slow queries. htmx requests get them as tables.
+func (h *PerformanceHandler) Performance(c *gin.Context) {
+	report := h.metrics.Report(performanceLimit)
+	if c.GetHeader("HX-Request") != "" {
+		c.HTML(http.StatusOK, "performance.html", mergeTemplateData(baseTemplateData(c), gin.H{
+			"Report": report,
+		}))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProxyHandler serves the proxy of outbound requests
+type ProxyHandler struct {
+	proxy service.ProxyServiceInterface
+}
+
+func NewProxyHandler(proxy service.ProxyServiceInterface) *ProxyHandler {
+	return &ProxyHandler{proxy: proxy}
+}
+
+// GetProxy returns the proxy set in the app and the proxy in effect. htmx
+// requests get the settings form.
+func (h *ProxyHandler) GetProxy(c *gin.Context) {
+	h.render(c, http.StatusOK, nil)
+}
+
+// SaveProxy stores the proxy set in the app; an empty URL uses the proxy
+// environment variables
+func (h *ProxyHandler) SaveProxy(c *gin.Context) {
+	var req service.ProxyConfig
+	if c.ContentType() == "application/json" {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apiBadRequest(c, ErrInvalidRequestBody)
+			return
+		}
+	} else {
+		req = service.ProxyConfig{URL: c.PostForm("proxy_url"), NoProxy: c.PostForm("no_proxy")}
+	}
+
+	err := h.proxy.Save(req)
+	if errors.Is(err, service.ErrInvalidProxyURL) {
+		if c.GetHeader("HX-Request") != "" {
+			h.render(c, http.StatusOK, gin.H{"Error": tr(c, "settings_proxy_invalid", "Enter a proxy URL like http://proxy.example.com:3128")})
+			return
+		}
+		apiBadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		slog.Error("failed to save proxy", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	h.render(c, http.StatusOK, gin.H{"Message": tr(c, "settings_proxy_saved", "Proxy saved")})
+}
+
+func (h *ProxyHandler) render(c *gin.Context, status int, message gin.H) {
+	if c.GetHeader("HX-Request") != "" {
+		data := mergeTemplateData(baseTemplateData(c), gin.H{
+			"Proxy":       h.proxy.Get(),
+			"ProxyStatus": h.proxy.Status(),
+		})
+		c.HTML(status, "proxy-settings.html", mergeTemplateData(data, message))
+		return
+	}
+	c.JSON(status, gin.H{"proxy": h.proxy.Get(), "effective": h.proxy.Status()})
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfirmReconcileRequest is the DTO for recording a reconciled bank statement.
+// Matches lists the indexes of the previewed matches to record; omitted records all.
+type ConfirmReconcileRequest struct {
+	Token   string `json:"token" binding:"required"`
+	Matches []int  `json:"matches"`
+}
+
+// PreviewReconcile matches an uploaded bank statement to subscriptions and
+// renders the matches and candidate forgotten subscriptions without recording anything
+func (h *PaymentHandler) PreviewReconcile(c *gin.Context) {
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		h.renderReconcile(c, http.StatusBadRequest, gin.H{"Error": ErrNoFileUploaded})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.renderReconcile(c, http.StatusBadRequest, gin.H{"Error": ErrFailedReadFile})
+		return
+	}
+
+	preview, err := h.reconcile.Preview(c.Request.Context(), data, c.PostForm("format"))
+	if err != nil {
+		status, message := reconcileError(err)
+		h.renderReconcile(c, status, gin.H{"Error": message})
+		return
+	}
+	h.renderReconcile(c, http.StatusOK, gin.H{"Preview": preview})
+}
+
+// ConfirmReconcile records the selected matches of a previewed statement in the payment ledger
+func (h *PaymentHandler) ConfirmReconcile(c *gin.Context) {
+	selected := []int{}
+	for _, value := range c.PostFormArray("match") {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			h.renderReconcile(c, http.StatusBadRequest, gin.H{"Error": ErrInvalidRequestBody})
+			return
+		}
+		selected = append(selected, i)
+	}
+
+	result, err := h.reconcile.Confirm(c.PostForm("token"), selected)
+	if err != nil {
+		status, message := reconcileError(err)
+		h.renderReconcile(c, status, gin.H{"Error": message})
+		return
+	}
+	h.renderReconcile(c, http.StatusOK, gin.H{"Result": result})
+}
+
+// ReconcileAPI matches a bank statement to subscriptions via JSON API. The
+// statement is sent as multipart "file" field or as the raw request body; the
+// format (csv or ofx) is detected unless set with ?format=. The returned
+// matches are recorded with POST /api/v1/reconcile/confirm.
+func (h *PaymentHandler) ReconcileAPI(c *gin.Context) {
+	data, err := readImportBody(c)
+	if err != nil {
+		apiBadRequest(c, err.Error())
+		return
+	}
+	format := c.Query("format")
+	if format == "" {
+		format = c.PostForm("format")
+	}
+
+	preview, err := h.reconcile.Preview(c.Request.Context(), data, format)
+	if err != nil {
+		status, message := reconcileError(err)
+		apiError(c, status, message)
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// ConfirmReconcileAPI records the matches of a previewed statement in the payment ledger
+func (h *PaymentHandler) ConfirmReconcileAPI(c *gin.Context) {
+	var req ConfirmReconcileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+
+	result, err := h.reconcile.Confirm(req.Token, req.Matches)
+	if err != nil {
+		status, message := reconcileError(err)
+		apiError(c, status, message)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *PaymentHandler) renderReconcile(c *gin.Context, status int, data gin.H) {
+	c.HTML(status, "reconcile-preview.html", mergeTemplateData(baseTemplateData(c), data))
+}
+
+// reconcileError maps a reconciliation error to a status and client message
+func reconcileError(err error) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrUnknownStatementFormat):
+		return http.StatusBadRequest, "Unknown format, upload a CSV or OFX bank statement"
+	case errors.Is(err, service.ErrInvalidStatement):
+		return http.StatusBadRequest, "The bank statement could not be read"
+	case errors.Is(err, service.ErrNoTransactions):
+		return http.StatusBadRequest, "No transactions found in the bank statement"
+	case errors.Is(err, service.ErrReconcilePreviewNotFound):
+		return http.StatusNotFound, "Reconciliation expired, please upload the statement again"
+	default:
+		slog.Error("failed to reconcile bank statement", "error", err)
+		return http.StatusInternalServerError, ErrInternalServer
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReminderSimulationHandler shows which reminders would be sent at a date
+type ReminderSimulationHandler struct {
+	simulation service.ReminderSimulationServiceInterface
+}
+
+func NewReminderSimulationHandler(simulation service.ReminderSimulationServiceInterface) *ReminderSimulationHandler {
+	return &ReminderSimulationHandler{simulation: simulation}
+}
+
+// Simulate runs the reminder selection for the date in the date query
+// parameter, today by default, and reports what would be sent to whom
+// without sending anything. htmx requests get the reminders as a table.
+func (h *ReminderSimulationHandler) Simulate(c *gin.Context) {
+	at, err := service.ParseSimulationTime(c.Query("date"), time.Now())
+	if errors.Is(err, service.ErrInvalidSimulationDate) {
+		apiBadRequest(c, tr(c, "reminder_simulation_invalid_date", "Enter a date as YYYY-MM-DD"))
+		return
+	}
+
+	simulation, err := h.simulation.Simulate(c.Request.Context(), at)
+	if err != nil {
+		slog.Error("failed to simulate reminders", "at", at, "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	if c.GetHeader("HX-Request") != "" {
+		c.HTML(http.StatusOK, "reminder-simulation.html", mergeTemplateData(baseTemplateData(c), gin.H{
+			"Simulation": simulation,
+		}))
+		return
+	}
+	c.JSON(http.StatusOK, simulation)
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kinds of command palette results
+const (
+	searchTypeAction       = "action"
+	searchTypeSubscription = "subscription"
+	searchTypeCategory     = "category"
+	searchTypePage         = "page"
+)
+
+// searchResult is an entry of the command palette, opened by navigating to URL.
+// Match names the subscription field a subscription was found by.
+type searchResult struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Match    string `json:"match,omitempty"`
+	URL      string `json:"url"`
+}
+
+// searchFieldLabels label the subtitle of subscriptions found by another
+// field than their name or category
+var searchFieldLabels = map[string][2]string{
+	service.SearchFieldCustomerNumber: {"sub_form_customer_number", "Customer Number"},
+	service.SearchFieldContractNumber: {"sub_form_contract_number", "Contract Number"},
+	service.SearchFieldURL:            {"sub_form_website", "Website"},
+	service.SearchFieldNotes:          {"sub_form_notes", "Notes"},
+}
+
+// searchSnippetLength is how many characters of a matched field are shown
+const searchSnippetLength = 60
+
+// searchPage is a page the command palette can jump to. Settings pages are
+// hidden from read-only viewers, who cannot open them.
+type searchPage struct {
+	key      string
+	fallback string
+	path     string
+	settings bool
+}
+
+var searchPages = []searchPage{
+	{"nav_dashboard", "Dashboard", "/", false},
+	{"nav_subscriptions", "Subscriptions", "/subscriptions", false},
+	{"nav_calendar", "Calendar", "/calendar", false},
+	{"nav_tax_report", "Tax Report", "/tax-report", false},
+	{"nav_renewals", "Renewals", "/renewals", false},
+	{"settings_tab_general", "General", "/settings", true},
+	{"settings_tab_notifications", "Notifications", "/settings/notifications", true},
+	{"settings_tab_data", "Data", "/settings/data", true},
+	{"settings_tab_appearance", "Appearance", "/settings/appearance", true},
+	{"settings_tab_security", "Security", "/settings/security", true},
+	{"settings_tab_jobs", "Jobs", "/settings/jobs", true},
+	{"settings_tab_api", "API", "/api-docs", true},
+}
+
+// SearchHandler serves the command palette
+type SearchHandler struct {
+	search service.SearchServiceInterface
+}
+
+func NewSearchHandler(search service.SearchServiceInterface) *SearchHandler {
+	return &SearchHandler{search: search}
+}
+
+// Search returns the actions, subscriptions, categories and pages matching the
+// q query parameter, subscriptions and categories ranked by how well they
+// match. Without a query only the actions and pages are listed.
+// Subscriptions found by their numbers, website or notes show the matching
+// text as subtitle.
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	readOnly := isReadOnly(c)
+
+	found, err := h.search.Search(c.Request.Context(), query)
+	if err != nil {
+		slog.Error("failed to search", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	results := []searchResult{}
+	if !readOnly {
+		results = appendIfMatches(results, query, searchResult{
+			Type:  searchTypeAction,
+			Title: tr(c, "palette_add_subscription", "Add subscription"),
+			URL:   "/subscriptions?new=1",
+		})
+		results = appendIfMatches(results, query, searchResult{
+			Type:  searchTypeAction,
+			Title: tr(c, "quick_add_title", "Quick add"),
+			URL:   "/quick-add",
+		})
+	}
+	for _, sub := range found.Subscriptions {
+		result := searchResult{
+			Type:  searchTypeSubscription,
+			Title: sub.Name,
+			Match: sub.Field,
+			URL:   "/subscriptions?q=" + url.QueryEscape(sub.Name),
+		}
+		if label, ok := searchFieldLabels[sub.Field]; ok {
+			result.Subtitle = tr(c, label[0], label[1]) + ": " + searchSnippet(matchedText(sub), query)
+		} else if sub.Category.Name != "" {
+			result.Subtitle = sub.Category.Name
+		}
+		results = append(results, result)
+	}
+	for _, category := range found.Categories {
+		results = append(results, searchResult{
+			Type:     searchTypeCategory,
+			Title:    category.Name,
+			Subtitle: tr(c, "palette_category", "Category"),
+			URL:      "/subscriptions?category=" + url.QueryEscape(category.Name),
+		})
+	}
+	for _, page := range searchPages {
+		if page.settings && readOnly {
+			continue
+		}
+		result := searchResult{Type: searchTypePage, Title: tr(c, page.key, page.fallback), URL: page.path}
+		if page.settings {
+			result.Subtitle = tr(c, "nav_settings", "Settings")
+		}
+		results = appendIfMatches(results, query, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": results})
+}
+
+// appendIfMatches adds result when its title or subtitle fuzzily matches
+// query. An empty query matches every result.
+func appendIfMatches(results []searchResult, query string, result searchResult) []searchResult {
+	if query == "" || service.FuzzyScore(query, result.Title) > 0 || service.FuzzyScore(query, result.Subtitle) > 0 {
+		return append(results, result)
+	}
+	return results
+}
+
+// matchedText returns the text of the field a subscription was found by
+func matchedText(sub service.SubscriptionMatch) string {
+	switch sub.Field {
+	case service.SearchFieldCustomerNumber:
+		return sub.CustomerNumber
+	case service.SearchFieldContractNumber:
+		return sub.ContractNumber
+	case service.SearchFieldURL:
+		return sub.URL
+	case service.SearchFieldNotes:
+		return sub.Notes
+	}
+	return sub.Name
+}
+
+// searchSnippet shortens text to the part around the first occurrence of
+// query, or its start when it does not contain the query literally
+func searchSnippet(text, query string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= searchSnippetLength {
+		return text
+	}
+	start := 0
+	lower := strings.ToLower(text)
+	if idx := strings.Index(lower, strings.ToLower(query)); idx >= 0 {
+		start = min(max(utf8.RuneCountInString(lower[:idx])-searchSnippetLength/3, 0), len(runes))
+	}
+	end := min(start+searchSnippetLength, len(runes))
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
@@ -15,10 +15,12 @@ type SettingsHandler struct {
 	notifConfig service.NotificationConfigServiceInterface
 	calendar    service.CalendarServiceInterface
 	currency    service.CurrencyServiceInterface
+	defaults    service.SubscriptionDefaultsServiceInterface
+	categories  service.CategoryServiceInterface
 	i18nService *i18n.I18nService
 }
 
-func NewSettingsHandler(settings service.SettingsServiceInterface, auth service.AuthServiceInterface, apiKey service.APIKeyServiceInterface, preferences service.PreferencesServiceInterface, notifConfig service.NotificationConfigServiceInterface, calendar service.CalendarServiceInterface, currency service.CurrencyServiceInterface, i18nService *i18n.I18nService) *SettingsHandler {
+func NewSettingsHandler(settings service.SettingsServiceInterface, auth service.AuthServiceInterface, apiKey service.APIKeyServiceInterface, preferences service.PreferencesServiceInterface, notifConfig service.NotificationConfigServiceInterface, calendar service.CalendarServiceInterface, currency service.CurrencyServiceInterface, defaults service.SubscriptionDefaultsServiceInterface, categories service.CategoryServiceInterface, i18nService *i18n.I18nService) *SettingsHandler {
 	return &SettingsHandler{
 		settings:    settings,
 		auth:        auth,
@@ -27,6 +29,8 @@ func NewSettingsHandler(settings service.SettingsServiceInterface, auth service.
 		notifConfig: notifConfig,
 		calendar:    calendar,
 		currency:    currency,
+		defaults:    defaults,
+		categories:  categories,
 		i18nService: i18nService,
 	}
 }
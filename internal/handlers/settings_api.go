@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeneralSettingsResponse is the JSON representation of the general preferences
+type GeneralSettingsResponse struct {
+	Currency             string  `json:"currency"`
+	Language             string  `json:"language"`
+	Theme                string  `json:"theme"`
+	DateFormat           string  `json:"date_format"`
+	DisplayRounding      string  `json:"display_rounding"`
+	RenewalWindowDays    int     `json:"renewal_window_days"`
+	UpcomingRenewals     int     `json:"upcoming_renewals_limit"`
+	CurrencyRefreshHours int     `json:"currency_refresh_hours"`
+	MonthlyBudget        float64 `json:"monthly_budget"`
+	AnnualBudget         float64 `json:"annual_budget"`
+	BudgetRollover       bool    `json:"budget_rollover"`
+	LogoPrivacyMode      bool    `json:"logo_privacy_mode"`
+	ChartTables          bool    `json:"chart_tables"`
+	AccentColor          string  `json:"accent_color"`
+	CustomCSS            string  `json:"custom_css"`
+	// OfflineMode is set with OFFLINE_MODE and cannot be changed here
+	OfflineMode bool `json:"offline_mode"`
+
+	PurposeBudgets map[string]service.PurposeBudget `json:"purpose_budgets"`
+}
+
+// UpdateGeneralSettingsRequest is the DTO for partial updates of the general preferences.
+// Omitted fields are left unchanged.
+type UpdateGeneralSettingsRequest struct {
+	Currency             *string  `json:"currency" binding:"omitempty,max=10"`
+	Language             *string  `json:"language" binding:"omitempty,max=10"`
+	Theme                *string  `json:"theme" binding:"omitempty,oneof=light dark system"`
+	DateFormat           *string  `json:"date_format"`
+	DisplayRounding      *string  `json:"display_rounding" binding:"omitempty,oneof=currency whole"`
+	RenewalWindowDays    *int     `json:"renewal_window_days" binding:"omitempty,min=1,max=90"`
+	UpcomingRenewals     *int     `json:"upcoming_renewals_limit" binding:"omitempty,min=1,max=20"`
+	CurrencyRefreshHours *int     `json:"currency_refresh_hours" binding:"omitempty,min=1,max=168"`
+	MonthlyBudget        *float64 `json:"monthly_budget" binding:"omitempty,min=0"`
+	AnnualBudget         *float64 `json:"annual_budget" binding:"omitempty,min=0"`
+	BudgetRollover       *bool    `json:"budget_rollover"`
+	LogoPrivacyMode      *bool    `json:"logo_privacy_mode"`
+	ChartTables          *bool    `json:"chart_tables"`
+	AccentColor          *string  `json:"accent_color"`
+	CustomCSS            *string  `json:"custom_css"`
+
+	// Monthly and annual budget per purpose; purposes left out keep their budgets
+	PurposeBudgets map[string]service.PurposeBudget `json:"purpose_budgets"`
+}
+
+// UpdateNotificationSettingsRequest is the DTO for partial updates of the notification preferences
+type UpdateNotificationSettingsRequest struct {
+	RenewalReminders         *bool    `json:"renewal_reminders"`
+	HighCostAlerts           *bool    `json:"high_cost_alerts"`
+	HighCostThreshold        *float64 `json:"high_cost_threshold" binding:"omitempty,min=0,max=10000"`
+	ReminderDays             *int     `json:"reminder_days" binding:"omitempty,min=1,max=90"`
+	CancellationReminders    *bool    `json:"cancellation_reminders"`
+	CancellationReminderDays *int     `json:"cancellation_reminder_days" binding:"omitempty,min=1,max=90"`
+	UnusedNudges             *bool    `json:"unused_nudges"`
+	UnusedNudgeThreshold     *float64 `json:"unused_nudge_threshold" binding:"omitempty,min=0,max=10000"`
+	RateAlerts               *bool    `json:"rate_alerts"`
+	RateAlertThreshold       *float64 `json:"rate_alert_threshold" binding:"omitempty,min=0.1,max=100"`
+	RenewalConfirmations     *bool    `json:"renewal_confirmations"`
+	LoginAlerts              *bool    `json:"login_alerts"`
+	WeeklySummary            *bool    `json:"weekly_summary"`
+	MonthlyReport            *bool    `json:"monthly_report"`
+
+	Languages *models.NotificationLanguages `json:"languages"`
+}
+
+// GetSettingsAPI returns the general preferences
+func (h *SettingsHandler) GetSettingsAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.generalSettings())
+}
+
+// UpdateSettingsAPI updates the general preferences via JSON API
+func (h *SettingsHandler) UpdateSettingsAPI(c *gin.Context) {
+	var req UpdateGeneralSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiBadRequest(c, "Invalid request body. Check value constraints.")
+		return
+	}
+
+	var goFormat string
+	if req.DateFormat != nil {
+		layout, ok := dateFormatLayouts[*req.DateFormat]
+		if !ok {
+			apiBadRequest(c, "Invalid date format")
+			return
+		}
+		goFormat = layout
+	}
+	for purpose, budget := range req.PurposeBudgets {
+		if !models.IsValidPurpose(purpose) || budget.Monthly < 0 || budget.Annual < 0 {
+			apiBadRequest(c, "Invalid purpose_budgets: use personal, business or shared with non-negative budgets")
+			return
+		}
+	}
+
+	if req.Currency != nil {
+		*req.Currency = strings.ToUpper(*req.Currency)
+		if !h.preferences.ValidCurrency(*req.Currency) {
+			apiBadRequest(c, "Invalid currency")
+			return
+		}
+	}
+	if req.Language != nil && !h.preferences.ValidLanguage(*req.Language) {
+		apiBadRequest(c, "Invalid language")
+		return
+	}
+	if req.AccentColor != nil {
+		if _, err := service.NormalizeAccentColor(*req.AccentColor); err != nil {
+			apiBadRequest(c, err.Error())
+			return
+		}
+	}
+	if req.CustomCSS != nil {
+		if _, err := service.SanitizeCustomCSS(*req.CustomCSS); err != nil {
+			apiBadRequest(c, err.Error())
+			return
+		}
+	}
+
+	// Everything is checked, so a rejected request changes nothing
+	var err error
+	if req.Currency != nil {
+		err = h.preferences.SetCurrency(*req.Currency)
+	}
+	if req.Language != nil && err == nil {
+		err = h.preferences.SetLanguage(*req.Language)
+	}
+	if req.Theme != nil && err == nil {
+		err = h.preferences.SetTheme(*req.Theme)
+	}
+	if req.DateFormat != nil && err == nil {
+		err = h.preferences.SetDateFormat(goFormat)
+	}
+	if req.DisplayRounding != nil && err == nil {
+		err = h.preferences.SetDisplayRounding(*req.DisplayRounding)
+	}
+	if req.RenewalWindowDays != nil && err == nil {
+		err = h.preferences.SetRenewalWindowDays(*req.RenewalWindowDays)
+	}
+	if req.UpcomingRenewals != nil && err == nil {
+		err = h.preferences.SetUpcomingRenewalsLimit(*req.UpcomingRenewals)
+	}
+	if req.CurrencyRefreshHours != nil && err == nil {
+		err = h.settings.SetIntSetting(service.SettingKeyCurrencyRefreshHours, *req.CurrencyRefreshHours)
+	}
+	if req.MonthlyBudget != nil && err == nil {
+		err = h.settings.SetFloatSetting("monthly_budget", *req.MonthlyBudget)
+	}
+	if req.AnnualBudget != nil && err == nil {
+		err = h.settings.SetFloatSetting("annual_budget", *req.AnnualBudget)
+	}
+	if req.BudgetRollover != nil && err == nil {
+		err = h.settings.SetBudgetRollover(*req.BudgetRollover)
+	}
+	if req.LogoPrivacyMode != nil && err == nil {
+		err = h.settings.SetBoolSetting(service.SettingKeyLogoPrivacyMode, *req.LogoPrivacyMode)
+	}
+	if req.ChartTables != nil && err == nil {
+		err = h.preferences.SetChartTables(*req.ChartTables)
+	}
+	if req.AccentColor != nil && err == nil {
+		err = h.preferences.SetAccentColor(*req.AccentColor)
+	}
+	if req.CustomCSS != nil && err == nil {
+		err = h.preferences.SetCustomCSS(*req.CustomCSS)
+	}
+	for purpose, budget := range req.PurposeBudgets {
+		if err == nil {
+			err = h.settings.SetPurposeBudget(purpose, budget)
+		}
+	}
+	if err != nil {
+		slog.Error("failed to update settings", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.generalSettings())
+}
+
+// UpdateNotificationSettingsAPI updates the notification preferences via JSON API
+func (h *SettingsHandler) UpdateNotificationSettingsAPI(c *gin.Context) {
+	var req UpdateNotificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiBadRequest(c, "Invalid request body. Check value constraints.")
+		return
+	}
+	h.saveNotificationSettings(c, &req)
+}
+
+// ReplaceNotificationSettingsAPI replaces the notification preferences via JSON
+// API. Unlike PATCH, fields left out are reset to their defaults, so a document
+// kept by infrastructure-as-code tooling fully describes the settings. The
+// read-only channel status of GET is ignored.
+func (h *SettingsHandler) ReplaceNotificationSettingsAPI(c *gin.Context) {
+	defaults := defaultNotificationSettings()
+	req := UpdateNotificationSettingsRequest{
+		RenewalReminders:         &defaults.RenewalReminders,
+		HighCostAlerts:           &defaults.HighCostAlerts,
+		HighCostThreshold:        &defaults.HighCostThreshold,
+		ReminderDays:             &defaults.ReminderDays,
+		CancellationReminders:    &defaults.CancellationReminders,
+		CancellationReminderDays: &defaults.CancellationReminderDays,
+		UnusedNudges:             &defaults.UnusedNudges,
+		UnusedNudgeThreshold:     &defaults.UnusedNudgeThreshold,
+		RateAlerts:               &defaults.RateAlerts,
+		RateAlertThreshold:       &defaults.RateAlertThreshold,
+		RenewalConfirmations:     &defaults.RenewalConfirmations,
+		LoginAlerts:              &defaults.LoginAlerts,
+		WeeklySummary:            &defaults.WeeklySummary,
+		MonthlyReport:            &defaults.MonthlyReport,
+		Languages:                &defaults.Languages,
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiBadRequest(c, "Invalid request body. Check value constraints.")
+		return
+	}
+	h.saveNotificationSettings(c, &req)
+}
+
+// saveNotificationSettings stores the fields of req that are set and responds
+// with the resulting settings
+func (h *SettingsHandler) saveNotificationSettings(c *gin.Context, req *UpdateNotificationSettingsRequest) {
+	if req.Languages != nil {
+		if err := req.Languages.Validate(h.i18nService.SupportedLanguages()); err != nil {
+			apiBadRequest(c, err.Error())
+			return
+		}
+	}
+
+	var err error
+	setBool := func(key string, value *bool) {
+		if value != nil && err == nil {
+			err = h.settings.SetBoolSetting(key, *value)
+		}
+	}
+	setInt := func(key string, value *int) {
+		if value != nil && err == nil {
+			err = h.settings.SetIntSetting(key, *value)
+		}
+	}
+	setFloat := func(key string, value *float64) {
+		if value != nil && err == nil {
+			err = h.settings.SetFloatSetting(key, *value)
+		}
+	}
+
+	setBool("renewal_reminders", req.RenewalReminders)
+	setBool("high_cost_alerts", req.HighCostAlerts)
+	setFloat("high_cost_threshold", req.HighCostThreshold)
+	setInt("reminder_days", req.ReminderDays)
+	setBool("cancellation_reminders", req.CancellationReminders)
+	setInt("cancellation_reminder_days", req.CancellationReminderDays)
+	setBool("unused_nudges", req.UnusedNudges)
+	setFloat("unused_nudge_threshold", req.UnusedNudgeThreshold)
+	setBool("rate_alerts", req.RateAlerts)
+	setFloat("rate_alert_threshold", req.RateAlertThreshold)
+	setBool("renewal_confirmations", req.RenewalConfirmations)
+	setBool("login_alerts", req.LoginAlerts)
+	setBool("weekly_summary", req.WeeklySummary)
+	setBool("monthly_report", req.MonthlyReport)
+	if req.Languages != nil && err == nil {
+		err = h.notifConfig.SaveNotificationLanguages(req.Languages)
+	}
+	if err != nil {
+		slog.Error("failed to update notification settings", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	h.GetNotificationSettings(c)
+}
+
+// SaveSMTPConfigAPI saves the SMTP configuration from a JSON body.
+// An empty password keeps the stored one so scripts don't have to resend it.
+func (h *SettingsHandler) SaveSMTPConfigAPI(c *gin.Context) {
+	var config models.SMTPConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+
+	if config.Password == "" {
+		if existing, err := h.notifConfig.GetSMTPConfig(); err == nil {
+			config.Password = existing.Password
+		}
+	}
+	if config.Host == "" || config.Port <= 0 || config.Username == "" || config.Password == "" || config.From == "" || config.To == "" {
+		apiBadRequest(c, "Required SMTP fields: smtp_host, smtp_port, smtp_username, smtp_password, smtp_from, smtp_to")
+		return
+	}
+	if err := config.ValidateRecipients(); err != nil {
+		apiBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.notifConfig.SaveSMTPConfig(&config); err != nil {
+		slog.Error("failed to save SMTP config", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	h.GetSMTPConfig(c)
+}
+
+// SaveShoutrrrConfigAPI saves the Shoutrrr notification URLs from a JSON body
+func (h *SettingsHandler) SaveShoutrrrConfigAPI(c *gin.Context) {
+	var req models.ShoutrrrConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+
+	var urls []string
+	for _, u := range req.URLs {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) == 0 {
+		apiBadRequest(c, "At least one notification URL is required")
+		return
+	}
+
+	if err := h.notifConfig.SaveShoutrrrConfig(&models.ShoutrrrConfig{URLs: urls}); err != nil {
+		slog.Error("failed to save Shoutrrr config", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	h.GetShoutrrrConfig(c)
+}
+
+// GetExchangeRateStatusAPI returns the exchange rate status
+func (h *SettingsHandler) GetExchangeRateStatusAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, exchangeRateStatusJSON(h.currency.GetStatus()))
+}
+
+// RefreshExchangeRatesAPI refreshes exchange rates from the ECB and returns the new status
+func (h *SettingsHandler) RefreshExchangeRatesAPI(c *gin.Context) {
+	if err := h.currency.RefreshRates(c.Request.Context()); err != nil {
+		slog.Warn("manual exchange rate refresh failed", "error", err)
+		apiError(c, http.StatusBadGateway, "Exchange rate refresh failed")
+		return
+	}
+	c.JSON(http.StatusOK, exchangeRateStatusJSON(h.currency.GetStatus()))
+}
+
+// SetManualRatesAPI stores exchange rates set by hand, as EUR-based rates
+// like {"rates": {"USD": 1.08}}, and returns the new status
+func (h *SettingsHandler) SetManualRatesAPI(c *gin.Context) {
+	var req struct {
+		Rates map[string]float64 `json:"rates" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiBadRequest(c, "Invalid request body")
+		return
+	}
+	if err := h.currency.SetManualRates(req.Rates); err != nil {
+		if errors.Is(err, service.ErrInvalidManualRate) {
+			apiBadRequest(c, "Rates must be positive numbers for currencies with ECB rates, based on EUR")
+			return
+		}
+		slog.Error("failed to save manual exchange rates", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, exchangeRateStatusJSON(h.currency.GetStatus()))
+}
+
+// GetCalendarAPI returns the calendar feed token and URL, if one was
+// generated. With a category and/or purpose query they are the token and URL
+// of the feed limited to those subscriptions.
+func (h *SettingsHandler) GetCalendarAPI(c *gin.Context) {
+	scope, ok := calendarScopeFromQuery(c)
+	if !ok {
+		apiBadRequest(c, "Invalid category or purpose")
+		return
+	}
+	if scope.CategoryID != 0 {
+		if _, err := h.categories.GetByID(scope.CategoryID); err != nil {
+			apiBadRequest(c, "Invalid category or purpose")
+			return
+		}
+	}
+
+	token, err := h.calendar.ScopedCalendarToken(scope)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	feedURL := scheme + "://" + c.Request.Host + "/cal/" + token + "/subscriptions.ics"
+	if query := scope.Query().Encode(); query != "" {
+		feedURL += "?" + query
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":  true,
+		"token":    token,
+		"feed_url": feedURL,
+	})
+}
+
+func (h *SettingsHandler) generalSettings() GeneralSettingsResponse {
+	theme, err := h.preferences.GetTheme()
+	if err != nil {
+		theme = "default"
+	}
+	return GeneralSettingsResponse{
+		Currency:             h.preferences.GetCurrency(),
+		Language:             h.preferences.GetLanguage(),
+		Theme:                theme,
+		DateFormat:           displayDateFormat(h.preferences.GetDateFormat()),
+		DisplayRounding:      h.preferences.GetDisplayRounding(),
+		RenewalWindowDays:    h.preferences.GetRenewalWindowDays(),
+		UpcomingRenewals:     h.preferences.GetUpcomingRenewalsLimit(),
+		CurrencyRefreshHours: h.settings.GetIntSettingWithDefault(service.SettingKeyCurrencyRefreshHours, 24),
+		MonthlyBudget:        h.settings.GetFloatSettingWithDefault("monthly_budget", 0),
+		AnnualBudget:         h.settings.GetFloatSettingWithDefault("annual_budget", 0),
+		BudgetRollover:       h.settings.GetBoolSettingWithDefault("budget_rollover", false),
+		LogoPrivacyMode:      h.settings.GetBoolSettingWithDefault(service.SettingKeyLogoPrivacyMode, false),
+		ChartTables:          h.preferences.ChartTablesEnabled(),
+		AccentColor:          h.preferences.GetAccentColor(),
+		CustomCSS:            h.preferences.GetCustomCSS(),
+		OfflineMode:          service.OfflineMode(),
+		PurposeBudgets:       h.settings.PurposeBudgets(),
+	}
+}
+
+func exchangeRateStatusJSON(status service.ExchangeRateStatus) gin.H {
+	return gin.H{
+		"source":         status.Source,
+		"last_fetch":     status.LastFetch,
+		"rate_date":      status.RateDate,
+		"rate_count":     status.RateCount,
+		"last_error":     status.LastError,
+		"interval_hours": status.IntervalH,
+		"health":         status.Health(),
+	}
+}
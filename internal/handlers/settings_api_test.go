@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateSettingsAPI(t *testing.T) {
+	h := newTestSettingsHandler(t)
+	router := gin.New()
+	router.PATCH("/settings", h.UpdateSettingsAPI)
+	patch := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/settings", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := patch(`{"currency":"eur","language":"en","accent_color":"#2563EB","monthly_budget":50}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var settings GeneralSettingsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &settings))
+	assert.Equal(t, "EUR", settings.Currency)
+	assert.Equal(t, "#2563eb", settings.AccentColor)
+	assert.Equal(t, 50.0, settings.MonthlyBudget)
+
+	// A rejected request changes nothing, even the fields before the bad one
+	for _, tc := range []struct{ name, body, want string }{
+		{"currency", `{"currency":"XXX","monthly_budget":80}`, "Invalid currency"},
+		{"language", `{"currency":"USD","language":"xx"}`, "Invalid language"},
+		{"accent color", `{"currency":"USD","accent_color":"red"}`, "invalid accent color"},
+		{"custom css", `{"currency":"USD","custom_css":"@import url(https://example.com/a.css);"}`, "invalid custom CSS"},
+		{"date format", `{"currency":"USD","date_format":"YYYY"}`, "Invalid date format"},
+		{"purpose budget", `{"currency":"USD","purpose_budgets":{"work":{"monthly":10}}}`, "Invalid purpose_budgets"},
+		{"constraint", `{"currency":"USD","renewal_window_days":0}`, "Invalid request body"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := patch(tc.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.want)
+			assert.Equal(t, settings, h.generalSettings())
+		})
+	}
+}
@@ -1,8 +1,6 @@
 package handlers
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -21,13 +19,6 @@ func (h *SettingsHandler) ListAPIKeys(c *gin.Context) {
 		return
 	}
 
-	// Don't send the actual key values for existing keys
-	for i := range keys {
-		if !keys[i].IsNew {
-			keys[i].Key = ""
-		}
-	}
-
 	c.HTML(http.StatusOK, "api-keys-list.html", mergeTemplateData(baseTemplateData(c), gin.H{
 		"Keys": keys,
 	}))
@@ -43,19 +34,8 @@ func (h *SettingsHandler) CreateAPIKey(c *gin.Context) {
 		return
 	}
 
-	// Generate a secure random API key
-	keyBytes := make([]byte, 32)
-	if _, err := rand.Read(keyBytes); err != nil {
-		c.HTML(http.StatusInternalServerError, "api-keys-list.html", mergeTemplateData(baseTemplateData(c), gin.H{
-			"Error": "Failed to generate API key",
-		}))
-		return
-	}
-
-	apiKey := "sk_" + hex.EncodeToString(keyBytes)
-
-	// Save the API key
-	newKey, err := h.apiKey.CreateAPIKey(name, apiKey)
+	// Generate and save the API key
+	newKey, err := h.apiKey.CreateAPIKey(name, c.PostFormArray("scopes"))
 	if err != nil {
 		slog.Error("failed to create API key", "error", err)
 		c.HTML(http.StatusInternalServerError, "api-keys-list.html", mergeTemplateData(baseTemplateData(c), gin.H{
@@ -74,13 +54,11 @@ func (h *SettingsHandler) CreateAPIKey(c *gin.Context) {
 		return
 	}
 
-	// Mark the new key and include its value
+	// Mark the new key and include its value; it is not shown again
 	for i := range keys {
 		if keys[i].ID == newKey.ID {
 			keys[i].IsNew = true
-			keys[i].Key = apiKey
-		} else {
-			keys[i].Key = ""
+			keys[i].Key = newKey.Key
 		}
 	}
 
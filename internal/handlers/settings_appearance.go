@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"subvault/internal/i18n"
+	"subvault/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,13 +30,6 @@ func (h *SettingsHandler) GetTheme(c *gin.Context) {
 func (h *SettingsHandler) SetTheme(c *gin.Context) {
 	theme := c.PostForm("theme")
 
-	// Validate theme mode
-	validThemes := map[string]bool{
-		"light":  true,
-		"dark":   true,
-		"system": true,
-	}
-
 	if !validThemes[theme] {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid theme name",
@@ -67,18 +66,79 @@ func (h *SettingsHandler) ToggleDarkMode(c *gin.Context) {
 	})
 }
 
+// ToggleChartTables handles POST /api/settings/chart-tables, switching between
+// charts and data tables
+func (h *SettingsHandler) ToggleChartTables(c *gin.Context) {
+	enabled := !h.preferences.ChartTablesEnabled()
+	if err := h.preferences.SetChartTables(enabled); err != nil {
+		slog.Error("failed to save chart tables preference", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+}
+
+// ThemeCSS handles GET /theme.css, the stylesheet with the custom accent color
+// and custom CSS that every page loads after the themes. Custom CSS saved
+// before a check was added is left out until it is fixed.
+func (h *SettingsHandler) ThemeCSS(c *gin.Context) {
+	customCSS := h.preferences.GetCustomCSS()
+	if _, err := service.SanitizeCustomCSS(customCSS); err != nil {
+		slog.Warn("stored custom CSS is not allowed, leaving it out", "error", err)
+		customCSS = ""
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, "text/css; charset=utf-8", []byte(service.ThemeStylesheet(h.preferences.GetAccentColor(), customCSS)))
+}
+
+// SaveAccentColor handles POST /api/settings/accent-color. An empty color
+// goes back to the theme's accent and the presets.
+func (h *SettingsHandler) SaveAccentColor(c *gin.Context) {
+	if err := h.preferences.SetAccentColor(c.PostForm("accent_color")); err != nil {
+		h.renderThemeError(c, err, tr(c, "settings_error_accent_invalid", "Enter a hex color like #2563eb"))
+		return
+	}
+	c.Header("HX-Refresh", "true")
+	c.JSON(http.StatusOK, gin.H{"success": true, "accent_color": h.preferences.GetAccentColor()})
+}
+
+// SaveCustomCSS handles POST /api/settings/custom-css
+func (h *SettingsHandler) SaveCustomCSS(c *gin.Context) {
+	if err := h.preferences.SetCustomCSS(c.PostForm("custom_css")); err != nil {
+		message := tr(c, "settings_error_custom_css_invalid", "The custom CSS was not saved")
+		if errors.Is(err, service.ErrInvalidCustomCSS) {
+			message += ": " + strings.TrimPrefix(err.Error(), service.ErrInvalidCustomCSS.Error()+": ")
+		}
+		h.renderThemeError(c, err, message)
+		return
+	}
+	c.Header("HX-Refresh", "true")
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// renderThemeError reports a rejected accent color or custom CSS, or logs a
+// failure to save them
+func (h *SettingsHandler) renderThemeError(c *gin.Context, err error, message string) {
+	if !errors.Is(err, service.ErrInvalidAccentColor) && !errors.Is(err, service.ErrInvalidCustomCSS) {
+		slog.Error("failed to save theme customization", "error", err)
+		c.HTML(http.StatusInternalServerError, "smtp-message.html", gin.H{
+			"Error": "An internal error occurred",
+			"Type":  "error",
+		})
+		return
+	}
+	c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
+		"Error": message,
+		"Type":  "error",
+	})
+}
+
 // SetDateFormat handles POST /api/settings/date-format
 func (h *SettingsHandler) SetDateFormat(c *gin.Context) {
 	format := c.PostForm("format")
 
-	validFormats := map[string]string{
-		"DD.MM.YYYY": "02.01.2006",
-		"MM/DD/YYYY": "01/02/2006",
-		"YYYY-MM-DD": "2006-01-02",
-		"":           "", // empty = locale default
-	}
-
-	goFormat, ok := validFormats[format]
+	goFormat, ok := dateFormatLayouts[format]
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
 		return
@@ -94,18 +154,79 @@ func (h *SettingsHandler) SetDateFormat(c *gin.Context) {
 
 // GetDateFormat handles GET /api/settings/date-format
 func (h *SettingsHandler) GetDateFormat(c *gin.Context) {
-	goFormat := h.preferences.GetDateFormat()
+	c.JSON(http.StatusOK, gin.H{"format": displayDateFormat(h.preferences.GetDateFormat())})
+}
+
+// SetDisplayRounding handles POST /api/settings/display-rounding
+func (h *SettingsHandler) SetDisplayRounding(c *gin.Context) {
+	rounding := c.PostForm("rounding")
+	if !i18n.ValidRounding(rounding) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid display rounding"})
+		return
+	}
+
+	if err := h.preferences.SetDisplayRounding(rounding); err != nil {
+		slog.Error("failed to save display rounding", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save display rounding"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "rounding": rounding})
+}
+
+// SaveDashboardPreferences handles POST /api/settings/dashboard, saving the
+// renewal window and the size of the upcoming renewals card
+func (h *SettingsHandler) SaveDashboardPreferences(c *gin.Context) {
+	days, daysErr := strconv.Atoi(strings.TrimSpace(c.PostForm("renewal_window_days")))
+	limit, limitErr := strconv.Atoi(strings.TrimSpace(c.PostForm("upcoming_renewals_limit")))
+	if daysErr != nil || limitErr != nil || days < 1 || days > service.MaxRenewalWindowDays || limit < 1 || limit > service.MaxUpcomingRenewalsLimit {
+		c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
+			"Error": tr(c, "settings_error_dashboard_invalid", "The renewal window must be between 1 and 90 days and the card must list 1 to 20 renewals"),
+			"Type":  "error",
+		})
+		return
+	}
 
-	// Map back to display format
-	displayFormat := ""
-	switch goFormat {
-	case "02.01.2006":
-		displayFormat = "DD.MM.YYYY"
-	case "01/02/2006":
-		displayFormat = "MM/DD/YYYY"
-	case "2006-01-02":
-		displayFormat = "YYYY-MM-DD"
+	err := h.preferences.SetRenewalWindowDays(days)
+	if err == nil {
+		err = h.preferences.SetUpcomingRenewalsLimit(limit)
+	}
+	if err != nil {
+		slog.Error("failed to save dashboard preferences", "error", err)
+		c.HTML(http.StatusInternalServerError, "smtp-message.html", gin.H{
+			"Error": "An internal error occurred",
+			"Type":  "error",
+		})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"format": displayFormat})
+	c.HTML(http.StatusOK, "smtp-message.html", gin.H{
+		"Message": tr(c, "settings_success_dashboard_saved", "Dashboard settings saved"),
+		"Type":    "success",
+	})
+}
+
+// validThemes lists the supported theme modes
+var validThemes = map[string]bool{
+	"light":  true,
+	"dark":   true,
+	"system": true,
+}
+
+// dateFormatLayouts maps the selectable date formats to Go layouts
+var dateFormatLayouts = map[string]string{
+	"DD.MM.YYYY": "02.01.2006",
+	"MM/DD/YYYY": "01/02/2006",
+	"YYYY-MM-DD": "2006-01-02",
+	"":           "", // empty = locale default
+}
+
+// displayDateFormat maps a Go layout back to its display format
+func displayDateFormat(goFormat string) string {
+	for display, layout := range dateFormatLayouts {
+		if layout == goFormat {
+			return display
+		}
+	}
+	return ""
 }
@@ -1,12 +1,31 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+// passwordPolicyMessage translates a password policy violation for the user.
+// ok is false when err is not a policy violation.
+func passwordPolicyMessage(c *gin.Context, err error) (msg string, ok bool) {
+	var policyErr *service.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return "", false
+	}
+	return trData(c, "password_rule_"+policyErr.Rule, map[string]interface{}{
+		"Min": policyErr.Policy.MinLength,
+		"Max": service.PasswordMaxBytes,
+	}, policyErr.Error()), true
+}
+
 // SetupAuth enables authentication with username and password
 func (h *SettingsHandler) SetupAuth(c *gin.Context) {
 	username := c.PostForm("username")
@@ -30,16 +49,12 @@ func (h *SettingsHandler) SetupAuth(c *gin.Context) {
 		return
 	}
 
-	if len(password) < 8 {
-		c.HTML(http.StatusBadRequest, "auth-message.html", gin.H{
-			"Error": tr(c, "settings_error_password_short", "Password must be at least 8 characters long"),
-			"Type":  "error",
-		})
-		return
-	}
-
 	// Setup authentication
 	err := h.auth.SetupAuth(username, password)
+	if msg, ok := passwordPolicyMessage(c, err); ok {
+		c.HTML(http.StatusBadRequest, "auth-message.html", gin.H{"Error": msg, "Type": "error"})
+		return
+	}
 	if err != nil {
 		slog.Error("failed to setup authentication", "error", err)
 		c.HTML(http.StatusInternalServerError, "auth-message.html", gin.H{
@@ -78,8 +93,186 @@ func (h *SettingsHandler) GetAuthStatus(c *gin.Context) {
 	isEnabled := h.auth.IsAuthEnabled()
 	username, _ := h.auth.GetAuthUsername()
 
+	viewerUsername, _ := h.auth.GetViewerUsername()
+
 	c.JSON(http.StatusOK, gin.H{
-		"enabled":  isEnabled,
-		"username": username,
+		"enabled":         isEnabled,
+		"username":        username,
+		"viewer_username": viewerUsername,
 	})
 }
+
+// SetupViewer creates or replaces the read-only viewer account
+func (h *SettingsHandler) SetupViewer(c *gin.Context) {
+	username := strings.TrimSpace(c.PostForm("username"))
+	password := c.PostForm("password")
+
+	var errMsg string
+	switch {
+	case username == "" || password == "":
+		errMsg = tr(c, "settings_error_auth_required", "Username and password are required")
+	case password != c.PostForm("confirm_password"):
+		errMsg = tr(c, "settings_error_password_mismatch", ErrPasswordsDoNotMatch)
+	}
+	if errMsg != "" {
+		c.HTML(http.StatusBadRequest, "auth-message.html", gin.H{"Error": errMsg, "Type": "error"})
+		return
+	}
+
+	if err := h.auth.SetViewer(username, password); err != nil {
+		if msg, ok := passwordPolicyMessage(c, err); ok {
+			c.HTML(http.StatusBadRequest, "auth-message.html", gin.H{"Error": msg, "Type": "error"})
+			return
+		}
+		if errors.Is(err, service.ErrViewerUsernameTaken) {
+			c.HTML(http.StatusBadRequest, "auth-message.html", gin.H{
+				"Error": tr(c, "viewer_error_username_taken", "The viewer needs a different username than the admin"),
+				"Type":  "error",
+			})
+			return
+		}
+		slog.Error("failed to set up viewer account", "error", err)
+		c.HTML(http.StatusInternalServerError, "auth-message.html", gin.H{
+			"Error": "An internal error occurred",
+			"Type":  "error",
+		})
+		return
+	}
+
+	c.Header("HX-Refresh", "true")
+	c.Status(http.StatusOK)
+}
+
+// RemoveViewer deletes the read-only viewer account
+func (h *SettingsHandler) RemoveViewer(c *gin.Context) {
+	if err := h.auth.RemoveViewer(); err != nil {
+		slog.Error("failed to remove viewer account", "error", err)
+		c.HTML(http.StatusInternalServerError, "auth-message.html", gin.H{
+			"Error": "An internal error occurred",
+			"Type":  "error",
+		})
+		return
+	}
+
+	c.Header("HX-Refresh", "true")
+	c.Status(http.StatusOK)
+}
+
+// SaveSessionLifetimes saves the session lifetimes from the security settings form
+func (h *SettingsHandler) SaveSessionLifetimes(c *gin.Context) {
+	sessionHours, _ := strconv.Atoi(c.PostForm("session_hours"))
+	rememberMeDays, _ := strconv.Atoi(c.PostForm("remember_me_days"))
+	absoluteDays, _ := strconv.Atoi(c.PostForm("absolute_days"))
+	lifetimes := models.SessionLifetimes{
+		SessionHours:   sessionHours,
+		RememberMeDays: rememberMeDays,
+		AbsoluteDays:   absoluteDays,
+		Sliding:        c.PostForm("sliding") == "on",
+	}
+	if err := lifetimes.Validate(); err != nil {
+		c.HTML(http.StatusBadRequest, "auth-message.html", gin.H{
+			"Error": tr(c, "sessions_error_invalid", "Invalid session lifetimes"),
+			"Type":  "error",
+		})
+		return
+	}
+
+	if err := h.auth.SaveSessionLifetimes(lifetimes); err != nil {
+		slog.Error("failed to save session lifetimes", "error", err)
+		c.HTML(http.StatusInternalServerError, "auth-message.html", gin.H{
+			"Error": "An internal error occurred",
+			"Type":  "error",
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "auth-message.html", gin.H{
+		"Message": tr(c, "sessions_saved", "Session lifetimes saved"),
+		"Type":    "success",
+	})
+}
+
+// GetSessionLifetimesAPI returns the session lifetimes
+func (h *SettingsHandler) GetSessionLifetimesAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.auth.GetSessionLifetimes())
+}
+
+// SaveSessionLifetimesAPI replaces the session lifetimes from a JSON body
+func (h *SettingsHandler) SaveSessionLifetimesAPI(c *gin.Context) {
+	var lifetimes models.SessionLifetimes
+	if err := c.ShouldBindJSON(&lifetimes); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+	if err := lifetimes.Validate(); err != nil {
+		apiBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.auth.SaveSessionLifetimes(lifetimes); err != nil {
+		slog.Error("failed to save session lifetimes", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, lifetimes)
+}
+
+// SavePasswordPolicy saves the password policy from the security settings form
+func (h *SettingsHandler) SavePasswordPolicy(c *gin.Context) {
+	minLength, _ := strconv.Atoi(c.PostForm("min_length"))
+	minStrength, _ := strconv.Atoi(c.PostForm("min_strength"))
+	policy := models.PasswordPolicy{
+		MinLength:        minLength,
+		RequireMixedCase: c.PostForm("require_mixed_case") == "on",
+		RequireDigit:     c.PostForm("require_digit") == "on",
+		RequireSymbol:    c.PostForm("require_symbol") == "on",
+		RejectBreached:   c.PostForm("reject_breached") == "on",
+		MinStrength:      minStrength,
+	}
+	if err := policy.Validate(); err != nil {
+		c.HTML(http.StatusBadRequest, "auth-message.html", gin.H{
+			"Error": tr(c, "password_policy_error_invalid", "Invalid password policy"),
+			"Type":  "error",
+		})
+		return
+	}
+
+	if err := h.auth.SavePasswordPolicy(policy); err != nil {
+		slog.Error("failed to save password policy", "error", err)
+		c.HTML(http.StatusInternalServerError, "auth-message.html", gin.H{
+			"Error": "An internal error occurred",
+			"Type":  "error",
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "auth-message.html", gin.H{
+		"Message": tr(c, "password_policy_saved", "Password policy saved. It applies to passwords set from now on."),
+		"Type":    "success",
+	})
+}
+
+// GetPasswordPolicyAPI returns the password policy
+func (h *SettingsHandler) GetPasswordPolicyAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.auth.GetPasswordPolicy())
+}
+
+// SavePasswordPolicyAPI replaces the password policy from a JSON body
+func (h *SettingsHandler) SavePasswordPolicyAPI(c *gin.Context) {
+	var policy models.PasswordPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		apiBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.auth.SavePasswordPolicy(policy); err != nil {
+		slog.Error("failed to save password policy", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
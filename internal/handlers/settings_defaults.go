@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SaveSubscriptionDefaults saves the defaults for new subscriptions from the general settings form
+func (h *SettingsHandler) SaveSubscriptionDefaults(c *gin.Context) {
+	defaults := models.SubscriptionDefaults{
+		Schedule:             c.PostForm("schedule"),
+		Currency:             strings.ToUpper(strings.TrimSpace(c.PostForm("currency"))),
+		PriceType:            c.PostForm("price_type"),
+		Purpose:              c.PostForm("purpose"),
+		RenewalReminder:      c.PostForm("renewal_reminder") == "on",
+		CancellationReminder: c.PostForm("cancellation_reminder") == "on",
+	}
+	if id, err := strconv.ParseUint(c.PostForm("category_id"), 10, 32); err == nil {
+		defaults.CategoryID = uint(id)
+	}
+	if rate := strings.TrimSpace(c.PostForm("tax_rate")); rate != "" {
+		taxRate, err := parseNumber(c, rate)
+		if err != nil {
+			renderDefaultsInvalid(c)
+			return
+		}
+		defaults.TaxRate = taxRate
+	}
+	defaults.RenewalReminderDays, _ = strconv.Atoi(c.PostForm("renewal_reminder_days"))
+	defaults.CancellationReminderDays, _ = strconv.Atoi(c.PostForm("cancellation_reminder_days"))
+
+	if err := h.defaults.Save(&defaults); err != nil {
+		if !errors.Is(err, service.ErrInvalidDefaults) {
+			slog.Error("failed to save subscription defaults", "error", err)
+		}
+		renderDefaultsInvalid(c)
+		return
+	}
+
+	c.HTML(http.StatusOK, "smtp-message.html", gin.H{
+		"Message": tr(c, "settings_success_defaults_saved", "Defaults saved"),
+		"Type":    "success",
+	})
+}
+
+// renderDefaultsInvalid reports subscription defaults that cannot be saved
+func renderDefaultsInvalid(c *gin.Context) {
+	c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
+		"Error": tr(c, "settings_error_defaults_invalid", "Check the default values: reminder days must be between 1 and 365 and the tax rate between 0 and 100"),
+		"Type":  "error",
+	})
+}
+
+// GetSubscriptionDefaultsAPI returns the defaults for new subscriptions
+func (h *SettingsHandler) GetSubscriptionDefaultsAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.defaults.Get())
+}
+
+// SaveSubscriptionDefaultsAPI replaces the defaults for new subscriptions from a JSON body.
+// Omitted fields take the built-in defaults.
+func (h *SettingsHandler) SaveSubscriptionDefaultsAPI(c *gin.Context) {
+	defaults := models.DefaultSubscriptionDefaults()
+	if err := c.ShouldBindJSON(&defaults); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+	defaults.Currency = strings.ToUpper(defaults.Currency)
+
+	if err := h.defaults.Save(&defaults); err != nil {
+		if errors.Is(err, service.ErrInvalidDefaults) {
+			apiBadRequest(c, err.Error())
+			return
+		}
+		slog.Error("failed to save subscription defaults", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.defaults.Get())
+}
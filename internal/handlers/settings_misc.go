@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"subvault/internal/models"
 	"subvault/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -62,14 +66,158 @@ func (h *SettingsHandler) RevokeCalendarToken(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// SaveCalendarFeedOptions saves the calendar feed options from the data settings form
+func (h *SettingsHandler) SaveCalendarFeedOptions(c *gin.Context) {
+	horizon, err := strconv.Atoi(c.PostForm("horizon_months"))
+	options := models.CalendarFeedOptions{
+		HorizonMonths:      horizon,
+		CancellationEvents: c.PostForm("cancellation_events") == "on",
+	}
+	if err == nil {
+		err = options.Validate()
+	}
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
+			"Error": trData(c, "settings_calendar_horizon_invalid", map[string]interface{}{
+				"Max": models.CalendarFeedMaxHorizon,
+			}, "Invalid calendar horizon"),
+			"Type": "error",
+		})
+		return
+	}
+
+	if err := h.calendar.SaveFeedOptions(options); err != nil {
+		slog.Error("failed to save calendar feed options", "error", err)
+		c.HTML(http.StatusInternalServerError, "smtp-message.html", gin.H{
+			"Error": "An internal error occurred",
+			"Type":  "error",
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "smtp-message.html", gin.H{
+		"Message": tr(c, "settings_calendar_options_saved", "Calendar feed options saved"),
+		"Type":    "success",
+	})
+}
+
+// GetCalendarFeedOptionsAPI returns the calendar feed options
+func (h *SettingsHandler) GetCalendarFeedOptionsAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.calendar.GetFeedOptions())
+}
+
+// SaveCalendarFeedOptionsAPI replaces the calendar feed options from a JSON body
+func (h *SettingsHandler) SaveCalendarFeedOptionsAPI(c *gin.Context) {
+	var options models.CalendarFeedOptions
+	if err := c.ShouldBindJSON(&options); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+	if err := options.Validate(); err != nil {
+		apiBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.calendar.SaveFeedOptions(options); err != nil {
+		slog.Error("failed to save calendar feed options", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, options)
+}
+
+// csvColumnChoice is a CSV column on the data settings page
+type csvColumnChoice struct {
+	Key      string
+	Header   string
+	Selected bool
+}
+
+// csvColumnChoices lists the selected CSV columns in their order, followed by
+// the remaining columns in their default order
+func csvColumnChoices(selected []string) []csvColumnChoice {
+	columns := service.CSVColumns()
+	choices := make([]csvColumnChoice, 0, len(columns))
+	for _, key := range selected {
+		for _, column := range columns {
+			if column.Key == key {
+				choices = append(choices, csvColumnChoice{Key: key, Header: column.Header, Selected: true})
+			}
+		}
+	}
+	for _, column := range columns {
+		if !slices.Contains(selected, column.Key) {
+			choices = append(choices, csvColumnChoice{Key: column.Key, Header: column.Header})
+		}
+	}
+	return choices
+}
+
+// SaveCSVColumns saves the CSV export columns from the data settings form; the
+// checked columns are saved in the order of the form
+func (h *SettingsHandler) SaveCSVColumns(c *gin.Context) {
+	err := h.preferences.SetCSVColumns(c.PostFormArray("columns"))
+	if errors.Is(err, service.ErrInvalidCSVColumns) {
+		c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
+			"Error": tr(c, "settings_csv_columns_invalid", "Select at least one column"),
+			"Type":  "error",
+		})
+		return
+	}
+	if err != nil {
+		slog.Error("failed to save CSV columns", "error", err)
+		c.HTML(http.StatusInternalServerError, "smtp-message.html", gin.H{
+			"Error": "An internal error occurred",
+			"Type":  "error",
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "smtp-message.html", gin.H{
+		"Message": tr(c, "settings_csv_columns_saved", "CSV columns saved"),
+		"Type":    "success",
+	})
+}
+
+// GetCSVColumnsAPI returns the CSV export columns and all available columns
+func (h *SettingsHandler) GetCSVColumnsAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"columns":   h.preferences.GetCSVColumns(),
+		"available": service.DefaultCSVColumns(),
+	})
+}
+
+// SaveCSVColumnsAPI replaces the CSV export columns from a JSON body
+func (h *SettingsHandler) SaveCSVColumnsAPI(c *gin.Context) {
+	var req struct {
+		Columns []string `json:"columns"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+	err := h.preferences.SetCSVColumns(req.Columns)
+	if errors.Is(err, service.ErrInvalidCSVColumns) {
+		apiBadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		slog.Error("failed to save CSV columns", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"columns": h.preferences.GetCSVColumns()})
+}
+
 // RefreshExchangeRates manually refreshes exchange rates from ECB
 func (h *SettingsHandler) RefreshExchangeRates(c *gin.Context) {
-	err := h.currency.RefreshRates()
+	err := h.currency.RefreshRates(c.Request.Context())
 	status := h.currency.GetStatus()
 
 	data := baseTemplateData(c)
 	mergeTemplateData(data, gin.H{
-		"RateStatus": status,
+		"RateStatus":  status,
+		"ManualRates": h.currency.ManualRateEntries(),
 	})
 
 	if err != nil {
@@ -86,6 +234,58 @@ func (h *SettingsHandler) RefreshExchangeRates(c *gin.Context) {
 	c.HTML(http.StatusOK, "exchange-rate-status.html", data)
 }
 
+// SetManualRates stores the exchange rates set by hand in the rate_<currency>
+// fields; empty fields keep their rate
+func (h *SettingsHandler) SetManualRates(c *gin.Context) {
+	rates := map[string]float64{}
+	invalid := false
+	for _, entry := range h.currency.ManualRateEntries() {
+		value := strings.TrimSpace(c.PostForm("rate_" + entry.Currency))
+		if value == "" {
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+		if err != nil {
+			invalid = true
+			continue
+		}
+		rates[entry.Currency] = rate
+	}
+
+	var err error
+	if invalid {
+		err = service.ErrInvalidManualRate
+	} else {
+		err = h.currency.SetManualRates(rates)
+	}
+	data := baseTemplateData(c)
+	mergeTemplateData(data, gin.H{
+		"RateStatus":  h.currency.GetStatus(),
+		"ManualRates": h.currency.ManualRateEntries(),
+	})
+	if err != nil {
+		if !errors.Is(err, service.ErrInvalidManualRate) {
+			slog.Error("failed to save manual exchange rates", "error", err)
+		}
+		data["ManualRatesError"] = true
+	} else {
+		data["ManualRatesSaved"] = true
+	}
+	c.HTML(http.StatusOK, "exchange-rate-status.html", data)
+}
+
+// ToggleLogoPrivacy switches privacy mode for logo lookups, which keeps the
+// website domains of subscriptions from third-party favicon services
+func (h *SettingsHandler) ToggleLogoPrivacy(c *gin.Context) {
+	enabled := !h.settings.GetBoolSettingWithDefault(service.SettingKeyLogoPrivacyMode, false)
+	if err := h.settings.SetBoolSetting(service.SettingKeyLogoPrivacyMode, enabled); err != nil {
+		slog.Error("failed to save logo privacy mode", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+}
+
 // UpdateCurrencyRefreshInterval updates the exchange rate refresh interval
 func (h *SettingsHandler) UpdateCurrencyRefreshInterval(c *gin.Context) {
 	hoursStr := c.PostForm("hours")
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"crypto/tls"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -25,6 +24,9 @@ func (h *SettingsHandler) SaveSMTPSettings(c *gin.Context) {
 	config.From = c.PostForm("smtp_from")
 	config.FromName = c.PostForm("smtp_from_name")
 	config.To = c.PostForm("smtp_to")
+	config.CC = c.PostForm("smtp_cc")
+	config.BCC = c.PostForm("smtp_bcc")
+	config.ReplyTo = strings.TrimSpace(c.PostForm("smtp_reply_to"))
 
 	// Parse port
 	if portStr := c.PostForm("smtp_port"); portStr != "" {
@@ -41,6 +43,13 @@ func (h *SettingsHandler) SaveSMTPSettings(c *gin.Context) {
 		})
 		return
 	}
+	if err := config.ValidateRecipients(); err != nil {
+		c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
+			"Error": tr(c, "settings_error_smtp_recipients", "Enter valid email addresses for To, CC, BCC and Reply-To; separate several with commas"),
+			"Type":  "error",
+		})
+		return
+	}
 
 	// Save configuration
 	err := h.notifConfig.SaveSMTPConfig(&config)
@@ -53,11 +62,31 @@ func (h *SettingsHandler) SaveSMTPSettings(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "smtp-message.html", gin.H{
-		"Message": tr(c, "settings_success_smtp_saved", "SMTP settings saved successfully"),
-		"Type":    "success",
+		"Message":  tr(c, "settings_success_smtp_saved", "SMTP settings saved successfully"),
+		"Type":     "success",
+		"Warnings": senderWarnings(c, &config),
 	})
 }
 
+// senderWarnings describes the sender setup problems found by
+// service.CheckSender that are likely to send reminders to spam
+func senderWarnings(c *gin.Context, config *models.SMTPConfig) []string {
+	fallbacks := map[string]string{
+		service.SenderWarningFreemailRelay: "%s only lets its own servers send its mail, so reminders from %s will likely be rejected or marked as spam. Use a From address of your own domain or the provider's SMTP server.",
+		service.SenderWarningDMARCRelay:    "%s rejects or quarantines mail failing DMARC. Make sure %s signs with DKIM for the domain or is included in its SPF record.",
+		service.SenderWarningNoDMARC:       "%s publishes no DMARC policy, so large providers may treat reminders from %s as spam. Set up SPF, DKIM and DMARC for the domain.",
+	}
+	var messages []string
+	for _, warning := range service.CheckSender(c.Request.Context(), config, !service.OfflineMode()) {
+		fallback := fmt.Sprintf(fallbacks[warning.Code], warning.Domain, warning.Host)
+		messages = append(messages, trData(c, "smtp_warning_"+warning.Code, map[string]interface{}{
+			"Domain": warning.Domain,
+			"Host":   warning.Host,
+		}, fallback))
+	}
+	return messages
+}
+
 // TestSMTPConnection tests SMTP configuration with TLS/SSL support
 func (h *SettingsHandler) TestSMTPConnection(c *gin.Context) {
 	var config models.SMTPConfig
@@ -69,6 +98,9 @@ func (h *SettingsHandler) TestSMTPConnection(c *gin.Context) {
 	config.From = c.PostForm("smtp_from")
 	config.FromName = c.PostForm("smtp_from_name")
 	config.To = c.PostForm("smtp_to")
+	config.CC = c.PostForm("smtp_cc")
+	config.BCC = c.PostForm("smtp_bcc")
+	config.ReplyTo = strings.TrimSpace(c.PostForm("smtp_reply_to"))
 
 	// Parse port
 	if portStr := c.PostForm("smtp_port"); portStr != "" {
@@ -87,69 +119,18 @@ func (h *SettingsHandler) TestSMTPConnection(c *gin.Context) {
 	}
 
 	// Test connection with TLS/SSL support
-	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
-	auth := smtp.PlainAuth("", config.Username, config.Password, config.Host)
-
-	// Determine if this is an implicit TLS port (SMTPS)
-	isSSLPort := config.Port == 465 || config.Port == 8465 || config.Port == 443
-
-	var client *smtp.Client
-	var err error
-
-	if isSSLPort {
-		// Use implicit TLS (direct SSL connection)
-		tlsConfig := &tls.Config{
-			ServerName: config.Host,
-		}
-
-		conn, err := tls.Dial("tcp", addr, tlsConfig)
-		if err != nil {
-			c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
-				"Error": fmt.Sprintf("Failed to connect via SSL: %v", err),
-				"Type":  "error",
-			})
-			return
-		}
-
-		client, err = smtp.NewClient(conn, config.Host)
-		if err != nil {
-			conn.Close()
-			c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
-				"Error": fmt.Sprintf("Failed to create SMTP client: %v", err),
-				"Type":  "error",
-			})
-			return
-		}
-	} else {
-		// Use STARTTLS (opportunistic TLS)
-		client, err = smtp.Dial(addr)
-		if err != nil {
-			c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
-				"Error": fmt.Sprintf("Failed to connect: %v", err),
-				"Type":  "error",
-			})
-			return
-		}
-
-		// Upgrade to TLS
-		tlsConfig := &tls.Config{
-			ServerName: config.Host,
-		}
-
-		if err = client.StartTLS(tlsConfig); err != nil {
-			client.Close()
-			c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
-				"Error": fmt.Sprintf("Failed to start TLS: %v", err),
-				"Type":  "error",
-			})
-			return
-		}
+	client, err := service.DialSMTP(c.Request.Context(), &config)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
+			"Error": fmt.Sprintf("Connection failed: %v", err),
+			"Type":  "error",
+		})
+		return
 	}
-
 	defer client.Close()
 
 	// Try to authenticate
-	if err = client.Auth(auth); err != nil {
+	if err = client.Auth(smtp.PlainAuth("", config.Username, config.Password, config.Host)); err != nil {
 		c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
 			"Error": fmt.Sprintf("Authentication failed: %v", err),
 			"Type":  "error",
@@ -176,6 +157,7 @@ func (h *SettingsHandler) GetSMTPConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"configured": true,
 		"config":     config,
+		"warnings":   service.CheckSender(c.Request.Context(), config, !service.OfflineMode()),
 	})
 }
 
@@ -216,6 +198,65 @@ func (h *SettingsHandler) SaveShoutrrrSettings(c *gin.Context) {
 	})
 }
 
+// SaveDeliveryWindows saves the per-channel delivery windows. Notifications
+// sent outside a channel's window are queued until it opens.
+func (h *SettingsHandler) SaveDeliveryWindows(c *gin.Context) {
+	window := func(channel string) models.DeliveryWindow {
+		return models.DeliveryWindow{
+			Enabled: c.PostForm(channel+"_enabled") == "true",
+			Start:   strings.TrimSpace(c.PostForm(channel + "_start")),
+			End:     strings.TrimSpace(c.PostForm(channel + "_end")),
+		}
+	}
+	windows := &models.DeliveryWindows{
+		Email:    window(models.ChannelEmail),
+		Shoutrrr: window(models.ChannelShoutrrr),
+	}
+
+	if err := h.notifConfig.SaveDeliveryWindows(windows); err != nil {
+		c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
+			"Error": tr(c, "settings_error_delivery_window_invalid", "Enter a valid start and end time (HH:MM) for each enabled window"),
+			"Type":  "error",
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "smtp-message.html", gin.H{
+		"Message": tr(c, "settings_success_delivery_windows_saved", "Delivery windows saved"),
+		"Type":    "success",
+	})
+}
+
+// SaveNotificationLanguages saves the language of each notification channel.
+// An empty language follows the app language.
+func (h *SettingsHandler) SaveNotificationLanguages(c *gin.Context) {
+	languages := &models.NotificationLanguages{
+		Email:    c.PostForm("email_language"),
+		Shoutrrr: c.PostForm("shoutrrr_language"),
+	}
+	if err := languages.Validate(h.i18nService.SupportedLanguages()); err != nil {
+		c.HTML(http.StatusBadRequest, "smtp-message.html", gin.H{
+			"Error": tr(c, "settings_error_notification_language_invalid", "Choose one of the available languages"),
+			"Type":  "error",
+		})
+		return
+	}
+
+	if err := h.notifConfig.SaveNotificationLanguages(languages); err != nil {
+		slog.Error("failed to save notification languages", "error", err)
+		c.HTML(http.StatusInternalServerError, "smtp-message.html", gin.H{
+			"Error": "An internal error occurred",
+			"Type":  "error",
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "smtp-message.html", gin.H{
+		"Message": tr(c, "settings_success_notification_languages_saved", "Notification languages saved"),
+		"Type":    "success",
+	})
+}
+
 // TestShoutrrrConnection tests Shoutrrr notification URLs
 func (h *SettingsHandler) TestShoutrrrConnection(c *gin.Context) {
 	urlsRaw := c.PostForm("shoutrrr_urls")
@@ -291,6 +332,70 @@ func (h *SettingsHandler) UpdateNotificationSetting(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"enabled": enabled})
 		return
 
+	case "unused":
+		enabled := !h.settings.GetBoolSettingWithDefault("unused_nudges", false)
+		h.settings.SetBoolSetting("unused_nudges", enabled)
+		c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+		return
+
+	case "unused_threshold":
+		thresholdStr := c.PostForm("unused_nudge_threshold")
+		if threshold, err := parseNumber(c, thresholdStr); err == nil && threshold >= 0 && threshold <= 10000 {
+			if err := h.settings.SetFloatSetting("unused_nudge_threshold", threshold); err != nil {
+				slog.Error("failed to save unused nudge threshold", "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"threshold": threshold})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid threshold value (must be between 0 and 10000)"})
+		}
+		return
+
+	case "rate":
+		enabled := !h.settings.GetBoolSettingWithDefault("rate_alerts", false)
+		h.settings.SetBoolSetting("rate_alerts", enabled)
+		c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+		return
+
+	case "renewal_confirmations":
+		enabled := !h.settings.GetBoolSettingWithDefault("renewal_confirmations", false)
+		h.settings.SetBoolSetting("renewal_confirmations", enabled)
+		c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+		return
+
+	case "login_alerts":
+		enabled := !h.settings.GetBoolSettingWithDefault("login_alerts", false)
+		h.settings.SetBoolSetting("login_alerts", enabled)
+		c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+		return
+
+	case "weekly_summary":
+		enabled := !h.settings.GetBoolSettingWithDefault("weekly_summary", false)
+		h.settings.SetBoolSetting("weekly_summary", enabled)
+		c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+		return
+
+	case "monthly_report":
+		enabled := !h.settings.GetBoolSettingWithDefault("monthly_report", false)
+		h.settings.SetBoolSetting("monthly_report", enabled)
+		c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+		return
+
+	case "rate_threshold":
+		thresholdStr := c.PostForm("rate_alert_threshold")
+		if threshold, err := parseNumber(c, thresholdStr); err == nil && threshold >= 0.1 && threshold <= 100 {
+			if err := h.settings.SetFloatSetting("rate_alert_threshold", threshold); err != nil {
+				slog.Error("failed to save rate alert threshold", "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"threshold": threshold})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid threshold value (must be between 0.1 and 100)"})
+		}
+		return
+
 	case "reminder_days":
 		daysStr := c.PostForm("reminder_days")
 		if days, err := strconv.Atoi(daysStr); err == nil && days >= 1 && days <= 90 {
@@ -313,7 +418,7 @@ func (h *SettingsHandler) UpdateNotificationSetting(c *gin.Context) {
 
 	case "threshold":
 		thresholdStr := c.PostForm("high_cost_threshold")
-		if threshold, err := strconv.ParseFloat(thresholdStr, 64); err == nil && threshold >= 0 && threshold <= 10000 {
+		if threshold, err := parseNumber(c, thresholdStr); err == nil && threshold >= 0 && threshold <= 10000 {
 			err := h.settings.SetFloatSetting("high_cost_threshold", threshold)
 			if err != nil {
 				slog.Error("failed to save high cost threshold", "error", err)
@@ -330,7 +435,7 @@ func (h *SettingsHandler) UpdateNotificationSetting(c *gin.Context) {
 		if value == "" {
 			value = "0"
 		}
-		floatVal, err := strconv.ParseFloat(value, 64)
+		floatVal, err := parseNumber(c, value)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget value"})
 			return
@@ -343,21 +448,123 @@ func (h *SettingsHandler) UpdateNotificationSetting(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"success": true})
 		return
 
+	case "annual_budget":
+		value := c.PostForm("value")
+		if value == "" {
+			value = "0"
+		}
+		floatVal, err := parseNumber(c, value)
+		if err != nil || floatVal < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget value"})
+			return
+		}
+		if err := h.settings.SetFloatSetting("annual_budget", floatVal); err != nil {
+			slog.Error("failed to save annual budget", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+		return
+
+	case "budget_rollover":
+		enabled := !h.settings.GetBoolSettingWithDefault("budget_rollover", false)
+		if err := h.settings.SetBudgetRollover(enabled); err != nil {
+			slog.Error("failed to save budget rollover", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+		return
+
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown setting"})
 	}
 }
 
+// SavePurposeBudget saves the monthly and annual budget of one purpose
+func (h *SettingsHandler) SavePurposeBudget(c *gin.Context) {
+	purpose := c.Param("purpose")
+	if !models.IsValidPurpose(purpose) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPurpose})
+		return
+	}
+
+	var budget service.PurposeBudget
+	for field, target := range map[string]*float64{"monthly": &budget.Monthly, "annual": &budget.Annual} {
+		value := strings.TrimSpace(c.PostForm(field))
+		if value == "" {
+			continue
+		}
+		parsed, err := parseNumber(c, value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget value"})
+			return
+		}
+		*target = parsed
+	}
+
+	if err := h.settings.SetPurposeBudget(purpose, budget); err != nil {
+		slog.Error("failed to save purpose budget", "error", err, "purpose", purpose)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
 // GetNotificationSettings returns current notification settings
 func (h *SettingsHandler) GetNotificationSettings(c *gin.Context) {
+	defaults := defaultNotificationSettings()
 	settings := models.NotificationSettings{
-		RenewalReminders:         h.settings.GetBoolSettingWithDefault("renewal_reminders", false),
-		HighCostAlerts:           h.settings.GetBoolSettingWithDefault("high_cost_alerts", true),
-		HighCostThreshold:        h.settings.GetFloatSettingWithDefault("high_cost_threshold", 50.0),
-		ReminderDays:             h.settings.GetIntSettingWithDefault("reminder_days", 7),
-		CancellationReminders:    h.settings.GetBoolSettingWithDefault("cancellation_reminders", false),
-		CancellationReminderDays: h.settings.GetIntSettingWithDefault("cancellation_reminder_days", 7),
+		RenewalReminders:         h.settings.GetBoolSettingWithDefault("renewal_reminders", defaults.RenewalReminders),
+		HighCostAlerts:           h.settings.GetBoolSettingWithDefault("high_cost_alerts", defaults.HighCostAlerts),
+		HighCostThreshold:        h.settings.GetFloatSettingWithDefault("high_cost_threshold", defaults.HighCostThreshold),
+		ReminderDays:             h.settings.GetIntSettingWithDefault("reminder_days", defaults.ReminderDays),
+		CancellationReminders:    h.settings.GetBoolSettingWithDefault("cancellation_reminders", defaults.CancellationReminders),
+		CancellationReminderDays: h.settings.GetIntSettingWithDefault("cancellation_reminder_days", defaults.CancellationReminderDays),
+		UnusedNudges:             h.settings.GetBoolSettingWithDefault("unused_nudges", defaults.UnusedNudges),
+		UnusedNudgeThreshold:     h.settings.GetFloatSettingWithDefault("unused_nudge_threshold", defaults.UnusedNudgeThreshold),
+		RateAlerts:               h.settings.GetBoolSettingWithDefault("rate_alerts", defaults.RateAlerts),
+		RateAlertThreshold:       h.settings.GetFloatSettingWithDefault("rate_alert_threshold", defaults.RateAlertThreshold),
+		RenewalConfirmations:     h.settings.GetBoolSettingWithDefault("renewal_confirmations", defaults.RenewalConfirmations),
+		LoginAlerts:              h.settings.GetBoolSettingWithDefault("login_alerts", defaults.LoginAlerts),
+		WeeklySummary:            h.settings.GetBoolSettingWithDefault("weekly_summary", defaults.WeeklySummary),
+		MonthlyReport:            h.settings.GetBoolSettingWithDefault("monthly_report", defaults.MonthlyReport),
+		Languages:                *h.notifConfig.GetNotificationLanguages(),
+		Channels:                 h.notificationChannels(),
 	}
 
 	c.JSON(http.StatusOK, settings)
 }
+
+// defaultNotificationSettings returns the notification preferences of a new
+// instance
+func defaultNotificationSettings() models.NotificationSettings {
+	return models.NotificationSettings{
+		HighCostAlerts:           true,
+		HighCostThreshold:        50.0,
+		ReminderDays:             7,
+		CancellationReminderDays: 7,
+		UnusedNudgeThreshold:     10.0,
+		RateAlertThreshold:       service.DefaultRateAlertThreshold,
+	}
+}
+
+// notificationChannels returns the status of the email and Shoutrrr channels.
+// Credentials, addresses and URLs are never included.
+func (h *SettingsHandler) notificationChannels() map[string]models.NotificationChannelStatus {
+	windows := h.notifConfig.GetDeliveryWindows()
+	email := models.NotificationChannelStatus{DeliveryWindow: windows.Email}
+	if config, err := h.notifConfig.GetSMTPConfig(); err == nil && config.Host != "" {
+		email.Configured = true
+		email.Targets = len(config.Recipients())
+	}
+	shoutrrr := models.NotificationChannelStatus{DeliveryWindow: windows.Shoutrrr}
+	if config, err := h.notifConfig.GetShoutrrrConfig(); err == nil && len(config.URLs) > 0 {
+		shoutrrr.Configured = true
+		shoutrrr.Targets = len(config.URLs)
+	}
+	return map[string]models.NotificationChannelStatus{
+		models.ChannelEmail:    email,
+		models.ChannelShoutrrr: shoutrrr,
+	}
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"net/http"
+	"subvault/internal/i18n"
 	"subvault/internal/models"
+	"subvault/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,15 +23,32 @@ func (h *SettingsHandler) SettingsGeneral(c *gin.Context) {
 	}
 
 	rateStatus := h.currency.GetStatus()
+	categories, err := h.categories.GetAll()
+	if err != nil {
+		categories = []models.Category{}
+	}
 
 	data := h.settingsBaseData(c, "general")
 	mergeTemplateData(data, gin.H{
-		"Title":      "Settings",
-		"Currency":   h.preferences.GetCurrency(),
-		"Language":   h.preferences.GetLanguage(),
-		"Languages":  h.i18nService.Languages(),
-		"DateFormat": displayFormat,
-		"RateStatus": rateStatus,
+		"Title":           "Settings",
+		"Currency":        h.preferences.GetCurrency(),
+		"Language":        h.preferences.GetLanguage(),
+		"Languages":       h.i18nService.Languages(),
+		"DateFormat":      displayFormat,
+		"Rounding":        h.preferences.GetDisplayRounding(),
+		"RateStatus":      rateStatus,
+		"ManualRates":     h.currency.ManualRateEntries(),
+		"Defaults":        h.defaults.Get(),
+		"Categories":      categories,
+		"Currencies":      service.SupportedCurrencies(),
+		"CurrencyOptions": i18n.Currencies(),
+
+		"LogoPrivacyMode": h.settings.GetBoolSettingWithDefault(service.SettingKeyLogoPrivacyMode, false),
+
+		"RenewalWindowDays":        h.preferences.GetRenewalWindowDays(),
+		"MaxRenewalWindowDays":     service.MaxRenewalWindowDays,
+		"UpcomingRenewalsLimit":    h.preferences.GetUpcomingRenewalsLimit(),
+		"MaxUpcomingRenewalsLimit": service.MaxUpcomingRenewalsLimit,
 	})
 	c.HTML(http.StatusOK, "settings-general.html", data)
 }
@@ -38,7 +57,11 @@ func (h *SettingsHandler) SettingsGeneral(c *gin.Context) {
 func (h *SettingsHandler) SettingsAppearance(c *gin.Context) {
 	data := h.settingsBaseData(c, "appearance")
 	mergeTemplateData(data, gin.H{
-		"Title": "Appearance",
+		"Title":        "Appearance",
+		"ChartTables":  h.preferences.ChartTablesEnabled(),
+		"AccentColor":  h.preferences.GetAccentColor(),
+		"CustomCSS":    h.preferences.GetCustomCSS(),
+		"MaxCustomCSS": service.MaxCustomCSSLength,
 	})
 	c.HTML(http.StatusOK, "settings-appearance.html", data)
 }
@@ -64,14 +87,31 @@ func (h *SettingsHandler) SettingsNotifications(c *gin.Context) {
 
 	data := h.settingsBaseData(c, "notifications")
 	mergeTemplateData(data, gin.H{
-		"Title":              "Notifications",
-		"SMTPConfig":         smtpConfig,
-		"SMTPConfigured":     smtpConfigured,
-		"ShoutrrrConfig":     shoutrrrConfig,
-		"ShoutrrrConfigured": shoutrrrConfigured,
-		"CurrencySymbol":     h.preferences.GetCurrencySymbol(),
-		"HighCostThreshold":  h.settings.GetFloatSettingWithDefault("high_cost_threshold", 50.0),
-		"MonthlyBudget":      h.settings.GetFloatSettingWithDefault("monthly_budget", 0),
+		"Title":                "Notifications",
+		"SMTPConfig":           smtpConfig,
+		"SMTPConfigured":       smtpConfigured,
+		"ShoutrrrConfig":       shoutrrrConfig,
+		"ShoutrrrConfigured":   shoutrrrConfigured,
+		"CurrencySymbol":       h.preferences.GetCurrencySymbol(),
+		"HighCostThreshold":    h.settings.GetFloatSettingWithDefault("high_cost_threshold", 50.0),
+		"MonthlyBudget":        h.settings.GetFloatSettingWithDefault("monthly_budget", 0),
+		"AnnualBudget":         h.settings.GetFloatSettingWithDefault("annual_budget", 0),
+		"BudgetRollover":       h.settings.GetBoolSettingWithDefault("budget_rollover", false),
+		"Purposes":             models.Purposes,
+		"PurposeBudgets":       h.settings.PurposeBudgets(),
+		"UnusedNudges":         h.settings.GetBoolSettingWithDefault("unused_nudges", false),
+		"UnusedThreshold":      h.settings.GetFloatSettingWithDefault("unused_nudge_threshold", 10.0),
+		"RateAlerts":           h.settings.GetBoolSettingWithDefault("rate_alerts", false),
+		"RateAlertThreshold":   h.settings.GetFloatSettingWithDefault("rate_alert_threshold", service.DefaultRateAlertThreshold),
+		"RenewalConfirmations": h.settings.GetBoolSettingWithDefault("renewal_confirmations", false),
+		"LoginAlerts":          h.settings.GetBoolSettingWithDefault("login_alerts", false),
+		"WeeklySummary":        h.settings.GetBoolSettingWithDefault("weekly_summary", false),
+		"MonthlyReport":        h.settings.GetBoolSettingWithDefault("monthly_report", false),
+		"DeliveryWindows":      h.notifConfig.GetDeliveryWindows(),
+		"ChannelLanguages":     h.notifConfig.GetNotificationLanguages(),
+		"Languages":            h.i18nService.Languages(),
+		"QueuedNotifications":  len(h.notifConfig.QueuedNotifications()),
+		"NotificationTypes":    service.NotificationTypes(),
 	})
 	c.HTML(http.StatusOK, "settings-notifications.html", data)
 }
@@ -79,12 +119,21 @@ func (h *SettingsHandler) SettingsNotifications(c *gin.Context) {
 // SettingsData renders the Data settings page (Export, Import, Backup, Calendar, Categories)
 func (h *SettingsHandler) SettingsData(c *gin.Context) {
 	calendarToken, _ := h.calendar.GetCalendarToken()
+	categories, err := h.categories.GetAll()
+	if err != nil {
+		categories = []models.Category{}
+	}
 
 	data := h.settingsBaseData(c, "data")
 	mergeTemplateData(data, gin.H{
 		"Title":         "Data",
 		"CalendarToken": calendarToken,
+		"CalendarFeed":  h.calendar.GetFeedOptions(),
+		"CalendarMax":   models.CalendarFeedMaxHorizon,
+		"Categories":    categories,
 		"BaseURL":       "http://" + c.Request.Host,
+		"AuthEnabled":   h.auth.IsAuthEnabled(),
+		"CSVColumns":    csvColumnChoices(h.preferences.GetCSVColumns()),
 	})
 	c.HTML(http.StatusOK, "settings-data.html", data)
 }
@@ -93,6 +142,7 @@ func (h *SettingsHandler) SettingsData(c *gin.Context) {
 func (h *SettingsHandler) SettingsSecurity(c *gin.Context) {
 	authEnabled := h.auth.IsAuthEnabled()
 	authUsername, _ := h.auth.GetAuthUsername()
+	viewerUsername, _ := h.auth.GetViewerUsername()
 
 	var smtpConfigured bool
 	_, err := h.notifConfig.GetSMTPConfig()
@@ -105,7 +155,11 @@ func (h *SettingsHandler) SettingsSecurity(c *gin.Context) {
 		"Title":          "Security",
 		"AuthEnabled":    authEnabled,
 		"AuthUsername":   authUsername,
+		"ViewerUsername": viewerUsername,
 		"SMTPConfigured": smtpConfigured,
+		"Sessions":       h.auth.GetSessionLifetimes(),
+		"PasswordPolicy": h.auth.GetPasswordPolicy(),
+		"StrengthLevels": []int{1, 2, 3, 4},
 	})
 	c.HTML(http.StatusOK, "settings-security.html", data)
 }
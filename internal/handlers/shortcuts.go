@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"subvault/internal/i18n"
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The shortcut endpoints are minimal endpoints for automation apps like
+// Shortcuts or Tasker. They answer with one line of plain text that can be
+// spoken or shown as is, or with a small JSON object when format=json.
+
+// ShortcutNextRenewal tells which subscription renews next
+func (h *SubscriptionHandler) ShortcutNextRenewal(c *gin.Context) {
+	subscriptions, err := h.service.GetAll(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to load subscriptions for next renewal shortcut", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	now := time.Now()
+	next := models.NextRenewal(subscriptions, now)
+	if next == nil {
+		shortcutReply(c, http.StatusOK, gin.H{"name": nil}, tr(c, "shortcut_no_renewal", "No upcoming renewals"))
+		return
+	}
+
+	days := daysUntilRenewal(next, now)
+	date := next.RenewalDate.Format("2006-01-02")
+	if t := getTranslator(c); t != nil {
+		date = t.FormatDate(next.RenewalDate)
+	}
+	cost := next.CostAt(*next.RenewalDate)
+	amount := service.CurrencySymbolForCode(next.OriginalCurrency) + h.preferences.FormatAmount(cost, next.OriginalCurrency)
+	text := trData(c, "shortcut_next_renewal", map[string]interface{}{"Name": next.Name, "Date": date, "Amount": amount, "Days": days},
+		fmt.Sprintf("%s renews on %s (%s)", next.Name, date, amount))
+
+	shortcutReply(c, http.StatusOK, gin.H{
+		"name":         next.Name,
+		"renewal_date": next.RenewalDate.Format("2006-01-02"),
+		"days":         days,
+		"cost":         cost,
+		"currency":     next.OriginalCurrency,
+	}, text)
+}
+
+// ShortcutMonthlyTotal tells the monthly spend of all active subscriptions in
+// the display currency
+func (h *SubscriptionHandler) ShortcutMonthlyTotal(c *gin.Context) {
+	stats, err := h.service.GetStats(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to load stats for monthly total shortcut", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	total := i18n.RoundAmount(stats.TotalMonthlySpend, h.preferences.GetCurrency())
+	amount := h.preferences.GetCurrencySymbol() + h.preferences.FormatAmount(total, "")
+	text := trData(c, "shortcut_monthly_total", map[string]interface{}{"Amount": amount, "Count": stats.ActiveSubscriptions},
+		fmt.Sprintf("%s per month (%d active)", amount, stats.ActiveSubscriptions))
+
+	shortcutReply(c, http.StatusOK, gin.H{
+		"monthly_total": total,
+		"currency":      h.preferences.GetCurrency(),
+		"active":        stats.ActiveSubscriptions,
+	}, text)
+}
+
+// ShortcutAddSubscription adds a subscription from just a name and a cost,
+// read from the query string, a form or JSON. Everything else takes the
+// subscription defaults.
+func (h *SubscriptionHandler) ShortcutAddSubscription(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" form:"name"`
+		Cost string `json:"cost" form:"cost"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	cost, err := parseNumber(c, req.Cost)
+	if name == "" || len(name) > 100 || err != nil || math.Abs(cost) > models.MaxCost {
+		apiBadRequest(c, tr(c, "shortcut_add_invalid", "Send a name and a cost"))
+		return
+	}
+
+	subscription := h.defaults.NewSubscription()
+	subscription.Name = name
+	subscription.Cost = cost
+	created, err := h.service.Create(c.Request.Context(), subscription)
+	if err != nil {
+		slog.Error("failed to create subscription via shortcut", "error", err)
+		apiInternalError(c, "Failed to create subscription")
+		return
+	}
+	h.afterCreate(c.Request.Context(), created)
+
+	amount := service.CurrencySymbolForCode(created.OriginalCurrency) + h.preferences.FormatAmount(created.Cost, created.OriginalCurrency)
+	text := trData(c, "shortcut_added", map[string]interface{}{"Name": created.Name, "Amount": amount},
+		fmt.Sprintf("Added %s (%s)", created.Name, amount))
+	shortcutReply(c, http.StatusCreated, gin.H{"id": created.ID, "name": created.Name, "cost": created.Cost, "currency": created.OriginalCurrency}, text)
+}
+
+// daysUntilRenewal counts the days from today to the renewal date of the subscription
+func daysUntilRenewal(sub *models.Subscription, now time.Time) int {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, sub.RenewalDate.Location())
+	return int(math.Round(sub.RenewalDate.Sub(today).Hours() / 24))
+}
+
+// shortcutReply answers with data for format=json and with text otherwise
+func shortcutReply(c *gin.Context, status int, data gin.H, text string) {
+	if c.Query("format") == "json" {
+		data["text"] = text
+		c.JSON(status, data)
+		return
+	}
+	c.String(status, text)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestShortcutRouter(h *SubscriptionHandler) *gin.Engine {
+	router := gin.New()
+	router.GET("/shortcuts/next-renewal", h.ShortcutNextRenewal)
+	router.GET("/shortcuts/monthly-total", h.ShortcutMonthlyTotal)
+	router.POST("/shortcuts/add", h.ShortcutAddSubscription)
+	return router
+}
+
+func shortcutRequest(router *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestShortcuts_Empty(t *testing.T) {
+	h, _ := newTestSubscriptionHandler(t)
+	router := newTestShortcutRouter(h)
+
+	rec := shortcutRequest(router, http.MethodGet, "/shortcuts/next-renewal")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "No upcoming renewals", rec.Body.String())
+
+	rec = shortcutRequest(router, http.MethodGet, "/shortcuts/next-renewal?format=json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"name":null,"text":"No upcoming renewals"}`, rec.Body.String())
+
+	rec = shortcutRequest(router, http.MethodGet, "/shortcuts/monthly-total?format=json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"monthly_total":0,"currency":"USD","active":0,"text":"$0.00 per month (0 active)"}`, rec.Body.String())
+}
+
+func TestShortcuts_Replies(t *testing.T) {
+	h, subscriptions := newTestSubscriptionHandler(t)
+	router := newTestShortcutRouter(h)
+	renewal := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 3)
+	for _, sub := range []*models.Subscription{
+		{Name: "Netflix", Cost: 15.49, Schedule: "Monthly", Status: "Active", OriginalCurrency: "USD", RenewalDate: &renewal},
+		{Name: "Backup", Cost: 60, Schedule: "Annual", Status: "Active", OriginalCurrency: "USD"},
+	} {
+		_, err := subscriptions.Create(t.Context(), sub)
+		require.NoError(t, err)
+	}
+
+	rec := shortcutRequest(router, http.MethodGet, "/shortcuts/next-renewal")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "Netflix renews on "+renewal.Format("2006-01-02")+" ($15.49)", rec.Body.String())
+
+	rec = shortcutRequest(router, http.MethodGet, "/shortcuts/next-renewal?format=json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var next map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &next))
+	assert.Equal(t, map[string]any{
+		"name": "Netflix", "renewal_date": renewal.Format("2006-01-02"), "days": 3.0, "cost": 15.49, "currency": "USD",
+		"text": "Netflix renews on " + renewal.Format("2006-01-02") + " ($15.49)",
+	}, next)
+
+	// 15.49 + 60/12
+	rec = shortcutRequest(router, http.MethodGet, "/shortcuts/monthly-total")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "$20.49 per month (2 active)", rec.Body.String())
+}
+
+func TestShortcutAddSubscription(t *testing.T) {
+	h, subscriptions := newTestSubscriptionHandler(t)
+	router := newTestShortcutRouter(h)
+
+	rec := shortcutRequest(router, http.MethodPost, "/shortcuts/add?name=Spotify&cost=10.99&format=json")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "Spotify", created["name"])
+	assert.Equal(t, 10.99, created["cost"])
+	assert.Equal(t, "Added Spotify ($10.99)", created["text"])
+
+	req := httptest.NewRequest(http.MethodPost, "/shortcuts/add", strings.NewReader(`{"name":"  Tidal ","cost":"1,000.50"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, "Added Tidal ($1000.50)", rec.Body.String())
+
+	for _, query := range []url.Values{
+		{"cost": {"5"}},
+		{"name": {"   "}, "cost": {"5"}},
+		{"name": {strings.Repeat("x", 101)}, "cost": {"5"}},
+		{"name": {"Spotify"}},
+		{"name": {"Spotify"}, "cost": {"five"}},
+		{"name": {"Spotify"}, "cost": {"1000001"}},
+		{"name": {"Spotify"}, "cost": {"-1000001"}},
+	} {
+		t.Run(query.Encode(), func(t *testing.T) {
+			rec := shortcutRequest(router, http.MethodPost, "/shortcuts/add?"+query.Encode())
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.JSONEq(t, `{"error":"Send a name and a cost"}`, rec.Body.String())
+		})
+	}
+
+	subs, err := subscriptions.GetAll(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, subs, 2)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SplitHandler struct {
+	splits        service.SplitServiceInterface
+	subscriptions service.SubscriptionServiceInterface
+	preferences   service.PreferencesServiceInterface
+	notifier      service.NotificationDispatcherInterface
+}
+
+func NewSplitHandler(splits service.SplitServiceInterface, subscriptions service.SubscriptionServiceInterface, preferences service.PreferencesServiceInterface, notifier service.NotificationDispatcherInterface) *SplitHandler {
+	return &SplitHandler{
+		splits:        splits,
+		subscriptions: subscriptions,
+		preferences:   preferences,
+		notifier:      notifier,
+	}
+}
+
+// ShareRequest is one entry of a split configuration
+type ShareRequest struct {
+	Person    string  `json:"person" binding:"required,max=100"`
+	ShareType string  `json:"share_type" binding:"required,oneof=percent fixed seats"`
+	Value     float64 `json:"value" binding:"required"`
+}
+
+// SplitForm renders the split editor for a subscription
+func (h *SplitHandler) SplitForm(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.String(http.StatusBadRequest, ErrInvalidID)
+		return
+	}
+
+	subscription, err := h.subscriptions.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.String(http.StatusNotFound, ErrSubscriptionNotFound)
+		return
+	}
+	shares, err := h.splits.GetShares(uint(id))
+	if err != nil {
+		slog.Error("failed to get shares", "error", err, "id", id)
+		c.String(http.StatusInternalServerError, ErrInternalServer)
+		return
+	}
+
+	c.HTML(http.StatusOK, "split-form.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Subscription": subscription,
+		"Shares":       shares,
+	}))
+}
+
+// GetShares returns the split configuration of a subscription
+func (h *SplitHandler) GetShares(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+
+	shares, err := h.splits.GetShares(uint(id))
+	if err != nil {
+		slog.Error("failed to get shares", "error", err, "id", id)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, shares)
+}
+
+// SaveShares replaces the split configuration of a subscription. Accepts a JSON
+// array of shares or the person/share_type/value form arrays of the split editor.
+func (h *SplitHandler) SaveShares(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+
+	var shares []models.SubscriptionShare
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		var req []ShareRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apiBadRequest(c, ErrInvalidRequestBody)
+			return
+		}
+		for _, r := range req {
+			shares = append(shares, models.SubscriptionShare{Person: r.Person, ShareType: r.ShareType, Value: r.Value})
+		}
+	} else {
+		shares, err = parseShareForm(c)
+		if err != nil {
+			h.renderShareError(c, err.Error())
+			return
+		}
+	}
+
+	err = h.splits.SetShares(c.Request.Context(), uint(id), shares)
+	switch {
+	case errors.Is(err, service.ErrSplitSubscriptionNotFound):
+		apiNotFound(c, ErrSubscriptionNotFound)
+		return
+	case errors.Is(err, service.ErrInvalidShare):
+		h.renderShareError(c, err.Error())
+		return
+	case err != nil:
+		slog.Error("failed to save shares", "error", err, "id", id)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Refresh", "true")
+		c.Status(http.StatusOK)
+		return
+	}
+	saved, _ := h.splits.GetShares(uint(id))
+	c.JSON(http.StatusOK, saved)
+}
+
+// GetSettlement returns who owes what this month as JSON
+func (h *SplitHandler) GetSettlement(c *gin.Context) {
+	report, err := h.splits.GetSettlement(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to build settlement", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportSettlementCSV downloads this month's settlement as CSV
+func (h *SplitHandler) ExportSettlementCSV(c *gin.Context) {
+	report, err := h.splits.GetSettlement(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to build settlement", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternalServer})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename=settlement-"+report.Month+".csv")
+	if err := h.splits.WriteSettlementCSV(c.Writer, report); err != nil {
+		slog.Error("failed to write settlement CSV", "error", err)
+	}
+}
+
+// SettlementCard renders the dashboard card with the monthly settlement.
+// Renders nothing when no subscription is shared.
+func (h *SplitHandler) SettlementCard(c *gin.Context) {
+	report, err := h.splits.GetSettlement(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to build settlement", "error", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.HTML(http.StatusOK, "split-settlement.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Report":         report,
+		"CurrencySymbol": h.preferences.GetCurrencySymbol(),
+	}))
+}
+
+// SendSettlement sends this month's settlement through all notification channels
+func (h *SplitHandler) SendSettlement(c *gin.Context) {
+	report, err := h.splits.GetSettlement(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to build settlement", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	if len(report.People) == 0 {
+		apiBadRequest(c, "No shared subscriptions to settle")
+		return
+	}
+
+	if err := h.notifier.SendSettlementReport(report); err != nil {
+		slog.Error("failed to send settlement report", "error", err)
+		apiError(c, http.StatusBadGateway, "Failed to send settlement, check your notification settings")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sent": true})
+}
+
+// renderShareError reports an invalid split configuration to the editor or API client
+func (h *SplitHandler) renderShareError(c *gin.Context, message string) {
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Retarget", "#split-errors")
+		c.HTML(http.StatusBadRequest, "form-errors.html", mergeTemplateData(baseTemplateData(c), gin.H{
+			"Error": message,
+		}))
+		return
+	}
+	apiBadRequest(c, message)
+}
+
+// parseShareForm reads the parallel person/share_type/value arrays of the split editor.
+// Rows without a person are ignored.
+func parseShareForm(c *gin.Context) ([]models.SubscriptionShare, error) {
+	people := c.PostFormArray("person")
+	types := c.PostFormArray("share_type")
+	values := c.PostFormArray("value")
+	if len(types) != len(people) || len(values) != len(people) {
+		return nil, errors.New(ErrInvalidRequestBody)
+	}
+
+	var shares []models.SubscriptionShare
+	for i, person := range people {
+		if strings.TrimSpace(person) == "" {
+			continue
+		}
+		value, err := parseNumber(c, values[i])
+		if err != nil {
+			return nil, errors.New("Invalid share value for " + person)
+		}
+		shares = append(shares, models.SubscriptionShare{Person: person, ShareType: types[i], Value: value})
+	}
+	return shares, nil
+}
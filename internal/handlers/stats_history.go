@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatsHistoryHandler serves the daily statistics snapshots
+type StatsHistoryHandler struct {
+	history service.StatsHistoryServiceInterface
+}
+
+func NewStatsHistoryHandler(history service.StatsHistoryServiceInterface) *StatsHistoryHandler {
+	return &StatsHistoryHandler{history: history}
+}
+
+// GetHistory returns the snapshots of the last ?months= months (default 24), oldest first
+func (h *StatsHistoryHandler) GetHistory(c *gin.Context) {
+	months := service.DefaultStatsHistoryMonths
+	if value := c.Query("months"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > service.MaxStatsHistoryMonths {
+			apiBadRequest(c, "Invalid months (1-120)")
+			return
+		}
+		months = parsed
+	}
+
+	snapshots, err := h.history.History(months, time.Now())
+	if err != nil {
+		slog.Error("failed to load stats history", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"months":    months,
+		"snapshots": snapshots,
+	})
+}
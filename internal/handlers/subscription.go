@@ -23,20 +23,30 @@ type SubscriptionHandler struct {
 	settings        service.SettingsServiceInterface
 	calendarService service.CalendarServiceInterface
 	currencyService service.CurrencyServiceInterface
-	emailService    service.EmailServiceInterface
-	shoutrrrService service.ShoutrrrServiceInterface
+	notifier        service.NotificationDispatcherInterface
 	logoService     service.LogoServiceInterface
+	exportService   *service.ExportService
+	hooks           service.HookServiceInterface
+	defaults        service.SubscriptionDefaultsServiceInterface
+	logoQueue       service.LogoQueueServiceInterface
+	vendors         service.VendorServiceInterface
+	undo            service.UndoServiceInterface
 }
 
-func NewSubscriptionHandler(svc service.SubscriptionServiceInterface, preferences service.PreferencesServiceInterface, settings service.SettingsServiceInterface, calendarService service.CalendarServiceInterface, currencyService service.CurrencyServiceInterface, emailService service.EmailServiceInterface, shoutrrrService service.ShoutrrrServiceInterface, logoService service.LogoServiceInterface) *SubscriptionHandler {
+func NewSubscriptionHandler(svc service.SubscriptionServiceInterface, preferences service.PreferencesServiceInterface, settings service.SettingsServiceInterface, calendarService service.CalendarServiceInterface, currencyService service.CurrencyServiceInterface, notifier service.NotificationDispatcherInterface, logoService service.LogoServiceInterface, exportService *service.ExportService, hooks service.HookServiceInterface, defaults service.SubscriptionDefaultsServiceInterface, logoQueue service.LogoQueueServiceInterface, vendors service.VendorServiceInterface, undo service.UndoServiceInterface) *SubscriptionHandler {
 	return &SubscriptionHandler{
 		service:         svc,
 		preferences:     preferences,
 		settings:        settings,
 		calendarService: calendarService,
 		currencyService: currencyService,
-		emailService:    emailService,
-		shoutrrrService: shoutrrrService,
+		notifier:        notifier,
 		logoService:     logoService,
+		exportService:   exportService,
+		hooks:           hooks,
+		defaults:        defaults,
+		logoQueue:       logoQueue,
+		vendors:         vendors,
+		undo:            undo,
 	}
 }
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CancelSubscription marks a subscription as cancelled without editing the whole form
+func (h *SubscriptionHandler) CancelSubscription(c *gin.Context) {
+	h.quickAction(c, h.service.Cancel)
+}
+
+// PauseSubscription pauses a subscription
+func (h *SubscriptionHandler) PauseSubscription(c *gin.Context) {
+	h.quickAction(c, h.service.Pause)
+}
+
+// ResumeSubscription reactivates a paused or cancelled subscription
+func (h *SubscriptionHandler) ResumeSubscription(c *gin.Context) {
+	h.quickAction(c, h.service.Resume)
+}
+
+// quickAction applies a status change to the subscription in the path. HTMX
+// requests get a page refresh, API clients the updated subscription.
+func (h *SubscriptionHandler) quickAction(c *gin.Context, action func(ctx context.Context, id uint) (*models.Subscription, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+
+	updated, err := action(c.Request.Context(), uint(id))
+	switch {
+	case errors.Is(err, service.ErrSubscriptionNotFound):
+		apiNotFound(c, ErrSubscriptionNotFound)
+		return
+	case errors.Is(err, service.ErrInvalidStatusChange):
+		apiError(c, http.StatusConflict, tr(c, "quick_action_not_allowed", "This action is not available for the subscription's current status"))
+		return
+	case err != nil:
+		slog.Error("failed to change subscription status", "error", err, "id", id)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	h.hooks.Fire(service.EventSubscriptionUpdated, updated)
+
+	if c.GetHeader("HX-Request") != "" {
+		c.Header("HX-Refresh", "true")
+		c.Status(http.StatusOK)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
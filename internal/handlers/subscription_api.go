@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"subvault/internal/models"
+	"subvault/internal/service"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,40 +18,59 @@ import (
 // Required fields are enforced via binding tags.
 type CreateSubscriptionRequest struct {
 	Name                     string     `json:"name" binding:"required,max=255"`
-	Cost                     float64    `json:"cost" binding:"required,gt=0,max=1000000"`
-	Schedule                 string     `json:"schedule" binding:"required,oneof=Monthly Annual Weekly Daily Quarterly"`
+	Cost                     float64    `json:"cost" binding:"required,min=-1000000,max=1000000"`
+	Schedule                 string     `json:"schedule" binding:"omitempty,oneof=Monthly Annual Weekly Daily Quarterly"`
 	Status                   string     `json:"status" binding:"required,oneof=Active Cancelled Paused Trial"`
 	OriginalCurrency         string     `json:"original_currency" binding:"omitempty,max=10"`
 	CategoryID               uint       `json:"category_id"`
+	VendorID                 *uint      `json:"vendor_id"`
 	PaymentMethod            string     `json:"payment_method" binding:"omitempty,max=255"`
 	LoginName                string     `json:"login_name" binding:"omitempty,max=255"`
-	TaxRate                  float64    `json:"tax_rate" binding:"omitempty,min=0,max=100"`
+	TaxRate                  *float64   `json:"tax_rate" binding:"omitempty,min=0,max=100"`
 	PriceType                string     `json:"price_type" binding:"omitempty,oneof=gross net"`
 	CustomerNumber           string     `json:"customer_number" binding:"omitempty,max=255"`
 	ContractNumber           string     `json:"contract_number" binding:"omitempty,max=255"`
 	StartDate                *time.Time `json:"start_date"`
 	RenewalDate              *time.Time `json:"renewal_date"`
 	CancellationDate         *time.Time `json:"cancellation_date"`
+	PaidThroughDate          *time.Time `json:"paid_through_date"`
+	PromoCost                *float64   `json:"promo_cost" binding:"omitempty,min=-1000000,max=1000000"`
+	PromoEndDate             *time.Time `json:"promo_end_date"`
+	PromoEndReminder         bool       `json:"promo_end_reminder"`
+	PaymentFailed            bool       `json:"payment_failed"`
+	PaymentRetryDate         *time.Time `json:"payment_retry_date"`
+	GracePeriodEnd           *time.Time `json:"grace_period_end"`
+	ContractStartDate        *time.Time `json:"contract_start_date"`
+	ContractEndDate          *time.Time `json:"contract_end_date"`
+	MinimumTermMonths        int        `json:"minimum_term_months" binding:"omitempty,min=0,max=120"`
+	NoticePeriodDays         int        `json:"notice_period_days" binding:"omitempty,min=0,max=365"`
+	AutoRenew                *bool      `json:"auto_renew"`
 	URL                      string     `json:"url" binding:"omitempty,url,max=2048"`
 	IconURL                  string     `json:"icon_url" binding:"omitempty,url,max=2048"`
 	Notes                    string     `json:"notes" binding:"omitempty,max=5000"`
+	CancelURL                string     `json:"cancel_url" binding:"omitempty,url,max=2048"`
+	CancelNotes              string     `json:"cancel_notes" binding:"omitempty,max=5000"`
 	Usage                    string     `json:"usage" binding:"omitempty,oneof=High Medium Low None"`
-	RenewalReminder          bool       `json:"renewal_reminder"`
+	Purpose                  string     `json:"purpose" binding:"omitempty,oneof=personal business shared"`
+	Seats                    int        `json:"seats" binding:"omitempty,min=0,max=100"`
+	RenewalReminder          *bool      `json:"renewal_reminder"`
 	RenewalReminderDays      int        `json:"renewal_reminder_days" binding:"omitempty,min=1,max=365"`
-	CancellationReminder     bool       `json:"cancellation_reminder"`
+	CancellationReminder     *bool      `json:"cancellation_reminder"`
 	CancellationReminderDays int        `json:"cancellation_reminder_days" binding:"omitempty,min=1,max=365"`
 	HighCostAlert            bool       `json:"high_cost_alert"`
+	NotifyChannels           string     `json:"notify_channels" binding:"omitempty,max=64"`
 }
 
 // UpdateSubscriptionRequest is the DTO for partial updates via API.
 // All fields are pointers so we can distinguish between "not provided" (nil) and "set to zero value".
 type UpdateSubscriptionRequest struct {
 	Name                     *string    `json:"name" binding:"omitempty,max=255"`
-	Cost                     *float64   `json:"cost" binding:"omitempty,gt=0,max=1000000"`
+	Cost                     *float64   `json:"cost" binding:"omitempty,ne=0,min=-1000000,max=1000000"`
 	Schedule                 *string    `json:"schedule" binding:"omitempty,oneof=Monthly Annual Weekly Daily Quarterly"`
 	Status                   *string    `json:"status" binding:"omitempty,oneof=Active Cancelled Paused Trial"`
 	OriginalCurrency         *string    `json:"original_currency" binding:"omitempty,max=10"`
 	CategoryID               *uint      `json:"category_id"`
+	VendorID                 *uint      `json:"vendor_id"` // 0 removes the vendor
 	PaymentMethod            *string    `json:"payment_method" binding:"omitempty,max=255"`
 	LoginName                *string    `json:"login_name" binding:"omitempty,max=255"`
 	TaxRate                  *float64   `json:"tax_rate" binding:"omitempty,min=0,max=100"`
@@ -57,15 +80,32 @@ type UpdateSubscriptionRequest struct {
 	StartDate                *time.Time `json:"start_date"`
 	RenewalDate              *time.Time `json:"renewal_date"`
 	CancellationDate         *time.Time `json:"cancellation_date"`
+	PaidThroughDate          *time.Time `json:"paid_through_date"`
+	PromoCost                *float64   `json:"promo_cost" binding:"omitempty,min=-1000000,max=1000000"`
+	PromoEndDate             *time.Time `json:"promo_end_date"`
+	PromoEndReminder         *bool      `json:"promo_end_reminder"`
+	PaymentFailed            *bool      `json:"payment_failed"`
+	PaymentRetryDate         *time.Time `json:"payment_retry_date"`
+	GracePeriodEnd           *time.Time `json:"grace_period_end"`
+	ContractStartDate        *time.Time `json:"contract_start_date"`
+	ContractEndDate          *time.Time `json:"contract_end_date"`
+	MinimumTermMonths        *int       `json:"minimum_term_months" binding:"omitempty,min=0,max=120"`
+	NoticePeriodDays         *int       `json:"notice_period_days" binding:"omitempty,min=0,max=365"`
+	AutoRenew                *bool      `json:"auto_renew"`
 	URL                      *string    `json:"url" binding:"omitempty,url,max=2048"`
 	IconURL                  *string    `json:"icon_url" binding:"omitempty,url,max=2048"`
 	Notes                    *string    `json:"notes" binding:"omitempty,max=5000"`
+	CancelURL                *string    `json:"cancel_url" binding:"omitempty,url,max=2048"`
+	CancelNotes              *string    `json:"cancel_notes" binding:"omitempty,max=5000"`
 	Usage                    *string    `json:"usage" binding:"omitempty,oneof=High Medium Low None"`
+	Purpose                  *string    `json:"purpose" binding:"omitempty,oneof=personal business shared"`
+	Seats                    *int       `json:"seats" binding:"omitempty,min=0,max=100"`
 	RenewalReminder          *bool      `json:"renewal_reminder"`
 	RenewalReminderDays      *int       `json:"renewal_reminder_days" binding:"omitempty,min=1,max=365"`
 	CancellationReminder     *bool      `json:"cancellation_reminder"`
 	CancellationReminderDays *int       `json:"cancellation_reminder_days" binding:"omitempty,min=1,max=365"`
 	HighCostAlert            *bool      `json:"high_cost_alert"`
+	NotifyChannels           *string    `json:"notify_channels" binding:"omitempty,max=64"`
 }
 
 // CreateSubscriptionAPI handles creating a new subscription via JSON API
@@ -76,19 +116,53 @@ func (h *SubscriptionHandler) CreateSubscriptionAPI(c *gin.Context) {
 		return
 	}
 
-	// Default price type to "gross" if not provided
-	priceType := req.PriceType
-	if priceType == "" {
-		priceType = "gross"
+	created, err := h.createSubscription(c.Request.Context(), &req)
+	if errors.Is(err, errUnknownVendor) || errors.Is(err, errBadNotifyChannels) {
+		apiBadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		slog.Error("failed to create subscription via API", "error", err)
+		apiInternalError(c, "Failed to create subscription")
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// Invalid references in a subscription request, with the API's error messages
+var (
+	errUnknownVendor     = errors.New(ErrVendorNotFound)
+	errBadNotifyChannels = errors.New(ErrInvalidNotifyChannels)
+)
+
+// createSubscription creates a subscription from the create DTO and runs the
+// follow-ups of a new subscription
+func (h *SubscriptionHandler) createSubscription(ctx context.Context, req *CreateSubscriptionRequest) (*models.Subscription, error) {
+	subscription, err := h.subscriptionFromRequest(req)
+	if err != nil {
+		return nil, errBadNotifyChannels
+	}
+	vendorID, ok := h.knownVendor(req.VendorID)
+	if !ok {
+		return nil, errUnknownVendor
 	}
+	subscription.VendorID = vendorID
 
-	reminderDays := req.RenewalReminderDays
-	if reminderDays <= 0 {
-		reminderDays = 3
+	created, err := h.service.Create(ctx, &subscription)
+	if err != nil {
+		return nil, err
 	}
-	cancellationDays := req.CancellationReminderDays
-	if cancellationDays <= 0 {
-		cancellationDays = 7
+	h.afterCreate(ctx, created)
+	return created, nil
+}
+
+// subscriptionFromRequest builds a new subscription from the create DTO.
+// Omitted fields take the configured defaults.
+func (h *SubscriptionHandler) subscriptionFromRequest(req *CreateSubscriptionRequest) (models.Subscription, error) {
+	notifyChannels, err := models.NormalizeNotifyChannels(req.NotifyChannels)
+	if err != nil {
+		return models.Subscription{}, err
 	}
 
 	subscription := models.Subscription{
@@ -100,51 +174,57 @@ func (h *SubscriptionHandler) CreateSubscriptionAPI(c *gin.Context) {
 		CategoryID:               req.CategoryID,
 		PaymentMethod:            req.PaymentMethod,
 		LoginName:                req.LoginName,
-		TaxRate:                  req.TaxRate,
-		PriceType:                priceType,
+		PriceType:                req.PriceType,
 		CustomerNumber:           req.CustomerNumber,
 		ContractNumber:           req.ContractNumber,
 		StartDate:                req.StartDate,
 		RenewalDate:              req.RenewalDate,
 		CancellationDate:         req.CancellationDate,
+		PaidThroughDate:          req.PaidThroughDate,
+		PromoCost:                req.PromoCost,
+		PromoEndDate:             req.PromoEndDate,
+		PromoEndReminder:         req.PromoEndReminder,
+		ContractStartDate:        req.ContractStartDate,
+		ContractEndDate:          req.ContractEndDate,
+		MinimumTermMonths:        req.MinimumTermMonths,
+		NoticePeriodDays:         req.NoticePeriodDays,
 		URL:                      req.URL,
 		IconURL:                  req.IconURL,
 		Notes:                    req.Notes,
+		CancelURL:                req.CancelURL,
+		CancelNotes:              req.CancelNotes,
 		Usage:                    req.Usage,
-		RenewalReminder:          req.RenewalReminder,
-		RenewalReminderDays:      reminderDays,
-		CancellationReminder:     req.CancellationReminder,
-		CancellationReminderDays: cancellationDays,
+		Purpose:                  req.Purpose,
+		Seats:                    req.Seats,
+		RenewalReminderDays:      req.RenewalReminderDays,
+		CancellationReminderDays: req.CancellationReminderDays,
 		HighCostAlert:            req.HighCostAlert,
+		NotifyChannels:           notifyChannels,
 	}
 
-	if subscription.OriginalCurrency == "" {
-		subscription.OriginalCurrency = "USD"
-	}
-
-	h.fetchAndSetLogo(&subscription)
+	defaults := h.defaults.Get()
+	subscription.TaxRate = valueOr(req.TaxRate, defaults.TaxRate)
+	subscription.RenewalReminder = valueOr(req.RenewalReminder, defaults.RenewalReminder)
+	subscription.CancellationReminder = valueOr(req.CancellationReminder, defaults.CancellationReminder)
+	subscription.AutoRenew = valueOr(req.AutoRenew, true)
+	h.defaults.Apply(&subscription)
+	if req.PaymentFailed || req.PaymentRetryDate != nil || req.GracePeriodEnd != nil {
+		subscription.MarkPaymentFailed(time.Now(), req.PaymentRetryDate, req.GracePeriodEnd)
+	}
+	return subscription, nil
+}
 
-	created, err := h.service.Create(&subscription)
-	if err != nil {
-		slog.Error("failed to create subscription via API", "error", err)
-		apiInternalError(c, "Failed to create subscription")
-		return
-	}
+// afterCreate queues the logo lookup, sends the high-cost alert and fires the
+// hooks for a new subscription
+func (h *SubscriptionHandler) afterCreate(ctx context.Context, created *models.Subscription) {
+	h.queueLogo(ctx, created, nil)
 
 	// Send high-cost alert if applicable (per-subscription setting)
 	if created.HighCostAlert && h.isHighCostWithCurrency(created) {
-		subscriptionWithCategory, err := h.service.GetByID(created.ID)
-		if err == nil && subscriptionWithCategory != nil {
-			if err := h.emailService.SendHighCostAlert(subscriptionWithCategory); err != nil {
-				slog.Error("failed to send high-cost alert email", "error", err)
-			}
-			if err := h.shoutrrrService.SendHighCostAlert(subscriptionWithCategory); err != nil {
-				slog.Error("failed to send high-cost alert shoutrrr notification", "error", err)
-			}
-		}
+		h.sendHighCostAlert(ctx, created.ID)
 	}
 
-	c.JSON(http.StatusCreated, created)
+	h.hooks.Fire(service.EventSubscriptionCreated, created)
 }
 
 // UpdateSubscriptionAPI handles partial updates to a subscription via JSON API
@@ -155,7 +235,7 @@ func (h *SubscriptionHandler) UpdateSubscriptionAPI(c *gin.Context) {
 		return
 	}
 
-	original, err := h.service.GetByID(uint(id))
+	original, err := h.service.GetByID(c.Request.Context(), uint(id))
 	if err != nil {
 		apiNotFound(c, ErrSubscriptionNotFound)
 		return
@@ -167,8 +247,54 @@ func (h *SubscriptionHandler) UpdateSubscriptionAPI(c *gin.Context) {
 		return
 	}
 
+	updated, err := h.updateSubscription(c.Request.Context(), original, &req)
+	if errors.Is(err, errUnknownVendor) || errors.Is(err, errBadNotifyChannels) {
+		apiBadRequest(c, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrInvalidStatusChange) {
+		apiError(c, http.StatusConflict, fmt.Sprintf("Status cannot change from %s to %s", original.Status, valueOr(req.Status, original.Status)))
+		return
+	}
+	if err != nil {
+		slog.Error("failed to update subscription via API", "error", err, "id", id)
+		apiInternalError(c, "Failed to update subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// updateSubscription applies the provided fields of the update DTO to a
+// subscription, saves it and runs the follow-ups of a changed subscription
+func (h *SubscriptionHandler) updateSubscription(ctx context.Context, original *models.Subscription, req *UpdateSubscriptionRequest) (*models.Subscription, error) {
 	wasHighCost := h.isHighCostWithCurrency(original)
+	subscription, err := h.mergeUpdate(original, req)
+	if err != nil {
+		return nil, err
+	}
 
+	updated, err := h.service.Update(ctx, original.ID, &subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	if updated != nil {
+		h.queueLogo(ctx, updated, original)
+	}
+
+	// Send high-cost alert if subscription became high-cost (per-subscription setting)
+	if updated != nil && updated.HighCostAlert && !wasHighCost && h.isHighCostWithCurrency(updated) {
+		h.sendHighCostAlert(ctx, updated.ID)
+	}
+
+	h.hooks.Fire(service.EventSubscriptionUpdated, updated)
+	return updated, nil
+}
+
+// mergeUpdate returns a copy of original with the provided fields of the
+// update DTO applied
+func (h *SubscriptionHandler) mergeUpdate(original *models.Subscription, req *UpdateSubscriptionRequest) (models.Subscription, error) {
 	// Merge: only overwrite fields that were provided (non-nil)
 	subscription := *original
 	if req.Name != nil {
@@ -189,6 +315,13 @@ func (h *SubscriptionHandler) UpdateSubscriptionAPI(c *gin.Context) {
 	if req.CategoryID != nil {
 		subscription.CategoryID = *req.CategoryID
 	}
+	if req.VendorID != nil {
+		vendorID, ok := h.knownVendor(req.VendorID)
+		if !ok {
+			return subscription, errUnknownVendor
+		}
+		subscription.VendorID = vendorID
+	}
 	if req.PaymentMethod != nil {
 		subscription.PaymentMethod = *req.PaymentMethod
 	}
@@ -216,6 +349,46 @@ func (h *SubscriptionHandler) UpdateSubscriptionAPI(c *gin.Context) {
 	if req.CancellationDate != nil {
 		subscription.CancellationDate = req.CancellationDate
 	}
+	if req.PaidThroughDate != nil {
+		subscription.PaidThroughDate = req.PaidThroughDate
+	}
+	if req.PromoCost != nil {
+		subscription.PromoCost = req.PromoCost
+	}
+	if req.PromoEndDate != nil {
+		subscription.PromoEndDate = req.PromoEndDate
+	}
+	if req.PromoEndReminder != nil {
+		subscription.PromoEndReminder = *req.PromoEndReminder
+	}
+	// payment_failed false resolves a failed payment; a retry or cutoff date marks one
+	if req.PaymentFailed != nil && !*req.PaymentFailed {
+		subscription.ResolvePaymentFailure()
+	} else if req.PaymentFailed != nil || req.PaymentRetryDate != nil || req.GracePeriodEnd != nil {
+		retryDate, gracePeriodEnd := subscription.PaymentRetryDate, subscription.GracePeriodEnd
+		if req.PaymentRetryDate != nil {
+			retryDate = req.PaymentRetryDate
+		}
+		if req.GracePeriodEnd != nil {
+			gracePeriodEnd = req.GracePeriodEnd
+		}
+		subscription.MarkPaymentFailed(time.Now(), retryDate, gracePeriodEnd)
+	}
+	if req.ContractStartDate != nil {
+		subscription.ContractStartDate = req.ContractStartDate
+	}
+	if req.ContractEndDate != nil {
+		subscription.ContractEndDate = req.ContractEndDate
+	}
+	if req.MinimumTermMonths != nil {
+		subscription.MinimumTermMonths = *req.MinimumTermMonths
+	}
+	if req.NoticePeriodDays != nil {
+		subscription.NoticePeriodDays = *req.NoticePeriodDays
+	}
+	if req.AutoRenew != nil {
+		subscription.AutoRenew = *req.AutoRenew
+	}
 	if req.URL != nil {
 		subscription.URL = *req.URL
 	}
@@ -225,9 +398,21 @@ func (h *SubscriptionHandler) UpdateSubscriptionAPI(c *gin.Context) {
 	if req.Notes != nil {
 		subscription.Notes = *req.Notes
 	}
+	if req.CancelURL != nil {
+		subscription.CancelURL = *req.CancelURL
+	}
+	if req.CancelNotes != nil {
+		subscription.CancelNotes = *req.CancelNotes
+	}
 	if req.Usage != nil {
 		subscription.Usage = *req.Usage
 	}
+	if req.Purpose != nil {
+		subscription.Purpose = *req.Purpose
+	}
+	if req.Seats != nil {
+		subscription.Seats = *req.Seats
+	}
 	if req.RenewalReminder != nil {
 		subscription.RenewalReminder = *req.RenewalReminder
 	}
@@ -243,34 +428,15 @@ func (h *SubscriptionHandler) UpdateSubscriptionAPI(c *gin.Context) {
 	if req.HighCostAlert != nil {
 		subscription.HighCostAlert = *req.HighCostAlert
 	}
-
-	// Fetch logo if URL changed or new URL without icon
-	urlChanged := req.URL != nil && original.URL != subscription.URL
-	if urlChanged || (subscription.URL != "" && subscription.IconURL == "") {
-		h.fetchAndSetLogo(&subscription)
-	}
-
-	updated, err := h.service.Update(uint(id), &subscription)
-	if err != nil {
-		slog.Error("failed to update subscription via API", "error", err, "id", id)
-		apiInternalError(c, "Failed to update subscription")
-		return
-	}
-
-	// Send high-cost alert if subscription became high-cost (per-subscription setting)
-	if updated != nil && updated.HighCostAlert && !wasHighCost && h.isHighCostWithCurrency(updated) {
-		subscriptionWithCategory, err := h.service.GetByID(updated.ID)
-		if err == nil && subscriptionWithCategory != nil {
-			if err := h.emailService.SendHighCostAlert(subscriptionWithCategory); err != nil {
-				slog.Error("failed to send high-cost alert email", "error", err)
-			}
-			if err := h.shoutrrrService.SendHighCostAlert(subscriptionWithCategory); err != nil {
-				slog.Error("failed to send high-cost alert shoutrrr notification", "error", err)
-			}
+	if req.NotifyChannels != nil {
+		notifyChannels, err := models.NormalizeNotifyChannels(*req.NotifyChannels)
+		if err != nil {
+			return subscription, errBadNotifyChannels
 		}
+		subscription.NotifyChannels = notifyChannels
 	}
 
-	c.JSON(http.StatusOK, updated)
+	return subscription, nil
 }
 
 // DeleteSubscriptionAPI handles deleting a subscription via JSON API
@@ -282,14 +448,13 @@ func (h *SubscriptionHandler) DeleteSubscriptionAPI(c *gin.Context) {
 	}
 
 	// Check if subscription exists first (for proper 404)
-	_, err = h.service.GetByID(uint(id))
+	deleted, err := h.service.GetByID(c.Request.Context(), uint(id))
 	if err != nil {
 		apiNotFound(c, ErrSubscriptionNotFound)
 		return
 	}
 
-	err = h.service.Delete(uint(id))
-	if err != nil {
+	if err := h.deleteSubscription(c.Request.Context(), deleted); err != nil {
 		slog.Error("failed to delete subscription via API", "error", err, "id", id)
 		apiInternalError(c, "Failed to delete subscription")
 		return
@@ -297,3 +462,112 @@ func (h *SubscriptionHandler) DeleteSubscriptionAPI(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+// deleteSubscription deletes a subscription and fires the hooks
+func (h *SubscriptionHandler) deleteSubscription(ctx context.Context, deleted *models.Subscription) error {
+	if err := h.service.Delete(ctx, deleted.ID); err != nil {
+		return err
+	}
+	h.hooks.Fire(service.EventSubscriptionDeleted, deleted)
+	return nil
+}
+
+// maxOccurrenceDays limits the range of projected renewal dates returned at once
+const maxOccurrenceDays = 5 * 366
+
+// SubscriptionOccurrence is a projected billing date of a subscription. Amount
+// is charged in the subscription currency, including tax for net prices;
+// DisplayAmount is converted to the display currency when a rate is available.
+type SubscriptionOccurrence struct {
+	Date            string   `json:"date"`
+	Amount          float64  `json:"amount"`
+	Currency        string   `json:"currency"`
+	DisplayAmount   *float64 `json:"display_amount,omitempty"`
+	DisplayCurrency string   `json:"display_currency,omitempty"`
+}
+
+// SubscriptionOccurrencesResponse lists the projected billing dates of a subscription
+type SubscriptionOccurrencesResponse struct {
+	SubscriptionID uint                     `json:"subscription_id"`
+	Name           string                   `json:"name"`
+	Schedule       string                   `json:"schedule"`
+	Status         string                   `json:"status"`
+	From           string                   `json:"from"`
+	To             string                   `json:"to"`
+	Occurrences    []SubscriptionOccurrence `json:"occurrences"`
+}
+
+// GetSubscriptionOccurrencesAPI returns the projected billing dates of a
+// subscription between from and to (YYYY-MM-DD, both inclusive; default the
+// next 12 months), projected from its renewal date like the calendar. Only
+// active and trial subscriptions bill.
+func (h *SubscriptionHandler) GetSubscriptionOccurrencesAPI(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to := today, today.AddDate(1, 0, -1)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse("2006-01-02", value); err != nil {
+			apiBadRequest(c, "Invalid from, use YYYY-MM-DD")
+			return
+		}
+		if c.Query("to") == "" {
+			to = from.AddDate(1, 0, -1)
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse("2006-01-02", value); err != nil {
+			apiBadRequest(c, "Invalid to, use YYYY-MM-DD")
+			return
+		}
+	}
+	if to.Before(from) {
+		apiBadRequest(c, "to must not be before from")
+		return
+	}
+	if to.Sub(from).Hours()/24 >= maxOccurrenceDays {
+		apiBadRequest(c, "Range too long, at most 5 years")
+		return
+	}
+
+	sub, err := h.service.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		apiNotFound(c, ErrSubscriptionNotFound)
+		return
+	}
+
+	response := SubscriptionOccurrencesResponse{
+		SubscriptionID: sub.ID,
+		Name:           sub.Name,
+		Schedule:       sub.Schedule,
+		Status:         sub.Status,
+		From:           from.Format("2006-01-02"),
+		To:             to.Format("2006-01-02"),
+		Occurrences:    []SubscriptionOccurrence{},
+	}
+	if sub.RenewalDate == nil || (sub.Status != "Active" && sub.Status != "Trial") {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	displayCurrency := h.preferences.GetCurrency()
+	for _, date := range models.ProjectRenewalDates(*sub.RenewalDate, sub.Schedule, from, to.AddDate(0, 0, 1)) {
+		// A promotional price ends within the range, so each date has its own amount
+		amount := sub.GrossCostAt(date)
+		occurrence := SubscriptionOccurrence{
+			Date:     date.Format("2006-01-02"),
+			Amount:   amount,
+			Currency: sub.OriginalCurrency,
+		}
+		if converted, err := h.currencyService.ConvertAmount(amount, sub.OriginalCurrency, displayCurrency); err == nil {
+			occurrence.DisplayAmount = &converted
+			occurrence.DisplayCurrency = displayCurrency
+		}
+		response.Occurrences = append(response.Occurrences, occurrence)
+	}
+	c.JSON(http.StatusOK, response)
+}
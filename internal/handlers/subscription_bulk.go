@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"subvault/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxBulkSubscriptions limits the number of subscriptions created in one bulk request
+const maxBulkSubscriptions = 500
+
+// Bulk item statuses
+const (
+	BulkStatusCreated = "created"
+	BulkStatusFailed  = "failed"
+	// BulkStatusSkipped marks valid items that were not created because another
+	// item of a transactional request failed
+	BulkStatusSkipped = "skipped"
+)
+
+// BulkItemResult is the outcome for one item of a bulk create, in request order
+type BulkItemResult struct {
+	Index        int                  `json:"index"`
+	Status       string               `json:"status"`
+	Error        string               `json:"error,omitempty"`
+	Subscription *models.Subscription `json:"subscription,omitempty"`
+}
+
+// BulkCreateResponse summarizes a bulk create
+type BulkCreateResponse struct {
+	Transactional bool             `json:"transactional"`
+	Created       int              `json:"created"`
+	Failed        int              `json:"failed"`
+	Results       []BulkItemResult `json:"results"`
+}
+
+// BulkCreateSubscriptionsAPI creates many subscriptions from a JSON array of
+// create requests. Each item is validated on its own and reported in the
+// results. With ?transactional=true either all items are created or none.
+// Logos are looked up in the background afterwards.
+func (h *SubscriptionHandler) BulkCreateSubscriptionsAPI(c *gin.Context) {
+	transactional, _ := strconv.ParseBool(c.Query("transactional"))
+
+	var items []json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodySize)).Decode(&items); err != nil {
+		apiBadRequest(c, "Invalid request body. Send a JSON array of subscriptions.")
+		return
+	}
+	if len(items) == 0 {
+		apiBadRequest(c, "No subscriptions in request")
+		return
+	}
+	if len(items) > maxBulkSubscriptions {
+		apiBadRequest(c, "Too many subscriptions, at most "+strconv.Itoa(maxBulkSubscriptions)+" per request")
+		return
+	}
+
+	response := BulkCreateResponse{Transactional: transactional, Results: make([]BulkItemResult, len(items))}
+	subscriptions := make([]*models.Subscription, len(items))
+
+	for i, item := range items {
+		response.Results[i] = BulkItemResult{Index: i}
+
+		var req CreateSubscriptionRequest
+		if err := json.Unmarshal(item, &req); err != nil {
+			h.bulkFail(&response, i, "Invalid subscription. Check field types.")
+			continue
+		}
+		if err := binding.Validator.ValidateStruct(&req); err != nil {
+			h.bulkFail(&response, i, "Invalid subscription. Check required fields and value constraints.")
+			continue
+		}
+		subscription, err := h.subscriptionFromRequest(&req)
+		if err != nil {
+			h.bulkFail(&response, i, ErrInvalidNotifyChannels)
+			continue
+		}
+		subscriptions[i] = &subscription
+	}
+
+	if transactional {
+		h.bulkCreateAll(c, &response, subscriptions)
+		return
+	}
+
+	for i, subscription := range subscriptions {
+		if subscription == nil {
+			continue
+		}
+		created, err := h.service.Create(c.Request.Context(), subscription)
+		if err != nil {
+			slog.Error("failed to create subscription via bulk API", "index", i, "error", err)
+			h.bulkFail(&response, i, "Failed to create subscription")
+			continue
+		}
+		h.bulkCreated(c.Request.Context(), &response, i, created)
+	}
+
+	status := http.StatusCreated
+	if response.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, response)
+}
+
+// bulkCreateAll creates the valid subscriptions in one transaction, or none of
+// them when any item failed validation
+func (h *SubscriptionHandler) bulkCreateAll(c *gin.Context, response *BulkCreateResponse, subscriptions []*models.Subscription) {
+	if response.Failed == 0 {
+		if err := h.service.CreateAll(c.Request.Context(), subscriptions); err != nil {
+			slog.Error("bulk create failed, transaction rolled back", "count", len(subscriptions), "error", err)
+			apiInternalError(c, "Failed to create subscriptions, nothing was created")
+			return
+		}
+		for i, subscription := range subscriptions {
+			h.bulkCreated(c.Request.Context(), response, i, subscription)
+		}
+		c.JSON(http.StatusCreated, response)
+		return
+	}
+
+	for i, subscription := range subscriptions {
+		if subscription != nil {
+			response.Results[i].Status = BulkStatusSkipped
+		}
+	}
+	c.JSON(http.StatusUnprocessableEntity, response)
+}
+
+func (h *SubscriptionHandler) bulkCreated(ctx context.Context, response *BulkCreateResponse, i int, created *models.Subscription) {
+	response.Results[i].Status = BulkStatusCreated
+	response.Results[i].Subscription = created
+	response.Created++
+	h.afterCreate(ctx, created)
+}
+
+func (h *SubscriptionHandler) bulkFail(response *BulkCreateResponse, i int, message string) {
+	response.Results[i].Status = BulkStatusFailed
+	response.Results[i].Error = message
+	response.Failed++
+}
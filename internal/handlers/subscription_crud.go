@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
 
 	"subvault/internal/models"
+	"subvault/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,7 +19,7 @@ func (h *SubscriptionHandler) GetSubscriptions(c *gin.Context) {
 	order := c.DefaultQuery("order", "desc")
 
 	// Get sorted subscriptions
-	subscriptions, err := h.service.GetAllSorted(sortBy, order)
+	subscriptions, err := h.service.GetAllSorted(c.Request.Context(), sortBy, order)
 	if err != nil {
 		slog.Error("failed to get subscriptions", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
@@ -38,10 +40,16 @@ func (h *SubscriptionHandler) GetSubscriptions(c *gin.Context) {
 }
 
 // GetSubscriptionsAPI returns subscriptions as JSON for API calls with pagination.
+// The optional purpose query parameter limits the list to one purpose.
 func (h *SubscriptionHandler) GetSubscriptionsAPI(c *gin.Context) {
 	limit, offset := parsePagination(c)
+	purpose := c.Query("purpose")
+	if purpose != "" && !models.IsValidPurpose(purpose) {
+		apiBadRequest(c, ErrInvalidPurpose)
+		return
+	}
 
-	subscriptions, total, err := h.service.GetAllPaginated(limit, offset)
+	subscriptions, total, err := h.service.GetAllPaginated(c.Request.Context(), limit, offset, purpose)
 	if err != nil {
 		slog.Error("failed to get subscriptions via API", "error", err)
 		apiInternalError(c, "Failed to retrieve subscriptions")
@@ -66,7 +74,7 @@ func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
 		return
 	}
 
-	subscription, err := h.service.GetByID(uint(id))
+	subscription, err := h.service.GetByID(c.Request.Context(), uint(id))
 	if err != nil {
 		apiNotFound(c, ErrSubscriptionNotFound)
 		return
@@ -90,9 +98,6 @@ func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 	subscription.Schedule = c.PostForm("schedule")
 	subscription.Status = c.PostForm("status")
 	subscription.OriginalCurrency = c.PostForm("original_currency")
-	if subscription.OriginalCurrency == "" {
-		subscription.OriginalCurrency = "USD" // Default to USD
-	}
 	subscription.PaymentMethod = c.PostForm("payment_method")
 	subscription.LoginName = c.PostForm("login_name")
 	subscription.CustomerNumber = c.PostForm("customer_number")
@@ -100,54 +105,59 @@ func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 	subscription.URL = c.PostForm("url")
 	subscription.IconURL = c.PostForm("icon_url") // Allow manual icon URL override
 	subscription.Notes = c.PostForm("notes")
+	subscription.CancelURL = c.PostForm("cancel_url")
+	subscription.CancelNotes = c.PostForm("cancel_notes")
 	subscription.Usage = c.PostForm("usage")
+	subscription.Purpose = formPurpose(c)
+	subscription.Seats = min(formNonNegativeInt(c, "seats"), maxSeats)
+	subscription.VendorID = h.formVendor(c)
 
-	// Parse cost
-	if costStr := c.PostForm("cost"); costStr != "" {
-		if cost, err := strconv.ParseFloat(costStr, 64); err == nil {
-			subscription.Cost = cost
-		}
-	}
-
-	// Parse tax rate
-	if taxRateStr := c.PostForm("tax_rate"); taxRateStr != "" {
-		if taxRate, err := strconv.ParseFloat(taxRateStr, 64); err == nil {
-			subscription.TaxRate = taxRate
-		}
+	// Parse cost and tax rate
+	if err := formAmounts(c, &subscription); err != nil {
+		c.Header("HX-Retarget", "#form-errors")
+		c.HTML(http.StatusBadRequest, "form-errors.html", gin.H{
+			"Error": amountErrorMessage(c, err),
+		})
+		return
 	}
 
-	// Parse price type (default to "gross")
-	priceType := c.PostForm("price_type")
-	if priceType == "" {
-		priceType = "gross"
-	}
-	subscription.PriceType = priceType
+	subscription.PriceType = c.PostForm("price_type")
 
 	// Parse dates using helper function
 	subscription.StartDate = parseDatePtr(c.PostForm("start_date"))
 	subscription.RenewalDate = parseDatePtr(c.PostForm("renewal_date"))
 	subscription.CancellationDate = parseDatePtr(c.PostForm("cancellation_date"))
+	subscription.PaidThroughDate = parseDatePtr(c.PostForm("paid_through_date"))
 
 	// Parse per-subscription notification settings
 	subscription.RenewalReminder = c.PostForm("renewal_reminder") == "on"
 	if days, err := strconv.Atoi(c.PostForm("renewal_reminder_days")); err == nil && days > 0 {
 		subscription.RenewalReminderDays = days
-	} else {
-		subscription.RenewalReminderDays = 3
 	}
 	subscription.CancellationReminder = c.PostForm("cancellation_reminder") == "on"
 	if days, err := strconv.Atoi(c.PostForm("cancellation_reminder_days")); err == nil && days > 0 {
 		subscription.CancellationReminderDays = days
-	} else {
-		subscription.CancellationReminderDays = 7
 	}
 	subscription.HighCostAlert = c.PostForm("high_cost_alert") == "on"
 
-	// Fetch logo synchronously before creation if URL is provided and icon_url is empty
-	h.fetchAndSetLogo(&subscription)
+	// Fill missing currency, price type, reminder days etc. from the configured defaults
+	h.defaults.Apply(&subscription)
+	notifyChannels, err := parseNotifyChannels(c)
+	if err != nil {
+		c.Header("HX-Retarget", "#form-errors")
+		c.HTML(http.StatusBadRequest, "form-errors.html", gin.H{
+			"Error": tr(c, "sub_form_error_notify_channels", "Select at least one notification channel"),
+		})
+		return
+	}
+	subscription.NotifyChannels = notifyChannels
+
+	formPaymentFailure(c, &subscription, nil)
+	formContract(c, &subscription, nil)
+	formPromo(c, &subscription, nil)
 
 	// Create subscription
-	created, err := h.service.Create(&subscription)
+	created, err := h.service.Create(c.Request.Context(), &subscription)
 	if err != nil {
 		// Log the error for debugging
 		slog.Error("failed to create subscription", "error", err)
@@ -164,21 +174,17 @@ func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 		return
 	}
 
-	// Send high-cost alert email and Shoutrrr notification if applicable (per-subscription setting)
+	h.queueLogo(c.Request.Context(), created, nil)
+
+	// Send the high-cost alert through the notification channels if applicable (per-subscription setting)
 	if created.HighCostAlert && h.isHighCostWithCurrency(created) {
-		subscriptionWithCategory, err := h.service.GetByID(created.ID)
-		if err == nil && subscriptionWithCategory != nil {
-			if err := h.emailService.SendHighCostAlert(subscriptionWithCategory); err != nil {
-				slog.Error("failed to send high-cost alert email", "error", err)
-			}
-			if err := h.shoutrrrService.SendHighCostAlert(subscriptionWithCategory); err != nil {
-				slog.Error("failed to send high-cost alert shoutrrr notification", "error", err)
-			}
-		}
+		h.sendHighCostAlert(c.Request.Context(), created.ID)
 	}
 
+	h.hooks.Fire(service.EventSubscriptionCreated, created)
+
 	// Check budget after creating subscription
-	h.checkBudgetExceeded()
+	h.checkBudgetExceeded(c.Request.Context())
 
 	if c.GetHeader("HX-Request") != "" {
 		c.Header("HX-Refresh", "true")
@@ -209,9 +215,6 @@ func (h *SubscriptionHandler) UpdateSubscription(c *gin.Context) {
 	subscription.Schedule = c.PostForm("schedule")
 	subscription.Status = c.PostForm("status")
 	subscription.OriginalCurrency = c.PostForm("original_currency")
-	if subscription.OriginalCurrency == "" {
-		subscription.OriginalCurrency = "USD" // Default to USD
-	}
 	subscription.PaymentMethod = c.PostForm("payment_method")
 	subscription.LoginName = c.PostForm("login_name")
 	subscription.CustomerNumber = c.PostForm("customer_number")
@@ -219,90 +222,97 @@ func (h *SubscriptionHandler) UpdateSubscription(c *gin.Context) {
 	subscription.URL = c.PostForm("url")
 	subscription.IconURL = c.PostForm("icon_url") // Allow manual icon URL override
 	subscription.Notes = c.PostForm("notes")
+	subscription.CancelURL = c.PostForm("cancel_url")
+	subscription.CancelNotes = c.PostForm("cancel_notes")
 	subscription.Usage = c.PostForm("usage")
+	subscription.Purpose = formPurpose(c)
+	subscription.Seats = min(formNonNegativeInt(c, "seats"), maxSeats)
+	subscription.VendorID = h.formVendor(c)
 
-	// Parse cost
-	if costStr := c.PostForm("cost"); costStr != "" {
-		if cost, err := strconv.ParseFloat(costStr, 64); err == nil {
-			subscription.Cost = cost
-		}
-	}
-
-	// Parse tax rate
-	if taxRateStr := c.PostForm("tax_rate"); taxRateStr != "" {
-		if taxRate, err := strconv.ParseFloat(taxRateStr, 64); err == nil {
-			subscription.TaxRate = taxRate
-		}
+	// Parse cost and tax rate
+	if err := formAmounts(c, &subscription); err != nil {
+		c.Header("HX-Retarget", "#form-errors")
+		c.HTML(http.StatusBadRequest, "form-errors.html", gin.H{
+			"Error": amountErrorMessage(c, err),
+		})
+		return
 	}
 
-	// Parse price type (default to "gross")
-	priceType := c.PostForm("price_type")
-	if priceType == "" {
-		priceType = "gross"
-	}
-	subscription.PriceType = priceType
+	subscription.PriceType = c.PostForm("price_type")
 
 	// Parse dates using helper function
 	// Always parse renewal date if provided; let service/model layer handle schedule change logic
 	subscription.StartDate = parseDatePtr(c.PostForm("start_date"))
 	subscription.RenewalDate = parseDatePtr(c.PostForm("renewal_date"))
 	subscription.CancellationDate = parseDatePtr(c.PostForm("cancellation_date"))
+	subscription.PaidThroughDate = parseDatePtr(c.PostForm("paid_through_date"))
 
 	// Parse per-subscription notification settings
 	subscription.RenewalReminder = c.PostForm("renewal_reminder") == "on"
 	if days, err := strconv.Atoi(c.PostForm("renewal_reminder_days")); err == nil && days > 0 {
 		subscription.RenewalReminderDays = days
-	} else {
-		subscription.RenewalReminderDays = 3
 	}
 	subscription.CancellationReminder = c.PostForm("cancellation_reminder") == "on"
 	if days, err := strconv.Atoi(c.PostForm("cancellation_reminder_days")); err == nil && days > 0 {
 		subscription.CancellationReminderDays = days
-	} else {
-		subscription.CancellationReminderDays = 7
 	}
 	subscription.HighCostAlert = c.PostForm("high_cost_alert") == "on"
 
+	// Fill missing currency, price type, reminder days etc. from the configured defaults
+	h.defaults.Apply(&subscription)
+	notifyChannels, err := parseNotifyChannels(c)
+	if err != nil {
+		c.Header("HX-Retarget", "#form-errors")
+		c.HTML(http.StatusBadRequest, "form-errors.html", gin.H{
+			"Error": tr(c, "sub_form_error_notify_channels", "Select at least one notification channel"),
+		})
+		return
+	}
+	subscription.NotifyChannels = notifyChannels
+
 	// Get the original subscription to check if it was high-cost before update
-	original, _ := h.service.GetByID(uint(id))
+	original, _ := h.service.GetByID(c.Request.Context(), uint(id))
 	wasHighCost := original != nil && h.isHighCostWithCurrency(original)
 
+	formPaymentFailure(c, &subscription, original)
+	formContract(c, &subscription, original)
+	formPromo(c, &subscription, original)
+	if original != nil {
+		subscription.LastPaidThroughReminderDate = original.LastPaidThroughReminderDate
+	}
+
 	// Preserve existing IconURL if not explicitly set in form
 	if subscription.IconURL == "" && original != nil {
 		subscription.IconURL = original.IconURL
 	}
 
-	// Check if URL changed - if so, we should fetch a new logo
-	urlChanged := original != nil && original.URL != subscription.URL
-	if urlChanged || (subscription.URL != "" && subscription.IconURL == "") {
-		h.fetchAndSetLogo(&subscription)
-	}
-
 	// Update subscription
-	updated, err := h.service.Update(uint(id), &subscription)
+	updated, err := h.service.Update(c.Request.Context(), uint(id), &subscription)
 	if err != nil {
+		message := err.Error()
+		if errors.Is(err, service.ErrInvalidStatusChange) {
+			message = tr(c, "sub_form_error_status_change", "A cancelled subscription cannot be paused. Make it active first.")
+		}
 		c.Header("HX-Retarget", "#form-errors")
 		c.HTML(http.StatusBadRequest, "form-errors.html", gin.H{
-			"Error": err.Error(),
+			"Error": message,
 		})
 		return
 	}
 
+	if updated != nil {
+		h.queueLogo(c.Request.Context(), updated, original)
+	}
+
 	// Send high-cost alert if subscription became high-cost (per-subscription setting)
 	if updated != nil && updated.HighCostAlert && !wasHighCost && h.isHighCostWithCurrency(updated) {
-		subscriptionWithCategory, err := h.service.GetByID(updated.ID)
-		if err == nil && subscriptionWithCategory != nil {
-			if err := h.emailService.SendHighCostAlert(subscriptionWithCategory); err != nil {
-				slog.Error("failed to send high-cost alert email", "error", err)
-			}
-			if err := h.shoutrrrService.SendHighCostAlert(subscriptionWithCategory); err != nil {
-				slog.Error("failed to send high-cost alert shoutrrr notification", "error", err)
-			}
-		}
+		h.sendHighCostAlert(c.Request.Context(), updated.ID)
 	}
 
+	h.hooks.Fire(service.EventSubscriptionUpdated, updated)
+
 	// Check budget after updating subscription
-	h.checkBudgetExceeded()
+	h.checkBudgetExceeded(c.Request.Context())
 
 	// Return success response that triggers a page refresh
 	c.Header("HX-Refresh", "true")
@@ -317,14 +327,21 @@ func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
 		return
 	}
 
-	err = h.service.Delete(uint(id))
+	// Keep the deleted subscription for hooks
+	deleted, err := h.service.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrSubscriptionNotFound})
+		return
+	}
+
+	action, err := h.undo.DeleteSubscription(c.Request.Context(), uint(id))
 	if err != nil {
 		slog.Error("failed to delete subscription", "error", err, "id", id)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
+	h.hooks.Fire(service.EventSubscriptionDeleted, deleted)
 
-	// Return success response that triggers a page refresh
-	c.Header("HX-Refresh", "true")
-	c.Status(http.StatusOK)
+	// The row is removed by the page; the toast offers to undo the deletion
+	renderUndoToast(c, action, trData(c, "undo_subscription_deleted", map[string]interface{}{"Name": deleted.Name}, "Deleted "+deleted.Name))
 }
@@ -1,81 +1,80 @@
 package handlers
 
 import (
-	"crypto/subtle"
-	"encoding/csv"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"subvault/internal/crypto"
 	"subvault/internal/models"
+	"subvault/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-// ExportCSV exports all subscriptions as CSV
+// exportFilter reads the filter of an export from the query: status (comma-separated
+// or repeated), category, purpose, and from/to for the renewal date range
+func exportFilter(c *gin.Context) (models.SubscriptionFilter, bool) {
+	filter, err := models.ParseSubscriptionFilter(strings.Join(c.QueryArray("status"), ","),
+		c.Query("category"), c.Query("purpose"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		apiBadRequest(c, err.Error())
+		return filter, false
+	}
+	return filter, true
+}
+
+// exportColumns reads the CSV columns of an export from the query (comma-separated
+// or repeated). Without columns the saved column preference applies.
+func exportColumns(c *gin.Context) ([]string, bool) {
+	keys := c.QueryArray("columns")
+	if len(keys) == 0 {
+		return nil, true
+	}
+	columns, err := service.ParseCSVColumns(strings.Split(strings.Join(keys, ","), ","))
+	if err != nil {
+		apiBadRequest(c, err.Error())
+		return nil, false
+	}
+	return columns, true
+}
+
+// ExportCSV exports the subscriptions passing the query filter as CSV, with the
+// columns of the query or the saved column preference
 func (h *SubscriptionHandler) ExportCSV(c *gin.Context) {
-	subscriptions, err := h.service.GetAll()
+	filter, ok := exportFilter(c)
+	if !ok {
+		return
+	}
+	columns, ok := exportColumns(c)
+	if !ok {
+		return
+	}
+	subscriptions, err := h.service.GetAll(c.Request.Context())
 	if err != nil {
 		slog.Error("failed to get subscriptions for CSV export", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
+	subscriptions = filter.Apply(subscriptions)
 
 	c.Header("Content-Type", "text/csv")
 	c.Header("Content-Disposition", "attachment; filename=subscriptions.csv")
 
-	writer := csv.NewWriter(c.Writer)
-	defer writer.Flush()
-
-	// Write CSV header
-	header := []string{"ID", "Name", "Category", "Cost", "Tax Rate", "Price Type", "Net Cost", "Gross Cost", "Tax Amount", "Schedule", "Status", "Payment Method", "Login Name", "Customer Number", "Contract Number", "Start Date", "Renewal Date", "Cancellation Date", "URL", "Notes", "Usage", "Renewal Reminder", "Renewal Reminder Days", "Cancellation Reminder", "Cancellation Reminder Days", "High Cost Alert", "Created At"}
-	writer.Write(header)
-
-	// Write subscription data
-	for _, sub := range subscriptions {
-		categoryName := ""
-		if sub.Category.Name != "" {
-			categoryName = sub.Category.Name
-		}
-		record := []string{
-			fmt.Sprintf("%d", sub.ID),
-			sub.Name,
-			categoryName,
-			fmt.Sprintf("%.2f", sub.Cost),
-			fmt.Sprintf("%.2f", sub.TaxRate),
-			sub.PriceType,
-			fmt.Sprintf("%.2f", sub.NetCost()),
-			fmt.Sprintf("%.2f", sub.GrossCost()),
-			fmt.Sprintf("%.2f", sub.TaxAmount()),
-			sub.Schedule,
-			sub.Status,
-			sub.PaymentMethod,
-			sub.LoginName,
-			sub.CustomerNumber,
-			sub.ContractNumber,
-			formatDate(sub.StartDate),
-			formatDate(sub.RenewalDate),
-			formatDate(sub.CancellationDate),
-			sub.URL,
-			sub.Notes,
-			sub.Usage,
-			fmt.Sprintf("%t", sub.RenewalReminder),
-			fmt.Sprintf("%d", sub.RenewalReminderDays),
-			fmt.Sprintf("%t", sub.CancellationReminder),
-			fmt.Sprintf("%d", sub.CancellationReminderDays),
-			fmt.Sprintf("%t", sub.HighCostAlert),
-			sub.CreatedAt.Format("2006-01-02 15:04:05"),
-		}
-		writer.Write(record)
+	if err := h.exportService.WriteCSV(c.Writer, subscriptions, columns); err != nil {
+		slog.Error("failed to write CSV export", "error", err)
 	}
 }
 
-// ExportJSON exports all subscriptions as JSON
+// ExportJSON exports the subscriptions passing the query filter as JSON
 func (h *SubscriptionHandler) ExportJSON(c *gin.Context) {
-	subscriptions, err := h.service.GetAll()
+	filter, ok := exportFilter(c)
+	if !ok {
+		return
+	}
+	export, err := h.exportService.BuildJSONExport(c.Request.Context(), filter)
 	if err != nil {
 		slog.Error("failed to get subscriptions for JSON export", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
@@ -85,11 +84,7 @@ func (h *SubscriptionHandler) ExportJSON(c *gin.Context) {
 	c.Header("Content-Type", "application/json")
 	c.Header("Content-Disposition", "attachment; filename=subscriptions.json")
 
-	c.JSON(http.StatusOK, gin.H{
-		"subscriptions": subscriptions,
-		"exported_at":   time.Now(),
-		"total_count":   len(subscriptions),
-	})
+	c.JSON(http.StatusOK, export)
 }
 
 // ExportEncrypted creates an AES-256-GCM encrypted backup file (.stbk)
@@ -100,38 +95,13 @@ func (h *SubscriptionHandler) ExportEncrypted(c *gin.Context) {
 		return
 	}
 
-	subscriptions, err := h.service.GetAll()
+	encrypted, err := h.exportService.BuildEncryptedBackup(c.Request.Context(), password)
 	if err != nil {
-		slog.Error("failed to get subscriptions for encrypted export", "error", err)
+		slog.Error("failed to create encrypted export", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
 
-	categories, err := h.service.GetAllCategories()
-	if err != nil {
-		categories = nil
-	}
-
-	backupData := gin.H{
-		"subscriptions": subscriptions,
-		"categories":    categories,
-		"exported_at":   time.Now(),
-		"total_count":   len(subscriptions),
-		"version":       "2.0",
-	}
-
-	jsonData, err := json.Marshal(backupData)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize data"})
-		return
-	}
-
-	encrypted, err := crypto.Encrypt(jsonData, password)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Encryption failed"})
-		return
-	}
-
 	c.Header("Content-Type", "application/octet-stream")
 	c.Header("Content-Disposition", `attachment; filename="subvault-backup.stbk"`)
 	c.Data(http.StatusOK, "application/octet-stream", encrypted)
@@ -139,68 +109,34 @@ func (h *SubscriptionHandler) ExportEncrypted(c *gin.Context) {
 
 // BackupData creates a complete backup of all data
 func (h *SubscriptionHandler) BackupData(c *gin.Context) {
-	subscriptions, err := h.service.GetAll()
-	if err != nil {
-		slog.Error("failed to get subscriptions for backup", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-		return
-	}
-
-	stats, err := h.service.GetStats()
+	backup, err := h.exportService.BuildBackup(c.Request.Context())
 	if err != nil {
-		slog.Error("failed to get stats for backup", "error", err)
+		slog.Error("failed to build backup", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
 
-	backup := gin.H{
-		"version":       "1.0",
-		"backup_date":   time.Now(),
-		"subscriptions": subscriptions,
-		"stats":         stats,
-		"total_count":   len(subscriptions),
-	}
-
 	c.Header("Content-Type", "application/json")
 	c.Header("Content-Disposition", "attachment; filename=subvault-backup.json")
 	c.JSON(http.StatusOK, backup)
 }
 
-// ClearAllData removes all subscription data
-func (h *SubscriptionHandler) ClearAllData(c *gin.Context) {
-	subscriptions, err := h.service.GetAll()
-	if err != nil {
-		slog.Error("failed to get subscriptions for clearing data", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+// ExportICal generates and downloads an iCal file with the renewal dates of the
+// subscriptions passing the query filter
+func (h *SubscriptionHandler) ExportICal(c *gin.Context) {
+	filter, ok := exportFilter(c)
+	if !ok {
 		return
 	}
-
-	// Delete all subscriptions
-	for _, sub := range subscriptions {
-		err := h.service.Delete(sub.ID)
-		if err != nil {
-			slog.Error("failed to delete subscription during clear", "error", err, "id", sub.ID)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			return
-		}
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":       "All subscription data has been cleared",
-		"deleted_count": len(subscriptions),
-	})
-}
-
-// ExportICal generates and downloads an iCal file with all subscription renewal dates
-func (h *SubscriptionHandler) ExportICal(c *gin.Context) {
-	subscriptions, err := h.service.GetAll()
+	subscriptions, err := h.service.GetAll(c.Request.Context())
 	if err != nil {
 		slog.Error("failed to get subscriptions for iCal export", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
+	subscriptions = filter.Apply(subscriptions)
 
-	icalContent := h.generateICal(subscriptions)
+	icalContent := h.generateICal(subscriptions, h.calendarService.GetFeedOptions(), time.Now())
 
 	c.Header("Content-Type", "text/calendar; charset=utf-8")
 	c.Header("Content-Disposition", `attachment; filename="subvault-renewals.ics"`)
@@ -215,35 +151,79 @@ func (h *SubscriptionHandler) ServeCalendarFeed(c *gin.Context) {
 		return
 	}
 
-	storedToken, err := h.calendarService.GetCalendarToken()
-	if err != nil || storedToken == "" || subtle.ConstantTimeCompare([]byte(storedToken), []byte(token)) != 1 {
+	scope, ok := calendarScopeFromQuery(c)
+	if !ok {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	if !h.calendarService.AuthorizeCalendarFeed(token, scope) {
 		c.Status(http.StatusNotFound)
 		return
 	}
+	h.serveCalendarFeed(c, scope)
+}
 
-	subscriptions, err := h.service.GetAll()
+// ServeCalendarFeedAPI serves the calendar feed to an API key instead of the
+// calendar token, limited by the category and purpose of the query
+func (h *SubscriptionHandler) ServeCalendarFeedAPI(c *gin.Context) {
+	scope, ok := calendarScopeFromQuery(c)
+	if !ok {
+		apiBadRequest(c, "Invalid category or purpose")
+		return
+	}
+	h.serveCalendarFeed(c, scope)
+}
+
+// serveCalendarFeed writes the calendar feed of the subscriptions in scope
+func (h *SubscriptionHandler) serveCalendarFeed(c *gin.Context, scope service.CalendarScope) {
+	all, err := h.service.GetAll(c.Request.Context())
 	if err != nil {
 		c.Status(http.StatusInternalServerError)
 		return
 	}
+	var subscriptions []models.Subscription
+	for i := range all {
+		if scope.Includes(&all[i]) {
+			subscriptions = append(subscriptions, all[i])
+		}
+	}
 
-	icalContent := h.generateICal(subscriptions)
+	icalContent := h.generateICal(subscriptions, h.calendarService.GetFeedOptions(), time.Now())
 
 	c.Header("Content-Type", "text/calendar; charset=utf-8")
 	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
 	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(icalContent))
 }
 
+// calendarScopeFromQuery reads the category and purpose a calendar feed is
+// limited to; ok is false if either is invalid
+func calendarScopeFromQuery(c *gin.Context) (scope service.CalendarScope, ok bool) {
+	if category := c.Query("category"); category != "" {
+		id, err := strconv.ParseUint(category, 10, 32)
+		if err != nil || id == 0 {
+			return scope, false
+		}
+		scope.CategoryID = uint(id)
+	}
+	if purpose := c.Query("purpose"); purpose != "" {
+		if !models.IsValidPurpose(purpose) {
+			return scope, false
+		}
+		scope.Purpose = purpose
+	}
+	return scope, true
+}
+
 // generateICal creates iCal content from subscriptions
-func (h *SubscriptionHandler) generateICal(subscriptions []models.Subscription) string {
+func (h *SubscriptionHandler) generateICal(subscriptions []models.Subscription, options models.CalendarFeedOptions, now time.Time) string {
 	icalContent := "BEGIN:VCALENDAR\r\n"
 	icalContent += "VERSION:2.0\r\n"
 	icalContent += "PRODID:-//SubVault//Subscription Renewals//EN\r\n"
 	icalContent += "CALSCALE:GREGORIAN\r\n"
 	icalContent += "METHOD:PUBLISH\r\n"
 
-	now := time.Now()
-	dtStamp := now.Format("20060102T150000Z")
+	dtStamp := now.UTC().Format("20060102T150405Z")
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 
 	for _, sub := range subscriptions {
 		currency := sub.OriginalCurrency
@@ -253,69 +233,55 @@ func (h *SubscriptionHandler) generateICal(subscriptions []models.Subscription)
 
 		// Renewal events for Active, Trial and Paused subscriptions
 		if sub.RenewalDate != nil && (sub.Status == "Active" || sub.Status == "Trial" || sub.Status == "Paused") {
-			dtStart := sub.RenewalDate.Format("20060102")
-			uid := fmt.Sprintf("subvault-renewal-%d-%d@subvault", sub.ID, sub.RenewalDate.Unix())
-
 			summary := fmt.Sprintf("%s Renewal", sub.Name)
 			if sub.Status != "Active" {
 				summary = fmt.Sprintf("%s Renewal (%s)", sub.Name, sub.Status)
 			}
-			description := fmt.Sprintf("Subscription: %s\\nCost: %s %.2f\\nSchedule: %s", sub.Name, currency, sub.Cost, sub.Schedule)
-			if sub.URL != "" {
-				description += fmt.Sprintf("\\nURL: %s", sub.URL)
-			}
-
-			icalContent += "BEGIN:VEVENT\r\n"
-			icalContent += fmt.Sprintf("UID:%s\r\n", uid)
-			icalContent += fmt.Sprintf("DTSTAMP:%s\r\n", dtStamp)
-			icalContent += fmt.Sprintf("DTSTART;VALUE=DATE:%s\r\n", dtStart)
-			icalContent += fmt.Sprintf("DTEND;VALUE=DATE:%s\r\n", sub.RenewalDate.AddDate(0, 0, 1).Format("20060102"))
-			icalContent += fmt.Sprintf("SUMMARY:%s\r\n", summary)
-			icalContent += fmt.Sprintf("DESCRIPTION:%s\r\n", description)
-			icalContent += "STATUS:CONFIRMED\r\n"
-			icalContent += "SEQUENCE:0\r\n"
-
-			// RFC 7986 COLOR property based on status
-			switch sub.Status {
-			case "Active":
-				icalContent += "COLOR:mediumseagreen\r\n"
-			case "Trial":
-				icalContent += "COLOR:dodgerblue\r\n"
-			case "Paused":
-				icalContent += "COLOR:darkgray\r\n"
-			}
+			for _, renewal := range icalRenewals(&sub, options, today) {
+				description := fmt.Sprintf("Subscription: %s\\nCost: %s %s\\nSchedule: %s", sub.Name, currency, h.preferences.FormatAmount(sub.CostAt(renewal.date), currency), sub.Schedule)
+				if sub.URL != "" {
+					description += fmt.Sprintf("\\nURL: %s", sub.URL)
+				}
 
-			// Add category as CATEGORIES property
-			if sub.Category.Name != "" {
-				icalContent += fmt.Sprintf("CATEGORIES:%s\r\n", sub.Category.Name)
-			}
+				icalContent += "BEGIN:VEVENT\r\n"
+				icalContent += fmt.Sprintf("UID:subvault-renewal-%d-%d@subvault\r\n", sub.ID, renewal.date.Unix())
+				icalContent += fmt.Sprintf("DTSTAMP:%s\r\n", dtStamp)
+				icalContent += fmt.Sprintf("DTSTART;VALUE=DATE:%s\r\n", renewal.date.Format("20060102"))
+				icalContent += fmt.Sprintf("DTEND;VALUE=DATE:%s\r\n", renewal.date.AddDate(0, 0, 1).Format("20060102"))
+				icalContent += fmt.Sprintf("SUMMARY:%s\r\n", summary)
+				icalContent += fmt.Sprintf("DESCRIPTION:%s\r\n", description)
+				icalContent += "STATUS:CONFIRMED\r\n"
+				icalContent += "SEQUENCE:0\r\n"
+
+				// RFC 7986 COLOR property based on status
+				switch sub.Status {
+				case "Active":
+					icalContent += "COLOR:mediumseagreen\r\n"
+				case "Trial":
+					icalContent += "COLOR:dodgerblue\r\n"
+				case "Paused":
+					icalContent += "COLOR:darkgray\r\n"
+				}
 
-			// Add recurrence rule for active subscriptions
-			if sub.Status == "Active" {
-				switch sub.Schedule {
-				case "Daily":
-					icalContent += "RRULE:FREQ=DAILY;INTERVAL=1\r\n"
-				case "Weekly":
-					icalContent += "RRULE:FREQ=WEEKLY;INTERVAL=1\r\n"
-				case "Monthly":
-					icalContent += "RRULE:FREQ=MONTHLY;INTERVAL=1\r\n"
-				case "Quarterly":
-					icalContent += "RRULE:FREQ=MONTHLY;INTERVAL=3\r\n"
-				case "Annual":
-					icalContent += "RRULE:FREQ=YEARLY;INTERVAL=1\r\n"
+				// Add category as CATEGORIES property
+				if sub.Category.Name != "" {
+					icalContent += fmt.Sprintf("CATEGORIES:%s\r\n", sub.Category.Name)
+				}
+				if renewal.rrule != "" {
+					icalContent += fmt.Sprintf("RRULE:%s\r\n", renewal.rrule)
 				}
-			}
 
-			icalContent += "END:VEVENT\r\n"
+				icalContent += "END:VEVENT\r\n"
+			}
 		}
 
 		// Cancellation date events (for any subscription with a cancellation date)
-		if sub.CancellationDate != nil {
+		if sub.CancellationDate != nil && options.CancellationEvents {
 			dtStart := sub.CancellationDate.Format("20060102")
 			uid := fmt.Sprintf("subvault-cancel-%d-%d@subvault", sub.ID, sub.CancellationDate.Unix())
 
 			summary := fmt.Sprintf("%s - Cancel By", sub.Name)
-			description := fmt.Sprintf("Reminder: Cancel %s before this date\\nCost: %s %.2f\\nSchedule: %s", sub.Name, currency, sub.Cost, sub.Schedule)
+			description := fmt.Sprintf("Reminder: Cancel %s before this date\\nCost: %s %s\\nSchedule: %s", sub.Name, currency, h.preferences.FormatAmount(sub.Cost, currency), sub.Schedule)
 
 			icalContent += "BEGIN:VEVENT\r\n"
 			icalContent += fmt.Sprintf("UID:%s\r\n", uid)
@@ -332,8 +298,110 @@ func (h *SubscriptionHandler) generateICal(subscriptions []models.Subscription)
 			}
 			icalContent += "END:VEVENT\r\n"
 		}
+
+		// Service cutoff of a failed payment that keeps failing
+		if sub.PaymentFailedAt != nil && sub.GracePeriodEnd != nil && sub.Status == "Active" {
+			uid := fmt.Sprintf("subvault-cutoff-%d-%d@subvault", sub.ID, sub.GracePeriodEnd.Unix())
+			description := fmt.Sprintf("Payment for %s failed, service ends unless a retry succeeds\\nCost: %s %s\\nSchedule: %s", sub.Name, currency, h.preferences.FormatAmount(sub.Cost, currency), sub.Schedule)
+			if sub.PaymentRetryDate != nil {
+				description += fmt.Sprintf("\\nNext retry: %s", sub.PaymentRetryDate.Format("2006-01-02"))
+			}
+
+			icalContent += "BEGIN:VEVENT\r\n"
+			icalContent += fmt.Sprintf("UID:%s\r\n", uid)
+			icalContent += fmt.Sprintf("DTSTAMP:%s\r\n", dtStamp)
+			icalContent += fmt.Sprintf("DTSTART;VALUE=DATE:%s\r\n", sub.GracePeriodEnd.Format("20060102"))
+			icalContent += fmt.Sprintf("DTEND;VALUE=DATE:%s\r\n", sub.GracePeriodEnd.AddDate(0, 0, 1).Format("20060102"))
+			icalContent += fmt.Sprintf("SUMMARY:%s - Service Cutoff\r\n", sub.Name)
+			icalContent += fmt.Sprintf("DESCRIPTION:%s\r\n", description)
+			icalContent += "STATUS:CONFIRMED\r\n"
+			icalContent += "SEQUENCE:0\r\n"
+			icalContent += "COLOR:tomato\r\n"
+			icalContent += "END:VEVENT\r\n"
+		}
 	}
 
 	icalContent += "END:VCALENDAR\r\n"
 	return icalContent
 }
+
+// icalRenewal is one renewal event of the calendar feed; rrule repeats it
+type icalRenewal struct {
+	date  time.Time
+	rrule string
+}
+
+// icalRenewals returns the renewal events of a subscription. Within a horizon,
+// an active subscription gets one event per renewal from today on, while
+// trials and paused subscriptions get their next renewal only. Without a
+// horizon, an active subscription gets a single recurring event, or two when
+// a promotional price ends. Either way renewals stop before the cancellation
+// date.
+func icalRenewals(sub *models.Subscription, options models.CalendarFeedOptions, today time.Time) []icalRenewal {
+	renewal := *sub.RenewalDate
+	var cancelled time.Time
+	if sub.CancellationDate != nil {
+		cancelled = time.Date(sub.CancellationDate.Year(), sub.CancellationDate.Month(), sub.CancellationDate.Day(), 0, 0, 0, 0, time.UTC)
+		if !renewal.Before(cancelled) {
+			return nil
+		}
+	}
+
+	if options.HorizonMonths == 0 {
+		event := icalRenewal{date: renewal}
+		rrule := icalRRule(sub.Schedule)
+		if sub.Status != "Active" || rrule == "" {
+			return []icalRenewal{event}
+		}
+		untilCancelled := ""
+		if !cancelled.IsZero() {
+			untilCancelled = ";UNTIL=" + cancelled.AddDate(0, 0, -1).Format("20060102")
+		}
+		// The description holds the cost, so a promotional price that ends
+		// splits the series into one at the promotional and one at the
+		// regular price
+		if sub.PromoActive(renewal) {
+			promoEnd := time.Date(sub.PromoEndDate.Year(), sub.PromoEndDate.Month(), sub.PromoEndDate.Day(), 0, 0, 0, 0, time.UTC)
+			if regular := models.ProjectRenewalDates(renewal, sub.Schedule, promoEnd, promoEnd.AddDate(1, 0, 1)); len(regular) > 0 && (cancelled.IsZero() || regular[0].Before(cancelled)) {
+				event.rrule = rrule + ";UNTIL=" + regular[0].AddDate(0, 0, -1).Format("20060102")
+				return []icalRenewal{event, {date: regular[0], rrule: rrule + untilCancelled}}
+			}
+		}
+		event.rrule = rrule + untilCancelled
+		return []icalRenewal{event}
+	}
+
+	until := today.AddDate(0, options.HorizonMonths, 0)
+	if !cancelled.IsZero() && cancelled.Before(until) {
+		until = cancelled
+	}
+	if sub.Status != "Active" {
+		if renewal.Before(today) || !renewal.Before(until) {
+			return nil
+		}
+		return []icalRenewal{{date: renewal}}
+	}
+
+	var events []icalRenewal
+	for _, d := range models.ProjectRenewalDates(renewal, sub.Schedule, today, until) {
+		events = append(events, icalRenewal{date: d})
+	}
+	return events
+}
+
+// icalRRule returns the recurrence rule of a billing schedule
+func icalRRule(schedule string) string {
+	switch schedule {
+	case "Daily":
+		return "FREQ=DAILY;INTERVAL=1"
+	case "Weekly":
+		return "FREQ=WEEKLY;INTERVAL=1"
+	case "Monthly":
+		return "FREQ=MONTHLY;INTERVAL=1"
+	case "Quarterly":
+		return "FREQ=MONTHLY;INTERVAL=3"
+	case "Annual":
+		return "FREQ=YEARLY;INTERVAL=1"
+	}
+	return ""
+}
@@ -1,11 +1,19 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"subvault/internal/models"
 	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
 )
 
 // enrichWithCurrencyConversion adds currency conversion info to subscriptions
@@ -48,10 +56,60 @@ func (h *SubscriptionHandler) enrichWithCurrencyConversion(subscriptions []model
 	return result
 }
 
+// RateWarning is the banner shown above converted amounts when they may be inaccurate
+type RateWarning struct {
+	Health   string    // service.RateHealthOK when only Unrated currencies are affected
+	RateDate time.Time // Date of the rates in use, zero without rates
+	Unrated  []string  // Currencies without an exchange rate, converted 1:1
+	Key      string    // Changes with the warning, so a dismissed banner returns for a new problem
+}
+
+// UnratedList lists the currencies without an exchange rate for display
+func (w *RateWarning) UnratedList() string {
+	return strings.Join(w.Unrated, ", ")
+}
+
+// rateWarning returns the exchange rate banner for a page listing subs, or nil
+// when no subscription is billed in a foreign currency or all conversions use
+// fresh rates
+func (h *SubscriptionHandler) rateWarning(subs []models.Subscription) *RateWarning {
+	displayCurrency := h.preferences.GetCurrency()
+	var foreign []string
+	for i := range subs {
+		currency := subs[i].OriginalCurrency
+		if currency != "" && currency != displayCurrency && !slices.Contains(foreign, currency) {
+			foreign = append(foreign, currency)
+		}
+	}
+	if len(foreign) == 0 {
+		return nil
+	}
+
+	status := h.currencyService.GetStatus()
+	warning := &RateWarning{Health: status.Health()}
+	if status.RateCount > 0 {
+		warning.RateDate = status.RateDate
+	}
+	for _, currency := range foreign {
+		if !service.HasECBRate(currency) || !service.HasECBRate(displayCurrency) {
+			warning.Unrated = append(warning.Unrated, currency)
+		}
+	}
+	if warning.Health == service.RateHealthOK && len(warning.Unrated) == 0 {
+		return nil
+	}
+	slices.Sort(warning.Unrated)
+	warning.Key = warning.Health + ":" + warning.RateDate.Format("2006-01-02") + ":" + warning.UnratedList()
+	return warning
+}
+
 // isHighCostWithCurrency checks if a subscription is high-cost, respecting currency conversion
 // The threshold is in the user's display currency, so we convert the subscription's monthly cost
 // to the display currency before comparing
 func (h *SubscriptionHandler) isHighCostWithCurrency(subscription *models.Subscription) bool {
+	if subscription.IsCredit() {
+		return false
+	}
 	threshold := h.settings.GetFloatSettingWithDefault("high_cost_threshold", 50.0)
 	displayCurrency := h.preferences.GetCurrency()
 
@@ -77,20 +135,31 @@ func (h *SubscriptionHandler) isHighCostWithCurrency(subscription *models.Subscr
 	return convertedMonthlyCost > threshold
 }
 
-// fetchAndSetLogo fetches a logo for a subscription if URL is provided and icon_url is empty
-// This is a helper method to avoid code duplication between create and update handlers
-func (h *SubscriptionHandler) fetchAndSetLogo(subscription *models.Subscription) {
-	if subscription.URL == "" || subscription.IconURL != "" {
+// queueLogo looks up the logo of a saved subscription in the background when it
+// has a website but no icon, or when the website behind a fetched logo changed.
+// original is the subscription before an update, nil on create. Nothing is
+// looked up in offline mode.
+func (h *SubscriptionHandler) queueLogo(ctx context.Context, subscription *models.Subscription, original *models.Subscription) {
+	if subscription.URL == "" || service.OfflineMode() {
 		return
 	}
 
-	iconURL, err := h.logoService.FetchLogoFromURL(subscription.URL)
-	if err == nil && iconURL != "" {
-		subscription.IconURL = iconURL
-		slog.Info("fetched logo", "url", subscription.URL, "iconURL", iconURL)
-	} else if err != nil {
-		slog.Error("failed to fetch logo", "url", subscription.URL, "error", err)
+	var err error
+	switch {
+	case subscription.IconURL == "":
+		err = h.logoQueue.Enqueue(ctx, subscription.ID)
+	case original != nil && original.URL != subscription.URL &&
+		original.LogoStatus == models.LogoFetched && original.IconURL == subscription.IconURL:
+		err = h.logoQueue.Retry(ctx, subscription.ID)
+		subscription.IconURL = ""
+	default:
+		return
 	}
+	if err != nil {
+		slog.Error("failed to queue logo lookup", "id", subscription.ID, "error", err)
+		return
+	}
+	subscription.LogoStatus = models.LogoPending
 }
 
 // getScheduleMultiplier returns the annual multiplier for a schedule
@@ -111,27 +180,193 @@ func (h *SubscriptionHandler) getScheduleMultiplier(schedule string) float64 {
 	}
 }
 
-// checkBudgetExceeded checks if the monthly budget has been exceeded and sends alerts
-func (h *SubscriptionHandler) checkBudgetExceeded() {
-	budget := h.settings.GetFloatSettingWithDefault("monthly_budget", 0)
-	if budget <= 0 {
+// sendHighCostAlert sends the high-cost alert for a subscription through its
+// selected notification channels
+func (h *SubscriptionHandler) sendHighCostAlert(ctx context.Context, id uint) {
+	subscription, err := h.service.GetByID(ctx, id)
+	if err != nil || subscription == nil {
 		return
 	}
+	h.notifier.SendHighCostAlert(subscription)
+}
 
-	stats, err := h.service.GetStats()
+// checkBudgetExceeded checks if the monthly (including rollover) or annual budget has been exceeded and sends alerts
+func (h *SubscriptionHandler) checkBudgetExceeded(ctx context.Context) {
+	if h.settings.GetFloatSettingWithDefault("monthly_budget", 0) <= 0 && h.settings.GetFloatSettingWithDefault("annual_budget", 0) <= 0 {
+		return
+	}
+
+	stats, err := h.service.GetStats(ctx)
 	if err != nil {
 		return
 	}
 
-	if stats.TotalMonthlySpend > budget {
+	if period, spend, budget, exceeded := stats.ExceededBudget(); exceeded {
 		currencySymbol := h.preferences.GetCurrencySymbol()
-		if h.emailService != nil {
-			go h.emailService.SendBudgetExceededAlert(stats.TotalMonthlySpend, budget, currencySymbol)
+		if h.notifier != nil {
+			go h.notifier.SendBudgetExceededAlert(period, spend, budget, currencySymbol)
+		}
+		h.hooks.Fire(service.EventBudgetExceeded, map[string]interface{}{
+			"period":              period,
+			"spend":               spend,
+			"total_monthly_spend": stats.TotalMonthlySpend,
+			"budget":              budget,
+			"currency":            h.preferences.GetCurrency(),
+		})
+	}
+}
+
+// errNoNotifyChannels is returned by parseNotifyChannels when the form has no channel checked
+var errNoNotifyChannels = errors.New("select at least one notification channel")
+
+// parseNotifyChannels reads the notification channel checkboxes of the
+// subscription form. The form always posts an empty marker value, so a
+// request without the field keeps all channels while a form with every box
+// unchecked is rejected.
+func parseNotifyChannels(c *gin.Context) (string, error) {
+	values, ok := c.GetPostFormArray("notify_channels")
+	if !ok {
+		return "", nil
+	}
+	var selected []string
+	for _, value := range values {
+		if value != "" {
+			selected = append(selected, value)
+		}
+	}
+	if len(selected) == 0 {
+		return "", errNoNotifyChannels
+	}
+	return models.NormalizeNotifyChannels(strings.Join(selected, ","))
+}
+
+// maxSeats bounds the seats of a family or team plan
+const maxSeats = 100
+
+var (
+	errInvalidCost    = errors.New("invalid cost")
+	errInvalidTaxRate = errors.New("invalid tax rate")
+)
+
+// formAmounts reads the cost, promotional price and tax rate of the
+// subscription form. Text that is not a number is rejected instead of silently
+// becoming zero. A negative cost enters a credit.
+func formAmounts(c *gin.Context, sub *models.Subscription) error {
+	if costStr := strings.TrimSpace(c.PostForm("cost")); costStr != "" {
+		cost, err := parseNumber(c, costStr)
+		if err != nil || math.Abs(cost) > models.MaxCost {
+			return errInvalidCost
 		}
-		if h.shoutrrrService != nil {
-			go h.shoutrrrService.SendBudgetExceededAlert(stats.TotalMonthlySpend, budget, currencySymbol)
+		sub.Cost = cost
+	}
+	sub.PromoCost = nil
+	if promoStr := strings.TrimSpace(c.PostForm("promo_cost")); promoStr != "" {
+		promo, err := parseNumber(c, promoStr)
+		if err != nil || math.Abs(promo) > models.MaxCost {
+			return errInvalidCost
+		}
+		sub.PromoCost = &promo
+	}
+	if taxRateStr := strings.TrimSpace(c.PostForm("tax_rate")); taxRateStr != "" {
+		taxRate, err := parseNumber(c, taxRateStr)
+		if err != nil || taxRate < 0 || taxRate > 100 {
+			return errInvalidTaxRate
 		}
+		sub.TaxRate = taxRate
+	}
+	return nil
+}
+
+// amountErrorMessage translates an error of formAmounts
+func amountErrorMessage(c *gin.Context, err error) string {
+	if errors.Is(err, errInvalidTaxRate) {
+		return tr(c, "sub_form_error_tax_rate", "Enter a tax rate between 0 and 100, e.g. 19 or 7,5")
+	}
+	return tr(c, "sub_form_error_cost", "Enter the cost as a number, e.g. 9.99 or 9,99")
+}
+
+// formPurpose reads the purpose of the subscription form. Unknown values are
+// dropped so the configured default applies.
+func formPurpose(c *gin.Context) string {
+	purpose := c.PostForm("purpose")
+	if !models.IsValidPurpose(purpose) {
+		return ""
+	}
+	return purpose
+}
+
+// knownVendor checks the vendor of a subscription. nil and 0 mean no vendor;
+// ok is false for a vendor that does not exist.
+func (h *SubscriptionHandler) knownVendor(id *uint) (vendorID *uint, ok bool) {
+	if id == nil || *id == 0 {
+		return nil, true
+	}
+	if _, err := h.vendors.GetByID(*id); err != nil {
+		return nil, false
+	}
+	return id, true
+}
+
+// formVendor reads the vendor of the subscription form. Unknown vendors are
+// dropped.
+func (h *SubscriptionHandler) formVendor(c *gin.Context) *uint {
+	id, err := strconv.ParseUint(c.PostForm("vendor_id"), 10, 32)
+	if err != nil {
+		return nil
+	}
+	vendorID := uint(id)
+	known, _ := h.knownVendor(&vendorID)
+	return known
+}
+
+// formPaymentFailure applies the failed payment fields of the subscription form.
+// While the payment stays marked as failed, the first failure date and the sent
+// grace period reminder are kept from original (nil when creating).
+func formPaymentFailure(c *gin.Context, sub, original *models.Subscription) {
+	if c.PostForm("payment_failed") != "on" {
+		sub.ResolvePaymentFailure()
+		return
 	}
+	if original != nil {
+		sub.PaymentFailedAt = original.PaymentFailedAt
+		sub.LastGraceReminderDate = original.LastGraceReminderDate
+	}
+	sub.MarkPaymentFailed(time.Now(), parseDatePtr(c.PostForm("payment_retry_date")), parseDatePtr(c.PostForm("grace_period_end")))
+}
+
+// formContract applies the contract term fields of the subscription form.
+// The last contract reminder is kept from original (nil when creating) so that
+// saving the form does not trigger a second reminder for the same deadline.
+func formContract(c *gin.Context, sub, original *models.Subscription) {
+	sub.ContractStartDate = parseDatePtr(c.PostForm("contract_start_date"))
+	sub.ContractEndDate = parseDatePtr(c.PostForm("contract_end_date"))
+	sub.MinimumTermMonths = formNonNegativeInt(c, "minimum_term_months")
+	sub.NoticePeriodDays = formNonNegativeInt(c, "notice_period_days")
+	sub.AutoRenew = c.PostForm("auto_renew") == "on"
+	if original != nil {
+		sub.LastContractReminderDate = original.LastContractReminderDate
+	}
+}
+
+// formPromo applies the promotional price end of the subscription form. The
+// last promo reminder is kept from original (nil when creating) so that saving
+// the form does not repeat the reminder for the same end date.
+func formPromo(c *gin.Context, sub, original *models.Subscription) {
+	sub.PromoEndDate = parseDatePtr(c.PostForm("promo_end_date"))
+	sub.PromoEndReminder = c.PostForm("promo_end_reminder") == "on"
+	if original != nil {
+		sub.LastPromoReminderDate = original.LastPromoReminderDate
+	}
+}
+
+// formNonNegativeInt parses an optional non-negative integer form field.
+// Empty, invalid, or negative values yield 0.
+func formNonNegativeInt(c *gin.Context, field string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(c.PostForm(field)))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
 }
 
 // parseDatePtr parses a date string in "2006-01-02" format and returns a pointer to time.Time.
@@ -149,10 +384,32 @@ func parseDatePtr(dateStr string) *time.Time {
 	return nil
 }
 
-// Helper function to format date pointers
-func formatDate(date *time.Time) string {
-	if date == nil {
-		return ""
+// valueOr returns the value of an optional request field or fallback when it was omitted
+func valueOr[T any](value *T, fallback T) T {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
+// prefillSubscription fills a new subscription from the name, cost, currency,
+// schedule and renewal_date query parameters, used to propose charges found at
+// the bank and drafts from the quick-add parser
+func prefillSubscription(c *gin.Context, sub *models.Subscription) {
+	if name := strings.TrimSpace(c.Query("name")); name != "" {
+		sub.Name = name
+	}
+	if cost, err := strconv.ParseFloat(c.Query("cost"), 64); err == nil && cost >= 0 {
+		sub.Cost = cost
+	}
+	if currency := strings.ToUpper(c.Query("currency")); slices.Contains(service.SupportedCurrencies(), currency) {
+		sub.OriginalCurrency = currency
+	}
+	switch schedule := c.Query("schedule"); schedule {
+	case "Monthly", "Annual", "Weekly", "Daily", "Quarterly":
+		sub.Schedule = schedule
+	}
+	if date := parseDatePtr(c.Query("renewal_date")); date != nil {
+		sub.RenewalDate = date
 	}
-	return date.Format("2006-01-02")
 }
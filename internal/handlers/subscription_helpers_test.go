@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+	"subvault/internal/service"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testLangProvider struct{}
+
+func (testLangProvider) SupportedLanguages() []string { return []string{"en"} }
+
+func BenchmarkEnrichWithCurrencyConversion(b *testing.B) {
+	db, err := gorm.Open(sqlite.Open(b.TempDir()+"/bench.db"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(b, err)
+	require.NoError(b, db.AutoMigrate(&models.Settings{}, &models.ExchangeRate{}))
+
+	// Fresh rates for the seeded currencies keep conversions off the network
+	exchangeRates := repository.NewExchangeRateRepository(db)
+	var rates []models.ExchangeRate
+	for currency, rate := range map[string]float64{"USD": 1.08, "GBP": 0.86, "CHF": 0.95, "SEK": 11.4, "JPY": 162} {
+		rates = append(rates, models.ExchangeRate{BaseCurrency: "EUR", Currency: currency, Rate: rate, Date: time.Now()})
+	}
+	require.NoError(b, exchangeRates.SaveRates(rates))
+
+	settingsService := service.NewSettingsService(repository.NewSettingsRepository(db))
+	h := &SubscriptionHandler{
+		preferences:     service.NewPreferencesService(settingsService, testLangProvider{}),
+		currencyService: service.NewCurrencyService(exchangeRates, settingsService),
+	}
+
+	for _, n := range []int{100, 1000, 5000} {
+		generated := service.SyntheticSubscriptions(n, []uint{1}, rand.New(rand.NewPCG(1, 1)), time.Now())
+		subscriptions := make([]models.Subscription, n)
+		for i, sub := range generated {
+			subscriptions[i] = *sub
+		}
+
+		b.Run(fmt.Sprintf("subs=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				h.enrichWithCurrencyConversion(subscriptions)
+			}
+		})
+	}
+}
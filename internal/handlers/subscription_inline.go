@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fields of the subscriptions table that can be edited inline
+const (
+	inlineCost        = "cost"
+	inlineRenewalDate = "renewal_date"
+	inlineStatus      = "status"
+)
+
+var inlineStatuses = []string{"Active", "Trial", "Paused", "Cancelled"}
+
+// InlineCell renders a cell of the subscriptions table, as an inline editor
+// with ?edit=1
+func (h *SubscriptionHandler) InlineCell(c *gin.Context) {
+	field := c.Param("field")
+	if field != inlineCost && field != inlineRenewalDate && field != inlineStatus {
+		c.String(http.StatusNotFound, "Unknown field")
+		return
+	}
+	sub, ok := h.inlineSubscription(c)
+	if !ok {
+		return
+	}
+	h.renderInlineCell(c, sub, field, c.Query("edit") == "1", "")
+}
+
+// PatchCost updates the cost of a subscription from the inline editor
+func (h *SubscriptionHandler) PatchCost(c *gin.Context) {
+	sub, ok := h.inlineSubscription(c)
+	if !ok {
+		return
+	}
+	cost, err := parseNumber(c, c.PostForm("cost"))
+	if err != nil || cost == 0 || math.Abs(cost) > models.MaxCost {
+		h.renderInlineCell(c, sub, inlineCost, true, tr(c, "inline_invalid_cost", "Enter a cost other than 0, or a negative amount for a credit"))
+		return
+	}
+
+	wasHighCost := h.isHighCostWithCurrency(sub)
+	sub.Cost = cost
+	updated, err := h.service.Update(c.Request.Context(), sub.ID, sub)
+	if err != nil {
+		slog.Error("failed to update subscription cost", "error", err, "id", sub.ID)
+		h.renderInlineCell(c, sub, inlineCost, true, tr(c, "inline_save_failed", "Could not save the change"))
+		return
+	}
+	if updated.HighCostAlert && !wasHighCost && h.isHighCostWithCurrency(updated) {
+		h.sendHighCostAlert(c.Request.Context(), updated.ID)
+	}
+	h.afterInlineUpdate(c.Request.Context(), updated)
+	h.renderInlineCell(c, updated, inlineCost, false, "")
+}
+
+// PatchRenewalDate updates the renewal date of a subscription from the inline
+// editor. A date in the past is advanced like in the form.
+func (h *SubscriptionHandler) PatchRenewalDate(c *gin.Context) {
+	sub, ok := h.inlineSubscription(c)
+	if !ok {
+		return
+	}
+	date, err := time.Parse("2006-01-02", c.PostForm("renewal_date"))
+	if err != nil {
+		h.renderInlineCell(c, sub, inlineRenewalDate, true, tr(c, "inline_invalid_date", "Enter a valid date"))
+		return
+	}
+
+	sub.RenewalDate = &date
+	updated, err := h.service.Update(c.Request.Context(), sub.ID, sub)
+	if err != nil {
+		slog.Error("failed to update subscription renewal date", "error", err, "id", sub.ID)
+		h.renderInlineCell(c, sub, inlineRenewalDate, true, tr(c, "inline_save_failed", "Could not save the change"))
+		return
+	}
+	h.afterInlineUpdate(c.Request.Context(), updated)
+	h.renderInlineCell(c, updated, inlineRenewalDate, false, "")
+}
+
+// PatchStatus changes the status of a subscription from the inline editor.
+// A status change also moves the renewal and cancellation dates, so the page
+// is refreshed afterwards.
+func (h *SubscriptionHandler) PatchStatus(c *gin.Context) {
+	sub, ok := h.inlineSubscription(c)
+	if !ok {
+		return
+	}
+	status := c.PostForm("status")
+	if status == sub.Status {
+		h.renderInlineCell(c, sub, inlineStatus, false, "")
+		return
+	}
+
+	updated, err := h.service.SetStatus(c.Request.Context(), sub.ID, status)
+	switch {
+	case errors.Is(err, service.ErrInvalidStatusChange):
+		h.renderInlineCell(c, sub, inlineStatus, true, tr(c, "quick_action_not_allowed", "This action is not available for the subscription's current status"))
+		return
+	case err != nil:
+		slog.Error("failed to change subscription status", "error", err, "id", sub.ID)
+		h.renderInlineCell(c, sub, inlineStatus, true, tr(c, "inline_save_failed", "Could not save the change"))
+		return
+	}
+	h.afterInlineUpdate(c.Request.Context(), updated)
+	c.Header("HX-Refresh", "true")
+	h.renderInlineCell(c, updated, inlineStatus, false, "")
+}
+
+// inlineSubscription loads the subscription in the path, answering with an
+// error if it is unknown
+func (h *SubscriptionHandler) inlineSubscription(c *gin.Context) (*models.Subscription, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.String(http.StatusBadRequest, ErrInvalidID)
+		return nil, false
+	}
+	sub, err := h.service.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.String(http.StatusNotFound, ErrSubscriptionNotFound)
+		return nil, false
+	}
+	return sub, true
+}
+
+func (h *SubscriptionHandler) afterInlineUpdate(ctx context.Context, updated *models.Subscription) {
+	h.hooks.Fire(service.EventSubscriptionUpdated, updated)
+	h.checkBudgetExceeded(ctx)
+}
+
+// renderInlineCell renders a table cell. Errors are shown in the editor with a
+// 200 response, since HTMX does not swap error responses.
+func (h *SubscriptionHandler) renderInlineCell(c *gin.Context, sub *models.Subscription, field string, editing bool, errMsg string) {
+	enriched := h.enrichWithCurrencyConversion([]models.Subscription{*sub})
+	data := baseTemplateData(c)
+	mergeTemplateData(data, gin.H{
+		"Sub":      enriched[0],
+		"Field":    field,
+		"Editing":  editing,
+		"Error":    errMsg,
+		"Statuses": inlineStatuses,
+	})
+	c.HTML(http.StatusOK, "inline-cell.html", data)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLogoUploadSize is the largest icon accepted, the size of stored logos
+const maxLogoUploadSize = 512 * 1024
+
+// RetryLogo looks up the logo of a subscription again and renders its logo status
+func (h *SubscriptionHandler) RetryLogo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidID})
+		return
+	}
+
+	sub, err := h.service.GetByID(c.Request.Context(), uint(id))
+	if err == nil {
+		err = h.logoQueue.Retry(c.Request.Context(), sub.ID)
+	} else {
+		err = service.ErrLogoSubscriptionNotFound
+	}
+	if err != nil {
+		status, message := logoRetryError(err)
+		c.Header("HX-Retarget", "#form-errors")
+		c.HTML(status, "form-errors.html", gin.H{"Error": message})
+		return
+	}
+	sub.IconURL = ""
+	sub.LogoStatus = models.LogoPending
+
+	c.HTML(http.StatusOK, "logo-status.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Subscription": sub,
+	}))
+}
+
+// RetryLogoAPI looks up the logo of a subscription again. The lookup runs in
+// the background, poll the subscription for logo_status.
+func (h *SubscriptionHandler) RetryLogoAPI(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+
+	if err := h.logoQueue.Retry(c.Request.Context(), uint(id)); err != nil {
+		status, message := logoRetryError(err)
+		apiError(c, status, message)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"id": id, "logo_status": models.LogoPending})
+}
+
+// LogoCandidates renders the logo picker with the possible logos of a website.
+// A search term (a domain or URL) takes precedence over the subscription website.
+func (h *SubscriptionHandler) LogoCandidates(c *gin.Context) {
+	website := strings.TrimSpace(c.Query("logo_search"))
+	if website == "" {
+		website = strings.TrimSpace(c.Query("url"))
+	}
+
+	data := gin.H{"PrivacyMode": h.logoService.PrivacyMode()}
+	if website != "" {
+		candidates, err := h.logoService.Candidates(c.Request.Context(), website)
+		if errors.Is(err, service.ErrOfflineMode) {
+			data["Error"] = tr(c, "logo_offline", "Logos are not looked up in offline mode, upload an icon instead")
+		} else if err != nil {
+			data["Error"] = tr(c, "logo_picker_invalid", "Enter a website or domain to search for logos")
+		}
+		data["Candidates"] = candidates
+	}
+	c.HTML(http.StatusOK, "logo-candidates.html", mergeTemplateData(baseTemplateData(c), data))
+}
+
+// LogoCandidatesAPI returns the possible logos of a website (?url=) in the
+// order they are tried
+func (h *SubscriptionHandler) LogoCandidatesAPI(c *gin.Context) {
+	candidates, err := h.logoService.Candidates(c.Request.Context(), c.Query("url"))
+	if errors.Is(err, service.ErrOfflineMode) {
+		apiError(c, http.StatusConflict, "Logos are not looked up in offline mode")
+		return
+	}
+	if err != nil {
+		apiBadRequest(c, "Invalid url, use a website or domain")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"privacy_mode": h.logoService.PrivacyMode(), "candidates": candidates})
+}
+
+// UploadLogo stores an uploaded icon for a subscription and renders its logo status
+func (h *SubscriptionHandler) UploadLogo(c *gin.Context) {
+	sub, err := h.uploadLogo(c)
+	if err != nil {
+		status, message := logoRetryError(err)
+		if errors.Is(err, service.ErrInvalidLogo) {
+			message = tr(c, "logo_upload_invalid", "Upload a PNG, JPEG, GIF, WebP, ICO or SVG image of at most 512 KB")
+		}
+		c.Header("HX-Retarget", "#form-errors")
+		c.HTML(status, "form-errors.html", gin.H{"Error": message})
+		return
+	}
+	c.HTML(http.StatusOK, "logo-status.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Subscription": sub,
+	}))
+}
+
+// UploadLogoAPI stores an uploaded icon (multipart field "icon") for a
+// subscription and returns its URL and color
+func (h *SubscriptionHandler) UploadLogoAPI(c *gin.Context) {
+	sub, err := h.uploadLogo(c)
+	if err != nil {
+		status, message := logoRetryError(err)
+		apiError(c, status, message)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": sub.ID, "icon_url": sub.IconURL, "icon_color": sub.IconColor})
+}
+
+// uploadLogo stores the icon of the "icon" field for the subscription of the
+// id parameter and returns the subscription with its new icon
+func (h *SubscriptionHandler) uploadLogo(c *gin.Context) (*models.Subscription, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return nil, service.ErrLogoSubscriptionNotFound
+	}
+	file, _, err := c.Request.FormFile("icon")
+	if err != nil {
+		return nil, service.ErrInvalidLogo
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxLogoUploadSize+1))
+	if err != nil {
+		return nil, service.ErrInvalidLogo
+	}
+
+	if _, err := h.logoQueue.Upload(c.Request.Context(), uint(id), data); err != nil {
+		return nil, err
+	}
+	return h.service.GetByID(c.Request.Context(), uint(id))
+}
+
+// logoRetryError maps logo queue errors to a status code and client message
+func logoRetryError(err error) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrLogoSubscriptionNotFound):
+		return http.StatusNotFound, ErrSubscriptionNotFound
+	case errors.Is(err, service.ErrNoWebsite):
+		return http.StatusBadRequest, "Subscription has no website to look up a logo for"
+	case errors.Is(err, service.ErrOfflineMode):
+		return http.StatusConflict, "Logos are not looked up in offline mode, upload an icon instead"
+	case errors.Is(err, service.ErrInvalidLogo):
+		return http.StatusBadRequest, "Upload a PNG, JPEG, GIF, WebP, ICO or SVG image of at most 512 KB"
+	default:
+		slog.Error("failed to queue logo lookup", "error", err)
+		return http.StatusInternalServerError, ErrInternalServer
+	}
+}
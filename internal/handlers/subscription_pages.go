@@ -10,14 +10,22 @@ import (
 	"strconv"
 	"time"
 
+	"subvault/internal/i18n"
 	"subvault/internal/models"
+	"subvault/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Dashboard renders the main dashboard page
+// Dashboard renders the main dashboard page. The purpose query parameter
+// limits it to personal, business or shared subscriptions.
 func (h *SubscriptionHandler) Dashboard(c *gin.Context) {
-	stats, err := h.service.GetStats()
+	purpose := c.Query("purpose")
+	if !models.IsValidPurpose(purpose) {
+		purpose = ""
+	}
+
+	stats, err := h.service.GetStatsForPurpose(c.Request.Context(), purpose)
 	if err != nil {
 		slog.Error("failed to get subscription stats", "error", err)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": "An internal error occurred"})
@@ -27,32 +35,54 @@ func (h *SubscriptionHandler) Dashboard(c *gin.Context) {
 	// Use subscriptions from GetStats (already loaded, avoids duplicate DB query)
 	enrichedSubs := h.enrichWithCurrencyConversion(stats.AllSubscriptions)
 
-	// Build upcoming renewals (next 5 active subs by renewal date)
 	now := time.Now()
+	data := baseTemplateData(c)
+	mergeTemplateData(data, gin.H{
+		"Title":             "Dashboard",
+		"CurrentPage":       "dashboard",
+		"Stats":             stats,
+		"Subscriptions":     enrichedSubs,
+		"UpcomingRenewals":  upcomingRenewals(enrichedSubs, now, h.preferences.GetUpcomingRenewalsLimit()),
+		"ContractDecisions": models.ContractDecisions(stats.AllSubscriptions, now),
+		"CategoryDonut":     categoryDonut(stats.Categories),
+		"ChartTables":       h.preferences.ChartTablesEnabled(),
+		"Purpose":           purpose,
+		"Purposes":          models.Purposes,
+		"CurrencySymbol":    h.preferences.GetCurrencySymbol(),
+		"DarkMode":          h.preferences.IsDarkModeEnabled(),
+		"RateWarning":       h.rateWarning(stats.AllSubscriptions),
+	})
+	c.HTML(http.StatusOK, "dashboard.html", data)
+}
+
+// upcomingRenewals returns the active subscriptions that renew next, soonest
+// first and at most limit. A renewal date that has passed is replaced by the
+// next one its schedule projects, without saving it.
+func upcomingRenewals(subs []SubscriptionWithConversion, now time.Time, limit int) []SubscriptionWithConversion {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	var upcoming []SubscriptionWithConversion
-	for _, sub := range enrichedSubs {
-		if sub.Status == "Active" && sub.RenewalDate != nil && sub.RenewalDate.After(now) {
-			upcoming = append(upcoming, sub)
+	for _, sub := range subs {
+		if sub.Status != "Active" {
+			continue
+		}
+		next := sub.ProjectedRenewalDate(today)
+		if next == nil {
+			continue
 		}
+		if !next.Equal(*sub.RenewalDate) {
+			projected := *sub.Subscription
+			projected.RenewalDate = next
+			sub.Subscription = &projected
+		}
+		upcoming = append(upcoming, sub)
 	}
-	sort.Slice(upcoming, func(i, j int) bool {
+	sort.SliceStable(upcoming, func(i, j int) bool {
 		return upcoming[i].RenewalDate.Before(*upcoming[j].RenewalDate)
 	})
-	if len(upcoming) > 5 {
-		upcoming = upcoming[:5]
+	if len(upcoming) > limit {
+		upcoming = upcoming[:limit]
 	}
-
-	data := baseTemplateData(c)
-	mergeTemplateData(data, gin.H{
-		"Title":            "Dashboard",
-		"CurrentPage":      "dashboard",
-		"Stats":            stats,
-		"Subscriptions":    enrichedSubs,
-		"UpcomingRenewals": upcoming,
-		"CurrencySymbol":   h.preferences.GetCurrencySymbol(),
-		"DarkMode":         h.preferences.IsDarkModeEnabled(),
-	})
-	c.HTML(http.StatusOK, "dashboard.html", data)
+	return upcoming
 }
 
 // SubscriptionsList renders the subscriptions list page
@@ -62,7 +92,7 @@ func (h *SubscriptionHandler) SubscriptionsList(c *gin.Context) {
 	order := c.DefaultQuery("order", "desc")
 
 	// Get sorted subscriptions
-	subscriptions, err := h.service.GetAllSorted(sortBy, order)
+	subscriptions, err := h.service.GetAllSorted(c.Request.Context(), sortBy, order)
 	if err != nil {
 		slog.Error("failed to get sorted subscriptions", "error", err)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": "An internal error occurred"})
@@ -81,14 +111,73 @@ func (h *SubscriptionHandler) SubscriptionsList(c *gin.Context) {
 		"DarkMode":       h.preferences.IsDarkModeEnabled(),
 		"SortBy":         sortBy,
 		"Order":          order,
+		"VendorGroups":   vendorGroups(enrichedSubs),
+		"RateWarning":    h.rateWarning(subscriptions),
 	})
 	c.HTML(http.StatusOK, "subscriptions.html", data)
 }
 
+// VendorGroup is a vendor's row group in the subscriptions table
+type VendorGroup struct {
+	Key          string // Matches the data-vendor attribute of the rows
+	Name         string // Empty for the subscriptions without a vendor
+	Count        int
+	MonthlySpend float64 // Active subscriptions only, in the display currency
+}
+
+// vendorKey identifies the vendor group of a subscription
+func vendorKey(sub *models.Subscription) string {
+	if sub.VendorID == nil {
+		return "none"
+	}
+	return "v" + strconv.FormatUint(uint64(*sub.VendorID), 10)
+}
+
+// vendorGroups totals the subscriptions per vendor, highest monthly spend
+// first and those without a vendor last. It returns nil when no subscription
+// has a vendor.
+func vendorGroups(subs []SubscriptionWithConversion) []VendorGroup {
+	index := make(map[string]int)
+	var groups []VendorGroup
+	var ungrouped VendorGroup
+	for _, sub := range subs {
+		group := &ungrouped
+		if sub.VendorID != nil {
+			key := vendorKey(sub.Subscription)
+			pos, ok := index[key]
+			if !ok {
+				pos = len(groups)
+				index[key] = pos
+				groups = append(groups, VendorGroup{Key: key, Name: sub.VendorName()})
+			}
+			group = &groups[pos]
+		}
+		group.Count++
+		if sub.Status == "Active" {
+			group.MonthlySpend += sub.ConvertedMonthlyCost
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].MonthlySpend != groups[j].MonthlySpend {
+			return groups[i].MonthlySpend > groups[j].MonthlySpend
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	if ungrouped.Count > 0 {
+		ungrouped.Key = "none"
+		groups = append(groups, ungrouped)
+	}
+	return groups
+}
+
 // Calendar renders the calendar page with subscription renewal dates
 func (h *SubscriptionHandler) Calendar(c *gin.Context) {
 	// Get all subscriptions with renewal dates
-	subscriptions, err := h.service.GetAll()
+	subscriptions, err := h.service.GetAll(c.Request.Context())
 	if err != nil {
 		slog.Error("failed to get subscriptions for calendar", "error", err)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": "An internal error occurred"})
@@ -142,18 +231,22 @@ func (h *SubscriptionHandler) Calendar(c *gin.Context) {
 			case "Paused":
 				color = "gray"
 			}
+			// A fetched logo's own color tells the subscriptions apart; paused ones stay grey
+			if sub.IconColor != "" && sub.Status != "Paused" {
+				color = sub.IconColor
+			}
 			name := sub.Name
 			if sub.Status != "Active" {
 				name = fmt.Sprintf("%s (%s)", sub.Name, sub.Status)
 			}
 
 			// Calculate projected renewal dates in the viewed month
-			dates := projectRenewalDates(*sub.RenewalDate, sub.Schedule, viewStart, viewEnd)
+			dates := models.ProjectRenewalDates(*sub.RenewalDate, sub.Schedule, viewStart, viewEnd)
 			for _, d := range dates {
 				dateKey := d.Format("2006-01-02")
 				eventsByDate[dateKey] = append(eventsByDate[dateKey], Event{
 					Name:    name,
-					Cost:    sub.Cost,
+					Cost:    sub.CostAt(d),
 					ID:      sub.ID,
 					IconURL: sub.IconURL,
 					Color:   color,
@@ -175,6 +268,31 @@ func (h *SubscriptionHandler) Calendar(c *gin.Context) {
 				})
 			}
 		}
+		// Billing retry and service cutoff of a failed payment
+		if sub.PaymentFailedAt != nil && sub.Status == "Active" {
+			for _, event := range []struct {
+				date  *time.Time
+				label string
+				color string
+				kind  string
+			}{
+				{sub.PaymentRetryDate, "Payment Retry", "orange", "payment_retry"},
+				{sub.GracePeriodEnd, "Service Cutoff", "tomato", "service_cutoff"},
+			} {
+				if event.date == nil || event.date.Before(viewStart) || !event.date.Before(viewEnd) {
+					continue
+				}
+				dateKey := event.date.Format("2006-01-02")
+				eventsByDate[dateKey] = append(eventsByDate[dateKey], Event{
+					Name:    fmt.Sprintf("%s - %s", sub.Name, event.label),
+					Cost:    sub.Cost,
+					ID:      sub.ID,
+					IconURL: sub.IconURL,
+					Color:   event.color,
+					Type:    event.kind,
+				})
+			}
+		}
 	}
 
 	// Calculate previous and next month
@@ -216,7 +334,7 @@ func (h *SubscriptionHandler) GetSubscriptionForm(c *gin.Context) {
 	if idStr := c.Param("id"); idStr != "" {
 		id, err := strconv.ParseUint(idStr, 10, 32)
 		if err == nil {
-			sub, err := h.service.GetByID(uint(id))
+			sub, err := h.service.GetByID(c.Request.Context(), uint(id))
 			if err == nil {
 				subscription = sub
 				isEdit = true
@@ -229,19 +347,39 @@ func (h *SubscriptionHandler) GetSubscriptionForm(c *gin.Context) {
 		categories = []models.Category{}
 	}
 
-	var defaultCategoryID uint
-	if defaultCat, err := h.service.GetDefaultCategory(); err == nil {
-		defaultCategoryID = defaultCat.ID
+	// New subscriptions start with the configured defaults
+	if subscription == nil {
+		subscription = h.defaults.NewSubscription()
+		prefillSubscription(c, subscription)
+	}
+	defaultCategoryID := subscription.CategoryID
+
+	vendors, err := h.vendors.List()
+	if err != nil {
+		vendors = []models.Vendor{}
+	}
+	var vendorID uint
+	if subscription.VendorID != nil {
+		vendorID = *subscription.VendorID
 	}
 
 	data := baseTemplateData(c)
 	mergeTemplateData(data, gin.H{
-		"Subscription":      subscription,
-		"IsEdit":            isEdit,
-		"CurrencySymbol":    h.preferences.GetCurrencySymbol(),
-		"PreferredCurrency": h.preferences.GetCurrency(),
-		"Categories":        categories,
-		"DefaultCategoryID": defaultCategoryID,
+		"Subscription":            subscription,
+		"IsEdit":                  isEdit,
+		"CurrencySymbol":          h.preferences.GetCurrencySymbol(),
+		"PreferredCurrency":       h.preferences.GetCurrency(),
+		"CurrencyOptions":         i18n.Currencies(),
+		"Categories":              categories,
+		"DefaultCategoryID":       defaultCategoryID,
+		"Vendors":                 vendors,
+		"VendorID":                vendorID,
+		"GracePeriodReminderDays": models.GracePeriodReminderDays,
+		"PaidThroughReminderDays": models.PaidThroughReminderDays,
+		"PromoReminderDays":       models.PromoReminderDays,
+		"KnownCancelURL":          service.KnownCancelURL(subscription.Name),
+		"ContractExitDate":        subscription.EarliestExitDate(time.Now()),
+		"ContractDecideBy":        subscription.ContractDecideBy(time.Now()),
 	})
 	c.HTML(http.StatusOK, "subscription-form.html", data)
 }
@@ -265,60 +403,3 @@ func translateMonth(c *gin.Context, month int) string {
 	}
 	return tr(c, monthKeys[month-1], fallbacks[month-1])
 }
-
-// projectRenewalDates calculates all renewal dates that fall within [viewStart, viewEnd)
-// by stepping forward or backward from the base renewal date using the subscription schedule.
-func projectRenewalDates(baseDate time.Time, schedule string, viewStart, viewEnd time.Time) []time.Time {
-	var step func(t time.Time, n int) time.Time
-	switch schedule {
-	case "Daily":
-		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) }
-	case "Weekly":
-		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) }
-	case "Monthly":
-		step = func(t time.Time, n int) time.Time { return t.AddDate(0, n, 0) }
-	case "Quarterly":
-		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 3*n, 0) }
-	case "Annual":
-		step = func(t time.Time, n int) time.Time { return t.AddDate(n, 0, 0) }
-	default:
-		// Unknown schedule: just check if baseDate falls in range
-		if !baseDate.Before(viewStart) && baseDate.Before(viewEnd) {
-			return []time.Time{baseDate}
-		}
-		return nil
-	}
-
-	var dates []time.Time
-
-	// Step forward from baseDate
-	for i := 0; ; i++ {
-		d := step(baseDate, i)
-		if !d.Before(viewEnd) {
-			break
-		}
-		if !d.Before(viewStart) {
-			dates = append(dates, d)
-		}
-		// Safety: don't generate more than 31 dates for daily schedules
-		if len(dates) > 31 {
-			break
-		}
-	}
-
-	// Step backward from baseDate (skip i=0 already handled above)
-	for i := 1; ; i++ {
-		d := step(baseDate, -i)
-		if d.Before(viewStart) {
-			break
-		}
-		if d.Before(viewEnd) {
-			dates = append(dates, d)
-		}
-		if i > 366 {
-			break
-		}
-	}
-
-	return dates
-}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quickParseMaxLength limits the free text accepted by the quick-add parser
+const quickParseMaxLength = 500
+
+// quickParseRequest is free text describing a subscription
+type quickParseRequest struct {
+	Text string `json:"text" form:"text"`
+}
+
+// quickParseResponse is the parsed draft plus the link that opens the
+// subscription form prefilled with it
+type quickParseResponse struct {
+	service.QuickAddDraft
+	FormURL string `json:"form_url"`
+}
+
+// QuickParse turns free text like "Netflix 17.99 monthly renews on the 12th"
+// into a draft subscription without saving it. A missing currency or schedule
+// takes the subscription defaults.
+func (h *SubscriptionHandler) QuickParse(c *gin.Context) {
+	var req quickParseRequest
+	if err := c.ShouldBind(&req); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+	text := strings.TrimSpace(req.Text)
+	if text == "" || len(text) > quickParseMaxLength {
+		apiBadRequest(c, tr(c, "quick_add_invalid_text", "Enter a text of up to 500 characters"))
+		return
+	}
+
+	draft := service.ParseQuickAdd(text, time.Now())
+	defaults := h.defaults.NewSubscription()
+	if draft.Currency == "" {
+		draft.Currency = defaults.OriginalCurrency
+	}
+	if draft.Schedule == "" {
+		draft.Schedule = defaults.Schedule
+	}
+	c.JSON(http.StatusOK, quickParseResponse{QuickAddDraft: draft, FormURL: quickAddFormURL(draft)})
+}
+
+// QuickAdd renders the simplified add form for phones. It is also the share
+// target of the installed app, so shared titles, texts and links are prefilled.
+func (h *SubscriptionHandler) QuickAdd(c *gin.Context) {
+	var shared []string
+	for _, param := range []string{"title", "text", "url"} {
+		if value := strings.TrimSpace(c.Query(param)); value != "" {
+			shared = append(shared, value)
+		}
+	}
+
+	data := baseTemplateData(c)
+	mergeTemplateData(data, gin.H{
+		"Title":       "Quick Add",
+		"CurrentPage": "quick-add",
+		"Text":        strings.Join(shared, " "),
+	})
+	c.HTML(http.StatusOK, "quick-add.html", data)
+}
+
+// quickAddFormURL links to the subscriptions page with the form prefilled
+func quickAddFormURL(draft service.QuickAddDraft) string {
+	params := url.Values{"new": {"1"}}
+	if draft.Name != "" {
+		params.Set("name", draft.Name)
+	}
+	if draft.Cost > 0 {
+		params.Set("cost", strconv.FormatFloat(draft.Cost, 'f', -1, 64))
+	}
+	if draft.Currency != "" {
+		params.Set("currency", draft.Currency)
+	}
+	if draft.Schedule != "" {
+		params.Set("schedule", draft.Schedule)
+	}
+	if draft.RenewalDate != "" {
+		params.Set("renewal_date", draft.RenewalDate)
+	}
+	return "/subscriptions?" + params.Encode()
+}
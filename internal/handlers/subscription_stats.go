@@ -1,15 +1,27 @@
 package handlers
 
 import (
+	"errors"
+	"html/template"
 	"log/slog"
 	"net/http"
+	"strconv"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetStats returns current statistics
+// GetStats returns current statistics, optionally limited to one purpose
 func (h *SubscriptionHandler) GetStats(c *gin.Context) {
-	stats, err := h.service.GetStats()
+	purpose := c.Query("purpose")
+	if purpose != "" && !models.IsValidPurpose(purpose) {
+		apiBadRequest(c, ErrInvalidPurpose)
+		return
+	}
+
+	stats, err := h.service.GetStatsForPurpose(c.Request.Context(), purpose)
 	if err != nil {
 		slog.Error("failed to get stats", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
@@ -18,3 +30,72 @@ func (h *SubscriptionHandler) GetStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// GetCategorySubscriptions returns the active subscriptions that make up the
+// monthly spend of a category, with their amounts converted to the display
+// currency. Category ID 0 covers subscriptions without a category. HTMX
+// requests get the dashboard drill-down partial.
+func (h *SubscriptionHandler) GetCategorySubscriptions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+	purpose := c.Query("purpose")
+	if purpose != "" && !models.IsValidPurpose(purpose) {
+		apiBadRequest(c, ErrInvalidPurpose)
+		return
+	}
+
+	breakdown, err := h.service.GetCategoryBreakdown(c.Request.Context(), uint(id), purpose)
+	if errors.Is(err, service.ErrCategoryNotFound) {
+		apiNotFound(c, ErrCategoryNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to get category breakdown", "error", err, "category_id", id)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	if c.GetHeader("HX-Request") != "" {
+		c.HTML(http.StatusOK, "category-breakdown.html", mergeTemplateData(baseTemplateData(c), gin.H{
+			"Breakdown":      breakdown,
+			"CurrencySymbol": service.CurrencySymbolForCode(breakdown.Currency),
+		}))
+		return
+	}
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// donutColors are the colors of the category donut segments, repeated when
+// there are more categories
+var donutColors = []template.CSS{"var(--accent)", "var(--info)", "var(--success)", "var(--warning)", "var(--danger)", "var(--text-muted)"}
+
+// DonutSegment is one category of the dashboard's spending donut. The circle
+// has a circumference of 100, so Dash is the category's share; Gap and
+// DashOffset complete the stroke-dasharray starting at the top.
+type DonutSegment struct {
+	models.CategorySpend
+	Color      template.CSS
+	Dash       float64
+	Gap        float64
+	DashOffset float64
+}
+
+// categoryDonut lays out the category spend as donut segments
+func categoryDonut(categories []models.CategorySpend) []DonutSegment {
+	segments := make([]DonutSegment, 0, len(categories))
+	offset := 0.0
+	for i, category := range categories {
+		segments = append(segments, DonutSegment{
+			CategorySpend: category,
+			Color:         donutColors[i%len(donutColors)],
+			Dash:          category.Share,
+			Gap:           100 - category.Share,
+			DashOffset:    25 - offset,
+		})
+		offset += category.Share
+	}
+	return segments
+}
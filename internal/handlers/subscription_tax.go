@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTaxReport returns net, gross and tax amounts per period and category.
+// Query parameters: year (default: current year), period (month or quarter).
+func (h *SubscriptionHandler) GetTaxReport(c *gin.Context) {
+	report, ok := h.taxReport(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportTaxReportCSV exports the tax report as CSV
+func (h *SubscriptionHandler) ExportTaxReportCSV(c *gin.Context) {
+	report, ok := h.taxReport(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=tax-report-%d-%s.csv", report.Year, report.Period))
+	if err := h.exportService.WriteTaxReportCSV(c.Writer, report); err != nil {
+		slog.Error("failed to write tax report CSV", "error", err)
+	}
+}
+
+// TaxReport renders the tax report page
+func (h *SubscriptionHandler) TaxReport(c *gin.Context) {
+	year := time.Now().Year()
+	if y, err := strconv.Atoi(c.Query("year")); err == nil && y >= 1970 && y <= 9999 {
+		year = y
+	}
+	period := models.TaxPeriodMonth
+	if c.Query("period") == models.TaxPeriodQuarter {
+		period = models.TaxPeriodQuarter
+	}
+
+	report, err := h.service.GetTaxReport(c.Request.Context(), year, period)
+	if err != nil {
+		slog.Error("failed to build tax report", "error", err)
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": "An internal error occurred"})
+		return
+	}
+
+	data := baseTemplateData(c)
+	mergeTemplateData(data, gin.H{
+		"Title":          "Tax Report",
+		"CurrentPage":    "tax-report",
+		"Report":         report,
+		"PrevYear":       year - 1,
+		"NextYear":       year + 1,
+		"CurrencySymbol": h.preferences.GetCurrencySymbol(),
+	})
+	c.HTML(http.StatusOK, "tax-report.html", data)
+}
+
+// taxReport parses the year and period query parameters and builds the report,
+// writing an error response if that fails
+func (h *SubscriptionHandler) taxReport(c *gin.Context) (*models.TaxReport, bool) {
+	year := time.Now().Year()
+	if y := c.Query("year"); y != "" {
+		parsed, err := strconv.Atoi(y)
+		if err != nil || parsed < 1970 || parsed > 9999 {
+			apiBadRequest(c, "Invalid year")
+			return nil, false
+		}
+		year = parsed
+	}
+
+	report, err := h.service.GetTaxReport(c.Request.Context(), year, c.Query("period"))
+	if errors.Is(err, service.ErrInvalidTaxPeriod) {
+		apiBadRequest(c, "Invalid period, use month or quarter")
+		return nil, false
+	}
+	if err != nil {
+		slog.Error("failed to build tax report", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return nil, false
+	}
+	return report, true
+}
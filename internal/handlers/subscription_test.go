@@ -1,10 +1,21 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"subvault/internal/middleware"
+	"subvault/internal/models"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseDatePtr(t *testing.T) {
@@ -101,7 +112,198 @@ func TestParseDatePtr(t *testing.T) {
 	}
 }
 
+func TestParseNotifyChannels(t *testing.T) {
+	parse := func(form string) (string, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/subscriptions", strings.NewReader(form))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return parseNotifyChannels(c)
+	}
+
+	channels, err := parse("name=Netflix")
+	assert.NoError(t, err)
+	assert.Empty(t, channels, "forms without the field keep all channels")
+
+	channels, err = parse("notify_channels=&notify_channels=shoutrrr")
+	assert.NoError(t, err)
+	assert.Equal(t, "shoutrrr", channels)
+
+	_, err = parse("notify_channels=")
+	assert.ErrorIs(t, err, errNoNotifyChannels)
+}
+
+func TestVendorGroups(t *testing.T) {
+	aws, google := uint(1), uint(2)
+	sub := func(name, status string, vendorID *uint, vendor string, monthly float64) SubscriptionWithConversion {
+		s := &models.Subscription{Name: name, Status: status, VendorID: vendorID}
+		if vendorID != nil {
+			s.Vendor = &models.Vendor{ID: *vendorID, Name: vendor}
+		}
+		return SubscriptionWithConversion{Subscription: s, ConvertedMonthlyCost: monthly}
+	}
+
+	assert.Nil(t, vendorGroups([]SubscriptionWithConversion{sub("Netflix", "Active", nil, "", 15)}),
+		"no groups without vendors")
+
+	groups := vendorGroups([]SubscriptionWithConversion{
+		sub("Netflix", "Active", nil, "", 15),
+		sub("AWS prod", "Active", &aws, "AWS", 40),
+		sub("AWS old", "Cancelled", &aws, "AWS", 99),
+		sub("Workspace", "Active", &google, "Google", 60),
+	})
+	assert.Equal(t, []VendorGroup{
+		{Key: "v2", Name: "Google", Count: 1, MonthlySpend: 60},
+		{Key: "v1", Name: "AWS", Count: 2, MonthlySpend: 40},
+		{Key: "none", Count: 1, MonthlySpend: 15},
+	}, groups)
+}
+
+func TestQuickAddFormURL(t *testing.T) {
+	assert.Equal(t,
+		"/subscriptions?cost=17.99&currency=EUR&name=Disney%2B&new=1&renewal_date=2025-04-12&schedule=Monthly",
+		quickAddFormURL(service.QuickAddDraft{Name: "Disney+", Cost: 17.99, Currency: "EUR", Schedule: "Monthly", RenewalDate: "2025-04-12"}))
+	assert.Equal(t, "/subscriptions?name=Hulu&new=1", quickAddFormURL(service.QuickAddDraft{Name: "Hulu"}))
+}
+
 // Helper function to create time pointer
 func timePtr(t time.Time) *time.Time {
 	return &t
 }
+
+func TestUpcomingRenewals(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	sub := func(name, schedule, status string, renewal time.Time) SubscriptionWithConversion {
+		return SubscriptionWithConversion{Subscription: &models.Subscription{Name: name, Schedule: schedule, Status: status, RenewalDate: timePtr(renewal)}}
+	}
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	stale := sub("Stale", "Monthly", "Active", day(2026, 1, 12))
+	subs := []SubscriptionWithConversion{
+		sub("Annual", "Annual", "Active", day(2026, 9, 1)),
+		stale,
+		sub("Today", "Weekly", "Active", day(2026, 3, 10)),
+		sub("Cancelled", "Monthly", "Cancelled", day(2026, 3, 11)),
+		sub("Lifetime", "Lifetime", "Active", day(2025, 5, 1)),
+	}
+
+	var names []string
+	upcoming := upcomingRenewals(subs, now, 5)
+	for _, s := range upcoming {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"Today", "Stale", "Annual"}, names)
+	assert.Equal(t, day(2026, 3, 12), *upcoming[1].RenewalDate, "a passed renewal date is projected by the schedule")
+	assert.Equal(t, day(2026, 1, 12), *stale.RenewalDate, "the subscription itself is left unchanged")
+
+	assert.Len(t, upcomingRenewals(subs, now, 2), 2)
+}
+
+func TestDaysUntilRenewal(t *testing.T) {
+	now := time.Date(2026, 3, 10, 23, 30, 0, 0, time.UTC)
+	days := func(renewal time.Time) int {
+		return daysUntilRenewal(&models.Subscription{RenewalDate: timePtr(renewal)}, now)
+	}
+	assert.Equal(t, 0, days(time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 1, days(time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 31, days(time.Date(2026, 4, 10, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestICalRenewals(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	dates := func(events []icalRenewal) []time.Time {
+		var result []time.Time
+		for _, event := range events {
+			result = append(result, event.date)
+		}
+		return result
+	}
+	today := day(2026, 1, 10)
+	horizon := models.CalendarFeedOptions{HorizonMonths: 3}
+
+	t.Run("Horizon emits each renewal from today on", func(t *testing.T) {
+		sub := &models.Subscription{Status: "Active", Schedule: "Monthly", RenewalDate: timePtr(day(2026, 1, 15))}
+		events := icalRenewals(sub, horizon, today)
+		assert.Equal(t, []time.Time{day(2026, 1, 15), day(2026, 2, 15), day(2026, 3, 15)}, dates(events))
+		assert.Empty(t, events[0].rrule)
+	})
+
+	t.Run("Horizon stops before the cancellation date", func(t *testing.T) {
+		sub := &models.Subscription{Status: "Active", Schedule: "Monthly", RenewalDate: timePtr(day(2026, 1, 15)), CancellationDate: timePtr(day(2026, 2, 15))}
+		assert.Equal(t, []time.Time{day(2026, 1, 15)}, dates(icalRenewals(sub, horizon, today)))
+	})
+
+	t.Run("Trials only get their next renewal", func(t *testing.T) {
+		sub := &models.Subscription{Status: "Trial", Schedule: "Monthly", RenewalDate: timePtr(day(2026, 1, 20))}
+		assert.Equal(t, []time.Time{day(2026, 1, 20)}, dates(icalRenewals(sub, horizon, today)))
+		sub.RenewalDate = timePtr(day(2026, 6, 1))
+		assert.Empty(t, icalRenewals(sub, horizon, today))
+	})
+
+	t.Run("Without a horizon the rule ends before the cancellation date", func(t *testing.T) {
+		sub := &models.Subscription{Status: "Active", Schedule: "Quarterly", RenewalDate: timePtr(day(2026, 1, 15)), CancellationDate: timePtr(day(2026, 12, 1))}
+		events := icalRenewals(sub, models.CalendarFeedOptions{}, today)
+		assert.Equal(t, []icalRenewal{{date: day(2026, 1, 15), rrule: "FREQ=MONTHLY;INTERVAL=3;UNTIL=20261130"}}, events)
+
+		sub.CancellationDate = nil
+		assert.Equal(t, "FREQ=MONTHLY;INTERVAL=3", icalRenewals(sub, models.CalendarFeedOptions{}, today)[0].rrule)
+	})
+
+	t.Run("Without a horizon a promotional price splits the rule", func(t *testing.T) {
+		promo := 4.99
+		sub := &models.Subscription{Status: "Active", Schedule: "Monthly", RenewalDate: timePtr(day(2026, 1, 15)), PromoCost: &promo, PromoEndDate: timePtr(day(2026, 3, 1))}
+		assert.Equal(t, []icalRenewal{
+			{date: day(2026, 1, 15), rrule: "FREQ=MONTHLY;INTERVAL=1;UNTIL=20260314"},
+			{date: day(2026, 3, 15), rrule: "FREQ=MONTHLY;INTERVAL=1"},
+		}, icalRenewals(sub, models.CalendarFeedOptions{}, today))
+
+		// Cancelled before the regular price applies, the promotional series is all
+		sub.CancellationDate = timePtr(day(2026, 3, 10))
+		assert.Equal(t, []icalRenewal{{date: day(2026, 1, 15), rrule: "FREQ=MONTHLY;INTERVAL=1;UNTIL=20260309"}}, icalRenewals(sub, models.CalendarFeedOptions{}, today))
+	})
+}
+
+func TestAPIInternalErrorReportsTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestTimeout(time.Millisecond))
+	router.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		apiInternalError(c, ErrInternalServer)
+	})
+	router.GET("/failing", func(c *gin.Context) { apiInternalError(c, ErrInternalServer) })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrRequestTimeout)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/failing", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestGetSubscriptionOccurrencesAPI_PromoEnds(t *testing.T) {
+	h, subscriptions := newTestSubscriptionHandler(t)
+	promo := 4.99
+	renewal := time.Date(2030, 1, 15, 0, 0, 0, 0, time.UTC)
+	promoEnd := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+	sub, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Streaming", Cost: 12.99, Schedule: "Monthly", Status: "Active",
+		OriginalCurrency: h.preferences.GetCurrency(), RenewalDate: &renewal, PromoCost: &promo, PromoEndDate: &promoEnd})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/subscriptions/:id/occurrences", h.GetSubscriptionOccurrencesAPI)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/subscriptions/%d/occurrences?from=2030-01-01&to=2030-04-30", sub.ID), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response SubscriptionOccurrencesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	var amounts []float64
+	for _, occurrence := range response.Occurrences {
+		amounts = append(amounts, occurrence.Amount)
+		require.NotNil(t, occurrence.DisplayAmount)
+		assert.Equal(t, occurrence.Amount, *occurrence.DisplayAmount)
+	}
+	// Charged at the promotional price until it ends on March 1
+	assert.Equal(t, []float64{4.99, 4.99, 12.99, 12.99}, amounts)
+}
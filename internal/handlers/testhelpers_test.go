@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens an in-memory database with the given tables
+func newTestDB(t testing.TB, tables ...any) *gorm.DB {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(tables...))
+	return db
+}
+
+// newTestSubscriptionHandler wires a subscription handler to the services of an
+// in-memory database, with only the dependencies the API tests use
+func newTestSubscriptionHandler(t testing.TB) (*SubscriptionHandler, *service.SubscriptionService) {
+	t.Helper()
+	return newTestSubscriptionHandlerWithDB(newTestDB(t, &models.Settings{}, &models.ExchangeRate{}, &models.Category{}, &models.Subscription{}))
+}
+
+func newTestSubscriptionHandlerWithDB(db *gorm.DB) (*SubscriptionHandler, *service.SubscriptionService) {
+	subscriptions, preferences, settings, currency := newTestSubscriptionServices(db)
+	defaults := service.NewSubscriptionDefaultsService(settings, preferences, service.NewCategoryService(repository.NewCategoryRepository(db)))
+	return &SubscriptionHandler{service: subscriptions, preferences: preferences, settings: settings, currencyService: currency, hooks: service.NewHookService(), defaults: defaults}, subscriptions
+}
+
+// newTestChangeProposalHandler wires a change proposal handler to a
+// subscription handler on the same in-memory database
+func newTestChangeProposalHandler(t testing.TB) (*ChangeProposalHandler, *service.SubscriptionService) {
+	t.Helper()
+	db := newTestDB(t, &models.Settings{}, &models.ExchangeRate{}, &models.Category{}, &models.Subscription{}, &models.ChangeProposal{},
+		&models.UsageEvent{}, &models.SubscriptionShare{}, &models.ReminderRetry{}, &models.Payment{})
+	subscriptionHandler, subscriptions := newTestSubscriptionHandlerWithDB(db)
+	proposals := service.NewChangeProposalService(repository.NewChangeProposalRepository(db), subscriptions)
+	settingsRepo := repository.NewSettingsRepository(db)
+	auth := service.NewAuthService(service.NewSettingsService(settingsRepo), settingsRepo)
+	return NewChangeProposalHandler(proposals, subscriptionHandler, auth), subscriptions
+}
+
+// newTestSettingsHandler wires a settings handler to the preferences of an
+// in-memory database
+func newTestSettingsHandler(t testing.TB) *SettingsHandler {
+	t.Helper()
+	db := newTestDB(t, &models.Settings{})
+	settings := service.NewSettingsService(repository.NewSettingsRepository(db))
+	return &SettingsHandler{settings: settings, preferences: service.NewPreferencesService(settings, testLangProvider{})}
+}
+
+// newTestImportHandler wires an import handler to the services of an in-memory
+// database
+func newTestImportHandler(t testing.TB) (*ImportHandler, *service.SubscriptionService) {
+	t.Helper()
+	db := newTestDB(t, &models.Settings{}, &models.ExchangeRate{}, &models.Category{}, &models.Subscription{},
+		&models.ImportBatch{}, &models.Payment{}, &models.CategoryRule{})
+	subscriptions, _, settings, _ := newTestSubscriptionServices(db)
+	categories := service.NewCategoryService(repository.NewCategoryRepository(db))
+	rules := service.NewCategoryRuleService(repository.NewCategoryRuleRepository(db), categories)
+	imports := service.NewImportService(subscriptions, categories, rules, service.NewRenewalService(), repository.NewImportBatchRepository(db), t.TempDir())
+	return &ImportHandler{importService: imports, settingsService: settings}, subscriptions
+}
+
+func newTestSubscriptionServices(db *gorm.DB) (*service.SubscriptionService, *service.PreferencesService, *service.SettingsService, *service.CurrencyService) {
+	settings := service.NewSettingsService(repository.NewSettingsRepository(db))
+	categories := service.NewCategoryService(repository.NewCategoryRepository(db))
+	currency := service.NewCurrencyService(repository.NewExchangeRateRepository(db), settings)
+	preferences := service.NewPreferencesService(settings, testLangProvider{})
+	subscriptions := service.NewSubscriptionService(repository.NewSubscriptionRepository(db), categories, currency, preferences, settings, service.NewRenewalService())
+	return subscriptions, preferences, settings, currency
+}
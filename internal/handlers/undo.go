@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UndoHandler restores deletions within their undo window
+type UndoHandler struct {
+	undo          service.UndoServiceInterface
+	subscriptions service.SubscriptionServiceInterface
+	hooks         service.HookServiceInterface
+}
+
+func NewUndoHandler(undo service.UndoServiceInterface, subscriptions service.SubscriptionServiceInterface, hooks service.HookServiceInterface) *UndoHandler {
+	return &UndoHandler{undo: undo, subscriptions: subscriptions, hooks: hooks}
+}
+
+// Undo restores the subscriptions deleted by the action of an undo token and
+// reloads the page to show them
+func (h *UndoHandler) Undo(c *gin.Context) {
+	action, err := h.undo.Undo(c.Request.Context(), c.Param("token"))
+	if errors.Is(err, service.ErrUndoExpired) {
+		apiError(c, http.StatusGone, tr(c, "undo_expired", "This can no longer be undone"))
+		return
+	}
+	if err != nil {
+		slog.Error("failed to undo deletion", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	// A restored subscription is announced like a new one; clearing all
+	// subscriptions fires no hooks, so neither does undoing it
+	if action.Kind == service.UndoDelete {
+		for _, id := range action.IDs {
+			if sub, err := h.subscriptions.GetByID(c.Request.Context(), id); err == nil {
+				h.hooks.Fire(service.EventSubscriptionCreated, sub)
+			}
+		}
+	}
+
+	c.Header("HX-Refresh", "true")
+	c.JSON(http.StatusOK, action)
+}
+
+// renderUndoToast answers a deletion with a toast that offers to undo it until
+// the undo window has passed
+func renderUndoToast(c *gin.Context, action *service.UndoAction, message string) {
+	c.Header("HX-Retarget", "#toast-container")
+	c.Header("HX-Reswap", "beforeend")
+	c.HTML(http.StatusOK, "undo-toast.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"Undo":    action,
+		"Message": message,
+		"Seconds": int(service.UndoWindow.Seconds()),
+	}))
+}
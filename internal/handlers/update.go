@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateHandler serves version information and the update check setting
+type UpdateHandler struct {
+	updates service.UpdateServiceInterface
+}
+
+func NewUpdateHandler(updates service.UpdateServiceInterface) *UpdateHandler {
+	return &UpdateHandler{updates: updates}
+}
+
+// GetVersion returns the running version, build info and, when update checks
+// are enabled, whether a newer release is available
+func (h *UpdateHandler) GetVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, h.updates.Status())
+}
+
+// ToggleUpdateCheck switches the update check on or off. Enabling it checks
+// right away instead of waiting for the next scheduled run.
+func (h *UpdateHandler) ToggleUpdateCheck(c *gin.Context) {
+	enabled := !h.updates.IsEnabled()
+	err := h.updates.SetEnabled(enabled)
+	if errors.Is(err, service.ErrOfflineMode) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Update checks are not available in offline mode"})
+		return
+	}
+	if err != nil {
+		slog.Error("failed to save update check setting", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if enabled {
+		go func() {
+			if err := h.updates.Check(c.Request.Context()); err != nil {
+				slog.Warn("update check failed", "error", err)
+			}
+		}()
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+}
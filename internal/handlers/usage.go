@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UsageHandler struct {
+	usage       service.UsageServiceInterface
+	preferences service.PreferencesServiceInterface
+}
+
+func NewUsageHandler(usage service.UsageServiceInterface, preferences service.PreferencesServiceInterface) *UsageHandler {
+	return &UsageHandler{usage: usage, preferences: preferences}
+}
+
+// LogUsageEventRequest is the optional body for logging a usage event.
+// OccurredAt accepts RFC 3339 or YYYY-MM-DD and defaults to now.
+type LogUsageEventRequest struct {
+	OccurredAt string `json:"occurred_at" form:"occurred_at"`
+	Note       string `json:"note" form:"note" binding:"omitempty,max=500"`
+}
+
+// LogUsageEvent records a use of a subscription
+func (h *UsageHandler) LogUsageEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+
+	var req LogUsageEventRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBind(&req); err != nil {
+			apiBadRequest(c, ErrInvalidRequestBody)
+			return
+		}
+	}
+
+	var occurredAt time.Time
+	if req.OccurredAt != "" {
+		occurredAt, err = parseUsageTime(req.OccurredAt)
+		if err != nil {
+			apiBadRequest(c, "Invalid occurred_at, use RFC 3339 or YYYY-MM-DD")
+			return
+		}
+	}
+
+	event, err := h.usage.LogEvent(c.Request.Context(), uint(id), occurredAt, req.Note)
+	switch {
+	case errors.Is(err, service.ErrUsageSubscriptionNotFound):
+		apiNotFound(c, ErrSubscriptionNotFound)
+		return
+	case errors.Is(err, service.ErrUsageInFuture):
+		apiBadRequest(c, "occurred_at cannot be in the future")
+		return
+	case err != nil:
+		slog.Error("failed to log usage event", "error", err, "id", id)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	// Let the dashboard refresh its usage insights
+	c.Header("HX-Trigger", "usageLogged")
+	c.JSON(http.StatusCreated, event)
+}
+
+// GetUsageEvents returns the most recent usage events of a subscription
+func (h *UsageHandler) GetUsageEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+	limit, _ := parsePagination(c)
+
+	events, err := h.usage.GetEvents(uint(id), limit)
+	if err != nil {
+		slog.Error("failed to get usage events", "error", err, "id", id)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// GetCostPerUse returns cost-per-use analytics and the "consider cancelling" list
+func (h *UsageHandler) GetCostPerUse(c *gin.Context) {
+	costPerUse, err := h.usage.GetCostPerUse(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to compute cost per use", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	considerCancelling, err := h.usage.GetConsiderCancelling(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to compute cancellation candidates", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"window_days":         service.UsageWindowDays,
+		"subscriptions":       costPerUse,
+		"consider_cancelling": considerCancelling,
+	})
+}
+
+// UsageInsights renders the dashboard card with unused subscriptions and cost per use
+func (h *UsageHandler) UsageInsights(c *gin.Context) {
+	considerCancelling, err := h.usage.GetConsiderCancelling(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to compute cancellation candidates", "error", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	costPerUse, err := h.usage.GetCostPerUse(c.Request.Context())
+	if err != nil {
+		slog.Error("failed to compute cost per use", "error", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	// Only show subscriptions that were used in the window, most expensive per use first
+	type costPerUseRow struct {
+		Name       string
+		Uses       int64
+		CostPerUse float64
+	}
+	var used []costPerUseRow
+	for _, entry := range costPerUse {
+		if entry.CostPerUse != nil && len(used) < 5 {
+			used = append(used, costPerUseRow{Name: entry.Name, Uses: entry.Uses, CostPerUse: *entry.CostPerUse})
+		}
+	}
+
+	c.HTML(http.StatusOK, "usage-insights.html", mergeTemplateData(baseTemplateData(c), gin.H{
+		"ConsiderCancelling": considerCancelling,
+		"CostPerUse":         used,
+		"WindowDays":         service.UsageWindowDays,
+		"CurrencySymbol":     h.preferences.GetCurrencySymbol(),
+	}))
+}
+
+func parseUsageTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
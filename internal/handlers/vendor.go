@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VendorHandler manages the vendors that group related subscriptions
+type VendorHandler struct {
+	vendors service.VendorServiceInterface
+}
+
+func NewVendorHandler(vendors service.VendorServiceInterface) *VendorHandler {
+	return &VendorHandler{vendors: vendors}
+}
+
+// vendorRequest names a vendor
+type vendorRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// ListVendors returns all vendors sorted by name
+func (h *VendorHandler) ListVendors(c *gin.Context) {
+	vendors, err := h.vendors.List()
+	if err != nil {
+		slog.Error("failed to list vendors", "error", err)
+		apiInternalError(c, ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": vendors})
+}
+
+// CreateVendor adds a vendor
+func (h *VendorHandler) CreateVendor(c *gin.Context) {
+	var req vendorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+	vendor, err := h.vendors.Create(req.Name)
+	if err != nil {
+		h.vendorError(c, err, 0)
+		return
+	}
+	c.JSON(http.StatusCreated, vendor)
+}
+
+// UpdateVendor renames a vendor
+func (h *VendorHandler) UpdateVendor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+	var req vendorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiBadRequest(c, ErrInvalidRequestBody)
+		return
+	}
+	vendor, err := h.vendors.Rename(uint(id), req.Name)
+	if err != nil {
+		h.vendorError(c, err, uint(id))
+		return
+	}
+	c.JSON(http.StatusOK, vendor)
+}
+
+// DeleteVendor removes a vendor; its subscriptions are kept without a vendor
+func (h *VendorHandler) DeleteVendor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		apiBadRequest(c, ErrInvalidID)
+		return
+	}
+	if err := h.vendors.Delete(uint(id)); err != nil {
+		h.vendorError(c, err, uint(id))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// vendorError maps vendor service errors to API responses
+func (h *VendorHandler) vendorError(c *gin.Context, err error, id uint) {
+	switch {
+	case errors.Is(err, service.ErrVendorNotFound):
+		apiNotFound(c, "Vendor not found")
+	case errors.Is(err, service.ErrInvalidVendor):
+		apiBadRequest(c, err.Error())
+	case errors.Is(err, service.ErrVendorExists):
+		apiError(c, http.StatusConflict, err.Error())
+	default:
+		slog.Error("failed to save vendor", "error", err, "id", id)
+		apiInternalError(c, ErrInternalServer)
+	}
+}
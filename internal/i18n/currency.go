@@ -0,0 +1,226 @@
+package i18n
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Display rounding preferences
+const (
+	// RoundingCurrency shows amounts with the minor unit of their currency
+	RoundingCurrency = "currency"
+	// RoundingWhole rounds displayed amounts to whole currency units
+	RoundingWhole = "whole"
+)
+
+//go:embed currencies.yaml
+var embeddedCurrencies []byte
+
+// Currency describes a currency amounts can be entered and shown in
+type Currency struct {
+	Code   string
+	Name   string
+	Symbol string
+	// Decimals is how many decimals amounts are written with
+	Decimals int
+	// ECB is set for currencies the ECB publishes daily rates for
+	ECB bool
+	// Unlisted currencies are only known for formatting amounts
+	Unlisted bool
+}
+
+// currencyEntry is a currency in a currencies file. Unset fields keep the
+// value of an already known currency.
+type currencyEntry struct {
+	Code     string `yaml:"code"`
+	Name     string `yaml:"name"`
+	Symbol   string `yaml:"symbol"`
+	Decimals *int   `yaml:"decimals"`
+	ECB      *bool  `yaml:"ecb"`
+	Unlisted *bool  `yaml:"unlisted"`
+}
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+var (
+	currenciesMu  sync.RWMutex
+	currencies    []Currency
+	currencyIndex map[string]int
+)
+
+func init() {
+	if err := mergeCurrencies(embeddedCurrencies); err != nil {
+		panic("invalid embedded currencies: " + err.Error())
+	}
+}
+
+// LoadCurrencies adds the currencies of a YAML file to the embedded ones and
+// overrides the fields it sets for currencies that are already known. A
+// missing file is not an error.
+func LoadCurrencies(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := mergeCurrencies(data); err != nil {
+		return fmt.Errorf("invalid currencies file %s: %w", path, err)
+	}
+	slog.Info("currencies loaded", "file", path)
+	return nil
+}
+
+func mergeCurrencies(data []byte) error {
+	var file struct {
+		Currencies []currencyEntry `yaml:"currencies"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	for i := range file.Currencies {
+		entry := &file.Currencies[i]
+		entry.Code = strings.ToUpper(strings.TrimSpace(entry.Code))
+		if !currencyCodePattern.MatchString(entry.Code) {
+			return fmt.Errorf("invalid currency code %q", entry.Code)
+		}
+		if entry.Decimals != nil && (*entry.Decimals < 0 || *entry.Decimals > 4) {
+			return fmt.Errorf("%s: decimals must be between 0 and 4", entry.Code)
+		}
+	}
+
+	currenciesMu.Lock()
+	defer currenciesMu.Unlock()
+	if currencyIndex == nil {
+		currencyIndex = make(map[string]int)
+	}
+	for _, entry := range file.Currencies {
+		i, known := currencyIndex[entry.Code]
+		if !known {
+			currencies = append(currencies, Currency{Code: entry.Code, Name: entry.Code, Symbol: entry.Code, Decimals: 2})
+			i = len(currencies) - 1
+			currencyIndex[entry.Code] = i
+		}
+		c := &currencies[i]
+		if entry.Name != "" {
+			c.Name = entry.Name
+		}
+		if entry.Symbol != "" {
+			c.Symbol = entry.Symbol
+		}
+		if entry.Decimals != nil {
+			c.Decimals = *entry.Decimals
+		}
+		if entry.ECB != nil {
+			c.ECB = *entry.ECB
+		}
+		if entry.Unlisted != nil {
+			c.Unlisted = *entry.Unlisted
+		}
+	}
+	return nil
+}
+
+// LookupCurrency returns a known currency
+func LookupCurrency(code string) (Currency, bool) {
+	currenciesMu.RLock()
+	defer currenciesMu.RUnlock()
+	if i, ok := currencyIndex[code]; ok {
+		return currencies[i], true
+	}
+	return Currency{}, false
+}
+
+// Currencies returns the currencies that can be chosen, in file order
+func Currencies() []Currency {
+	currenciesMu.RLock()
+	defer currenciesMu.RUnlock()
+	listed := make([]Currency, 0, len(currencies))
+	for _, c := range currencies {
+		if !c.Unlisted {
+			listed = append(listed, c)
+		}
+	}
+	return listed
+}
+
+// CurrencyCodes returns the codes of the currencies that can be chosen
+func CurrencyCodes() []string {
+	listed := Currencies()
+	codes := make([]string, len(listed))
+	for i, c := range listed {
+		codes[i] = c.Code
+	}
+	return codes
+}
+
+// CurrencySymbol returns the symbol written before amounts in a currency, "$"
+// for unknown currencies
+func CurrencySymbol(code string) string {
+	if c, ok := LookupCurrency(code); ok {
+		return c.Symbol
+	}
+	return "$"
+}
+
+// CurrencyDecimals returns how many decimals amounts in a currency are written
+// with, two for unknown currencies
+func CurrencyDecimals(code string) int {
+	if c, ok := LookupCurrency(code); ok {
+		return c.Decimals
+	}
+	return 2
+}
+
+// CurrencyHasECBRate reports whether the ECB publishes rates for a currency.
+// EUR is the base of the ECB rates.
+func CurrencyHasECBRate(code string) bool {
+	c, ok := LookupCurrency(code)
+	return code == "EUR" || ok && c.ECB
+}
+
+// ValidRounding reports whether rounding is a display rounding preference
+func ValidRounding(rounding string) bool {
+	return rounding == RoundingCurrency || rounding == RoundingWhole
+}
+
+// DisplayDecimals returns the decimals amounts in a currency are displayed with
+func DisplayDecimals(code, rounding string) int {
+	if rounding == RoundingWhole {
+		return 0
+	}
+	return CurrencyDecimals(code)
+}
+
+// FormatAmount writes an amount without currency symbol, rounded for display
+func FormatAmount(amount float64, code, rounding string) string {
+	decimals := DisplayDecimals(code, rounding)
+	return strconv.FormatFloat(roundDecimals(amount, decimals), 'f', decimals, 64)
+}
+
+// RoundAmount rounds an amount to the precision of its currency, for amounts
+// handed to other programs as numbers
+func RoundAmount(amount float64, code string) float64 {
+	return roundDecimals(amount, CurrencyDecimals(code))
+}
+
+func roundDecimals(amount float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	rounded := math.Round(amount*scale) / scale
+	if rounded == 0 {
+		// Avoid "-0" for small negative amounts
+		rounded = 0
+	}
+	return rounded
+}
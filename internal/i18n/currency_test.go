@@ -0,0 +1,92 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		code     string
+		rounding string
+		want     string
+	}{
+		{12.5, "EUR", RoundingCurrency, "12.50"},
+		{1234.56, "JPY", RoundingCurrency, "1235"},
+		{3.4567, "KWD", RoundingCurrency, "3.457"},
+		{12.5, "EUR", RoundingWhole, "13"},
+		{3.4567, "KWD", RoundingWhole, "3"},
+		{-0.004, "USD", RoundingCurrency, "0.00"},
+		{-0.4, "EUR", RoundingWhole, "0"},
+		{-7.25, "USD", RoundingCurrency, "-7.25"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FormatAmount(tt.amount, tt.code, tt.rounding), "%v %s %s", tt.amount, tt.code, tt.rounding)
+	}
+}
+
+func TestRoundAmount(t *testing.T) {
+	assert.Equal(t, 12.35, RoundAmount(12.345678, "EUR"))
+	assert.Equal(t, 1235.0, RoundAmount(1234.56, "JPY"))
+	assert.Equal(t, 3.457, RoundAmount(3.4567, "KWD"))
+	assert.Equal(t, 7.13, RoundAmount(7.125, "XYZ"), "unknown currencies keep two decimals")
+	assert.Equal(t, 0.0, RoundAmount(-0.001, "USD"))
+}
+
+func TestTranslationHelper_Amount(t *testing.T) {
+	helper := &TranslationHelper{}
+	helper.SetCurrencyFormat("JPY", RoundingCurrency)
+	assert.Equal(t, "980", helper.Amount(980.4))
+	assert.Equal(t, "9.99", helper.AmountIn(9.99, "USD"))
+	assert.Equal(t, "980", helper.AmountIn(980.4, ""))
+
+	helper.SetCurrencyFormat("EUR", RoundingWhole)
+	assert.Equal(t, "10", helper.AmountIn(9.99, "USD"))
+}
+
+func TestLoadCurrencies(t *testing.T) {
+	saved, savedIndex := slices.Clone(currencies), currencyIndex
+	t.Cleanup(func() {
+		currencies, currencyIndex = saved, savedIndex
+	})
+	currencyIndex = make(map[string]int, len(savedIndex))
+	for code, i := range savedIndex {
+		currencyIndex[code] = i
+	}
+
+	assert.Equal(t, "EUR", CurrencyCodes()[0])
+	assert.NotContains(t, CurrencyCodes(), "KWD")
+	assert.Equal(t, "₽", CurrencySymbol("RUB"))
+	assert.False(t, CurrencyHasECBRate("RUB"))
+	assert.True(t, CurrencyHasECBRate("EUR"))
+	assert.Equal(t, "$", CurrencySymbol("XXX"))
+
+	// A missing file keeps the embedded currencies
+	require.NoError(t, LoadCurrencies(filepath.Join(t.TempDir(), "missing.yaml")))
+
+	path := filepath.Join(t.TempDir(), "currencies.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`currencies:
+  - {code: ghs, name: Ghanaian Cedi, symbol: "GH₵"}
+  - {code: RUB, symbol: "руб", decimals: 0}
+`), 0o644))
+	require.NoError(t, LoadCurrencies(path))
+
+	assert.Equal(t, "GHS", CurrencyCodes()[len(CurrencyCodes())-1])
+	ghs, ok := LookupCurrency("GHS")
+	require.True(t, ok)
+	assert.Equal(t, Currency{Code: "GHS", Name: "Ghanaian Cedi", Symbol: "GH₵", Decimals: 2}, ghs)
+
+	// Only the fields that are set are overridden
+	rub, _ := LookupCurrency("RUB")
+	assert.Equal(t, Currency{Code: "RUB", Name: "Russian Ruble", Symbol: "руб", Decimals: 0}, rub)
+	assert.Equal(t, "1235", FormatAmount(1234.56, "RUB", RoundingCurrency))
+
+	require.NoError(t, os.WriteFile(path, []byte(`currencies: [{code: EURO}]`), 0o644))
+	assert.Error(t, LoadCurrencies(path))
+}
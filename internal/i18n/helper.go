@@ -1,6 +1,7 @@
 package i18n
 
 import (
+	"strings"
 	"time"
 
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -12,6 +13,8 @@ type TranslationHelper struct {
 	service    *I18nService
 	lang       string
 	dateFormat string // Go format string, e.g. "02.01.2006"
+	currency   string // Display currency
+	rounding   string // Display rounding preference
 }
 
 // NewTranslationHelper creates a new TranslationHelper for use in templates
@@ -28,6 +31,26 @@ func (h *TranslationHelper) SetDateFormat(format string) {
 	h.dateFormat = format
 }
 
+// SetCurrencyFormat sets the display currency and rounding used by Amount
+func (h *TranslationHelper) SetCurrencyFormat(currency, rounding string) {
+	h.currency = currency
+	h.rounding = rounding
+}
+
+// Amount formats an amount in the display currency, without symbol
+func (h *TranslationHelper) Amount(amount float64) string {
+	return FormatAmount(amount, h.currency, h.rounding)
+}
+
+// AmountIn formats an amount in the given currency, without symbol. An empty
+// currency is the display currency.
+func (h *TranslationHelper) AmountIn(amount float64, currency string) string {
+	if currency == "" {
+		currency = h.currency
+	}
+	return FormatAmount(amount, currency, h.rounding)
+}
+
 // FormatDate formats a date according to the current locale.
 // Accepts time.Time or *time.Time.
 func (h *TranslationHelper) FormatDate(v any) string {
@@ -59,6 +82,19 @@ func (h *TranslationHelper) Tr(messageID string) string {
 	return h.service.T(h.localizer, messageID)
 }
 
+// CurrencyName translates the name of a currency, falling back to the name in
+// the currencies file for currencies the locale has no message for
+func (h *TranslationHelper) CurrencyName(code string) string {
+	messageID := "currency_" + strings.ToLower(code)
+	if name := h.Tr(messageID); name != messageID {
+		return name
+	}
+	if c, ok := LookupCurrency(code); ok {
+		return c.Name
+	}
+	return code
+}
+
 // TrData translates a string with template data
 func (h *TranslationHelper) TrData(messageID string, data map[string]interface{}) string {
 	return h.service.TData(h.localizer, messageID, data)
@@ -0,0 +1,122 @@
+package i18n
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidNumber is returned by ParseNumber for text that is not a number
+var ErrInvalidNumber = errors.New("invalid number")
+
+// commaDecimalLanguages are the languages that write "9,99" and group
+// thousands with a dot
+var commaDecimalLanguages = map[string]bool{
+	"de": true, "fr": true, "es": true, "it": true, "nl": true, "pt": true,
+	"pl": true, "cs": true, "da": true, "sv": true, "nb": true, "fi": true,
+	"ru": true, "tr": true,
+}
+
+// ParseNumber reads a number typed by a user of the given language. Both "9.99"
+// and "9,99" are accepted, as are thousands groups like "1.234,56",
+// "1,234.56", "1 234,56" or "1'234.56". Only a single separator followed by
+// exactly three digits is ambiguous: it groups thousands if it is the
+// language's grouping separator and is the decimal separator otherwise, so
+// "1.234" is 1234 in German and 1.234 in English.
+func ParseNumber(text, lang string) (float64, error) {
+	text = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f', '\'':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(text))
+
+	sign := ""
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+") {
+		sign, text = text[:1], text[1:]
+	}
+	if text == "" || strings.Trim(text, "0123456789.,") != "" {
+		return 0, ErrInvalidNumber
+	}
+
+	group := ","
+	if commaDecimalLanguages[baseLanguage(lang)] {
+		group = "."
+	}
+
+	lastDot, lastComma := strings.LastIndex(text, "."), strings.LastIndex(text, ",")
+	decimal := ""
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		// The separator that comes last is the decimal one
+		decimal = "."
+		if lastComma > lastDot {
+			decimal = ","
+		}
+	case lastDot >= 0 || lastComma >= 0:
+		separator := "."
+		if lastComma >= 0 {
+			separator = ","
+		}
+		last := max(lastDot, lastComma)
+		if strings.Count(text, separator) == 1 && (len(text)-last-1 != 3 || separator != group) {
+			decimal = separator
+		}
+	}
+
+	integer, fraction := text, ""
+	if decimal != "" {
+		i := strings.LastIndex(text, decimal)
+		integer, fraction = text[:i], text[i+1:]
+		if strings.ContainsAny(fraction, ".,") {
+			return 0, ErrInvalidNumber
+		}
+	}
+	integer, ok := ungroup(integer)
+	if !ok {
+		return 0, ErrInvalidNumber
+	}
+	if integer == "" && fraction == "" {
+		return 0, ErrInvalidNumber
+	}
+	if integer == "" {
+		integer = "0"
+	}
+
+	number := sign + integer
+	if fraction != "" {
+		number += "." + fraction
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, ErrInvalidNumber
+	}
+	return value, nil
+}
+
+// ungroup removes thousands separators from the integer part of a number. All
+// groups after the first must have three digits and use the same separator.
+func ungroup(integer string) (string, bool) {
+	i := strings.IndexAny(integer, ".,")
+	if i < 0 {
+		return integer, true
+	}
+	separator := integer[i : i+1]
+	groups := strings.Split(integer, separator)
+	if groups[0] == "" || len(groups[0]) > 3 {
+		return "", false
+	}
+	for _, g := range groups[1:] {
+		if len(g) != 3 || strings.ContainsAny(g, ".,") {
+			return "", false
+		}
+	}
+	return strings.Join(groups, ""), true
+}
+
+// baseLanguage returns the language of a tag like "de-AT"
+func baseLanguage(lang string) string {
+	base, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	return base
+}
@@ -0,0 +1,43 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		text string
+		lang string
+		want float64
+	}{
+		{"9.99", "en", 9.99},
+		{"9,99", "en", 9.99},
+		{"9,99", "de", 9.99},
+		{" 12 ", "de", 12},
+		{"1,234.56", "en", 1234.56},
+		{"1.234,56", "de", 1234.56},
+		{"1.234,56", "en", 1234.56},
+		{"1 234,56", "fr", 1234.56},
+		{"1'234.56", "de", 1234.56},
+		{"1.234.567", "en", 1234567},
+		{"1,234", "en", 1234},
+		{"1.234", "en", 1.234},
+		{"1.234", "de-AT", 1234},
+		{"1,234", "de", 1.234},
+		{",5", "de", 0.5},
+		{"-3,5", "de", -3.5},
+	}
+	for _, tt := range tests {
+		got, err := ParseNumber(tt.text, tt.lang)
+		if assert.NoError(t, err, tt.text) {
+			assert.InDelta(t, tt.want, got, 1e-9, "%s (%s)", tt.text, tt.lang)
+		}
+	}
+
+	for _, text := range []string{"", "abc", "9.99€", "1,2,3", "12.34.5", "1.23,4.5", ".", "Inf", "1e5", "--1"} {
+		_, err := ParseNumber(text, "en")
+		assert.ErrorIs(t, err, ErrInvalidNumber, text)
+	}
+}
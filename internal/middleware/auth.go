@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
+	"subvault/internal/models"
 	"subvault/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -25,8 +27,14 @@ func AuthMiddleware(authService service.AuthServiceInterface, sessionService *se
 			return
 		}
 
-		// Check if user is authenticated
-		if !sessionService.IsAuthenticated(c.Request) {
+		// Check if user is authenticated. Viewer sessions end when the viewer
+		// account is removed.
+		role := sessionService.GetRole(c.Request)
+		authenticated := sessionService.IsAuthenticated(c.Request)
+		if authenticated && role == service.RoleViewer {
+			_, authenticated = authService.GetViewerUsername()
+		}
+		if !authenticated {
 			// Redirect to login page for HTML requests
 			if isHTMLRequest(c.Request) {
 				c.Redirect(http.StatusFound, "/login?redirect="+url.QueryEscape(c.Request.URL.Path))
@@ -40,10 +48,82 @@ func AuthMiddleware(authService service.AuthServiceInterface, sessionService *se
 			return
 		}
 
+		c.Set(contextKeyRole, role)
+
+		// Sliding expiration: activity keeps the session alive
+		if err := sessionService.RefreshSession(c.Writer, c.Request); err != nil {
+			slog.Warn("failed to refresh session", "error", err)
+		}
+
+		// Viewers can look at the subscriptions and reports, but only propose changes
+		if role == service.RoleViewer && !isViewerAllowed(c.Request) {
+			if c.Request.Method == http.MethodGet && isHTMLRequest(c.Request) && c.GetHeader("HX-Request") == "" {
+				c.Redirect(http.StatusFound, "/")
+				c.Abort()
+				return
+			}
+			c.JSON(http.StatusForbidden, gin.H{"error": "Read-only access"})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
 
+// contextKeyRole is the context key holding the role of the logged-in user
+const contextKeyRole = "auth_role"
+
+// viewerRoutes are the pages and read-only endpoints viewers may open, with
+// everything below them. Anything else, like settings, edit forms or cards that
+// contact a bank or budgeting tool, stays closed, including routes added later.
+var viewerRoutes = []string{
+	"/dashboard",
+	"/subscriptions",
+	"/analytics",
+	"/calendar",
+	"/tax-report",
+	"/renewals",
+	"/proposals",
+	"/api/subscriptions",
+	"/api/stats",
+	"/api/version",
+	"/api/reports/tax",
+	"/api/payments",
+	"/api/proposals",
+	"/api/usage",
+	"/api/splits",
+	"/api/export",
+	"/api/categories",
+	"/api/vendors",
+	"/api/search",
+}
+
+// viewerProposalRoute is where viewers propose changes for the admin to
+// approve, the only change they may make
+const viewerProposalRoute = "/api/proposals"
+
+// isViewerAllowed checks if a read-only viewer may perform a request
+func isViewerAllowed(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	case http.MethodPost:
+		return r.URL.Path == viewerProposalRoute
+	default:
+		return false
+	}
+	path := r.URL.Path
+	if path == "/" {
+		return true
+	}
+	for _, route := range viewerRoutes {
+		if path == route || strings.HasPrefix(path, route+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // isPublicRoute checks if a route should be accessible without authentication
 func isPublicRoute(path string) bool {
 	publicRoutes := []string{
@@ -54,10 +134,14 @@ func isPublicRoute(path string) bool {
 		"/api/auth/logout",
 		"/api/auth/forgot-password",
 		"/api/auth/reset-password",
+		"/api/auth/password-strength",
 		"/static/",
 		"/favicon.ico",
+		"/theme.css",
 		"/healthz",
+		"/readyz",
 		"/cal/",
+		"/inbound/",
 	}
 
 	// API v1 routes use API keys, not session auth
@@ -80,8 +164,37 @@ func isHTMLRequest(r *http.Request) bool {
 	return strings.Contains(accept, "text/html") || accept == ""
 }
 
-// APIKeyAuth creates middleware that requires API key authentication
+// apiKeyQuery is which API keys may be passed in the api_key query parameter
+type apiKeyQuery int
+
+const (
+	apiKeyQueryNone     apiKeyQuery = iota // Only headers
+	apiKeyQueryAny                         // Any key
+	apiKeyQueryReadOnly                    // Only read-only keys
+)
+
+// APIKeyAuth creates middleware that requires API key authentication. Read-only
+// keys are limited to GET and HEAD requests.
 func APIKeyAuth(apiKeyService service.APIKeyServiceInterface) gin.HandlerFunc {
+	return apiKeyAuth(apiKeyService, apiKeyQueryNone)
+}
+
+// APIKeyQueryAuth is APIKeyAuth that also accepts the key in the api_key query
+// parameter, for automation apps like Shortcuts or Tasker that cannot set
+// headers easily. Query strings end up in logs and history, so it is only used
+// for the shortcut that adds a subscription.
+func APIKeyQueryAuth(apiKeyService service.APIKeyServiceInterface) gin.HandlerFunc {
+	return apiKeyAuth(apiKeyService, apiKeyQueryAny)
+}
+
+// APIKeyFeedAuth is APIKeyAuth for feeds that calendar apps subscribe to and
+// widgets that poll a URL. They keep the URL around, so the api_key query
+// parameter only accepts read-only keys.
+func APIKeyFeedAuth(apiKeyService service.APIKeyServiceInterface) gin.HandlerFunc {
+	return apiKeyAuth(apiKeyService, apiKeyQueryReadOnly)
+}
+
+func apiKeyAuth(apiKeyService service.APIKeyServiceInterface, query apiKeyQuery) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("X-API-Key")
 
@@ -93,6 +206,12 @@ func APIKeyAuth(apiKeyService service.APIKeyServiceInterface) gin.HandlerFunc {
 			}
 		}
 
+		fromQuery := false
+		if apiKey == "" && query != apiKeyQueryNone {
+			apiKey = c.Query("api_key")
+			fromQuery = apiKey != ""
+		}
+
 		if apiKey == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
 			c.Abort()
@@ -100,13 +219,42 @@ func APIKeyAuth(apiKeyService service.APIKeyServiceInterface) gin.HandlerFunc {
 		}
 
 		// Validate API key
-		_, err := apiKeyService.ValidateAPIKey(apiKey)
+		key, err := apiKeyService.ValidateAPIKey(apiKey)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
 			c.Abort()
 			return
 		}
 
+		if fromQuery && query == apiKeyQueryReadOnly && !key.IsReadOnly() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only read-only API keys can be passed as api_key"})
+			c.Abort()
+			return
+		}
+		if key.IsReadOnly() && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key is read-only"})
+			c.Abort()
+			return
+		}
+
+		c.Set(apiKeyContextKey, key)
+		c.Next()
+	}
+}
+
+// apiKeyContextKey holds the authenticated *models.APIKey in the gin context
+const apiKeyContextKey = "api_key"
+
+// RequireAPIKeyScope creates middleware that only lets API keys with the given
+// scope through. It must run after APIKeyAuth.
+func RequireAPIKeyScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := c.Get(apiKeyContextKey)
+		if apiKey, isKey := key.(*models.APIKey); !ok || !isKey || !apiKey.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + scope + " scope"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
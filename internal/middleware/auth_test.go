@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsViewerAllowed(t *testing.T) {
+	allowed := []string{
+		"/",
+		"/dashboard",
+		"/subscriptions",
+		"/renewals",
+		"/api/subscriptions/3",
+		"/api/stats/categories/2/subscriptions",
+		"/api/splits/settlement/card",
+		"/api/export/csv",
+	}
+	for _, path := range allowed {
+		assert.True(t, isViewerAllowed(httptest.NewRequest(http.MethodGet, path, nil)), path)
+	}
+
+	blocked := []string{
+		"/settings",
+		"/settings/security",
+		"/api-docs",
+		"/form/subscription/3",
+		"/quick-add",
+		"/api/settings/smtp",
+		"/api/backup",
+		"/api/import/batches",
+		"/api/inbound-email",
+		"/api/bank",
+		"/api/budget",
+		"/api/budget/export",
+		"/api/logos",
+		"/api/category-rules",
+		"/api/subscriptionsx",
+	}
+	for _, path := range blocked {
+		assert.False(t, isViewerAllowed(httptest.NewRequest(http.MethodGet, path, nil)), path)
+	}
+
+	assert.True(t, isViewerAllowed(httptest.NewRequest(http.MethodPost, "/api/proposals", nil)))
+	assert.False(t, isViewerAllowed(httptest.NewRequest(http.MethodPost, "/api/proposals/1/approve", nil)), "only the admin decides")
+	assert.False(t, isViewerAllowed(httptest.NewRequest(http.MethodPost, "/api/proposals/1/reject", nil)), "only the admin decides")
+	assert.False(t, isViewerAllowed(httptest.NewRequest(http.MethodPost, "/api/subscriptions", nil)))
+	assert.False(t, isViewerAllowed(httptest.NewRequest(http.MethodDelete, "/api/subscriptions/3", nil)))
+}
@@ -65,6 +65,9 @@ func isCSRFExempt(path string) bool {
 	if strings.HasPrefix(path, "/cal/") {
 		return true
 	}
+	if strings.HasPrefix(path, "/inbound/") {
+		return true
+	}
 
 	exemptPaths := []string{"/favicon.ico", "/healthz", "/manifest.json"}
 	for _, p := range exemptPaths {
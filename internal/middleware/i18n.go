@@ -16,6 +16,7 @@ func I18nMiddleware(i18nService *i18n.I18nService, preferences service.Preferenc
 		if df := preferences.GetDateFormat(); df != "" {
 			helper.SetDateFormat(df)
 		}
+		helper.SetCurrencyFormat(preferences.GetCurrency(), preferences.GetDisplayRounding())
 
 		c.Set("lang", lang)
 		c.Set("localizer", localizer)
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+// RequestMetrics times every request except static files and records it in
+// metrics, together with the database and template time the request's trace
+// collected. It should run first so the trace reaches all later middleware.
+func RequestMetrics(metrics *service.RequestMetrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if strings.HasPrefix(path, "/static/") || strings.HasPrefix(path, "/logos/") {
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = "(unmatched)"
+		}
+		ctx, trace := service.WithRequestTrace(c.Request.Context(), route)
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &tracedWriter{ResponseWriter: c.Writer, trace: trace}
+
+		start := time.Now()
+		c.Next()
+
+		metrics.RecordRequest(service.RequestTiming{
+			Method:   c.Request.Method,
+			Route:    route,
+			Path:     path,
+			Status:   c.Writer.Status(),
+			Duration: time.Since(start),
+			At:       start,
+		}, trace)
+	}
+}
+
+// tracedWriter carries the request trace to TimedHTMLRender, which only sees
+// the response writer
+type tracedWriter struct {
+	gin.ResponseWriter
+	trace *service.RequestTrace
+}
+
+// TimedHTMLRender wraps the router's HTML renderer so that template rendering
+// time is added to the trace of the request it renders for
+func TimedHTMLRender(r render.HTMLRender) render.HTMLRender {
+	return timedHTMLRender{r}
+}
+
+type timedHTMLRender struct {
+	render.HTMLRender
+}
+
+func (r timedHTMLRender) Instance(name string, data any) render.Render {
+	return timedRender{r.HTMLRender.Instance(name, data)}
+}
+
+type timedRender struct {
+	inner render.Render
+}
+
+func (r timedRender) WriteContentType(w http.ResponseWriter) {
+	r.inner.WriteContentType(w)
+}
+
+func (r timedRender) Render(w http.ResponseWriter) error {
+	start := time.Now()
+	err := r.inner.Render(w)
+	if tw, ok := w.(*tracedWriter); ok {
+		tw.trace.AddRender(time.Since(start))
+	}
+	return err
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"subvault/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateStatusMiddleware exposes the version and update check result to templates
+func UpdateStatusMiddleware(updates service.UpdateServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("update_status", updates.Status())
+		c.Next()
+	}
+}
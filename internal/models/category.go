@@ -4,9 +4,11 @@ import "time"
 
 // Category represents a subscription category
 type Category struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" gorm:"uniqueIndex;not null"`
-	IsDefault bool      `json:"is_default" gorm:"default:false"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	Name      string `json:"name" gorm:"uniqueIndex;not null"`
+	IsDefault bool   `json:"is_default" gorm:"default:false"`
+	// ImportBatchID is set when the category was created by an import
+	ImportBatchID *uint     `json:"-" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
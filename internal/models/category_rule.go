@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Import sources a CategoryRule can be limited to. An empty source applies the
+// rule to every importer.
+const (
+	CategoryRuleSourceAny       = ""
+	CategoryRuleSourceWallos    = "wallos"
+	CategoryRuleSourceSubTrackr = "subtrackr"
+	CategoryRuleSourceBundle    = "svbundle"
+	CategoryRuleSourceYNAB      = BudgetProviderYNAB
+	CategoryRuleSourceFirefly   = BudgetProviderFirefly
+)
+
+// CategoryRule maps a category name found in an import file to an existing
+// category, e.g. Wallos "Streaming" to "Entertainment"
+type CategoryRule struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Source     string    `json:"source" gorm:"size:20;not null;default:'';uniqueIndex:idx_category_rule_match"`
+	Match      string    `json:"match" gorm:"not null;uniqueIndex:idx_category_rule_match"`
+	CategoryID uint      `json:"category_id" gorm:"not null;index"`
+	Category   Category  `json:"category" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// IsValidCategoryRuleSource reports whether source is a known import source
+func IsValidCategoryRuleSource(source string) bool {
+	switch source {
+	case CategoryRuleSourceAny, CategoryRuleSourceWallos, CategoryRuleSourceSubTrackr, CategoryRuleSourceBundle, CategoryRuleSourceYNAB, CategoryRuleSourceFirefly:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Change proposal actions
+const (
+	ProposalCreate = "create" // Add a new subscription
+	ProposalUpdate = "update" // Change fields of a subscription
+	ProposalDelete = "delete" // Delete a subscription
+)
+
+// Change proposal statuses
+const (
+	ProposalPending  = "pending"  // Waiting for the admin
+	ProposalApproved = "approved" // Applied by the admin
+	ProposalRejected = "rejected" // Dismissed by the admin
+)
+
+// ChangeProposal is a subscription change suggested by a viewer. It waits in
+// the pending queue until the admin applies or rejects it. Changes holds the
+// proposed fields as JSON, keyed like the subscription API.
+type ChangeProposal struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	SubscriptionID *uint      `json:"subscription_id,omitempty" gorm:"index"` // Unset for a new subscription
+	Name           string     `json:"name"`                                   // Subscription name when proposed, kept after a delete
+	Action         string     `json:"action" gorm:"size:10;not null"`
+	Changes        string     `json:"-" gorm:"type:text"`
+	Note           string     `json:"note"` // Why the change is proposed
+	ProposedBy     string     `json:"proposed_by"`
+	Status         string     `json:"status" gorm:"size:10;not null;index"`
+	DecidedAt      *time.Time `json:"decided_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
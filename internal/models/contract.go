@@ -0,0 +1,124 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/dromara/carbon/v2"
+)
+
+// ContractDecisionWindowDays is how many days before the deadline to cancel
+// or extend a contract it shows up on the dashboard and a reminder is sent
+const ContractDecisionWindowDays = 30
+
+// defaultContractRenewalMonths is the term an auto-renewing contract without a
+// minimum term renews for
+const defaultContractRenewalMonths = 12
+
+// ContractDecision is a contract whose decision deadline is near
+type ContractDecision struct {
+	Subscription *Subscription `json:"subscription"`
+	ExitDate     time.Time     `json:"exit_date"`  // Earliest date the contract can end
+	DecideBy     time.Time     `json:"decide_by"`  // Last day to give notice, or the end of a fixed term
+	DaysLeft     int           `json:"days_left"`  // Days until DecideBy
+	AutoRenew    bool          `json:"auto_renew"` // Whether the contract renews unless cancelled
+}
+
+// HasContract reports whether the subscription has a contract term, either an
+// end date or a start date with a minimum term
+func (s *Subscription) HasContract() bool {
+	return s.ContractEndDate != nil || (s.ContractStartDate != nil && s.MinimumTermMonths > 0)
+}
+
+// contractTermEnd returns the end of the first contract term
+func (s *Subscription) contractTermEnd() *time.Time {
+	if s.ContractEndDate != nil {
+		end := *s.ContractEndDate
+		return &end
+	}
+	if s.ContractStartDate != nil && s.MinimumTermMonths > 0 {
+		end := carbon.CreateFromStdTime(*s.ContractStartDate).AddMonthsNoOverflow(s.MinimumTermMonths).StdTime()
+		return &end
+	}
+	return nil
+}
+
+// EarliestExitDate returns the first date the contract can end when notice is
+// given on today. Auto-renewing contracts renew by their minimum term (a year
+// without one) until a term end whose notice period has not started yet; a
+// fixed-term contract ends at the end of its term. It returns nil without a
+// contract term.
+func (s *Subscription) EarliestExitDate(today time.Time) *time.Time {
+	end := s.contractTermEnd()
+	if end == nil || !s.AutoRenew {
+		return end
+	}
+	term := s.MinimumTermMonths
+	if term <= 0 {
+		term = defaultContractRenewalMonths
+	}
+	day := startOfDay(today)
+	current := carbon.CreateFromStdTime(*end)
+	for startOfDay(current.StdTime()).AddDate(0, 0, -s.NoticePeriodDays).Before(day) {
+		current = current.AddMonthsNoOverflow(term)
+	}
+	exit := current.StdTime()
+	return &exit
+}
+
+// ContractDecideBy returns the deadline for deciding about the contract: the
+// last day to give notice for an auto-renewing contract, or the end of a fixed
+// term. It returns nil without a contract term.
+func (s *Subscription) ContractDecideBy(today time.Time) *time.Time {
+	exit := s.EarliestExitDate(today)
+	if exit == nil || !s.AutoRenew {
+		return exit
+	}
+	decideBy := exit.AddDate(0, 0, -s.NoticePeriodDays)
+	return &decideBy
+}
+
+// ContractDecision returns the decision about the contract if its deadline is
+// at most ContractDecisionWindowDays days after today. Fixed-term contracts
+// that already ended and cancelled subscriptions have none.
+func (s *Subscription) ContractDecision(today time.Time) (*ContractDecision, bool) {
+	if s.Status == "Cancelled" {
+		return nil, false
+	}
+	exit := s.EarliestExitDate(today)
+	if exit == nil {
+		return nil, false
+	}
+	decideBy := s.ContractDecideBy(today)
+	daysLeft := int(startOfDay(*decideBy).Sub(startOfDay(today)).Hours() / 24)
+	if daysLeft < 0 || daysLeft > ContractDecisionWindowDays {
+		return nil, false
+	}
+	return &ContractDecision{
+		Subscription: s,
+		ExitDate:     *exit,
+		DecideBy:     *decideBy,
+		DaysLeft:     daysLeft,
+		AutoRenew:    s.AutoRenew,
+	}, true
+}
+
+// ContractDecisions returns the contract decisions due within the decision
+// window, nearest deadline first
+func ContractDecisions(subs []Subscription, today time.Time) []ContractDecision {
+	var decisions []ContractDecision
+	for i := range subs {
+		if decision, ok := subs[i].ContractDecision(today); ok {
+			decisions = append(decisions, *decision)
+		}
+	}
+	sort.SliceStable(decisions, func(i, j int) bool {
+		return decisions[i].DecideBy.Before(decisions[j].DecideBy)
+	})
+	return decisions
+}
+
+// startOfDay returns midnight of t's day in its location
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func contractDate(year int, month time.Month, day int) *time.Time {
+	d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &d
+}
+
+func TestEarliestExitDate(t *testing.T) {
+	today := *contractDate(2025, time.March, 1)
+
+	tests := []struct {
+		name     string
+		sub      Subscription
+		expected *time.Time
+	}{
+		{
+			name:     "no contract",
+			sub:      Subscription{AutoRenew: true},
+			expected: nil,
+		},
+		{
+			name: "fixed term ends at contract end",
+			sub: Subscription{
+				ContractEndDate: contractDate(2025, time.June, 30),
+			},
+			expected: contractDate(2025, time.June, 30),
+		},
+		{
+			name: "end derived from start and minimum term",
+			sub: Subscription{
+				ContractStartDate: contractDate(2024, time.January, 31),
+				MinimumTermMonths: 24,
+			},
+			expected: contractDate(2026, time.January, 31),
+		},
+		{
+			name: "auto-renew before notice period keeps the current term",
+			sub: Subscription{
+				ContractEndDate:  contractDate(2025, time.June, 30),
+				NoticePeriodDays: 30,
+				AutoRenew:        true,
+			},
+			expected: contractDate(2025, time.June, 30),
+		},
+		{
+			name: "auto-renew inside notice period rolls to next term",
+			sub: Subscription{
+				ContractEndDate:   contractDate(2025, time.March, 15),
+				MinimumTermMonths: 12,
+				NoticePeriodDays:  30,
+				AutoRenew:         true,
+			},
+			expected: contractDate(2026, time.March, 15),
+		},
+		{
+			name: "auto-renew without minimum term renews yearly",
+			sub: Subscription{
+				ContractEndDate: contractDate(2023, time.January, 1),
+				AutoRenew:       true,
+			},
+			expected: contractDate(2026, time.January, 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.sub.EarliestExitDate(today))
+		})
+	}
+}
+
+func TestContractDecideBy(t *testing.T) {
+	today := *contractDate(2025, time.March, 1)
+
+	autoRenew := Subscription{ContractEndDate: contractDate(2025, time.June, 30), NoticePeriodDays: 90, AutoRenew: true}
+	assert.Equal(t, contractDate(2025, time.April, 1), autoRenew.ContractDecideBy(today))
+
+	fixedTerm := Subscription{ContractEndDate: contractDate(2025, time.June, 30), NoticePeriodDays: 90}
+	assert.Equal(t, contractDate(2025, time.June, 30), fixedTerm.ContractDecideBy(today))
+
+	assert.Nil(t, (&Subscription{}).ContractDecideBy(today))
+}
+
+func TestContractDecision(t *testing.T) {
+	today := *contractDate(2025, time.March, 1)
+
+	inWindow := Subscription{Status: "Active", ContractEndDate: contractDate(2025, time.April, 30), NoticePeriodDays: 30, AutoRenew: true}
+	decision, ok := inWindow.ContractDecision(today)
+	require.True(t, ok)
+	assert.Equal(t, 30, decision.DaysLeft)
+	assert.Equal(t, *contractDate(2025, time.March, 31), decision.DecideBy)
+	assert.Equal(t, *contractDate(2025, time.April, 30), decision.ExitDate)
+	assert.True(t, decision.AutoRenew)
+
+	_, ok = (&Subscription{Status: "Active", ContractEndDate: contractDate(2025, time.December, 31), AutoRenew: true}).ContractDecision(today)
+	assert.False(t, ok, "deadline outside the window")
+
+	_, ok = (&Subscription{Status: "Active", ContractEndDate: contractDate(2025, time.February, 1)}).ContractDecision(today)
+	assert.False(t, ok, "fixed term already ended")
+
+	cancelled := inWindow
+	cancelled.Status = "Cancelled"
+	_, ok = cancelled.ContractDecision(today)
+	assert.False(t, ok, "cancelled subscription")
+}
+
+func TestContractDecisions_SortedByDeadline(t *testing.T) {
+	today := *contractDate(2025, time.March, 1)
+	subs := []Subscription{
+		{Name: "Later", Status: "Active", ContractEndDate: contractDate(2025, time.March, 25)},
+		{Name: "None", Status: "Active"},
+		{Name: "Sooner", Status: "Active", ContractEndDate: contractDate(2025, time.March, 10)},
+	}
+
+	decisions := ContractDecisions(subs, today)
+	require.Len(t, decisions, 2)
+	assert.Equal(t, "Sooner", decisions[0].Subscription.Name)
+	assert.Equal(t, 9, decisions[0].DaysLeft)
+	assert.Equal(t, "Later", decisions[1].Subscription.Name)
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// FireflyRecurrence links a subscription to the recurring transaction it was
+// exported to in Firefly III, so a re-sync updates that transaction instead
+// of creating another one. The link outlives the subscription so the
+// recurring transaction can be removed once the subscription is deleted.
+type FireflyRecurrence struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint      `json:"subscription_id" gorm:"not null;uniqueIndex"`
+	RecurrenceID   string    `json:"recurrence_id" gorm:"not null"`
+	SyncedAt       time.Time `json:"synced_at"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
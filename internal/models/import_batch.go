@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ImportBatch groups the subscriptions and categories created by a single import
+// so the whole import can be undone later
+type ImportBatch struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	Format            string    `json:"format"`
+	SubscriptionCount int       `json:"subscription_count"`
+	CategoryCount     int       `json:"category_count"`
+	CreatedAt         time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
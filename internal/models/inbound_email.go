@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// InboundEmail is a receipt received through the inbound email webhook that
+// matched no subscription. It waits on the renewals page to be added as a
+// subscription or dismissed.
+type InboundEmail struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Sender     string    `json:"sender"`
+	Subject    string    `json:"subject"`
+	Merchant   string    `json:"merchant"`
+	Amount     float64   `json:"amount"`
+	Currency   string    `json:"currency"`
+	VendorID   *uint     `json:"vendor_id,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Kinds of data integrity issues
+const (
+	IntegrityPastRenewal              = "past_renewal"               // Active subscription whose renewal date has passed
+	IntegrityOrphanCategory           = "orphan_category"            // Category that does not exist
+	IntegrityRenewalReminderDays      = "renewal_reminder_days"      // Renewal reminder enabled with no days
+	IntegrityCancellationReminderDays = "cancellation_reminder_days" // Cancellation reminder enabled with no days
+	IntegrityInvalidCost              = "invalid_cost"               // Cost of zero or beyond the accepted range
+	IntegrityUnknownCurrency          = "unknown_currency"           // Currency that is not supported
+)
+
+// IntegrityIssue is an anomaly in the stored data of a subscription. Value is
+// the offending value, like the passed renewal date or the unknown currency.
+type IntegrityIssue struct {
+	Kind           string `json:"kind"`
+	SubscriptionID uint   `json:"subscription_id"`
+	Name           string `json:"name"`
+	Value          string `json:"value"`
+	Fixable        bool   `json:"fixable"`
+}
+
+// IntegrityReport lists the issues found by a data integrity check
+type IntegrityReport struct {
+	CheckedAt     time.Time        `json:"checked_at"`
+	Subscriptions int              `json:"subscriptions"`
+	Issues        []IntegrityIssue `json:"issues"`
+}
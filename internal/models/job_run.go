@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// JobRun persists the outcome of a background job's last run so the
+// scheduler can catch up on missed runs after a restart
+type JobRun struct {
+	Name       string    `json:"name" gorm:"primaryKey"`
+	LastRun    time.Time `json:"last_run"`
+	DurationMs int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error"`
+	Runs       int       `json:"runs"` // Runs since the job was first recorded
+}
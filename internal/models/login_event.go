@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// LoginEvent records a login attempt on the login page
+type LoginEvent struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	Username  string `json:"username"`       // Username as entered
+	Role      string `json:"role,omitempty"` // Role of the account that logged in, empty for failed attempts
+	Success   bool   `json:"success"`
+	IP        string `json:"ip" gorm:"index:idx_login_device"`
+	UserAgent string `json:"user_agent" gorm:"index:idx_login_device"`
+	// NewDevice is set on successful logins from an IP and user agent that
+	// never logged in successfully before
+	NewDevice bool      `json:"new_device"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
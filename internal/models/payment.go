@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Payment statuses
+const (
+	PaymentPending   = "pending"   // Renewal date passed, charge not confirmed yet
+	PaymentConfirmed = "confirmed" // Charge confirmed, part of the payment ledger
+	PaymentMissed    = "missed"    // Reported as not charged
+)
+
+// PaymentOverdueDays is how long a renewal may stay unconfirmed before it is
+// flagged as a possible involuntary cancellation
+const PaymentOverdueDays = 7
+
+// Payment is one renewal charge of a subscription. Renewals that passed while
+// confirmations are enabled start out pending; confirming them adds them to the
+// payment ledger with the amount actually charged.
+type Payment struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint       `json:"subscription_id" gorm:"not null;uniqueIndex:idx_payment_due"`
+	Name           string     `json:"name" gorm:"-"`                                        // Subscription name, filled in when listing
+	DueDate        time.Time  `json:"due_date" gorm:"not null;uniqueIndex:idx_payment_due"` // Renewal date of the charge
+	Amount         float64    `json:"amount"`                                               // Expected amount, or the charged amount once confirmed
+	Currency       string     `json:"currency" gorm:"size:3"`
+	Status         string     `json:"status" gorm:"size:20;not null;index"`
+	PaidAt         *time.Time `json:"paid_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// PossiblyCancelled reports whether the renewal points to an involuntary
+// cancellation: it was reported as not charged, or stayed unconfirmed for
+// more than PaymentOverdueDays
+func (p *Payment) PossiblyCancelled(now time.Time) bool {
+	switch p.Status {
+	case PaymentMissed:
+		return true
+	case PaymentPending:
+		return now.Sub(p.DueDate) > PaymentOverdueDays*24*time.Hour
+	}
+	return false
+}
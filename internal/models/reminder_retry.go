@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Reminder kinds that are retried when sending fails
+const (
+	ReminderKindRenewal      = "renewal"
+	ReminderKindCancellation = "cancellation"
+	ReminderKindGracePeriod  = "grace_period"
+	ReminderKindContract     = "contract"
+	ReminderKindPaidThrough  = "paid_through"
+	ReminderKindPromoEnd     = "promo_end"
+)
+
+// ReminderRetry tracks a reminder that failed on some of its channels so the
+// scheduler can retry those channels with backoff until the date it is about
+type ReminderRetry struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint      `json:"subscription_id" gorm:"not null;uniqueIndex:idx_reminder_retry"`
+	Kind           string    `json:"kind" gorm:"not null;uniqueIndex:idx_reminder_retry"`
+	DueDate        time.Time `json:"due_date"` // Renewal or cancellation date the reminder is for
+	Channels       string    `json:"channels"` // Comma-separated channels that still have to deliver
+	Attempts       int       `json:"attempts"` // Failed attempts so far
+	NextAttemptAt  time.Time `json:"next_attempt_at" gorm:"index"`
+	LastError      string    `json:"last_error"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
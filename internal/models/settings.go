@@ -1,6 +1,12 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -21,7 +27,57 @@ type SMTPConfig struct {
 	Password string `json:"smtp_password"`
 	From     string `json:"smtp_from"`
 	FromName string `json:"smtp_from_name"`
-	To       string `json:"smtp_to"` // Recipient email address for notifications
+	To       string `json:"smtp_to"`  // Comma-separated recipients of notifications
+	CC       string `json:"smtp_cc"`  // Comma-separated, optional
+	BCC      string `json:"smtp_bcc"` // Comma-separated, optional; not shown to the other recipients
+	ReplyTo  string `json:"smtp_reply_to"`
+}
+
+// SplitAddresses splits a comma-separated list of email addresses, dropping
+// empty entries
+func SplitAddresses(list string) []string {
+	var addresses []string
+	for _, address := range strings.Split(list, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// ValidateRecipients checks that To has at least one address and that To, CC,
+// BCC and Reply-To only hold plain email addresses
+func (c *SMTPConfig) ValidateRecipients() error {
+	if len(SplitAddresses(c.To)) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+	addresses := c.Recipients()
+	if c.ReplyTo != "" {
+		addresses = append(addresses, c.ReplyTo)
+	}
+	for _, address := range addresses {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil || parsed.Address != address {
+			return fmt.Errorf("invalid email address: %s", address)
+		}
+	}
+	return nil
+}
+
+// Recipients returns the envelope recipients, To followed by CC and BCC,
+// without duplicates
+func (c *SMTPConfig) Recipients() []string {
+	var recipients []string
+	seen := make(map[string]bool)
+	for _, list := range []string{c.To, c.CC, c.BCC} {
+		for _, address := range SplitAddresses(list) {
+			if key := strings.ToLower(address); !seen[key] {
+				seen[key] = true
+				recipients = append(recipients, address)
+			}
+		}
+	}
+	return recipients
 }
 
 // ShoutrrrConfig represents Shoutrrr notification configuration
@@ -29,6 +85,149 @@ type ShoutrrrConfig struct {
 	URLs []string `json:"shoutrrr_urls"`
 }
 
+// Notification channels. Email and Shoutrrr support delivery windows; the
+// webhook channel is delivered through hooks.
+const (
+	ChannelEmail    = "email"
+	ChannelShoutrrr = "shoutrrr"
+	ChannelWebhook  = "webhook"
+)
+
+// NotificationChannels lists the channels a subscription can be notified through
+var NotificationChannels = []string{ChannelEmail, ChannelShoutrrr, ChannelWebhook}
+
+// DeliveryWindow limits when a notification channel may deliver. Start and End
+// are "HH:MM" in server local time; a window whose end is before its start
+// spans midnight. A disabled window allows delivery at any time.
+type DeliveryWindow struct {
+	Enabled bool   `json:"enabled"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+// DeliveryWindows holds the delivery window of each notification channel
+type DeliveryWindows struct {
+	Email    DeliveryWindow `json:"email"`
+	Shoutrrr DeliveryWindow `json:"shoutrrr"`
+}
+
+// NotificationChannelStatus describes a notification channel without its
+// credentials or addresses
+type NotificationChannelStatus struct {
+	Configured     bool           `json:"configured"`
+	Targets        int            `json:"targets"` // Email recipients or Shoutrrr URLs
+	DeliveryWindow DeliveryWindow `json:"delivery_window"`
+}
+
+// NotificationLanguages holds the language of each notification channel. An
+// empty language follows the app language.
+type NotificationLanguages struct {
+	Email    string `json:"email"`
+	Shoutrrr string `json:"shoutrrr"`
+}
+
+// QueuedNotification is a notification held back until its channel's
+// delivery window opens
+type QueuedNotification struct {
+	ID       string    `json:"id"`
+	Channel  string    `json:"channel"`
+	Title    string    `json:"title"`
+	Body     string    `json:"body"`
+	QueuedAt time.Time `json:"queued_at"`
+	Attempts int       `json:"attempts"`
+}
+
+// SessionLifetimes controls how long login sessions last. A session ends
+// when it was idle for its lifetime or reaches the absolute cap, whichever
+// comes first. With sliding expiration every request renews the idle
+// lifetime; without it sessions end a fixed time after login.
+type SessionLifetimes struct {
+	SessionHours   int  `json:"session_hours"`    // Lifetime of sessions without "remember me"
+	RememberMeDays int  `json:"remember_me_days"` // Lifetime of "remember me" sessions
+	AbsoluteDays   int  `json:"absolute_days"`    // Maximum age of any session, however active
+	Sliding        bool `json:"sliding"`
+}
+
+// DefaultSessionLifetimes are used until the lifetimes are configured
+var DefaultSessionLifetimes = SessionLifetimes{SessionHours: 24, RememberMeDays: 30, AbsoluteDays: 90, Sliding: true}
+
+// Session returns the idle lifetime of a session
+func (l SessionLifetimes) Session(rememberMe bool) time.Duration {
+	if rememberMe {
+		return time.Duration(l.RememberMeDays) * 24 * time.Hour
+	}
+	return time.Duration(l.SessionHours) * time.Hour
+}
+
+// Absolute returns the maximum age of a session
+func (l SessionLifetimes) Absolute() time.Duration {
+	return time.Duration(l.AbsoluteDays) * 24 * time.Hour
+}
+
+// Validate checks that the lifetimes are in range and the absolute cap is
+// not shorter than either lifetime
+func (l SessionLifetimes) Validate() error {
+	switch {
+	case l.SessionHours < 1 || l.SessionHours > 720:
+		return fmt.Errorf("session lifetime must be between 1 and 720 hours")
+	case l.RememberMeDays < 1 || l.RememberMeDays > 365:
+		return fmt.Errorf("remember me lifetime must be between 1 and 365 days")
+	case l.AbsoluteDays < 1 || l.AbsoluteDays > 365:
+		return fmt.Errorf("absolute session lifetime must be between 1 and 365 days")
+	case l.Absolute() < l.Session(false) || l.Absolute() < l.Session(true):
+		return fmt.Errorf("absolute session lifetime must not be shorter than the session and remember me lifetimes")
+	}
+	return nil
+}
+
+// CalendarFeedOptions controls the events of the calendar feed and the iCal
+// export. With a horizon, each renewal in the next HorizonMonths months is
+// its own event; a horizon of 0 emits one recurring event per subscription
+// instead, which ends at the cancellation date if there is one.
+type CalendarFeedOptions struct {
+	HorizonMonths      int  `json:"horizon_months"`
+	CancellationEvents bool `json:"cancellation_events"` // Add a "cancel by" event on cancellation dates
+}
+
+// CalendarFeedMaxHorizon is the longest projection horizon in months
+const CalendarFeedMaxHorizon = 60
+
+// DefaultCalendarFeedOptions are used until the feed is configured
+var DefaultCalendarFeedOptions = CalendarFeedOptions{HorizonMonths: 12, CancellationEvents: true}
+
+// Validate checks that the horizon is in range
+func (o CalendarFeedOptions) Validate() error {
+	if o.HorizonMonths < 0 || o.HorizonMonths > CalendarFeedMaxHorizon {
+		return fmt.Errorf("calendar horizon must be between 0 and %d months", CalendarFeedMaxHorizon)
+	}
+	return nil
+}
+
+// PasswordPolicy sets the requirements for the admin and viewer passwords.
+// MinStrength is the minimum estimated strength from 0 (off) to 4.
+type PasswordPolicy struct {
+	MinLength        int  `json:"min_length"`
+	RequireMixedCase bool `json:"require_mixed_case"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	RejectBreached   bool `json:"reject_breached"` // Reject the most common breached passwords
+	MinStrength      int  `json:"min_strength"`
+}
+
+// DefaultPasswordPolicy is used until the password policy is configured
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8, RejectBreached: true}
+
+// Validate checks that the policy is in range
+func (p PasswordPolicy) Validate() error {
+	if p.MinLength < 8 || p.MinLength > 64 {
+		return fmt.Errorf("minimum password length must be between 8 and 64")
+	}
+	if p.MinStrength < 0 || p.MinStrength > 4 {
+		return fmt.Errorf("minimum password strength must be between 0 and 4")
+	}
+	return nil
+}
+
 // NotificationSettings represents notification preferences
 type NotificationSettings struct {
 	RenewalReminders         bool    `json:"renewal_reminders"`
@@ -37,16 +236,261 @@ type NotificationSettings struct {
 	ReminderDays             int     `json:"reminder_days"`
 	CancellationReminders    bool    `json:"cancellation_reminders"`
 	CancellationReminderDays int     `json:"cancellation_reminder_days"`
+	UnusedNudges             bool    `json:"unused_nudges"`
+	UnusedNudgeThreshold     float64 `json:"unused_nudge_threshold"`
+	RateAlerts               bool    `json:"rate_alerts"`
+	RateAlertThreshold       float64 `json:"rate_alert_threshold"` // percent
+	RenewalConfirmations     bool    `json:"renewal_confirmations"`
+	LoginAlerts              bool    `json:"login_alerts"`
+	WeeklySummary            bool    `json:"weekly_summary"`
+	MonthlyReport            bool    `json:"monthly_report"`
+
+	Languages NotificationLanguages `json:"languages"`
+	// Channels is the status of each channel by name; it is read-only
+	Channels map[string]NotificationChannelStatus `json:"channels,omitempty"`
 }
 
-// APIKey represents an API key for external access
+// SubscriptionDefaults are the values a new subscription starts with when the
+// form or API leaves them out
+type SubscriptionDefaults struct {
+	Schedule                 string  `json:"schedule"`
+	Currency                 string  `json:"currency"`    // Empty means the display currency
+	CategoryID               uint    `json:"category_id"` // Zero means the default category
+	PriceType                string  `json:"price_type"`
+	Purpose                  string  `json:"purpose"`
+	TaxRate                  float64 `json:"tax_rate"`
+	RenewalReminder          bool    `json:"renewal_reminder"`
+	RenewalReminderDays      int     `json:"renewal_reminder_days"`
+	CancellationReminder     bool    `json:"cancellation_reminder"`
+	CancellationReminderDays int     `json:"cancellation_reminder_days"`
+}
+
+// DefaultSubscriptionDefaults returns the built-in subscription defaults
+func DefaultSubscriptionDefaults() SubscriptionDefaults {
+	return SubscriptionDefaults{
+		Schedule:                 "Monthly",
+		PriceType:                "gross",
+		Purpose:                  PurposePersonal,
+		RenewalReminderDays:      3,
+		CancellationReminderDays: 7,
+	}
+}
+
+// BankConnection configures the optional GoCardless Bank Account Data
+// (formerly Nordigen) connection. The requisition holds the consent given at
+// the bank; Accounts are filled once the bank has linked them.
+type BankConnection struct {
+	SecretID      string     `json:"secret_id"`
+	SecretKey     string     `json:"secret_key,omitempty"`
+	InstitutionID string     `json:"institution_id,omitempty"`
+	RequisitionID string     `json:"requisition_id,omitempty"`
+	Accounts      []string   `json:"accounts,omitempty"`
+	LastSync      *time.Time `json:"last_sync,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// Configured reports whether API credentials are set
+func (b BankConnection) Configured() bool {
+	return b.SecretID != "" && b.SecretKey != ""
+}
+
+// Linked reports whether bank accounts are connected and can be synced
+func (b BankConnection) Linked() bool {
+	return len(b.Accounts) > 0
+}
+
+// Budgeting tools a BudgetConnection can read recurring expenses from
+const (
+	BudgetProviderYNAB    = "ynab"
+	BudgetProviderFirefly = "firefly"
+)
+
+// BudgetConnection configures the optional import of recurring expenses from
+// YNAB (scheduled transactions) or Firefly III (recurring transactions).
+// URL is the Firefly III instance; BudgetID picks the YNAB budget.
+type BudgetConnection struct {
+	Provider string `json:"provider"`
+	Token    string `json:"token,omitempty"`
+	URL      string `json:"url,omitempty"`
+	BudgetID string `json:"budget_id,omitempty"`
+	// SyncAmounts updates the cost of matching subscriptions once a day
+	SyncAmounts bool       `json:"sync_amounts"`
+	LastSync    *time.Time `json:"last_sync,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	// ExportAccountID is the Firefly III asset account that exported
+	// subscriptions are paid from
+	ExportAccountID string     `json:"export_account_id,omitempty"`
+	LastExport      *time.Time `json:"last_export,omitempty"`
+}
+
+// Configured reports whether a provider and API token are set
+func (b BudgetConnection) Configured() bool {
+	return b.Provider != "" && b.Token != ""
+}
+
+// APIKey represents an API key for external access. Only a hash of the key
+// is stored; the key itself is shown once when it is created.
 type APIKey struct {
 	ID         uint       `json:"id" gorm:"primaryKey"`
 	Name       string     `json:"name" gorm:"not null"`
-	Key        string     `json:"key" gorm:"uniqueIndex;not null"`
+	KeyHash    string     `json:"-" gorm:"column:key;uniqueIndex;not null"` // See HashAPIKey
+	Prefix     string     `json:"prefix"`                                   // Start of the key, to tell keys apart
+	Key        string     `json:"key,omitempty" gorm:"-"`                   // Only set right after creation
 	LastUsed   *time.Time `json:"last_used"`
 	UsageCount int        `json:"usage_count" gorm:"default:0"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json"` // Extra permissions, see APIKeyScopes
 	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 	IsNew      bool       `json:"is_new" gorm:"-"` // Not stored in DB, just for display
 }
+
+// API key scopes. Every key can use the API; scopes grant extra permissions
+// or, for read, restrict them.
+const (
+	// APIKeyScopeAdmin allows replacing instance configuration such as the
+	// notification settings
+	APIKeyScopeAdmin = "admin"
+	// APIKeyScopeRead limits a key to reading, e.g. for the calendar feed or
+	// a home screen widget
+	APIKeyScopeRead = "read"
+)
+
+// APIKeyScopes lists the scopes an API key can be granted
+var APIKeyScopes = []string{APIKeyScopeAdmin, APIKeyScopeRead}
+
+// HasScope reports whether the key was granted scope
+func (k APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// IsReadOnly reports whether the key may only read
+func (k APIKey) IsReadOnly() bool {
+	return k.HasScope(APIKeyScopeRead)
+}
+
+// NormalizeAPIKeyScopes drops unknown and repeated scopes. A read-only key
+// cannot hold the admin scope.
+func NormalizeAPIKeyScopes(scopes []string) []string {
+	var normalized []string
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if slices.Contains(APIKeyScopes, scope) && !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	if slices.Contains(normalized, APIKeyScopeRead) {
+		normalized = slices.DeleteFunc(normalized, func(scope string) bool { return scope == APIKeyScopeAdmin })
+	}
+	return normalized
+}
+
+// apiKeyPrefixLength is how much of a key is kept to recognize it: "sk_" and
+// the first 8 hex characters
+const apiKeyPrefixLength = 11
+
+// HashAPIKey returns the stored form of an API key. Keys are 256 random bits,
+// so a fast unsalted hash is enough to make a leaked database useless.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyPrefix returns the part of an API key that is stored in plain text
+func APIKeyPrefix(key string) string {
+	if len(key) <= apiKeyPrefixLength {
+		return key
+	}
+	return key[:apiKeyPrefixLength]
+}
+
+// Validate checks that an enabled window has valid, distinct start and end times
+func (w DeliveryWindow) Validate() error {
+	if !w.Enabled {
+		return nil
+	}
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("delivery window start and end must differ")
+	}
+	return nil
+}
+
+// Allows reports whether the window is open at t. Windows that cannot be
+// parsed allow delivery so a bad setting never swallows notifications.
+func (w DeliveryWindow) Allows(t time.Time) bool {
+	if !w.Enabled {
+		return true
+	}
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return true
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return true
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// Validate checks that each channel language is empty or one of supported
+func (l NotificationLanguages) Validate(supported []string) error {
+	for _, lang := range []string{l.Email, l.Shoutrrr} {
+		if lang != "" && !slices.Contains(supported, lang) {
+			return fmt.Errorf("unsupported language: %s", lang)
+		}
+	}
+	return nil
+}
+
+// For returns the language of a channel, empty if it follows the app language
+func (l NotificationLanguages) For(channel string) string {
+	switch channel {
+	case ChannelEmail:
+		return l.Email
+	case ChannelShoutrrr:
+		return l.Shoutrrr
+	}
+	return ""
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Validate checks the schedule, price type, purpose, tax rate and reminder days
+func (d SubscriptionDefaults) Validate() error {
+	switch d.Schedule {
+	case "Monthly", "Annual", "Weekly", "Daily", "Quarterly":
+	default:
+		return fmt.Errorf("invalid schedule %q", d.Schedule)
+	}
+	if d.PriceType != "gross" && d.PriceType != "net" {
+		return fmt.Errorf("invalid price type %q", d.PriceType)
+	}
+	if d.Purpose != "" && !IsValidPurpose(d.Purpose) {
+		return fmt.Errorf("invalid purpose %q", d.Purpose)
+	}
+	if d.TaxRate < 0 || d.TaxRate > 100 {
+		return fmt.Errorf("tax rate must be between 0 and 100")
+	}
+	if d.RenewalReminderDays < 1 || d.RenewalReminderDays > 365 || d.CancellationReminderDays < 1 || d.CancellationReminderDays > 365 {
+		return fmt.Errorf("reminder days must be between 1 and 365")
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMTPConfig_Recipients(t *testing.T) {
+	config := &SMTPConfig{To: " me@example.com,partner@example.com ,", CC: "Partner@example.com, kid@example.com", BCC: "archive@example.com"}
+	assert.NoError(t, config.ValidateRecipients())
+	assert.Equal(t, []string{"me@example.com", "partner@example.com", "kid@example.com", "archive@example.com"}, config.Recipients())
+
+	assert.Error(t, (&SMTPConfig{To: " , "}).ValidateRecipients(), "a recipient is required")
+	assert.Error(t, (&SMTPConfig{To: "me@example.com", CC: "not-an-address"}).ValidateRecipients())
+	assert.Error(t, (&SMTPConfig{To: "me@example.com; partner@example.com"}).ValidateRecipients())
+	assert.Error(t, (&SMTPConfig{To: "Me <me@example.com>"}).ValidateRecipients(), "only plain addresses")
+	assert.Error(t, (&SMTPConfig{To: "me@example.com\r\nBcc: victim@example.com"}).ValidateRecipients())
+	assert.Error(t, (&SMTPConfig{To: "me@example.com", ReplyTo: "a@example.com, b@example.com"}).ValidateRecipients(), "a single reply-to address")
+}
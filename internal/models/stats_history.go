@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// StatsSnapshot records the key statistics of one day. Snapshots keep the
+// figures as they were, so long-term trends survive later edits to
+// subscriptions, prices and categories.
+type StatsSnapshot struct {
+	ID            uint               `json:"-" gorm:"primaryKey"`
+	Date          time.Time          `json:"date" gorm:"not null;uniqueIndex"` // Start of the day the snapshot covers
+	ActiveCount   int                `json:"active_count"`
+	Currency      string             `json:"currency" gorm:"size:3"`                // Display currency of MonthlySpend and Categories at the time
+	MonthlySpend  float64            `json:"monthly_spend"`                         // Monthly spend of active subscriptions in Currency
+	CurrencySpend map[string]float64 `json:"currency_spend" gorm:"serializer:json"` // Unconverted monthly spend per original currency
+	Categories    []CategorySpend    `json:"categories" gorm:"serializer:json"`
+	CreatedAt     time.Time          `json:"-" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time          `json:"-" gorm:"autoUpdateTime"`
+}
+
+// TableName keeps the snapshots in the stats_history table
+func (StatsSnapshot) TableName() string {
+	return "stats_history"
+}
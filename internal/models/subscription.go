@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/dromara/carbon/v2"
@@ -10,38 +13,216 @@ import (
 type Subscription struct {
 	ID                           uint       `json:"id" gorm:"primaryKey"`
 	Name                         string     `json:"name" gorm:"not null" validate:"required"`
-	Cost                         float64    `json:"cost" gorm:"not null" validate:"required,gt=0"`
+	Cost                         float64    `json:"cost" gorm:"not null" validate:"required"`
 	OriginalCurrency             string     `json:"original_currency" gorm:"size:3;default:'USD'"`
 	Schedule                     string     `json:"schedule" gorm:"not null" validate:"required,oneof=Monthly Annual Weekly Daily Quarterly"`
 	Status                       string     `json:"status" gorm:"not null" validate:"required,oneof=Active Cancelled Paused Trial"`
 	CategoryID                   uint       `json:"category_id"`
 	Category                     Category   `json:"category" gorm:"foreignKey:CategoryID"`
+	VendorID                     *uint      `json:"vendor_id" gorm:"index"` // Optional grouping of related subscriptions
+	Vendor                       *Vendor    `json:"vendor,omitempty" gorm:"foreignKey:VendorID"`
 	PaymentMethod                string     `json:"payment_method" gorm:""`
 	Account                      string     `json:"-" gorm:""`
 	TaxRate                      float64    `json:"tax_rate" gorm:"default:0"`
 	PriceType                    string     `json:"price_type" gorm:"default:'gross'"`
+	Seats                        int        `json:"seats" gorm:"default:0"` // People a family or team plan covers; 0 for a single-user plan
 	CustomerNumber               string     `json:"customer_number" gorm:"default:''"`
 	ContractNumber               string     `json:"contract_number" gorm:"default:''"`
 	LoginName                    string     `json:"login_name" gorm:"default:''"`
 	StartDate                    *time.Time `json:"start_date" gorm:""`
 	RenewalDate                  *time.Time `json:"renewal_date" gorm:""`
 	CancellationDate             *time.Time `json:"cancellation_date" gorm:""`
+	PaidThroughDate              *time.Time `json:"paid_through_date" gorm:""` // Last day of access already paid for after cancelling
+	PromoCost                    *float64   `json:"promo_cost" gorm:""`        // Introductory price charged until PromoEndDate
+	PromoEndDate                 *time.Time `json:"promo_end_date" gorm:""`    // First day the regular cost applies
+	PromoEndReminder             bool       `json:"promo_end_reminder" gorm:"default:false"`
+	ContractStartDate            *time.Time `json:"contract_start_date" gorm:""`
+	ContractEndDate              *time.Time `json:"contract_end_date" gorm:""` // End of the current contract term
+	MinimumTermMonths            int        `json:"minimum_term_months" gorm:"default:0"`
+	NoticePeriodDays             int        `json:"notice_period_days" gorm:"default:0"`
+	AutoRenew                    bool       `json:"auto_renew" gorm:"default:false"` // The contract renews unless cancelled within the notice period
 	URL                          string     `json:"url" gorm:""`
-	IconURL                      string     `json:"icon_url" gorm:""` // URL to subscription icon/logo
+	IconURL                      string     `json:"icon_url" gorm:""`                      // URL to subscription icon/logo
+	LogoStatus                   string     `json:"logo_status" gorm:"size:10;default:''"` // Background logo lookup: pending, fetched or failed
+	IconColor                    string     `json:"icon_color" gorm:"size:7;default:''"`   // Dominant color of a fetched logo, like #1db954
 	Notes                        string     `json:"notes" gorm:""`
+	CancelURL                    string     `json:"cancel_url" gorm:"default:''"`   // Page to cancel the subscription on
+	CancelNotes                  string     `json:"cancel_notes" gorm:"default:''"` // Steps to cancel, included in reminders
 	Usage                        string     `json:"usage" gorm:"" validate:"omitempty,oneof=High Medium Low None"`
+	Purpose                      string     `json:"purpose" gorm:"size:20;default:'personal'" validate:"omitempty,oneof=personal business shared"` // Scope for dashboards and budgets
 	DateCalculationVersion       int        `json:"date_calculation_version" gorm:"default:1"`
 	RenewalReminder              bool       `json:"renewal_reminder" gorm:"default:false"`
 	RenewalReminderDays          int        `json:"renewal_reminder_days" gorm:""`
 	CancellationReminder         bool       `json:"cancellation_reminder" gorm:"default:false"`
 	CancellationReminderDays     int        `json:"cancellation_reminder_days" gorm:""`
 	HighCostAlert                bool       `json:"high_cost_alert" gorm:"default:false"`
+	NotifyChannels               string     `json:"notify_channels" gorm:"default:''"`       // Comma-separated channels for reminders and alerts; empty means all
 	LastReminderSent             *time.Time `json:"last_reminder_sent" gorm:""`              // Tracks when the last reminder was sent
 	LastReminderRenewalDate      *time.Time `json:"last_reminder_renewal_date" gorm:""`      // Tracks which renewal date the last reminder was for
 	LastCancellationReminderSent *time.Time `json:"last_cancellation_reminder_sent" gorm:""` // Tracks when the last cancellation reminder was sent
 	LastCancellationReminderDate *time.Time `json:"last_cancellation_reminder_date" gorm:""` // Tracks which cancellation date the last reminder was for
+	PaymentFailedAt              *time.Time `json:"payment_failed_at" gorm:""`               // Set while a failed charge is being retried by the provider
+	PaymentRetryDate             *time.Time `json:"payment_retry_date" gorm:""`              // When the provider retries the failed charge
+	GracePeriodEnd               *time.Time `json:"grace_period_end" gorm:""`                // Service cutoff if the retries keep failing
+	LastGraceReminderDate        *time.Time `json:"last_grace_reminder_date" gorm:""`        // Tracks which cutoff date the last grace period reminder was for
+	LastContractReminderDate     *time.Time `json:"last_contract_reminder_date" gorm:""`     // Tracks which decision deadline the last contract reminder was for
+	LastPaidThroughReminderDate  *time.Time `json:"last_paid_through_reminder_date" gorm:""` // Tracks which paid-through date the last access end reminder was for
+	LastPromoReminderDate        *time.Time `json:"last_promo_reminder_date" gorm:""`        // Tracks which promo end date the last price increase reminder was for
+	ImportBatchID                *uint      `json:"import_batch_id,omitempty" gorm:"index"`  // Set when created by an import, used to undo it
 	CreatedAt                    time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt                    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// DeletedAt is set while a deletion can still be undone; deleted rows are
+	// purged once the undo window has passed
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// GracePeriodReminderDays is how many days before the service cutoff of a
+// failed payment a reminder is sent
+const GracePeriodReminderDays = 3
+
+// PaidThroughReminderDays is how many days before the paid period of a
+// cancelled subscription ends a reminder is sent
+const PaidThroughReminderDays = 3
+
+// PromoReminderDays is how many days before a promotional price ends a
+// reminder of the price increase is sent
+const PromoReminderDays = 7
+
+// MarkPaymentFailed records a failed charge that the provider retries on
+// retryDate, cutting off service after gracePeriodEnd. The date of the first
+// failure is kept while the subscription is already in the failed state.
+func (s *Subscription) MarkPaymentFailed(now time.Time, retryDate, gracePeriodEnd *time.Time) {
+	if s.PaymentFailedAt == nil {
+		s.PaymentFailedAt = &now
+	}
+	s.PaymentRetryDate = retryDate
+	s.GracePeriodEnd = gracePeriodEnd
+}
+
+// ResolvePaymentFailure ends the failed payment state once a retry succeeded
+func (s *Subscription) ResolvePaymentFailure() {
+	s.PaymentFailedAt = nil
+	s.PaymentRetryDate = nil
+	s.GracePeriodEnd = nil
+	s.LastGraceReminderDate = nil
+}
+
+// Logo lookup states. Logos are looked up in the background after a
+// subscription with a website but without an icon is saved.
+const (
+	LogoPending = "pending"
+	LogoFetched = "fetched"
+	LogoFailed  = "failed"
+)
+
+// Subscription statuses
+const (
+	StatusActive    = "Active"
+	StatusTrial     = "Trial"
+	StatusPaused    = "Paused"
+	StatusCancelled = "Cancelled"
+)
+
+// Statuses lists the valid subscription statuses
+var Statuses = []string{StatusActive, StatusTrial, StatusPaused, StatusCancelled}
+
+// statusTransitions lists the statuses a subscription can change to from each
+// status. A cancelled subscription has to be resumed before it can be paused.
+var statusTransitions = map[string][]string{
+	StatusActive:    {StatusTrial, StatusPaused, StatusCancelled},
+	StatusTrial:     {StatusActive, StatusPaused, StatusCancelled},
+	StatusPaused:    {StatusActive, StatusTrial, StatusCancelled},
+	StatusCancelled: {StatusActive, StatusTrial},
+}
+
+// IsValidStatus reports whether status is one of Statuses
+func IsValidStatus(status string) bool {
+	return slices.Contains(Statuses, status)
+}
+
+// CanChangeStatus reports whether a subscription may change from one status to
+// another. Keeping the status is always allowed.
+func CanChangeStatus(from, to string) bool {
+	if from == to {
+		return IsValidStatus(to)
+	}
+	return slices.Contains(statusTransitions[from], to)
+}
+
+// Subscription purposes, used to view and budget personal and business
+// subscriptions separately
+const (
+	PurposePersonal = "personal"
+	PurposeBusiness = "business"
+	PurposeShared   = "shared"
+)
+
+// Purposes lists the valid subscription purposes
+var Purposes = []string{PurposePersonal, PurposeBusiness, PurposeShared}
+
+// IsValidPurpose reports whether purpose is one of Purposes
+func IsValidPurpose(purpose string) bool {
+	return slices.Contains(Purposes, purpose)
+}
+
+// EffectivePurpose returns the purpose of the subscription, treating an unset
+// purpose as personal
+func (s *Subscription) EffectivePurpose() string {
+	if s.Purpose == "" {
+		return PurposePersonal
+	}
+	return s.Purpose
+}
+
+// VendorName returns the name of the subscription's vendor, or "" without one
+func (s *Subscription) VendorName() string {
+	if s.Vendor == nil {
+		return ""
+	}
+	return s.Vendor.Name
+}
+
+// NotifiesVia reports whether reminders and alerts for the subscription are
+// sent through channel
+func (s *Subscription) NotifiesVia(channel string) bool {
+	if s.NotifyChannels == "" {
+		return true
+	}
+	for _, selected := range strings.Split(s.NotifyChannels, ",") {
+		if selected == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeNotifyChannels validates a comma-separated channel list and returns
+// it in canonical order. An empty list or one naming every channel is stored
+// as "" so channels added later are enabled too.
+func NormalizeNotifyChannels(value string) (string, error) {
+	selected := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		if !slices.Contains(NotificationChannels, part) {
+			return "", fmt.Errorf("unknown notification channel %q", part)
+		}
+		selected[part] = true
+	}
+	if len(selected) == 0 || len(selected) == len(NotificationChannels) {
+		return "", nil
+	}
+
+	var channels []string
+	for _, channel := range NotificationChannels {
+		if selected[channel] {
+			channels = append(channels, channel)
+		}
+	}
+	return strings.Join(channels, ","), nil
 }
 
 // AnnualCost calculates the annual cost based on schedule
@@ -87,9 +268,35 @@ func (s *Subscription) DailyCost() float64 {
 	return s.MonthlyCost() / 30.44 // Average days per month
 }
 
-// IsHighCost determines if this is a high-cost subscription based on the threshold
+// IsHighCost determines if this is a high-cost subscription based on the threshold.
+// Credits are never high-cost.
 func (s *Subscription) IsHighCost(threshold float64) bool {
-	return s.MonthlyCost() > threshold
+	return !s.IsCredit() && s.MonthlyCost() > threshold
+}
+
+// SeatCount returns the number of people the plan covers, at least 1
+func (s *Subscription) SeatCount() int {
+	return max(s.Seats, 1)
+}
+
+// IsSeatPlan reports whether the plan is shared by several people
+func (s *Subscription) IsSeatPlan() bool {
+	return s.Seats > 1
+}
+
+// MonthlyCostPerSeat returns the effective monthly cost per person of the plan
+func (s *Subscription) MonthlyCostPerSeat() float64 {
+	return s.MonthlyCost() / float64(s.SeatCount())
+}
+
+// MaxCost bounds the cost of a subscription, and of a credit below zero
+const MaxCost = 1000000
+
+// IsCredit reports whether the subscription is a credit, such as a refund or
+// a recurring discount, entered with a negative cost. Credits lower the
+// monthly spend and budget totals.
+func (s *Subscription) IsCredit() bool {
+	return s.Cost < 0
 }
 
 // AfterFind hook to auto-update renewal date if it has passed (Issue #29)
@@ -405,20 +612,58 @@ func (s *Subscription) calculateNextRenewalDateFromNowV2() {
 	}
 }
 
-// GrossCost returns the gross cost of the subscription
+// PromoActive reports whether the promotional price applies at now. It
+// applies until the day before PromoEndDate; from that day on the regular
+// cost is charged.
+func (s *Subscription) PromoActive(now time.Time) bool {
+	if s.PromoCost == nil || s.PromoEndDate == nil {
+		return false
+	}
+	// Compare calendar days, whatever the time of day or zone
+	return now.Format(time.DateOnly) < s.PromoEndDate.Format(time.DateOnly)
+}
+
+// OnPromo reports whether the promotional price applies today
+func (s *Subscription) OnPromo() bool {
+	return s.PromoActive(time.Now())
+}
+
+// CurrentCost returns the price charged today: the promotional price while it
+// applies, the regular cost afterwards
+func (s *Subscription) CurrentCost() float64 {
+	return s.CostAt(time.Now())
+}
+
+// CostAt returns the price charged on date, for renewals projected into the
+// future
+func (s *Subscription) CostAt(date time.Time) float64 {
+	if s.PromoActive(date) {
+		return *s.PromoCost
+	}
+	return s.Cost
+}
+
+// GrossCost returns the current gross cost of the subscription
 func (s *Subscription) GrossCost() float64 {
+	return s.GrossCostAt(time.Now())
+}
+
+// GrossCostAt returns the gross cost charged on date
+func (s *Subscription) GrossCostAt(date time.Time) float64 {
+	cost := s.CostAt(date)
 	if s.PriceType == "net" && s.TaxRate > 0 {
-		return s.Cost * (1 + s.TaxRate/100)
+		return cost * (1 + s.TaxRate/100)
 	}
-	return s.Cost
+	return cost
 }
 
-// NetCost returns the net cost of the subscription
+// NetCost returns the current net cost of the subscription
 func (s *Subscription) NetCost() float64 {
+	cost := s.CurrentCost()
 	if s.PriceType == "gross" && s.TaxRate > 0 {
-		return s.Cost / (1 + s.TaxRate/100)
+		return cost / (1 + s.TaxRate/100)
 	}
-	return s.Cost
+	return cost
 }
 
 // TaxAmount returns the tax amount of the subscription
@@ -426,6 +671,83 @@ func (s *Subscription) TaxAmount() float64 {
 	return s.GrossCost() - s.NetCost()
 }
 
+// maxProjectionSteps bounds the schedule steps walked to project renewal dates
+const maxProjectionSteps = 20000
+
+// ProjectRenewalDates calculates all renewal dates that fall within [viewStart, viewEnd)
+// in order by stepping forward or backward from the base renewal date using the subscription schedule.
+func ProjectRenewalDates(baseDate time.Time, schedule string, viewStart, viewEnd time.Time) []time.Time {
+	var step func(t time.Time, n int) time.Time
+	switch schedule {
+	case "Daily":
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) }
+	case "Weekly":
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) }
+	case "Monthly":
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, n, 0) }
+	case "Quarterly":
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 3*n, 0) }
+	case "Annual":
+		step = func(t time.Time, n int) time.Time { return t.AddDate(n, 0, 0) }
+	default:
+		// Unknown schedule: just check if baseDate falls in range
+		if !baseDate.Before(viewStart) && baseDate.Before(viewEnd) {
+			return []time.Time{baseDate}
+		}
+		return nil
+	}
+
+	// Walk to the first renewal in the view, then forward through it
+	n := 0
+	for i := 0; i < maxProjectionSteps && !step(baseDate, n).Before(viewStart); i++ {
+		n--
+	}
+	for i := 0; i < maxProjectionSteps && step(baseDate, n).Before(viewStart); i++ {
+		n++
+	}
+
+	var dates []time.Time
+	for i := 0; i < maxProjectionSteps; i++ {
+		d := step(baseDate, n+i)
+		if !d.Before(viewEnd) {
+			break
+		}
+		dates = append(dates, d)
+	}
+	return dates
+}
+
+// ProjectedRenewalDate returns the first renewal date from from on, projected
+// from the stored renewal date by the schedule, or nil if there is none within
+// a year. A stored date that has passed is not changed.
+func (s *Subscription) ProjectedRenewalDate(from time.Time) *time.Time {
+	if s.RenewalDate == nil {
+		return nil
+	}
+	dates := ProjectRenewalDates(*s.RenewalDate, s.Schedule, from, from.AddDate(1, 0, 1))
+	if len(dates) == 0 {
+		return nil
+	}
+	return &dates[0]
+}
+
+// NextRenewal returns the active or trial subscription that renews next, from
+// today on, or nil if none has an upcoming renewal date
+func NextRenewal(subscriptions []Subscription, now time.Time) *Subscription {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var next *Subscription
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		if sub.RenewalDate == nil || sub.RenewalDate.Before(today) || (sub.Status != "Active" && sub.Status != "Trial") {
+			continue
+		}
+		if next == nil || sub.RenewalDate.Before(*next.RenewalDate) {
+			next = sub
+		}
+	}
+	return next
+}
+
 // Stats represents aggregated subscription statistics
 type Stats struct {
 	TotalMonthlySpend      float64            `json:"total_monthly_spend"`
@@ -434,13 +756,127 @@ type Stats struct {
 	CancelledSubscriptions int                `json:"cancelled_subscriptions"`
 	TotalSaved             float64            `json:"total_saved"`
 	MonthlySaved           float64            `json:"monthly_saved"`
-	UpcomingRenewals       int                `json:"upcoming_renewals"`
+	UpcomingRenewals       int                `json:"upcoming_renewals"`   // Renewals of active subscriptions within RenewalWindowDays from today
+	RenewalWindowDays      int                `json:"renewal_window_days"` // Days ahead UpcomingRenewals counts
+	FailedPayments         int                `json:"failed_payments"`     // Active subscriptions whose last charge failed and is being retried
 	CategorySpending       map[string]float64 `json:"category_spending"`
+	Categories             []CategorySpend    `json:"categories"` // CategorySpending with category IDs, highest spend first
+	Vendors                []VendorSpend      `json:"vendors"`    // Spend per vendor, highest first; subscriptions without a vendor are left out
+	Currencies             []CurrencySpend    `json:"currencies"` // Unconverted spend per original currency, highest converted spend first
+	SeatPlans              []SeatSpend        `json:"seat_plans"` // Active plans with more than one seat, highest cost per seat first
 	MonthlyBudget          float64            `json:"monthly_budget"`
+	BudgetRollover         float64            `json:"budget_rollover"`          // Unused budget carried over from previous months
+	EffectiveMonthlyBudget float64            `json:"effective_monthly_budget"` // MonthlyBudget plus BudgetRollover
 	BudgetUtilization      float64            `json:"budget_utilization"`
+	AnnualBudget           float64            `json:"annual_budget"`
+	AnnualUtilization      float64            `json:"annual_budget_utilization"`
+	Trend                  *StatsTrend        `json:"trend"`
+	Purpose                string             `json:"purpose,omitempty"` // Set when the stats only cover one purpose
+	PurposeSpending        map[string]float64 `json:"purpose_spending"`  // Monthly spend of all active subscriptions per purpose
 	AllSubscriptions       []Subscription     `json:"-"`
 }
 
+// SeatSpend is the monthly cost of an active plan shared by several people
+type SeatSpend struct {
+	SubscriptionID uint    `json:"subscription_id"`
+	Name           string  `json:"name"`
+	Seats          int     `json:"seats"`
+	MonthlySpend   float64 `json:"monthly_spend"`
+	PerSeat        float64 `json:"per_seat"` // Effective monthly cost per person
+}
+
+// Budget periods
+const (
+	BudgetPeriodMonthly = "monthly"
+	BudgetPeriodAnnual  = "annual"
+)
+
+// ExceededBudget returns the first budget the spend is over, the monthly
+// budget (including rollover) before the annual one
+func (s *Stats) ExceededBudget() (period string, spend, budget float64, exceeded bool) {
+	if s.EffectiveMonthlyBudget > 0 && s.TotalMonthlySpend > s.EffectiveMonthlyBudget {
+		return BudgetPeriodMonthly, s.TotalMonthlySpend, s.EffectiveMonthlyBudget, true
+	}
+	if s.AnnualBudget > 0 && s.TotalAnnualSpend > s.AnnualBudget {
+		return BudgetPeriodAnnual, s.TotalAnnualSpend, s.AnnualBudget, true
+	}
+	return "", 0, 0, false
+}
+
+// StatsTrend compares the current monthly spend with the spend one month ago.
+// Past spend is derived from start and cancellation dates at today's prices.
+type StatsTrend struct {
+	PreviousMonthlySpend float64         `json:"previous_monthly_spend"`
+	MonthlySpendChange   float64         `json:"monthly_spend_change"`
+	ChangePercent        float64         `json:"change_percent"`
+	NewlyAdded           []TrendItem     `json:"newly_added"`
+	NewlyCancelled       []TrendItem     `json:"newly_cancelled"`
+	TopMovers            []TrendItem     `json:"top_movers"`
+	Categories           []CategoryTrend `json:"categories"`
+}
+
+// TrendItem is a subscription that changed the monthly spend
+type TrendItem struct {
+	ID          uint    `json:"id"`
+	Name        string  `json:"name"`
+	Category    string  `json:"category"`
+	MonthlyCost float64 `json:"monthly_cost"`
+	Change      float64 `json:"change"`
+}
+
+// CategoryTrend is the monthly spend of a category now and one month ago
+type CategoryTrend struct {
+	Category string  `json:"category"`
+	Current  float64 `json:"current"`
+	Previous float64 `json:"previous"`
+	Change   float64 `json:"change"`
+}
+
+// CategorySpend is the monthly spend of the active subscriptions in a category
+type CategorySpend struct {
+	ID           uint    `json:"id"` // 0 for subscriptions without a category
+	Name         string  `json:"name"`
+	MonthlySpend float64 `json:"monthly_spend"`
+	Count        int     `json:"count"`
+	Share        float64 `json:"share"` // Percent of the total monthly spend
+}
+
+// CurrencySpend is the spend of the active subscriptions billed in a currency,
+// in that currency. It stays exact when exchange rates are stale or missing.
+type CurrencySpend struct {
+	Currency              string  `json:"currency"`
+	MonthlySpend          float64 `json:"monthly_spend"`
+	AnnualSpend           float64 `json:"annual_spend"`
+	ConvertedMonthlySpend float64 `json:"converted_monthly_spend"` // MonthlySpend in the display currency
+	Count                 int     `json:"count"`
+	Share                 float64 `json:"share"`        // Percent of the total monthly spend
+	RateMissing           bool    `json:"rate_missing"` // No exchange rate, converted 1:1
+}
+
+// CategoryBreakdown lists the active subscriptions that make up the monthly
+// spend of a category, highest spend first
+type CategoryBreakdown struct {
+	ID            uint                   `json:"id"`
+	Name          string                 `json:"name"`
+	Currency      string                 `json:"currency"` // Display currency of the converted amounts
+	MonthlySpend  float64                `json:"monthly_spend"`
+	Purpose       string                 `json:"purpose,omitempty"`
+	Subscriptions []CategorySubscription `json:"subscriptions"`
+}
+
+// CategorySubscription is one subscription's contribution to its category's spend
+type CategorySubscription struct {
+	ID                   uint    `json:"id"`
+	Name                 string  `json:"name"`
+	IconURL              string  `json:"icon_url,omitempty"`
+	Cost                 float64 `json:"cost"`
+	Schedule             string  `json:"schedule"`
+	Currency             string  `json:"currency"`
+	MonthlyCost          float64 `json:"monthly_cost"`           // In the subscription's currency
+	ConvertedMonthlyCost float64 `json:"converted_monthly_cost"` // In the display currency
+	Share                float64 `json:"share"`                  // Percent of the category's spend
+}
+
 // CategoryStat represents spending by category
 type CategoryStat struct {
 	Category string  `json:"category"`
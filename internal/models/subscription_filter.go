@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SubscriptionFilter selects subscriptions, e.g. for an export. Empty fields
+// match every subscription.
+type SubscriptionFilter struct {
+	Statuses []string   // Any of these statuses
+	Category string     // Category ID or name, case-insensitive
+	Purpose  string     // Effective purpose
+	From     *time.Time // Earliest renewal date
+	To       *time.Time // Latest renewal date
+}
+
+// ParseSubscriptionFilter builds a filter from text parameters as sent to the
+// export endpoints: a comma-separated list of statuses, a category ID or name,
+// a purpose and a renewal date range in YYYY-MM-DD. Statuses and purposes are
+// matched case-insensitively.
+func ParseSubscriptionFilter(statuses, category, purpose, from, to string) (SubscriptionFilter, error) {
+	var filter SubscriptionFilter
+	for _, status := range strings.Split(statuses, ",") {
+		status = strings.TrimSpace(status)
+		if status == "" {
+			continue
+		}
+		i := slices.IndexFunc(Statuses, func(s string) bool { return strings.EqualFold(s, status) })
+		if i < 0 {
+			return filter, fmt.Errorf("invalid status %q: use %s", status, strings.Join(Statuses, ", "))
+		}
+		filter.Statuses = append(filter.Statuses, Statuses[i])
+	}
+
+	filter.Category = strings.TrimSpace(category)
+
+	if purpose = strings.ToLower(strings.TrimSpace(purpose)); purpose != "" {
+		if !IsValidPurpose(purpose) {
+			return filter, fmt.Errorf("invalid purpose %q: use %s", purpose, strings.Join(Purposes, ", "))
+		}
+		filter.Purpose = purpose
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value string
+		date  **time.Time
+	}{{"from", from, &filter.From}, {"to", to, &filter.To}} {
+		if bound.value == "" {
+			continue
+		}
+		date, err := time.Parse(time.DateOnly, strings.TrimSpace(bound.value))
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s date %q: use YYYY-MM-DD", bound.name, bound.value)
+		}
+		*bound.date = &date
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return filter, errors.New("the to date must not be before the from date")
+	}
+	return filter, nil
+}
+
+// IsEmpty reports whether the filter matches every subscription
+func (f SubscriptionFilter) IsEmpty() bool {
+	return len(f.Statuses) == 0 && f.Category == "" && f.Purpose == "" && f.From == nil && f.To == nil
+}
+
+// Matches reports whether a subscription passes the filter. With a date range
+// only subscriptions whose renewal date falls within it match.
+func (f SubscriptionFilter) Matches(sub *Subscription) bool {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, sub.Status) {
+		return false
+	}
+	if f.Category != "" && !f.matchesCategory(sub) {
+		return false
+	}
+	if f.Purpose != "" && sub.EffectivePurpose() != f.Purpose {
+		return false
+	}
+	if f.From != nil || f.To != nil {
+		if sub.RenewalDate == nil {
+			return false
+		}
+		// Compare calendar days, whatever the time of day or zone
+		renewal := sub.RenewalDate.Format(time.DateOnly)
+		if f.From != nil && renewal < f.From.Format(time.DateOnly) {
+			return false
+		}
+		if f.To != nil && renewal > f.To.Format(time.DateOnly) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the subscriptions that pass the filter
+func (f SubscriptionFilter) Apply(subscriptions []Subscription) []Subscription {
+	if f.IsEmpty() {
+		return subscriptions
+	}
+	matching := make([]Subscription, 0, len(subscriptions))
+	for i := range subscriptions {
+		if f.Matches(&subscriptions[i]) {
+			matching = append(matching, subscriptions[i])
+		}
+	}
+	return matching
+}
+
+func (f SubscriptionFilter) matchesCategory(sub *Subscription) bool {
+	if id, err := strconv.ParseUint(f.Category, 10, 32); err == nil {
+		return sub.CategoryID == uint(id)
+	}
+	return strings.EqualFold(sub.Category.Name, f.Category)
+}
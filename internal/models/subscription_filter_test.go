@@ -0,0 +1,62 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionFilter(t *testing.T) {
+	date := func(s string) *time.Time {
+		d, err := time.Parse(time.DateOnly, s)
+		require.NoError(t, err)
+		return &d
+	}
+	subs := []Subscription{
+		{Name: "Netflix", Status: StatusActive, CategoryID: 1, Category: Category{Name: "Streaming"}, RenewalDate: date("2026-03-01")},
+		{Name: "GitHub", Status: StatusActive, Purpose: PurposeBusiness, CategoryID: 2, Category: Category{Name: "Software"}, RenewalDate: date("2026-04-15")},
+		{Name: "Figma", Status: StatusCancelled, Purpose: PurposeBusiness, CategoryID: 2, Category: Category{Name: "Software"}},
+		{Name: "Disney+", Status: StatusTrial, CategoryID: 1, Category: Category{Name: "Streaming"}, RenewalDate: date("2026-05-01")},
+	}
+	names := func(filter SubscriptionFilter) []string {
+		var names []string
+		for _, sub := range filter.Apply(subs) {
+			names = append(names, sub.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name                                string
+		status, category, purpose, from, to string
+		want                                []string
+	}{
+		{name: "no filter", want: []string{"Netflix", "GitHub", "Figma", "Disney+"}},
+		{name: "active business", status: "active", purpose: "Business", want: []string{"GitHub"}},
+		{name: "statuses", status: "Active, Trial", want: []string{"Netflix", "GitHub", "Disney+"}},
+		{name: "category name", category: "software", want: []string{"GitHub", "Figma"}},
+		{name: "category ID", category: "1", want: []string{"Netflix", "Disney+"}},
+		{name: "personal is the default purpose", purpose: "personal", want: []string{"Netflix", "Disney+"}},
+		{name: "renewal range", from: "2026-03-01", to: "2026-04-30", want: []string{"Netflix", "GitHub"}},
+		{name: "open range", from: "2026-04-01", want: []string{"GitHub", "Disney+"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseSubscriptionFilter(tt.status, tt.category, tt.purpose, tt.from, tt.to)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, names(filter))
+		})
+	}
+
+	for _, invalid := range [][5]string{
+		{"Deleted", "", "", "", ""},
+		{"", "", "work", "", ""},
+		{"", "", "", "01.03.2026", ""},
+		{"", "", "", "2026-05-01", "2026-04-01"},
+	} {
+		_, err := ParseSubscriptionFilter(invalid[0], invalid[1], invalid[2], invalid[3], invalid[4])
+		assert.Error(t, err, invalid)
+	}
+}
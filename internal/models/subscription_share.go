@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Share types of a SubscriptionShare
+const (
+	ShareTypePercent = "percent"
+	ShareTypeFixed   = "fixed"
+	ShareTypeSeats   = "seats"
+)
+
+// SubscriptionShare assigns part of a subscription's cost to another person.
+// Percent shares are a percentage of the monthly cost, fixed shares a monthly
+// amount in the subscription's currency and seat shares a number of the plan's
+// seats. Whatever remains is the owner's part.
+type SubscriptionShare struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint      `json:"subscription_id" gorm:"not null;index"`
+	Person         string    `json:"person" gorm:"not null"`
+	ShareType      string    `json:"share_type" gorm:"not null;default:'percent'"`
+	Value          float64   `json:"value" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -314,6 +314,43 @@ func TestSubscription_IsHighCost(t *testing.T) {
 	}
 }
 
+func TestSubscription_PromoPricing(t *testing.T) {
+	now := time.Date(2026, 3, 15, 18, 0, 0, 0, time.UTC)
+	promo := 4.99
+	end := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	sub := &Subscription{Cost: 12.99, Schedule: "Monthly", PriceType: "net", TaxRate: 20, PromoCost: &promo, PromoEndDate: &end}
+
+	assert.True(t, sub.PromoActive(now))
+	assert.True(t, sub.PromoActive(end.AddDate(0, 0, -1)))
+	// The regular price applies from the end date on
+	assert.False(t, sub.PromoActive(end))
+	assert.False(t, sub.PromoActive(end.AddDate(0, 2, 0)))
+
+	// Projected renewals are charged at the price of their own date
+	assert.Equal(t, 4.99, sub.CostAt(end.AddDate(0, 0, -1)))
+	assert.Equal(t, 12.99, sub.CostAt(end))
+	assert.InDelta(t, 5.988, sub.GrossCostAt(now), 0.0001)
+	assert.InDelta(t, 15.588, sub.GrossCostAt(end), 0.0001)
+
+	// Without an end date the promotional price never applies
+	sub.PromoEndDate = nil
+	assert.False(t, sub.PromoActive(now))
+	assert.Equal(t, 12.99, sub.CurrentCost())
+
+	// Costs follow the price charged today
+	future := time.Now().AddDate(0, 1, 0)
+	sub.PromoEndDate = &future
+	assert.Equal(t, 4.99, sub.CurrentCost())
+	assert.InDelta(t, 5.988, sub.GrossCost(), 0.0001)
+	assert.InDelta(t, 4.99, sub.NetCost(), 0.0001)
+	assert.InDelta(t, 5.988*12, sub.AnnualCost(), 0.0001)
+
+	past := time.Now().AddDate(0, 0, -1)
+	sub.PromoEndDate = &past
+	assert.Equal(t, 12.99, sub.CurrentCost())
+	assert.InDelta(t, 15.588, sub.MonthlyCost(), 0.0001)
+}
+
 // TestSubscription_DateEdgeCases tests critical edge cases for date calculations
 // Note: These tests focus on the core logic, not exact historical sequences
 func TestSubscription_DateEdgeCases(t *testing.T) {
@@ -774,3 +811,83 @@ func TestSubscription_CarbonLibraryFeatures(t *testing.T) {
 		})
 	}
 }
+
+func TestSubscription_NotifyChannels(t *testing.T) {
+	all := &Subscription{}
+	for _, channel := range NotificationChannels {
+		assert.True(t, all.NotifiesVia(channel), "empty selection notifies via %s", channel)
+	}
+
+	channels, err := NormalizeNotifyChannels(" Webhook, email,email ")
+	assert.NoError(t, err)
+	assert.Equal(t, "email,webhook", channels)
+
+	sub := &Subscription{NotifyChannels: channels}
+	assert.True(t, sub.NotifiesVia(ChannelEmail))
+	assert.False(t, sub.NotifiesVia(ChannelShoutrrr))
+	assert.True(t, sub.NotifiesVia(ChannelWebhook))
+
+	channels, err = NormalizeNotifyChannels("shoutrrr,email,webhook")
+	assert.NoError(t, err)
+	assert.Empty(t, channels, "selecting every channel is stored as all")
+
+	_, err = NormalizeNotifyChannels("email,sms")
+	assert.Error(t, err)
+}
+
+func TestNextRenewal(t *testing.T) {
+	now := time.Date(2025, time.March, 10, 15, 0, 0, 0, time.UTC)
+	date := func(day int) *time.Time {
+		d := time.Date(2025, time.March, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	subscriptions := []Subscription{
+		{Name: "Yesterday", Status: "Active", RenewalDate: date(9)},
+		{Name: "Later", Status: "Active", RenewalDate: date(20)},
+		{Name: "Paused", Status: "Paused", RenewalDate: date(11)},
+		{Name: "Trial", Status: "Trial", RenewalDate: date(12)},
+		{Name: "No date", Status: "Active"},
+	}
+	assert.Equal(t, "Trial", NextRenewal(subscriptions, now).Name)
+
+	subscriptions = append(subscriptions, Subscription{Name: "Today", Status: "Active", RenewalDate: date(10)})
+	assert.Equal(t, "Today", NextRenewal(subscriptions, now).Name)
+
+	assert.Nil(t, NextRenewal(subscriptions[:1], now))
+}
+
+func TestCanChangeStatus(t *testing.T) {
+	assert.True(t, CanChangeStatus(StatusActive, StatusCancelled))
+	assert.True(t, CanChangeStatus(StatusTrial, StatusPaused))
+	assert.True(t, CanChangeStatus(StatusCancelled, StatusActive))
+	assert.True(t, CanChangeStatus(StatusCancelled, StatusCancelled))
+	assert.False(t, CanChangeStatus(StatusCancelled, StatusPaused))
+	assert.False(t, CanChangeStatus(StatusActive, "Expired"))
+	assert.False(t, CanChangeStatus("Expired", "Expired"))
+}
+
+func TestProjectRenewalDates(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	t.Run("Monthly walks back from a future renewal date", func(t *testing.T) {
+		dates := ProjectRenewalDates(day(2025, 6, 15), "Monthly", day(2025, 1, 1), day(2025, 4, 1))
+		assert.Equal(t, []time.Time{day(2025, 1, 15), day(2025, 2, 15), day(2025, 3, 15)}, dates)
+	})
+
+	t.Run("Annual walks forward from a past renewal date", func(t *testing.T) {
+		dates := ProjectRenewalDates(day(2020, 3, 1), "Annual", day(2024, 1, 1), day(2027, 1, 1))
+		assert.Equal(t, []time.Time{day(2024, 3, 1), day(2025, 3, 1), day(2026, 3, 1)}, dates)
+	})
+
+	t.Run("Daily covers ranges longer than a month", func(t *testing.T) {
+		dates := ProjectRenewalDates(day(2025, 1, 1), "Daily", day(2025, 1, 1), day(2026, 1, 1))
+		assert.Len(t, dates, 365)
+		assert.Equal(t, day(2025, 12, 31), dates[len(dates)-1])
+	})
+
+	t.Run("Unknown schedule only returns the renewal date", func(t *testing.T) {
+		assert.Equal(t, []time.Time{day(2025, 2, 1)}, ProjectRenewalDates(day(2025, 2, 1), "Lifetime", day(2025, 1, 1), day(2025, 3, 1)))
+		assert.Empty(t, ProjectRenewalDates(day(2025, 4, 1), "Lifetime", day(2025, 1, 1), day(2025, 3, 1)))
+	})
+}
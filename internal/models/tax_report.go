@@ -0,0 +1,38 @@
+package models
+
+// Tax report grouping periods
+const (
+	TaxPeriodMonth   = "month"
+	TaxPeriodQuarter = "quarter"
+)
+
+// TaxReport sums the net, gross and tax amounts of the subscription charges
+// of one year, grouped per month or quarter and category. Amounts are in the
+// display currency.
+type TaxReport struct {
+	Year     int               `json:"year"`
+	Period   string            `json:"period"`
+	Currency string            `json:"currency"`
+	Net      float64           `json:"net"`
+	Gross    float64           `json:"gross"`
+	Tax      float64           `json:"tax"`
+	Periods  []TaxReportPeriod `json:"periods"`
+}
+
+// TaxReportPeriod holds the totals of one month (2026-01) or quarter (2026-Q1)
+type TaxReportPeriod struct {
+	Label      string              `json:"label"`
+	Net        float64             `json:"net"`
+	Gross      float64             `json:"gross"`
+	Tax        float64             `json:"tax"`
+	Categories []TaxReportCategory `json:"categories"`
+}
+
+// TaxReportCategory holds the totals of one category within a period
+type TaxReportCategory struct {
+	Category string  `json:"category"`
+	Net      float64 `json:"net"`
+	Gross    float64 `json:"gross"`
+	Tax      float64 `json:"tax"`
+	Charges  int     `json:"charges"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// UsageEvent records a single use of a subscription (e.g. "watched Netflix today")
+type UsageEvent struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint      `json:"subscription_id" gorm:"not null;index"`
+	OccurredAt     time.Time `json:"occurred_at" gorm:"not null;index"`
+	Note           string    `json:"note,omitempty"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// UsageSummary aggregates usage events of one subscription
+type UsageSummary struct {
+	SubscriptionID uint
+	RecentCount    int64      // Events inside the analysis window
+	TotalCount     int64      // All events ever recorded
+	LastUsed       *time.Time // Most recent event, nil if never used
+}
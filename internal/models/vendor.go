@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Vendor groups related subscriptions of one provider or account, e.g. several
+// AWS accounts, so they roll up in the subscription list and statistics
+type Vendor struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// VendorSpend is the monthly spend of the active subscriptions of a vendor
+type VendorSpend struct {
+	ID           uint    `json:"id"`
+	Name         string  `json:"name"`
+	MonthlySpend float64 `json:"monthly_spend"`
+	Count        int     `json:"count"`
+	Share        float64 `json:"share"` // Percent of the total monthly spend
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"subvault/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CategoryRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRuleRepository(db *gorm.DB) *CategoryRuleRepository {
+	return &CategoryRuleRepository{db: db}
+}
+
+// GetAll returns all rules with their target category, ordered by source and match
+func (r *CategoryRuleRepository) GetAll() ([]models.CategoryRule, error) {
+	var rules []models.CategoryRule
+	if err := r.db.Preload("Category").Order("source ASC, match ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *CategoryRuleRepository) GetByID(id uint) (*models.CategoryRule, error) {
+	var rule models.CategoryRule
+	if err := r.db.Preload("Category").First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *CategoryRuleRepository) Create(rule *models.CategoryRule) error {
+	return r.db.Omit("Category").Create(rule).Error
+}
+
+// UpdateCategory points an existing rule at another category
+func (r *CategoryRuleRepository) UpdateCategory(id, categoryID uint) error {
+	return r.db.Model(&models.CategoryRule{}).Where("id = ?", id).Update("category_id", categoryID).Error
+}
+
+func (r *CategoryRuleRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.CategoryRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
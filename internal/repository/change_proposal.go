@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"subvault/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ChangeProposalRepository struct {
+	db *gorm.DB
+}
+
+func NewChangeProposalRepository(db *gorm.DB) *ChangeProposalRepository {
+	return &ChangeProposalRepository{db: db}
+}
+
+func (r *ChangeProposalRepository) Create(proposal *models.ChangeProposal) error {
+	return r.db.Create(proposal).Error
+}
+
+func (r *ChangeProposalRepository) GetByID(id uint) (*models.ChangeProposal, error) {
+	var proposal models.ChangeProposal
+	if err := r.db.First(&proposal, id).Error; err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// List returns proposals, newest first, optionally limited to the given
+// statuses and to limit entries (limit > 0)
+func (r *ChangeProposalRepository) List(limit int, statuses ...string) ([]models.ChangeProposal, error) {
+	query := r.db.Order("created_at DESC, id DESC")
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var proposals []models.ChangeProposal
+	if err := query.Find(&proposals).Error; err != nil {
+		return nil, err
+	}
+	return proposals, nil
+}
+
+// CountPending returns how many proposals wait for a decision
+func (r *ChangeProposalRepository) CountPending() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ChangeProposal{}).Where("status = ?", models.ProposalPending).Count(&count).Error
+	return count, err
+}
+
+// SetStatus moves a proposal from one status to another and reports whether
+// it was still in the from status, so that a decision is only taken once
+func (r *ChangeProposalRepository) SetStatus(id uint, from, to string, decidedAt *time.Time) (bool, error) {
+	result := r.db.Model(&models.ChangeProposal{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(map[string]interface{}{"status": to, "decided_at": decidedAt})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -66,3 +66,10 @@ func (r *ExchangeRateRepository) DeleteStaleRates(olderThan time.Duration) error
 	cutoff := time.Now().Add(-olderThan)
 	return r.db.Where("date < ?", cutoff).Delete(&models.ExchangeRate{}).Error
 }
+
+// DeleteRatesBefore removes exchange rates dated before the cutoff, always keeping
+// the most recent set so conversions keep working while the ECB is unreachable
+func (r *ExchangeRateRepository) DeleteRatesBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("date < ? AND date < (SELECT MAX(date) FROM exchange_rates)", cutoff).Delete(&models.ExchangeRate{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"subvault/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type FireflyRecurrenceRepository struct {
+	db *gorm.DB
+}
+
+func NewFireflyRecurrenceRepository(db *gorm.DB) *FireflyRecurrenceRepository {
+	return &FireflyRecurrenceRepository{db: db}
+}
+
+// GetAll returns every link between a subscription and a Firefly III recurring transaction
+func (r *FireflyRecurrenceRepository) GetAll() ([]models.FireflyRecurrence, error) {
+	var links []models.FireflyRecurrence
+	if err := r.db.Order("subscription_id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// Save creates or updates a link
+func (r *FireflyRecurrenceRepository) Save(link *models.FireflyRecurrence) error {
+	return r.db.Save(link).Error
+}
+
+func (r *FireflyRecurrenceRepository) Delete(id uint) error {
+	return r.db.Delete(&models.FireflyRecurrence{}, id).Error
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"subvault/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ImportBatchEntry is a subscription to create as part of an import batch.
+// NewCategory is created with the batch when set; entries may share the same pointer.
+// Payments are the subscription's past payments, added to the payment ledger.
+type ImportBatchEntry struct {
+	Subscription *models.Subscription
+	NewCategory  *models.Category
+	Payments     []models.Payment
+}
+
+type ImportBatchRepository struct {
+	db *gorm.DB
+}
+
+func NewImportBatchRepository(db *gorm.DB) *ImportBatchRepository {
+	return &ImportBatchRepository{db: db}
+}
+
+// Create stores the batch together with all its subscriptions, new categories
+// and payments in a single transaction. Nothing is written if any entry fails.
+func (r *ImportBatchRepository) Create(batch *models.ImportBatch, entries []ImportBatchEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if entry.NewCategory != nil {
+				if entry.NewCategory.ID == 0 {
+					entry.NewCategory.ImportBatchID = &batch.ID
+					if err := tx.Create(entry.NewCategory).Error; err != nil {
+						return err
+					}
+					batch.CategoryCount++
+				}
+				entry.Subscription.CategoryID = entry.NewCategory.ID
+			}
+
+			entry.Subscription.ImportBatchID = &batch.ID
+			if err := tx.Omit("Category").Create(entry.Subscription).Error; err != nil {
+				return err
+			}
+			batch.SubscriptionCount++
+
+			for i := range entry.Payments {
+				entry.Payments[i].SubscriptionID = entry.Subscription.ID
+			}
+			if len(entry.Payments) > 0 {
+				if err := tx.Create(&entry.Payments).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Model(batch).Updates(map[string]interface{}{
+			"subscription_count": batch.SubscriptionCount,
+			"category_count":     batch.CategoryCount,
+		}).Error
+	})
+}
+
+// GetAll returns all import batches, newest first
+func (r *ImportBatchRepository) GetAll() ([]models.ImportBatch, error) {
+	var batches []models.ImportBatch
+	if err := r.db.Order("created_at DESC, id DESC").Find(&batches).Error; err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// Delete removes a batch and every subscription created by it. Categories created
+// by the batch are removed as well unless other subscriptions now use them.
+// Returns the number of deleted subscriptions.
+func (r *ImportBatchRepository) Delete(id uint) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var batch models.ImportBatch
+		if err := tx.First(&batch, id).Error; err != nil {
+			return err
+		}
+
+		imported := tx.Unscoped().Model(&models.Subscription{}).Where("import_batch_id = ?", id).Select("id")
+		if err := tx.Where("subscription_id IN (?)", imported).Delete(&models.UsageEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id IN (?)", imported).Delete(&models.SubscriptionShare{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id IN (?)", imported).Delete(&models.ReminderRetry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id IN (?)", imported).Delete(&models.Payment{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("import_batch_id = ?", id).Delete(&models.Subscription{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+
+		if err := tx.Where("import_batch_id = ? AND is_default = ? AND id NOT IN (?)",
+			id, false, tx.Unscoped().Model(&models.Subscription{}).Where("category_id IS NOT NULL").Select("category_id")).
+			Delete(&models.Category{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&batch).Error
+	})
+	return deleted, err
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"subvault/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type InboundEmailRepository struct {
+	db *gorm.DB
+}
+
+func NewInboundEmailRepository(db *gorm.DB) *InboundEmailRepository {
+	return &InboundEmailRepository{db: db}
+}
+
+func (r *InboundEmailRepository) Create(email *models.InboundEmail) error {
+	return r.db.Create(email).Error
+}
+
+// GetAll returns the pending receipts, newest first
+func (r *InboundEmailRepository) GetAll() ([]models.InboundEmail, error) {
+	var emails []models.InboundEmail
+	if err := r.db.Order("received_at DESC, id DESC").Find(&emails).Error; err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+// Delete removes a pending receipt
+func (r *InboundEmailRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.InboundEmail{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Trim keeps the newest keep pending receipts and removes the rest
+func (r *InboundEmailRepository) Trim(keep int) error {
+	return r.db.Where("id NOT IN (?)", r.db.Model(&models.InboundEmail{}).Select("id").Order("received_at DESC, id DESC").Limit(keep)).
+		Delete(&models.InboundEmail{}).Error
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"subvault/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type JobRunRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRunRepository(db *gorm.DB) *JobRunRepository {
+	return &JobRunRepository{db: db}
+}
+
+// GetAll returns the last run of every job that has run
+func (r *JobRunRepository) GetAll() ([]models.JobRun, error) {
+	var runs []models.JobRun
+	if err := r.db.Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// Save creates or updates the last run of a job
+func (r *JobRunRepository) Save(run *models.JobRun) error {
+	return r.db.Save(run).Error
+}
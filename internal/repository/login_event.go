@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"subvault/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type LoginEventRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginEventRepository(db *gorm.DB) *LoginEventRepository {
+	return &LoginEventRepository{db: db}
+}
+
+func (r *LoginEventRepository) Create(event *models.LoginEvent) error {
+	return r.db.Create(event).Error
+}
+
+// Recent returns the newest limit login attempts, newest first
+func (r *LoginEventRepository) Recent(limit int) ([]models.LoginEvent, error) {
+	var events []models.LoginEvent
+	if err := r.db.Order("created_at DESC, id DESC").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// CountSuccessful returns how many successful logins are recorded
+func (r *LoginEventRepository) CountSuccessful() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.LoginEvent{}).Where("success = ?", true).Count(&count).Error
+	return count, err
+}
+
+// CountSuccessfulFrom returns how many successful logins came from an IP and user agent
+func (r *LoginEventRepository) CountSuccessfulFrom(ip, userAgent string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.LoginEvent{}).Where("success = ? AND ip = ? AND user_agent = ?", true, ip, userAgent).Count(&count).Error
+	return count, err
+}
+
+// Trim keeps the newest keep login attempts and removes the rest
+func (r *LoginEventRepository) Trim(keep int) error {
+	return r.db.Where("id NOT IN (?)", r.db.Model(&models.LoginEvent{}).Select("id").Order("created_at DESC, id DESC").Limit(keep)).
+		Delete(&models.LoginEvent{}).Error
+}
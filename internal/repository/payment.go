@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"subvault/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PaymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository(db *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+func (r *PaymentRepository) Create(payment *models.Payment) error {
+	return r.db.Create(payment).Error
+}
+
+func (r *PaymentRepository) Save(payment *models.Payment) error {
+	return r.db.Save(payment).Error
+}
+
+func (r *PaymentRepository) GetByID(id uint) (*models.Payment, error) {
+	var payment models.Payment
+	if err := r.db.First(&payment, id).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// Exists reports whether a payment was recorded for the renewal of a
+// subscription on dueDate
+func (r *PaymentRepository) Exists(subscriptionID uint, dueDate time.Time) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.Payment{}).
+		Where("subscription_id = ? AND due_date = ?", subscriptionID, dueDate).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FindByDueDate returns the payment recorded for the renewal of a subscription
+// on dueDate
+func (r *PaymentRepository) FindByDueDate(subscriptionID uint, dueDate time.Time) (*models.Payment, error) {
+	var payment models.Payment
+	if err := r.db.Where("subscription_id = ? AND due_date = ?", subscriptionID, dueDate).First(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// List returns payments, newest renewal first, optionally limited to one
+// subscription (subscriptionID > 0) and to the given statuses
+func (r *PaymentRepository) List(subscriptionID uint, statuses ...string) ([]models.Payment, error) {
+	query := r.db.Order("due_date DESC, id DESC")
+	if subscriptionID > 0 {
+		query = query.Where("subscription_id = ?", subscriptionID)
+	}
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+
+	var payments []models.Payment
+	if err := query.Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"subvault/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReminderRetryRepository struct {
+	db *gorm.DB
+}
+
+func NewReminderRetryRepository(db *gorm.DB) *ReminderRetryRepository {
+	return &ReminderRetryRepository{db: db}
+}
+
+// Get returns the retry of a subscription's reminder kind
+func (r *ReminderRetryRepository) Get(subscriptionID uint, kind string) (*models.ReminderRetry, error) {
+	var retry models.ReminderRetry
+	if err := r.db.Where("subscription_id = ? AND kind = ?", subscriptionID, kind).First(&retry).Error; err != nil {
+		return nil, err
+	}
+	return &retry, nil
+}
+
+// Save creates or updates a retry
+func (r *ReminderRetryRepository) Save(retry *models.ReminderRetry) error {
+	return r.db.Save(retry).Error
+}
+
+// Delete removes the retry of a subscription's reminder kind
+func (r *ReminderRetryRepository) Delete(subscriptionID uint, kind string) error {
+	return r.db.Where("subscription_id = ? AND kind = ?", subscriptionID, kind).Delete(&models.ReminderRetry{}).Error
+}
+
+// GetDue returns retries whose next attempt is at or before now and that have
+// fewer than maxAttempts failed attempts
+func (r *ReminderRetryRepository) GetDue(now time.Time, maxAttempts int) ([]models.ReminderRetry, error) {
+	var retries []models.ReminderRetry
+	if err := r.db.Where("next_attempt_at <= ? AND attempts < ?", now, maxAttempts).
+		Order("next_attempt_at").Find(&retries).Error; err != nil {
+		return nil, err
+	}
+	return retries, nil
+}
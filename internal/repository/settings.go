@@ -74,10 +74,10 @@ func (r *SettingsRepository) GetAllAPIKeys() ([]models.APIKey, error) {
 	return keys, err
 }
 
-// GetAPIKeyByKey retrieves an API key by its key value
-func (r *SettingsRepository) GetAPIKeyByKey(key string) (*models.APIKey, error) {
+// GetAPIKeyByHash retrieves an API key by the hash of its key value
+func (r *SettingsRepository) GetAPIKeyByHash(hash string) (*models.APIKey, error) {
 	var apiKey models.APIKey
-	err := r.db.Where("key = ?", key).First(&apiKey).Error
+	err := r.db.Where("key = ?", hash).First(&apiKey).Error
 	if err != nil {
 		return nil, err
 	}
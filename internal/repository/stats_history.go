@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"subvault/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StatsHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewStatsHistoryRepository(db *gorm.DB) *StatsHistoryRepository {
+	return &StatsHistoryRepository{db: db}
+}
+
+// Save stores a snapshot, replacing an earlier one of the same day
+func (r *StatsHistoryRepository) Save(snapshot *models.StatsSnapshot) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"active_count", "currency", "monthly_spend", "currency_spend", "categories", "updated_at"}),
+	}).Create(snapshot).Error
+}
+
+// Since returns the snapshots taken on or after since, oldest first
+func (r *StatsHistoryRepository) Since(since time.Time) ([]models.StatsSnapshot, error) {
+	var snapshots []models.StatsSnapshot
+	if err := r.db.Where("date >= ?", since).Order("date").Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
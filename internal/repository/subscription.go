@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"strings"
 	"subvault/internal/models"
 	"time"
@@ -28,16 +29,17 @@ func (r *SubscriptionRepository) checkLegacyColumn() bool {
 	return exists
 }
 
-func (r *SubscriptionRepository) Create(subscription *models.Subscription) (*models.Subscription, error) {
+func (r *SubscriptionRepository) Create(ctx context.Context, subscription *models.Subscription) (*models.Subscription, error) {
+	db := r.db.WithContext(ctx)
 	// Check if the old category column exists (for legacy schema support)
 	columnExists := r.checkLegacyColumn()
 
 	if columnExists && subscription.CategoryID > 0 {
 		// For legacy schema, we need to populate the old category column
 		var category models.Category
-		if err := r.db.First(&category, subscription.CategoryID).Error; err == nil {
+		if err := db.First(&category, subscription.CategoryID).Error; err == nil {
 			// Use transaction for thread safety
-			err := r.db.Transaction(func(tx *gorm.DB) error {
+			err := db.Transaction(func(tx *gorm.DB) error {
 				result := tx.Exec(`
 					INSERT INTO subscriptions (
 						name, cost, schedule, status, category_id, category, original_currency,
@@ -74,30 +76,38 @@ func (r *SubscriptionRepository) Create(subscription *models.Subscription) (*mod
 	}
 
 	// Normal creation for migrated schema
-	if err := r.db.Create(subscription).Error; err != nil {
+	if err := db.Create(subscription).Error; err != nil {
 		return nil, err
 	}
 	return subscription, nil
 }
 
-func (r *SubscriptionRepository) GetAll() ([]models.Subscription, error) {
+func (r *SubscriptionRepository) GetAll(ctx context.Context) ([]models.Subscription, error) {
 	var subscriptions []models.Subscription
-	if err := r.db.Preload("Category").Order("created_at DESC").Find(&subscriptions).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Category").Preload("Vendor").Order("created_at DESC").Find(&subscriptions).Error; err != nil {
 		return nil, err
 	}
 	return subscriptions, nil
 }
 
 // GetAllPaginated returns subscriptions with pagination support.
-// Returns the subscriptions for the requested page and the total count.
-func (r *SubscriptionRepository) GetAllPaginated(limit, offset int) ([]models.Subscription, int64, error) {
+// Returns the subscriptions for the requested page and the total count,
+// limited to one purpose unless purpose is empty.
+func (r *SubscriptionRepository) GetAllPaginated(ctx context.Context, limit, offset int, purpose string) ([]models.Subscription, int64, error) {
+	byPurpose := func(db *gorm.DB) *gorm.DB {
+		if purpose == "" {
+			return db
+		}
+		return db.Where("purpose = ?", purpose)
+	}
+
 	var total int64
-	if err := r.db.Model(&models.Subscription{}).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&models.Subscription{}).Scopes(byPurpose).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	var subscriptions []models.Subscription
-	if err := r.db.Preload("Category").Order("created_at DESC").Limit(limit).Offset(offset).Find(&subscriptions).Error; err != nil {
+	if err := r.db.WithContext(ctx).Scopes(byPurpose).Preload("Category").Preload("Vendor").Order("created_at DESC").Limit(limit).Offset(offset).Find(&subscriptions).Error; err != nil {
 		return nil, 0, err
 	}
 	return subscriptions, total, nil
@@ -106,9 +116,9 @@ func (r *SubscriptionRepository) GetAllPaginated(limit, offset int) ([]models.Su
 // GetAllSorted returns all subscriptions sorted by the specified column and order
 // sortBy: name, cost, status, renewal_date, schedule, category, created_at
 // order: asc, desc
-func (r *SubscriptionRepository) GetAllSorted(sortBy, order string) ([]models.Subscription, error) {
+func (r *SubscriptionRepository) GetAllSorted(ctx context.Context, sortBy, order string) ([]models.Subscription, error) {
 	var subscriptions []models.Subscription
-	query := r.db.Preload("Category")
+	query := r.db.WithContext(ctx).Preload("Category").Preload("Vendor")
 
 	// Validate and set sort column
 	validSortColumns := map[string]string{
@@ -145,18 +155,19 @@ func (r *SubscriptionRepository) GetAllSorted(sortBy, order string) ([]models.Su
 	return subscriptions, nil
 }
 
-func (r *SubscriptionRepository) GetByID(id uint) (*models.Subscription, error) {
+func (r *SubscriptionRepository) GetByID(ctx context.Context, id uint) (*models.Subscription, error) {
 	var subscription models.Subscription
-	if err := r.db.Preload("Category").First(&subscription, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Category").Preload("Vendor").First(&subscription, id).Error; err != nil {
 		return nil, err
 	}
 	return &subscription, nil
 }
 
-func (r *SubscriptionRepository) Update(id uint, subscription *models.Subscription) (*models.Subscription, error) {
+func (r *SubscriptionRepository) Update(ctx context.Context, id uint, subscription *models.Subscription) (*models.Subscription, error) {
+	db := r.db.WithContext(ctx)
 	// First, get the existing subscription
 	var existing models.Subscription
-	if err := r.db.First(&existing, id).Error; err != nil {
+	if err := db.First(&existing, id).Error; err != nil {
 		return nil, err
 	}
 
@@ -169,6 +180,7 @@ func (r *SubscriptionRepository) Update(id uint, subscription *models.Subscripti
 	existing.Schedule = subscription.Schedule
 	existing.Status = subscription.Status
 	existing.CategoryID = subscription.CategoryID
+	existing.VendorID = subscription.VendorID
 	existing.OriginalCurrency = subscription.OriginalCurrency
 	existing.PaymentMethod = subscription.PaymentMethod
 	existing.Account = subscription.Account
@@ -180,118 +192,348 @@ func (r *SubscriptionRepository) Update(id uint, subscription *models.Subscripti
 	existing.StartDate = subscription.StartDate
 	existing.LastReminderSent = subscription.LastReminderSent
 	existing.LastReminderRenewalDate = subscription.LastReminderRenewalDate
+	existing.LastCancellationReminderSent = subscription.LastCancellationReminderSent
+	existing.LastCancellationReminderDate = subscription.LastCancellationReminderDate
 	existing.RenewalDate = subscription.RenewalDate
 	existing.CancellationDate = subscription.CancellationDate
+	existing.PaidThroughDate = subscription.PaidThroughDate
+	existing.PromoCost = subscription.PromoCost
+	existing.PromoEndDate = subscription.PromoEndDate
+	existing.PromoEndReminder = subscription.PromoEndReminder
+	existing.LastPromoReminderDate = subscription.LastPromoReminderDate
+	existing.ContractStartDate = subscription.ContractStartDate
+	existing.ContractEndDate = subscription.ContractEndDate
+	existing.MinimumTermMonths = subscription.MinimumTermMonths
+	existing.NoticePeriodDays = subscription.NoticePeriodDays
+	existing.AutoRenew = subscription.AutoRenew
+	existing.LastContractReminderDate = subscription.LastContractReminderDate
+	existing.LastPaidThroughReminderDate = subscription.LastPaidThroughReminderDate
+	existing.PaymentFailedAt = subscription.PaymentFailedAt
+	existing.PaymentRetryDate = subscription.PaymentRetryDate
+	existing.GracePeriodEnd = subscription.GracePeriodEnd
+	existing.LastGraceReminderDate = subscription.LastGraceReminderDate
 	existing.URL = subscription.URL
+	if existing.IconURL != subscription.IconURL {
+		// The color belongs to the fetched logo
+		existing.IconColor = ""
+	}
 	existing.IconURL = subscription.IconURL
 	existing.Notes = subscription.Notes
+	existing.CancelURL = subscription.CancelURL
+	existing.CancelNotes = subscription.CancelNotes
 	existing.Usage = subscription.Usage
+	existing.Purpose = subscription.Purpose
+	existing.Seats = subscription.Seats
 	existing.RenewalReminder = subscription.RenewalReminder
 	existing.RenewalReminderDays = subscription.RenewalReminderDays
 	existing.CancellationReminder = subscription.CancellationReminder
 	existing.CancellationReminderDays = subscription.CancellationReminderDays
 	existing.HighCostAlert = subscription.HighCostAlert
+	existing.NotifyChannels = subscription.NotifyChannels
 
 	if columnExists && subscription.CategoryID > 0 {
 		// For legacy schema, we need to update the old category column too
 		var category models.Category
-		if err := r.db.First(&category, subscription.CategoryID).Error; err == nil {
+		if err := db.First(&category, subscription.CategoryID).Error; err == nil {
 			// We need to manually set the category name for legacy schema
 			updates := map[string]interface{}{
-				"name":                       existing.Name,
-				"cost":                       existing.Cost,
-				"schedule":                   existing.Schedule,
-				"status":                     existing.Status,
-				"category_id":                existing.CategoryID,
-				"category":                   category.Name,
-				"original_currency":          existing.OriginalCurrency,
-				"payment_method":             existing.PaymentMethod,
-				"account":                    existing.Account,
-				"login_name":                 existing.LoginName,
-				"tax_rate":                   existing.TaxRate,
-				"price_type":                 existing.PriceType,
-				"customer_number":            existing.CustomerNumber,
-				"contract_number":            existing.ContractNumber,
-				"start_date":                 existing.StartDate,
-				"renewal_date":               existing.RenewalDate,
-				"cancellation_date":          existing.CancellationDate,
-				"url":                        existing.URL,
-				"icon_url":                   existing.IconURL,
-				"notes":                      existing.Notes,
-				"usage":                      existing.Usage,
-				"last_reminder_sent":         existing.LastReminderSent,
-				"last_reminder_renewal_date": existing.LastReminderRenewalDate,
-				"updated_at":                 time.Now(),
+				"name":                            existing.Name,
+				"cost":                            existing.Cost,
+				"schedule":                        existing.Schedule,
+				"status":                          existing.Status,
+				"category_id":                     existing.CategoryID,
+				"category":                        category.Name,
+				"vendor_id":                       existing.VendorID,
+				"original_currency":               existing.OriginalCurrency,
+				"payment_method":                  existing.PaymentMethod,
+				"account":                         existing.Account,
+				"login_name":                      existing.LoginName,
+				"tax_rate":                        existing.TaxRate,
+				"price_type":                      existing.PriceType,
+				"customer_number":                 existing.CustomerNumber,
+				"contract_number":                 existing.ContractNumber,
+				"start_date":                      existing.StartDate,
+				"renewal_date":                    existing.RenewalDate,
+				"cancellation_date":               existing.CancellationDate,
+				"paid_through_date":               existing.PaidThroughDate,
+				"promo_cost":                      existing.PromoCost,
+				"promo_end_date":                  existing.PromoEndDate,
+				"promo_end_reminder":              existing.PromoEndReminder,
+				"last_promo_reminder_date":        existing.LastPromoReminderDate,
+				"contract_start_date":             existing.ContractStartDate,
+				"contract_end_date":               existing.ContractEndDate,
+				"minimum_term_months":             existing.MinimumTermMonths,
+				"notice_period_days":              existing.NoticePeriodDays,
+				"auto_renew":                      existing.AutoRenew,
+				"last_contract_reminder_date":     existing.LastContractReminderDate,
+				"last_paid_through_reminder_date": existing.LastPaidThroughReminderDate,
+				"payment_failed_at":               existing.PaymentFailedAt,
+				"payment_retry_date":              existing.PaymentRetryDate,
+				"grace_period_end":                existing.GracePeriodEnd,
+				"last_grace_reminder_date":        existing.LastGraceReminderDate,
+				"url":                             existing.URL,
+				"icon_url":                        existing.IconURL,
+				"icon_color":                      existing.IconColor,
+				"notes":                           existing.Notes,
+				"cancel_url":                      existing.CancelURL,
+				"cancel_notes":                    existing.CancelNotes,
+				"usage":                           existing.Usage,
+				"purpose":                         existing.Purpose,
+				"seats":                           existing.Seats,
+				"notify_channels":                 existing.NotifyChannels,
+				"last_reminder_sent":              existing.LastReminderSent,
+				"last_reminder_renewal_date":      existing.LastReminderRenewalDate,
+				"updated_at":                      time.Now(),
 			}
-			if err := r.db.Model(&existing).Where("id = ?", id).Updates(updates).Error; err != nil {
+			if err := db.Model(&existing).Where("id = ?", id).Updates(updates).Error; err != nil {
 				return nil, err
 			}
-			return r.GetByID(id)
+			return r.GetByID(ctx, id)
 		}
 	}
 
 	// The existing record already has the correct ID from the First() query above
 	// Use Save which will update only the record with matching primary key
 	// This also properly triggers the BeforeUpdate hook
-	if err := r.db.Save(&existing).Error; err != nil {
+	if err := db.Save(&existing).Error; err != nil {
 		return nil, err
 	}
 
 	// Reload to get any changes from hooks
-	return r.GetByID(id)
+	return r.GetByID(ctx, id)
+}
+
+// CreateAll stores all subscriptions in a single transaction. Nothing is written
+// if any of them fails.
+func (r *SubscriptionRepository) CreateAll(ctx context.Context, subscriptions []*models.Subscription) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, subscription := range subscriptions {
+			if err := tx.Omit("Category").Create(subscription).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SubscriptionRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deleteDependents(tx, "subscription_id = ?", id); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Subscription{}, id).Error
+	})
+}
+
+// SoftDelete hides a subscription until it is restored or purged. Its usage
+// events, shares, reminder retries and payments are kept for a restore.
+func (r *SubscriptionRepository) SoftDelete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Subscription{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SoftDeleteAll hides every subscription and returns the IDs of those hidden
+func (r *SubscriptionRepository) SoftDeleteAll(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Subscription{}).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Delete(&models.Subscription{}, ids).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Restore brings back soft deleted subscriptions and returns how many were restored
+func (r *SubscriptionRepository) Restore(ctx context.Context, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Subscription{}).
+		Where("id IN ? AND deleted_at IS NOT NULL", ids).
+		Update("deleted_at", nil)
+	return result.RowsAffected, result.Error
+}
+
+// PurgeDeleted permanently deletes soft deleted subscriptions together with
+// their usage events, shares, reminder retries and payments. With ids only
+// those subscriptions are purged, otherwise all deleted before the given time.
+// Returns the IDs of the purged subscriptions.
+func (r *SubscriptionRepository) PurgeDeleted(ctx context.Context, ids []uint, before time.Time) ([]uint, error) {
+	var purged []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Unscoped().Model(&models.Subscription{}).Where("deleted_at IS NOT NULL")
+		if len(ids) > 0 {
+			query = query.Where("id IN ?", ids)
+		} else {
+			query = query.Where("deleted_at < ?", before)
+		}
+		if err := query.Pluck("id", &purged).Error; err != nil {
+			return err
+		}
+		if len(purged) == 0 {
+			return nil
+		}
+		if err := deleteDependents(tx, "subscription_id IN ?", purged); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Subscription{}, purged).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
+
+// deleteDependents deletes the rows matching conds from the tables that
+// reference subscriptions
+func deleteDependents(tx *gorm.DB, conds ...any) error {
+	for _, model := range []any{&models.UsageEvent{}, &models.SubscriptionShare{}, &models.ReminderRetry{}, &models.Payment{}} {
+		if err := tx.Delete(model, conds...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAllAsStored returns all subscriptions as stored, without the passed
+// renewal dates of active subscriptions being advanced on load
+func (r *SubscriptionRepository) GetAllAsStored(ctx context.Context) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	if err := r.db.WithContext(ctx).Model(&models.Subscription{}).Order("name ASC").Scan(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// UpdateColumns sets columns of a subscription, leaving the others untouched
+func (r *SubscriptionRepository) UpdateColumns(ctx context.Context, id uint, columns map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&models.Subscription{}).Where("id = ?", id).UpdateColumns(columns)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetLogoStatus sets the logo lookup state of a subscription
+func (r *SubscriptionRepository) SetLogoStatus(ctx context.Context, id uint, status string) error {
+	return r.db.WithContext(ctx).Model(&models.Subscription{}).Where("id = ?", id).UpdateColumn("logo_status", status).Error
+}
+
+// QueueLogo removes the icon of a subscription and marks its logo for lookup
+func (r *SubscriptionRepository) QueueLogo(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Model(&models.Subscription{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"icon_url":    "",
+		"icon_color":  "",
+		"logo_status": models.LogoPending,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetFetchedLogo stores a looked up logo and its dominant color unless an
+// icon was set in the meantime
+func (r *SubscriptionRepository) SetFetchedLogo(ctx context.Context, id uint, iconURL, color string) error {
+	return r.db.WithContext(ctx).Model(&models.Subscription{}).Where("id = ? AND (icon_url = '' OR icon_url IS NULL)", id).UpdateColumns(map[string]interface{}{
+		"icon_url":    iconURL,
+		"icon_color":  color,
+		"logo_status": models.LogoFetched,
+	}).Error
 }
 
-func (r *SubscriptionRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Subscription{}, id).Error
+// SetUploadedLogo stores an uploaded icon and its dominant color. The logo
+// status is cleared, as an uploaded icon is not looked up again.
+func (r *SubscriptionRepository) SetUploadedLogo(ctx context.Context, id uint, iconURL, color string) error {
+	result := r.db.WithContext(ctx).Model(&models.Subscription{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"icon_url":    iconURL,
+		"icon_color":  color,
+		"logo_status": "",
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
-func (r *SubscriptionRepository) Count() int64 {
+// GetPendingLogos returns up to limit subscriptions waiting for a logo lookup, oldest first
+func (r *SubscriptionRepository) GetPendingLogos(ctx context.Context, limit int) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	if err := r.db.WithContext(ctx).Where("logo_status = ?", models.LogoPending).Order("updated_at, id").Limit(limit).Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *SubscriptionRepository) Count(ctx context.Context) int64 {
 	var count int64
-	r.db.Model(&models.Subscription{}).Count(&count)
+	r.db.WithContext(ctx).Model(&models.Subscription{}).Count(&count)
 	return count
 }
 
-func (r *SubscriptionRepository) GetActiveSubscriptions() ([]models.Subscription, error) {
+func (r *SubscriptionRepository) GetActiveSubscriptions(ctx context.Context) ([]models.Subscription, error) {
 	var subscriptions []models.Subscription
-	if err := r.db.Preload("Category").Where("status = ?", "Active").Find(&subscriptions).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Category").Preload("Vendor").Where("status = ?", "Active").Find(&subscriptions).Error; err != nil {
 		return nil, err
 	}
 	return subscriptions, nil
 }
 
-func (r *SubscriptionRepository) GetCancelledSubscriptions() ([]models.Subscription, error) {
+func (r *SubscriptionRepository) GetCancelledSubscriptions(ctx context.Context) ([]models.Subscription, error) {
 	var subscriptions []models.Subscription
-	if err := r.db.Preload("Category").Where("status = ?", "Cancelled").Find(&subscriptions).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Category").Preload("Vendor").Where("status = ?", "Cancelled").Find(&subscriptions).Error; err != nil {
 		return nil, err
 	}
 	return subscriptions, nil
 }
 
-func (r *SubscriptionRepository) GetUpcomingRenewals(days int) ([]models.Subscription, error) {
+func (r *SubscriptionRepository) GetUpcomingRenewals(ctx context.Context, days int) ([]models.Subscription, error) {
 	var subscriptions []models.Subscription
 	endDate := time.Now().AddDate(0, 0, days)
 
-	if err := r.db.Where("status = ? AND renewal_date IS NOT NULL AND renewal_date BETWEEN ? AND ?",
+	if err := r.db.WithContext(ctx).Where("status = ? AND renewal_date IS NOT NULL AND renewal_date BETWEEN ? AND ?",
 		"Active", time.Now(), endDate).Find(&subscriptions).Error; err != nil {
 		return nil, err
 	}
 	return subscriptions, nil
 }
 
-func (r *SubscriptionRepository) GetUpcomingCancellations(days int) ([]models.Subscription, error) {
+func (r *SubscriptionRepository) GetUpcomingCancellations(ctx context.Context, days int) ([]models.Subscription, error) {
 	var subscriptions []models.Subscription
 	endDate := time.Now().AddDate(0, 0, days)
 
-	if err := r.db.Where("status = ? AND cancellation_date IS NOT NULL AND cancellation_date BETWEEN ? AND ?",
+	if err := r.db.WithContext(ctx).Where("status = ? AND cancellation_date IS NOT NULL AND cancellation_date BETWEEN ? AND ?",
 		"Cancelled", time.Now(), endDate).Find(&subscriptions).Error; err != nil {
 		return nil, err
 	}
 	return subscriptions, nil
 }
 
-func (r *SubscriptionRepository) GetSubscriptionsWithRenewalReminder() ([]models.Subscription, error) {
+func (r *SubscriptionRepository) GetSubscriptionsWithRenewalReminder(ctx context.Context) ([]models.Subscription, error) {
 	var subscriptions []models.Subscription
-	if err := r.db.Preload("Category").
+	if err := r.db.WithContext(ctx).Preload("Category").Preload("Vendor").
 		Where("status = ? AND renewal_reminder = ? AND renewal_date IS NOT NULL", "Active", true).
 		Find(&subscriptions).Error; err != nil {
 		return nil, err
@@ -299,9 +541,57 @@ func (r *SubscriptionRepository) GetSubscriptionsWithRenewalReminder() ([]models
 	return subscriptions, nil
 }
 
-func (r *SubscriptionRepository) GetSubscriptionsWithCancellationReminder() ([]models.Subscription, error) {
+// GetSubscriptionsInGracePeriod returns active subscriptions with a failed
+// payment and a known service cutoff date
+func (r *SubscriptionRepository) GetSubscriptionsInGracePeriod(ctx context.Context) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	if err := r.db.WithContext(ctx).Preload("Category").Preload("Vendor").
+		Where("status = ? AND payment_failed_at IS NOT NULL AND grace_period_end IS NOT NULL", "Active").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetSubscriptionsWithContract returns the subscriptions that are not
+// cancelled and have a contract term
+func (r *SubscriptionRepository) GetSubscriptionsWithContract(ctx context.Context) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	if err := r.db.WithContext(ctx).Preload("Category").Preload("Vendor").
+		Where("status != ? AND (contract_end_date IS NOT NULL OR (contract_start_date IS NOT NULL AND minimum_term_months > 0))", "Cancelled").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetCancelledSubscriptionsWithPaidThrough returns cancelled subscriptions
+// with a known end of their paid period
+func (r *SubscriptionRepository) GetCancelledSubscriptionsWithPaidThrough(ctx context.Context) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	if err := r.db.WithContext(ctx).Preload("Category").Preload("Vendor").
+		Where("status = ? AND paid_through_date IS NOT NULL", "Cancelled").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetSubscriptionsWithPromoEndReminder returns subscriptions that are not
+// cancelled and want a reminder before their promotional price ends
+func (r *SubscriptionRepository) GetSubscriptionsWithPromoEndReminder(ctx context.Context) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	if err := r.db.WithContext(ctx).Preload("Category").Preload("Vendor").
+		Where("status != ? AND promo_end_reminder = ? AND promo_cost IS NOT NULL AND promo_end_date IS NOT NULL", "Cancelled", true).
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *SubscriptionRepository) GetSubscriptionsWithCancellationReminder(ctx context.Context) ([]models.Subscription, error) {
 	var subscriptions []models.Subscription
-	if err := r.db.Preload("Category").
+	if err := r.db.WithContext(ctx).Preload("Category").Preload("Vendor").
 		Where("cancellation_reminder = ? AND cancellation_date IS NOT NULL", true).
 		Find(&subscriptions).Error; err != nil {
 		return nil, err
@@ -309,9 +599,9 @@ func (r *SubscriptionRepository) GetSubscriptionsWithCancellationReminder() ([]m
 	return subscriptions, nil
 }
 
-func (r *SubscriptionRepository) GetSubscriptionsWithHighCostAlert() ([]models.Subscription, error) {
+func (r *SubscriptionRepository) GetSubscriptionsWithHighCostAlert(ctx context.Context) ([]models.Subscription, error) {
 	var subscriptions []models.Subscription
-	if err := r.db.Preload("Category").
+	if err := r.db.WithContext(ctx).Preload("Category").Preload("Vendor").
 		Where("high_cost_alert = ?", true).
 		Find(&subscriptions).Error; err != nil {
 		return nil, err
@@ -319,12 +609,12 @@ func (r *SubscriptionRepository) GetSubscriptionsWithHighCostAlert() ([]models.S
 	return subscriptions, nil
 }
 
-func (r *SubscriptionRepository) GetCategoryStats() ([]models.CategoryStat, error) {
+func (r *SubscriptionRepository) GetCategoryStats(ctx context.Context) ([]models.CategoryStat, error) {
 	var stats []models.CategoryStat
-	if err := r.db.Table("subscriptions").
+	if err := r.db.WithContext(ctx).Table("subscriptions").
 		Select("categories.name as category, SUM(CASE WHEN subscriptions.schedule = 'Annual' THEN subscriptions.cost/12 WHEN subscriptions.schedule = 'Quarterly' THEN subscriptions.cost/3 WHEN subscriptions.schedule = 'Monthly' THEN subscriptions.cost WHEN subscriptions.schedule = 'Weekly' THEN subscriptions.cost*4.33 WHEN subscriptions.schedule = 'Daily' THEN subscriptions.cost*30.44 ELSE subscriptions.cost END) as amount, COUNT(*) as count").
 		Joins("left join categories on subscriptions.category_id = categories.id").
-		Where("subscriptions.status = ?", "Active").
+		Where("subscriptions.status = ? AND subscriptions.deleted_at IS NULL", "Active").
 		Group("categories.name").
 		Scan(&stats).Error; err != nil {
 		return nil, err
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"subvault/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type SubscriptionShareRepository struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionShareRepository(db *gorm.DB) *SubscriptionShareRepository {
+	return &SubscriptionShareRepository{db: db}
+}
+
+// GetBySubscription returns the shares of a subscription in the order they were entered
+func (r *SubscriptionShareRepository) GetBySubscription(subscriptionID uint) ([]models.SubscriptionShare, error) {
+	var shares []models.SubscriptionShare
+	if err := r.db.Where("subscription_id = ?", subscriptionID).Order("id").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// GetAll returns all shares of all subscriptions
+func (r *SubscriptionShareRepository) GetAll() ([]models.SubscriptionShare, error) {
+	var shares []models.SubscriptionShare
+	if err := r.db.Order("subscription_id, id").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// Replace atomically replaces all shares of a subscription
+func (r *SubscriptionShareRepository) Replace(subscriptionID uint, shares []models.SubscriptionShare) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", subscriptionID).Delete(&models.SubscriptionShare{}).Error; err != nil {
+			return err
+		}
+		for i := range shares {
+			shares[i].ID = 0
+			shares[i].SubscriptionID = subscriptionID
+			if err := tx.Create(&shares[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"subvault/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UsageEventRepository struct {
+	db *gorm.DB
+}
+
+func NewUsageEventRepository(db *gorm.DB) *UsageEventRepository {
+	return &UsageEventRepository{db: db}
+}
+
+func (r *UsageEventRepository) Create(event *models.UsageEvent) (*models.UsageEvent, error) {
+	if err := r.db.Create(event).Error; err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// GetBySubscription returns the most recent events of a subscription
+func (r *UsageEventRepository) GetBySubscription(subscriptionID uint, limit int) ([]models.UsageEvent, error) {
+	var events []models.UsageEvent
+	if err := r.db.Where("subscription_id = ?", subscriptionID).
+		Order("occurred_at DESC").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetSummaries returns usage counts per subscription, counting events since the
+// given time as recent. Subscriptions without any events are not included.
+func (r *UsageEventRepository) GetSummaries(since time.Time) (map[uint]*models.UsageSummary, error) {
+	var counts []struct {
+		SubscriptionID uint
+		TotalCount     int64
+		RecentCount    int64
+	}
+	if err := r.db.Model(&models.UsageEvent{}).
+		Select("subscription_id, COUNT(*) AS total_count, SUM(CASE WHEN occurred_at >= ? THEN 1 ELSE 0 END) AS recent_count", since).
+		Group("subscription_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[uint]*models.UsageSummary, len(counts))
+	for _, c := range counts {
+		summaries[c.SubscriptionID] = &models.UsageSummary{
+			SubscriptionID: c.SubscriptionID,
+			TotalCount:     c.TotalCount,
+			RecentCount:    c.RecentCount,
+		}
+	}
+
+	// Latest event per subscription
+	var latest []models.UsageEvent
+	if err := r.db.Raw(`SELECT e.* FROM usage_events e
+		JOIN (SELECT subscription_id, MAX(occurred_at) AS last_used FROM usage_events GROUP BY subscription_id) l
+		ON e.subscription_id = l.subscription_id AND e.occurred_at = l.last_used`).Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+	for i := range latest {
+		if summary, ok := summaries[latest[i].SubscriptionID]; ok {
+			summary.LastUsed = &latest[i].OccurredAt
+		}
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"subvault/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type VendorRepository struct {
+	db *gorm.DB
+}
+
+func NewVendorRepository(db *gorm.DB) *VendorRepository {
+	return &VendorRepository{db: db}
+}
+
+func (r *VendorRepository) Create(vendor *models.Vendor) error {
+	return r.db.Create(vendor).Error
+}
+
+func (r *VendorRepository) GetAll() ([]models.Vendor, error) {
+	var vendors []models.Vendor
+	if err := r.db.Order("name ASC").Find(&vendors).Error; err != nil {
+		return nil, err
+	}
+	return vendors, nil
+}
+
+func (r *VendorRepository) GetByID(id uint) (*models.Vendor, error) {
+	var vendor models.Vendor
+	if err := r.db.First(&vendor, id).Error; err != nil {
+		return nil, err
+	}
+	return &vendor, nil
+}
+
+// Rename changes the name of a vendor
+func (r *VendorRepository) Rename(id uint, name string) error {
+	return r.db.Model(&models.Vendor{}).Where("id = ?", id).Update("name", name).Error
+}
+
+// Delete removes a vendor; its subscriptions are kept without a vendor
+func (r *VendorRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Subscription{}).Where("vendor_id = ?", id).Update("vendor_id", nil).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Vendor{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,337 @@
+// Package scheduler runs the background jobs on their intervals, records the
+// outcome of every run and lets jobs be listed and triggered on demand.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// Background job names
+const (
+	JobRenewalReminders      = "renewal_reminders"
+	JobCancellationReminders = "cancellation_reminders"
+	JobGracePeriodReminders  = "grace_period_reminders"
+	JobContractReminders     = "contract_reminders"
+	JobPaidThroughReminders  = "paid_through_reminders"
+	JobPromoReminders        = "promo_reminders"
+	JobUnusedNudge           = "unused_nudge"
+	JobRateAlerts            = "rate_alerts"
+	JobCurrencyRefresh       = "currency_refresh"
+	JobBackup                = "backup"
+	JobHousekeeping          = "housekeeping"
+	JobNotificationQueue     = "notification_queue"
+	JobReminderRetries       = "reminder_retries"
+	JobRenewalConfirmations  = "renewal_confirmations"
+	JobBankSync              = "bank_sync"
+	JobBudgetSync            = "budget_sync"
+	JobLogoQueue             = "logo_queue"
+	JobUpdateCheck           = "update_check"
+	JobStatsSnapshot         = "stats_snapshot"
+	JobWeeklySummary         = "weekly_summary"
+	JobMonthlyReport         = "monthly_report"
+)
+
+// DefaultTick is how often Start checks for due jobs
+const DefaultTick = time.Minute
+
+// ErrJobNotFound is returned when triggering a job that is not registered
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobRunning is returned when triggering a job that is already running
+var ErrJobRunning = errors.New("job is already running")
+
+// Clock tells the scheduler what time it is, so tests can move time
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the wall clock
+var SystemClock Clock = systemClock{}
+
+// Job is a unit of background work. Run receives the scheduler's current time
+// so a job can be tested against any date, and a context that is cancelled
+// when the scheduler stops.
+type Job interface {
+	Name() string
+	// Interval is how often the job runs; zero means manual runs only
+	Interval() time.Duration
+	Run(ctx context.Context, now time.Time) error
+}
+
+type funcJob struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context, now time.Time) error
+}
+
+func (j *funcJob) Name() string                                 { return j.name }
+func (j *funcJob) Interval() time.Duration                      { return j.interval }
+func (j *funcJob) Run(ctx context.Context, now time.Time) error { return j.run(ctx, now) }
+
+// NewJob returns a Job that calls run every interval
+func NewJob(name string, interval time.Duration, run func(ctx context.Context, now time.Time) error) Job {
+	return &funcJob{name: name, interval: interval, run: run}
+}
+
+// Store persists the last run of each job across restarts
+type Store interface {
+	GetAll() ([]models.JobRun, error)
+	Save(run *models.JobRun) error
+}
+
+// Status describes a background job and the outcome of its last run
+type Status struct {
+	Name string `json:"name"`
+	// IntervalHours is how often the scheduler runs the job; 0 with no
+	// IntervalMinutes means manual only
+	IntervalHours int `json:"interval_hours"`
+	// IntervalMinutes is set for jobs that run more often than hourly
+	IntervalMinutes int        `json:"interval_minutes,omitempty"`
+	Running         bool       `json:"running"`
+	LastRun         *time.Time `json:"last_run,omitempty"`
+	NextRun         *time.Time `json:"next_run,omitempty"`
+	DurationMs      int64      `json:"duration_ms"`
+	Success         bool       `json:"success"`
+	Error           string     `json:"error,omitempty"`
+	Runs            int        `json:"runs"`
+}
+
+type entry struct {
+	job    Job
+	status Status
+}
+
+// Scheduler runs the registered jobs when their interval has elapsed since the
+// last run. Because the last run is persisted, a job that was due while the
+// server was down runs once on the first check after startup instead of
+// waiting a full interval. Every run is recorded, whether it was started by
+// the schedule or by a user.
+type Scheduler struct {
+	mu        sync.Mutex
+	clock     Clock
+	store     Store
+	jobs      map[string]*entry
+	order     []string
+	persisted map[string]models.JobRun
+}
+
+// New returns a scheduler. store may be nil to keep run status in memory only.
+func New(clock Clock, store Store) *Scheduler {
+	s := &Scheduler{
+		clock:     clock,
+		store:     store,
+		jobs:      make(map[string]*entry),
+		persisted: make(map[string]models.JobRun),
+	}
+	if store != nil {
+		runs, err := store.GetAll()
+		if err != nil {
+			slog.Warn("failed to load job runs", "error", err)
+		}
+		for _, run := range runs {
+			s.persisted[run.Name] = run
+		}
+	}
+	return s
+}
+
+// Register adds a job, restoring the outcome of its last persisted run
+func (s *Scheduler) Register(job Job) {
+	status := Status{Name: job.Name()}
+	if interval := job.Interval(); interval >= time.Hour {
+		status.IntervalHours = int(interval / time.Hour)
+	} else if interval > 0 {
+		status.IntervalMinutes = int(interval / time.Minute)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run, ok := s.persisted[job.Name()]; ok {
+		lastRun := run.LastRun
+		status.LastRun = &lastRun
+		status.DurationMs = run.DurationMs
+		status.Success = run.Success
+		status.Error = run.Error
+		status.Runs = run.Runs
+	}
+	if _, exists := s.jobs[job.Name()]; !exists {
+		s.order = append(s.order, job.Name())
+	}
+	s.jobs[job.Name()] = &entry{job: job, status: status}
+}
+
+// Run executes a job synchronously and records its outcome. Panics are
+// recovered and recorded as failures.
+func (s *Scheduler) Run(name string) error {
+	return s.run(context.Background(), name)
+}
+
+func (s *Scheduler) run(ctx context.Context, name string) error {
+	e, err := s.start(name)
+	if err != nil {
+		return err
+	}
+
+	start := s.clock.Now()
+	began := time.Now()
+	runErr := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("panic in job", "job", name, "panic", r)
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return e.job.Run(ctx, start)
+	}()
+	duration := time.Since(began)
+
+	s.mu.Lock()
+	e.status.Running = false
+	e.status.LastRun = &start
+	e.status.DurationMs = duration.Milliseconds()
+	e.status.Success = runErr == nil
+	e.status.Error = ""
+	if runErr != nil {
+		e.status.Error = runErr.Error()
+	}
+	run := models.JobRun{Name: name, LastRun: start, DurationMs: e.status.DurationMs, Success: e.status.Success, Error: e.status.Error, Runs: e.status.Runs}
+	s.mu.Unlock()
+
+	if s.store != nil {
+		if err := s.store.Save(&run); err != nil {
+			slog.Warn("failed to save job run", "job", name, "error", err)
+		}
+	}
+	if runErr != nil {
+		slog.Warn("job failed", "job", name, "duration", duration, "error", runErr)
+	}
+	return runErr
+}
+
+// Trigger starts a job in the background. It returns immediately with
+// ErrJobNotFound or ErrJobRunning if the job cannot be started.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	e, ok := s.jobs[name]
+	running := ok && e.status.Running
+	s.mu.Unlock()
+	if !ok {
+		return ErrJobNotFound
+	}
+	if running {
+		return ErrJobRunning
+	}
+
+	slog.Info("job triggered manually", "job", name)
+	go s.Run(name)
+	return nil
+}
+
+// List returns the status of all jobs in registration order
+func (s *Scheduler) List() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]Status, 0, len(s.order))
+	for _, name := range s.order {
+		e := s.jobs[name]
+		status := e.status
+		if interval := e.job.Interval(); interval > 0 {
+			next := s.clock.Now()
+			if status.LastRun != nil && status.LastRun.Add(interval).After(next) {
+				next = status.LastRun.Add(interval)
+			}
+			status.NextRun = &next
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// AnyRunning reports whether at least one job is running
+func (s *Scheduler) AnyRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.jobs {
+		if e.status.Running {
+			return true
+		}
+	}
+	return false
+}
+
+// Due returns the scheduled jobs that are not running and have never run or
+// whose interval has elapsed since their last run, in registration order
+func (s *Scheduler) Due(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []string
+	for _, name := range s.order {
+		e := s.jobs[name]
+		interval := e.job.Interval()
+		if interval <= 0 || e.status.Running {
+			continue
+		}
+		if e.status.LastRun == nil || !now.Before(e.status.LastRun.Add(interval)) {
+			due = append(due, name)
+		}
+	}
+	return due
+}
+
+// Start checks for due jobs every tick until ctx is cancelled and runs each due
+// job in its own goroutine with ctx. The first check is one tick after startup
+// so startup work such as the rate refresh finishes first.
+func (s *Scheduler) Start(ctx context.Context, tick time.Duration) {
+	for _, status := range s.List() {
+		if status.IntervalHours == 0 && status.IntervalMinutes == 0 {
+			slog.Info("job scheduling disabled", "job", status.Name)
+		}
+	}
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, name := range s.Due(s.clock.Now()) {
+				go s.run(ctx, name)
+			}
+		}
+	}
+}
+
+// RunOnWakeup runs a job each time the wakeup channel is signalled
+func (s *Scheduler) RunOnWakeup(name string, wakeups <-chan struct{}) {
+	for range wakeups {
+		s.Run(name)
+	}
+}
+
+// start marks a job as running
+func (s *Scheduler) start(name string) (*entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.jobs[name]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	if e.status.Running {
+		return nil, ErrJobRunning
+	}
+	e.status.Running = true
+	e.status.Runs++
+	return e, nil
+}
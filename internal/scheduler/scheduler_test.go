@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedClock struct{ now time.Time }
+
+func (c *fixedClock) Now() time.Time { return c.now }
+
+// memoryStore keeps job runs in a map
+type memoryStore struct{ runs map[string]models.JobRun }
+
+func (m *memoryStore) GetAll() ([]models.JobRun, error) {
+	var runs []models.JobRun
+	for _, run := range m.runs {
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func (m *memoryStore) Save(run *models.JobRun) error {
+	m.runs[run.Name] = *run
+	return nil
+}
+
+func TestScheduler_RunRecordsStatus(t *testing.T) {
+	jobs := New(SystemClock, nil)
+	fail := true
+	jobs.Register(NewJob(JobHousekeeping, 24*time.Hour, func(context.Context, time.Time) error {
+		if fail {
+			return errors.New("disk full")
+		}
+		return nil
+	}))
+	jobs.Register(NewJob(JobBackup, 0, func(context.Context, time.Time) error { panic("boom") }))
+
+	assert.ErrorIs(t, jobs.Run("missing"), ErrJobNotFound)
+
+	assert.Error(t, jobs.Run(JobHousekeeping))
+	status := jobs.List()[0]
+	assert.Equal(t, JobHousekeeping, status.Name)
+	assert.Equal(t, 24, status.IntervalHours)
+	require.NotNil(t, status.LastRun)
+	assert.False(t, status.Success)
+	assert.Equal(t, "disk full", status.Error)
+
+	fail = false
+	require.NoError(t, jobs.Run(JobHousekeeping))
+	status = jobs.List()[0]
+	assert.True(t, status.Success)
+	assert.Empty(t, status.Error)
+	assert.Equal(t, 2, status.Runs)
+
+	// Panics are recorded as failures instead of crashing the caller
+	assert.Error(t, jobs.Run(JobBackup))
+	assert.Contains(t, jobs.List()[1].Error, "boom")
+	assert.Nil(t, jobs.List()[1].NextRun)
+}
+
+func TestScheduler_TriggerRejectsRunningJob(t *testing.T) {
+	jobs := New(SystemClock, nil)
+	release := make(chan struct{})
+	done := make(chan struct{})
+	jobs.Register(NewJob(JobCurrencyRefresh, 0, func(context.Context, time.Time) error {
+		<-release
+		close(done)
+		return nil
+	}))
+
+	require.NoError(t, jobs.Trigger(JobCurrencyRefresh))
+	require.Eventually(t, jobs.AnyRunning, time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, jobs.Trigger(JobCurrencyRefresh), ErrJobRunning)
+	assert.ErrorIs(t, jobs.Trigger("missing"), ErrJobNotFound)
+
+	close(release)
+	<-done
+	require.Eventually(t, func() bool { return !jobs.AnyRunning() }, time.Second, 10*time.Millisecond)
+	assert.True(t, jobs.List()[0].Success)
+}
+
+func TestScheduler_DueCatchesUpAfterRestart(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)}
+	store := &memoryStore{runs: map[string]models.JobRun{
+		// Last ran two days ago, so the daily run was missed while down
+		JobRenewalReminders: {Name: JobRenewalReminders, LastRun: clock.now.Add(-48 * time.Hour), Success: true, Runs: 5},
+		// Ran an hour ago, not due yet
+		JobRateAlerts: {Name: JobRateAlerts, LastRun: clock.now.Add(-time.Hour), Success: true, Runs: 2},
+	}}
+
+	jobs := New(clock, store)
+	var ranAt []time.Time
+	jobs.Register(NewJob(JobRenewalReminders, 24*time.Hour, func(_ context.Context, now time.Time) error {
+		ranAt = append(ranAt, now)
+		return nil
+	}))
+	jobs.Register(NewJob(JobRateAlerts, 24*time.Hour, func(context.Context, time.Time) error { return nil }))
+	jobs.Register(NewJob(JobNotificationQueue, 5*time.Minute, func(context.Context, time.Time) error { return nil }))
+	jobs.Register(NewJob(JobCurrencyRefresh, 0, func(context.Context, time.Time) error { return nil }))
+
+	// Persisted runs are restored, jobs that never ran are due immediately and
+	// manual jobs never are
+	status := jobs.List()[0]
+	assert.Equal(t, 5, status.Runs)
+	require.NotNil(t, status.NextRun)
+	assert.Equal(t, clock.now, *status.NextRun)
+	assert.Equal(t, clock.now.Add(23*time.Hour), *jobs.List()[1].NextRun)
+	assert.Equal(t, 5, jobs.List()[2].IntervalMinutes)
+	assert.Equal(t, []string{JobRenewalReminders, JobNotificationQueue}, jobs.Due(clock.now))
+
+	// A missed run happens once, then waits a full interval
+	require.NoError(t, jobs.Run(JobRenewalReminders))
+	assert.Equal(t, []time.Time{clock.now}, ranAt)
+	assert.Equal(t, 6, store.runs[JobRenewalReminders].Runs)
+	assert.Equal(t, clock.now, store.runs[JobRenewalReminders].LastRun)
+	assert.NotContains(t, jobs.Due(clock.now), JobRenewalReminders)
+
+	clock.now = clock.now.Add(24 * time.Hour)
+	assert.Equal(t, []string{JobRenewalReminders, JobRateAlerts, JobNotificationQueue}, jobs.Due(clock.now))
+
+	// A new scheduler picks up where the last one stopped
+	restarted := New(clock, store)
+	restarted.Register(NewJob(JobRenewalReminders, 24*time.Hour, func(context.Context, time.Time) error { return nil }))
+	assert.Equal(t, 6, restarted.List()[0].Runs)
+	assert.Equal(t, []string{JobRenewalReminders}, restarted.Due(clock.now))
+	assert.Empty(t, restarted.Due(clock.now.Add(-time.Minute)))
+}
@@ -1,6 +1,10 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
 	"subvault/internal/models"
 	"subvault/internal/repository"
 )
@@ -13,13 +17,27 @@ func NewAPIKeyService(repo *repository.SettingsRepository) *APIKeyService {
 	return &APIKeyService{repo: repo}
 }
 
-// CreateAPIKey creates a new API key
-func (a *APIKeyService) CreateAPIKey(name, key string) (*models.APIKey, error) {
-	apiKey := &models.APIKey{
-		Name: name,
-		Key:  key,
+// CreateAPIKey generates and stores a new API key with the given scopes; unknown
+// scopes are dropped. The returned key is the only time its value is
+// available; only its hash and prefix are stored.
+func (a *APIKeyService) CreateAPIKey(name string, scopes []string) (*models.APIKey, error) {
+	keyBytes := make([]byte, 32)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := "sk_" + hex.EncodeToString(keyBytes)
+
+	apiKey, err := a.repo.CreateAPIKey(&models.APIKey{
+		Name:    name,
+		KeyHash: models.HashAPIKey(key),
+		Prefix:  models.APIKeyPrefix(key),
+		Scopes:  models.NormalizeAPIKeyScopes(scopes),
+	})
+	if err != nil {
+		return nil, err
 	}
-	return a.repo.CreateAPIKey(apiKey)
+	apiKey.Key = key
+	return apiKey, nil
 }
 
 // GetAllAPIKeys retrieves all API keys
@@ -34,7 +52,7 @@ func (a *APIKeyService) DeleteAPIKey(id uint) error {
 
 // ValidateAPIKey checks if an API key is valid and updates usage
 func (a *APIKeyService) ValidateAPIKey(key string) (*models.APIKey, error) {
-	apiKey, err := a.repo.GetAPIKeyByKey(key)
+	apiKey, err := a.repo.GetAPIKeyByHash(models.HashAPIKey(key))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyService_StoresOnlyHash(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.APIKey{}))
+	apiKeys := NewAPIKeyService(repository.NewSettingsRepository(db))
+
+	created, err := apiKeys.CreateAPIKey("Home Assistant", nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Key, "sk_"))
+	assert.Len(t, created.Key, 67)
+	assert.Equal(t, created.Key[:11], created.Prefix)
+
+	// The stored row has no trace of the key beyond its prefix
+	var stored models.APIKey
+	require.NoError(t, db.First(&stored, created.ID).Error)
+	assert.Empty(t, stored.Key)
+	assert.Equal(t, models.HashAPIKey(created.Key), stored.KeyHash)
+	assert.NotContains(t, stored.KeyHash, created.Key[3:])
+
+	validated, err := apiKeys.ValidateAPIKey(created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, validated.ID)
+
+	_, err = apiKeys.ValidateAPIKey(stored.KeyHash)
+	assert.Error(t, err)
+	_, err = apiKeys.ValidateAPIKey(created.Prefix)
+	assert.Error(t, err)
+
+	keys, err := apiKeys.GetAllAPIKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, 1, keys[0].UsageCount)
+	assert.Empty(t, keys[0].Key)
+}
+
+func TestAPIKeyService_Scopes(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.APIKey{}))
+	apiKeys := NewAPIKeyService(repository.NewSettingsRepository(db))
+
+	plain, err := apiKeys.CreateAPIKey("Dashboard", nil)
+	require.NoError(t, err)
+	admin, err := apiKeys.CreateAPIKey("Terraform", []string{" Admin ", "admin", "root"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.APIKeyScopeAdmin}, admin.Scopes)
+
+	validated, err := apiKeys.ValidateAPIKey(plain.Key)
+	require.NoError(t, err)
+	assert.False(t, validated.HasScope(models.APIKeyScopeAdmin))
+
+	validated, err = apiKeys.ValidateAPIKey(admin.Key)
+	require.NoError(t, err)
+	assert.True(t, validated.HasScope(models.APIKeyScopeAdmin))
+	assert.False(t, validated.IsReadOnly())
+
+	// A read-only key cannot also be an admin key
+	widget, err := apiKeys.CreateAPIKey("Widget", []string{"admin", "READ"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.APIKeyScopeRead}, widget.Scopes)
+	validated, err = apiKeys.ValidateAPIKey(widget.Key)
+	require.NoError(t, err)
+	assert.True(t, validated.IsReadOnly())
+	assert.False(t, validated.HasScope(models.APIKeyScopeAdmin))
+}
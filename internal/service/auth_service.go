@@ -4,14 +4,29 @@ import (
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"subvault/internal/models"
 	"subvault/internal/repository"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Session roles. Admins can change everything, viewers can only look.
+const (
+	RoleAdmin  = "admin"
+	RoleViewer = "viewer"
+)
+
+// ErrInvalidCredentials is returned when a username and password match no account
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrViewerUsernameTaken is returned when the viewer would share the admin's username
+var ErrViewerUsernameTaken = errors.New("viewer username must differ from the admin username")
+
 type AuthService struct {
 	settings *SettingsService
 	repo     *repository.SettingsRepository
@@ -58,8 +73,13 @@ func (a *AuthService) HashPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
-// SetAuthPassword hashes and stores the admin password
+// SetAuthPassword checks the admin password against the password policy,
+// then hashes and stores it
 func (a *AuthService) SetAuthPassword(password string) error {
+	username, _ := a.GetAuthUsername()
+	if err := a.EvaluatePassword(password, username).Err(a.GetPasswordPolicy()); err != nil {
+		return err
+	}
 	hash, err := a.HashPassword(password)
 	if err != nil {
 		return err
@@ -77,6 +97,126 @@ func (a *AuthService) ValidatePassword(password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
+// Authenticate checks a username and password against the admin and the
+// viewer account and returns the role of the matching account
+func (a *AuthService) Authenticate(username, password string) (string, error) {
+	if adminUsername, err := a.GetAuthUsername(); err == nil &&
+		subtle.ConstantTimeCompare([]byte(adminUsername), []byte(username)) == 1 {
+		if a.ValidatePassword(password) == nil {
+			return RoleAdmin, nil
+		}
+		return "", ErrInvalidCredentials
+	}
+
+	viewerUsername, ok := a.GetViewerUsername()
+	hash, hasHash := a.settings.GetCached(SettingKeyViewerPassword)
+	if !ok || !hasHash || subtle.ConstantTimeCompare([]byte(viewerUsername), []byte(username)) != 1 {
+		return "", ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return "", ErrInvalidCredentials
+	}
+	return RoleViewer, nil
+}
+
+// GetViewerUsername returns the username of the read-only viewer account, if one is set up
+func (a *AuthService) GetViewerUsername() (string, bool) {
+	username, ok := a.settings.GetCached(SettingKeyViewerUsername)
+	return username, ok && username != ""
+}
+
+// SetViewer creates or replaces the read-only viewer account
+func (a *AuthService) SetViewer(username, password string) error {
+	if adminUsername, err := a.GetAuthUsername(); err == nil && adminUsername == username {
+		return ErrViewerUsernameTaken
+	}
+	if err := a.EvaluatePassword(password, username).Err(a.GetPasswordPolicy()); err != nil {
+		return err
+	}
+	hash, err := a.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	defer a.settings.InvalidateCache()
+	if err := a.repo.Set(SettingKeyViewerUsername, username); err != nil {
+		return err
+	}
+	return a.repo.Set(SettingKeyViewerPassword, hash)
+}
+
+// RemoveViewer deletes the read-only viewer account
+func (a *AuthService) RemoveViewer() error {
+	defer a.settings.InvalidateCache()
+	if err := a.repo.Delete(SettingKeyViewerUsername); err != nil {
+		return err
+	}
+	return a.repo.Delete(SettingKeyViewerPassword)
+}
+
+// GetPasswordPolicy returns the configured password policy, or the default
+// policy if none is configured
+func (a *AuthService) GetPasswordPolicy() models.PasswordPolicy {
+	data, ok := a.settings.GetCached(SettingKeyPasswordPolicy)
+	if !ok {
+		return models.DefaultPasswordPolicy
+	}
+	var policy models.PasswordPolicy
+	if err := json.Unmarshal([]byte(data), &policy); err != nil || policy.Validate() != nil {
+		slog.Warn("invalid password policy setting, using defaults", "error", err)
+		return models.DefaultPasswordPolicy
+	}
+	return policy
+}
+
+// SavePasswordPolicy validates and saves the password policy. It applies to
+// passwords set afterwards; existing passwords keep working.
+func (a *AuthService) SavePasswordPolicy(policy models.PasswordPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	defer a.settings.InvalidateCache()
+	return a.repo.Set(SettingKeyPasswordPolicy, string(data))
+}
+
+// EvaluatePassword checks a password against the password policy and
+// estimates its strength. userInputs such as the username count against it.
+func (a *AuthService) EvaluatePassword(password string, userInputs ...string) PasswordCheck {
+	return CheckPassword(password, a.GetPasswordPolicy(), append(userInputs, "subvault")...)
+}
+
+// GetSessionLifetimes returns the configured session lifetimes, or the
+// defaults if none are configured
+func (a *AuthService) GetSessionLifetimes() models.SessionLifetimes {
+	data, ok := a.settings.GetCached(SettingKeySessionLifetimes)
+	if !ok {
+		return models.DefaultSessionLifetimes
+	}
+	var lifetimes models.SessionLifetimes
+	if err := json.Unmarshal([]byte(data), &lifetimes); err != nil || lifetimes.Validate() != nil {
+		slog.Warn("invalid session lifetimes setting, using defaults", "error", err)
+		return models.DefaultSessionLifetimes
+	}
+	return lifetimes
+}
+
+// SaveSessionLifetimes validates and saves the session lifetimes. They apply
+// to existing sessions from their next request.
+func (a *AuthService) SaveSessionLifetimes(lifetimes models.SessionLifetimes) error {
+	if err := lifetimes.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(lifetimes)
+	if err != nil {
+		return err
+	}
+	defer a.settings.InvalidateCache()
+	return a.repo.Set(SettingKeySessionLifetimes, string(data))
+}
+
 // GetOrGenerateSessionSecret returns the session secret, generating one if it doesn't exist
 func (a *AuthService) GetOrGenerateSessionSecret() (string, error) {
 	secret, ok := a.settings.GetCached(SettingKeyAuthSessionSecret)
@@ -128,6 +268,10 @@ func (a *AuthService) GetOrGenerateCSRFSecret() ([]byte, error) {
 
 // SetupAuth sets up authentication with username and password
 func (a *AuthService) SetupAuth(username, password string) error {
+	if err := a.EvaluatePassword(password, username).Err(a.GetPasswordPolicy()); err != nil {
+		return err
+	}
+
 	// Set username
 	if err := a.SetAuthUsername(username); err != nil {
 		return err
@@ -198,6 +342,28 @@ func (a *AuthService) ValidateResetToken(token string) error {
 	return nil
 }
 
+// PruneExpiredResetToken removes a password reset token whose expiry has passed
+// (or is missing). It reports whether a token was removed.
+func (a *AuthService) PruneExpiredResetToken() (bool, error) {
+	if _, ok := a.settings.GetCached(SettingKeyAuthResetToken); !ok {
+		return false, nil
+	}
+	if expiryStr, ok := a.settings.GetCached(SettingKeyAuthResetExpiry); ok {
+		if expiry, err := time.Parse(time.RFC3339, expiryStr); err == nil && time.Now().Before(expiry) {
+			return false, nil
+		}
+	}
+
+	if err := a.repo.Delete(SettingKeyAuthResetToken); err != nil {
+		return false, err
+	}
+	if err := a.repo.Delete(SettingKeyAuthResetExpiry); err != nil {
+		return false, err
+	}
+	a.settings.InvalidateCache()
+	return true, nil
+}
+
 // ClearResetToken removes the reset token after use
 func (a *AuthService) ClearResetToken() error {
 	a.repo.Delete(SettingKeyAuthResetToken)
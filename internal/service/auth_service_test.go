@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService_AuthenticateRoles(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	settingsRepo := repository.NewSettingsRepository(db)
+	authService := NewAuthService(NewSettingsService(settingsRepo), settingsRepo)
+	require.NoError(t, authService.SetupAuth("admin", "admin-password"))
+
+	role, err := authService.Authenticate("admin", "admin-password")
+	require.NoError(t, err)
+	assert.Equal(t, RoleAdmin, role)
+
+	// No viewer yet
+	_, err = authService.Authenticate("partner", "viewer-password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	assert.ErrorIs(t, authService.SetViewer("admin", "viewer-password"), ErrViewerUsernameTaken)
+	require.NoError(t, authService.SetViewer("partner", "viewer-password"))
+
+	role, err = authService.Authenticate("partner", "viewer-password")
+	require.NoError(t, err)
+	assert.Equal(t, RoleViewer, role)
+
+	// The viewer password does not unlock the admin and vice versa
+	_, err = authService.Authenticate("admin", "viewer-password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = authService.Authenticate("partner", "admin-password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	require.NoError(t, authService.RemoveViewer())
+	_, ok := authService.GetViewerUsername()
+	assert.False(t, ok)
+	_, err = authService.Authenticate("partner", "viewer-password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
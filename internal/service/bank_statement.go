@@ -0,0 +1,325 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bank statement formats accepted by the reconciliation
+const (
+	StatementCSV = "csv"
+	StatementOFX = "ofx"
+)
+
+// ErrUnknownStatementFormat is returned for a bank statement that is neither CSV nor OFX
+var ErrUnknownStatementFormat = errors.New("unknown bank statement format")
+
+// ErrInvalidStatement is returned for a bank statement that cannot be read
+var ErrInvalidStatement = errors.New("invalid bank statement")
+
+// ErrNoTransactions is returned when a bank statement contains no readable transactions
+var ErrNoTransactions = errors.New("no transactions found in bank statement")
+
+// BankTransaction is a booking from a bank statement. Amount is negative for
+// money leaving the account.
+type BankTransaction struct {
+	Date        time.Time `json:"date"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency,omitempty"`
+	Description string    `json:"description"`
+}
+
+// Column headers recognised in CSV statements, lowercase and in order of
+// preference. Headers match when they contain one of the names.
+var (
+	csvDateColumns        = []string{"booking date", "buchungstag", "buchungsdatum", "transaction date", "date", "datum", "valuta", "wertstellung"}
+	csvAmountColumns      = []string{"amount", "betrag", "umsatz", "value"}
+	csvDebitColumns       = []string{"debit", "soll", "withdrawal"}
+	csvCreditColumns      = []string{"credit", "haben", "deposit"}
+	csvCurrencyColumns    = []string{"currency", "währung", "waehrung"}
+	csvDescriptionColumns = []string{"payee", "merchant", "counterparty", "beguenstigter", "begünstigter", "empfänger", "empfaenger", "auftraggeber", "name", "description", "verwendungszweck", "memo", "details", "text", "reference"}
+)
+
+// csvHeaderScanRows limits how many preamble rows are skipped looking for the header
+const csvHeaderScanRows = 20
+
+var statementDateLayouts = []string{"2006-01-02", "02.01.2006", "02.01.06", "01/02/2006", "2006/01/02", "02-01-2006", "20060102", time.RFC3339}
+
+// DetectStatementFormat returns StatementOFX for OFX/QFX files and StatementCSV
+// for anything that looks like delimited text, or "" if unknown
+func DetectStatementFormat(data []byte) string {
+	head := bytes.ToUpper(data[:min(len(data), 1024)])
+	if bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>")) {
+		return StatementOFX
+	}
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.ContainsAny(firstLine, ",;\t") {
+		return StatementCSV
+	}
+	return ""
+}
+
+// ParseBankStatement reads the transactions of a CSV or OFX bank statement. An
+// empty format is detected. Rows without a date or amount are skipped.
+func ParseBankStatement(data []byte, format string) ([]BankTransaction, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if format == "" {
+		format = DetectStatementFormat(data)
+	}
+
+	var transactions []BankTransaction
+	var err error
+	switch format {
+	case StatementCSV:
+		transactions, err = parseStatementCSV(data)
+	case StatementOFX:
+		transactions = parseStatementOFX(data)
+	default:
+		return nil, ErrUnknownStatementFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, ErrNoTransactions
+	}
+	return transactions, nil
+}
+
+// statementColumns are the indexes of the recognised CSV columns, -1 if missing
+type statementColumns struct {
+	date, amount, debit, credit, currency int
+	description                           []int
+}
+
+func parseStatementCSV(data []byte) ([]BankTransaction, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = csvDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+
+	// Banks often put account details above the header
+	for i := 0; i < len(records) && i < csvHeaderScanRows; i++ {
+		columns, ok := findStatementColumns(records[i])
+		if !ok {
+			continue
+		}
+		var transactions []BankTransaction
+		for _, record := range records[i+1:] {
+			if tx, ok := columns.transaction(record); ok {
+				transactions = append(transactions, tx)
+			}
+		}
+		return transactions, nil
+	}
+	return nil, ErrNoTransactions
+}
+
+// csvDelimiter picks the most frequent of comma, semicolon and tab in the first lines
+func csvDelimiter(data []byte) rune {
+	head := data[:min(len(data), 4096)]
+	best, bestCount := ',', 0
+	for _, delimiter := range []rune{',', ';', '\t'} {
+		if count := bytes.Count(head, []byte(string(delimiter))); count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+	return best
+}
+
+func findStatementColumns(header []string) (statementColumns, bool) {
+	names := make([]string, len(header))
+	for i, name := range header {
+		names[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	find := func(candidates []string) int {
+		for _, candidate := range candidates {
+			for i, name := range names {
+				if strings.Contains(name, candidate) {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+
+	columns := statementColumns{
+		date:     find(csvDateColumns),
+		amount:   find(csvAmountColumns),
+		debit:    find(csvDebitColumns),
+		credit:   find(csvCreditColumns),
+		currency: find(csvCurrencyColumns),
+	}
+	for i, name := range names {
+		if i == columns.date || i == columns.amount || i == columns.currency {
+			continue
+		}
+		for _, candidate := range csvDescriptionColumns {
+			if strings.Contains(name, candidate) {
+				columns.description = append(columns.description, i)
+				break
+			}
+		}
+	}
+	hasAmount := columns.amount >= 0 || columns.debit >= 0 || columns.credit >= 0
+	return columns, columns.date >= 0 && hasAmount
+}
+
+func (c statementColumns) transaction(record []string) (BankTransaction, bool) {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	date, ok := parseStatementDate(field(c.date))
+	if !ok {
+		return BankTransaction{}, false
+	}
+
+	var amount float64
+	if c.amount >= 0 {
+		if amount, ok = parseStatementAmount(field(c.amount)); !ok {
+			return BankTransaction{}, false
+		}
+	} else {
+		debit, hasDebit := parseStatementAmount(field(c.debit))
+		credit, hasCredit := parseStatementAmount(field(c.credit))
+		if !hasDebit && !hasCredit {
+			return BankTransaction{}, false
+		}
+		amount = credit - math.Abs(debit)
+	}
+
+	var description []string
+	for _, i := range c.description {
+		if value := field(i); value != "" {
+			description = append(description, value)
+		}
+	}
+	return BankTransaction{
+		Date:        date,
+		Amount:      amount,
+		Currency:    strings.ToUpper(field(c.currency)),
+		Description: strings.Join(description, " "),
+	}, true
+}
+
+func parseStatementDate(value string) (time.Time, bool) {
+	for _, layout := range statementDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseStatementAmount parses amounts like "-12.99", "1.234,56", "12,99 €" or "(12.99)".
+// When both separators occur the last one is the decimal separator; a lone comma
+// followed by one or two digits is one too.
+func parseStatementAmount(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+	}
+	if strings.HasSuffix(value, "-") {
+		negative = true
+	}
+	value = strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == ',' || r == '-' {
+			return r
+		}
+		return -1
+	}, value)
+	value = strings.TrimSuffix(value, "-")
+	if value == "" {
+		return 0, false
+	}
+
+	lastDot, lastComma := strings.LastIndex(value, "."), strings.LastIndex(value, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastComma > lastDot {
+			value = strings.ReplaceAll(value, ".", "")
+			value = strings.Replace(value, ",", ".", 1)
+		} else {
+			value = strings.ReplaceAll(value, ",", "")
+		}
+	case lastComma >= 0:
+		if decimals := len(value) - lastComma - 1; decimals <= 2 && strings.Count(value, ",") == 1 {
+			value = strings.Replace(value, ",", ".", 1)
+		} else {
+			value = strings.ReplaceAll(value, ",", "")
+		}
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative && amount > 0 {
+		amount = -amount
+	}
+	return amount, true
+}
+
+var ofxTagPattern = regexp.MustCompile(`(?i)<([A-Z.]+)>([^<\r\n]*)`)
+
+// parseStatementOFX reads the STMTTRN entries of an OFX (SGML or XML) statement
+func parseStatementOFX(data []byte) []BankTransaction {
+	text := string(data)
+	currency := ""
+	if match := regexp.MustCompile(`(?i)<CURDEF>([A-Z]{3})`).FindStringSubmatch(text); match != nil {
+		currency = strings.ToUpper(match[1])
+	}
+
+	var transactions []BankTransaction
+	blocks := regexp.MustCompile(`(?i)<STMTTRN>`).Split(text, -1)
+	for _, block := range blocks[1:] {
+		if end := strings.Index(strings.ToUpper(block), "</STMTTRN>"); end >= 0 {
+			block = block[:end]
+		}
+		fields := make(map[string]string)
+		for _, match := range ofxTagPattern.FindAllStringSubmatch(block, -1) {
+			fields[strings.ToUpper(match[1])] = strings.TrimSpace(match[2])
+		}
+
+		posted := fields["DTPOSTED"]
+		if len(posted) < 8 {
+			continue
+		}
+		date, ok := parseStatementDate(posted[:8])
+		if !ok {
+			continue
+		}
+		amount, ok := parseStatementAmount(fields["TRNAMT"])
+		if !ok {
+			continue
+		}
+
+		description := fields["NAME"]
+		if memo := fields["MEMO"]; memo != "" && memo != description {
+			description = strings.TrimSpace(description + " " + memo)
+		}
+		transactions = append(transactions, BankTransaction{
+			Date:        date,
+			Amount:      amount,
+			Currency:    currency,
+			Description: description,
+		})
+	}
+	return transactions
+}
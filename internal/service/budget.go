@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// maxRolloverMonths limits how many past months are replayed for the budget
+// rollover
+const maxRolloverMonths = 120
+
+// SetBudgetRollover enables or disables carrying unused monthly budget into
+// the next month. Enabling it starts the rollover with the current month.
+func (s *SettingsService) SetBudgetRollover(enabled bool) error {
+	if enabled && !s.GetBoolSettingWithDefault("budget_rollover", false) {
+		if err := s.repo.Set(SettingKeyBudgetRolloverSince, time.Now().Format("2006-01")); err != nil {
+			return err
+		}
+	}
+	return s.SetBoolSetting("budget_rollover", enabled)
+}
+
+// PurposeBudget is the monthly and annual budget of one subscription purpose.
+// Zero means no budget.
+type PurposeBudget struct {
+	Monthly float64 `json:"monthly" yaml:"monthly"`
+	Annual  float64 `json:"annual" yaml:"annual"`
+}
+
+// PurposeBudgets returns the budgets of every purpose
+func (s *SettingsService) PurposeBudgets() map[string]PurposeBudget {
+	budgets := make(map[string]PurposeBudget, len(models.Purposes))
+	for _, purpose := range models.Purposes {
+		budgets[purpose] = PurposeBudget{
+			Monthly: s.GetFloatSettingWithDefault(BudgetSettingKey("monthly_budget", purpose), 0),
+			Annual:  s.GetFloatSettingWithDefault(BudgetSettingKey("annual_budget", purpose), 0),
+		}
+	}
+	return budgets
+}
+
+// SetPurposeBudget stores the monthly and annual budget of a purpose
+func (s *SettingsService) SetPurposeBudget(purpose string, budget PurposeBudget) error {
+	if !models.IsValidPurpose(purpose) {
+		return fmt.Errorf("invalid purpose %q", purpose)
+	}
+	if budget.Monthly < 0 || budget.Annual < 0 {
+		return fmt.Errorf("budgets must not be negative")
+	}
+	if err := s.SetFloatSetting(BudgetSettingKey("monthly_budget", purpose), budget.Monthly); err != nil {
+		return err
+	}
+	return s.SetFloatSetting(BudgetSettingKey("annual_budget", purpose), budget.Annual)
+}
+
+// applyBudgets fills the budget fields of stats from the monthly and annual
+// budget settings of the purpose the stats cover
+func (s *SubscriptionService) applyBudgets(stats *models.Stats, subs []models.Subscription, now time.Time, displayCurrency string) {
+	monthly := s.settings.GetFloatSettingWithDefault(BudgetSettingKey("monthly_budget", stats.Purpose), 0)
+	stats.MonthlyBudget = monthly
+	if monthly > 0 && s.settings.GetBoolSettingWithDefault("budget_rollover", false) {
+		if since, ok := s.settings.GetCached(SettingKeyBudgetRolloverSince); ok {
+			if start, err := time.ParseInLocation("2006-01", since, now.Location()); err == nil {
+				stats.BudgetRollover = roundCents(s.budgetCarry(subs, monthly, start, now, displayCurrency))
+			}
+		}
+	}
+	stats.EffectiveMonthlyBudget = monthly + stats.BudgetRollover
+	if stats.EffectiveMonthlyBudget > 0 {
+		stats.BudgetUtilization = stats.TotalMonthlySpend / stats.EffectiveMonthlyBudget * 100
+	}
+
+	stats.AnnualBudget = s.settings.GetFloatSettingWithDefault(BudgetSettingKey("annual_budget", stats.Purpose), 0)
+	if stats.AnnualBudget > 0 {
+		stats.AnnualUtilization = stats.TotalAnnualSpend / stats.AnnualBudget * 100
+	}
+}
+
+// BudgetSettingKey returns the setting key of a budget ("monthly_budget" or
+// "annual_budget") for a purpose, e.g. monthly_budget_business. An empty
+// purpose means the overall budget.
+func BudgetSettingKey(base, purpose string) string {
+	if purpose == "" {
+		return base
+	}
+	return base + "_" + purpose
+}
+
+// budgetCarry replays the completed months since start and returns the unused
+// budget carried into the current month. A month's spend is the monthly cost of
+// the subscriptions that counted at its end; overspending uses up carried
+// budget but never goes below zero.
+func (s *SubscriptionService) budgetCarry(subs []models.Subscription, budget float64, start, now time.Time, displayCurrency string) float64 {
+	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, now.Location())
+	if earliest := currentMonth.AddDate(0, -maxRolloverMonths, 0); month.Before(earliest) {
+		month = earliest
+	}
+
+	carry := 0.0
+	for ; month.Before(currentMonth); month = month.AddDate(0, 1, 0) {
+		end := month.AddDate(0, 1, 0).Add(-time.Second)
+		spend := 0.0
+		for i := range subs {
+			if countedAt(&subs[i], end, now) {
+				spend += s.convertAmount(subs[i].MonthlyCost(), subs[i].OriginalCurrency, displayCurrency)
+			}
+		}
+		carry = max(0, carry+budget-spend)
+	}
+	return carry
+}
+
+// budgetAlertKeys returns the translation keys of the alert text and the
+// budget and spend labels for a budget period
+func budgetAlertKeys(period string) (alert, budgetLabel, spendLabel string) {
+	if period == models.BudgetPeriodAnnual {
+		return "budget_exceeded_alert_annual", "dashboard_annual_budget", "dashboard_annual_spend"
+	}
+	return "budget_exceeded_alert", "dashboard_budget", "analytics_monthly_cost"
+}
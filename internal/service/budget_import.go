@@ -0,0 +1,578 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// ynabBaseURL is the YNAB API
+const ynabBaseURL = "https://api.ynab.com/v1"
+
+// ynabDefaultBudget is the YNAB alias for the budget opened last
+const ynabDefaultBudget = "last-used"
+
+// fireflyMaxPages caps the pages of recurring transactions read from Firefly III
+const fireflyMaxPages = 20
+
+var (
+	// ErrBudgetNotConfigured is returned when no budgeting tool is connected
+	ErrBudgetNotConfigured = errors.New("budgeting tool not configured")
+	// ErrInvalidBudgetConnection is returned for an unknown provider or a missing Firefly III URL
+	ErrInvalidBudgetConnection = errors.New("invalid budgeting tool connection")
+	// ErrBudgetAPI is returned when YNAB or Firefly III rejects a request
+	ErrBudgetAPI = errors.New("budgeting tool error")
+
+	// errBudgetNotFound marks an ErrBudgetAPI for a resource that does not exist
+	errBudgetNotFound = errors.New("not found")
+)
+
+// RecurringExpense is a scheduled outflow read from a budgeting tool
+type RecurringExpense struct {
+	Name     string     `json:"name"`
+	Amount   float64    `json:"amount"`
+	Currency string     `json:"currency"`
+	Schedule string     `json:"schedule"`
+	Category string     `json:"category,omitempty"`
+	NextDate *time.Time `json:"next_date,omitempty"`
+	// Frequency is the schedule as named by the budgeting tool, kept when it
+	// has no SubVault equivalent
+	Frequency string `json:"frequency,omitempty"`
+}
+
+// BudgetMatch is a recurring expense that matches a subscription by name
+type BudgetMatch struct {
+	SubscriptionID uint    `json:"subscription_id"`
+	Name           string  `json:"name"`
+	Cost           float64 `json:"cost"`
+	Amount         float64 `json:"amount"`
+	Currency       string  `json:"currency"`
+	// Syncable is set when currency and schedule agree, so the amount can
+	// replace the cost
+	Syncable bool `json:"syncable"`
+}
+
+// Differs reports whether the budgeted amount differs from the cost
+func (m BudgetMatch) Differs() bool {
+	return math.Abs(m.Amount-m.Cost) >= 0.005
+}
+
+// BudgetPreview is a staged import of the recurring expenses that match no
+// subscription, plus the expenses that are already tracked
+type BudgetPreview struct {
+	*ImportPreview
+	Tracked []BudgetMatch `json:"tracked"`
+	// Unsupported lists expenses whose schedule SubVault cannot represent
+	Unsupported []RecurringExpense `json:"unsupported"`
+}
+
+// BudgetSyncResult reports what an amount sync read and changed
+type BudgetSyncResult struct {
+	Expenses int `json:"expenses"`
+	Matched  int `json:"matched"`
+	Updated  int `json:"updated"`
+}
+
+// BudgetImportService reads recurring expenses from YNAB or Firefly III,
+// proposes the ones without a subscription through an import preview and
+// optionally keeps the cost of matching subscriptions in sync
+type BudgetImportService struct {
+	settings      *SettingsService
+	imports       *ImportService
+	subscriptions SubscriptionServiceInterface
+	httpClient    *http.Client
+	ynabURL       string
+
+	mu sync.Mutex
+}
+
+func NewBudgetImportService(settings *SettingsService, imports *ImportService, subscriptions SubscriptionServiceInterface) *BudgetImportService {
+	return &BudgetImportService{
+		settings:      settings,
+		imports:       imports,
+		subscriptions: subscriptions,
+		httpClient:    NewHTTPClient(30 * time.Second),
+		ynabURL:       ynabBaseURL,
+	}
+}
+
+// Connection returns the stored budgeting tool connection
+func (s *BudgetImportService) Connection() models.BudgetConnection {
+	var connection models.BudgetConnection
+	data, ok := s.settings.GetCached(SettingKeyBudgetConnection)
+	if !ok || data == "" {
+		return connection
+	}
+	if err := json.Unmarshal([]byte(data), &connection); err != nil {
+		slog.Warn("failed to parse budget connection", "error", err)
+	}
+	return connection
+}
+
+// Save checks the connection by reading its recurring expenses and stores it.
+// An empty token keeps the stored one of the same provider.
+func (s *BudgetImportService) Save(ctx context.Context, connection models.BudgetConnection) error {
+	connection.Provider = strings.TrimSpace(connection.Provider)
+	connection.Token = strings.TrimSpace(connection.Token)
+	connection.BudgetID = strings.TrimSpace(connection.BudgetID)
+	switch connection.Provider {
+	case models.BudgetProviderYNAB:
+		connection.URL = ""
+	case models.BudgetProviderFirefly:
+		connection.BudgetID = ""
+		baseURL, err := normalizeFireflyURL(connection.URL)
+		if err != nil {
+			return err
+		}
+		connection.URL = baseURL
+	default:
+		return fmt.Errorf("%w: unknown provider %q", ErrInvalidBudgetConnection, connection.Provider)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.Connection()
+	if connection.Token == "" && stored.Provider == connection.Provider {
+		connection.Token = stored.Token
+	}
+	if !connection.Configured() {
+		return ErrBudgetNotConfigured
+	}
+	if _, err := s.fetch(ctx, connection); err != nil {
+		return err
+	}
+	connection.LastSync = stored.LastSync
+	if stored.Provider == connection.Provider && stored.URL == connection.URL {
+		connection.ExportAccountID = stored.ExportAccountID
+		connection.LastExport = stored.LastExport
+	}
+	return s.save(connection)
+}
+
+// Expenses reads the recurring expenses of the connected budgeting tool
+func (s *BudgetImportService) Expenses(ctx context.Context) ([]RecurringExpense, error) {
+	connection := s.Connection()
+	if !connection.Configured() {
+		return nil, ErrBudgetNotConfigured
+	}
+	return s.fetch(ctx, connection)
+}
+
+// Preview stages the recurring expenses that match no subscription as an
+// import, confirmed like a file import with ImportService.Confirm
+func (s *BudgetImportService) Preview(ctx context.Context) (*BudgetPreview, error) {
+	connection := s.Connection()
+	expenses, err := s.Expenses(ctx)
+	if err != nil {
+		return nil, s.recordError(err)
+	}
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &BudgetPreview{Tracked: []BudgetMatch{}, Unsupported: []RecurringExpense{}}
+	var items []stagedSubscription
+	for _, expense := range expenses {
+		if match, ok := matchBudgetExpense(subscriptions, expense); ok {
+			preview.Tracked = append(preview.Tracked, match)
+			continue
+		}
+		if expense.Schedule == "" {
+			preview.Unsupported = append(preview.Unsupported, expense)
+			continue
+		}
+		sub := models.Subscription{
+			Name:                   expense.Name,
+			Cost:                   expense.Amount,
+			OriginalCurrency:       expense.Currency,
+			Schedule:               expense.Schedule,
+			Status:                 models.StatusActive,
+			RenewalDate:            expense.NextDate,
+			DateCalculationVersion: 2,
+		}
+		items = append(items, stagedSubscription{sub: sub, categoryName: expense.Category})
+	}
+
+	preview.ImportPreview, err = s.imports.stage(ctx, items, connection.Provider)
+	if err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// SyncAmounts sets the cost of every active subscription that matches a
+// recurring expense with the same currency and schedule to the budgeted amount
+func (s *BudgetImportService) SyncAmounts(ctx context.Context) (*BudgetSyncResult, error) {
+	expenses, err := s.Expenses(ctx)
+	if err != nil {
+		return nil, s.recordError(err)
+	}
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BudgetSyncResult{Expenses: len(expenses)}
+	for _, expense := range expenses {
+		match, ok := matchBudgetExpense(subscriptions, expense)
+		if !ok || !match.Syncable {
+			continue
+		}
+		result.Matched++
+		if !match.Differs() {
+			continue
+		}
+		sub, err := s.subscriptions.GetByID(ctx, match.SubscriptionID)
+		if err != nil {
+			return nil, err
+		}
+		sub.Cost = expense.Amount
+		if _, err := s.subscriptions.Update(ctx, sub.ID, sub); err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", sub.Name, err)
+		}
+		slog.Info("synced subscription cost from budget", "subscription", sub.Name, "old", match.Cost, "new", expense.Amount)
+		result.Updated++
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	connection := s.Connection()
+	now := time.Now()
+	connection.LastSync = &now
+	connection.LastError = ""
+	if err := s.save(connection); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Disconnect removes the connection and its token
+func (s *BudgetImportService) Disconnect() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.settings.InvalidateCache()
+	return s.settings.Repo().Delete(SettingKeyBudgetConnection)
+}
+
+// matchBudgetExpense finds the subscription of an expense by name, ignoring
+// case. Cancelled subscriptions are left alone.
+func matchBudgetExpense(subscriptions []models.Subscription, expense RecurringExpense) (BudgetMatch, bool) {
+	for _, sub := range subscriptions {
+		if sub.Status == models.StatusCancelled || !strings.EqualFold(strings.TrimSpace(sub.Name), expense.Name) {
+			continue
+		}
+		return BudgetMatch{
+			SubscriptionID: sub.ID,
+			Name:           sub.Name,
+			Cost:           sub.Cost,
+			Amount:         expense.Amount,
+			Currency:       expense.Currency,
+			Syncable:       strings.EqualFold(sub.OriginalCurrency, expense.Currency) && sub.Schedule == expense.Schedule,
+		}, true
+	}
+	return BudgetMatch{}, false
+}
+
+// fetch reads the recurring expenses of a connection
+func (s *BudgetImportService) fetch(ctx context.Context, connection models.BudgetConnection) ([]RecurringExpense, error) {
+	switch connection.Provider {
+	case models.BudgetProviderYNAB:
+		return s.ynabExpenses(ctx, connection)
+	case models.BudgetProviderFirefly:
+		return s.fireflyExpenses(ctx, connection)
+	default:
+		return nil, ErrBudgetNotConfigured
+	}
+}
+
+// ynabScheduledTransaction is a scheduled transaction as returned by YNAB.
+// Amounts are in milliunits, negative for outflows.
+type ynabScheduledTransaction struct {
+	DateNext          string  `json:"date_next"`
+	Frequency         string  `json:"frequency"`
+	Amount            int64   `json:"amount"`
+	Memo              string  `json:"memo"`
+	PayeeName         string  `json:"payee_name"`
+	CategoryName      string  `json:"category_name"`
+	TransferAccountID *string `json:"transfer_account_id"`
+	Deleted           bool    `json:"deleted"`
+}
+
+// ynabSchedules maps YNAB frequencies to schedules. Others, such as
+// everyOtherWeek or twiceAYear, have no equivalent.
+var ynabSchedules = map[string]string{
+	"daily":        "Daily",
+	"weekly":       "Weekly",
+	"monthly":      "Monthly",
+	"every3Months": "Quarterly",
+	"yearly":       "Annual",
+}
+
+func (s *BudgetImportService) ynabExpenses(ctx context.Context, connection models.BudgetConnection) ([]RecurringExpense, error) {
+	budget := connection.BudgetID
+	if budget == "" {
+		budget = ynabDefaultBudget
+	}
+	base := s.ynabURL + "/budgets/" + url.PathEscape(budget)
+
+	var settings struct {
+		Data struct {
+			Settings struct {
+				CurrencyFormat struct {
+					ISOCode string `json:"iso_code"`
+				} `json:"currency_format"`
+			} `json:"settings"`
+		} `json:"data"`
+	}
+	if err := s.get(ctx, base+"/settings", connection.Token, &settings); err != nil {
+		return nil, err
+	}
+	currency := strings.ToUpper(settings.Data.Settings.CurrencyFormat.ISOCode)
+
+	var scheduled struct {
+		Data struct {
+			ScheduledTransactions []ynabScheduledTransaction `json:"scheduled_transactions"`
+		} `json:"data"`
+	}
+	if err := s.get(ctx, base+"/scheduled_transactions", connection.Token, &scheduled); err != nil {
+		return nil, err
+	}
+
+	var expenses []RecurringExpense
+	for _, transaction := range scheduled.Data.ScheduledTransactions {
+		if transaction.Deleted || transaction.TransferAccountID != nil || transaction.Amount >= 0 || transaction.Frequency == "never" {
+			continue
+		}
+		name := strings.TrimSpace(transaction.PayeeName)
+		if name == "" {
+			name = strings.TrimSpace(transaction.Memo)
+		}
+		if name == "" {
+			continue
+		}
+		expense := RecurringExpense{
+			Name:      name,
+			Amount:    float64(-transaction.Amount) / 1000,
+			Currency:  currency,
+			Schedule:  ynabSchedules[transaction.Frequency],
+			Category:  transaction.CategoryName,
+			Frequency: transaction.Frequency,
+		}
+		if next, ok := parseStatementDate(transaction.DateNext); ok {
+			expense.NextDate = &next
+		}
+		expenses = append(expenses, expense)
+	}
+	return expenses, nil
+}
+
+// fireflyRecurrence is a recurring transaction as returned by Firefly III
+type fireflyRecurrence struct {
+	Attributes struct {
+		Type        string `json:"type"`
+		Title       string `json:"title"`
+		Active      bool   `json:"active"`
+		Repetitions []struct {
+			Type        string   `json:"type"`
+			Skip        int      `json:"skip"`
+			Occurrences []string `json:"occurrences"`
+		} `json:"repetitions"`
+		Transactions []struct {
+			Amount       string `json:"amount"`
+			CurrencyCode string `json:"currency_code"`
+			CategoryName string `json:"category_name"`
+		} `json:"transactions"`
+	} `json:"attributes"`
+}
+
+// fireflySchedule maps a Firefly III repetition to a schedule. Skip is the
+// number of periods left out between two transactions.
+func fireflySchedule(repetition string, skip int) string {
+	switch {
+	case repetition == "daily" && skip == 0:
+		return "Daily"
+	case repetition == "weekly" && skip == 0:
+		return "Weekly"
+	case (repetition == "monthly" || repetition == "ndom") && skip == 0:
+		return "Monthly"
+	case repetition == "monthly" && skip == 2:
+		return "Quarterly"
+	case repetition == "monthly" && skip == 11, repetition == "yearly" && skip == 0:
+		return "Annual"
+	}
+	return ""
+}
+
+func (s *BudgetImportService) fireflyExpenses(ctx context.Context, connection models.BudgetConnection) ([]RecurringExpense, error) {
+	var expenses []RecurringExpense
+	today := time.Now().Format(time.DateOnly)
+	for page := 1; page <= fireflyMaxPages; page++ {
+		var response struct {
+			Data []fireflyRecurrence `json:"data"`
+			Meta struct {
+				Pagination struct {
+					TotalPages int `json:"total_pages"`
+				} `json:"pagination"`
+			} `json:"meta"`
+		}
+		if err := s.get(ctx, connection.URL+"/api/v1/recurrences?page="+strconv.Itoa(page), connection.Token, &response); err != nil {
+			return nil, err
+		}
+
+		for _, recurrence := range response.Data {
+			attributes := recurrence.Attributes
+			if !attributes.Active || attributes.Type != "withdrawal" || len(attributes.Transactions) == 0 || len(attributes.Repetitions) == 0 {
+				continue
+			}
+			expense := RecurringExpense{
+				Name:     strings.TrimSpace(attributes.Title),
+				Currency: strings.ToUpper(attributes.Transactions[0].CurrencyCode),
+				Category: attributes.Transactions[0].CategoryName,
+			}
+			// Split transactions are charged together
+			for _, transaction := range attributes.Transactions {
+				amount, err := strconv.ParseFloat(transaction.Amount, 64)
+				if err != nil {
+					return nil, fmt.Errorf("%w: invalid amount %q of %s", ErrBudgetAPI, transaction.Amount, expense.Name)
+				}
+				expense.Amount += math.Abs(amount)
+			}
+			repetition := attributes.Repetitions[0]
+			expense.Schedule = fireflySchedule(repetition.Type, repetition.Skip)
+			expense.Frequency = repetition.Type
+			if repetition.Skip > 0 {
+				expense.Frequency += fmt.Sprintf(" (skip %d)", repetition.Skip)
+			}
+			for _, occurrence := range repetition.Occurrences {
+				if next, ok := parseStatementDate(occurrence); ok && next.Format(time.DateOnly) >= today {
+					expense.NextDate = &next
+					break
+				}
+			}
+			if expense.Name != "" {
+				expenses = append(expenses, expense)
+			}
+		}
+
+		if page >= response.Meta.Pagination.TotalPages {
+			break
+		}
+	}
+	return expenses, nil
+}
+
+// get calls a budgeting tool API with the token and decodes the JSON response into out
+func (s *BudgetImportService) get(ctx context.Context, endpoint, token string, out any) error {
+	return s.request(ctx, http.MethodGet, endpoint, token, nil, out)
+}
+
+// request calls a budgeting tool API with the token, sending body as JSON,
+// and decodes the JSON response into out
+func (s *BudgetImportService) request(ctx context.Context, method, endpoint, token string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the budgeting tool: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		// YNAB reports {"error": {"detail"}}, Firefly III {"message"}
+		var apiErr struct {
+			Error struct {
+				Detail string `json:"detail"`
+			} `json:"error"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&apiErr)
+		err := fmt.Errorf("%w: %s (status %d)", ErrBudgetAPI, strings.TrimSpace(apiErr.Error.Detail+" "+apiErr.Message), resp.StatusCode)
+		if resp.StatusCode == http.StatusNotFound {
+			return errors.Join(err, errBudgetNotFound)
+		}
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w: failed to decode response: %v", ErrBudgetAPI, err)
+	}
+	return nil
+}
+
+// normalizeFireflyURL checks the address of a Firefly III instance and drops
+// a trailing slash or /api/v1
+func normalizeFireflyURL(raw string) (string, error) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+	raw = strings.TrimRight(strings.TrimSuffix(raw, "/api/v1"), "/")
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%w: enter the http:// or https:// address of Firefly III", ErrInvalidBudgetConnection)
+	}
+	return raw, nil
+}
+
+// update changes the stored connection
+func (s *BudgetImportService) update(change func(*models.BudgetConnection)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	connection := s.Connection()
+	change(&connection)
+	return s.save(connection)
+}
+
+// recordError stores the error of a failed read to show with the connection
+func (s *BudgetImportService) recordError(readErr error) error {
+	if errors.Is(readErr, ErrBudgetNotConfigured) {
+		return readErr
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	connection := s.Connection()
+	connection.LastError = readErr.Error()
+	if err := s.save(connection); err != nil {
+		slog.Warn("failed to store budget sync error", "error", err)
+	}
+	return readErr
+}
+
+// save stores the connection. Caller must hold s.mu.
+func (s *BudgetImportService) save(connection models.BudgetConnection) error {
+	data, err := json.Marshal(connection)
+	if err != nil {
+		return err
+	}
+	defer s.settings.InvalidateCache()
+	return s.settings.Repo().Set(SettingKeyBudgetConnection, string(data))
+}
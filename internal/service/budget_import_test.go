@@ -0,0 +1,151 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupBudgetImportService(t *testing.T) (*BudgetImportService, *SubscriptionService, *ImportService) {
+	db := setupRenewalReminderTestDB(t)
+	return newBudgetImportTestService(t, db)
+}
+
+func newBudgetImportTestService(t *testing.T, db *gorm.DB) (*BudgetImportService, *SubscriptionService, *ImportService) {
+	require.NoError(t, db.AutoMigrate(&models.ImportBatch{}, &models.UsageEvent{}, &models.SubscriptionShare{}, &models.ReminderRetry{}, &models.Payment{}, &models.CategoryRule{}))
+	services := newTestSubscriptionService(t, db)
+	ruleService := NewCategoryRuleService(repository.NewCategoryRuleRepository(db), services.categories)
+	importService := NewImportService(services.subscriptions, services.categories, ruleService, NewRenewalService(), repository.NewImportBatchRepository(db), t.TempDir())
+	return NewBudgetImportService(services.settings, importService, services.subscriptions), services.subscriptions, importService
+}
+
+// fakeBudgetAPI serves the YNAB and Firefly III endpoints used by the importer
+func fakeBudgetAPI(t *testing.T, ynabNetflix int64) *httptest.Server {
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, r *http.Request, body any) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{"id": "401", "detail": "Unauthorized"}})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(body))
+	}
+	next := time.Now().AddDate(0, 0, 10).Format(time.DateOnly)
+
+	mux.HandleFunc("GET /budgets/last-used/settings", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, r, map[string]any{"data": map[string]any{"settings": map[string]any{"currency_format": map[string]string{"iso_code": "EUR"}}}})
+	})
+	mux.HandleFunc("GET /budgets/last-used/scheduled_transactions", func(w http.ResponseWriter, r *http.Request) {
+		transfer := "account-2"
+		reply(w, r, map[string]any{"data": map[string]any{"scheduled_transactions": []map[string]any{
+			{"date_next": next, "frequency": "monthly", "amount": ynabNetflix, "payee_name": "Netflix", "category_name": "Streaming"},
+			{"date_next": next, "frequency": "yearly", "amount": -59990, "payee_name": "Adobe", "category_name": "Software"},
+			{"date_next": next, "frequency": "everyOtherWeek", "amount": -20000, "payee_name": "Cleaner"},
+			{"date_next": next, "frequency": "monthly", "amount": 2500000, "payee_name": "Salary"},
+			{"date_next": next, "frequency": "monthly", "amount": -500000, "payee_name": "Savings", "transfer_account_id": transfer},
+			{"date_next": next, "frequency": "monthly", "amount": -9990, "payee_name": "Old gym", "deleted": true},
+		}}})
+	})
+	mux.HandleFunc("GET /api/v1/recurrences", func(w http.ResponseWriter, r *http.Request) {
+		recurrence := func(title, kind, repetition string, skip int, amounts ...string) map[string]any {
+			var transactions []map[string]string
+			for _, amount := range amounts {
+				transactions = append(transactions, map[string]string{"amount": amount, "currency_code": "usd", "category_name": "Bills"})
+			}
+			return map[string]any{"attributes": map[string]any{
+				"type": kind, "title": title, "active": true,
+				"repetitions":  []map[string]any{{"type": repetition, "skip": skip, "occurrences": []string{"2020-01-01T00:00:00+00:00", next + "T00:00:00+00:00"}}},
+				"transactions": transactions,
+			}}
+		}
+		page := r.URL.Query().Get("page")
+		data := []map[string]any{recurrence("Phone", "withdrawal", "monthly", 0, "20.00", "5.50")}
+		if page == "2" {
+			data = []map[string]any{recurrence("Insurance", "withdrawal", "monthly", 2, "90"), recurrence("Refund", "deposit", "monthly", 0, "10")}
+		}
+		reply(w, r, map[string]any{"data": data, "meta": map[string]any{"pagination": map[string]int{"total_pages": 2, "current_page": 1}}})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBudgetImportService_YNAB(t *testing.T) {
+	budget, subscriptions, imports := setupBudgetImportService(t)
+	server := fakeBudgetAPI(t, -15990)
+	budget.ynabURL = server.URL
+
+	err := budget.Save(t.Context(), models.BudgetConnection{Provider: models.BudgetProviderYNAB, Token: "wrong"})
+	assert.ErrorIs(t, err, ErrBudgetAPI)
+	assert.False(t, budget.Connection().Configured())
+	require.NoError(t, budget.Save(t.Context(), models.BudgetConnection{Provider: models.BudgetProviderYNAB, Token: " token ", SyncAmounts: true}))
+	assert.Equal(t, "token", budget.Connection().Token)
+
+	renewal := time.Now().AddDate(0, 0, 5)
+	netflix, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "netflix", Cost: 12.99, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", RenewalDate: &renewal})
+	require.NoError(t, err)
+
+	preview, err := budget.Preview(t.Context())
+	require.NoError(t, err)
+	require.Len(t, preview.Tracked, 1)
+	assert.Equal(t, BudgetMatch{SubscriptionID: netflix.ID, Name: "netflix", Cost: 12.99, Amount: 15.99, Currency: "EUR", Syncable: true}, preview.Tracked[0])
+	require.Len(t, preview.Unsupported, 1)
+	assert.Equal(t, "Cleaner", preview.Unsupported[0].Name)
+	// Income, transfers and deleted transactions are left out
+	require.Len(t, preview.Items, 1)
+	assert.Equal(t, ImportPreviewItem{Name: "Adobe", Cost: 59.99, Currency: "EUR", Schedule: "Annual", Category: "Software", CategoryMapping: CategoryMappingNew, Action: ImportActionCreate}, preview.Items[0])
+	assert.Equal(t, models.BudgetProviderYNAB, preview.Format)
+
+	result, err := imports.Confirm(t.Context(), preview.Token, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+
+	synced, err := budget.SyncAmounts(t.Context())
+	require.NoError(t, err)
+	// Adobe was just imported with the budgeted amount
+	assert.Equal(t, &BudgetSyncResult{Expenses: 3, Matched: 2, Updated: 1}, synced)
+	updated, err := subscriptions.GetByID(t.Context(), netflix.ID)
+	require.NoError(t, err)
+	assert.InDelta(t, 15.99, updated.Cost, 0.001)
+	assert.NotNil(t, budget.Connection().LastSync)
+
+	require.NoError(t, budget.Disconnect())
+	_, err = budget.SyncAmounts(t.Context())
+	assert.ErrorIs(t, err, ErrBudgetNotConfigured)
+}
+
+func TestBudgetImportService_Firefly(t *testing.T) {
+	budget, _, _ := setupBudgetImportService(t)
+	server := fakeBudgetAPI(t, -15990)
+
+	err := budget.Save(t.Context(), models.BudgetConnection{Provider: models.BudgetProviderFirefly, Token: "token", URL: "ftp://firefly"})
+	assert.ErrorIs(t, err, ErrInvalidBudgetConnection)
+	err = budget.Save(t.Context(), models.BudgetConnection{Provider: "mint", Token: "token"})
+	assert.ErrorIs(t, err, ErrInvalidBudgetConnection)
+
+	require.NoError(t, budget.Save(t.Context(), models.BudgetConnection{Provider: models.BudgetProviderFirefly, Token: "token", URL: server.URL + "/api/v1/"}))
+	assert.Equal(t, server.URL, budget.Connection().URL)
+
+	expenses, err := budget.Expenses(t.Context())
+	require.NoError(t, err)
+	require.Len(t, expenses, 2)
+	// Split transactions add up, past occurrences are skipped
+	assert.Equal(t, "Phone", expenses[0].Name)
+	assert.InDelta(t, 25.5, expenses[0].Amount, 0.001)
+	assert.Equal(t, "USD", expenses[0].Currency)
+	assert.Equal(t, "Monthly", expenses[0].Schedule)
+	require.NotNil(t, expenses[0].NextDate)
+	assert.True(t, expenses[0].NextDate.After(time.Now()))
+	assert.Equal(t, "Quarterly", expenses[1].Schedule)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupBudgetService(t *testing.T) (*SubscriptionService, *SettingsService) {
+	db := setupRenewalReminderTestDB(t)
+	services := newTestSubscriptionService(t, db)
+	return services.subscriptions, services.settings
+}
+
+func TestSubscriptionService_BudgetCarry(t *testing.T) {
+	s, _ := setupBudgetService(t)
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+	subs := []models.Subscription{
+		{Name: "Netflix", Cost: 60, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", StartDate: date(2025, 1, 1)},
+		// Only counted in March
+		{Name: "Gym", Cost: 70, Schedule: "Monthly", Status: "Cancelled", OriginalCurrency: "EUR", StartDate: date(2026, 3, 5), CancellationDate: date(2026, 4, 1)},
+	}
+
+	// February: 40 unused, March: 30 over (10 left), April: 40 unused
+	assert.Equal(t, 50.0, s.budgetCarry(subs, 100, *date(2026, 2, 1), now, "EUR"))
+	// Nothing carried in the month rollover starts
+	assert.Zero(t, s.budgetCarry(subs, 100, *date(2026, 5, 1), now, "EUR"))
+	// Overspending never carries a negative amount
+	assert.Equal(t, 40.0, s.budgetCarry(subs, 100, *date(2026, 3, 1), now, "EUR"))
+}
+
+func TestSubscriptionService_Budgets(t *testing.T) {
+	s, settings := setupBudgetService(t)
+	require.NoError(t, settings.SetFloatSetting("monthly_budget", 50))
+	require.NoError(t, settings.SetFloatSetting("annual_budget", 500))
+
+	stats := &models.Stats{TotalMonthlySpend: 45, TotalAnnualSpend: 540}
+	s.applyBudgets(stats, nil, time.Now(), "EUR")
+	assert.Equal(t, 90.0, stats.BudgetUtilization)
+	assert.Equal(t, 108.0, stats.AnnualUtilization)
+
+	period, spend, budget, exceeded := stats.ExceededBudget()
+	assert.True(t, exceeded)
+	assert.Equal(t, models.BudgetPeriodAnnual, period)
+	assert.Equal(t, 540.0, spend)
+	assert.Equal(t, 500.0, budget)
+
+	// Enabling rollover starts with the current month and keeps its start when enabled again
+	require.NoError(t, settings.SetBudgetRollover(true))
+	since, ok := settings.GetCached(SettingKeyBudgetRolloverSince)
+	require.True(t, ok)
+	assert.Equal(t, time.Now().Format("2006-01"), since)
+	require.NoError(t, settings.Repo().Set(SettingKeyBudgetRolloverSince, "2020-01"))
+	settings.InvalidateCache()
+	require.NoError(t, settings.SetBudgetRollover(true))
+	since, _ = settings.GetCached(SettingKeyBudgetRolloverSince)
+	assert.Equal(t, "2020-01", since)
+
+	// Without subscriptions every past month is unused
+	stats = &models.Stats{TotalMonthlySpend: 60}
+	s.applyBudgets(stats, nil, time.Now(), "EUR")
+	assert.Greater(t, stats.BudgetRollover, 0.0)
+	assert.Equal(t, stats.MonthlyBudget+stats.BudgetRollover, stats.EffectiveMonthlyBudget)
+	_, _, _, exceeded = stats.ExceededBudget()
+	assert.False(t, exceeded)
+}
+
+func TestSubscriptionService_StatsForPurpose(t *testing.T) {
+	s, settings := setupBudgetService(t)
+	for _, sub := range []models.Subscription{
+		{Name: "Netflix", Cost: 15, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR"},
+		{Name: "IDE", Cost: 20, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", Purpose: models.PurposeBusiness},
+		{Name: "Hosting", Cost: 120, Schedule: "Annual", Status: "Active", OriginalCurrency: "EUR", Purpose: models.PurposeBusiness},
+		{Name: "Old CRM", Cost: 50, Schedule: "Monthly", Status: "Cancelled", OriginalCurrency: "EUR", Purpose: models.PurposeBusiness},
+	} {
+		_, err := s.Create(t.Context(), &sub)
+		require.NoError(t, err)
+	}
+	require.NoError(t, settings.SetPurposeBudget(models.PurposeBusiness, PurposeBudget{Monthly: 40, Annual: 600}))
+	require.NoError(t, settings.SetFloatSetting("monthly_budget", 100))
+
+	stats, err := s.GetStatsForPurpose(t.Context(), models.PurposeBusiness)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.ActiveSubscriptions)
+	assert.Equal(t, 1, stats.CancelledSubscriptions)
+	assert.InDelta(t, 30.0, stats.TotalMonthlySpend, 0.001)
+	assert.Equal(t, 40.0, stats.MonthlyBudget)
+	assert.Equal(t, 600.0, stats.AnnualBudget)
+	assert.InDelta(t, 75.0, stats.BudgetUtilization, 0.001)
+
+	// The spend per purpose always covers every subscription
+	assert.InDelta(t, 15.0, stats.PurposeSpending[models.PurposePersonal], 0.001)
+	assert.InDelta(t, 30.0, stats.PurposeSpending[models.PurposeBusiness], 0.001)
+	assert.Zero(t, stats.PurposeSpending[models.PurposeShared])
+
+	all, err := s.GetStats(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 3, all.ActiveSubscriptions)
+	assert.Equal(t, 100.0, all.MonthlyBudget)
+
+	assert.Error(t, settings.SetPurposeBudget("hobby", PurposeBudget{Monthly: 10}))
+	assert.Error(t, settings.SetPurposeBudget(models.PurposeShared, PurposeBudget{Monthly: -1}))
+	assert.Equal(t, PurposeBudget{Monthly: 40, Annual: 600}, settings.PurposeBudgets()[models.PurposeBusiness])
+}
+
+func TestSubscriptionService_StatsWithCredit(t *testing.T) {
+	s, settings := setupBudgetService(t)
+	for _, sub := range []models.Subscription{
+		{Name: "Phone", Cost: 40, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR"},
+		// A loyalty discount refunded every quarter
+		{Name: "Loyalty discount", Cost: -30, Schedule: "Quarterly", Status: "Active", OriginalCurrency: "EUR"},
+	} {
+		_, err := s.Create(t.Context(), &sub)
+		require.NoError(t, err)
+	}
+	require.NoError(t, settings.SetFloatSetting("monthly_budget", 50))
+
+	stats, err := s.GetStats(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.ActiveSubscriptions)
+	assert.InDelta(t, 30.0, stats.TotalMonthlySpend, 0.001)
+	assert.InDelta(t, 360.0, stats.TotalAnnualSpend, 0.001)
+	assert.InDelta(t, 60.0, stats.BudgetUtilization, 0.001)
+
+	credit := models.Subscription{Cost: -500, Schedule: "Monthly"}
+	assert.True(t, credit.IsCredit())
+	assert.False(t, credit.IsHighCost(-1000))
+}
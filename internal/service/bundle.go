@@ -0,0 +1,334 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// BundleFormat identifies a .svbundle archive in its manifest
+const BundleFormat = "svbundle"
+
+// BundleVersion is the manifest version written by this release
+const BundleVersion = 1
+
+// bundleManifestName is the manifest file inside the archive
+const bundleManifestName = "manifest.json"
+
+// bundleLogoDir is the directory inside the archive holding logo files
+const bundleLogoDir = "logos/"
+
+// maxBundleManifestSize limits the size of the manifest when importing
+const maxBundleManifestSize = 8 * 1024 * 1024
+
+// ErrInvalidBundle is returned when a .svbundle archive cannot be read
+var ErrInvalidBundle = errors.New("invalid subscription bundle")
+
+// bundleLogoTypes maps the logo content types accepted in a bundle to file extensions
+var bundleLogoTypes = map[string]string{
+	"image/png":                ".png",
+	"image/jpeg":               ".jpg",
+	"image/gif":                ".gif",
+	"image/webp":               ".webp",
+	"image/x-icon":             ".ico",
+	"image/vnd.microsoft.icon": ".ico",
+	"image/svg+xml":            ".svg",
+}
+
+// BundleCategory is a category definition in a bundle
+type BundleCategory struct {
+	Name string `json:"name"`
+}
+
+// BundleSubscription is a subscription in a bundle. Logo is the path of its
+// logo file inside the archive, if one was embedded.
+type BundleSubscription struct {
+	models.Subscription
+	Logo string `json:"logo,omitempty"`
+}
+
+// BundleManifest is the manifest.json of a .svbundle archive
+type BundleManifest struct {
+	Format        string               `json:"format"`
+	Version       int                  `json:"version"`
+	Name          string               `json:"name"`
+	ExportedAt    time.Time            `json:"exported_at"`
+	Categories    []BundleCategory     `json:"categories"`
+	Subscriptions []BundleSubscription `json:"subscriptions"`
+}
+
+// BundleFilter selects the subscriptions written to a bundle. An empty filter
+// selects all subscriptions.
+type BundleFilter struct {
+	Name            string
+	CategoryID      uint
+	SubscriptionIDs []uint
+}
+
+// BundleService exports curated sets of subscriptions as .svbundle archives
+// that another SubVault instance can import with logos and categories intact
+type BundleService struct {
+	subscriptions SubscriptionServiceInterface
+	logos         LogoServiceInterface
+	logosDir      string
+}
+
+func NewBundleService(subscriptions SubscriptionServiceInterface, logos LogoServiceInterface, logosDir string) *BundleService {
+	return &BundleService{subscriptions: subscriptions, logos: logos, logosDir: logosDir}
+}
+
+// Export writes a bundle of the subscriptions matching filter to w and returns
+// the number of subscriptions written. Logos are embedded when they can be
+// loaded; otherwise the subscription keeps its icon URL. Account details such
+// as customer and contract numbers are not exported.
+func (s *BundleService) Export(ctx context.Context, w io.Writer, filter BundleFilter) (int, error) {
+	all, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make(map[uint]bool, len(filter.SubscriptionIDs))
+	for _, id := range filter.SubscriptionIDs {
+		ids[id] = true
+	}
+
+	manifest := BundleManifest{
+		Format:     BundleFormat,
+		Version:    BundleVersion,
+		Name:       filter.Name,
+		ExportedAt: time.Now(),
+	}
+	logos := make(map[string][]byte)
+	seenCategories := make(map[string]bool)
+
+	for _, sub := range all {
+		if filter.CategoryID != 0 && sub.CategoryID != filter.CategoryID {
+			continue
+		}
+		if len(ids) > 0 && !ids[sub.ID] {
+			continue
+		}
+
+		entry := BundleSubscription{Subscription: sanitizeBundleSubscription(sub)}
+		if name := sub.Category.Name; name != "" && !seenCategories[strings.ToLower(name)] {
+			seenCategories[strings.ToLower(name)] = true
+			manifest.Categories = append(manifest.Categories, BundleCategory{Name: name})
+		}
+		if data, ext := s.loadLogo(ctx, sub.IconURL); data != nil {
+			file := bundleLogoDir + contentAddressedName(data, ext)
+			logos[file] = data
+			entry.Logo = file
+		}
+		manifest.Subscriptions = append(manifest.Subscriptions, entry)
+	}
+
+	zw := zip.NewWriter(w)
+	mw, err := zw.Create(bundleManifestName)
+	if err != nil {
+		return 0, err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return 0, err
+	}
+	for file, data := range logos {
+		lw, err := zw.Create(file)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := lw.Write(data); err != nil {
+			return 0, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return len(manifest.Subscriptions), nil
+}
+
+// loadLogo returns the logo of a subscription and its file extension, reading
+// locally stored logos from the logos directory and downloading remote ones
+func (s *BundleService) loadLogo(ctx context.Context, iconURL string) ([]byte, string) {
+	var data []byte
+	switch {
+	case iconURL == "":
+		return nil, ""
+	case strings.HasPrefix(iconURL, "/logos/"):
+		local, err := os.ReadFile(filepath.Join(s.logosDir, path.Base(iconURL)))
+		if err != nil {
+			slog.Warn("failed to read logo for bundle", "icon_url", iconURL, "error", err)
+			return nil, ""
+		}
+		data = local
+	case strings.HasPrefix(iconURL, "http://"), strings.HasPrefix(iconURL, "https://"):
+		remote, err := s.logos.DownloadLogo(ctx, iconURL)
+		if err != nil {
+			slog.Warn("failed to download logo for bundle", "icon_url", iconURL, "error", err)
+			return nil, ""
+		}
+		data = remote
+	default:
+		return nil, ""
+	}
+
+	ext, ok := detectLogoType(data)
+	if !ok || len(data) > maxLogoSize {
+		slog.Warn("skipping unsupported logo for bundle", "icon_url", iconURL, "size", len(data))
+		return nil, ""
+	}
+	return data, ext
+}
+
+// sanitizeBundleSubscription resets database and reminder state and removes
+// account details that should not leave the instance
+func sanitizeBundleSubscription(sub models.Subscription) models.Subscription {
+	sub.ID = 0
+	sub.CategoryID = 0
+	sub.Category = models.Category{Name: sub.Category.Name}
+	sub.CustomerNumber = ""
+	sub.ContractNumber = ""
+	sub.LoginName = ""
+	sub.LastReminderSent = nil
+	sub.LastReminderRenewalDate = nil
+	sub.LastCancellationReminderSent = nil
+	sub.LastCancellationReminderDate = nil
+	sub.ImportBatchID = nil
+	sub.NotifyChannels, _ = models.NormalizeNotifyChannels(sub.NotifyChannels) // unknown channels fall back to all
+	sub.CreatedAt = time.Time{}
+	sub.UpdatedAt = time.Time{}
+	return sub
+}
+
+// isBundle reports whether data looks like a zip archive
+func isBundle(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// parseBundle reads a .svbundle archive into staged subscriptions with their logos
+func parseBundle(data []byte) ([]stagedSubscription, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	mf, ok := files[bundleManifestName]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidBundle, bundleManifestName)
+	}
+	raw, err := readZipFile(mf, maxBundleManifestSize)
+	if err != nil {
+		return nil, err
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if manifest.Format != BundleFormat {
+		return nil, fmt.Errorf("%w: unexpected format %q", ErrInvalidBundle, manifest.Format)
+	}
+	if manifest.Version > BundleVersion {
+		return nil, fmt.Errorf("%w: version %d is newer than supported (%d)", ErrInvalidBundle, manifest.Version, BundleVersion)
+	}
+
+	items := make([]stagedSubscription, 0, len(manifest.Subscriptions))
+	for _, entry := range manifest.Subscriptions {
+		item := stagedSubscription{
+			sub:          sanitizeBundleSubscription(entry.Subscription),
+			categoryName: entry.Category.Name,
+		}
+
+		if entry.Logo != "" {
+			f, ok := files[entry.Logo]
+			if !ok {
+				slog.Warn("bundle logo missing from archive", "subscription", entry.Name, "logo", entry.Logo)
+			} else if logo, err := readZipFile(f, maxLogoSize); err != nil {
+				slog.Warn("skipping bundle logo", "subscription", entry.Name, "error", err)
+			} else if ext, ok := detectLogoType(logo); !ok {
+				slog.Warn("skipping bundle logo with unsupported type", "subscription", entry.Name)
+			} else {
+				item.logo = logo
+				item.logoExt = ext
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// readZipFile reads a file from an archive, failing if it exceeds limit bytes
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: %s is too large", ErrInvalidBundle, f.Name)
+	}
+	return data, nil
+}
+
+// detectLogoType returns the file extension for supported logo image data
+func detectLogoType(data []byte) (string, bool) {
+	contentType := http.DetectContentType(data)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	if ext, ok := bundleLogoTypes[contentType]; ok {
+		return ext, true
+	}
+	// SVG is detected as XML or plain text
+	head := bytes.ToLower(data[:min(len(data), 1024)])
+	if bytes.Contains(head, []byte("<svg")) {
+		return ".svg", true
+	}
+	return "", false
+}
+
+// contentAddressedName names a logo file after a hash of its content so
+// identical logos are stored once
+func contentAddressedName(data []byte, ext string) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:12]) + ext
+}
+
+// storeLogo writes a logo into dir and returns the URL it is served under
+func storeLogo(dir string, data []byte, ext string) (string, error) {
+	name := contentAddressedName(data, ext)
+	target := filepath.Join(dir, name)
+	if _, err := os.Stat(target); err != nil {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return "", err
+		}
+	}
+	return "/logos/" + name, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundleService_RoundTrip(t *testing.T) {
+	source, _, _ := setupImportExportServices(t)
+	target, importService, _ := setupImportExportServices(t)
+
+	sourceLogos := t.TempDir()
+	logo := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 32)...)
+	require.NoError(t, os.WriteFile(filepath.Join(sourceLogos, "grafana.png"), logo, 0o644))
+
+	homelab, err := source.categoryService.Create(&models.Category{Name: "Homelab"})
+	require.NoError(t, err)
+	other, err := source.categoryService.Create(&models.Category{Name: "Streaming"})
+	require.NoError(t, err)
+	for _, sub := range []*models.Subscription{
+		{Name: "Grafana Cloud", Cost: 19, Schedule: "Monthly", Status: "Active", CategoryID: homelab.ID, IconURL: "/logos/grafana.png", CustomerNumber: "C-123"},
+		{Name: "Tailscale", Cost: 60, Schedule: "Annual", Status: "Active", CategoryID: homelab.ID},
+		{Name: "Netflix", Cost: 12.99, Schedule: "Monthly", Status: "Active", CategoryID: other.ID},
+	} {
+		_, err := source.Create(t.Context(), sub)
+		require.NoError(t, err)
+	}
+
+	var buf bytes.Buffer
+	count, err := NewBundleService(source, NewLogoService(source.settings, LogoFetchPolicy{}), sourceLogos).Export(t.Context(), &buf, BundleFilter{Name: "My homelab", CategoryID: homelab.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, BundleFormat, importService.DetectFormat(buf.Bytes()))
+
+	result, err := importService.Import(t.Context(), buf.Bytes(), "", ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+
+	subs, err := target.GetAll(t.Context())
+	require.NoError(t, err)
+	require.Len(t, subs, 2)
+	for _, sub := range subs {
+		assert.Equal(t, "Homelab", sub.Category.Name)
+		assert.Empty(t, sub.CustomerNumber)
+		if sub.Name != "Grafana Cloud" {
+			assert.Empty(t, sub.IconURL)
+			continue
+		}
+		require.Contains(t, sub.IconURL, "/logos/")
+		stored, err := os.ReadFile(filepath.Join(importService.logosDir, path.Base(sub.IconURL)))
+		require.NoError(t, err)
+		assert.Equal(t, logo, stored)
+	}
+}
+
+func TestParseBundle_Invalid(t *testing.T) {
+	_, err := parseBundle([]byte("PK\x03\x04garbage"))
+	assert.ErrorIs(t, err, ErrInvalidBundle)
+}
@@ -1,11 +1,45 @@
 package service
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"subvault/internal/models"
 	"subvault/internal/repository"
 )
 
+// CalendarScope limits the calendar feed to the subscriptions of one
+// category and/or purpose. The zero scope is the full feed.
+type CalendarScope struct {
+	CategoryID uint
+	Purpose    string
+}
+
+// Query returns the feed URL query parameters of the scope
+func (s CalendarScope) Query() url.Values {
+	query := url.Values{}
+	if s.CategoryID != 0 {
+		query.Set("category", strconv.FormatUint(uint64(s.CategoryID), 10))
+	}
+	if s.Purpose != "" {
+		query.Set("purpose", s.Purpose)
+	}
+	return query
+}
+
+// Includes reports whether a subscription belongs to the scope
+func (s CalendarScope) Includes(sub *models.Subscription) bool {
+	return (s.CategoryID == 0 || sub.CategoryID == s.CategoryID) &&
+		(s.Purpose == "" || sub.EffectivePurpose() == s.Purpose)
+}
+
 type CalendarService struct {
 	settings *SettingsService
 	repo     *repository.SettingsRepository
@@ -46,3 +80,61 @@ func (c *CalendarService) RevokeCalendarToken() error {
 	defer c.settings.InvalidateCache()
 	return c.repo.Set(SettingKeyCalendarToken, "")
 }
+
+// ScopedCalendarToken returns the feed token for a scope. It is derived from
+// the calendar token, so it only opens the feed for that scope and stops
+// working when the calendar token is revoked or regenerated.
+func (c *CalendarService) ScopedCalendarToken(scope CalendarScope) (string, error) {
+	token, err := c.GetCalendarToken()
+	if err != nil || token == "" {
+		return "", fmt.Errorf("calendar_token not found")
+	}
+	if scope == (CalendarScope{}) {
+		return token, nil
+	}
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte("calendar-scope:" + scope.Query().Encode()))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// AuthorizeCalendarFeed reports whether token opens the feed for scope. The
+// calendar token opens every scope, a scoped token only its own.
+func (c *CalendarService) AuthorizeCalendarFeed(token string, scope CalendarScope) bool {
+	stored, err := c.GetCalendarToken()
+	if err != nil || stored == "" || token == "" {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(token)) == 1 {
+		return true
+	}
+	scoped, err := c.ScopedCalendarToken(scope)
+	return err == nil && subtle.ConstantTimeCompare([]byte(scoped), []byte(token)) == 1
+}
+
+// GetFeedOptions returns the configured calendar feed options, or the
+// defaults if none are configured
+func (c *CalendarService) GetFeedOptions() models.CalendarFeedOptions {
+	data, ok := c.settings.GetCached(SettingKeyCalendarFeed)
+	if !ok {
+		return models.DefaultCalendarFeedOptions
+	}
+	var options models.CalendarFeedOptions
+	if err := json.Unmarshal([]byte(data), &options); err != nil || options.Validate() != nil {
+		slog.Warn("invalid calendar feed setting, using defaults", "error", err)
+		return models.DefaultCalendarFeedOptions
+	}
+	return options
+}
+
+// SaveFeedOptions validates and saves the calendar feed options
+func (c *CalendarService) SaveFeedOptions(options models.CalendarFeedOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	defer c.settings.InvalidateCache()
+	return c.repo.Set(SettingKeyCalendarFeed, string(data))
+}
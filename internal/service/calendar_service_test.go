@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarService_ScopedTokens(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	settingsRepo := repository.NewSettingsRepository(db)
+	calendarService := NewCalendarService(NewSettingsService(settingsRepo), settingsRepo)
+
+	work := CalendarScope{Purpose: models.PurposeBusiness}
+	_, err := calendarService.ScopedCalendarToken(work)
+	assert.Error(t, err, "no feed without a calendar token")
+
+	token, err := calendarService.GenerateCalendarToken()
+	require.NoError(t, err)
+	scoped, err := calendarService.ScopedCalendarToken(work)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, scoped)
+
+	// The calendar token opens every scope, a scoped token only its own
+	assert.True(t, calendarService.AuthorizeCalendarFeed(token, CalendarScope{}))
+	assert.True(t, calendarService.AuthorizeCalendarFeed(token, work))
+	assert.True(t, calendarService.AuthorizeCalendarFeed(scoped, work))
+	assert.False(t, calendarService.AuthorizeCalendarFeed(scoped, CalendarScope{}))
+	assert.False(t, calendarService.AuthorizeCalendarFeed(scoped, CalendarScope{Purpose: models.PurposeBusiness, CategoryID: 2}))
+	assert.False(t, calendarService.AuthorizeCalendarFeed("", CalendarScope{}))
+
+	// Regenerating the calendar token invalidates scoped tokens
+	_, err = calendarService.GenerateCalendarToken()
+	require.NoError(t, err)
+	assert.False(t, calendarService.AuthorizeCalendarFeed(scoped, work))
+}
+
+func TestCalendarScope_Includes(t *testing.T) {
+	sub := &models.Subscription{CategoryID: 3}
+	assert.True(t, CalendarScope{}.Includes(sub))
+	assert.True(t, CalendarScope{CategoryID: 3, Purpose: models.PurposePersonal}.Includes(sub), "unset purpose is personal")
+	assert.False(t, CalendarScope{CategoryID: 4}.Includes(sub))
+	assert.False(t, CalendarScope{Purpose: models.PurposeBusiness}.Includes(sub))
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"subvault/internal/models"
+)
+
+// cancelCatalog maps the normalized names of popular services to the page
+// their subscriptions are cancelled on. Longer names take precedence, so
+// "youtube premium" wins over a shorter entry starting the same way.
+var cancelCatalog = map[string]string{
+	"netflix":         "https://www.netflix.com/cancelplan",
+	"spotify":         "https://www.spotify.com/account/subscription/",
+	"disney":          "https://www.disneyplus.com/account/subscription",
+	"amazon prime":    "https://www.amazon.com/mc",
+	"prime video":     "https://www.amazon.com/mc",
+	"audible":         "https://www.audible.com/account/overview",
+	"youtube premium": "https://www.youtube.com/paid_memberships",
+	"youtube music":   "https://www.youtube.com/paid_memberships",
+	"google one":      "https://one.google.com/settings",
+	"apple":           "https://apps.apple.com/account/subscriptions",
+	"icloud":          "https://apps.apple.com/account/subscriptions",
+	"microsoft 365":   "https://account.microsoft.com/services",
+	"office 365":      "https://account.microsoft.com/services",
+	"xbox game pass":  "https://account.microsoft.com/services",
+	"adobe":           "https://account.adobe.com/plans",
+	"dropbox":         "https://www.dropbox.com/account/plan",
+	"github":          "https://github.com/settings/billing",
+	"hulu":            "https://secure.hulu.com/account/cancel",
+	"paramount":       "https://www.paramountplus.com/account/",
+	"crunchyroll":     "https://www.crunchyroll.com/account/membership",
+	"dazn":            "https://www.dazn.com/myaccount",
+	"patreon":         "https://www.patreon.com/settings/memberships",
+}
+
+// normalizeServiceName lowercases a name and reduces it to letters and digits
+// separated by single spaces, so "Disney+" becomes "disney"
+func normalizeServiceName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, strings.ToLower(name))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// KnownCancelURL returns the cancellation page of the popular service a
+// subscription name starts with, like "Netflix Premium", or "" when the
+// service is unknown
+func KnownCancelURL(name string) string {
+	normalized := normalizeServiceName(name)
+	if normalized == "" {
+		return ""
+	}
+
+	best, bestLen := "", 0
+	for key, url := range cancelCatalog {
+		matches := normalized == key || strings.HasPrefix(normalized, key+" ")
+		if matches && len(key) > bestLen {
+			best, bestLen = url, len(key)
+		}
+	}
+	return best
+}
+
+// CancelURL returns where a subscription is cancelled: its own cancellation
+// page, or the known one of the service
+func CancelURL(sub *models.Subscription) string {
+	if sub.CancelURL != "" {
+		return sub.CancelURL
+	}
+	return KnownCancelURL(sub.Name)
+}
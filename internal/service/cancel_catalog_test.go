@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKnownCancelURL(t *testing.T) {
+	tests := map[string]string{
+		"Netflix":                "https://www.netflix.com/cancelplan",
+		"netflix premium":        "https://www.netflix.com/cancelplan",
+		"Disney+":                "https://www.disneyplus.com/account/subscription",
+		"YouTube Premium Family": "https://www.youtube.com/paid_memberships",
+		"Microsoft 365 Family":   "https://account.microsoft.com/services",
+		"Apple TV+":              "https://apps.apple.com/account/subscriptions",
+		"Netflixer":              "",
+		"YouTube":                "",
+		"My Local Gym":           "",
+		"":                       "",
+	}
+	for name, expected := range tests {
+		assert.Equal(t, expected, KnownCancelURL(name), name)
+	}
+}
+
+func TestCancelURL_PrefersOwnPage(t *testing.T) {
+	sub := &models.Subscription{Name: "Spotify"}
+	assert.Equal(t, "https://www.spotify.com/account/subscription/", CancelURL(sub))
+
+	sub.CancelURL = "https://example.com/cancel"
+	assert.Equal(t, "https://example.com/cancel", CancelURL(sub))
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCategoryRule is returned when a category rule fails validation
+var ErrInvalidCategoryRule = errors.New("invalid category rule")
+
+// ErrCategoryRuleNotFound is returned when deleting a rule that does not exist
+var ErrCategoryRuleNotFound = errors.New("category rule not found")
+
+// CategoryRuleService manages the rules that map category names from import
+// files to existing categories
+type CategoryRuleService struct {
+	repo       *repository.CategoryRuleRepository
+	categories CategoryServiceInterface
+}
+
+// NewCategoryRuleService creates a category rule service
+func NewCategoryRuleService(repo *repository.CategoryRuleRepository, categories CategoryServiceInterface) *CategoryRuleService {
+	return &CategoryRuleService{repo: repo, categories: categories}
+}
+
+// List returns all rules with their target category
+func (s *CategoryRuleService) List() ([]models.CategoryRule, error) {
+	return s.repo.GetAll()
+}
+
+// Set maps match from source to the given category. An existing rule for the
+// same source and name (ignoring case) is pointed at the new category.
+func (s *CategoryRuleService) Set(source, match string, categoryID uint) (*models.CategoryRule, error) {
+	source = normalizeRuleSource(source)
+	match = strings.TrimSpace(match)
+	if !models.IsValidCategoryRuleSource(source) {
+		return nil, fmt.Errorf("%w: unknown source %q", ErrInvalidCategoryRule, source)
+	}
+	if match == "" {
+		return nil, fmt.Errorf("%w: category name to match is required", ErrInvalidCategoryRule)
+	}
+	if _, err := s.categories.GetByID(categoryID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: unknown category %d", ErrInvalidCategoryRule, categoryID)
+		}
+		return nil, err
+	}
+
+	rules, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule.Source == source && strings.EqualFold(rule.Match, match) {
+			if err := s.repo.UpdateCategory(rule.ID, categoryID); err != nil {
+				return nil, err
+			}
+			return s.repo.GetByID(rule.ID)
+		}
+	}
+
+	rule := &models.CategoryRule{Source: source, Match: match, CategoryID: categoryID}
+	if err := s.repo.Create(rule); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(rule.ID)
+}
+
+// Delete removes a rule
+func (s *CategoryRuleService) Delete(id uint) error {
+	err := s.repo.Delete(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCategoryRuleNotFound
+	}
+	return err
+}
+
+// normalizeRuleSource maps import format names to rule sources; SubVault
+// exports share the SubTrackr format
+func normalizeRuleSource(source string) string {
+	source = strings.ToLower(strings.TrimSpace(source))
+	if source == "subvault" {
+		return models.CategoryRuleSourceSubTrackr
+	}
+	return source
+}
+
+// matchCategoryRule returns the rule for a category name of an import in the
+// given format. Rules for that format win over rules for any source.
+func matchCategoryRule(rules []models.CategoryRule, format, name string) *models.CategoryRule {
+	source := normalizeRuleSource(format)
+	var fallback *models.CategoryRule
+	for i := range rules {
+		if !strings.EqualFold(rules[i].Match, strings.TrimSpace(name)) {
+			continue
+		}
+		switch rules[i].Source {
+		case source:
+			return &rules[i]
+		case models.CategoryRuleSourceAny:
+			fallback = &rules[i]
+		}
+	}
+	return fallback
+}
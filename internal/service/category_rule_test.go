@@ -0,0 +1,124 @@
+package service
+
+import (
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCategoryRuleServices(t *testing.T) (*CategoryRuleService, *CategoryService, *SubscriptionService, *ImportService) {
+	db := setupRenewalReminderTestDB(t)
+	// Rules are removed with their category by the foreign key
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.Exec("PRAGMA foreign_keys = ON").Error)
+	require.NoError(t, db.AutoMigrate(&models.ImportBatch{}, &models.UsageEvent{}, &models.SubscriptionShare{}, &models.ReminderRetry{}, &models.Payment{}, &models.CategoryRule{}))
+	services := newTestSubscriptionService(t, db)
+	ruleService := NewCategoryRuleService(repository.NewCategoryRuleRepository(db), services.categories)
+	importService := NewImportService(services.subscriptions, services.categories, ruleService, NewRenewalService(), repository.NewImportBatchRepository(db), t.TempDir())
+	return ruleService, services.categories, services.subscriptions, importService
+}
+
+func TestCategoryRuleService_Set(t *testing.T) {
+	rules, categories, _, _ := setupCategoryRuleServices(t)
+	_, err := categories.Create(&models.Category{Name: "General", IsDefault: true})
+	require.NoError(t, err)
+	entertainment, err := categories.Create(&models.Category{Name: "Entertainment"})
+	require.NoError(t, err)
+	media, err := categories.Create(&models.Category{Name: "Media"})
+	require.NoError(t, err)
+
+	rule, err := rules.Set("Wallos", " Streaming ", entertainment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.CategoryRuleSourceWallos, rule.Source)
+	assert.Equal(t, "Streaming", rule.Match)
+	assert.Equal(t, "Entertainment", rule.Category.Name)
+
+	// The same source and name, ignoring case, updates the rule
+	updated, err := rules.Set("wallos", "streaming", media.ID)
+	require.NoError(t, err)
+	assert.Equal(t, rule.ID, updated.ID)
+	assert.Equal(t, "Media", updated.Category.Name)
+
+	// "subvault" is stored as the SubTrackr format it shares
+	rule, err = rules.Set("subvault", "Streaming", entertainment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.CategoryRuleSourceSubTrackr, rule.Source)
+
+	_, err = rules.Set("mint", "Streaming", entertainment.ID)
+	assert.ErrorIs(t, err, ErrInvalidCategoryRule)
+	_, err = rules.Set("", "  ", entertainment.ID)
+	assert.ErrorIs(t, err, ErrInvalidCategoryRule)
+	_, err = rules.Set("", "Streaming", 999)
+	assert.ErrorIs(t, err, ErrInvalidCategoryRule)
+
+	list, err := rules.List()
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	// Rules go away with their category
+	require.NoError(t, categories.Delete(media.ID))
+	list, err = rules.List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	require.NoError(t, rules.Delete(list[0].ID))
+	assert.ErrorIs(t, rules.Delete(list[0].ID), ErrCategoryRuleNotFound)
+}
+
+func TestMatchCategoryRule(t *testing.T) {
+	rules := []models.CategoryRule{
+		{ID: 1, Source: models.CategoryRuleSourceAny, Match: "Streaming", CategoryID: 1},
+		{ID: 2, Source: models.CategoryRuleSourceWallos, Match: "streaming", CategoryID: 2},
+	}
+
+	assert.Equal(t, uint(2), matchCategoryRule(rules, "wallos", "Streaming").ID)
+	assert.Equal(t, uint(1), matchCategoryRule(rules, "subvault", "STREAMING").ID)
+	assert.Equal(t, uint(1), matchCategoryRule(rules, BundleFormat, "Streaming").ID)
+	assert.Nil(t, matchCategoryRule(rules, "wallos", "Music"))
+}
+
+func TestImportService_CategoryRules(t *testing.T) {
+	rules, categories, subscriptions, importService := setupCategoryRuleServices(t)
+	entertainment, err := categories.Create(&models.Category{Name: "Entertainment"})
+	require.NoError(t, err)
+	_, err = rules.Set(models.CategoryRuleSourceWallos, "Streaming", entertainment.ID)
+	require.NoError(t, err)
+
+	data := []byte(`{"subscriptions":[
+		{"name":"Netflix","price":"12.99","cycle":3,"category_name":"streaming"},
+		{"name":"Notion","price":"10","cycle":4,"category_name":"Productivity"}
+	]}`)
+
+	preview, err := importService.Preview(t.Context(), data, "wallos")
+	require.NoError(t, err)
+	require.Len(t, preview.Items, 2)
+	assert.Equal(t, CategoryMappingRule, preview.Items[0].CategoryMapping)
+	assert.Equal(t, "Entertainment", preview.Items[0].MappedCategory)
+	assert.Equal(t, []string{"Productivity"}, preview.NewCategories)
+
+	result, err := importService.Confirm(t.Context(), preview.Token, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+
+	subs, err := subscriptions.GetAll(t.Context())
+	require.NoError(t, err)
+	byName := make(map[string]string)
+	for _, sub := range subs {
+		byName[sub.Name] = sub.Category.Name
+	}
+	assert.Equal(t, "Entertainment", byName["Netflix"])
+	assert.Equal(t, "Productivity", byName["Notion"])
+
+	// Wallos rules do not apply to SubVault exports
+	_, err = importService.Import(t.Context(), []byte(`{"exported_at":"2026-01-01T00:00:00Z","subscriptions":[{"name":"Hulu","cost":7.99,"schedule":"Monthly","status":"Active","category":{"name":"Streaming"}}]}`), "", ImportOptions{})
+	require.NoError(t, err)
+	all, err := categories.GetAll()
+	require.NoError(t, err)
+	assert.NotNil(t, findCategory(all, "Streaming"))
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// MaxPendingProposals caps the change proposals waiting for the admin
+const MaxPendingProposals = 100
+
+var (
+	// ErrProposalNotFound is returned for an unknown change proposal
+	ErrProposalNotFound = errors.New("change proposal not found")
+	// ErrProposalDecided is returned when deciding on a proposal that was already approved or rejected
+	ErrProposalDecided = errors.New("change proposal was already decided")
+	// ErrInvalidProposal is returned for a proposal with an unknown action, no changes or a missing subscription
+	ErrInvalidProposal = errors.New("invalid change proposal")
+	// ErrTooManyProposals is returned when MaxPendingProposals proposals are waiting
+	ErrTooManyProposals = errors.New("too many change proposals are waiting for a decision")
+)
+
+// ProposalChange is one field a proposal changes, with the values formatted
+// for display. Old is empty for a new subscription.
+type ProposalChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ChangeProposalView is a proposal with the changes it makes to the
+// subscription as it is now
+type ChangeProposalView struct {
+	models.ChangeProposal
+	Diff []ProposalChange `json:"diff"`
+}
+
+// ChangeProposalService keeps the queue of subscription changes proposed by
+// viewers. The admin approves a proposal, which applies it, or rejects it.
+type ChangeProposalService struct {
+	repo          *repository.ChangeProposalRepository
+	subscriptions SubscriptionServiceInterface
+}
+
+func NewChangeProposalService(repo *repository.ChangeProposalRepository, subscriptions SubscriptionServiceInterface) *ChangeProposalService {
+	return &ChangeProposalService{repo: repo, subscriptions: subscriptions}
+}
+
+// Propose queues a change. Changes is a JSON object of subscription fields,
+// required for create and update and ignored for delete; the caller checks
+// the fields themselves.
+func (s *ChangeProposalService) Propose(ctx context.Context, action string, subscriptionID uint, changes json.RawMessage, note, proposedBy string) (*models.ChangeProposal, error) {
+	pending, err := s.repo.CountPending()
+	if err != nil {
+		return nil, err
+	}
+	if pending >= MaxPendingProposals {
+		return nil, ErrTooManyProposals
+	}
+
+	proposal := &models.ChangeProposal{
+		Action:     action,
+		Note:       strings.TrimSpace(note),
+		ProposedBy: proposedBy,
+		Status:     models.ProposalPending,
+	}
+	var fields map[string]json.RawMessage
+	switch action {
+	case models.ProposalCreate, models.ProposalUpdate:
+		if err := json.Unmarshal(changes, &fields); err != nil || len(fields) == 0 {
+			return nil, fmt.Errorf("%w: no changes", ErrInvalidProposal)
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, changes); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProposal, err)
+		}
+		proposal.Changes = compact.String()
+	case models.ProposalDelete:
+	default:
+		return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidProposal, action)
+	}
+
+	if action == models.ProposalCreate {
+		_ = json.Unmarshal(fields["name"], &proposal.Name)
+	} else {
+		sub, err := s.subscriptions.GetByID(ctx, subscriptionID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: subscription not found", ErrInvalidProposal)
+		}
+		if err != nil {
+			return nil, err
+		}
+		proposal.SubscriptionID = &sub.ID
+		proposal.Name = sub.Name
+	}
+
+	if err := s.repo.Create(proposal); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// Get returns a proposal with its changes
+func (s *ChangeProposalService) Get(ctx context.Context, id uint) (*ChangeProposalView, error) {
+	proposal, err := s.repo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProposalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.view(ctx, *proposal)
+}
+
+// List returns proposals, newest first, optionally limited to limit entries
+// (limit > 0) and to the given statuses
+func (s *ChangeProposalService) List(ctx context.Context, limit int, statuses ...string) ([]ChangeProposalView, error) {
+	proposals, err := s.repo.List(limit, statuses...)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]ChangeProposalView, 0, len(proposals))
+	for _, proposal := range proposals {
+		view, err := s.view(ctx, proposal)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, *view)
+	}
+	return views, nil
+}
+
+// CountPending returns how many proposals wait for a decision
+func (s *ChangeProposalService) CountPending() (int64, error) {
+	return s.repo.CountPending()
+}
+
+// Approve applies a pending proposal with apply and marks it approved. The
+// proposal is claimed first so that it is applied once; if apply fails it
+// goes back to pending.
+func (s *ChangeProposalService) Approve(ctx context.Context, id uint, apply func(*models.ChangeProposal) error) (*models.ChangeProposal, error) {
+	proposal, err := s.decide(id, models.ProposalApproved)
+	if err != nil {
+		return nil, err
+	}
+	if err := apply(proposal); err != nil {
+		if _, resetErr := s.repo.SetStatus(id, models.ProposalApproved, models.ProposalPending, nil); resetErr != nil {
+			return nil, errors.Join(err, resetErr)
+		}
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// Reject marks a pending proposal rejected without applying it
+func (s *ChangeProposalService) Reject(ctx context.Context, id uint) (*models.ChangeProposal, error) {
+	return s.decide(id, models.ProposalRejected)
+}
+
+// decide moves a pending proposal to status and returns it
+func (s *ChangeProposalService) decide(id uint, status string) (*models.ChangeProposal, error) {
+	now := time.Now()
+	ok, err := s.repo.SetStatus(id, models.ProposalPending, status, &now)
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := s.repo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProposalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrProposalDecided
+	}
+	return proposal, nil
+}
+
+// view adds the changes of a pending proposal compared to the subscription as
+// it is now. Decided proposals and those of a deleted subscription list the
+// proposed values only.
+func (s *ChangeProposalService) view(ctx context.Context, proposal models.ChangeProposal) (*ChangeProposalView, error) {
+	view := &ChangeProposalView{ChangeProposal: proposal, Diff: []ProposalChange{}}
+	if proposal.Action == models.ProposalDelete || proposal.Changes == "" {
+		return view, nil
+	}
+
+	var changes map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(proposal.Changes), &changes); err != nil {
+		return nil, err
+	}
+	current := map[string]json.RawMessage{}
+	compare := proposal.Action == models.ProposalUpdate && proposal.Status == models.ProposalPending
+	if compare && proposal.SubscriptionID != nil {
+		sub, err := s.subscriptions.GetByID(ctx, *proposal.SubscriptionID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			data, err := json.Marshal(sub)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(data, &current); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, field := range proposalFieldOrder(changes) {
+		change := ProposalChange{Field: field, Old: formatProposalValue(current[field]), New: formatProposalValue(changes[field])}
+		if change.Old != change.New || !compare {
+			view.Diff = append(view.Diff, change)
+		}
+	}
+	return view, nil
+}
+
+// proposalFieldOrder returns the fields of changes in the order of the
+// subscription model, followed by any others
+func proposalFieldOrder(changes map[string]json.RawMessage) []string {
+	var fields []string
+	seen := map[string]bool{}
+	t := reflect.TypeOf(models.Subscription{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if _, ok := changes[name]; ok && !seen[name] {
+			fields = append(fields, name)
+			seen[name] = true
+		}
+	}
+	var rest []string
+	for name := range changes {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(fields, rest...)
+}
+
+// formatProposalValue formats a JSON value for display: strings without
+// quotes, dates without their time and numbers in their shortest form
+func formatProposalValue(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return string(raw)
+	}
+	switch v := value.(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t.Format(time.DateOnly)
+		}
+		return v
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return string(raw)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupChangeProposalService(t *testing.T) (*ChangeProposalService, *SubscriptionService) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ChangeProposal{}))
+	services := newTestSubscriptionService(t, db)
+	return NewChangeProposalService(repository.NewChangeProposalRepository(db), services.subscriptions), services.subscriptions
+}
+
+func TestChangeProposalService_Propose(t *testing.T) {
+	proposals, subscriptions := setupChangeProposalService(t)
+	renewal := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+	sub, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 12.99, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", RenewalDate: &renewal})
+	require.NoError(t, err)
+
+	update, err := proposals.Propose(t.Context(), models.ProposalUpdate, sub.ID, json.RawMessage(`{"cost": 14.99, "status": "Active", "renewal_date": "2030-04-01T00:00:00Z"}`), " Price went up ", "kid")
+	require.NoError(t, err)
+	assert.Equal(t, models.ProposalPending, update.Status)
+	assert.Equal(t, "Netflix", update.Name)
+	assert.Equal(t, "Price went up", update.Note)
+	assert.Equal(t, "kid", update.ProposedBy)
+
+	view, err := proposals.Get(t.Context(), update.ID)
+	require.NoError(t, err)
+	// Unchanged fields are left out, dates show without their time
+	assert.Equal(t, []ProposalChange{
+		{Field: "cost", Old: "12.99", New: "14.99"},
+		{Field: "renewal_date", Old: "2030-03-01", New: "2030-04-01"},
+	}, view.Diff)
+
+	create, err := proposals.Propose(t.Context(), models.ProposalCreate, 0, json.RawMessage(`{"name": "Spotify", "cost": 10, "status": "Active"}`), "", "kid")
+	require.NoError(t, err)
+	assert.Equal(t, "Spotify", create.Name)
+	assert.Nil(t, create.SubscriptionID)
+	view, err = proposals.Get(t.Context(), create.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []ProposalChange{
+		{Field: "name", New: "Spotify"},
+		{Field: "cost", New: "10"},
+		{Field: "status", New: "Active"},
+	}, view.Diff)
+
+	_, err = proposals.Propose(t.Context(), models.ProposalUpdate, sub.ID, json.RawMessage(`{}`), "", "kid")
+	assert.ErrorIs(t, err, ErrInvalidProposal)
+	_, err = proposals.Propose(t.Context(), models.ProposalDelete, 999, nil, "", "kid")
+	assert.ErrorIs(t, err, ErrInvalidProposal)
+	_, err = proposals.Propose(t.Context(), "rename", sub.ID, nil, "", "kid")
+	assert.ErrorIs(t, err, ErrInvalidProposal)
+
+	pending, err := proposals.List(t.Context(), 0, models.ProposalPending)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, create.ID, pending[0].ID)
+}
+
+func TestChangeProposalService_Decide(t *testing.T) {
+	proposals, subscriptions := setupChangeProposalService(t)
+	sub, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 12.99, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR"})
+	require.NoError(t, err)
+	update, err := proposals.Propose(t.Context(), models.ProposalUpdate, sub.ID, json.RawMessage(`{"cost": 14.99}`), "", "kid")
+	require.NoError(t, err)
+	remove, err := proposals.Propose(t.Context(), models.ProposalDelete, sub.ID, nil, "", "kid")
+	require.NoError(t, err)
+
+	// A failed change goes back to the queue
+	failed := errors.New("apply failed")
+	_, err = proposals.Approve(t.Context(), update.ID, func(*models.ChangeProposal) error { return failed })
+	assert.ErrorIs(t, err, failed)
+	view, err := proposals.Get(t.Context(), update.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProposalPending, view.Status)
+	assert.Nil(t, view.DecidedAt)
+
+	applied := 0
+	approved, err := proposals.Approve(t.Context(), update.ID, func(p *models.ChangeProposal) error {
+		applied++
+		assert.JSONEq(t, `{"cost": 14.99}`, p.Changes)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.ProposalApproved, approved.Status)
+	assert.NotNil(t, approved.DecidedAt)
+
+	// A decision is only taken once
+	_, err = proposals.Approve(t.Context(), update.ID, func(*models.ChangeProposal) error { applied++; return nil })
+	assert.ErrorIs(t, err, ErrProposalDecided)
+	_, err = proposals.Reject(t.Context(), update.ID)
+	assert.ErrorIs(t, err, ErrProposalDecided)
+	assert.Equal(t, 1, applied)
+
+	rejected, err := proposals.Reject(t.Context(), remove.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProposalRejected, rejected.Status)
+
+	_, err = proposals.Reject(t.Context(), 999)
+	assert.ErrorIs(t, err, ErrProposalNotFound)
+
+	count, err := proposals.CountPending()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// Decided proposals list the proposed values
+	view, err = proposals.Get(t.Context(), update.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []ProposalChange{{Field: "cost", New: "14.99"}}, view.Diff)
+}
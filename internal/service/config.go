@@ -0,0 +1,375 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"subvault/internal/i18n"
+	"subvault/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigVersion is the format version written into configuration exports
+const ConfigVersion = 1
+
+// ErrInvalidConfig is returned when an imported configuration fails validation
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// Config is the portable, non-secret configuration of an instance. SMTP and
+// Shoutrrr credentials, API keys, login data and the calendar token are never included.
+// On import omitted fields are left unchanged.
+type Config struct {
+	Version       int                  `json:"version" yaml:"version"`
+	General       ConfigGeneral        `json:"general" yaml:"general"`
+	Notifications ConfigNotifications  `json:"notifications" yaml:"notifications"`
+	Categories    []string             `json:"categories,omitempty" yaml:"categories,omitempty"`
+	CategoryRules []ConfigCategoryRule `json:"category_rules,omitempty" yaml:"category_rules,omitempty"`
+}
+
+// ConfigCategoryRule maps a category name of an import source to a category,
+// referenced by name so that rules carry over between instances
+type ConfigCategoryRule struct {
+	Source   string `json:"source,omitempty" yaml:"source,omitempty"`
+	Match    string `json:"match" yaml:"match"`
+	Category string `json:"category" yaml:"category"`
+}
+
+// ConfigGeneral holds display and budget preferences
+type ConfigGeneral struct {
+	Currency             *string  `json:"currency,omitempty" yaml:"currency,omitempty"`
+	Language             *string  `json:"language,omitempty" yaml:"language,omitempty"`
+	Theme                *string  `json:"theme,omitempty" yaml:"theme,omitempty"`
+	DateFormat           *string  `json:"date_format,omitempty" yaml:"date_format,omitempty"`
+	DisplayRounding      *string  `json:"display_rounding,omitempty" yaml:"display_rounding,omitempty"`
+	RenewalWindowDays    *int     `json:"renewal_window_days,omitempty" yaml:"renewal_window_days,omitempty"`
+	UpcomingRenewals     *int     `json:"upcoming_renewals_limit,omitempty" yaml:"upcoming_renewals_limit,omitempty"`
+	CurrencyRefreshHours *int     `json:"currency_refresh_hours,omitempty" yaml:"currency_refresh_hours,omitempty"`
+	MonthlyBudget        *float64 `json:"monthly_budget,omitempty" yaml:"monthly_budget,omitempty"`
+	AnnualBudget         *float64 `json:"annual_budget,omitempty" yaml:"annual_budget,omitempty"`
+	BudgetRollover       *bool    `json:"budget_rollover,omitempty" yaml:"budget_rollover,omitempty"`
+
+	PurposeBudgets map[string]PurposeBudget `json:"purpose_budgets,omitempty" yaml:"purpose_budgets,omitempty"`
+}
+
+// ConfigNotifications holds the notification preferences
+type ConfigNotifications struct {
+	RenewalReminders         *bool    `json:"renewal_reminders,omitempty" yaml:"renewal_reminders,omitempty"`
+	ReminderDays             *int     `json:"reminder_days,omitempty" yaml:"reminder_days,omitempty"`
+	CancellationReminders    *bool    `json:"cancellation_reminders,omitempty" yaml:"cancellation_reminders,omitempty"`
+	CancellationReminderDays *int     `json:"cancellation_reminder_days,omitempty" yaml:"cancellation_reminder_days,omitempty"`
+	HighCostAlerts           *bool    `json:"high_cost_alerts,omitempty" yaml:"high_cost_alerts,omitempty"`
+	HighCostThreshold        *float64 `json:"high_cost_threshold,omitempty" yaml:"high_cost_threshold,omitempty"`
+	UnusedNudges             *bool    `json:"unused_nudges,omitempty" yaml:"unused_nudges,omitempty"`
+	UnusedNudgeThreshold     *float64 `json:"unused_nudge_threshold,omitempty" yaml:"unused_nudge_threshold,omitempty"`
+	RateAlerts               *bool    `json:"rate_alerts,omitempty" yaml:"rate_alerts,omitempty"`
+	RateAlertThreshold       *float64 `json:"rate_alert_threshold,omitempty" yaml:"rate_alert_threshold,omitempty"`
+	RenewalConfirmations     *bool    `json:"renewal_confirmations,omitempty" yaml:"renewal_confirmations,omitempty"`
+}
+
+// ConfigImportResult summarizes what a configuration import changed
+type ConfigImportResult struct {
+	SettingsApplied      int      `json:"settings_applied"`
+	CategoriesCreated    []string `json:"categories_created"`
+	CategoryRulesApplied int      `json:"category_rules_applied"`
+}
+
+// ConfigService exports and re-imports the instance configuration ("configuration as code")
+type ConfigService struct {
+	settings    SettingsServiceInterface
+	preferences PreferencesServiceInterface
+	categories  CategoryServiceInterface
+	rules       CategoryRuleServiceInterface
+}
+
+func NewConfigService(settings SettingsServiceInterface, preferences PreferencesServiceInterface, categories CategoryServiceInterface, rules CategoryRuleServiceInterface) *ConfigService {
+	return &ConfigService{
+		settings:    settings,
+		preferences: preferences,
+		categories:  categories,
+		rules:       rules,
+	}
+}
+
+// Export collects the current configuration
+func (s *ConfigService) Export() (*Config, error) {
+	theme, err := s.preferences.GetTheme()
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categories.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.rules.List()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Version: ConfigVersion,
+		General: ConfigGeneral{
+			Currency:             ptr(s.preferences.GetCurrency()),
+			Language:             ptr(s.preferences.GetLanguage()),
+			Theme:                ptr(theme),
+			DateFormat:           ptr(s.preferences.GetDateFormat()),
+			DisplayRounding:      ptr(s.preferences.GetDisplayRounding()),
+			RenewalWindowDays:    ptr(s.preferences.GetRenewalWindowDays()),
+			UpcomingRenewals:     ptr(s.preferences.GetUpcomingRenewalsLimit()),
+			CurrencyRefreshHours: ptr(s.settings.GetIntSettingWithDefault(SettingKeyCurrencyRefreshHours, 24)),
+			MonthlyBudget:        ptr(s.settings.GetFloatSettingWithDefault("monthly_budget", 0)),
+			AnnualBudget:         ptr(s.settings.GetFloatSettingWithDefault("annual_budget", 0)),
+			BudgetRollover:       ptr(s.settings.GetBoolSettingWithDefault("budget_rollover", false)),
+			PurposeBudgets:       s.settings.PurposeBudgets(),
+		},
+		Notifications: ConfigNotifications{
+			RenewalReminders:         ptr(s.settings.GetBoolSettingWithDefault("renewal_reminders", false)),
+			ReminderDays:             ptr(s.settings.GetIntSettingWithDefault("reminder_days", 7)),
+			CancellationReminders:    ptr(s.settings.GetBoolSettingWithDefault("cancellation_reminders", false)),
+			CancellationReminderDays: ptr(s.settings.GetIntSettingWithDefault("cancellation_reminder_days", 7)),
+			HighCostAlerts:           ptr(s.settings.GetBoolSettingWithDefault("high_cost_alerts", true)),
+			HighCostThreshold:        ptr(s.settings.GetFloatSettingWithDefault("high_cost_threshold", 50.0)),
+			UnusedNudges:             ptr(s.settings.GetBoolSettingWithDefault("unused_nudges", false)),
+			UnusedNudgeThreshold:     ptr(s.settings.GetFloatSettingWithDefault("unused_nudge_threshold", 10.0)),
+			RateAlerts:               ptr(s.settings.GetBoolSettingWithDefault("rate_alerts", false)),
+			RateAlertThreshold:       ptr(s.settings.GetFloatSettingWithDefault("rate_alert_threshold", DefaultRateAlertThreshold)),
+			RenewalConfirmations:     ptr(s.settings.GetBoolSettingWithDefault("renewal_confirmations", false)),
+		},
+	}
+	for _, category := range categories {
+		cfg.Categories = append(cfg.Categories, category.Name)
+	}
+	for _, rule := range rules {
+		cfg.CategoryRules = append(cfg.CategoryRules, ConfigCategoryRule{
+			Source:   rule.Source,
+			Match:    rule.Match,
+			Category: rule.Category.Name,
+		})
+	}
+	return cfg, nil
+}
+
+// Marshal encodes a configuration as "yaml" (default) or "json"
+func (s *ConfigService) Marshal(cfg *Config, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "", "yaml", "yml":
+		return yaml.Marshal(cfg)
+	case "json":
+		return json.MarshalIndent(cfg, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported format %q (use yaml or json)", format)
+	}
+}
+
+// Parse decodes a YAML or JSON configuration
+func (s *ConfigService) Parse(data []byte) (*Config, error) {
+	var cfg Config
+	trimmed := bytes.TrimSpace(data)
+	var err error
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		err = json.Unmarshal(trimmed, &cfg)
+	} else {
+		err = yaml.Unmarshal(trimmed, &cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if cfg.Version > ConfigVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidConfig, cfg.Version)
+	}
+	return &cfg, nil
+}
+
+// Import validates the whole configuration first and then applies it. Categories
+// are only added, existing ones are never renamed or removed. Categories that
+// category rules point to are created when missing; rules with the same source
+// and name replace existing ones.
+func (s *ConfigService) Import(cfg *Config) (*ConfigImportResult, error) {
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	result := &ConfigImportResult{CategoriesCreated: []string{}}
+	g := cfg.General
+	// The language is validated by the preferences service, so it goes first
+	if g.Language != nil {
+		if err := s.preferences.SetLanguage(*g.Language); err != nil {
+			return nil, fmt.Errorf("%w: unsupported language %q", ErrInvalidConfig, *g.Language)
+		}
+		result.SettingsApplied++
+	}
+
+	var err error
+	apply := func(set bool, fn func() error) {
+		if set && err == nil {
+			err = fn()
+			result.SettingsApplied++
+		}
+	}
+	apply(g.Currency != nil, func() error { return s.preferences.SetCurrency(strings.ToUpper(*g.Currency)) })
+	apply(g.Theme != nil, func() error { return s.preferences.SetTheme(*g.Theme) })
+	apply(g.DateFormat != nil, func() error { return s.preferences.SetDateFormat(*g.DateFormat) })
+	apply(g.DisplayRounding != nil, func() error { return s.preferences.SetDisplayRounding(*g.DisplayRounding) })
+	apply(g.RenewalWindowDays != nil, func() error { return s.preferences.SetRenewalWindowDays(*g.RenewalWindowDays) })
+	apply(g.UpcomingRenewals != nil, func() error { return s.preferences.SetUpcomingRenewalsLimit(*g.UpcomingRenewals) })
+	apply(g.CurrencyRefreshHours != nil, func() error {
+		return s.settings.SetIntSetting(SettingKeyCurrencyRefreshHours, *g.CurrencyRefreshHours)
+	})
+	apply(g.MonthlyBudget != nil, func() error { return s.settings.SetFloatSetting("monthly_budget", *g.MonthlyBudget) })
+	apply(g.AnnualBudget != nil, func() error { return s.settings.SetFloatSetting("annual_budget", *g.AnnualBudget) })
+	apply(g.BudgetRollover != nil, func() error { return s.settings.SetBudgetRollover(*g.BudgetRollover) })
+	for _, purpose := range models.Purposes {
+		if budget, ok := g.PurposeBudgets[purpose]; ok {
+			apply(true, func() error { return s.settings.SetPurposeBudget(purpose, budget) })
+		}
+	}
+
+	n := cfg.Notifications
+	apply(n.RenewalReminders != nil, func() error { return s.settings.SetBoolSetting("renewal_reminders", *n.RenewalReminders) })
+	apply(n.ReminderDays != nil, func() error { return s.settings.SetIntSetting("reminder_days", *n.ReminderDays) })
+	apply(n.CancellationReminders != nil, func() error {
+		return s.settings.SetBoolSetting("cancellation_reminders", *n.CancellationReminders)
+	})
+	apply(n.CancellationReminderDays != nil, func() error {
+		return s.settings.SetIntSetting("cancellation_reminder_days", *n.CancellationReminderDays)
+	})
+	apply(n.HighCostAlerts != nil, func() error { return s.settings.SetBoolSetting("high_cost_alerts", *n.HighCostAlerts) })
+	apply(n.HighCostThreshold != nil, func() error {
+		return s.settings.SetFloatSetting("high_cost_threshold", *n.HighCostThreshold)
+	})
+	apply(n.UnusedNudges != nil, func() error { return s.settings.SetBoolSetting("unused_nudges", *n.UnusedNudges) })
+	apply(n.UnusedNudgeThreshold != nil, func() error {
+		return s.settings.SetFloatSetting("unused_nudge_threshold", *n.UnusedNudgeThreshold)
+	})
+	apply(n.RateAlerts != nil, func() error { return s.settings.SetBoolSetting("rate_alerts", *n.RateAlerts) })
+	apply(n.RateAlertThreshold != nil, func() error {
+		return s.settings.SetFloatSetting("rate_alert_threshold", *n.RateAlertThreshold)
+	})
+	apply(n.RenewalConfirmations != nil, func() error {
+		return s.settings.SetBoolSetting("renewal_confirmations", *n.RenewalConfirmations)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Categories) == 0 && len(cfg.CategoryRules) == 0 {
+		return result, nil
+	}
+
+	existing, err := s.categories.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]uint, len(existing))
+	for _, category := range existing {
+		known[strings.ToLower(category.Name)] = category.ID
+	}
+	ensureCategory := func(name string) (uint, error) {
+		if id, ok := known[strings.ToLower(name)]; ok {
+			return id, nil
+		}
+		created, err := s.categories.Create(&models.Category{Name: name})
+		if err != nil {
+			return 0, fmt.Errorf("failed to create category %q: %w", name, err)
+		}
+		known[strings.ToLower(name)] = created.ID
+		result.CategoriesCreated = append(result.CategoriesCreated, name)
+		return created.ID, nil
+	}
+
+	for _, name := range cfg.Categories {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if _, err := ensureCategory(name); err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range cfg.CategoryRules {
+		categoryID, err := ensureCategory(strings.TrimSpace(rule.Category))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.rules.Set(rule.Source, rule.Match, categoryID); err != nil {
+			return nil, fmt.Errorf("failed to save category rule %q: %w", rule.Match, err)
+		}
+		result.CategoryRulesApplied++
+	}
+
+	return result, nil
+}
+
+// validateConfig checks value ranges so that a bad file does not leave a half-applied configuration
+func validateConfig(cfg *Config) error {
+	g, n := cfg.General, cfg.Notifications
+	switch {
+	case g.Currency != nil && !isSupportedCurrency(strings.ToUpper(*g.Currency)):
+		return fmt.Errorf("%w: unsupported currency %q", ErrInvalidConfig, *g.Currency)
+	case g.Theme != nil && !validConfigThemes[*g.Theme]:
+		return fmt.Errorf("%w: unsupported theme %q", ErrInvalidConfig, *g.Theme)
+	case g.DateFormat != nil && !validConfigDateFormats[*g.DateFormat]:
+		return fmt.Errorf("%w: unsupported date format %q", ErrInvalidConfig, *g.DateFormat)
+	case g.DisplayRounding != nil && !i18n.ValidRounding(*g.DisplayRounding):
+		return fmt.Errorf("%w: unsupported display rounding %q", ErrInvalidConfig, *g.DisplayRounding)
+	case g.RenewalWindowDays != nil && (*g.RenewalWindowDays < 1 || *g.RenewalWindowDays > MaxRenewalWindowDays):
+		return fmt.Errorf("%w: renewal_window_days must be between 1 and %d", ErrInvalidConfig, MaxRenewalWindowDays)
+	case g.UpcomingRenewals != nil && (*g.UpcomingRenewals < 1 || *g.UpcomingRenewals > MaxUpcomingRenewalsLimit):
+		return fmt.Errorf("%w: upcoming_renewals_limit must be between 1 and %d", ErrInvalidConfig, MaxUpcomingRenewalsLimit)
+	case g.CurrencyRefreshHours != nil && (*g.CurrencyRefreshHours < 1 || *g.CurrencyRefreshHours > 168):
+		return fmt.Errorf("%w: currency_refresh_hours must be between 1 and 168", ErrInvalidConfig)
+	case g.MonthlyBudget != nil && *g.MonthlyBudget < 0:
+		return fmt.Errorf("%w: monthly_budget must not be negative", ErrInvalidConfig)
+	case g.AnnualBudget != nil && *g.AnnualBudget < 0:
+		return fmt.Errorf("%w: annual_budget must not be negative", ErrInvalidConfig)
+	case n.ReminderDays != nil && (*n.ReminderDays < 1 || *n.ReminderDays > 90):
+		return fmt.Errorf("%w: reminder_days must be between 1 and 90", ErrInvalidConfig)
+	case n.CancellationReminderDays != nil && (*n.CancellationReminderDays < 1 || *n.CancellationReminderDays > 90):
+		return fmt.Errorf("%w: cancellation_reminder_days must be between 1 and 90", ErrInvalidConfig)
+	case n.HighCostThreshold != nil && (*n.HighCostThreshold < 0 || *n.HighCostThreshold > 10000):
+		return fmt.Errorf("%w: high_cost_threshold must be between 0 and 10000", ErrInvalidConfig)
+	case n.UnusedNudgeThreshold != nil && (*n.UnusedNudgeThreshold < 0 || *n.UnusedNudgeThreshold > 10000):
+		return fmt.Errorf("%w: unused_nudge_threshold must be between 0 and 10000", ErrInvalidConfig)
+	case n.RateAlertThreshold != nil && (*n.RateAlertThreshold < 0.1 || *n.RateAlertThreshold > 100):
+		return fmt.Errorf("%w: rate_alert_threshold must be between 0.1 and 100", ErrInvalidConfig)
+	}
+	for purpose, budget := range g.PurposeBudgets {
+		if !models.IsValidPurpose(purpose) {
+			return fmt.Errorf("%w: unknown purpose %q in purpose_budgets", ErrInvalidConfig, purpose)
+		}
+		if budget.Monthly < 0 || budget.Annual < 0 {
+			return fmt.Errorf("%w: purpose_budgets must not be negative", ErrInvalidConfig)
+		}
+	}
+	for _, rule := range cfg.CategoryRules {
+		switch {
+		case !models.IsValidCategoryRuleSource(normalizeRuleSource(rule.Source)):
+			return fmt.Errorf("%w: unknown category rule source %q", ErrInvalidConfig, rule.Source)
+		case strings.TrimSpace(rule.Match) == "":
+			return fmt.Errorf("%w: category rules need a name to match", ErrInvalidConfig)
+		case strings.TrimSpace(rule.Category) == "":
+			return fmt.Errorf("%w: category rule %q has no target category", ErrInvalidConfig, rule.Match)
+		}
+	}
+	return nil
+}
+
+var validConfigThemes = map[string]bool{"default": true, "light": true, "dark": true, "system": true}
+
+// validConfigDateFormats are the stored Go layouts; empty means locale default
+var validConfigDateFormats = map[string]bool{"": true, "02.01.2006": true, "01/02/2006": true, "2006-01-02": true}
+
+func isSupportedCurrency(currency string) bool {
+	for _, c := range SupportedCurrencies() {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupConfigService(t *testing.T) (*ConfigService, *SettingsService, *CategoryService) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.CategoryRule{}))
+	categoryService := NewCategoryService(repository.NewCategoryRepository(db))
+	settingsService := NewSettingsService(repository.NewSettingsRepository(db))
+	preferencesService := NewPreferencesService(settingsService, defaultLangProvider())
+	ruleService := NewCategoryRuleService(repository.NewCategoryRuleRepository(db), categoryService)
+
+	return NewConfigService(settingsService, preferencesService, categoryService, ruleService), settingsService, categoryService
+}
+
+func TestConfigService_RoundTrip(t *testing.T) {
+	configService, settingsService, categoryService := setupConfigService(t)
+	_, err := categoryService.Create(&models.Category{Name: "Streaming"})
+	require.NoError(t, err)
+	require.NoError(t, settingsService.SetIntSetting("reminder_days", 14))
+
+	cfg, err := configService.Export()
+	require.NoError(t, err)
+	data, err := configService.Marshal(cfg, "yaml")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "reminder_days: 14")
+	assert.NotContains(t, string(data), "smtp")
+
+	// Apply the export to a fresh instance
+	other, otherSettings, otherCategories := setupConfigService(t)
+	parsed, err := other.Parse(data)
+	require.NoError(t, err)
+	result, err := other.Import(parsed)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Streaming"}, result.CategoriesCreated)
+	assert.Equal(t, 14, otherSettings.GetIntSettingWithDefault("reminder_days", 7))
+
+	categories, err := otherCategories.GetAll()
+	require.NoError(t, err)
+	assert.Len(t, categories, 1)
+
+	// Importing again creates nothing new
+	result, err = other.Import(parsed)
+	require.NoError(t, err)
+	assert.Empty(t, result.CategoriesCreated)
+}
+
+func TestConfigService_CategoryRules(t *testing.T) {
+	configService, _, _ := setupConfigService(t)
+
+	cfg, err := configService.Parse([]byte("category_rules:\n  - source: wallos\n    match: Streaming\n    category: Entertainment\n  - match: Games\n    category: Entertainment\n"))
+	require.NoError(t, err)
+	result, err := configService.Import(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Entertainment"}, result.CategoriesCreated)
+	assert.Equal(t, 2, result.CategoryRulesApplied)
+
+	exported, err := configService.Export()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, cfg.CategoryRules, exported.CategoryRules)
+
+	// Re-importing updates the rules instead of duplicating them
+	result, err = configService.Import(exported)
+	require.NoError(t, err)
+	assert.Empty(t, result.CategoriesCreated)
+	again, err := configService.Export()
+	require.NoError(t, err)
+	assert.Len(t, again.CategoryRules, 2)
+
+	cfg, err = configService.Parse([]byte("category_rules:\n  - source: mint\n    match: Streaming\n    category: Entertainment\n"))
+	require.NoError(t, err)
+	_, err = configService.Import(cfg)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestConfigService_ImportPartialAndInvalid(t *testing.T) {
+	configService, settingsService, _ := setupConfigService(t)
+
+	cfg, err := configService.Parse([]byte(`{"notifications": {"high_cost_threshold": 75}}`))
+	require.NoError(t, err)
+	result, err := configService.Import(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.SettingsApplied)
+	assert.Equal(t, 75.0, settingsService.GetFloatSettingWithDefault("high_cost_threshold", 50))
+	assert.False(t, settingsService.GetBoolSettingWithDefault("renewal_reminders", false))
+
+	// Invalid values are rejected before anything is written
+	cfg, err = configService.Parse([]byte("general:\n  currency_refresh_hours: 6\nnotifications:\n  reminder_days: 500\n"))
+	require.NoError(t, err)
+	_, err = configService.Import(cfg)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.Equal(t, 24, settingsService.GetIntSettingWithDefault(SettingKeyCurrencyRefreshHours, 24))
+
+	_, err = configService.Parse([]byte("version: 99\n"))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionService_GetSubscriptionsNeedingContractReminders(t *testing.T) {
+	_, subscriptions := setupPaymentService(t)
+	now := time.Now()
+	end := func(days int) *time.Time { return timePtr(now.AddDate(0, 0, days)) }
+
+	for _, sub := range []models.Subscription{
+		{Name: "Notice soon", ContractEndDate: end(40), NoticePeriodDays: 30, AutoRenew: true},
+		{Name: "Ends soon", ContractEndDate: end(5)},
+		{Name: "Ends later", ContractEndDate: end(models.ContractDecisionWindowDays + 10)},
+		{Name: "Already reminded", ContractEndDate: end(3)},
+		{Name: "Cancelled", Status: "Cancelled", ContractEndDate: end(3)},
+		{Name: "No contract", AutoRenew: true},
+	} {
+		sub.Cost, sub.Schedule, sub.OriginalCurrency = 10, "Monthly", "EUR"
+		if sub.Status == "" {
+			sub.Status = "Active"
+		}
+		if sub.Name == "Already reminded" {
+			sub.LastContractReminderDate = sub.ContractEndDate
+		}
+		_, err := subscriptions.Create(t.Context(), &sub)
+		require.NoError(t, err)
+	}
+
+	result, err := subscriptions.GetSubscriptionsNeedingContractReminders(t.Context(), time.Now())
+	require.NoError(t, err)
+	days := map[string]int{}
+	for sub, d := range result {
+		days[sub.Name] = d
+	}
+	assert.Equal(t, map[string]int{"Notice soon": 10, "Ends soon": 5}, days)
+}
@@ -1,12 +1,15 @@
 package service
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"sort"
+	"subvault/internal/i18n"
 	"subvault/internal/models"
 	"subvault/internal/repository"
 	"sync"
@@ -15,32 +18,28 @@ import (
 
 const ecbDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
 
-// SupportedCurrencies defines the list of currencies supported for exchange rates and settings.
-// Currencies with ECB rates are listed first, followed by currencies without ECB data.
-var SupportedCurrencies = []string{
-	"EUR", "USD", "GBP", "JPY", "CHF", "SEK", "PLN", "INR", "BRL",
-	"AUD", "CAD", "CNY", "CZK", "DKK", "HKD", "HUF", "IDR", "ILS",
-	"ISK", "KRW", "MXN", "MYR", "NOK", "NZD", "PHP", "RON", "SGD",
-	"THB", "TRY", "ZAR",
-	"RUB", "COP", "BDT", // No ECB rates available for these
-}
+// ecbMinFetchInterval is the least time between two requests to the ECB, so a
+// burst of conversions after a failed fetch does not hammer it
+const ecbMinFetchInterval = time.Minute
+
+// errRateFetchThrottled is returned instead of contacting the ECB again within
+// ecbMinFetchInterval of the last request; it wraps that request's error
+var errRateFetchThrottled = errors.New("ECB was contacted less than a minute ago")
+
+// ErrInvalidManualRate is returned for a manual rate of a currency without
+// ECB rates or a rate that is not a positive number
+var ErrInvalidManualRate = errors.New("invalid exchange rate")
 
-// ecbCurrencies contains currencies provided by the ECB (EUR is implicit as base)
-var ecbCurrencies = map[string]bool{
-	"USD": true, "JPY": true, "GBP": true, "CHF": true, "SEK": true,
-	"PLN": true, "INR": true, "BRL": true, "AUD": true, "CAD": true,
-	"CNY": true, "CZK": true, "DKK": true, "HKD": true, "HUF": true,
-	"IDR": true, "ILS": true, "ISK": true, "KRW": true, "MXN": true,
-	"MYR": true, "NOK": true, "NZD": true, "PHP": true, "RON": true,
-	"SGD": true, "THB": true, "TRY": true, "ZAR": true,
+// SupportedCurrencies returns the currencies that can be chosen for settings
+// and subscriptions. They come from the embedded currencies file and
+// CURRENCIES_FILE (see i18n.LoadCurrencies).
+func SupportedCurrencies() []string {
+	return i18n.CurrencyCodes()
 }
 
 // HasECBRate returns whether the ECB provides exchange rates for this currency
 func HasECBRate(currency string) bool {
-	if currency == "EUR" {
-		return true
-	}
-	return ecbCurrencies[currency]
+	return i18n.CurrencyHasECBRate(currency)
 }
 
 // ECB XML response structs
@@ -65,27 +64,74 @@ type ExchangeRateStatus struct {
 	LastFetch time.Time
 	RateDate  time.Time
 	RateCount int
-	Source    string // "ecb", "db_cache", "db_stale", "none"
+	Source    string // "ecb", "db_cache", "db_stale", "offline", "manual", "none"
 	LastError string
 	IntervalH int
 	Rates     []ExchangeRateEntry
 }
 
+// Exchange rate health, telling whether converted amounts can be trusted
+const (
+	RateHealthOK    = "ok"
+	RateHealthStale = "stale" // Converted with outdated rates
+	RateHealthNone  = "none"  // No rates could be loaded, amounts are converted 1:1
+)
+
+// Health reports the exchange rate health. Rates from the stale database
+// fallback, or older than twice the refresh interval, are stale; when no rates
+// could be loaded after a failed fetch, conversions fall back to 1:1. Rates
+// set by hand or used in offline mode are never stale, as they cannot be
+// refreshed.
+func (st ExchangeRateStatus) Health() string {
+	switch {
+	case st.RateCount == 0 && st.LastError != "":
+		return RateHealthNone
+	case st.Source == "offline" || st.Source == "manual":
+		return RateHealthOK
+	case st.Source == "db_stale":
+		return RateHealthStale
+	case st.RateCount > 0 && time.Since(st.RateDate) > 2*time.Duration(st.IntervalH)*time.Hour:
+		return RateHealthStale
+	}
+	return RateHealthOK
+}
+
+// rateFetch is an ECB request that concurrent callers wait for instead of
+// sending their own
+type rateFetch struct {
+	done chan struct{}
+	err  error
+}
+
 type CurrencyService struct {
 	repo       *repository.ExchangeRateRepository
 	settings   SettingsServiceInterface
+	client     *http.Client
+	ecbURL     string
 	mu         sync.RWMutex
 	eurRates   map[string]float64 // currency -> rate (EUR-based)
 	rateDate   time.Time
-	rateSource string    // "ecb", "db_cache", "db_stale"
+	rateSource string    // "ecb", "db_cache", "db_stale", "offline", "manual"
 	lastError  error     // last fetch error
 	lastFetch  time.Time // last successful ECB fetch
+
+	// fetchMu guards the ECB request in flight and when the last one was sent.
+	// The validators of the last ECB response are only used by the request in
+	// flight, so there is never more than one user.
+	fetchMu      sync.Mutex
+	inflight     *rateFetch
+	lastAttempt  time.Time
+	attemptErr   error
+	etag         string
+	lastModified string
 }
 
 func NewCurrencyService(repo *repository.ExchangeRateRepository, settings SettingsServiceInterface) *CurrencyService {
 	return &CurrencyService{
 		repo:     repo,
 		settings: settings,
+		client:   NewHTTPClient(10 * time.Second),
+		ecbURL:   ecbDailyURL,
 		eurRates: make(map[string]float64),
 	}
 }
@@ -99,43 +145,50 @@ func (s *CurrencyService) getRefreshInterval() time.Duration {
 	return time.Duration(hours) * time.Hour
 }
 
-// ensureRates loads exchange rates into memory if needed, with fallback to stale DB rates
+// ensureRates loads exchange rates into memory if needed, with fallback to
+// stale DB rates. In offline mode the latest DB rates are used at any age.
 func (s *CurrencyService) ensureRates() error {
 	interval := s.getRefreshInterval()
+	offline := OfflineMode()
 
 	s.mu.RLock()
-	if len(s.eurRates) > 0 && time.Since(s.rateDate) < interval {
+	if len(s.eurRates) > 0 && (offline || time.Since(s.rateDate) < interval) {
 		s.mu.RUnlock()
 		return nil
 	}
 	s.mu.RUnlock()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// Double-check after write lock
-	if len(s.eurRates) > 0 && time.Since(s.rateDate) < interval {
-		return nil
+	if offline {
+		return s.loadOfflineRates()
 	}
 
 	// Try loading fresh DB rates
 	rates, err := s.repo.GetLatestRates("EUR")
 	if err == nil && len(rates) > 0 && !rates[0].IsStaleAfter(interval) {
+		s.mu.Lock()
 		s.loadRatesLocked(rates, "db_cache")
+		s.mu.Unlock()
 		return nil
 	}
 
-	// Fetch fresh rates from ECB
-	if err := s.fetchAndCacheRatesLocked(); err != nil {
+	// Fetch fresh rates from ECB, together with any concurrent caller
+	if err := s.fetchRates(context.Background()); err != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
 		s.lastError = err
-		slog.Warn("ECB fetch failed, trying stale DB rates as fallback", "error", err)
+		throttled := errors.Is(err, errRateFetchThrottled)
+		if !throttled {
+			slog.Warn("ECB fetch failed, trying stale DB rates as fallback", "error", err)
+		}
 
 		// Fallback: use stale DB rates if available
-		if rates != nil && len(rates) > 0 {
+		if len(rates) > 0 {
 			s.loadRatesLocked(rates, "db_stale")
-			slog.Warn("using stale exchange rates as fallback",
-				"rate_date", rates[0].Date,
-				"age", time.Since(rates[0].Date).Round(time.Minute))
+			if !throttled {
+				slog.Warn("using stale exchange rates as fallback",
+					"rate_date", rates[0].Date,
+					"age", time.Since(rates[0].Date).Round(time.Minute))
+			}
 			return nil
 		}
 
@@ -145,8 +198,28 @@ func (s *CurrencyService) ensureRates() error {
 	return nil
 }
 
+// loadOfflineRates loads the latest DB rates, whatever their age, without
+// contacting the ECB
+func (s *CurrencyService) loadOfflineRates() error {
+	rates, err := s.repo.GetLatestRates("EUR")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil || len(rates) == 0 {
+		s.lastError = ErrOfflineMode
+		return fmt.Errorf("no exchange rates available: %w", ErrOfflineMode)
+	}
+	s.loadRatesLocked(rates, "offline")
+	return nil
+}
+
 // loadRatesLocked populates the in-memory cache from DB rates. Caller must hold write lock.
+// Rates from the database may be older than the last ECB response, so the next
+// ECB request is not conditional.
 func (s *CurrencyService) loadRatesLocked(rates []models.ExchangeRate, source string) {
+	s.fetchMu.Lock()
+	s.etag, s.lastModified = "", ""
+	s.fetchMu.Unlock()
+
 	s.eurRates = make(map[string]float64, len(rates)+1)
 	s.eurRates["EUR"] = 1.0
 	for _, r := range rates {
@@ -202,23 +275,72 @@ func (s *CurrencyService) ConvertAmount(amount float64, fromCurrency, toCurrency
 	return amount * rate, nil
 }
 
-// fetchAndCacheRatesLocked fetches all EUR-based rates from ECB and populates the in-memory cache.
-// Caller must hold s.mu write lock.
-func (s *CurrencyService) fetchAndCacheRatesLocked() error {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				MinVersion: tls.VersionTLS12,
-			},
-		},
-	}
-	resp, err := client.Get(ecbDailyURL)
+// fetchRates fetches the rates from the ECB. Concurrent callers share one
+// request, and within ecbMinFetchInterval of the last request the ECB is not
+// contacted again: the outcome of that request is returned instead, wrapped
+// in errRateFetchThrottled when it failed.
+func (s *CurrencyService) fetchRates(ctx context.Context) error {
+	s.fetchMu.Lock()
+	if fetch := s.inflight; fetch != nil {
+		s.fetchMu.Unlock()
+		select {
+		case <-fetch.done:
+			return fetch.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if !s.lastAttempt.IsZero() && time.Since(s.lastAttempt) < ecbMinFetchInterval {
+		err := s.attemptErr
+		s.fetchMu.Unlock()
+		if err != nil {
+			return fmt.Errorf("%w: %w", errRateFetchThrottled, err)
+		}
+		return nil
+	}
+	fetch := &rateFetch{done: make(chan struct{})}
+	s.inflight = fetch
+	etag, lastModified := s.etag, s.lastModified
+	s.fetchMu.Unlock()
+
+	fetch.err = s.fetchAndCacheRates(ctx, etag, lastModified)
+
+	s.fetchMu.Lock()
+	s.inflight = nil
+	s.lastAttempt = time.Now()
+	s.attemptErr = fetch.err
+	s.fetchMu.Unlock()
+	close(fetch.done)
+	return fetch.err
+}
+
+// fetchAndCacheRates fetches all EUR-based rates from ECB and populates the in-memory cache.
+// With the validators of the last response the request is conditional; when
+// the rates have not changed since, the cached ones are marked as fetched.
+func (s *CurrencyService) fetchAndCacheRates(ctx context.Context, etag, lastModified string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.ecbURL, nil)
+	if err != nil {
+		return err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to fetch ECB exchange rates: %w", err)
 	}
 	defer resp.Body.Close()
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if resp.StatusCode == http.StatusNotModified && len(s.eurRates) > 0 {
+		s.storeFetchedRatesLocked()
+		return nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("ECB API returned status %d", resp.StatusCode)
 	}
@@ -233,12 +355,23 @@ func (s *CurrencyService) fetchAndCacheRatesLocked() error {
 	}
 
 	// Populate in-memory cache
-	rateDate := time.Now()
 	s.eurRates = make(map[string]float64, len(envelope.Rates)+1)
 	s.eurRates["EUR"] = 1.0
 	for _, r := range envelope.Rates {
 		s.eurRates[r.Currency] = r.Rate
 	}
+	s.storeFetchedRatesLocked()
+
+	s.fetchMu.Lock()
+	s.etag, s.lastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	s.fetchMu.Unlock()
+	return nil
+}
+
+// storeFetchedRatesLocked marks the cached rates as just fetched from the ECB
+// and persists them. Caller must hold s.mu write lock.
+func (s *CurrencyService) storeFetchedRatesLocked() {
+	rateDate := time.Now()
 	s.rateDate = rateDate
 	s.rateSource = "ecb"
 	s.lastFetch = rateDate
@@ -257,27 +390,74 @@ func (s *CurrencyService) fetchAndCacheRatesLocked() error {
 	if err := s.repo.SaveRates(ratesToSave); err != nil {
 		slog.Warn("failed to cache exchange rates", "error", err)
 	}
-
-	return nil
 }
 
 // RefreshRates updates all exchange rates from the ECB
-func (s *CurrencyService) RefreshRates() error {
-	s.mu.Lock()
-	err := s.fetchAndCacheRatesLocked()
-	if err != nil {
+func (s *CurrencyService) RefreshRates(ctx context.Context) error {
+	if OfflineMode() {
+		return fmt.Errorf("failed to refresh rates: %w", ErrOfflineMode)
+	}
+	if err := s.fetchRates(ctx); err != nil {
+		s.mu.Lock()
 		s.lastError = err
 		s.mu.Unlock()
 		return fmt.Errorf("failed to refresh rates: %w", err)
 	}
-	deleteErr := s.repo.DeleteStaleRates(7 * 24 * time.Hour)
-	s.mu.Unlock()
-	if deleteErr != nil {
-		slog.Warn("failed to delete stale rates", "error", deleteErr)
+	if err := s.repo.DeleteStaleRates(7 * 24 * time.Hour); err != nil {
+		slog.Warn("failed to delete stale rates", "error", err)
+	}
+	return nil
+}
+
+// SetManualRates stores EUR-based rates set by hand, for deployments that
+// cannot reach the ECB. Currencies left out keep their current rate.
+func (s *CurrencyService) SetManualRates(rates map[string]float64) error {
+	if len(rates) == 0 {
+		return fmt.Errorf("%w: no rates given", ErrInvalidManualRate)
+	}
+	rateDate := time.Now()
+	ratesToSave := make([]models.ExchangeRate, 0, len(rates))
+	for currency, rate := range rates {
+		if currency == "EUR" || !HasECBRate(currency) {
+			return fmt.Errorf("%w: no exchange rates for %s", ErrInvalidManualRate, currency)
+		}
+		if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+			return fmt.Errorf("%w: %s rate must be a positive number", ErrInvalidManualRate, currency)
+		}
+		ratesToSave = append(ratesToSave, models.ExchangeRate{BaseCurrency: "EUR", Currency: currency, Rate: rate, Date: rateDate})
+	}
+	if err := s.repo.SaveRates(ratesToSave); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.eurRates) == 0 {
+		s.eurRates = map[string]float64{"EUR": 1.0}
+	}
+	for currency, rate := range rates {
+		s.eurRates[currency] = rate
 	}
+	s.rateDate = rateDate
+	s.rateSource = "manual"
+	s.lastError = nil
 	return nil
 }
 
+// ManualRateEntries lists every currency with ECB rates except EUR with its
+// current rate, 0 if it has none, for setting rates by hand
+func (s *CurrencyService) ManualRateEntries() []ExchangeRateEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var entries []ExchangeRateEntry
+	for _, currency := range SupportedCurrencies() {
+		if currency != "EUR" && HasECBRate(currency) {
+			entries = append(entries, ExchangeRateEntry{Currency: currency, Rate: s.eurRates[currency]})
+		}
+	}
+	return entries
+}
+
 // GetStatus returns the current exchange rate status
 func (s *CurrencyService) GetStatus() ExchangeRateStatus {
 	intervalH := s.settings.GetIntSettingWithDefault(SettingKeyCurrencyRefreshHours, 24)
@@ -317,3 +497,22 @@ func (s *CurrencyService) GetStatus() ExchangeRateStatus {
 
 	return status
 }
+
+// CheckFreshness reports an error if the loaded exchange rates are older than
+// twice the configured refresh interval or no rates could be loaded at all
+func (s *CurrencyService) CheckFreshness() error {
+	if err := s.ensureRates(); err != nil {
+		return err
+	}
+
+	maxAge := 2 * s.getRefreshInterval()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	age := time.Since(s.rateDate)
+	if age > maxAge && s.rateSource != "offline" && s.rateSource != "manual" {
+		return fmt.Errorf("exchange rates are %s old (source: %s)", age.Round(time.Minute), s.rateSource)
+	}
+	return nil
+}
@@ -1,12 +1,18 @@
 package service
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"subvault/internal/models"
 	"subvault/internal/repository"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -109,7 +115,7 @@ func TestCurrencyService_SupportedCurrencies(t *testing.T) {
 	service := setupCurrencyService(t, db)
 
 	// Test that same-currency conversion works for all supported currencies
-	for _, currency := range SupportedCurrencies {
+	for _, currency := range SupportedCurrencies() {
 		t.Run(currency, func(t *testing.T) {
 			result, err := service.ConvertAmount(100.0, currency, currency)
 			assert.NoError(t, err)
@@ -153,7 +159,7 @@ func TestCurrencyService_BDTCurrency(t *testing.T) {
 
 	t.Run("BDT in SupportedCurrencies list", func(t *testing.T) {
 		found := false
-		for _, currency := range SupportedCurrencies {
+		for _, currency := range SupportedCurrencies() {
 			if currency == "BDT" {
 				found = true
 				break
@@ -233,3 +239,176 @@ func TestSettingsService_SetCurrency_Validation(t *testing.T) {
 		})
 	}
 }
+
+func TestExchangeRateStatus_Health(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		status ExchangeRateStatus
+		want   string
+	}{
+		{"Not loaded yet", ExchangeRateStatus{Source: "none", IntervalH: 24}, RateHealthOK},
+		{"Fresh ECB rates", ExchangeRateStatus{Source: "ecb", RateCount: 30, RateDate: now.Add(-time.Hour), IntervalH: 24}, RateHealthOK},
+		{"Stale fallback", ExchangeRateStatus{Source: "db_stale", RateCount: 30, RateDate: now.Add(-30 * time.Hour), IntervalH: 24, LastError: "timeout"}, RateHealthStale},
+		{"Old cached rates", ExchangeRateStatus{Source: "db_cache", RateCount: 30, RateDate: now.Add(-72 * time.Hour), IntervalH: 24}, RateHealthStale},
+		{"Fetch failed without rates", ExchangeRateStatus{Source: "none", IntervalH: 24, LastError: "timeout"}, RateHealthNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.status.Health())
+		})
+	}
+}
+
+const testECBResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<Cube><Cube time="2026-10-16"><Cube currency="USD" rate="1.1"/><Cube currency="GBP" rate="0.85"/></Cube></Cube>
+</gesmes:Envelope>`
+
+func TestCurrencyService_RefreshRates_Conditional(t *testing.T) {
+	var requests []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Clone())
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Fri, 16 Oct 2026 14:00:00 GMT")
+		_, _ = w.Write([]byte(testECBResponse))
+	}))
+	defer server.Close()
+
+	service := setupCurrencyService(t, setupTestDB(t))
+	service.ecbURL = server.URL
+
+	require.NoError(t, service.RefreshRates(context.Background()))
+	firstFetch := service.GetStatus().LastFetch
+
+	// Let the minimum interval pass
+	service.lastAttempt = time.Now().Add(-ecbMinFetchInterval)
+	require.NoError(t, service.RefreshRates(context.Background()))
+
+	require.Len(t, requests, 2)
+	assert.Empty(t, requests[0].Get("If-None-Match"))
+	assert.Equal(t, `"v1"`, requests[1].Get("If-None-Match"))
+	assert.Equal(t, "Fri, 16 Oct 2026 14:00:00 GMT", requests[1].Get("If-Modified-Since"))
+
+	status := service.GetStatus()
+	assert.Equal(t, "ecb", status.Source)
+	assert.Equal(t, 3, status.RateCount, "unchanged rates are kept")
+	assert.True(t, status.LastFetch.After(firstFetch), "unchanged rates count as fetched")
+	rate, err := service.GetExchangeRate("EUR", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 1.1, rate)
+}
+
+func TestCurrencyService_FetchRates_SingleFlight(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		_, _ = w.Write([]byte(testECBResponse))
+	}))
+	defer server.Close()
+
+	service := setupCurrencyService(t, setupTestDB(t))
+	service.ecbURL = server.URL
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.ConvertAmount(10, "USD", "EUR")
+			errs <- err
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), hits.Load(), "concurrent conversions share one request")
+}
+
+func TestCurrencyService_FetchRates_MinimumInterval(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	service := setupCurrencyService(t, setupTestDB(t))
+	service.ecbURL = server.URL
+
+	assert.Error(t, service.RefreshRates(context.Background()))
+	for range 5 {
+		_, err := service.ConvertAmount(10, "USD", "EUR")
+		assert.ErrorIs(t, err, errRateFetchThrottled)
+	}
+	assert.Equal(t, int32(1), hits.Load(), "failed fetches are not retried right away")
+
+	service.lastAttempt = time.Now().Add(-ecbMinFetchInterval)
+	assert.Error(t, service.RefreshRates(context.Background()))
+	assert.Equal(t, int32(2), hits.Load())
+}
+
+func TestCurrencyService_OfflineMode(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	t.Cleanup(server.Close)
+	enableOfflineMode(t)
+
+	db := setupTestDB(t)
+	service := setupCurrencyService(t, db)
+	service.ecbURL = server.URL
+
+	// Without stored rates there is nothing to convert with
+	_, err := service.ConvertAmount(10, "EUR", "USD")
+	assert.ErrorIs(t, err, ErrOfflineMode)
+	assert.Equal(t, RateHealthNone, service.GetStatus().Health())
+	assert.ErrorIs(t, service.RefreshRates(t.Context()), ErrOfflineMode)
+
+	// Rates are set by hand and never turn stale
+	assert.ErrorIs(t, service.SetManualRates(map[string]float64{"USD": -1}), ErrInvalidManualRate)
+	assert.ErrorIs(t, service.SetManualRates(map[string]float64{"EUR": 1}), ErrInvalidManualRate)
+	assert.ErrorIs(t, service.SetManualRates(nil), ErrInvalidManualRate)
+	require.NoError(t, service.SetManualRates(map[string]float64{"USD": 1.25}))
+	result, err := service.ConvertAmount(10, "EUR", "USD")
+	require.NoError(t, err)
+	assert.InDelta(t, 12.5, result, 0.001)
+	status := service.GetStatus()
+	assert.Equal(t, "manual", status.Source)
+	assert.Equal(t, RateHealthOK, status.Health())
+
+	// Old stored rates are used as they are after a restart
+	repo := repository.NewExchangeRateRepository(db)
+	require.NoError(t, repo.SaveRates([]models.ExchangeRate{
+		{BaseCurrency: "EUR", Currency: "GBP", Rate: 0.8, Date: time.Now().AddDate(0, -6, 0)},
+	}))
+	restarted := setupCurrencyService(t, db)
+	restarted.ecbURL = server.URL
+	result, err = restarted.ConvertAmount(10, "EUR", "GBP")
+	require.NoError(t, err)
+	assert.InDelta(t, 8, result, 0.001)
+	assert.Equal(t, "offline", restarted.GetStatus().Source)
+	assert.NoError(t, restarted.CheckFreshness())
+
+	var entry ExchangeRateEntry
+	for _, e := range restarted.ManualRateEntries() {
+		if e.Currency == "USD" {
+			entry = e
+		}
+	}
+	assert.Equal(t, ExchangeRateEntry{Currency: "USD", Rate: 1.25}, entry)
+	assert.Zero(t, requests.Load(), "the ECB was contacted in offline mode")
+}
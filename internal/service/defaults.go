@@ -0,0 +1,134 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"subvault/internal/models"
+)
+
+// ErrInvalidDefaults is returned when saving subscription defaults that fail validation
+var ErrInvalidDefaults = errors.New("invalid subscription defaults")
+
+// SubscriptionDefaultsService stores the values new subscriptions start with
+type SubscriptionDefaultsService struct {
+	settings    *SettingsService
+	preferences PreferencesServiceInterface
+	categories  CategoryServiceInterface
+}
+
+func NewSubscriptionDefaultsService(settings *SettingsService, preferences PreferencesServiceInterface, categories CategoryServiceInterface) *SubscriptionDefaultsService {
+	return &SubscriptionDefaultsService{settings: settings, preferences: preferences, categories: categories}
+}
+
+// Get returns the configured defaults, falling back to the built-in values for
+// anything not set
+func (s *SubscriptionDefaultsService) Get() models.SubscriptionDefaults {
+	defaults := models.DefaultSubscriptionDefaults()
+	data, ok := s.settings.GetCached(SettingKeySubscriptionDefaults)
+	if !ok || data == "" {
+		return defaults
+	}
+
+	var stored models.SubscriptionDefaults
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		slog.Warn("failed to parse subscription defaults", "error", err)
+		return defaults
+	}
+	if stored.Validate() != nil {
+		return defaults
+	}
+	return stored
+}
+
+// Save validates and stores the defaults. The currency must be empty or
+// supported and the category must exist.
+func (s *SubscriptionDefaultsService) Save(defaults *models.SubscriptionDefaults) error {
+	if err := defaults.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDefaults, err)
+	}
+	if defaults.Currency != "" && !slices.Contains(SupportedCurrencies(), defaults.Currency) {
+		return fmt.Errorf("%w: unsupported currency %q", ErrInvalidDefaults, defaults.Currency)
+	}
+	if defaults.CategoryID != 0 {
+		if _, err := s.categories.GetByID(defaults.CategoryID); err != nil {
+			return fmt.Errorf("%w: unknown category %d", ErrInvalidDefaults, defaults.CategoryID)
+		}
+	}
+
+	data, err := json.Marshal(defaults)
+	if err != nil {
+		return err
+	}
+	defer s.settings.InvalidateCache()
+	return s.settings.Repo().Set(SettingKeySubscriptionDefaults, string(data))
+}
+
+// NewSubscription returns a subscription prefilled with the defaults, used
+// for the empty subscription form
+func (s *SubscriptionDefaultsService) NewSubscription() *models.Subscription {
+	defaults := s.Get()
+	subscription := &models.Subscription{
+		Status:               "Active",
+		TaxRate:              defaults.TaxRate,
+		RenewalReminder:      defaults.RenewalReminder,
+		CancellationReminder: defaults.CancellationReminder,
+		AutoRenew:            true,
+	}
+	s.apply(subscription, defaults)
+	return subscription
+}
+
+// Apply fills the fields of a new subscription that were left empty with the
+// defaults. Reminder toggles and the tax rate cannot be told apart from an
+// explicit zero value, so callers set them from Get when they were omitted.
+func (s *SubscriptionDefaultsService) Apply(subscription *models.Subscription) {
+	s.apply(subscription, s.Get())
+}
+
+func (s *SubscriptionDefaultsService) apply(subscription *models.Subscription, defaults models.SubscriptionDefaults) {
+	if subscription.Schedule == "" {
+		subscription.Schedule = defaults.Schedule
+	}
+	if subscription.OriginalCurrency == "" {
+		subscription.OriginalCurrency = defaults.Currency
+		if subscription.OriginalCurrency == "" {
+			subscription.OriginalCurrency = s.preferences.GetCurrency()
+		}
+	}
+	if subscription.CategoryID == 0 {
+		subscription.CategoryID = s.defaultCategoryID(defaults)
+	}
+	if subscription.PriceType == "" {
+		subscription.PriceType = defaults.PriceType
+	}
+	if subscription.Purpose == "" {
+		subscription.Purpose = defaults.Purpose
+		if subscription.Purpose == "" {
+			subscription.Purpose = models.PurposePersonal
+		}
+	}
+	if subscription.RenewalReminderDays <= 0 {
+		subscription.RenewalReminderDays = defaults.RenewalReminderDays
+	}
+	if subscription.CancellationReminderDays <= 0 {
+		subscription.CancellationReminderDays = defaults.CancellationReminderDays
+	}
+}
+
+// defaultCategoryID returns the configured default category if it still
+// exists, otherwise the app's default category
+func (s *SubscriptionDefaultsService) defaultCategoryID(defaults models.SubscriptionDefaults) uint {
+	if defaults.CategoryID != 0 {
+		if _, err := s.categories.GetByID(defaults.CategoryID); err == nil {
+			return defaults.CategoryID
+		}
+	}
+	if category, err := s.categories.GetDefault(); err == nil {
+		return category.ID
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDefaultsService(t *testing.T) (*SubscriptionDefaultsService, *CategoryService) {
+	db := setupRenewalReminderTestDB(t)
+	categoryService := NewCategoryService(repository.NewCategoryRepository(db))
+	settingsService := NewSettingsService(repository.NewSettingsRepository(db))
+	preferencesService := NewPreferencesService(settingsService, defaultLangProvider())
+	require.NoError(t, preferencesService.SetCurrency("EUR"))
+	return NewSubscriptionDefaultsService(settingsService, preferencesService, categoryService), categoryService
+}
+
+func TestSubscriptionDefaultsService_Apply(t *testing.T) {
+	defaults, categories := setupDefaultsService(t)
+	software, err := categories.Create(&models.Category{Name: "Software"})
+	require.NoError(t, err)
+
+	// Built-in defaults use the display currency
+	sub := defaults.NewSubscription()
+	assert.Equal(t, "Monthly", sub.Schedule)
+	assert.Equal(t, "EUR", sub.OriginalCurrency)
+	assert.Equal(t, "gross", sub.PriceType)
+	assert.Equal(t, 3, sub.RenewalReminderDays)
+	assert.Equal(t, 7, sub.CancellationReminderDays)
+
+	require.NoError(t, defaults.Save(&models.SubscriptionDefaults{
+		Schedule:                 "Annual",
+		Currency:                 "USD",
+		CategoryID:               software.ID,
+		PriceType:                "net",
+		TaxRate:                  19,
+		RenewalReminder:          true,
+		RenewalReminderDays:      14,
+		CancellationReminderDays: 30,
+	}))
+
+	sub = defaults.NewSubscription()
+	assert.Equal(t, "Annual", sub.Schedule)
+	assert.Equal(t, software.ID, sub.CategoryID)
+	assert.Equal(t, 19.0, sub.TaxRate)
+	assert.True(t, sub.RenewalReminder)
+
+	// Apply only fills what is missing
+	sub = &models.Subscription{Schedule: "Weekly", RenewalReminderDays: 2}
+	defaults.Apply(sub)
+	assert.Equal(t, "Weekly", sub.Schedule)
+	assert.Equal(t, "USD", sub.OriginalCurrency)
+	assert.Equal(t, "net", sub.PriceType)
+	assert.Equal(t, 2, sub.RenewalReminderDays)
+	assert.Equal(t, 30, sub.CancellationReminderDays)
+}
+
+func TestSubscriptionDefaultsService_SaveInvalid(t *testing.T) {
+	defaults, _ := setupDefaultsService(t)
+
+	invalid := []models.SubscriptionDefaults{
+		{Schedule: "Hourly", PriceType: "gross", RenewalReminderDays: 3, CancellationReminderDays: 7},
+		{Schedule: "Monthly", PriceType: "gross", RenewalReminderDays: 0, CancellationReminderDays: 7},
+		{Schedule: "Monthly", PriceType: "gross", TaxRate: 120, RenewalReminderDays: 3, CancellationReminderDays: 7},
+		{Schedule: "Monthly", PriceType: "gross", Currency: "XYZ", RenewalReminderDays: 3, CancellationReminderDays: 7},
+		{Schedule: "Monthly", PriceType: "gross", CategoryID: 999, RenewalReminderDays: 3, CancellationReminderDays: 7},
+	}
+	for _, d := range invalid {
+		assert.ErrorIs(t, defaults.Save(&d), ErrInvalidDefaults)
+	}
+	assert.Equal(t, models.DefaultSubscriptionDefaults(), defaults.Get())
+}
@@ -2,12 +2,22 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"fmt"
+	"html"
 	"html/template"
+	"mime"
+	"mime/multipart"
+	"net"
 	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
 	"subvault/internal/i18n"
 	"subvault/internal/models"
+	"time"
 )
 
 // EmailService handles sending emails via SMTP
@@ -15,6 +25,9 @@ type EmailService struct {
 	preferences PreferencesServiceInterface
 	notifConfig NotificationConfigServiceInterface
 	i18nService *i18n.I18nService
+	// Set on the copies that render a preview instead of sending
+	preview         *RenderedEmail
+	previewLanguage string
 }
 
 // NewEmailService creates a new email service
@@ -29,13 +42,29 @@ func NewEmailService(preferences PreferencesServiceInterface, notifConfig Notifi
 	return svc
 }
 
-// t translates a message ID using the user's language setting
+// Channel returns the notification channel name
+func (e *EmailService) Channel() string {
+	return models.ChannelEmail
+}
+
+// language returns the language of email notifications, the email channel's
+// override or else the app language
+func (e *EmailService) language() string {
+	if e.previewLanguage != "" {
+		return e.previewLanguage
+	}
+	if lang := e.notifConfig.ChannelLanguage(models.ChannelEmail); lang != "" {
+		return lang
+	}
+	return e.preferences.GetLanguage()
+}
+
+// t translates a message ID into the email language
 func (e *EmailService) t(messageID string) string {
 	if e.i18nService == nil {
 		return messageID
 	}
-	lang := e.preferences.GetLanguage()
-	localizer := e.i18nService.NewLocalizer(lang)
+	localizer := e.i18nService.NewLocalizer(e.language())
 	return e.i18nService.T(localizer, messageID)
 }
 
@@ -44,8 +73,7 @@ func (e *EmailService) tData(messageID string, data map[string]interface{}) stri
 	if e.i18nService == nil {
 		return messageID
 	}
-	lang := e.preferences.GetLanguage()
-	localizer := e.i18nService.NewLocalizer(lang)
+	localizer := e.i18nService.NewLocalizer(e.language())
 	return e.i18nService.TData(localizer, messageID, data)
 }
 
@@ -54,153 +82,246 @@ func (e *EmailService) tPlural(messageID string, count int, data map[string]inte
 	if e.i18nService == nil {
 		return messageID
 	}
-	lang := e.preferences.GetLanguage()
-	localizer := e.i18nService.NewLocalizer(lang)
+	localizer := e.i18nService.NewLocalizer(e.language())
 	return e.i18nService.TPluralCount(localizer, messageID, count, data)
 }
 
-// SendEmail sends an email using the configured SMTP settings
-func (e *EmailService) SendEmail(subject, body string) error {
+// templateFuncs formats amounts in email templates with the display rounding:
+// amount for the display currency, amountIn for another currency
+func (e *EmailService) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"amount":   func(v float64) string { return e.preferences.FormatAmount(v, "") },
+		"amountIn": e.preferences.FormatAmount,
+	}
+}
+
+// sendNotification sends a notification email now, or queues it when the
+// email delivery window is closed
+func (e *EmailService) sendNotification(subject, body string) error {
+	if e.preview != nil {
+		*e.preview = RenderedEmail{Subject: subject, Body: body}
+		return nil
+	}
+	if e.notifConfig.DeliveryAllowed(models.ChannelEmail, time.Now()) {
+		return e.SendEmail(context.Background(), subject, body)
+	}
 	config, err := e.notifConfig.GetSMTPConfig()
 	if err != nil {
-		return fmt.Errorf("failed to get SMTP config: %w", err)
+		return fmt.Errorf("%w: failed to get SMTP config: %v", ErrChannelNotConfigured, err)
 	}
-
-	if config.To == "" {
-		return fmt.Errorf("no recipient email configured")
+	if len(models.SplitAddresses(config.To)) == 0 {
+		return fmt.Errorf("%w: no recipient email configured", ErrChannelNotConfigured)
 	}
+	return e.notifConfig.QueueNotification(models.ChannelEmail, subject, body)
+}
 
-	// Determine if this is an implicit TLS port (SMTPS)
-	isSSLPort := config.Port == 465 || config.Port == 8465 || config.Port == 443
+// FlushQueued sends the emails deferred by the delivery window if it is open
+func (e *EmailService) FlushQueued(now time.Time) (int, error) {
+	return e.notifConfig.FlushQueue(models.ChannelEmail, now, func(subject, body string) error {
+		return e.SendEmail(context.Background(), subject, body)
+	})
+}
 
-	var auth smtp.Auth
-	var addr string
+// Attachment is a file attached to an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
 
-	auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
-	addr = fmt.Sprintf("%s:%d", config.Host, config.Port)
+// SendEmail sends an email immediately using the configured SMTP settings,
+// with any attachments. Cancelling ctx aborts the delivery.
+func (e *EmailService) SendEmail(ctx context.Context, subject, body string, attachments ...Attachment) error {
+	config, err := e.notifConfig.GetSMTPConfig()
+	if err != nil {
+		return fmt.Errorf("%w: failed to get SMTP config: %v", ErrChannelNotConfigured, err)
+	}
 
-	if isSSLPort {
-		// Use implicit TLS (direct SSL connection)
-		tlsConfig := &tls.Config{
-			ServerName: config.Host,
-		}
+	if len(models.SplitAddresses(config.To)) == 0 {
+		return fmt.Errorf("%w: no recipient email configured", ErrChannelNotConfigured)
+	}
 
-		conn, err := tls.Dial("tcp", addr, tlsConfig)
-		if err != nil {
-			return fmt.Errorf("failed to connect via SSL: %w", err)
-		}
-		defer conn.Close()
+	WarnSender(ctx, config, false)
+	client, err := DialSMTP(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
 
-		client, err := smtp.NewClient(conn, config.Host)
-		if err != nil {
-			return fmt.Errorf("failed to create SMTP client: %w", err)
-		}
-		defer client.Close()
+	// Authenticate
+	if err = client.Auth(smtp.PlainAuth("", config.Username, config.Password, config.Host)); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
 
-		// Authenticate
-		if err = client.Auth(auth); err != nil {
-			return fmt.Errorf("authentication failed: %w", err)
+	// Set sender and recipient
+	if err = client.Mail(config.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, recipient := range config.Recipients() {
+		if err = client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("failed to set recipient %s: %w", recipient, err)
 		}
+	}
 
-		// Set sender and recipient
-		if err = client.Mail(config.From); err != nil {
-			return fmt.Errorf("failed to set sender: %w", err)
-		}
-		if err = client.Rcpt(config.To); err != nil {
-			return fmt.Errorf("failed to set recipient: %w", err)
-		}
+	// Send email body
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to get data writer: %w", err)
+	}
 
-		// Send email body
-		writer, err := client.Data()
-		if err != nil {
-			return fmt.Errorf("failed to get data writer: %w", err)
-		}
+	_, err = writer.Write([]byte(composeMessage(config, subject, body, attachments...)))
+	if err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	err = writer.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close writer: %w", err)
+	}
 
-		fromName := config.FromName
-		if fromName == "" {
-			fromName = "SubVault"
-		}
+	return nil
+}
 
-		message := fmt.Sprintf("From: %s <%s>\r\n", fromName, config.From)
-		message += fmt.Sprintf("To: %s\r\n", config.To)
-		message += fmt.Sprintf("Subject: %s\r\n", subject)
-		message += "MIME-Version: 1.0\r\n"
-		message += "Content-Type: text/html; charset=UTF-8\r\n"
-		message += "\r\n"
-		message += body
+// smtpTimeout bounds an SMTP session when ctx has no earlier deadline
+const smtpTimeout = 30 * time.Second
 
-		_, err = writer.Write([]byte(message))
-		if err != nil {
-			return fmt.Errorf("failed to write message: %w", err)
-		}
-		err = writer.Close()
+// DialSMTP connects to the configured SMTP server and secures the connection:
+// implicit TLS on the SMTPS ports, STARTTLS otherwise. The connection is closed
+// when ctx is cancelled, which aborts any command in progress.
+func DialSMTP(ctx context.Context, config *models.SMTPConfig) (*smtp.Client, error) {
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	tlsConfig := &tls.Config{
+		ServerName: config.Host,
+	}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	// Determine if this is an implicit TLS port (SMTPS)
+	isSSLPort := config.Port == 465 || config.Port == 8465 || config.Port == 443
+
+	var conn net.Conn
+	var err error
+	if isSSLPort {
+		// Use implicit TLS (direct SSL connection)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
 		if err != nil {
-			return fmt.Errorf("failed to close writer: %w", err)
+			return nil, fmt.Errorf("failed to connect via SSL: %w", err)
 		}
 	} else {
-		// Use STARTTLS (opportunistic TLS)
-		client, err := smtp.Dial(addr)
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
 		if err != nil {
-			return fmt.Errorf("failed to connect: %w", err)
-		}
-		defer client.Close()
-
-		// Upgrade to TLS
-		tlsConfig := &tls.Config{
-			ServerName: config.Host,
-		}
-
-		if err = client.StartTLS(tlsConfig); err != nil {
-			return fmt.Errorf("failed to start TLS: %w", err)
+			return nil, fmt.Errorf("failed to connect: %w", err)
 		}
+	}
 
-		// Authenticate
-		if err = client.Auth(auth); err != nil {
-			return fmt.Errorf("authentication failed: %w", err)
-		}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
 
-		// Set sender and recipient
-		if err = client.Mail(config.From); err != nil {
-			return fmt.Errorf("failed to set sender: %w", err)
-		}
-		if err = client.Rcpt(config.To); err != nil {
-			return fmt.Errorf("failed to set recipient: %w", err)
-		}
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, fmt.Errorf("failed to create SMTP client: %w", err)
+	}
 
-		// Send email body
-		writer, err := client.Data()
-		if err != nil {
-			return fmt.Errorf("failed to get data writer: %w", err)
+	if !isSSLPort {
+		// Upgrade to TLS (opportunistic STARTTLS)
+		if err = client.StartTLS(tlsConfig); err != nil {
+			stop()
+			client.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
 		}
+	}
+	return client, nil
+}
 
-		fromName := config.FromName
-		if fromName == "" {
-			fromName = "SubVault"
-		}
+// composeMessage builds an HTML email, a multipart/mixed one with attachments.
+// Header values are reduced to plain text and MIME encoded, so a subscription
+// name in the subject cannot add headers.
+func composeMessage(config *models.SMTPConfig, subject, body string, attachments ...Attachment) string {
+	fromName := config.FromName
+	if fromName == "" {
+		fromName = "SubVault"
+	}
 
-		message := fmt.Sprintf("From: %s <%s>\r\n", fromName, config.From)
-		message += fmt.Sprintf("To: %s\r\n", config.To)
-		message += fmt.Sprintf("Subject: %s\r\n", subject)
-		message += "MIME-Version: 1.0\r\n"
+	message := fmt.Sprintf("From: %s <%s>\r\n", encodeHeader(fromName), plainText(config.From))
+	message += fmt.Sprintf("To: %s\r\n", addressHeader(config.To))
+	if cc := addressHeader(config.CC); cc != "" {
+		message += fmt.Sprintf("Cc: %s\r\n", cc)
+	}
+	if config.ReplyTo != "" {
+		message += fmt.Sprintf("Reply-To: %s\r\n", plainText(config.ReplyTo))
+	}
+	message += fmt.Sprintf("Subject: %s\r\n", encodeHeader(subject))
+	message += "MIME-Version: 1.0\r\n"
+	if len(attachments) == 0 {
 		message += "Content-Type: text/html; charset=UTF-8\r\n"
 		message += "\r\n"
 		message += body
+		return message
+	}
 
-		_, err = writer.Write([]byte(message))
-		if err != nil {
-			return fmt.Errorf("failed to write message: %w", err)
-		}
-		err = writer.Close()
-		if err != nil {
-			return fmt.Errorf("failed to close writer: %w", err)
+	// Writing to a buffer cannot fail
+	var parts bytes.Buffer
+	writer := multipart.NewWriter(&parts)
+	part, _ := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	part.Write([]byte(body))
+	for _, attachment := range attachments {
+		part, _ = writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": plainText(attachment.Filename)})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
 		}
+		part.Write([]byte(encoded + "\r\n"))
 	}
+	writer.Close()
 
-	return nil
+	message += fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s\r\n", writer.Boundary())
+	message += "\r\n"
+	message += parts.String()
+	return message
+}
+
+// addressHeader formats a comma-separated address list as a header value.
+// BCC recipients are never written to a header.
+func addressHeader(list string) string {
+	addresses := models.SplitAddresses(list)
+	for i, address := range addresses {
+		addresses[i] = plainText(address)
+	}
+	return strings.Join(addresses, ", ")
+}
+
+// CheckSMTPReachable verifies that the configured SMTP server accepts TCP connections.
+// Returns ErrCheckSkipped when SMTP is not configured or in offline mode.
+func (e *EmailService) CheckSMTPReachable(timeout time.Duration) error {
+	config, err := e.notifConfig.GetSMTPConfig()
+	if err != nil || config == nil || config.Host == "" {
+		return fmt.Errorf("smtp not configured: %w", ErrCheckSkipped)
+	}
+
+	if OfflineMode() {
+		return fmt.Errorf("offline mode: %w", ErrCheckSkipped)
+	}
+
+	addr := net.JoinHostPort(config.Host, fmt.Sprintf("%d", config.Port))
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return fmt.Errorf("smtp server %s unreachable: %w", addr, err)
+	}
+	return conn.Close()
 }
 
 // SendHighCostAlert sends an email alert when a high-cost subscription is created
 func (e *EmailService) SendHighCostAlert(subscription *models.Subscription) error {
+	subscription = plainSubscription(subscription)
 	// Get currency symbol
 	currencySymbol := e.preferences.GetCurrencySymbol()
 
@@ -229,8 +350,8 @@ func (e *EmailService) SendHighCostAlert(subscription *models.Subscription) erro
 		<div class="subscription-details">
 			<h3>{{.DetailsTitle}}</h3>
 			<div class="detail-row"><span class="label">{{.LabelName}}</span> {{.Subscription.Name}}</div>
-			<div class="detail-row"><span class="label">{{.LabelCost}}</span> {{.CurrencySymbol}}{{printf "%.2f" .Subscription.Cost}} {{.Subscription.Schedule}}</div>
-			<div class="detail-row"><span class="label">{{.LabelMonthlyCost}}</span> {{.CurrencySymbol}}{{printf "%.2f" (.Subscription.MonthlyCost)}}</div>
+			<div class="detail-row"><span class="label">{{.LabelCost}}</span> {{.CurrencySymbol}}{{amount .Subscription.Cost}} {{.Subscription.Schedule}}</div>
+			<div class="detail-row"><span class="label">{{.LabelMonthlyCost}}</span> {{.CurrencySymbol}}{{amount .Subscription.MonthlyCost}}</div>
 			{{if and .Subscription.Category .Subscription.Category.Name}}<div class="detail-row"><span class="label">{{.LabelCategory}}</span> {{.Subscription.Category.Name}}</div>{{end}}
 			{{if .Subscription.RenewalDate}}<div class="detail-row"><span class="label">{{.LabelNextRenewal}}</span> {{.Subscription.RenewalDate.Format "January 2, 2006"}}</div>{{end}}
 			{{if .Subscription.URL}}<div class="detail-row"><span class="label">{{.LabelURL}}</span> <a href="{{.Subscription.URL}}">{{.Subscription.URL}}</a></div>{{end}}
@@ -278,7 +399,7 @@ func (e *EmailService) SendHighCostAlert(subscription *models.Subscription) erro
 		FooterManage:     e.t("email_footer_manage"),
 	}
 
-	tpl, err := template.New("highCostAlert").Parse(tmpl)
+	tpl, err := template.New("highCostAlert").Funcs(e.templateFuncs()).Parse(tmpl)
 	if err != nil {
 		return fmt.Errorf("failed to parse email template: %w", err)
 	}
@@ -288,12 +409,13 @@ func (e *EmailService) SendHighCostAlert(subscription *models.Subscription) erro
 		return fmt.Errorf("failed to execute email template: %w", err)
 	}
 
-	subject := fmt.Sprintf("%s: %s - %s%.2f/month", e.t("shoutrrr_high_cost_alert"), subscription.Name, currencySymbol, subscription.MonthlyCost())
-	return e.SendEmail(subject, buf.String())
+	subject := fmt.Sprintf("%s: %s - %s%s/month", e.t("shoutrrr_high_cost_alert"), subscription.Name, currencySymbol, e.preferences.FormatAmount(subscription.MonthlyCost(), ""))
+	return e.sendNotification(subject, buf.String())
 }
 
 // SendRenewalReminder sends an email reminder for an upcoming subscription renewal
 func (e *EmailService) SendRenewalReminder(subscription *models.Subscription, daysUntilRenewal int) error {
+	subscription = plainSubscription(subscription)
 	// Get currency symbol
 	currencySymbol := e.preferences.GetCurrencySymbol()
 
@@ -307,6 +429,8 @@ func (e *EmailService) SendRenewalReminder(subscription *models.Subscription, da
 		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
 		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
 		.reminder { background-color: #d1ecf1; border: 1px solid #0c5460; border-radius: 5px; padding: 15px; margin: 20px 0; }
+		.cancel-how { border-left: 4px solid #0d6efd; padding: 10px 15px; margin: 20px 0; }
+		.cancel-how p { margin: 5px 0 0; }
 		.subscription-details { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
 		.detail-row { margin: 10px 0; }
 		.label { font-weight: bold; }
@@ -319,11 +443,15 @@ func (e *EmailService) SendRenewalReminder(subscription *models.Subscription, da
 		<div class="reminder">
 			<strong>` + "\U0001f514" + ` {{.ReminderLabel}}</strong> {{.ReminderText}}
 		</div>
+		{{if or .CancelURL .Subscription.CancelNotes}}<div class="cancel-how">
+			<strong>{{.LabelCancelHow}}</strong>{{if .CancelURL}} <a href="{{.CancelURL}}">{{.CancelURL}}</a>{{end}}
+			{{if .Subscription.CancelNotes}}<p>{{.Subscription.CancelNotes}}</p>{{end}}
+		</div>{{end}}
 		<div class="subscription-details">
 			<h3>{{.DetailsTitle}}</h3>
 			<div class="detail-row"><span class="label">{{.LabelName}}</span> {{.Subscription.Name}}</div>
-			<div class="detail-row"><span class="label">{{.LabelCost}}</span> {{.CurrencySymbol}}{{printf "%.2f" .Subscription.Cost}} {{.Subscription.Schedule}}</div>
-			<div class="detail-row"><span class="label">{{.LabelMonthlyCost}}</span> {{.CurrencySymbol}}{{printf "%.2f" (.Subscription.MonthlyCost)}}</div>
+			<div class="detail-row"><span class="label">{{.LabelCost}}</span> {{.CurrencySymbol}}{{amount .Subscription.Cost}} {{.Subscription.Schedule}}</div>
+			<div class="detail-row"><span class="label">{{.LabelMonthlyCost}}</span> {{.CurrencySymbol}}{{amount .Subscription.MonthlyCost}}</div>
 			{{if and .Subscription.Category .Subscription.Category.Name}}<div class="detail-row"><span class="label">{{.LabelCategory}}</span> {{.Subscription.Category.Name}}</div>{{end}}
 			{{if .Subscription.RenewalDate}}<div class="detail-row"><span class="label">{{.LabelRenewalDate}}</span> {{.Subscription.RenewalDate.Format "January 2, 2006"}}</div>{{end}}
 			{{if .Subscription.URL}}<div class="detail-row"><span class="label">{{.LabelURL}}</span> <a href="{{.Subscription.URL}}">{{.Subscription.URL}}</a></div>{{end}}
@@ -353,6 +481,8 @@ func (e *EmailService) SendRenewalReminder(subscription *models.Subscription, da
 		LabelCategory    string
 		LabelRenewalDate string
 		LabelURL         string
+		LabelCancelHow   string
+		CancelURL        string
 		FooterAuto       string
 		FooterManage     string
 	}
@@ -371,11 +501,13 @@ func (e *EmailService) SendRenewalReminder(subscription *models.Subscription, da
 		LabelCategory:    e.t("email_category"),
 		LabelRenewalDate: e.t("email_renewal_date"),
 		LabelURL:         e.t("email_url"),
+		LabelCancelHow:   e.t("email_cancel_how"),
+		CancelURL:        CancelURL(subscription),
 		FooterAuto:       e.t("email_footer_auto"),
 		FooterManage:     e.t("email_footer_manage"),
 	}
 
-	tpl, err := template.New("renewalReminder").Parse(tmpl)
+	tpl, err := template.New("renewalReminder").Funcs(e.templateFuncs()).Parse(tmpl)
 	if err != nil {
 		return fmt.Errorf("failed to parse email template: %w", err)
 	}
@@ -386,11 +518,12 @@ func (e *EmailService) SendRenewalReminder(subscription *models.Subscription, da
 	}
 
 	subject := fmt.Sprintf("%s: %s", e.t("shoutrrr_renewal_reminder"), reminderText)
-	return e.SendEmail(subject, buf.String())
+	return e.sendNotification(subject, buf.String())
 }
 
 // SendCancellationReminder sends an email reminder for an upcoming subscription cancellation
 func (e *EmailService) SendCancellationReminder(subscription *models.Subscription, daysUntilCancellation int) error {
+	subscription = plainSubscription(subscription)
 	// Get currency symbol
 	currencySymbol := e.preferences.GetCurrencySymbol()
 
@@ -404,6 +537,8 @@ func (e *EmailService) SendCancellationReminder(subscription *models.Subscriptio
 		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
 		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
 		.reminder { background-color: #fff3cd; border: 1px solid #856404; border-radius: 5px; padding: 15px; margin: 20px 0; }
+		.cancel-how { border-left: 4px solid #0d6efd; padding: 10px 15px; margin: 20px 0; }
+		.cancel-how p { margin: 5px 0 0; }
 		.subscription-details { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
 		.detail-row { margin: 10px 0; }
 		.label { font-weight: bold; }
@@ -416,11 +551,15 @@ func (e *EmailService) SendCancellationReminder(subscription *models.Subscriptio
 		<div class="reminder">
 			<strong>` + "\u26a0\ufe0f" + ` {{.ReminderLabel}}</strong> {{.ReminderText}}
 		</div>
+		{{if or .CancelURL .Subscription.CancelNotes}}<div class="cancel-how">
+			<strong>{{.LabelCancelHow}}</strong>{{if .CancelURL}} <a href="{{.CancelURL}}">{{.CancelURL}}</a>{{end}}
+			{{if .Subscription.CancelNotes}}<p>{{.Subscription.CancelNotes}}</p>{{end}}
+		</div>{{end}}
 		<div class="subscription-details">
 			<h3>{{.DetailsTitle}}</h3>
 			<div class="detail-row"><span class="label">{{.LabelName}}</span> {{.Subscription.Name}}</div>
-			<div class="detail-row"><span class="label">{{.LabelCost}}</span> {{.CurrencySymbol}}{{printf "%.2f" .Subscription.Cost}} {{.Subscription.Schedule}}</div>
-			<div class="detail-row"><span class="label">{{.LabelMonthlyCost}}</span> {{.CurrencySymbol}}{{printf "%.2f" (.Subscription.MonthlyCost)}}</div>
+			<div class="detail-row"><span class="label">{{.LabelCost}}</span> {{.CurrencySymbol}}{{amount .Subscription.Cost}} {{.Subscription.Schedule}}</div>
+			<div class="detail-row"><span class="label">{{.LabelMonthlyCost}}</span> {{.CurrencySymbol}}{{amount .Subscription.MonthlyCost}}</div>
 			{{if and .Subscription.Category .Subscription.Category.Name}}<div class="detail-row"><span class="label">{{.LabelCategory}}</span> {{.Subscription.Category.Name}}</div>{{end}}
 			{{if .Subscription.CancellationDate}}<div class="detail-row"><span class="label">{{.LabelCancellationDate}}</span> {{.Subscription.CancellationDate.Format "January 2, 2006"}}</div>{{end}}
 			{{if .Subscription.URL}}<div class="detail-row"><span class="label">{{.LabelURL}}</span> <a href="{{.Subscription.URL}}">{{.Subscription.URL}}</a></div>{{end}}
@@ -450,6 +589,8 @@ func (e *EmailService) SendCancellationReminder(subscription *models.Subscriptio
 		LabelCategory         string
 		LabelCancellationDate string
 		LabelURL              string
+		LabelCancelHow        string
+		CancelURL             string
 		FooterAuto            string
 		FooterManage          string
 	}
@@ -468,11 +609,13 @@ func (e *EmailService) SendCancellationReminder(subscription *models.Subscriptio
 		LabelCategory:         e.t("email_category"),
 		LabelCancellationDate: e.t("email_cancellation_date"),
 		LabelURL:              e.t("email_url"),
+		LabelCancelHow:        e.t("email_cancel_how"),
+		CancelURL:             CancelURL(subscription),
 		FooterAuto:            e.t("email_footer_auto"),
 		FooterManage:          e.t("email_footer_manage"),
 	}
 
-	tpl, err := template.New("cancellationReminder").Parse(tmpl)
+	tpl, err := template.New("cancellationReminder").Funcs(e.templateFuncs()).Parse(tmpl)
 	if err != nil {
 		return fmt.Errorf("failed to parse email template: %w", err)
 	}
@@ -483,30 +626,964 @@ func (e *EmailService) SendCancellationReminder(subscription *models.Subscriptio
 	}
 
 	subject := fmt.Sprintf("%s: %s", e.t("shoutrrr_cancellation_reminder"), reminderText)
-	return e.SendEmail(subject, buf.String())
+	return e.sendNotification(subject, buf.String())
 }
 
-func (e *EmailService) SendBudgetExceededAlert(totalSpend, budget float64, currencySymbol string) error {
-	config, err := e.notifConfig.GetSMTPConfig()
-	if err != nil || config == nil || config.Host == "" {
-		return nil
+// SendGracePeriodReminder sends an email reminder that the service of a subscription with a failed
+// payment will be cut off
+func (e *EmailService) SendGracePeriodReminder(subscription *models.Subscription, daysUntilCutoff int) error {
+	subscription = plainSubscription(subscription)
+	currencySymbol := e.preferences.GetCurrencySymbol()
+
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.reminder { background-color: #f8d7da; border: 1px solid #721c24; border-radius: 5px; padding: 15px; margin: 20px 0; }
+		.subscription-details { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
+		.detail-row { margin: 10px 0; }
+		.label { font-weight: bold; }
+		.footer { margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>{{.Title}}</h2>
+		<div class="reminder">
+			<strong>` + "\u26a0\ufe0f" + `</strong> {{.ReminderText}}
+		</div>
+		<div class="subscription-details">
+			<h3>{{.DetailsTitle}}</h3>
+			<div class="detail-row"><span class="label">{{.LabelName}}</span> {{.Subscription.Name}}</div>
+			<div class="detail-row"><span class="label">{{.LabelCost}}</span> {{.CurrencySymbol}}{{amount .Subscription.Cost}} {{.Subscription.Schedule}}</div>
+			{{if .Subscription.PaymentMethod}}<div class="detail-row"><span class="label">{{.LabelPaymentMethod}}</span> {{.Subscription.PaymentMethod}}</div>{{end}}
+			{{if .Subscription.PaymentRetryDate}}<div class="detail-row"><span class="label">{{.LabelRetryDate}}</span> {{.Subscription.PaymentRetryDate.Format "January 2, 2006"}}</div>{{end}}
+			<div class="detail-row"><span class="label">{{.LabelCutoffDate}}</span> {{.Subscription.GracePeriodEnd.Format "January 2, 2006"}}</div>
+			{{if .Subscription.URL}}<div class="detail-row"><span class="label">{{.LabelURL}}</span> <a href="{{.Subscription.URL}}">{{.Subscription.URL}}</a></div>{{end}}
+		</div>
+		<p>{{.Hint}}</p>
+		<div class="footer">
+			<p>{{.FooterAuto}}</p>
+			<p>{{.FooterManage}}</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+	reminderText := e.tPlural("email_grace_period_reminder", daysUntilCutoff, map[string]interface{}{"Name": subscription.Name})
+
+	data := struct {
+		Subscription       *models.Subscription
+		CurrencySymbol     string
+		Title              string
+		ReminderText       string
+		DetailsTitle       string
+		LabelName          string
+		LabelCost          string
+		LabelPaymentMethod string
+		LabelRetryDate     string
+		LabelCutoffDate    string
+		LabelURL           string
+		Hint               string
+		FooterAuto         string
+		FooterManage       string
+	}{
+		Subscription:       subscription,
+		CurrencySymbol:     currencySymbol,
+		Title:              e.t("email_grace_period_title"),
+		ReminderText:       reminderText,
+		DetailsTitle:       e.t("email_sub_details"),
+		LabelName:          e.t("email_name"),
+		LabelCost:          e.t("email_cost"),
+		LabelPaymentMethod: e.t("email_payment_method"),
+		LabelRetryDate:     e.t("email_payment_retry_date"),
+		LabelCutoffDate:    e.t("email_grace_period_end"),
+		LabelURL:           e.t("email_url"),
+		Hint:               e.t("email_grace_period_hint"),
+		FooterAuto:         e.t("email_footer_auto"),
+		FooterManage:       e.t("email_footer_manage"),
 	}
 
-	subject := e.t("email_budget_exceeded_subject")
+	tpl, err := template.New("gracePeriodReminder").Funcs(e.templateFuncs()).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
 
-	body := fmt.Sprintf(`<html><body style="font-family: Arial, sans-serif; padding: 20px;">
-<h2>%s</h2>
-<p>%s</p>
-<p><strong>%s:</strong> %s%.2f</p>
-<p><strong>%s:</strong> %s%.2f</p>
-<p style="color: #dc2626;">%s: %s%.2f</p>
-</body></html>`,
-		e.t("email_budget_exceeded_subject"),
-		e.t("budget_exceeded_alert"),
-		e.t("dashboard_budget"), currencySymbol, budget,
-		e.t("analytics_monthly_cost"), currencySymbol, totalSpend,
-		e.t("dashboard_budget_exceeded"), currencySymbol, totalSpend-budget,
-	)
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s: %s", e.t("shoutrrr_grace_period_reminder"), reminderText)
+	return e.sendNotification(subject, buf.String())
+}
+
+// SendContractReminder sends an email reminder that the decision deadline of a contract is near:
+// the last day to cancel an auto-renewing contract, or the end of a fixed term
+func (e *EmailService) SendContractReminder(subscription *models.Subscription, daysUntilDeadline int) error {
+	subscription = plainSubscription(subscription)
+	currencySymbol := e.preferences.GetCurrencySymbol()
+	now := time.Now()
+
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.reminder { background-color: #fff3cd; border: 1px solid #856404; border-radius: 5px; padding: 15px; margin: 20px 0; }
+		.subscription-details { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
+		.detail-row { margin: 10px 0; }
+		.label { font-weight: bold; }
+		.footer { margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>{{.Title}}</h2>
+		<div class="reminder">
+			<strong>` + "\U0001f4dd" + `</strong> {{.ReminderText}}
+		</div>
+		<div class="subscription-details">
+			<h3>{{.DetailsTitle}}</h3>
+			<div class="detail-row"><span class="label">{{.LabelName}}</span> {{.Subscription.Name}}</div>
+			<div class="detail-row"><span class="label">{{.LabelCost}}</span> {{.CurrencySymbol}}{{amount .Subscription.Cost}} {{.Subscription.Schedule}}</div>
+			{{if .Subscription.ContractNumber}}<div class="detail-row"><span class="label">{{.LabelContractNumber}}</span> {{.Subscription.ContractNumber}}</div>{{end}}
+			{{if .DecideBy}}<div class="detail-row"><span class="label">{{.LabelDecideBy}}</span> {{.DecideBy.Format "January 2, 2006"}}</div>{{end}}
+			{{if .ExitDate}}<div class="detail-row"><span class="label">{{.LabelExitDate}}</span> {{.ExitDate.Format "January 2, 2006"}}</div>{{end}}
+			{{if .Subscription.URL}}<div class="detail-row"><span class="label">{{.LabelURL}}</span> <a href="{{.Subscription.URL}}">{{.Subscription.URL}}</a></div>{{end}}
+		</div>
+		<p>{{.Hint}}</p>
+		<div class="footer">
+			<p>{{.FooterAuto}}</p>
+			<p>{{.FooterManage}}</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+	reminderText := e.tPlural(contractReminderKey(subscription), daysUntilDeadline, map[string]interface{}{"Name": subscription.Name})
+
+	data := struct {
+		Subscription        *models.Subscription
+		CurrencySymbol      string
+		DecideBy            *time.Time
+		ExitDate            *time.Time
+		Title               string
+		ReminderText        string
+		DetailsTitle        string
+		LabelName           string
+		LabelCost           string
+		LabelContractNumber string
+		LabelDecideBy       string
+		LabelExitDate       string
+		LabelURL            string
+		Hint                string
+		FooterAuto          string
+		FooterManage        string
+	}{
+		Subscription:        subscription,
+		CurrencySymbol:      currencySymbol,
+		DecideBy:            subscription.ContractDecideBy(now),
+		ExitDate:            subscription.EarliestExitDate(now),
+		Title:               e.t("email_contract_title"),
+		ReminderText:        reminderText,
+		DetailsTitle:        e.t("email_sub_details"),
+		LabelName:           e.t("email_name"),
+		LabelCost:           e.t("email_cost"),
+		LabelContractNumber: e.t("email_contract_number"),
+		LabelDecideBy:       e.t("email_contract_decide_by"),
+		LabelExitDate:       e.t("email_contract_exit_date"),
+		LabelURL:            e.t("email_url"),
+		Hint:                e.t("email_contract_hint"),
+		FooterAuto:          e.t("email_footer_auto"),
+		FooterManage:        e.t("email_footer_manage"),
+	}
+
+	tpl, err := template.New("contractReminder").Funcs(e.templateFuncs()).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s: %s", e.t("shoutrrr_contract_reminder"), reminderText)
+	return e.sendNotification(subject, buf.String())
+}
+
+// SendPaidThroughReminder sends an email reminder that the paid period of a cancelled
+// subscription ends soon, so data can be exported and payment details removed in time
+func (e *EmailService) SendPaidThroughReminder(subscription *models.Subscription, daysUntilEnd int) error {
+	subscription = plainSubscription(subscription)
+
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.reminder { background-color: #fff3cd; border: 1px solid #856404; border-radius: 5px; padding: 15px; margin: 20px 0; }
+		.subscription-details { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
+		.detail-row { margin: 10px 0; }
+		.label { font-weight: bold; }
+		.footer { margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>{{.Title}}</h2>
+		<div class="reminder">
+			<strong>` + "\U0001f6aa" + `</strong> {{.ReminderText}}
+		</div>
+		<div class="subscription-details">
+			<h3>{{.DetailsTitle}}</h3>
+			<div class="detail-row"><span class="label">{{.LabelName}}</span> {{.Subscription.Name}}</div>
+			{{if .Subscription.PaidThroughDate}}<div class="detail-row"><span class="label">{{.LabelPaidThrough}}</span> {{.Subscription.PaidThroughDate.Format "January 2, 2006"}}</div>{{end}}
+			{{if .Subscription.PaymentMethod}}<div class="detail-row"><span class="label">{{.LabelPaymentMethod}}</span> {{.Subscription.PaymentMethod}}</div>{{end}}
+			{{if .Subscription.URL}}<div class="detail-row"><span class="label">{{.LabelURL}}</span> <a href="{{.Subscription.URL}}">{{.Subscription.URL}}</a></div>{{end}}
+		</div>
+		<p>{{.Hint}}</p>
+		<div class="footer">
+			<p>{{.FooterAuto}}</p>
+			<p>{{.FooterManage}}</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+	reminderText := e.tPlural("email_paid_through_reminder", daysUntilEnd, map[string]interface{}{"Name": subscription.Name})
+
+	data := struct {
+		Subscription       *models.Subscription
+		Title              string
+		ReminderText       string
+		DetailsTitle       string
+		LabelName          string
+		LabelPaidThrough   string
+		LabelPaymentMethod string
+		LabelURL           string
+		Hint               string
+		FooterAuto         string
+		FooterManage       string
+	}{
+		Subscription:       subscription,
+		Title:              e.t("email_paid_through_title"),
+		ReminderText:       reminderText,
+		DetailsTitle:       e.t("email_sub_details"),
+		LabelName:          e.t("email_name"),
+		LabelPaidThrough:   e.t("email_paid_through_date"),
+		LabelPaymentMethod: e.t("email_payment_method"),
+		LabelURL:           e.t("email_url"),
+		Hint:               e.t("email_paid_through_hint"),
+		FooterAuto:         e.t("email_footer_auto"),
+		FooterManage:       e.t("email_footer_manage"),
+	}
+
+	tpl, err := template.New("paidThroughReminder").Funcs(e.templateFuncs()).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s: %s", e.t("shoutrrr_paid_through_reminder"), reminderText)
+	return e.sendNotification(subject, buf.String())
+}
+
+// SendPromoEndReminder sends an email reminder that the promotional price of a
+// subscription ends soon and the regular cost applies from then on
+func (e *EmailService) SendPromoEndReminder(subscription *models.Subscription, daysUntilEnd int) error {
+	subscription = plainSubscription(subscription)
+	currencySymbol := CurrencySymbolForCode(subscription.OriginalCurrency)
+
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.reminder { background-color: #fff3cd; border: 1px solid #856404; border-radius: 5px; padding: 15px; margin: 20px 0; }
+		.subscription-details { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
+		.detail-row { margin: 10px 0; }
+		.label { font-weight: bold; }
+		.footer { margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>{{.Title}}</h2>
+		<div class="reminder">
+			<strong>` + "\U0001f4c8" + `</strong> {{.ReminderText}}
+		</div>
+		<div class="subscription-details">
+			<h3>{{.DetailsTitle}}</h3>
+			<div class="detail-row"><span class="label">{{.LabelName}}</span> {{.Subscription.Name}}</div>
+			{{if .Subscription.PromoCost}}<div class="detail-row"><span class="label">{{.LabelPromoCost}}</span> {{.CurrencySymbol}}{{amountIn .Subscription.PromoCost .Subscription.OriginalCurrency}}</div>{{end}}
+			<div class="detail-row"><span class="label">{{.LabelRegularCost}}</span> {{.CurrencySymbol}}{{amountIn .Subscription.Cost .Subscription.OriginalCurrency}} {{.Subscription.Schedule}}</div>
+			{{if .Subscription.PromoEndDate}}<div class="detail-row"><span class="label">{{.LabelPromoEnd}}</span> {{.Subscription.PromoEndDate.Format "January 2, 2006"}}</div>{{end}}
+			{{if .Subscription.URL}}<div class="detail-row"><span class="label">{{.LabelURL}}</span> <a href="{{.Subscription.URL}}">{{.Subscription.URL}}</a></div>{{end}}
+		</div>
+		<p>{{.Hint}}</p>
+		<div class="footer">
+			<p>{{.FooterAuto}}</p>
+			<p>{{.FooterManage}}</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+	reminderText := e.tPlural("email_promo_end_reminder", daysUntilEnd, map[string]interface{}{"Name": subscription.Name})
+
+	data := struct {
+		Subscription     *models.Subscription
+		CurrencySymbol   string
+		Title            string
+		ReminderText     string
+		DetailsTitle     string
+		LabelName        string
+		LabelPromoCost   string
+		LabelRegularCost string
+		LabelPromoEnd    string
+		LabelURL         string
+		Hint             string
+		FooterAuto       string
+		FooterManage     string
+	}{
+		Subscription:     subscription,
+		CurrencySymbol:   currencySymbol,
+		Title:            e.t("email_promo_end_title"),
+		ReminderText:     reminderText,
+		DetailsTitle:     e.t("email_sub_details"),
+		LabelName:        e.t("email_name"),
+		LabelPromoCost:   e.t("email_promo_cost"),
+		LabelRegularCost: e.t("email_regular_cost"),
+		LabelPromoEnd:    e.t("email_promo_end_date"),
+		LabelURL:         e.t("email_url"),
+		Hint:             e.t("email_promo_end_hint"),
+		FooterAuto:       e.t("email_footer_auto"),
+		FooterManage:     e.t("email_footer_manage"),
+	}
+
+	tpl, err := template.New("promoEndReminder").Funcs(e.templateFuncs()).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s: %s", e.t("shoutrrr_promo_end_reminder"), reminderText)
+	return e.sendNotification(subject, buf.String())
+}
+
+// contractReminderKey returns the reminder text for an auto-renewing or a
+// fixed-term contract
+func contractReminderKey(subscription *models.Subscription) string {
+	if subscription.AutoRenew {
+		return "email_contract_reminder_auto_renew"
+	}
+	return "email_contract_reminder_fixed_term"
+}
+
+// SendBudgetExceededAlert notifies that the spend of period (monthly or annual) exceeds its budget
+func (e *EmailService) SendBudgetExceededAlert(period string, totalSpend, budget float64, currencySymbol string) error {
+	if e.preview == nil {
+		config, err := e.notifConfig.GetSMTPConfig()
+		if err != nil || config == nil || config.Host == "" {
+			return nil
+		}
+	}
+
+	subject := e.t("email_budget_exceeded_subject")
+	alert, budgetLabel, spendLabel := budgetAlertKeys(period)
+
+	body := fmt.Sprintf(`<html><body style="font-family: Arial, sans-serif; padding: 20px;">
+<h2>%s</h2>
+<p>%s</p>
+<p><strong>%s:</strong> %s%s</p>
+<p><strong>%s:</strong> %s%s</p>
+<p style="color: #dc2626;">%s: %s%s</p>
+</body></html>`,
+		html.EscapeString(e.t("email_budget_exceeded_subject")),
+		html.EscapeString(e.t(alert)),
+		html.EscapeString(e.t(budgetLabel)), html.EscapeString(currencySymbol), e.preferences.FormatAmount(budget, ""),
+		html.EscapeString(e.t(spendLabel)), html.EscapeString(currencySymbol), e.preferences.FormatAmount(totalSpend, ""),
+		html.EscapeString(e.t("dashboard_budget_exceeded")), html.EscapeString(currencySymbol), e.preferences.FormatAmount(totalSpend-budget, ""),
+	)
+
+	return e.sendNotification(subject, body)
+}
+
+// SendUnusedSubscriptionsNudge sends the monthly summary of rarely used subscriptions
+func (e *EmailService) SendUnusedSubscriptionsNudge(nudge *UnusedNudge) error {
+	currencySymbol := e.preferences.GetCurrencySymbol()
+
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.subscription-details { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
+		.detail-row { margin: 10px 0; display: flex; justify-content: space-between; }
+		.savings { background-color: #d1fae5; border: 1px solid #10b981; border-radius: 5px; padding: 15px; margin: 20px 0; }
+		.muted { color: #666; font-size: 13px; }
+		.footer { margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>{{.Title}}</h2>
+		<p>{{.Intro}}</p>
+		<div class="subscription-details">
+			{{range .Nudge.Subscriptions}}
+			<div class="detail-row">
+				<span><strong>{{.Name}}</strong>{{if .LastUsed}} <span class="muted">({{$.LabelLastUsed}} {{.LastUsed.Format "January 2, 2006"}})</span>{{end}}</span>
+				<span>{{$.CurrencySymbol}}{{amount .MonthlyCost}}</span>
+			</div>
+			{{end}}
+		</div>
+		<div class="savings">
+			<strong>{{.LabelSavings}}</strong> {{.CurrencySymbol}}{{amount .Nudge.MonthlySavings}} / {{.LabelMonth}}
+		</div>
+		<div class="footer">
+			<p>{{.FooterAuto}}</p>
+			<p>{{.FooterManage}}</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+	data := struct {
+		Nudge          *UnusedNudge
+		CurrencySymbol string
+		Title          string
+		Intro          string
+		LabelLastUsed  string
+		LabelSavings   string
+		LabelMonth     string
+		FooterAuto     string
+		FooterManage   string
+	}{
+		Nudge:          nudge,
+		CurrencySymbol: currencySymbol,
+		Title:          e.t("email_unused_title"),
+		Intro:          e.t("email_unused_intro"),
+		LabelLastUsed:  e.t("usage_last_used") + ":",
+		LabelSavings:   e.t("email_unused_savings"),
+		LabelMonth:     e.t("usage_per_month_short"),
+		FooterAuto:     e.t("email_footer_auto"),
+		FooterManage:   e.t("email_footer_manage"),
+	}
+
+	tpl, err := template.New("unusedNudge").Funcs(e.templateFuncs()).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s: %s%s%s", e.t("email_unused_title"), currencySymbol, e.preferences.FormatAmount(nudge.MonthlySavings, ""), e.t("email_unused_per_month"))
+	return e.sendNotification(subject, buf.String())
+}
+
+// SendExchangeRateAlert sends the currencies that moved beyond the alert threshold
+// together with the new effective monthly cost of the affected subscriptions
+func (e *EmailService) SendExchangeRateAlert(alert *RateAlert) error {
+	currencySymbol := CurrencySymbolForCode(alert.Currency)
+
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.subscription-details { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
+		.detail-row { margin: 10px 0; display: flex; justify-content: space-between; }
+		.muted { color: #666; font-size: 13px; }
+		.footer { margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>{{.Title}}</h2>
+		<p>{{.Intro}}</p>
+		{{range .Alert.Changes}}
+		{{$currency := .Currency}}
+		<div class="subscription-details">
+			<h3>{{.Currency}} &rarr; {{$.Alert.Currency}}: {{printf "%+.1f" .ChangePercent}}%</h3>
+			<p class="muted">{{printf "%.4f" .OldRate}} &rarr; {{printf "%.4f" .NewRate}}</p>
+			{{range .Subscriptions}}
+			<div class="detail-row">
+				<span><strong>{{.Name}}</strong> <span class="muted">({{amountIn .MonthlyCost $currency}} {{$.LabelPerMonth}})</span></span>
+				<span>{{$.CurrencySymbol}}{{amountIn .OldMonthlyCost $.Alert.Currency}} &rarr; <strong>{{$.CurrencySymbol}}{{amountIn .NewMonthlyCost $.Alert.Currency}}</strong></span>
+			</div>
+			{{end}}
+		</div>
+		{{end}}
+		<div class="footer">
+			<p>{{.FooterAuto}}</p>
+			<p>{{.FooterManage}}</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+	data := struct {
+		Alert          *RateAlert
+		CurrencySymbol string
+		Title          string
+		Intro          string
+		LabelPerMonth  string
+		FooterAuto     string
+		FooterManage   string
+	}{
+		Alert:          alert,
+		CurrencySymbol: currencySymbol,
+		Title:          e.t("email_rate_alert_title"),
+		Intro:          e.t("email_rate_alert_intro"),
+		LabelPerMonth:  "/ " + e.t("usage_per_month_short"),
+		FooterAuto:     e.t("email_footer_auto"),
+		FooterManage:   e.t("email_footer_manage"),
+	}
+
+	tpl, err := template.New("rateAlert").Funcs(e.templateFuncs()).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	currencies := make([]string, 0, len(alert.Changes))
+	for _, change := range alert.Changes {
+		currencies = append(currencies, fmt.Sprintf("%s %+.1f%%", change.Currency, change.ChangePercent))
+	}
+	subject := fmt.Sprintf("%s: %s", e.t("email_rate_alert_title"), strings.Join(currencies, ", "))
+	return e.sendNotification(subject, buf.String())
+}
+
+// SendSettlementReport sends the monthly shared expense settlement
+func (e *EmailService) SendSettlementReport(report *SettlementReport) error {
+	currencySymbol := e.preferences.GetCurrencySymbol()
+
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.person { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
+		.detail-row { margin: 6px 0; display: flex; justify-content: space-between; }
+		.total { font-weight: bold; border-top: 1px solid #ddd; padding-top: 6px; }
+		.footer { margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>{{.Title}} ({{.Report.Month}})</h2>
+		{{range .Report.People}}
+		<div class="person">
+			<h3>{{.Person}}</h3>
+			{{range .Items}}
+			<div class="detail-row"><span>{{.Name}}</span><span>{{$.CurrencySymbol}}{{amount .Amount}}</span></div>
+			{{end}}
+			<div class="detail-row total"><span>{{$.LabelTotal}}</span><span>{{$.CurrencySymbol}}{{amount .Total}}</span></div>
+		</div>
+		{{end}}
+		<div class="footer">
+			<p>{{.FooterAuto}}</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+	data := struct {
+		Report         *SettlementReport
+		CurrencySymbol string
+		Title          string
+		LabelTotal     string
+		FooterAuto     string
+	}{
+		Report:         report,
+		CurrencySymbol: currencySymbol,
+		Title:          e.t("split_settlement_title"),
+		LabelTotal:     e.t("split_total"),
+		FooterAuto:     e.t("email_footer_auto"),
+	}
+
+	tpl, err := template.New("settlementReport").Funcs(e.templateFuncs()).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s %s: %s%s", e.t("split_settlement_title"), report.Month, currencySymbol, e.preferences.FormatAmount(report.Total, ""))
+	return e.sendNotification(subject, buf.String())
+}
+
+// SendRenewalConfirmations asks to confirm the charges of renewals that just
+// passed, listing the expected amount of each
+func (e *EmailService) SendRenewalConfirmations(payments []models.Payment) error {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.subscription-details { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
+		.detail-row { margin: 10px 0; display: flex; justify-content: space-between; }
+		.muted { color: #666; font-size: 13px; }
+		.footer { margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>{{.Title}}</h2>
+		<p>{{.Intro}}</p>
+		<div class="subscription-details">
+			{{range .Renewals}}
+			<div class="detail-row">
+				<span><strong>{{.Name}}</strong> <span class="muted">({{.DueDate.Format "January 2, 2006"}})</span></span>
+				<span>{{.Symbol}}{{amountIn .Amount .Currency}}</span>
+			</div>
+			{{end}}
+		</div>
+		<p>{{.Hint}}</p>
+		<div class="footer">
+			<p>{{.FooterAuto}}</p>
+			<p>{{.FooterManage}}</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+	type renewal struct {
+		models.Payment
+		Symbol string
+	}
+	renewals := make([]renewal, len(payments))
+	for i, p := range payments {
+		renewals[i] = renewal{Payment: p, Symbol: CurrencySymbolForCode(p.Currency)}
+	}
+
+	data := struct {
+		Renewals     []renewal
+		Title        string
+		Intro        string
+		Hint         string
+		FooterAuto   string
+		FooterManage string
+	}{
+		Renewals:     renewals,
+		Title:        e.t("email_renewal_confirm_title"),
+		Intro:        e.t("email_renewal_confirm_intro"),
+		Hint:         e.t("email_renewal_confirm_hint"),
+		FooterAuto:   e.t("email_footer_auto"),
+		FooterManage: e.t("email_footer_manage"),
+	}
+
+	tpl, err := template.New("renewalConfirmations").Funcs(e.templateFuncs()).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	names := make([]string, len(payments))
+	for i, p := range payments {
+		names[i] = p.Name
+	}
+	subject := fmt.Sprintf("%s: %s", e.t("email_renewal_confirm_title"), strings.Join(names, ", "))
+	return e.sendNotification(subject, buf.String())
+}
+
+// SendLoginAlert notifies of a successful login from a new IP or browser
+func (e *EmailService) SendLoginAlert(event *models.LoginEvent) error {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.alert { background-color: #fff3cd; border-left: 4px solid #ffc107; padding: 15px; margin: 20px 0; }
+		.subscription-details { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
+		.detail-row { margin: 10px 0; }
+		.label { font-weight: bold; color: #666; }
+		.footer { margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>{{.Title}}</h2>
+		<div class="alert">{{.Intro}}</div>
+		<div class="subscription-details">
+			<div class="detail-row"><span class="label">{{.LabelUsername}}</span> {{.Event.Username}}</div>
+			<div class="detail-row"><span class="label">{{.LabelTime}}</span> {{.Event.CreatedAt.Format "January 2, 2006 15:04 MST"}}</div>
+			<div class="detail-row"><span class="label">{{.LabelIP}}</span> {{.Event.IP}}</div>
+			<div class="detail-row"><span class="label">{{.LabelDevice}}</span> {{.Event.UserAgent}}</div>
+		</div>
+		<p>{{.Hint}}</p>
+		<div class="footer">
+			<p>{{.FooterAuto}}</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+	data := struct {
+		Event         *models.LoginEvent
+		Title         string
+		Intro         string
+		LabelUsername string
+		LabelTime     string
+		LabelIP       string
+		LabelDevice   string
+		Hint          string
+		FooterAuto    string
+	}{
+		Event:         event,
+		Title:         e.t("email_login_alert_title"),
+		Intro:         e.t("email_login_alert_intro"),
+		LabelUsername: e.t("login_history_username"),
+		LabelTime:     e.t("login_history_time"),
+		LabelIP:       e.t("login_history_ip"),
+		LabelDevice:   e.t("login_history_device"),
+		Hint:          e.t("email_login_alert_hint"),
+		FooterAuto:    e.t("email_footer_auto"),
+	}
+
+	tpl, err := template.New("loginAlert").Funcs(e.templateFuncs()).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s: %s", e.t("email_login_alert_title"), event.IP)
+	return e.sendNotification(subject, buf.String())
+}
+
+// SendWeeklySummary sends the weekly summary of subscription changes, the
+// coming week's renewals and the budget status
+func (e *EmailService) SendWeeklySummary(summary *WeeklySummary) error {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.subscription-details { background-color: #f8f9fa; padding: 15px; border-radius: 5px; margin: 20px 0; }
+		.detail-row { margin: 10px 0; display: flex; justify-content: space-between; }
+		.over { color: #dc2626; font-weight: bold; }
+		.muted { color: #666; font-size: 13px; }
+		.footer { margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>{{t "email_weekly_summary_title"}}</h2>
+		<p class="muted">{{.Summary.Since.Format "January 2, 2006"}} – {{.Summary.Until.Format "January 2, 2006"}}</p>
+		{{if not .Summary.HasChanges}}<p>{{t "email_weekly_summary_no_changes"}}</p>{{end}}
+		{{range .Sections}}{{if .Items}}
+		<h3>{{.Title}}</h3>
+		<div class="subscription-details">
+			{{range .Items}}
+			<div class="detail-row">
+				<span><strong>{{.Name}}</strong></span>
+				<span>{{symbol .Currency}}{{amountIn .Cost .Currency}} <span class="muted">{{schedule .Schedule}}</span></span>
+			</div>
+			{{end}}
+		</div>
+		{{end}}{{end}}
+		{{if .Summary.PriceChanges}}
+		<h3>{{t "email_weekly_summary_price_changes"}}</h3>
+		<div class="subscription-details">
+			{{range .Summary.PriceChanges}}
+			<div class="detail-row">
+				<span><strong>{{.Name}}</strong></span>
+				<span>{{symbol .OldCurrency}}{{amountIn .OldCost .OldCurrency}} <span class="muted">{{schedule .OldSchedule}}</span> → {{symbol .NewCurrency}}{{amountIn .NewCost .NewCurrency}} <span class="muted">{{schedule .NewSchedule}}</span></span>
+			</div>
+			{{end}}
+		</div>
+		{{end}}
+		<h3>{{t "email_weekly_summary_renewals"}}</h3>
+		<div class="subscription-details">
+			{{range .Summary.Renewals}}
+			<div class="detail-row">
+				<span><strong>{{.Name}}</strong> <span class="muted">({{.Date.Format "January 2, 2006"}})</span></span>
+				<span>{{symbol .Currency}}{{amountIn .Cost .Currency}}</span>
+			</div>
+			{{else}}
+			<p>{{t "email_weekly_summary_no_renewals"}}</p>
+			{{end}}
+			{{if .Summary.Renewals}}
+			<div class="detail-row"><strong>{{t "email_weekly_summary_renewals_total"}}</strong> <strong>{{.CurrencySymbol}}{{amount .Summary.RenewalsTotal}}</strong></div>
+			{{end}}
+		</div>
+		<h3>{{t "email_weekly_summary_budget"}}</h3>
+		<div class="subscription-details">
+			<div class="detail-row">
+				<span>{{t "analytics_monthly_cost"}}</span>
+				<span{{if and .Summary.MonthlyBudget (gt .Summary.MonthlySpend .Summary.MonthlyBudget)}} class="over"{{end}}>{{.CurrencySymbol}}{{amount .Summary.MonthlySpend}}{{if .Summary.MonthlyBudget}} / {{.CurrencySymbol}}{{amount .Summary.MonthlyBudget}}{{end}}</span>
+			</div>
+			<div class="detail-row">
+				<span>{{t "dashboard_annual_spend"}}</span>
+				<span{{if and .Summary.AnnualBudget (gt .Summary.AnnualSpend .Summary.AnnualBudget)}} class="over"{{end}}>{{.CurrencySymbol}}{{amount .Summary.AnnualSpend}}{{if .Summary.AnnualBudget}} / {{.CurrencySymbol}}{{amount .Summary.AnnualBudget}}{{end}}</span>
+			</div>
+		</div>
+		<div class="footer">
+			<p>{{t "email_footer_auto"}}</p>
+			<p>{{t "email_weekly_summary_footer"}}</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+	type section struct {
+		Title string
+		Items []WeeklySummaryItem
+	}
+	data := struct {
+		Summary        *WeeklySummary
+		Sections       []section
+		CurrencySymbol string
+	}{
+		Summary: summary,
+		Sections: []section{
+			{e.t("email_weekly_summary_added"), summary.Added},
+			{e.t("email_weekly_summary_edited"), summary.Edited},
+			{e.t("email_weekly_summary_cancelled"), summary.Cancelled},
+			{e.t("email_weekly_summary_deleted"), summary.Deleted},
+		},
+		CurrencySymbol: CurrencySymbolForCode(summary.Currency),
+	}
+
+	funcs := e.templateFuncs()
+	funcs["t"] = e.t
+	funcs["symbol"] = CurrencySymbolForCode
+	funcs["schedule"] = func(schedule string) string { return e.t("schedule_" + strings.ToLower(schedule)) }
+	tpl, err := template.New("weeklySummary").Funcs(funcs).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s: %s", e.t("email_weekly_summary_title"), summary.Until.Format("January 2, 2006"))
+	return e.sendNotification(subject, buf.String())
+}
+
+// SendMonthlyReport emails the monthly CSV export as an attachment. It is
+// sent right away, as attachments cannot be queued for the delivery window.
+func (e *EmailService) SendMonthlyReport(report *MonthlyReport) error {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<style>
+		body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+		.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+		.footer { margin-top: 30px; padding-top: 20px; border-top: 1px solid #ddd; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>{{.Title}}</h2>
+		<p>{{.Text}}</p>
+		<div class="footer">
+			<p>{{t "email_footer_auto"}}</p>
+			<p>{{t "email_monthly_report_footer"}}</p>
+		</div>
+	</div>
+</body>
+</html>
+`
+
+	month := e.t("month_"+strings.ToLower(report.Month.Month().String())) + " " + strconv.Itoa(report.Month.Year())
+	data := struct {
+		Title string
+		Text  string
+	}{
+		Title: e.tData("email_monthly_report_title", map[string]interface{}{"Month": month}),
+		Text: e.tPlural("email_monthly_report_text", report.Subscriptions, map[string]interface{}{
+			"Count":    report.Subscriptions,
+			"Filename": report.Filename,
+		}),
+	}
+
+	tpl, err := template.New("monthlyReport").Funcs(template.FuncMap{"t": e.t}).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
 
-	return e.SendEmail(subject, body)
+	return e.SendEmail(context.Background(), data.Title, buf.String(), Attachment{
+		Filename:    report.Filename,
+		ContentType: "text/csv; charset=UTF-8",
+		Data:        report.CSV,
+	})
 }
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrUnknownNotificationType is returned when previewing a notification type that does not exist
+	ErrUnknownNotificationType = errors.New("unknown notification type")
+	// ErrUnsupportedLanguage is returned when previewing an email in a language that is not available
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+)
+
+// RenderedEmail is a notification email rendered without sending it
+type RenderedEmail struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// NotificationTypes returns the notification types that can be tested and
+// previewed, in order
+func NotificationTypes() []string {
+	var types []string
+	for _, sample := range sampleNotifications("", "", time.Now()) {
+		types = append(types, sample.kind)
+	}
+	return types
+}
+
+// Preview renders the email of a notification type for the sample
+// subscription, in language or else the language of email notifications.
+// Nothing is sent or queued, and SMTP does not have to be set up.
+func (e *EmailService) Preview(kind, language string, now time.Time) (*RenderedEmail, error) {
+	if language != "" && e.i18nService != nil && !slices.Contains(e.i18nService.SupportedLanguages(), language) {
+		return nil, ErrUnsupportedLanguage
+	}
+	for _, sample := range sampleNotifications(e.preferences.GetCurrency(), e.preferences.GetCurrencySymbol(), now) {
+		if sample.kind != kind {
+			continue
+		}
+		preview := *e
+		preview.preview = &RenderedEmail{}
+		preview.previewLanguage = language
+		if err := sample.send(&preview); err != nil {
+			return nil, err
+		}
+		return preview.preview, nil
+	}
+	return nil, ErrUnknownNotificationType
+}
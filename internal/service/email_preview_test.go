@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/i18n"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailService_Preview(t *testing.T) {
+	_, preferences, notifConfig, _ := setupShoutrrrServices(t)
+	emails := NewEmailService(preferences, notifConfig, i18n.NewI18nService(""))
+	now := time.Now()
+
+	// Every type renders without SMTP and nothing is sent or queued
+	for _, kind := range NotificationTypes() {
+		email, err := emails.Preview(kind, "", now)
+		require.NoError(t, err, kind)
+		assert.NotEmpty(t, email.Subject, kind)
+		assert.Contains(t, email.Body, "<html", kind)
+	}
+	assert.Empty(t, notifConfig.QueuedNotifications())
+
+	email, err := emails.Preview(NotificationTypeRenewal, "", now)
+	require.NoError(t, err)
+	assert.Contains(t, email.Body, "SubVault Test")
+
+	email, err = emails.Preview(NotificationTypeBudget, "de", now)
+	require.NoError(t, err)
+	assert.Equal(t, "SubVault: Monatsbudget überschritten", email.Subject)
+	assert.Contains(t, email.Body, "Deine monatlichen Abo-Ausgaben haben dein Budget überschritten.")
+	// The preview language does not stick
+	email, err = emails.Preview(NotificationTypeBudget, "", now)
+	require.NoError(t, err)
+	assert.Contains(t, email.Body, "Your monthly subscription spending has exceeded your budget.")
+
+	_, err = emails.Preview("unknown", "", now)
+	assert.ErrorIs(t, err, ErrUnknownNotificationType)
+	_, err = emails.Preview(NotificationTypeRenewal, "xx", now)
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// EraseConfirmation must be typed to confirm erasing all data
+const EraseConfirmation = "ERASE"
+
+// ErrEraseNotConfirmed is returned when the confirmation text does not match EraseConfirmation
+var ErrEraseNotConfirmed = errors.New("erasure not confirmed")
+
+// ErasureResult summarizes an erasure
+type ErasureResult struct {
+	Files int `json:"files"`
+}
+
+// ErasureService erases everything: settings, secrets, API keys and every
+// other table, plus cached logos, attachments and backups. Existing sessions
+// end as the session secret is replaced. Clearing only the subscriptions is
+// done by UndoService so that it can be undone.
+type ErasureService struct {
+	auth           *AuthService
+	sessions       *SessionService
+	erase          func() error
+	logosDir       string
+	attachmentsDir string
+	backupsDir     string
+}
+
+// NewErasureService creates an erasure service. erase empties the database.
+func NewErasureService(auth *AuthService, sessions *SessionService, erase func() error, logosDir, attachmentsDir, backupsDir string) *ErasureService {
+	return &ErasureService{
+		auth:           auth,
+		sessions:       sessions,
+		erase:          erase,
+		logosDir:       logosDir,
+		attachmentsDir: attachmentsDir,
+		backupsDir:     backupsDir,
+	}
+}
+
+// EraseAll deletes all data after checking the confirmation text and, when
+// authentication is enabled, the admin password
+func (s *ErasureService) EraseAll(password, confirmation string) (*ErasureResult, error) {
+	if confirmation != EraseConfirmation {
+		return nil, ErrEraseNotConfirmed
+	}
+	if s.auth.IsAuthEnabled() && s.auth.ValidatePassword(password) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.erase(); err != nil {
+		return nil, fmt.Errorf("failed to erase database: %w", err)
+	}
+	s.auth.settings.InvalidateCache()
+
+	result := &ErasureResult{}
+	var errs []error
+	for _, dir := range []string{s.logosDir, s.attachmentsDir, s.backupsDir} {
+		removed, err := clearDir(dir)
+		result.Files += removed
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	secret, err := s.auth.GetOrGenerateSessionSecret()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to generate session secret: %w", err))
+	} else {
+		s.sessions.Rotate(secret)
+	}
+
+	slog.Warn("all data erased", "files", result.Files)
+	return result, errors.Join(errs...)
+}
+
+// clearDir deletes everything inside dir and returns the number of files removed
+func clearDir(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	removed := 0
+	var errs []error
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		count := 1
+		if entry.IsDir() {
+			count = 0
+			filepath.WalkDir(path, func(_ string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					count++
+				}
+				return nil
+			})
+		}
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s: %w", path, err))
+			continue
+		}
+		removed += count
+	}
+	return removed, errors.Join(errs...)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErasureService_EraseAll(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	settingsRepo := repository.NewSettingsRepository(db)
+	authService := NewAuthService(NewSettingsService(settingsRepo), settingsRepo)
+	require.NoError(t, authService.SetupAuth("admin", "admin-password"))
+
+	secret, err := authService.GetOrGenerateSessionSecret()
+	require.NoError(t, err)
+	sessions := NewSessionService(secret, nil)
+
+	// A logged in browser
+	recorder := httptest.NewRecorder()
+	require.NoError(t, sessions.CreateSession(recorder, httptest.NewRequest(http.MethodGet, "/", nil), false, RoleAdmin))
+	cookies := recorder.Result().Cookies()
+	loggedIn := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range cookies {
+			r.AddCookie(cookie)
+		}
+		return r
+	}
+	require.True(t, sessions.IsAuthenticated(loggedIn()))
+
+	logos := t.TempDir()
+	attachments := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(logos, "netflix.png"), []byte("png"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(attachments, "7"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(attachments, "7", "invoice.pdf"), []byte("pdf"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(attachments, "7", "receipt.pdf"), []byte("pdf"), 0o644))
+
+	erased := 0
+	erase := func() error {
+		erased++
+		return db.Exec("DELETE FROM settings").Error
+	}
+	erasure := NewErasureService(authService, sessions, erase, logos, attachments, filepath.Join(t.TempDir(), "missing"))
+
+	_, err = erasure.EraseAll("admin-password", "erase")
+	assert.ErrorIs(t, err, ErrEraseNotConfirmed)
+	_, err = erasure.EraseAll("wrong-password", EraseConfirmation)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Zero(t, erased)
+
+	result, err := erasure.EraseAll("admin-password", EraseConfirmation)
+	require.NoError(t, err)
+	assert.Equal(t, 1, erased)
+	assert.Equal(t, 3, result.Files)
+
+	for _, dir := range []string{logos, attachments} {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	}
+
+	// Authentication is gone and the old session cookie no longer works
+	assert.False(t, authService.IsAuthEnabled())
+	assert.False(t, sessions.IsAuthenticated(loggedIn()))
+
+	// Without authentication no password is needed
+	_, err = erasure.EraseAll("", EraseConfirmation)
+	require.NoError(t, err)
+	assert.Equal(t, 2, erased)
+}
+
+func TestErasureService_EraseFailure(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	settingsRepo := repository.NewSettingsRepository(db)
+	authService := NewAuthService(NewSettingsService(settingsRepo), settingsRepo)
+	logos := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(logos, "netflix.png"), []byte("png"), 0o644))
+
+	erasure := NewErasureService(authService, NewSessionService("secret", nil), func() error { return errors.New("disk I/O error") }, logos, t.TempDir(), t.TempDir())
+	_, err := erasure.EraseAll("", EraseConfirmation)
+	assert.Error(t, err)
+
+	// Files are kept when the database could not be erased
+	_, err = os.Stat(filepath.Join(logos, "netflix.png"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"subvault/internal/crypto"
+	"subvault/internal/i18n"
+	"subvault/internal/models"
+)
+
+// SubscriptionExport is the JSON export format (also accepted by the importer)
+type SubscriptionExport struct {
+	Subscriptions []models.Subscription `json:"subscriptions"`
+	ExportedAt    time.Time             `json:"exported_at"`
+	TotalCount    int                   `json:"total_count"`
+}
+
+// EncryptedBackup is the payload stored inside an encrypted .stbk backup
+type EncryptedBackup struct {
+	Subscriptions []models.Subscription `json:"subscriptions"`
+	Categories    []models.Category     `json:"categories"`
+	ExportedAt    time.Time             `json:"exported_at"`
+	TotalCount    int                   `json:"total_count"`
+	Version       string                `json:"version"`
+}
+
+// Backup is the plain JSON backup format including statistics
+type Backup struct {
+	Version       string                `json:"version"`
+	BackupDate    time.Time             `json:"backup_date"`
+	Subscriptions []models.Subscription `json:"subscriptions"`
+	Stats         *models.Stats         `json:"stats"`
+	TotalCount    int                   `json:"total_count"`
+}
+
+// ExportService serializes subscription data for downloads, backups and the CLI
+type ExportService struct {
+	subscriptions SubscriptionServiceInterface
+	preferences   PreferencesServiceInterface
+}
+
+func NewExportService(subscriptions SubscriptionServiceInterface, preferences PreferencesServiceInterface) *ExportService {
+	return &ExportService{subscriptions: subscriptions, preferences: preferences}
+}
+
+// ErrInvalidCSVColumns is returned for a CSV column selection that is empty or
+// names an unknown or repeated column
+var ErrInvalidCSVColumns = errors.New("invalid CSV columns")
+
+// CSVColumn is a column of the CSV export
+type CSVColumn struct {
+	Key    string // Identifier used by the column preference and the columns parameter
+	Header string // Label in the header row
+	value  func(sub *models.Subscription) string
+}
+
+// csvColumns are the available CSV columns in their default order
+var csvColumns = []CSVColumn{
+	{"id", "ID", func(sub *models.Subscription) string { return fmt.Sprintf("%d", sub.ID) }},
+	{"name", "Name", func(sub *models.Subscription) string { return sub.Name }},
+	{"category", "Category", func(sub *models.Subscription) string { return sub.Category.Name }},
+	{"cost", "Cost", func(sub *models.Subscription) string { return exportAmount(sub, sub.Cost) }},
+	{"tax_rate", "Tax Rate", func(sub *models.Subscription) string { return fmt.Sprintf("%.2f", sub.TaxRate) }},
+	{"price_type", "Price Type", func(sub *models.Subscription) string { return sub.PriceType }},
+	{"net_cost", "Net Cost", func(sub *models.Subscription) string { return exportAmount(sub, sub.NetCost()) }},
+	{"gross_cost", "Gross Cost", func(sub *models.Subscription) string { return exportAmount(sub, sub.GrossCost()) }},
+	{"tax_amount", "Tax Amount", func(sub *models.Subscription) string { return exportAmount(sub, sub.TaxAmount()) }},
+	{"schedule", "Schedule", func(sub *models.Subscription) string { return sub.Schedule }},
+	{"status", "Status", func(sub *models.Subscription) string { return sub.Status }},
+	{"payment_method", "Payment Method", func(sub *models.Subscription) string { return sub.PaymentMethod }},
+	{"login_name", "Login Name", func(sub *models.Subscription) string { return sub.LoginName }},
+	{"customer_number", "Customer Number", func(sub *models.Subscription) string { return sub.CustomerNumber }},
+	{"contract_number", "Contract Number", func(sub *models.Subscription) string { return sub.ContractNumber }},
+	{"start_date", "Start Date", func(sub *models.Subscription) string { return formatExportDate(sub.StartDate) }},
+	{"renewal_date", "Renewal Date", func(sub *models.Subscription) string { return formatExportDate(sub.RenewalDate) }},
+	{"cancellation_date", "Cancellation Date", func(sub *models.Subscription) string { return formatExportDate(sub.CancellationDate) }},
+	{"url", "URL", func(sub *models.Subscription) string { return sub.URL }},
+	{"notes", "Notes", func(sub *models.Subscription) string { return sub.Notes }},
+	{"usage", "Usage", func(sub *models.Subscription) string { return sub.Usage }},
+	{"purpose", "Purpose", func(sub *models.Subscription) string { return sub.EffectivePurpose() }},
+	{"renewal_reminder", "Renewal Reminder", func(sub *models.Subscription) string { return fmt.Sprintf("%t", sub.RenewalReminder) }},
+	{"renewal_reminder_days", "Renewal Reminder Days", func(sub *models.Subscription) string { return fmt.Sprintf("%d", sub.RenewalReminderDays) }},
+	{"cancellation_reminder", "Cancellation Reminder", func(sub *models.Subscription) string { return fmt.Sprintf("%t", sub.CancellationReminder) }},
+	{"cancellation_reminder_days", "Cancellation Reminder Days", func(sub *models.Subscription) string { return fmt.Sprintf("%d", sub.CancellationReminderDays) }},
+	{"high_cost_alert", "High Cost Alert", func(sub *models.Subscription) string { return fmt.Sprintf("%t", sub.HighCostAlert) }},
+	{"created_at", "Created At", func(sub *models.Subscription) string { return sub.CreatedAt.Format("2006-01-02 15:04:05") }},
+}
+
+// CSVColumns returns the available CSV columns in their default order
+func CSVColumns() []CSVColumn {
+	return slices.Clone(csvColumns)
+}
+
+// DefaultCSVColumns returns the keys of all CSV columns in their default order
+func DefaultCSVColumns() []string {
+	keys := make([]string, len(csvColumns))
+	for i, column := range csvColumns {
+		keys[i] = column.Key
+	}
+	return keys
+}
+
+// ParseCSVColumns validates a CSV column selection and returns its keys in the
+// given order. Keys are matched case-insensitively and blank entries skipped.
+func ParseCSVColumns(keys []string) ([]string, error) {
+	var columns []string
+	for _, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		if csvColumnIndex(key) < 0 {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidCSVColumns, key)
+		}
+		if slices.Contains(columns, key) {
+			return nil, fmt.Errorf("%w: column %q listed twice", ErrInvalidCSVColumns, key)
+		}
+		columns = append(columns, key)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%w: select at least one column", ErrInvalidCSVColumns)
+	}
+	return columns, nil
+}
+
+func csvColumnIndex(key string) int {
+	return slices.IndexFunc(csvColumns, func(column CSVColumn) bool { return column.Key == key })
+}
+
+// WriteCSV writes the given subscriptions as CSV with the given columns in
+// their order. Without columns the saved column preference is used; the keys
+// must have passed ParseCSVColumns.
+func (s *ExportService) WriteCSV(w io.Writer, subscriptions []models.Subscription, columns []string) error {
+	if len(columns) == 0 {
+		columns = s.preferences.GetCSVColumns()
+	}
+	selected := make([]CSVColumn, 0, len(columns))
+	header := make([]string, 0, len(columns))
+	for _, key := range columns {
+		i := csvColumnIndex(key)
+		if i < 0 {
+			return fmt.Errorf("%w: unknown column %q", ErrInvalidCSVColumns, key)
+		}
+		selected = append(selected, csvColumns[i])
+		header = append(header, csvColumns[i].Header)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(selected))
+	for i := range subscriptions {
+		for j, column := range selected {
+			record[j] = column.value(&subscriptions[i])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteAllCSV loads the subscriptions passing the filter and writes them as CSV
+// with the given columns, or the saved column preference without any
+func (s *ExportService) WriteAllCSV(ctx context.Context, w io.Writer, filter models.SubscriptionFilter, columns []string) error {
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	return s.WriteCSV(w, filter.Apply(subscriptions), columns)
+}
+
+// WriteTaxReportCSV writes one row per period and category, followed by the yearly total
+func (s *ExportService) WriteTaxReportCSV(w io.Writer, report *models.TaxReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Period", "Category", "Charges", "Net", "Tax", "Gross", "Currency"}); err != nil {
+		return err
+	}
+	amount := func(v float64) string { return i18n.FormatAmount(v, report.Currency, i18n.RoundingCurrency) }
+	for _, p := range report.Periods {
+		for _, c := range p.Categories {
+			if err := writer.Write([]string{p.Label, c.Category, fmt.Sprintf("%d", c.Charges), amount(c.Net), amount(c.Tax), amount(c.Gross), report.Currency}); err != nil {
+				return err
+			}
+		}
+	}
+	total := []string{fmt.Sprintf("%d", report.Year), "Total", "", amount(report.Net), amount(report.Tax), amount(report.Gross), report.Currency}
+	if err := writer.Write(total); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// BuildJSONExport loads the subscriptions passing the filter into the JSON export format
+func (s *ExportService) BuildJSONExport(ctx context.Context, filter models.SubscriptionFilter) (*SubscriptionExport, error) {
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions = filter.Apply(subscriptions)
+	return &SubscriptionExport{
+		Subscriptions: subscriptions,
+		ExportedAt:    time.Now(),
+		TotalCount:    len(subscriptions),
+	}, nil
+}
+
+// BuildBackup loads all subscriptions and statistics into the plain backup format
+func (s *ExportService) BuildBackup(ctx context.Context) (*Backup, error) {
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.subscriptions.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backup{
+		Version:       "1.0",
+		BackupDate:    time.Now(),
+		Subscriptions: subscriptions,
+		Stats:         stats,
+		TotalCount:    len(subscriptions),
+	}, nil
+}
+
+// scheduledBackupPrefix marks backups written by WriteBackupFile; only these are rotated
+const scheduledBackupPrefix = "subvault-backup-"
+
+// WriteBackupFile writes a plain JSON backup into dir and removes the oldest
+// scheduled backups so that at most keep remain (keep <= 0 keeps all)
+func (s *ExportService) WriteBackupFile(ctx context.Context, dir string, keep int) (string, error) {
+	backup, err := s.BuildBackup(ctx)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, scheduledBackupPrefix+backup.BackupDate.Format("20060102-150405")+".json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+	slog.Info("backup written", "file", path, "subscriptions", backup.TotalCount)
+
+	if keep > 0 {
+		rotateBackups(dir, keep)
+	}
+	return path, nil
+}
+
+// rotateBackups deletes all but the newest keep scheduled backups. The timestamp
+// in the file name sorts chronologically.
+func rotateBackups(dir string, keep int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		slog.Warn("failed to list backups", "error", err)
+		return
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), scheduledBackupPrefix) && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	for len(names) > keep {
+		if err := os.Remove(filepath.Join(dir, names[0])); err != nil {
+			slog.Warn("failed to remove old backup", "file", names[0], "error", err)
+		}
+		names = names[1:]
+	}
+}
+
+// BuildEncryptedBackup serializes subscriptions and categories and encrypts them
+// with AES-256-GCM using the given password (.stbk format)
+func (s *ExportService) BuildEncryptedBackup(ctx context.Context, password string) ([]byte, error) {
+	if password == "" {
+		return nil, fmt.Errorf("password required")
+	}
+
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.subscriptions.GetAllCategories()
+	if err != nil {
+		categories = nil
+	}
+
+	jsonData, err := json.Marshal(EncryptedBackup{
+		Subscriptions: subscriptions,
+		Categories:    categories,
+		ExportedAt:    time.Now(),
+		TotalCount:    len(subscriptions),
+		Version:       "2.0",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize data: %w", err)
+	}
+
+	encrypted, err := crypto.Encrypt(jsonData, password)
+	if err != nil {
+		return nil, fmt.Errorf("encryption failed: %w", err)
+	}
+	return encrypted, nil
+}
+
+// exportAmount formats an amount with the precision of the subscription's
+// currency, whatever the display rounding
+func exportAmount(sub *models.Subscription, amount float64) string {
+	return i18n.FormatAmount(amount, sub.OriginalCurrency, i18n.RoundingCurrency)
+}
+
+// formatExportDate formats an optional date as YYYY-MM-DD
+func formatExportDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
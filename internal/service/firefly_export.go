@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+)
+
+var (
+	// ErrFireflyNotConnected is returned when exporting without a Firefly III connection
+	ErrFireflyNotConnected = errors.New("firefly III not connected")
+	// ErrFireflyAccountRequired is returned when exporting without an asset account to pay from
+	ErrFireflyAccountRequired = errors.New("no Firefly III account to pay subscriptions from")
+)
+
+// FireflyAccount is an asset account exported subscriptions can be paid from
+type FireflyAccount struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+}
+
+// FireflyExportResult reports what an export changed in Firefly III
+type FireflyExportResult struct {
+	Created     int `json:"created"`
+	Updated     int `json:"updated"`
+	Deactivated int `json:"deactivated"`
+	Removed     int `json:"removed"`
+	// Failed lists the subscriptions Firefly III rejected, with the reason
+	Failed []string `json:"failed"`
+}
+
+// FireflyExportService pushes subscriptions to Firefly III as recurring
+// transactions. Each subscription is linked to its recurring transaction, so
+// exporting again updates it instead of creating a duplicate.
+type FireflyExportService struct {
+	budget        *BudgetImportService
+	links         *repository.FireflyRecurrenceRepository
+	subscriptions SubscriptionServiceInterface
+
+	// mu keeps two exports from creating the same recurring transaction
+	mu sync.Mutex
+}
+
+func NewFireflyExportService(budget *BudgetImportService, links *repository.FireflyRecurrenceRepository, subscriptions SubscriptionServiceInterface) *FireflyExportService {
+	return &FireflyExportService{budget: budget, links: links, subscriptions: subscriptions}
+}
+
+// Accounts lists the active asset accounts of Firefly III
+func (s *FireflyExportService) Accounts(ctx context.Context) ([]FireflyAccount, error) {
+	connection, err := s.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := []FireflyAccount{}
+	for page := 1; page <= fireflyMaxPages; page++ {
+		var response struct {
+			Data []struct {
+				ID         string `json:"id"`
+				Attributes struct {
+					Name         string `json:"name"`
+					CurrencyCode string `json:"currency_code"`
+					Active       bool   `json:"active"`
+				} `json:"attributes"`
+			} `json:"data"`
+			Meta struct {
+				Pagination struct {
+					TotalPages int `json:"total_pages"`
+				} `json:"pagination"`
+			} `json:"meta"`
+		}
+		if err := s.budget.get(ctx, connection.URL+"/api/v1/accounts?type=asset&page="+strconv.Itoa(page), connection.Token, &response); err != nil {
+			return nil, err
+		}
+		for _, account := range response.Data {
+			if account.Attributes.Active {
+				accounts = append(accounts, FireflyAccount{ID: account.ID, Name: account.Attributes.Name, Currency: account.Attributes.CurrencyCode})
+			}
+		}
+		if page >= response.Meta.Pagination.TotalPages {
+			break
+		}
+	}
+	return accounts, nil
+}
+
+// Export creates or updates a recurring transaction, paid from the asset
+// account, for every active or trial subscription. Recurring transactions of
+// paused and cancelled subscriptions are deactivated, those of deleted
+// subscriptions removed. An empty accountID uses the account of the last export.
+func (s *FireflyExportService) Export(ctx context.Context, accountID string) (*FireflyExportResult, error) {
+	connection, err := s.connection()
+	if err != nil {
+		return nil, err
+	}
+	accountID = strings.TrimSpace(accountID)
+	if accountID == "" {
+		accountID = connection.ExportAccountID
+	}
+	if accountID == "" {
+		return nil, ErrFireflyAccountRequired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.links.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	linked := make(map[uint]models.FireflyRecurrence, len(links))
+	for _, link := range links {
+		linked[link.SubscriptionID] = link
+	}
+
+	result := &FireflyExportResult{Failed: []string{}}
+	today := time.Now()
+	exported := make(map[uint]bool, len(subscriptions))
+	for _, sub := range subscriptions {
+		exported[sub.ID] = true
+		link, ok := linked[sub.ID]
+		var err error
+		switch {
+		case sub.Status == models.StatusActive || sub.Status == models.StatusTrial:
+			if sub.GrossCost() <= 0 {
+				// Firefly III has no negative withdrawals, credits stay out
+				continue
+			}
+			err = s.push(ctx, connection, sub, link, ok, fireflyRecurrenceBody(sub, accountID, today), result)
+		case ok:
+			err = s.deactivate(ctx, connection, link, result)
+		}
+		if errors.Is(err, ErrBudgetAPI) {
+			slog.Warn("firefly III rejected subscription", "subscription", sub.Name, "error", err)
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %s", sub.Name, err.Error()))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, link := range links {
+		if exported[link.SubscriptionID] {
+			continue
+		}
+		err := s.budget.request(ctx, http.MethodDelete, s.recurrenceURL(connection, link.RecurrenceID), connection.Token, nil, nil)
+		if err != nil && !errors.Is(err, errBudgetNotFound) {
+			return nil, err
+		}
+		if err := s.links.Delete(link.ID); err != nil {
+			return nil, err
+		}
+		result.Removed++
+	}
+
+	if err := s.budget.update(func(stored *models.BudgetConnection) {
+		now := time.Now()
+		stored.ExportAccountID = accountID
+		stored.LastExport = &now
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// push updates the linked recurring transaction, or creates one when there is
+// no link or Firefly III no longer has it
+func (s *FireflyExportService) push(ctx context.Context, connection models.BudgetConnection, sub models.Subscription, link models.FireflyRecurrence, linked bool, body map[string]any, result *FireflyExportResult) error {
+	if linked {
+		err := s.budget.request(ctx, http.MethodPut, s.recurrenceURL(connection, link.RecurrenceID), connection.Token, body, nil)
+		if err == nil {
+			link.SyncedAt = time.Now()
+			result.Updated++
+			return s.links.Save(&link)
+		}
+		if !errors.Is(err, errBudgetNotFound) {
+			return err
+		}
+	}
+
+	var created struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := s.budget.request(ctx, http.MethodPost, connection.URL+"/api/v1/recurrences", connection.Token, body, &created); err != nil {
+		return err
+	}
+	if created.Data.ID == "" {
+		return fmt.Errorf("%w: no recurring transaction ID returned", ErrBudgetAPI)
+	}
+	link.SubscriptionID = sub.ID
+	link.RecurrenceID = created.Data.ID
+	link.SyncedAt = time.Now()
+	result.Created++
+	return s.links.Save(&link)
+}
+
+// deactivate stops the recurring transaction of a paused or cancelled
+// subscription. It is activated again when the subscription is resumed.
+func (s *FireflyExportService) deactivate(ctx context.Context, connection models.BudgetConnection, link models.FireflyRecurrence, result *FireflyExportResult) error {
+	err := s.budget.request(ctx, http.MethodPut, s.recurrenceURL(connection, link.RecurrenceID), connection.Token, map[string]any{"active": false}, nil)
+	if errors.Is(err, errBudgetNotFound) {
+		return s.links.Delete(link.ID)
+	}
+	if err != nil {
+		return err
+	}
+	result.Deactivated++
+	return nil
+}
+
+func (s *FireflyExportService) connection() (models.BudgetConnection, error) {
+	connection := s.budget.Connection()
+	if !connection.Configured() || connection.Provider != models.BudgetProviderFirefly {
+		return connection, ErrFireflyNotConnected
+	}
+	return connection, nil
+}
+
+func (s *FireflyExportService) recurrenceURL(connection models.BudgetConnection, id string) string {
+	return connection.URL + "/api/v1/recurrences/" + url.PathEscape(id)
+}
+
+// fireflyRecurrenceBody describes a subscription as a Firefly III recurring
+// withdrawal starting at its next renewal, or tomorrow without one
+func fireflyRecurrenceBody(sub models.Subscription, accountID string, today time.Time) map[string]any {
+	first := time.Date(today.Year(), today.Month(), today.Day()+1, 0, 0, 0, 0, today.Location())
+	if sub.RenewalDate != nil && sub.RenewalDate.After(today) {
+		first = *sub.RenewalDate
+	}
+
+	repetition := map[string]any{"type": "monthly", "moment": strconv.Itoa(first.Day()), "skip": 0, "weekend": 1}
+	switch sub.Schedule {
+	case "Daily":
+		repetition["type"], repetition["moment"] = "daily", ""
+	case "Weekly":
+		// Firefly III counts weekdays from Monday (1) to Sunday (7)
+		weekday := int(first.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		repetition["type"], repetition["moment"] = "weekly", strconv.Itoa(weekday)
+	case "Quarterly":
+		repetition["skip"] = 2
+	case "Annual":
+		repetition["type"], repetition["moment"] = "yearly", first.Format(time.DateOnly)
+	}
+
+	transaction := map[string]any{
+		"description": sub.Name,
+		"amount":      strconv.FormatFloat(sub.GrossCost(), 'f', 2, 64),
+		"source_id":   accountID,
+	}
+	if sub.OriginalCurrency != "" {
+		transaction["currency_code"] = sub.OriginalCurrency
+	}
+
+	return map[string]any{
+		"type":         "withdrawal",
+		"title":        sub.Name,
+		"description":  "Exported from SubVault",
+		"first_date":   first.Format(time.DateOnly),
+		"active":       true,
+		"apply_rules":  true,
+		"repetitions":  []map[string]any{repetition},
+		"transactions": []map[string]any{transaction},
+	}
+}
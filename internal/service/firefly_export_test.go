@@ -0,0 +1,169 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFireflyRecurrences keeps the recurring transactions sent to a fake Firefly III
+type fakeFireflyRecurrences struct {
+	mu     sync.Mutex
+	nextID int
+	byID   map[string]map[string]any
+}
+
+// find returns the ID and body of the recurring transaction with the title
+func (f *fakeFireflyRecurrences) find(title string) (string, map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, recurrence := range f.byID {
+		if recurrence["title"] == title {
+			return id, recurrence
+		}
+	}
+	return "", nil
+}
+
+func fakeFirefly(t *testing.T) (*httptest.Server, *fakeFireflyRecurrences) {
+	store := &fakeFireflyRecurrences{byID: map[string]map[string]any{}}
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, status int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		require.NoError(t, json.NewEncoder(w).Encode(body))
+	}
+	empty := map[string]any{"data": []any{}, "meta": map[string]any{"pagination": map[string]int{"total_pages": 1}}}
+
+	mux.HandleFunc("GET /api/v1/recurrences", func(w http.ResponseWriter, r *http.Request) { reply(w, http.StatusOK, empty) })
+	mux.HandleFunc("GET /api/v1/accounts", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "asset", r.URL.Query().Get("type"))
+		reply(w, http.StatusOK, map[string]any{"data": []map[string]any{
+			{"id": "1", "attributes": map[string]any{"name": "Checking", "currency_code": "EUR", "active": true}},
+			{"id": "2", "attributes": map[string]any{"name": "Closed", "currency_code": "EUR", "active": false}},
+		}, "meta": map[string]any{"pagination": map[string]int{"total_pages": 1}}})
+	})
+	mux.HandleFunc("POST /api/v1/recurrences", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		store.mu.Lock()
+		store.nextID++
+		id := strconv.Itoa(store.nextID)
+		store.byID[id] = body
+		store.mu.Unlock()
+		reply(w, http.StatusOK, map[string]any{"data": map[string]any{"id": id}})
+	})
+	mux.HandleFunc("PUT /api/v1/recurrences/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		existing, ok := store.byID[r.PathValue("id")]
+		if !ok {
+			reply(w, http.StatusNotFound, map[string]string{"message": "Resource not found"})
+			return
+		}
+		for key, value := range body {
+			existing[key] = value
+		}
+		reply(w, http.StatusOK, map[string]any{"data": map[string]any{"id": r.PathValue("id")}})
+	})
+	mux.HandleFunc("DELETE /api/v1/recurrences/{id}", func(w http.ResponseWriter, r *http.Request) {
+		store.mu.Lock()
+		delete(store.byID, r.PathValue("id"))
+		store.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, store
+}
+
+func TestFireflyExportService_Export(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.FireflyRecurrence{}))
+	budget, subscriptions, _ := newBudgetImportTestService(t, db)
+	links := repository.NewFireflyRecurrenceRepository(db)
+	export := NewFireflyExportService(budget, links, subscriptions)
+	server, store := fakeFirefly(t)
+
+	_, err := export.Export(t.Context(), "1")
+	assert.ErrorIs(t, err, ErrFireflyNotConnected)
+	require.NoError(t, budget.Save(t.Context(), models.BudgetConnection{Provider: models.BudgetProviderFirefly, Token: "token", URL: server.URL}))
+	_, err = export.Export(t.Context(), "")
+	assert.ErrorIs(t, err, ErrFireflyAccountRequired)
+
+	accounts, err := export.Accounts(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []FireflyAccount{{ID: "1", Name: "Checking", Currency: "EUR"}}, accounts)
+
+	renewal := time.Date(2030, 1, 15, 0, 0, 0, 0, time.UTC)
+	netflix, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 12.99, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", RenewalDate: &renewal})
+	require.NoError(t, err)
+	insurance, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Insurance", Cost: 120, Schedule: "Annual", Status: "Active", OriginalCurrency: "EUR", RenewalDate: &renewal})
+	require.NoError(t, err)
+	_, err = subscriptions.Create(t.Context(), &models.Subscription{Name: "Gym", Cost: 30, Schedule: "Monthly", Status: "Paused", OriginalCurrency: "EUR"})
+	require.NoError(t, err)
+
+	result, err := export.Export(t.Context(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, &FireflyExportResult{Created: 2, Failed: []string{}}, result)
+	assert.Equal(t, "1", budget.Connection().ExportAccountID)
+
+	netflixID, recurrence := store.find("Netflix")
+	assert.Equal(t, "Netflix", recurrence["title"])
+	assert.Equal(t, "2030-01-15", recurrence["first_date"])
+	assert.Equal(t, []any{map[string]any{"type": "monthly", "moment": "15", "skip": float64(0), "weekend": float64(1)}}, recurrence["repetitions"])
+	transaction := recurrence["transactions"].([]any)[0].(map[string]any)
+	assert.Equal(t, "12.99", transaction["amount"])
+	assert.Equal(t, "1", transaction["source_id"])
+	_, recurrence = store.find("Insurance")
+	assert.Equal(t, []any{map[string]any{"type": "yearly", "moment": "2030-01-15", "skip": float64(0), "weekend": float64(1)}}, recurrence["repetitions"])
+
+	// Exporting again updates instead of duplicating
+	netflix.Cost = 15.99
+	_, err = subscriptions.Update(t.Context(), netflix.ID, netflix)
+	require.NoError(t, err)
+	result, err = export.Export(t.Context(), "")
+	require.NoError(t, err)
+	assert.Equal(t, &FireflyExportResult{Updated: 2, Failed: []string{}}, result)
+	_, recurrence = store.find("Netflix")
+	assert.Equal(t, "15.99", recurrence["transactions"].([]any)[0].(map[string]any)["amount"])
+
+	// Paused subscriptions are deactivated, deleted ones removed and missing
+	// recurring transactions created again
+	_, err = subscriptions.Pause(t.Context(), netflix.ID)
+	require.NoError(t, err)
+	require.NoError(t, subscriptions.Delete(t.Context(), insurance.ID))
+	result, err = export.Export(t.Context(), "")
+	require.NoError(t, err)
+	assert.Equal(t, &FireflyExportResult{Deactivated: 1, Removed: 1, Failed: []string{}}, result)
+	_, recurrence = store.find("Netflix")
+	assert.Equal(t, false, recurrence["active"])
+	_, recurrence = store.find("Insurance")
+	assert.Nil(t, recurrence)
+
+	_, err = subscriptions.Resume(t.Context(), netflix.ID)
+	require.NoError(t, err)
+	store.mu.Lock()
+	delete(store.byID, netflixID)
+	store.mu.Unlock()
+	result, err = export.Export(t.Context(), "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	all, err := links.GetAll()
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, "3", all[0].RecurrenceID)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionService_GetSubscriptionsNeedingGracePeriodReminders(t *testing.T) {
+	_, subscriptions := setupPaymentService(t)
+	now := time.Now()
+	failedAt := now.AddDate(0, 0, -5)
+	cutoff := func(days int) *time.Time { return timePtr(now.AddDate(0, 0, days)) }
+
+	for _, sub := range []models.Subscription{
+		{Name: "Cutoff soon", GracePeriodEnd: cutoff(2)},
+		{Name: "Cutoff later", GracePeriodEnd: cutoff(models.GracePeriodReminderDays + 2)},
+		{Name: "Cut off", GracePeriodEnd: cutoff(-1)},
+		{Name: "Already reminded", GracePeriodEnd: cutoff(1)},
+		{Name: "Cancelled", Status: "Cancelled", GracePeriodEnd: cutoff(1)},
+	} {
+		sub.Cost, sub.Schedule, sub.OriginalCurrency = 10, "Monthly", "EUR"
+		if sub.Status == "" {
+			sub.Status = "Active"
+		}
+		sub.PaymentFailedAt = &failedAt
+		if sub.Name == "Already reminded" {
+			sub.LastGraceReminderDate = sub.GracePeriodEnd
+		}
+		_, err := subscriptions.Create(t.Context(), &sub)
+		require.NoError(t, err)
+	}
+	// A failed payment without a known cutoff has nothing to remind of
+	_, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "No cutoff", Cost: 10, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", PaymentFailedAt: &failedAt})
+	require.NoError(t, err)
+
+	result, err := subscriptions.GetSubscriptionsNeedingGracePeriodReminders(t.Context(), time.Now())
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	for sub, days := range result {
+		assert.Equal(t, "Cutoff soon", sub.Name)
+		assert.Equal(t, 2, days)
+	}
+}
+
+func TestSubscriptionService_StatsCountFailedPaymentsAsSpend(t *testing.T) {
+	_, subscriptions := setupPaymentService(t)
+	failedAt := time.Now().AddDate(0, 0, -2)
+	for _, sub := range []models.Subscription{
+		{Name: "Netflix", Cost: 10, Schedule: "Monthly", Status: "Active", OriginalCurrency: "USD", PaymentFailedAt: &failedAt},
+		{Name: "Spotify", Cost: 5, Schedule: "Monthly", Status: "Active", OriginalCurrency: "USD"},
+	} {
+		_, err := subscriptions.Create(t.Context(), &sub)
+		require.NoError(t, err)
+	}
+
+	stats, err := subscriptions.GetStats(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.ActiveSubscriptions)
+	assert.Equal(t, 1, stats.FailedPayments)
+	assert.InDelta(t, 15.0, stats.TotalMonthlySpend, 0.001)
+	assert.Zero(t, stats.TotalSaved)
+}
+
+func TestPaymentService_ConfirmResolvesPaymentFailure(t *testing.T) {
+	payments, subscriptions := setupPaymentService(t)
+	now := time.Now()
+	start := now.AddDate(0, -1, -2)
+	sub, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 10, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", StartDate: &start})
+	require.NoError(t, err)
+	created, err := payments.RecordRenewals(t.Context(), now)
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+
+	// The renewal bounced and is retried tomorrow
+	sub.MarkPaymentFailed(created[0].DueDate.Add(time.Hour), timePtr(now.AddDate(0, 0, 1)), timePtr(now.AddDate(0, 0, 7)))
+	_, err = subscriptions.Update(t.Context(), sub.ID, sub)
+	require.NoError(t, err)
+
+	paidAt := now
+	_, err = payments.Confirm(t.Context(), created[0].ID, nil, &paidAt)
+	require.NoError(t, err)
+
+	resolved, err := subscriptions.GetByID(t.Context(), sub.ID)
+	require.NoError(t, err)
+	assert.Nil(t, resolved.PaymentFailedAt)
+	assert.Nil(t, resolved.PaymentRetryDate)
+	assert.Nil(t, resolved.GracePeriodEnd)
+}
+
+func TestSubscription_MarkPaymentFailedKeepsFirstFailure(t *testing.T) {
+	first := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	retry := first.AddDate(0, 0, 3)
+	sub := models.Subscription{}
+	sub.MarkPaymentFailed(first, nil, nil)
+	sub.MarkPaymentFailed(first.AddDate(0, 0, 3), &retry, nil)
+	require.NotNil(t, sub.PaymentFailedAt)
+	assert.True(t, sub.PaymentFailedAt.Equal(first))
+	assert.Equal(t, &retry, sub.PaymentRetryDate)
+}
+
+func TestSubscriptionService_GetSubscriptionsNeedingPaidThroughReminders(t *testing.T) {
+	_, subscriptions := setupPaymentService(t)
+	now := time.Now()
+	paidThrough := func(days int) *time.Time { return timePtr(now.AddDate(0, 0, days)) }
+
+	for _, sub := range []models.Subscription{
+		{Name: "Access ends soon", PaidThroughDate: paidThrough(2)},
+		{Name: "Access ends later", PaidThroughDate: paidThrough(models.PaidThroughReminderDays + 2)},
+		{Name: "Access ended", PaidThroughDate: paidThrough(-1)},
+		{Name: "Already reminded", PaidThroughDate: paidThrough(1)},
+		{Name: "Still active", Status: "Active", PaidThroughDate: paidThrough(1)},
+		{Name: "No paid-through date"},
+	} {
+		sub.Cost, sub.Schedule, sub.OriginalCurrency = 10, "Monthly", "EUR"
+		if sub.Status == "" {
+			sub.Status = "Cancelled"
+		}
+		if sub.Name == "Already reminded" {
+			sub.LastPaidThroughReminderDate = sub.PaidThroughDate
+		}
+		_, err := subscriptions.Create(t.Context(), &sub)
+		require.NoError(t, err)
+	}
+
+	result, err := subscriptions.GetSubscriptionsNeedingPaidThroughReminders(t.Context(), time.Now())
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	for sub, days := range result {
+		assert.Equal(t, "Access ends soon", sub.Name)
+		assert.Equal(t, 2, days)
+	}
+}
+
+func TestSubscriptionService_GetSubscriptionsNeedingPromoReminders(t *testing.T) {
+	_, subscriptions := setupPaymentService(t)
+	now := time.Now()
+	promoEnd := func(days int) *time.Time { return timePtr(now.AddDate(0, 0, days)) }
+	promo := 4.99
+
+	for _, sub := range []models.Subscription{
+		{Name: "Price rises soon", PromoEndDate: promoEnd(5)},
+		{Name: "Price rises later", PromoEndDate: promoEnd(models.PromoReminderDays + 3)},
+		{Name: "Price rose", PromoEndDate: promoEnd(-1)},
+		{Name: "Already reminded", PromoEndDate: promoEnd(2)},
+		{Name: "No reminder wanted", PromoEndDate: promoEnd(2)},
+		{Name: "Cancelled", Status: "Cancelled", PromoEndDate: promoEnd(2)},
+		{Name: "No promo end", PromoEndDate: nil},
+	} {
+		sub.Cost, sub.Schedule, sub.OriginalCurrency, sub.PromoCost = 12.99, "Monthly", "EUR", &promo
+		sub.PromoEndReminder = sub.Name != "No reminder wanted"
+		if sub.Status == "" {
+			sub.Status = "Active"
+		}
+		if sub.Name == "Already reminded" {
+			sub.LastPromoReminderDate = sub.PromoEndDate
+		}
+		_, err := subscriptions.Create(t.Context(), &sub)
+		require.NoError(t, err)
+	}
+
+	result, err := subscriptions.GetSubscriptionsNeedingPromoReminders(t.Context(), time.Now())
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	for sub, days := range result {
+		assert.Equal(t, "Price rises soon", sub.Name)
+		assert.Equal(t, 5, days)
+	}
+}
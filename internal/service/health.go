@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Health check statuses
+const (
+	HealthStatusOK      = "ok"
+	HealthStatusFail    = "fail"
+	HealthStatusSkipped = "skipped"
+)
+
+// ErrCheckSkipped is returned by a health check that does not apply to the current configuration
+var ErrCheckSkipped = errors.New("check skipped")
+
+// HealthCheckResult is the outcome of a single readiness check. The endpoint
+// is public, so the reason a check failed is only logged.
+type HealthCheckResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Optional   bool   `json:"optional"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type healthCheck struct {
+	name     string
+	optional bool
+	run      func() error
+}
+
+// HealthService runs registered readiness checks.
+// Required checks always run; optional checks only run when explicitly requested.
+type HealthService struct {
+	mu     sync.RWMutex
+	checks []healthCheck
+}
+
+func NewHealthService() *HealthService {
+	return &HealthService{}
+}
+
+// Register adds a named check. A check returning ErrCheckSkipped (or wrapping it)
+// is reported as skipped and does not affect readiness.
+func (s *HealthService) Register(name string, optional bool, run func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks = append(s.checks, healthCheck{name: name, optional: optional, run: run})
+}
+
+// OptionalChecks returns the names of all optional checks
+func (s *HealthService) OptionalChecks() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var names []string
+	for _, c := range s.checks {
+		if c.optional {
+			names = append(names, c.name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run executes all required checks plus the optional checks named in include.
+// It returns the individual results and whether every executed check passed.
+func (s *HealthService) Run(include map[string]bool) ([]HealthCheckResult, bool) {
+	s.mu.RLock()
+	checks := make([]healthCheck, len(s.checks))
+	copy(checks, s.checks)
+	s.mu.RUnlock()
+
+	ready := true
+	results := make([]HealthCheckResult, 0, len(checks))
+	for _, c := range checks {
+		if c.optional && !include[c.name] {
+			continue
+		}
+
+		start := time.Now()
+		err := c.run()
+		result := HealthCheckResult{
+			Name:       c.name,
+			Status:     HealthStatusOK,
+			Optional:   c.optional,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		switch {
+		case errors.Is(err, ErrCheckSkipped):
+			result.Status = HealthStatusSkipped
+		case err != nil:
+			slog.Warn("readiness check failed", "check", c.name, "error", err)
+			result.Status = HealthStatusFail
+			ready = false
+		}
+		results = append(results, result)
+	}
+
+	return results, ready
+}
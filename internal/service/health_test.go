@@ -0,0 +1,32 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthService_Run(t *testing.T) {
+	svc := NewHealthService()
+	svc.Register("database", false, func() error { return nil })
+	svc.Register("smtp", true, func() error { return fmt.Errorf("smtp not configured: %w", ErrCheckSkipped) })
+	svc.Register("exchange_rates", true, func() error { return errors.New("stale") })
+
+	results, ready := svc.Run(nil)
+	assert.True(t, ready)
+	assert.Len(t, results, 1)
+	assert.Equal(t, HealthStatusOK, results[0].Status)
+
+	results, ready = svc.Run(map[string]bool{"smtp": true})
+	assert.True(t, ready, "skipped checks must not fail readiness")
+	assert.Len(t, results, 2)
+	assert.Equal(t, HealthStatusSkipped, results[1].Status)
+
+	results, ready = svc.Run(map[string]bool{"exchange_rates": true})
+	assert.False(t, ready)
+	assert.Equal(t, HealthStatusFail, results[1].Status)
+
+	assert.Equal(t, []string{"exchange_rates", "smtp"}, svc.OptionalChecks())
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Hook events
+const (
+	EventSubscriptionCreated = "subscription.created"
+	EventSubscriptionUpdated = "subscription.updated"
+	EventSubscriptionDeleted = "subscription.deleted"
+	EventRenewalImminent     = "renewal.imminent"
+	EventBudgetExceeded      = "budget.exceeded"
+)
+
+// HookEvents lists all events hooks can subscribe to
+var HookEvents = []string{EventSubscriptionCreated, EventSubscriptionUpdated, EventSubscriptionDeleted, EventRenewalImminent, EventBudgetExceeded}
+
+// defaultHookTimeout applies when neither the hook nor the file sets a timeout
+const defaultHookTimeout = 10 * time.Second
+
+// maxHookOutput limits how much command output is kept for logging
+const maxHookOutput = 4096
+
+// HookPayload is sent as JSON on stdin to command hooks and as request body to HTTP hooks
+type HookPayload struct {
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Hook is a single command or HTTP hook from the hooks file
+type Hook struct {
+	Name    string            `yaml:"name"`
+	Events  []string          `yaml:"events"`
+	Command string            `yaml:"command,omitempty"`
+	Args    []string          `yaml:"args,omitempty"`
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Timeout time.Duration     `yaml:"timeout,omitempty"`
+}
+
+// HooksConfig is the structure of the hooks file
+type HooksConfig struct {
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// AllowCommands are glob patterns of executables that may run; command hooks are rejected when empty
+	AllowCommands []string `yaml:"allow_commands,omitempty"`
+	// AllowHosts restricts HTTP hooks to these hosts when set
+	AllowHosts []string `yaml:"allow_hosts,omitempty"`
+	// DenyHosts are never called, even when allowed
+	DenyHosts []string `yaml:"deny_hosts,omitempty"`
+	Hooks     []Hook   `yaml:"hooks"`
+}
+
+// HookService runs user-defined hooks on application events. Hooks are read
+// from a file at startup and cannot be changed through the web interface or API.
+type HookService struct {
+	hooks      []Hook
+	httpClient *http.Client
+}
+
+// NewHookService creates a hook service without any hooks
+func NewHookService() *HookService {
+	return &HookService{httpClient: NewHTTPClient(0)}
+}
+
+// LoadHookService reads the hooks file. A missing file means no hooks. Hooks
+// that violate the allow/deny rules are skipped with a warning.
+func LoadHookService(path string) (*HookService, error) {
+	s := NewHookService()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg HooksConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid hooks file %s: %w", path, err)
+	}
+	for _, hook := range cfg.Hooks {
+		if err := cfg.validate(&hook); err != nil {
+			slog.Warn("hook skipped", "hook", hook.Name, "error", err)
+			continue
+		}
+		if hook.Timeout <= 0 {
+			hook.Timeout = cfg.Timeout
+		}
+		if hook.Timeout <= 0 {
+			hook.Timeout = defaultHookTimeout
+		}
+		s.hooks = append(s.hooks, hook)
+	}
+	slog.Info("hooks loaded", "file", path, "count", len(s.hooks))
+	return s, nil
+}
+
+// Has reports whether at least one hook listens to the event
+func (s *HookService) Has(event string) bool {
+	for _, hook := range s.hooks {
+		if hook.handles(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fire runs all hooks for the event in the background
+func (s *HookService) Fire(event string, data interface{}) {
+	if !s.Has(event) {
+		return
+	}
+	go s.dispatch(event, data)
+}
+
+// dispatch runs all hooks for the event concurrently and waits for them
+func (s *HookService) dispatch(event string, data interface{}) {
+	payload, err := json.Marshal(HookPayload{Event: event, Timestamp: time.Now().UTC(), Data: data})
+	if err != nil {
+		slog.Error("failed to encode hook payload", "event", event, "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, hook := range s.hooks {
+		if !hook.handles(event) {
+			continue
+		}
+		wg.Add(1)
+		go func(hook Hook) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("panic in hook", "hook", hook.Name, "panic", r)
+				}
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), hook.Timeout)
+			defer cancel()
+			var err error
+			if hook.Command != "" {
+				err = runCommandHook(ctx, hook, event, payload)
+			} else {
+				err = s.runHTTPHook(ctx, hook, event, payload)
+			}
+			if err != nil {
+				slog.Warn("hook failed", "hook", hook.Name, "event", event, "error", err)
+				return
+			}
+			slog.Debug("hook ran", "hook", hook.Name, "event", event)
+		}(hook)
+	}
+	wg.Wait()
+}
+
+// runCommandHook executes the hook's command with the payload on stdin. The
+// environment is reduced to PATH plus SUBVAULT_EVENT.
+func runCommandHook(ctx context.Context, hook Hook, event string, payload []byte) error {
+	cmd := exec.CommandContext(ctx, hook.Command, hook.Args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "SUBVAULT_EVENT=" + event}
+	var output limitedBuffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out after %s", hook.Timeout)
+		}
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// runHTTPHook posts the payload to the hook's URL
+func (s *HookService) runHTTPHook(ctx context.Context, hook Hook, event string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-SubVault-Event", event)
+	for key, value := range hook.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// validate checks a hook against the allow/deny rules of the file
+func (c *HooksConfig) validate(hook *Hook) error {
+	if hook.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(hook.Events) == 0 {
+		return errors.New("no events configured")
+	}
+	for _, event := range hook.Events {
+		if !isHookEvent(event) {
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+
+	switch {
+	case hook.Command != "" && hook.URL != "":
+		return errors.New("set either command or url, not both")
+	case hook.Command != "":
+		if !filepath.IsAbs(hook.Command) {
+			return errors.New("command must be an absolute path")
+		}
+		for _, pattern := range c.AllowCommands {
+			if ok, _ := filepath.Match(pattern, hook.Command); ok {
+				return nil
+			}
+		}
+		return fmt.Errorf("command %s is not matched by allow_commands", hook.Command)
+	case hook.URL != "":
+		u, err := url.Parse(hook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return errors.New("url must be an absolute http(s) URL")
+		}
+		host := strings.ToLower(u.Hostname())
+		for _, denied := range c.DenyHosts {
+			if strings.EqualFold(denied, host) {
+				return fmt.Errorf("host %s is denied", host)
+			}
+		}
+		if len(c.AllowHosts) == 0 {
+			return nil
+		}
+		for _, allowed := range c.AllowHosts {
+			if strings.EqualFold(allowed, host) {
+				return nil
+			}
+		}
+		return fmt.Errorf("host %s is not in allow_hosts", host)
+	default:
+		return errors.New("command or url is required")
+	}
+}
+
+func (h Hook) handles(event string) bool {
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func isHookEvent(event string) bool {
+	for _, e := range HookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// limitedBuffer keeps the first maxHookOutput bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxHookOutput - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeHooksFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hooks.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadHookService_MissingFile(t *testing.T) {
+	hooks, err := LoadHookService(filepath.Join(t.TempDir(), "hooks.yaml"))
+	require.NoError(t, err)
+	assert.False(t, hooks.Has(EventSubscriptionCreated))
+}
+
+func TestLoadHookService_Validation(t *testing.T) {
+	path := writeHooksFile(t, `
+allow_commands: ["/opt/hooks/*"]
+deny_hosts: ["169.254.169.254"]
+hooks:
+  - name: allowed
+    events: [subscription.created]
+    command: /opt/hooks/notify.sh
+  - name: not-allowed
+    events: [subscription.created]
+    command: /usr/bin/curl
+  - name: relative
+    events: [subscription.created]
+    command: notify.sh
+  - name: metadata
+    events: [budget.exceeded]
+    url: http://169.254.169.254/latest
+  - name: unknown-event
+    events: [subscription.exploded]
+    url: https://example.com/hook
+`)
+	hooks, err := LoadHookService(path)
+	require.NoError(t, err)
+	require.Len(t, hooks.hooks, 1)
+	assert.Equal(t, "allowed", hooks.hooks[0].Name)
+	assert.Equal(t, defaultHookTimeout, hooks.hooks[0].Timeout)
+	assert.True(t, hooks.Has(EventSubscriptionCreated))
+	assert.False(t, hooks.Has(EventBudgetExceeded))
+
+	_, err = LoadHookService(writeHooksFile(t, "hooks: [not: valid"))
+	assert.Error(t, err)
+}
+
+func TestHookService_CommandHook(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "payload.json")
+	script := filepath.Join(dir, "hook.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\ncat > \"$1\"\n"), 0700))
+
+	path := writeHooksFile(t, `
+allow_commands: ["`+dir+`/*"]
+hooks:
+  - name: capture
+    events: [subscription.created]
+    command: `+script+`
+    args: ["`+out+`"]
+    timeout: 5s
+`)
+	hooks, err := LoadHookService(path)
+	require.NoError(t, err)
+
+	hooks.dispatch(EventSubscriptionCreated, map[string]interface{}{"name": "Netflix"})
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var payload struct {
+		Event string            `json:"event"`
+		Data  map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(data, &payload))
+	assert.Equal(t, EventSubscriptionCreated, payload.Event)
+	assert.Equal(t, "Netflix", payload.Data["name"])
+}
+
+func TestHookService_CommandTimeout(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "slow.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nexec sleep 5\n"), 0700))
+
+	hooks := NewHookService()
+	hooks.hooks = []Hook{{Name: "slow", Events: []string{EventBudgetExceeded}, Command: script, Timeout: 100 * time.Millisecond}}
+
+	start := time.Now()
+	hooks.dispatch(EventBudgetExceeded, nil)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
+
+func TestHookService_HTTPHook(t *testing.T) {
+	received := make(chan HookPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, EventBudgetExceeded, r.Header.Get("X-SubVault-Event"))
+		assert.Equal(t, "secret", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		var payload HookPayload
+		assert.NoError(t, json.Unmarshal(body, &payload))
+		received <- payload
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	path := writeHooksFile(t, `
+allow_hosts: ["127.0.0.1"]
+hooks:
+  - name: webhook
+    events: [budget.exceeded]
+    url: `+server.URL+`
+    headers:
+      Authorization: secret
+`)
+	hooks, err := LoadHookService(path)
+	require.NoError(t, err)
+
+	hooks.dispatch(EventBudgetExceeded, map[string]interface{}{"budget": 50})
+
+	select {
+	case payload := <-received:
+		assert.Equal(t, EventBudgetExceeded, payload.Event)
+	default:
+		t.Fatal("HTTP hook was not called")
+	}
+}
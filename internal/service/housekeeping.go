@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"subvault/internal/repository"
+)
+
+// exchangeRateRetention is how long historical exchange rates are kept; the most
+// recent set is always kept regardless of age
+const exchangeRateRetention = 7 * 24 * time.Hour
+
+// housekeepingMetrics holds cumulative counters of all housekeeping runs, published
+// under "housekeeping" via expvar
+var housekeepingMetrics = expvar.NewMap("housekeeping")
+
+// HousekeepingResult summarises what a single housekeeping run removed
+type HousekeepingResult struct {
+	ResetTokens   int64         `json:"reset_tokens"`
+	ExchangeRates int64         `json:"exchange_rates"`
+	LogoFiles     int64         `json:"logo_files"`
+	Errors        int           `json:"errors"`
+	Duration      time.Duration `json:"duration"`
+}
+
+// HousekeepingService prunes data that is no longer needed: expired password
+// reset tokens, old exchange rates and cached logo files no subscription uses.
+// Sessions are stored in signed cookies and expire on the client, so there is
+// nothing to prune for them on the server.
+type HousekeepingService struct {
+	auth          *AuthService
+	exchangeRates *repository.ExchangeRateRepository
+	subscriptions *SubscriptionService
+	logosDir      string
+}
+
+func NewHousekeepingService(auth *AuthService, exchangeRates *repository.ExchangeRateRepository, subscriptions *SubscriptionService, logosDir string) *HousekeepingService {
+	return &HousekeepingService{
+		auth:          auth,
+		exchangeRates: exchangeRates,
+		subscriptions: subscriptions,
+		logosDir:      logosDir,
+	}
+}
+
+// Run performs all housekeeping tasks. A failing task is logged and does not
+// stop the others.
+func (s *HousekeepingService) Run(ctx context.Context) HousekeepingResult {
+	start := time.Now()
+	var result HousekeepingResult
+
+	if pruned, err := s.auth.PruneExpiredResetToken(); err != nil {
+		slog.Warn("failed to prune reset token", "error", err)
+		result.Errors++
+	} else if pruned {
+		result.ResetTokens = 1
+	}
+
+	if n, err := s.exchangeRates.DeleteRatesBefore(time.Now().Add(-exchangeRateRetention)); err != nil {
+		slog.Warn("failed to prune exchange rates", "error", err)
+		result.Errors++
+	} else {
+		result.ExchangeRates = n
+	}
+
+	if n, err := s.pruneOrphanedLogos(ctx); err != nil {
+		slog.Warn("failed to prune orphaned logos", "error", err)
+		result.Errors++
+	} else {
+		result.LogoFiles = n
+	}
+
+	result.Duration = time.Since(start)
+	housekeepingMetrics.Add("runs", 1)
+	housekeepingMetrics.Add("reset_tokens_pruned", result.ResetTokens)
+	housekeepingMetrics.Add("exchange_rates_pruned", result.ExchangeRates)
+	housekeepingMetrics.Add("logo_files_pruned", result.LogoFiles)
+	housekeepingMetrics.Add("errors", int64(result.Errors))
+
+	slog.Info("housekeeping complete",
+		"reset_tokens", result.ResetTokens,
+		"exchange_rates", result.ExchangeRates,
+		"logo_files", result.LogoFiles,
+		"errors", result.Errors,
+		"duration", result.Duration)
+	return result
+}
+
+// pruneOrphanedLogos deletes files in the logos directory whose name does not
+// appear as the last path element of any subscription's icon URL
+func (s *HousekeepingService) pruneOrphanedLogos(ctx context.Context) (int64, error) {
+	entries, err := os.ReadDir(s.logosDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	referenced := make(map[string]bool, len(subscriptions))
+	for _, sub := range subscriptions {
+		if sub.IconURL != "" {
+			referenced[path.Base(sub.IconURL)] = true
+		}
+	}
+
+	var removed int64
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || referenced[entry.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.logosDir, entry.Name())); err != nil {
+			slog.Warn("failed to remove orphaned logo", "file", entry.Name(), "error", err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHousekeepingService_Run(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	settingsRepo := repository.NewSettingsRepository(db)
+	services := newTestSubscriptionService(t, db)
+	exchangeRateRepo := repository.NewExchangeRateRepository(db)
+	authService := NewAuthService(services.settings, settingsRepo)
+
+	// Expired reset token
+	require.NoError(t, settingsRepo.Set(SettingKeyAuthResetToken, "token"))
+	require.NoError(t, settingsRepo.Set(SettingKeyAuthResetExpiry, time.Now().Add(-time.Hour).Format(time.RFC3339)))
+	services.settings.InvalidateCache()
+
+	// Old rates are pruned, the latest set is kept even though it is old as well
+	old := time.Now().AddDate(0, 0, -30)
+	latest := time.Now().AddDate(0, 0, -10)
+	require.NoError(t, exchangeRateRepo.SaveRates([]models.ExchangeRate{
+		{BaseCurrency: "EUR", Currency: "USD", Rate: 1.1, Date: old},
+		{BaseCurrency: "EUR", Currency: "GBP", Rate: 0.9, Date: old},
+		{BaseCurrency: "EUR", Currency: "USD", Rate: 1.2, Date: latest},
+	}))
+
+	// One logo in use, one orphaned
+	logosDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(logosDir, "netflix.png"), []byte("png"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(logosDir, "orphan.png"), []byte("png"), 0600))
+	_, err := services.subscriptions.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 10, Schedule: "Monthly", Status: "Active", IconURL: "/logos/netflix.png"})
+	require.NoError(t, err)
+
+	housekeeping := NewHousekeepingService(authService, exchangeRateRepo, services.subscriptions, logosDir)
+	result := housekeeping.Run(t.Context())
+
+	assert.Equal(t, int64(1), result.ResetTokens)
+	assert.Equal(t, int64(2), result.ExchangeRates)
+	assert.Equal(t, int64(1), result.LogoFiles)
+	assert.Zero(t, result.Errors)
+
+	assert.Error(t, authService.ValidateResetToken("token"))
+	rate, err := exchangeRateRepo.GetRate("EUR", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 1.2, rate.Rate)
+	assert.FileExists(t, filepath.Join(logosDir, "netflix.png"))
+	assert.NoFileExists(t, filepath.Join(logosDir, "orphan.png"))
+
+	// A second run has nothing left to do
+	result = housekeeping.Run(t.Context())
+	assert.Zero(t, result.ResetTokens+result.ExchangeRates+result.LogoFiles)
+}
+
+func TestAuthService_PruneExpiredResetToken_KeepsValidToken(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	settingsRepo := repository.NewSettingsRepository(db)
+	authService := NewAuthService(NewSettingsService(settingsRepo), settingsRepo)
+
+	token, err := authService.GenerateResetToken()
+	require.NoError(t, err)
+
+	pruned, err := authService.PruneExpiredResetToken()
+	require.NoError(t, err)
+	assert.False(t, pruned)
+	assert.NoError(t, authService.ValidateResetToken(token))
+}
@@ -0,0 +1,767 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"subvault/internal/crypto"
+	"subvault/internal/i18n"
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownImportFormat is returned when the import format cannot be determined
+var ErrUnknownImportFormat = errors.New("unknown import format")
+
+// ErrDecryptionFailed is returned when an encrypted backup cannot be decrypted
+var ErrDecryptionFailed = errors.New("decryption failed: wrong password or corrupted file")
+
+// ErrImportBatchNotFound is returned when undoing an import batch that does not exist
+var ErrImportBatchNotFound = errors.New("import batch not found")
+
+type ImportResult struct {
+	Imported int      `json:"imported"`
+	Skipped  int      `json:"skipped"`
+	Errors   int      `json:"errors"`
+	Details  []string `json:"details"`
+	BatchID  uint     `json:"batch_id,omitempty"`
+	// Payments is the number of past payments added to the payment ledger
+	Payments int `json:"payments,omitempty"`
+}
+
+// ImportOptions controls optional parts of an import
+type ImportOptions struct {
+	// Payments adds the payment history of Wallos exports to the payment ledger
+	Payments bool
+}
+
+// wallosNameObj represents a nested Wallos object with a name field
+type wallosNameObj struct {
+	Name string `json:"name"`
+}
+
+// wallosSubscription represents a subscription from Wallos export
+// Supports both real Wallos format (nested objects) and flat format
+type wallosSubscription struct {
+	Name              string          `json:"name"`
+	Price             json.RawMessage `json:"price"`
+	CurrencyCode      string          `json:"currency_code"`
+	Currency          wallosNameObj   `json:"currency"`
+	Cycle             int             `json:"cycle"`
+	Frequency         int             `json:"frequency"`
+	NextPayment       string          `json:"next_payment"`
+	StartDate         string          `json:"start_date"`
+	CategoryName      string          `json:"category_name"`
+	Category          wallosNameObj   `json:"category"`
+	URL               string          `json:"url"`
+	Notes             string          `json:"notes"`
+	PaymentMethodName string          `json:"payment_method_name"`
+	PaymentMethod     wallosNameObj   `json:"payment_method"`
+	Payments          []wallosPayment `json:"payments"`
+}
+
+// wallosPayment is an entry of the payment history nested in a Wallos subscription
+type wallosPayment struct {
+	Date         string          `json:"date"`
+	PaymentDate  string          `json:"payment_date"`
+	Price        json.RawMessage `json:"price"`
+	Amount       json.RawMessage `json:"amount"`
+	CurrencyCode string          `json:"currency_code"`
+	Currency     wallosNameObj   `json:"currency"`
+}
+
+// GetPrice returns the price as a string, handling both float and string JSON values
+func (ws *wallosSubscription) GetPrice() string {
+	return wallosNumber(ws.Price)
+}
+
+// GetCurrencyCode returns the currency code from either flat or nested format
+func (ws *wallosSubscription) GetCurrencyCode() string {
+	if ws.CurrencyCode != "" {
+		return ws.CurrencyCode
+	}
+	return ws.Currency.Name
+}
+
+// GetCategoryName returns the category name from either flat or nested format
+func (ws *wallosSubscription) GetCategoryName() string {
+	if ws.CategoryName != "" {
+		return ws.CategoryName
+	}
+	return ws.Category.Name
+}
+
+// GetPaymentMethodName returns the payment method from either flat or nested format
+func (ws *wallosSubscription) GetPaymentMethodName() string {
+	if ws.PaymentMethodName != "" {
+		return ws.PaymentMethodName
+	}
+	return ws.PaymentMethod.Name
+}
+
+// GetAmount returns the amount from either the price or the amount field
+func (wp *wallosPayment) GetAmount() string {
+	if wp.Price != nil {
+		return wallosNumber(wp.Price)
+	}
+	return wallosNumber(wp.Amount)
+}
+
+// GetDate returns the payment date from either the date or the payment_date field
+func (wp *wallosPayment) GetDate() string {
+	if wp.Date != "" {
+		return wp.Date
+	}
+	return wp.PaymentDate
+}
+
+// GetCurrencyCode returns the currency code from either flat or nested format
+func (wp *wallosPayment) GetCurrencyCode() string {
+	if wp.CurrencyCode != "" {
+		return wp.CurrencyCode
+	}
+	return wp.Currency.Name
+}
+
+// wallosNumber returns a number as a string, handling both float and string JSON values
+func wallosNumber(raw json.RawMessage) string {
+	if raw == nil {
+		return "0"
+	}
+	s := strings.TrimSpace(string(raw))
+	// Remove quotes if it's a JSON string
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+type wallosExport struct {
+	Subscriptions []wallosSubscription `json:"subscriptions"`
+}
+
+// subtrackrExport represents the SubTrackr JSON export format
+type subtrackrExport struct {
+	Subscriptions []models.Subscription `json:"subscriptions"`
+}
+
+// Import preview actions
+const (
+	ImportActionCreate = "create"
+	ImportActionSkip   = "skip"
+)
+
+// Category mapping outcomes shown in an import preview
+const (
+	CategoryMappingExisting = "existing"
+	CategoryMappingNew      = "new"
+	// CategoryMappingRule means a category rule maps the name to another category
+	CategoryMappingRule = "rule"
+)
+
+// importPreviewTTL is how long a staged import can be confirmed
+const importPreviewTTL = 30 * time.Minute
+
+// ErrImportPreviewNotFound is returned when a staged import does not exist or has expired
+var ErrImportPreviewNotFound = errors.New("import preview not found or expired")
+
+// ImportPreviewItem describes what would happen to a single entry of an import file
+type ImportPreviewItem struct {
+	Name            string  `json:"name"`
+	Cost            float64 `json:"cost"`
+	Currency        string  `json:"currency"`
+	Schedule        string  `json:"schedule"`
+	Category        string  `json:"category"`
+	CategoryMapping string  `json:"category_mapping,omitempty"`
+	// MappedCategory is the category a rule maps Category to
+	MappedCategory string `json:"mapped_category,omitempty"`
+	Action         string `json:"action"`
+	// Payments is the number of past payments found in the file
+	Payments int `json:"payments,omitempty"`
+}
+
+// ImportPreview is the result of a dry-run import. Nothing is written until the
+// preview is confirmed with its token.
+type ImportPreview struct {
+	Token         string              `json:"token"`
+	Format        string              `json:"format"`
+	Items         []ImportPreviewItem `json:"items"`
+	ToCreate      int                 `json:"to_create"`
+	ToSkip        int                 `json:"to_skip"`
+	NewCategories []string            `json:"new_categories"`
+	Payments      int                 `json:"payments"` // Past payments of the entries to create
+	ExpiresAt     time.Time           `json:"expires_at"`
+	ParseErrors   []string            `json:"parse_errors,omitempty"`
+}
+
+// stagedSubscription is a parsed import entry that has not been written yet
+type stagedSubscription struct {
+	sub          models.Subscription
+	categoryName string
+	// logo is an embedded logo from a bundle, stored when the import is applied
+	logo    []byte
+	logoExt string
+	// payments is the payment history of a Wallos entry
+	payments []models.Payment
+}
+
+// stagedImport holds parsed entries between preview and confirmation
+type stagedImport struct {
+	format    string
+	items     []stagedSubscription
+	expiresAt time.Time
+}
+
+// ImportService imports subscriptions from Wallos and SubVault/SubTrackr exports
+// and .svbundle archives
+type ImportService struct {
+	subscriptions SubscriptionServiceInterface
+	categories    CategoryServiceInterface
+	rules         CategoryRuleServiceInterface
+	renewal       RenewalServiceInterface
+	batches       *repository.ImportBatchRepository
+	logosDir      string
+
+	mu      sync.Mutex
+	staging map[string]stagedImport
+}
+
+func NewImportService(subscriptions SubscriptionServiceInterface, categories CategoryServiceInterface, rules CategoryRuleServiceInterface, renewal RenewalServiceInterface, batches *repository.ImportBatchRepository, logosDir string) *ImportService {
+	return &ImportService{
+		subscriptions: subscriptions,
+		categories:    categories,
+		rules:         rules,
+		renewal:       renewal,
+		batches:       batches,
+		logosDir:      logosDir,
+		staging:       make(map[string]stagedImport),
+	}
+}
+
+// Import imports subscriptions from raw JSON data. An empty format is auto-detected.
+// All entries are written in one transaction as an import batch that can be undone.
+func (s *ImportService) Import(ctx context.Context, data []byte, format string, opts ImportOptions) (ImportResult, error) {
+	if format == "" {
+		format = s.DetectFormat(data)
+	}
+	items, err := s.parse(data, format)
+	if err != nil {
+		if errors.Is(err, ErrUnknownImportFormat) {
+			return ImportResult{}, err
+		}
+		return parseErrorResult(err), nil
+	}
+	return s.apply(ctx, items, format, opts), nil
+}
+
+// ImportEncrypted decrypts an AES-256-GCM encrypted backup (.stbk) and imports it
+func (s *ImportService) ImportEncrypted(ctx context.Context, data []byte, password string) (ImportResult, error) {
+	decrypted, err := crypto.Decrypt(data, password)
+	if err != nil {
+		return ImportResult{}, ErrDecryptionFailed
+	}
+
+	// Re-import using the SubTrackr format
+	return s.Import(ctx, decrypted, "subtrackr", ImportOptions{})
+}
+
+// Preview parses the data and stages it without writing anything.
+// The returned preview lists what would be created or skipped and how categories map.
+func (s *ImportService) Preview(ctx context.Context, data []byte, format string) (*ImportPreview, error) {
+	if format == "" {
+		format = s.DetectFormat(data)
+	}
+	items, err := s.parse(data, format)
+	if err != nil {
+		if errors.Is(err, ErrUnknownImportFormat) {
+			return nil, err
+		}
+		return &ImportPreview{Format: format, ParseErrors: []string{fmt.Sprintf("Parse error: %s", err.Error())}}, nil
+	}
+	return s.stage(ctx, items, format)
+}
+
+// stage plans parsed entries and keeps them until the preview is confirmed
+func (s *ImportService) stage(ctx context.Context, items []stagedSubscription, format string) (*ImportPreview, error) {
+	preview, err := s.plan(ctx, items, format)
+	if err != nil {
+		return nil, err
+	}
+	preview.Format = format
+
+	token, err := generateImportToken()
+	if err != nil {
+		return nil, err
+	}
+	preview.Token = token
+	preview.ExpiresAt = time.Now().Add(importPreviewTTL)
+
+	s.mu.Lock()
+	s.pruneExpiredLocked()
+	s.staging[token] = stagedImport{format: format, items: items, expiresAt: preview.ExpiresAt}
+	s.mu.Unlock()
+
+	return preview, nil
+}
+
+// PreviewEncrypted decrypts an encrypted backup (.stbk) and stages it like Preview
+func (s *ImportService) PreviewEncrypted(ctx context.Context, data []byte, password string) (*ImportPreview, error) {
+	decrypted, err := crypto.Decrypt(data, password)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return s.Preview(ctx, decrypted, "subtrackr")
+}
+
+// Confirm writes a previously staged import. Duplicates are re-checked against
+// the current database, so entries added since the preview are still skipped.
+func (s *ImportService) Confirm(ctx context.Context, token string, opts ImportOptions) (ImportResult, error) {
+	s.mu.Lock()
+	staged, ok := s.staging[token]
+	delete(s.staging, token)
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(staged.expiresAt) {
+		return ImportResult{}, ErrImportPreviewNotFound
+	}
+	return s.apply(ctx, staged.items, staged.format, opts), nil
+}
+
+// Discard drops a staged import without writing anything
+func (s *ImportService) Discard(token string) {
+	s.mu.Lock()
+	delete(s.staging, token)
+	s.mu.Unlock()
+}
+
+// ListBatches returns all import batches, newest first
+func (s *ImportService) ListBatches() ([]models.ImportBatch, error) {
+	return s.batches.GetAll()
+}
+
+// UndoBatch deletes every subscription created by the given import batch, plus
+// categories the batch created that are no longer in use.
+// Returns the number of deleted subscriptions.
+func (s *ImportService) UndoBatch(id uint) (int64, error) {
+	deleted, err := s.batches.Delete(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrImportBatchNotFound
+	}
+	return deleted, err
+}
+
+// DetectFormat determines the export format of raw data.
+// Returns "svbundle", "wallos", "subtrackr" or "" if unknown.
+func (s *ImportService) DetectFormat(data []byte) string {
+	if isBundle(data) {
+		return BundleFormat
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return ""
+	}
+
+	// SubTrackr exports have "exported_at" and "total_count"
+	if _, ok := raw["exported_at"]; ok {
+		return "subtrackr"
+	}
+
+	// Check if subscriptions array contains Wallos-specific fields
+	if subsData, ok := raw["subscriptions"]; ok {
+		var subs []map[string]interface{}
+		if err := json.Unmarshal(subsData, &subs); err == nil && len(subs) > 0 {
+			if _, hasCycle := subs[0]["cycle"]; hasCycle {
+				return "wallos"
+			}
+			if _, hasSchedule := subs[0]["schedule"]; hasSchedule {
+				return "subtrackr"
+			}
+		}
+	}
+
+	return ""
+}
+
+// parse converts raw data in the given format into staged subscriptions
+func (s *ImportService) parse(data []byte, format string) ([]stagedSubscription, error) {
+	if format == "" {
+		format = s.DetectFormat(data)
+	}
+
+	switch format {
+	case "wallos":
+		return parseWallos(data)
+	case "subvault", "subtrackr":
+		return parseSubTrackr(data)
+	case BundleFormat:
+		return parseBundle(data)
+	default:
+		return nil, ErrUnknownImportFormat
+	}
+}
+
+func parseWallos(data []byte) ([]stagedSubscription, error) {
+	var export wallosExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+
+	items := make([]stagedSubscription, 0, len(export.Subscriptions))
+	for _, ws := range export.Subscriptions {
+		sub := models.Subscription{
+			Name:                   ws.Name,
+			OriginalCurrency:       ws.GetCurrencyCode(),
+			Status:                 "Active",
+			URL:                    ws.URL,
+			Notes:                  ws.Notes,
+			PaymentMethod:          ws.GetPaymentMethodName(),
+			DateCalculationVersion: 2,
+		}
+
+		// Parse price
+		price, err := i18n.ParseNumber(ws.GetPrice(), "")
+		if err != nil {
+			return nil, fmt.Errorf("%s has an invalid price %q", ws.Name, ws.GetPrice())
+		}
+		sub.Cost = price
+
+		// Map cycle to schedule
+		schedule := "Monthly"
+		switch ws.Cycle {
+		case 1:
+			schedule = "Daily"
+		case 2:
+			schedule = "Weekly"
+		case 3:
+			schedule = "Monthly"
+		case 4:
+			schedule = "Annual"
+		}
+		// Handle frequency multiplier
+		if ws.Frequency > 1 && ws.Cycle == 3 && ws.Frequency == 3 {
+			schedule = "Quarterly"
+		}
+		sub.Schedule = schedule
+
+		// Parse next_payment as renewal date
+		if ws.NextPayment != "" {
+			if t, err := time.Parse("2006-01-02", ws.NextPayment); err == nil {
+				sub.RenewalDate = &t
+			}
+		}
+
+		// Parse start_date if available
+		if ws.StartDate != "" {
+			if t, err := time.Parse("2006-01-02", ws.StartDate); err == nil {
+				sub.StartDate = &t
+			}
+		}
+
+		payments, err := parseWallosPayments(ws.Payments, sub.OriginalCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ws.Name, err)
+		}
+		items = append(items, stagedSubscription{sub: sub, categoryName: ws.GetCategoryName(), payments: payments})
+	}
+
+	return items, nil
+}
+
+// parseWallosPayments converts a Wallos payment history into confirmed payments.
+// Entries without a valid date are dropped, as are repeated dates, which the
+// ledger stores only once per subscription. An amount that is not a number
+// fails the whole history rather than recording a zero payment.
+func parseWallosPayments(history []wallosPayment, currency string) ([]models.Payment, error) {
+	var payments []models.Payment
+	seen := make(map[time.Time]bool)
+	for _, wp := range history {
+		date, ok := parseWallosDate(wp.GetDate())
+		if !ok || seen[date] {
+			continue
+		}
+		seen[date] = true
+
+		amount, err := i18n.ParseNumber(wp.GetAmount(), "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid payment amount %q on %s", wp.GetAmount(), date.Format("2006-01-02"))
+		}
+		paymentCurrency := wp.GetCurrencyCode()
+		if paymentCurrency == "" {
+			paymentCurrency = currency
+		}
+		paidAt := date
+		payments = append(payments, models.Payment{
+			DueDate:  date,
+			Amount:   amount,
+			Currency: paymentCurrency,
+			Status:   models.PaymentConfirmed,
+			PaidAt:   &paidAt,
+		})
+	}
+	return payments, nil
+}
+
+// parseWallosDate parses a Wallos date, with or without a time of day
+func parseWallosDate(value string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04:05", time.RFC3339} {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			y, m, d := t.Date()
+			return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseSubTrackr(data []byte) ([]stagedSubscription, error) {
+	var export subtrackrExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+
+	items := make([]stagedSubscription, 0, len(export.Subscriptions))
+	for _, sub := range export.Subscriptions {
+		// Reset ID and timestamps for re-import
+		newSub := sub
+		newSub.ID = 0
+		newSub.Category = models.Category{}
+		newSub.CategoryID = 0
+		newSub.CreatedAt = time.Time{}
+		newSub.UpdatedAt = time.Time{}
+		newSub.LastReminderSent = nil
+		newSub.LastReminderRenewalDate = nil
+		newSub.LastCancellationReminderSent = nil
+		newSub.LastCancellationReminderDate = nil
+		newSub.ImportBatchID = nil
+		newSub.NotifyChannels, _ = models.NormalizeNotifyChannels(sub.NotifyChannels) // unknown channels fall back to all
+		if !models.IsValidPurpose(sub.Purpose) {
+			newSub.Purpose = models.PurposePersonal
+		}
+		if !models.IsValidStatus(sub.Status) {
+			newSub.Status = models.StatusActive
+		}
+
+		items = append(items, stagedSubscription{sub: newSub, categoryName: sub.Category.Name})
+	}
+
+	return items, nil
+}
+
+// plan computes the preview for staged entries against the current database
+func (s *ImportService) plan(ctx context.Context, items []stagedSubscription, format string) (*ImportPreview, error) {
+	existing, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categories.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.rules.List()
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &ImportPreview{Items: make([]ImportPreviewItem, 0, len(items))}
+	newCategories := make(map[string]bool)
+
+	for _, item := range items {
+		previewItem := ImportPreviewItem{
+			Name:     item.sub.Name,
+			Cost:     item.sub.Cost,
+			Currency: item.sub.OriginalCurrency,
+			Schedule: item.sub.Schedule,
+			Category: item.categoryName,
+			Action:   ImportActionCreate,
+			Payments: len(item.payments),
+		}
+
+		if s.isDuplicate(existing, item.sub.Name, fmt.Sprintf("%.2f", item.sub.Cost)) {
+			previewItem.Action = ImportActionSkip
+			preview.ToSkip++
+		} else {
+			preview.ToCreate++
+			preview.Payments += len(item.payments)
+			if item.categoryName != "" {
+				if rule := matchCategoryRule(rules, format, item.categoryName); rule != nil {
+					previewItem.CategoryMapping = CategoryMappingRule
+					previewItem.MappedCategory = rule.Category.Name
+				} else if findCategory(categories, item.categoryName) != nil {
+					previewItem.CategoryMapping = CategoryMappingExisting
+				} else {
+					previewItem.CategoryMapping = CategoryMappingNew
+					key := strings.ToLower(item.categoryName)
+					if !newCategories[key] {
+						newCategories[key] = true
+						preview.NewCategories = append(preview.NewCategories, item.categoryName)
+					}
+				}
+			}
+		}
+
+		preview.Items = append(preview.Items, previewItem)
+	}
+
+	return preview, nil
+}
+
+// apply writes staged entries as a single import batch, skipping duplicates of
+// existing subscriptions. If any entry fails, nothing is written.
+func (s *ImportService) apply(ctx context.Context, items []stagedSubscription, format string, opts ImportOptions) ImportResult {
+	result := ImportResult{}
+
+	if len(items) == 0 {
+		result.Details = append(result.Details, "No subscriptions found in file")
+		return result
+	}
+
+	existing, _ := s.subscriptions.GetAll(ctx)
+	categories, err := s.categories.GetAll()
+	if err != nil {
+		slog.Error("failed to load categories for import", "error", err)
+		return failedImportResult(err)
+	}
+	rules, err := s.rules.List()
+	if err != nil {
+		slog.Error("failed to load category rules for import", "error", err)
+		return failedImportResult(err)
+	}
+
+	// Subscriptions without a category go to the default one
+	var defaultCategoryID uint
+	if def, err := s.categories.GetDefault(); err == nil {
+		defaultCategoryID = def.ID
+	}
+
+	newCategories := make(map[string]*models.Category)
+	entries := make([]repository.ImportBatchEntry, 0, len(items))
+
+	for _, item := range items {
+		sub := item.sub
+
+		// Duplicate check
+		if s.isDuplicate(existing, sub.Name, fmt.Sprintf("%.2f", sub.Cost)) {
+			result.Skipped++
+			result.Details = append(result.Details, fmt.Sprintf("Skipped (duplicate): %s", sub.Name))
+			continue
+		}
+
+		entry := repository.ImportBatchEntry{Subscription: &sub}
+		if opts.Payments {
+			entry.Payments = slices.Clone(item.payments)
+		}
+
+		// Map category through the rules, then by name, creating it with the
+		// batch if it does not exist
+		if item.categoryName != "" {
+			if rule := matchCategoryRule(rules, format, item.categoryName); rule != nil {
+				sub.CategoryID = rule.CategoryID
+			} else if cat := findCategory(categories, item.categoryName); cat != nil {
+				sub.CategoryID = cat.ID
+			} else {
+				key := strings.ToLower(item.categoryName)
+				if newCategories[key] == nil {
+					newCategories[key] = &models.Category{Name: item.categoryName}
+				}
+				entry.NewCategory = newCategories[key]
+			}
+		}
+		if sub.CategoryID == 0 && entry.NewCategory == nil {
+			sub.CategoryID = defaultCategoryID
+		}
+
+		if item.logo != nil {
+			iconURL, err := storeLogo(s.logosDir, item.logo, item.logoExt)
+			if err != nil {
+				slog.Warn("failed to store imported logo", "subscription", sub.Name, "error", err)
+			} else {
+				sub.IconURL = iconURL
+			}
+		}
+
+		s.renewal.NormalizeStatus(&sub, "", time.Now())
+		s.renewal.InitializeRenewalDate(&sub)
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return result
+	}
+
+	batch := &models.ImportBatch{Format: format}
+	if err := s.batches.Create(batch, entries); err != nil {
+		slog.Error("import failed, transaction rolled back", "format", format, "error", err)
+		failed := failedImportResult(err)
+		failed.Skipped = result.Skipped
+		failed.Details = append(result.Details, failed.Details...)
+		return failed
+	}
+
+	result.Imported = len(entries)
+	result.BatchID = batch.ID
+	for _, entry := range entries {
+		result.Payments += len(entry.Payments)
+	}
+	return result
+}
+
+func (s *ImportService) isDuplicate(existing []models.Subscription, name string, price string) bool {
+	for _, sub := range existing {
+		if strings.EqualFold(sub.Name, name) && fmt.Sprintf("%.2f", sub.Cost) == price {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneExpiredLocked removes expired staged imports. Caller must hold s.mu.
+func (s *ImportService) pruneExpiredLocked() {
+	now := time.Now()
+	for token, staged := range s.staging {
+		if now.After(staged.expiresAt) {
+			delete(s.staging, token)
+		}
+	}
+}
+
+func findCategory(categories []models.Category, name string) *models.Category {
+	for i := range categories {
+		if strings.EqualFold(categories[i].Name, name) {
+			return &categories[i]
+		}
+	}
+	return nil
+}
+
+func parseErrorResult(err error) ImportResult {
+	return ImportResult{
+		Errors:  1,
+		Details: []string{fmt.Sprintf("Parse error: %s", err.Error())},
+	}
+}
+
+func failedImportResult(err error) ImportResult {
+	return ImportResult{
+		Errors:  1,
+		Details: []string{fmt.Sprintf("Import failed, no changes were saved: %s", err.Error())},
+	}
+}
+
+func generateImportToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupImportExportServices(t *testing.T) (*SubscriptionService, *ImportService, *ExportService) {
+	return newImportExportServices(t, setupRenewalReminderTestDB(t))
+}
+
+func newImportExportServices(t *testing.T, db *gorm.DB) (*SubscriptionService, *ImportService, *ExportService) {
+	require.NoError(t, db.AutoMigrate(&models.ImportBatch{}, &models.UsageEvent{}, &models.SubscriptionShare{}, &models.ReminderRetry{}, &models.Payment{}, &models.CategoryRule{}))
+	services := newTestSubscriptionService(t, db)
+
+	ruleService := NewCategoryRuleService(repository.NewCategoryRuleRepository(db), services.categories)
+	importService := NewImportService(services.subscriptions, services.categories, ruleService, NewRenewalService(), repository.NewImportBatchRepository(db), t.TempDir())
+
+	return services.subscriptions, importService, NewExportService(services.subscriptions, services.preferences)
+}
+
+func TestImportService_DetectFormat(t *testing.T) {
+	_, importService, _ := setupImportExportServices(t)
+
+	assert.Equal(t, "subtrackr", importService.DetectFormat([]byte(`{"subscriptions":[],"exported_at":"2026-01-01T00:00:00Z"}`)))
+	assert.Equal(t, "wallos", importService.DetectFormat([]byte(`{"subscriptions":[{"name":"A","cycle":3}]}`)))
+	assert.Equal(t, "subtrackr", importService.DetectFormat([]byte(`{"subscriptions":[{"name":"A","schedule":"Monthly"}]}`)))
+	assert.Equal(t, "", importService.DetectFormat([]byte(`not json`)))
+}
+
+func TestImportService_ImportWallos(t *testing.T) {
+	subscriptionService, importService, _ := setupImportExportServices(t)
+
+	data := []byte(`{"subscriptions":[{"name":"Netflix","price":"12.99","currency":{"name":"EUR"},"cycle":4,"next_payment":"2026-11-01","category":{"name":"Streaming"}}]}`)
+
+	result, err := importService.Import(t.Context(), data, "", ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+
+	subs, err := subscriptionService.GetAll(t.Context())
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "Annual", subs[0].Schedule)
+	assert.Equal(t, 12.99, subs[0].Cost)
+	assert.Equal(t, "Streaming", subs[0].Category.Name)
+
+	// Importing the same file again skips the duplicate
+	result, err = importService.Import(t.Context(), data, "", ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 1, result.Skipped)
+}
+
+func TestImportService_ImportWallosPayments(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	_, importService, _ := newImportExportServices(t, db)
+
+	data := []byte(`{"subscriptions":[{"name":"Netflix","price":"12.99","currency":{"name":"EUR"},"cycle":3,"payments":[
+		{"date":"2026-01-01","price":"11.99"},
+		{"date":"2026-02-01 08:30:00","price":12.99,"currency_code":"USD"},
+		{"date":"2026-02-01","price":"12.99"},
+		{"date":"not a date","price":"12.99"}
+	]}]}`)
+
+	preview, err := importService.Preview(t.Context(), data, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, preview.Payments)
+	assert.Equal(t, 2, preview.Items[0].Payments)
+
+	result, err := importService.Confirm(t.Context(), preview.Token, ImportOptions{Payments: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 2, result.Payments)
+
+	var payments []models.Payment
+	require.NoError(t, db.Order("due_date").Find(&payments).Error)
+	require.Len(t, payments, 2)
+	assert.Equal(t, 11.99, payments[0].Amount)
+	assert.Equal(t, "EUR", payments[0].Currency)
+	assert.Equal(t, models.PaymentConfirmed, payments[0].Status)
+	assert.Equal(t, "USD", payments[1].Currency)
+
+	// Undoing the batch removes the payments as well
+	_, err = importService.UndoBatch(result.BatchID)
+	require.NoError(t, err)
+	var count int64
+	require.NoError(t, db.Model(&models.Payment{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	// Without the option the history is left out
+	result, err = importService.Import(t.Context(), data, "", ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Zero(t, result.Payments)
+	require.NoError(t, db.Model(&models.Payment{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestImportService_ImportWallosPrices(t *testing.T) {
+	subscriptionService, importService, _ := setupImportExportServices(t)
+
+	result, err := importService.Import(t.Context(), []byte(`{"subscriptions":[
+		{"name":"Netflix","price":"9,99","cycle":3},
+		{"name":"Office","price":"1.234,50","cycle":4}
+	]}`), "", ImportOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Imported)
+
+	subs, err := subscriptionService.GetAll(t.Context())
+	require.NoError(t, err)
+	costs := map[string]float64{}
+	for _, sub := range subs {
+		costs[sub.Name] = sub.Cost
+	}
+	assert.Equal(t, map[string]float64{"Netflix": 9.99, "Office": 1234.5}, costs)
+
+	// A price that is not a number fails the import instead of a zero cost
+	preview, err := importService.Preview(t.Context(), []byte(`{"subscriptions":[{"name":"Spotify","price":"free","cycle":3}]}`), "")
+	require.NoError(t, err)
+	require.Len(t, preview.ParseErrors, 1)
+	assert.Contains(t, preview.ParseErrors[0], `Spotify has an invalid price "free"`)
+	assert.Empty(t, preview.Items)
+
+	result, err = importService.Import(t.Context(), []byte(`{"subscriptions":[{"name":"Spotify","price":"9.99","cycle":3,"payments":[{"date":"2026-01-01","price":"n/a"}]}]}`), "", ImportOptions{Payments: true})
+	require.NoError(t, err)
+	assert.Zero(t, result.Imported)
+	assert.Equal(t, 1, result.Errors)
+	require.Len(t, result.Details, 1)
+	assert.Contains(t, result.Details[0], "invalid payment amount")
+}
+
+func TestImportService_DefaultCategory(t *testing.T) {
+	subscriptionService, importService, _ := setupImportExportServices(t)
+
+	def, err := importService.categories.Create(&models.Category{Name: "General", IsDefault: true})
+	require.NoError(t, err)
+
+	result, err := importService.Import(t.Context(), []byte(`{"subscriptions":[{"name":"Netflix","price":"12.99","cycle":3}]}`), "", ImportOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Imported)
+
+	subs, err := subscriptionService.GetAll(t.Context())
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, def.ID, subs[0].CategoryID)
+}
+
+func TestImportService_UnknownFormat(t *testing.T) {
+	_, importService, _ := setupImportExportServices(t)
+
+	_, err := importService.Import(t.Context(), []byte(`{"foo":1}`), "", ImportOptions{})
+	assert.ErrorIs(t, err, ErrUnknownImportFormat)
+}
+
+func TestExportService_RoundTrip(t *testing.T) {
+	_, importService, exportService := setupImportExportServices(t)
+
+	_, err := importService.Import(t.Context(), []byte(`{"subscriptions":[{"name":"Spotify","price":9.99,"cycle":3,"category_name":"Music"}]}`), "wallos", ImportOptions{})
+	require.NoError(t, err)
+
+	export, err := exportService.BuildJSONExport(t.Context(), models.SubscriptionFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, export.TotalCount)
+
+	var csvBuf bytes.Buffer
+	require.NoError(t, exportService.WriteAllCSV(t.Context(), &csvBuf, models.SubscriptionFilter{}, nil))
+	assert.Contains(t, csvBuf.String(), "Spotify,Music,9.99")
+
+	// Encrypted backup imports into a fresh database
+	encrypted, err := exportService.BuildEncryptedBackup(t.Context(), "secret")
+	require.NoError(t, err)
+
+	freshSubs, freshImport, _ := setupImportExportServices(t)
+	_, err = freshImport.ImportEncrypted(t.Context(), encrypted, "wrong")
+	assert.ErrorIs(t, err, ErrDecryptionFailed)
+
+	result, err := freshImport.ImportEncrypted(t.Context(), encrypted, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+
+	subs, err := freshSubs.GetAll(t.Context())
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "Music", subs[0].Category.Name)
+
+	// The JSON export is accepted by the importer
+	jsonData, err := json.Marshal(export)
+	require.NoError(t, err)
+	assert.Equal(t, "subtrackr", freshImport.DetectFormat(jsonData))
+}
+
+func TestExportService_CSVColumns(t *testing.T) {
+	subscriptionService, _, exportService := setupImportExportServices(t)
+	_, err := subscriptionService.Create(t.Context(), &models.Subscription{Name: "Spotify", Cost: 9.99, Schedule: "Monthly", Status: "Active"})
+	require.NoError(t, err)
+
+	// Columns passed explicitly are written in their order
+	var buf bytes.Buffer
+	require.NoError(t, exportService.WriteAllCSV(t.Context(), &buf, models.SubscriptionFilter{}, []string{"cost", "name"}))
+	assert.Equal(t, "Cost,Name\n9.99,Spotify\n", buf.String())
+
+	// Otherwise the saved preference applies
+	require.NoError(t, exportService.preferences.SetCSVColumns([]string{"Name", " status ", ""}))
+	assert.Equal(t, []string{"name", "status"}, exportService.preferences.GetCSVColumns())
+	buf.Reset()
+	require.NoError(t, exportService.WriteAllCSV(t.Context(), &buf, models.SubscriptionFilter{}, nil))
+	assert.Equal(t, "Name,Status\nSpotify,Active\n", buf.String())
+
+	for _, columns := range [][]string{nil, {""}, {"name", "unknown"}, {"name", "NAME"}} {
+		assert.ErrorIs(t, exportService.preferences.SetCSVColumns(columns), ErrInvalidCSVColumns, "%v", columns)
+	}
+	assert.Equal(t, []string{"name", "status"}, exportService.preferences.GetCSVColumns())
+	assert.Len(t, DefaultCSVColumns(), len(CSVColumns()))
+}
+
+func TestImportService_PreviewAndConfirm(t *testing.T) {
+	subscriptionService, importService, _ := setupImportExportServices(t)
+
+	_, err := importService.Import(t.Context(), []byte(`{"subscriptions":[{"name":"Netflix","price":"12.99","cycle":3,"category_name":"Streaming"}]}`), "wallos", ImportOptions{})
+	require.NoError(t, err)
+
+	data := []byte(`{"subscriptions":[
+		{"name":"Netflix","price":"12.99","cycle":3,"category_name":"Streaming"},
+		{"name":"Disney+","price":"8.99","cycle":3,"category_name":"streaming"},
+		{"name":"Notion","price":"10","cycle":4,"category_name":"Productivity"}
+	]}`)
+
+	preview, err := importService.Preview(t.Context(), data, "")
+	require.NoError(t, err)
+	assert.Equal(t, "wallos", preview.Format)
+	assert.NotEmpty(t, preview.Token)
+	assert.Equal(t, 2, preview.ToCreate)
+	assert.Equal(t, 1, preview.ToSkip)
+	assert.Equal(t, []string{"Productivity"}, preview.NewCategories)
+	require.Len(t, preview.Items, 3)
+	assert.Equal(t, ImportActionSkip, preview.Items[0].Action)
+	assert.Equal(t, CategoryMappingExisting, preview.Items[1].CategoryMapping)
+	assert.Equal(t, CategoryMappingNew, preview.Items[2].CategoryMapping)
+
+	// Nothing is written by the preview
+	subs, err := subscriptionService.GetAll(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, subs, 1)
+
+	result, err := importService.Confirm(t.Context(), preview.Token, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 1, result.Skipped)
+
+	// A token can only be confirmed once
+	_, err = importService.Confirm(t.Context(), preview.Token, ImportOptions{})
+	assert.ErrorIs(t, err, ErrImportPreviewNotFound)
+}
+
+func TestImportService_Discard(t *testing.T) {
+	subscriptionService, importService, _ := setupImportExportServices(t)
+
+	preview, err := importService.Preview(t.Context(), []byte(`{"subscriptions":[{"name":"Spotify","price":9.99,"cycle":3}]}`), "")
+	require.NoError(t, err)
+
+	importService.Discard(preview.Token)
+	_, err = importService.Confirm(t.Context(), preview.Token, ImportOptions{})
+	assert.ErrorIs(t, err, ErrImportPreviewNotFound)
+
+	subs, err := subscriptionService.GetAll(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+}
+
+func TestImportService_UndoBatch(t *testing.T) {
+	subscriptionService, importService, _ := setupImportExportServices(t)
+
+	first, err := importService.Import(t.Context(), []byte(`{"subscriptions":[{"name":"Netflix","price":"12.99","cycle":3,"category_name":"Streaming"}]}`), "", ImportOptions{})
+	require.NoError(t, err)
+	require.NotZero(t, first.BatchID)
+
+	second, err := importService.Import(t.Context(), []byte(`{"subscriptions":[
+		{"name":"Disney+","price":"8.99","cycle":3,"category_name":"Streaming"},
+		{"name":"Notion","price":"10","cycle":4,"category_name":"Productivity"}
+	]}`), "", ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.Imported)
+
+	batches, err := importService.ListBatches()
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Equal(t, second.BatchID, batches[0].ID)
+	assert.Equal(t, 2, batches[0].SubscriptionCount)
+	assert.Equal(t, 1, batches[0].CategoryCount)
+
+	deleted, err := importService.UndoBatch(second.BatchID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	subs, err := subscriptionService.GetAll(t.Context())
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "Netflix", subs[0].Name)
+
+	// The category created by the undone batch is removed, the one still in use is kept
+	categories, err := importService.categories.GetAll()
+	require.NoError(t, err)
+	var names []string
+	for _, cat := range categories {
+		names = append(names, cat.Name)
+	}
+	assert.Contains(t, names, "Streaming")
+	assert.NotContains(t, names, "Productivity")
+
+	_, err = importService.UndoBatch(second.BatchID)
+	assert.ErrorIs(t, err, ErrImportBatchNotFound)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+)
+
+// inboundAutoConfirmConfidence is the match confidence at which a receipt
+// confirms a renewal instead of waiting as a pending subscription
+const inboundAutoConfirmConfidence = 0.8
+
+// inboundMaxPending is how many unmatched receipts are kept for review
+const inboundMaxPending = 100
+
+// What happened to a received email
+const (
+	InboundIgnored         = "ignored"          // No charge was found in the email
+	InboundPaymentRecorded = "payment_recorded" // The charge confirmed a renewal
+	InboundDuplicate       = "duplicate"        // The renewal was already confirmed
+	InboundPending         = "pending"          // The charge waits to be added as a subscription
+)
+
+// InboundResult reports what a received email was recorded as
+type InboundResult struct {
+	Status         string  `json:"status"`
+	Merchant       string  `json:"merchant,omitempty"`
+	Amount         float64 `json:"amount,omitempty"`
+	Currency       string  `json:"currency,omitempty"`
+	SubscriptionID uint    `json:"subscription_id,omitempty"`
+	PendingID      uint    `json:"pending_id,omitempty"`
+}
+
+// InboundEmailService turns receipts forwarded by an email provider's inbound
+// route (Mailgun, SES) into payments of the subscriptions they pay, or into
+// pending subscriptions when they match none. The webhook URL contains a
+// secret token managed like the calendar feed token.
+type InboundEmailService struct {
+	settings      *SettingsService
+	emails        *repository.InboundEmailRepository
+	reconcile     *ReconcileService
+	subscriptions SubscriptionServiceInterface
+	vendors       VendorServiceInterface
+	preferences   PreferencesServiceInterface
+	httpClient    *http.Client
+}
+
+func NewInboundEmailService(settings *SettingsService, emails *repository.InboundEmailRepository, reconcile *ReconcileService, subscriptions SubscriptionServiceInterface, vendors VendorServiceInterface, preferences PreferencesServiceInterface) *InboundEmailService {
+	return &InboundEmailService{
+		settings:      settings,
+		emails:        emails,
+		reconcile:     reconcile,
+		subscriptions: subscriptions,
+		vendors:       vendors,
+		preferences:   preferences,
+		httpClient:    NewHTTPClient(15 * time.Second),
+	}
+}
+
+// GenerateToken creates a new webhook token, replacing the previous one
+func (s *InboundEmailService) GenerateToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	token := fmt.Sprintf("%x", bytes)
+	if err := s.settings.Repo().Set(SettingKeyInboundEmailToken, token); err != nil {
+		return "", err
+	}
+	s.settings.InvalidateCache()
+	return token, nil
+}
+
+// Token returns the webhook token, empty when the webhook is disabled
+func (s *InboundEmailService) Token() string {
+	token, _ := s.settings.GetCached(SettingKeyInboundEmailToken)
+	return token
+}
+
+// RevokeToken disables the webhook
+func (s *InboundEmailService) RevokeToken() error {
+	defer s.settings.InvalidateCache()
+	return s.settings.Repo().Set(SettingKeyInboundEmailToken, "")
+}
+
+// ValidToken reports whether token is the webhook token
+func (s *InboundEmailService) ValidToken(token string) bool {
+	stored := s.Token()
+	return stored != "" && subtle.ConstantTimeCompare([]byte(stored), []byte(token)) == 1
+}
+
+// Receive records the charge of a receipt. A charge that clearly pays a
+// subscription confirms its renewal in the payment ledger; any other charge is
+// kept as a pending subscription. Emails without a charge are ignored.
+func (s *InboundEmailService) Receive(ctx context.Context, msg InboundMessage) (*InboundResult, error) {
+	amount, currency := ExtractCharge(msg.Subject + "\n" + msg.Text)
+	if amount <= 0 {
+		return &InboundResult{Status: InboundIgnored}, nil
+	}
+	amount = roundCents(amount)
+	if currency == "" {
+		currency = s.preferences.GetCurrency()
+	}
+
+	subs, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vendors, err := s.vendors.List()
+	if err != nil {
+		return nil, err
+	}
+	merchant, vendorID := detectMerchant(senderOf(msg), subs, vendors)
+
+	date := msg.Date
+	if date.IsZero() || date.After(time.Now()) {
+		date = time.Now()
+	}
+	tx := BankTransaction{Date: date, Amount: amount, Currency: currency, Description: strings.TrimSpace(merchant + " " + msg.Subject)}
+	result := &InboundResult{Merchant: merchant, Amount: amount, Currency: currency}
+
+	preview, err := s.reconcile.analyze(ctx, []BankTransaction{tx}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(preview.Matches) > 0 && preview.Matches[0].Confidence >= inboundAutoConfirmConfidence {
+		match := preview.Matches[0]
+		recorded, err := s.reconcile.record([]ReconcileMatch{match})
+		if err != nil {
+			return nil, err
+		}
+		result.Merchant = match.Name
+		result.SubscriptionID = match.SubscriptionID
+		result.Status = InboundPaymentRecorded
+		if recorded.Skipped > 0 {
+			result.Status = InboundDuplicate
+		}
+		return result, nil
+	}
+
+	pending := &models.InboundEmail{
+		Sender:     truncate(msg.From, 255),
+		Subject:    truncate(msg.Subject, 255),
+		Merchant:   truncate(merchant, 100),
+		Amount:     amount,
+		Currency:   currency,
+		VendorID:   vendorID,
+		ReceivedAt: date,
+	}
+	if err := s.emails.Create(pending); err != nil {
+		return nil, err
+	}
+	if err := s.emails.Trim(inboundMaxPending); err != nil {
+		return nil, err
+	}
+	result.Status = InboundPending
+	result.PendingID = pending.ID
+	return result, nil
+}
+
+// Pending returns the receipts that matched no subscription, newest first
+func (s *InboundEmailService) Pending() ([]models.InboundEmail, error) {
+	return s.emails.GetAll()
+}
+
+// Dismiss removes a pending receipt
+func (s *InboundEmailService) Dismiss(id uint) error {
+	return s.emails.Delete(id)
+}
+
+// ConfirmSNSSubscription confirms the SNS topic subscription of an SES
+// receipt rule. subscribeURL must come from ParseSNSEnvelope, which only
+// accepts SNS endpoints.
+func (s *InboundEmailService) ConfirmSNSSubscription(subscribeURL string) error {
+	resp, err := s.httpClient.Get(subscribeURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("SNS subscription confirmation returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// truncate shortens text to at most n bytes without splitting a character
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
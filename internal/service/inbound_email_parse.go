@@ -0,0 +1,436 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// inboundMaxText limits how much of an email body is searched for a charge
+const inboundMaxText = 64 << 10
+
+// inboundMaxMIMEDepth limits how deeply nested multipart bodies are read
+const inboundMaxMIMEDepth = 5
+
+// ErrInvalidInboundEmail is returned when an inbound payload holds no readable email
+var ErrInvalidInboundEmail = errors.New("invalid inbound email")
+
+// InboundMessage is a received email reduced to what charge detection reads
+type InboundMessage struct {
+	From    string    `json:"from"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`
+	Date    time.Time `json:"date"`
+}
+
+// emailSenderRules names the merchant of well-known sender domains whose
+// display names are not the service name, like "Apple" for App Store receipts
+var emailSenderRules = map[string]string{
+	"netflix.com":    "Netflix",
+	"spotify.com":    "Spotify",
+	"apple.com":      "Apple",
+	"google.com":     "Google",
+	"youtube.com":    "YouTube",
+	"amazon.com":     "Amazon",
+	"amazon.de":      "Amazon",
+	"amazon.co.uk":   "Amazon",
+	"disneyplus.com": "Disney+",
+	"adobe.com":      "Adobe",
+	"microsoft.com":  "Microsoft",
+	"dropbox.com":    "Dropbox",
+	"github.com":     "GitHub",
+	"openai.com":     "OpenAI",
+	"anthropic.com":  "Anthropic",
+	"notion.so":      "Notion",
+	"1password.com":  "1Password",
+}
+
+// emailGenericNames are sender display name words that do not name a merchant
+var emailGenericNames = map[string]bool{
+	"no": true, "reply": true, "noreply": true, "donotreply": true, "do": true, "not": true,
+	"billing": true, "receipts": true, "receipt": true, "invoice": true, "invoices": true,
+	"payments": true, "payment": true, "team": true, "support": true, "info": true,
+	"account": true, "accounts": true, "service": true, "notifications": true, "via": true,
+	"the": true, "rechnung": true, "rechnungen": true,
+}
+
+var (
+	chargeAmountPattern = `(\d{1,3}(?:[.,']\d{3})+(?:[.,]\d{1,2})?|\d+(?:[.,]\d{1,2})?)`
+	chargeSymbolPattern = currencySymbolPattern()
+	chargeSymbolBefore  = regexp.MustCompile(`(` + chargeSymbolPattern + `)\s?` + chargeAmountPattern + `\b`)
+	chargeCodeBefore    = regexp.MustCompile(`\b([A-Z]{3})\s?` + chargeAmountPattern + `\b`)
+	chargeAfter         = regexp.MustCompile(`\b` + chargeAmountPattern + `\s?(` + chargeSymbolPattern + `|\b[A-Z]{3}\b)`)
+	chargeTotalLine     = regexp.MustCompile(`(?i)\b(?:total|amount|charged|paid|betrag|summe|gesamt|gesamtbetrag|insgesamt|zahlbetrag)\b`)
+	forwardedSubject    = regexp.MustCompile(`(?i)^\s*(?:fwd?|wg|aw):`)
+	forwardedFrom       = regexp.MustCompile(`(?im)^\s*[>*]*\s*(?:from|von):\s*\**\s*(.*@.*?)\s*$`)
+	htmlDropped         = regexp.MustCompile(`(?is)<(style|script|head)\b.*?</(?:style|script|head)>`)
+	htmlBreak           = regexp.MustCompile(`(?i)<(?:br|/p|/div|/tr|/li|/h\d)\b[^>]*>`)
+	htmlTag             = regexp.MustCompile(`<[^>]*>`)
+	blankRuns           = regexp.MustCompile(`[ \t\r\f\v]+`)
+	snsHost             = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(?:\.cn)?$`)
+)
+
+// currencySymbolPattern is an alternation of the known currency symbols, longest first
+func currencySymbolPattern() string {
+	symbols := make([]string, len(quickCurrencySymbols))
+	for i, s := range quickCurrencySymbols {
+		symbols[i] = regexp.QuoteMeta(s.symbol)
+	}
+	return strings.Join(symbols, "|")
+}
+
+// ParseRawEmail reads an RFC 5322 email, preferring its plain text body over
+// the HTML one
+func ParseRawEmail(raw []byte) (InboundMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return InboundMessage{}, fmt.Errorf("%w: %v", ErrInvalidInboundEmail, err)
+	}
+
+	decoder := new(mime.WordDecoder)
+	header := func(name string) string {
+		value, err := decoder.DecodeHeader(msg.Header.Get(name))
+		if err != nil {
+			return msg.Header.Get(name)
+		}
+		return value
+	}
+	parsed := InboundMessage{From: header("From"), Subject: header("Subject")}
+	if date, err := msg.Header.Date(); err == nil {
+		parsed.Date = date
+	}
+
+	plain, htmlBody := readMIMEText(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body, 0)
+	parsed.Text = plain
+	if strings.TrimSpace(plain) == "" {
+		parsed.Text = HTMLToText(htmlBody)
+	}
+	return parsed, nil
+}
+
+// readMIMEText returns the first plain text and HTML bodies of a MIME entity
+func readMIMEText(contentType, encoding string, body io.Reader, depth int) (plain, htmlBody string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= inboundMaxMIMEDepth || params["boundary"] == "" {
+			return "", ""
+		}
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			if disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition")); disposition == "attachment" {
+				continue
+			}
+			// NextPart already decodes quoted-printable parts
+			p, h := readMIMEText(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part, depth+1)
+			if plain == "" {
+				plain = p
+			}
+			if htmlBody == "" {
+				htmlBody = h
+			}
+			if plain != "" {
+				break
+			}
+		}
+		return plain, htmlBody
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", ""
+	}
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, newlineSkipper{body})
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, _ := io.ReadAll(io.LimitReader(body, inboundMaxText))
+	if mediaType == "text/html" {
+		return "", string(data)
+	}
+	return string(data), ""
+}
+
+// newlineSkipper drops line breaks, which base64 bodies are wrapped with
+type newlineSkipper struct{ r io.Reader }
+
+func (s newlineSkipper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[kept] = b
+			kept++
+		}
+	}
+	return kept, err
+}
+
+// HTMLToText reduces an HTML email to its text, one block per line
+func HTMLToText(body string) string {
+	body = htmlDropped.ReplaceAllString(body, "")
+	body = htmlBreak.ReplaceAllString(body, "\n")
+	body = html.UnescapeString(htmlTag.ReplaceAllString(body, " "))
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.TrimSpace(blankRuns.ReplaceAllString(line, " ")); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// snsEnvelope is an Amazon SNS HTTP(S) delivery
+type snsEnvelope struct {
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+// sesNotification is an Amazon SES receipt notification published through SNS
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	Mail             struct {
+		CommonHeaders struct {
+			From    []string `json:"from"`
+			Subject string   `json:"subject"`
+			Date    string   `json:"date"`
+		} `json:"commonHeaders"`
+	} `json:"mail"`
+	Receipt struct {
+		Action struct {
+			Encoding string `json:"encoding"`
+		} `json:"action"`
+	} `json:"receipt"`
+	Content string `json:"content"`
+}
+
+// ParseSESNotification reads the email of an SES receipt notification. The raw
+// email is only included by SNS actions; without it the headers are used.
+func ParseSESNotification(message string) (InboundMessage, error) {
+	var notification sesNotification
+	if err := json.Unmarshal([]byte(message), &notification); err != nil {
+		return InboundMessage{}, fmt.Errorf("%w: %v", ErrInvalidInboundEmail, err)
+	}
+	if notification.NotificationType != "Received" {
+		return InboundMessage{}, fmt.Errorf("%w: notification type %q", ErrInvalidInboundEmail, notification.NotificationType)
+	}
+
+	if notification.Content != "" {
+		raw := []byte(notification.Content)
+		if strings.EqualFold(notification.Receipt.Action.Encoding, "BASE64") {
+			decoded, err := base64.StdEncoding.DecodeString(notification.Content)
+			if err != nil {
+				return InboundMessage{}, fmt.Errorf("%w: %v", ErrInvalidInboundEmail, err)
+			}
+			raw = decoded
+		}
+		return ParseRawEmail(raw)
+	}
+
+	headers := notification.Mail.CommonHeaders
+	parsed := InboundMessage{Subject: headers.Subject}
+	if len(headers.From) > 0 {
+		parsed.From = headers.From[0]
+	}
+	if date, err := mail.ParseDate(headers.Date); err == nil {
+		parsed.Date = date
+	}
+	return parsed, nil
+}
+
+// ParseSNSEnvelope reads an SNS delivery. It returns the subscription URL to
+// confirm for subscription requests and the email for notifications.
+func ParseSNSEnvelope(body []byte) (subscribeURL string, msg InboundMessage, err error) {
+	var envelope snsEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return "", InboundMessage{}, fmt.Errorf("%w: %v", ErrInvalidInboundEmail, err)
+	}
+	switch envelope.Type {
+	case "SubscriptionConfirmation":
+		link, err := url.Parse(envelope.SubscribeURL)
+		if err != nil || link.Scheme != "https" || !snsHost.MatchString(link.Hostname()) {
+			return "", InboundMessage{}, fmt.Errorf("%w: unexpected subscribe URL", ErrInvalidInboundEmail)
+		}
+		return link.String(), InboundMessage{}, nil
+	case "Notification":
+		msg, err := ParseSESNotification(envelope.Message)
+		return "", msg, err
+	default:
+		return "", InboundMessage{}, fmt.Errorf("%w: SNS message type %q", ErrInvalidInboundEmail, envelope.Type)
+	}
+}
+
+// ExtractCharge finds the charged amount and its currency in the text of a
+// receipt. Amounts on lines naming a total are preferred over the first
+// amount found; amounts without a currency are ignored as they are usually
+// quantities, dates or order numbers.
+func ExtractCharge(text string) (float64, string) {
+	var firstAmount float64
+	var firstCurrency string
+	for _, line := range strings.Split(text, "\n") {
+		amount, currency := lineCharge(line)
+		if amount <= 0 {
+			continue
+		}
+		if chargeTotalLine.MatchString(line) {
+			return amount, currency
+		}
+		if firstAmount == 0 {
+			firstAmount, firstCurrency = amount, currency
+		}
+	}
+	return firstAmount, firstCurrency
+}
+
+// lineCharge returns the first amount with a currency in a line
+func lineCharge(line string) (float64, string) {
+	best := -1
+	var amount float64
+	var currency string
+	try := func(pattern *regexp.Regexp, amountGroup, currencyGroup int) {
+		for _, m := range pattern.FindAllStringSubmatchIndex(line, -1) {
+			if best >= 0 && m[0] >= best {
+				return
+			}
+			code := currencyCode(line[m[2*currencyGroup]:m[2*currencyGroup+1]])
+			value, ok := parseChargeAmount(line[m[2*amountGroup]:m[2*amountGroup+1]])
+			if code == "" || !ok || value <= 0 {
+				continue
+			}
+			best, amount, currency = m[0], value, code
+			return
+		}
+	}
+	try(chargeSymbolBefore, 2, 1)
+	try(chargeCodeBefore, 2, 1)
+	try(chargeAfter, 1, 2)
+	return amount, currency
+}
+
+// currencyCode maps a currency symbol or supported code to its code
+func currencyCode(token string) string {
+	for _, s := range quickCurrencySymbols {
+		if token == s.symbol {
+			return s.code
+		}
+	}
+	for _, code := range SupportedCurrencies() {
+		if token == code {
+			return code
+		}
+	}
+	return ""
+}
+
+// parseChargeAmount reads an amount written with either decimal separator.
+// A separator followed by one or two digits is the decimal one; all others
+// group thousands.
+func parseChargeAmount(text string) (float64, bool) {
+	decimals := ""
+	if i := strings.LastIndexAny(text, ".,"); i >= 0 && len(text)-i-1 <= 2 {
+		decimals = text[i+1:]
+		text = text[:i]
+	}
+	text = strings.NewReplacer(".", "", ",", "", "'", "").Replace(text)
+	if decimals != "" {
+		text += "." + decimals
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	return value, err == nil
+}
+
+// senderOf returns the original sender of a forwarded receipt and the sender
+// of the email otherwise
+func senderOf(msg InboundMessage) string {
+	if forwardedSubject.MatchString(msg.Subject) {
+		if m := forwardedFrom.FindStringSubmatch(msg.Text); m != nil {
+			return m[1]
+		}
+	}
+	return msg.From
+}
+
+// detectMerchant names the merchant of a receipt from its sender. A
+// subscription whose website shares the sender's domain wins, then the sender
+// rules, then the sender's display name and finally the domain itself. The
+// vendor is the one of the matched subscription or the vendor of that name.
+func detectMerchant(from string, subs []models.Subscription, vendors []models.Vendor) (string, *uint) {
+	name, domain := from, ""
+	if address, err := mail.ParseAddress(from); err == nil {
+		name = address.Name
+		if at := strings.LastIndex(address.Address, "@"); at >= 0 {
+			domain = baseDomain(address.Address[at+1:])
+		}
+	}
+
+	if domain != "" {
+		for i := range subs {
+			link, err := url.Parse(subs[i].URL)
+			if err != nil || link.Hostname() == "" || subs[i].URL == "" {
+				continue
+			}
+			if baseDomain(link.Hostname()) == domain {
+				return subs[i].Name, subs[i].VendorID
+			}
+		}
+	}
+
+	merchant := emailSenderRules[domain]
+	if merchant == "" {
+		var words []string
+		for _, word := range strings.Fields(name) {
+			if !emailGenericNames[strings.Trim(strings.ToLower(word), "-_.,:")] && !strings.Contains(word, "@") {
+				words = append(words, word)
+			}
+		}
+		merchant = trimName(strings.Join(words, " "))
+	}
+	if label := strings.SplitN(domain, ".", 2)[0]; merchant == "" && label != "" {
+		merchant = strings.ToUpper(label[:1]) + label[1:]
+	}
+
+	for i := range vendors {
+		if merchantNameScore(vendors[i].Name, merchant) >= reconcileNameMatch {
+			return merchant, &vendors[i].ID
+		}
+	}
+	return merchant, nil
+}
+
+// baseDomain strips subdomains from a host name, keeping two labels or three
+// for country domains like co.uk
+func baseDomain(host string) string {
+	labels := strings.Split(strings.Trim(strings.ToLower(host), "."), ".")
+	keep := 2
+	if n := len(labels); n >= 3 && len(labels[n-1]) == 2 && len(labels[n-2]) <= 3 {
+		keep = 3
+	}
+	if len(labels) <= keep {
+		return strings.Join(labels, ".")
+	}
+	return strings.Join(labels[len(labels)-keep:], ".")
+}
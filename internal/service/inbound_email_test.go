@@ -0,0 +1,173 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractCharge(t *testing.T) {
+	tests := []struct {
+		text     string
+		amount   float64
+		currency string
+	}{
+		{"Your receipt\nSubtotal $10.00\nTax $1.90\nTotal: $11.90", 11.90, "USD"},
+		{"Rechnung Nr. 2026-0042\nGesamtbetrag 12,99 €", 12.99, "EUR"},
+		{"Order #123456 for 2 items\nAmount charged: EUR 1.234,50", 1234.50, "EUR"},
+		{"Thanks for your payment of £7.99 on 3 May", 7.99, "GBP"},
+		{"Plan: Pro, 12 months\n99 CHF per year", 99, "CHF"},
+		{"No charge here, order 4711 ships on 05.06.2026", 0, ""},
+	}
+	for _, tt := range tests {
+		amount, currency := ExtractCharge(tt.text)
+		assert.Equal(t, tt.amount, amount, tt.text)
+		assert.Equal(t, tt.currency, currency, tt.text)
+	}
+}
+
+func TestParseRawEmail(t *testing.T) {
+	raw := "From: =?UTF-8?Q?Spotify_Abrechnung?= <no-reply@billing.spotify.com>\r\n" +
+		"Subject: Your receipt\r\n" +
+		"Date: Sun, 03 May 2026 10:00:00 +0200\r\n" +
+		"Content-Type: multipart/alternative; boundary=b1\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"\r\n" +
+		"<p>Total <b>10,99&nbsp;€</b></p>\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		base64.StdEncoding.EncodeToString([]byte("Premium\nTotal 10,99 €\n")) + "\r\n" +
+		"--b1--\r\n"
+
+	msg, err := ParseRawEmail([]byte(raw))
+	require.NoError(t, err)
+	assert.Equal(t, "Spotify Abrechnung <no-reply@billing.spotify.com>", msg.From)
+	assert.Equal(t, "Your receipt", msg.Subject)
+	assert.Equal(t, "Premium\nTotal 10,99 €\n", msg.Text)
+	assert.Equal(t, time.May, msg.Date.Month())
+
+	// Emails with only an HTML body are reduced to text
+	htmlOnly := "From: shop@example.com\r\nContent-Type: text/html\r\n\r\n<style>p{}</style><p>Total</p><p>&euro;5.00</p>"
+	msg, err = ParseRawEmail([]byte(htmlOnly))
+	require.NoError(t, err)
+	assert.Equal(t, "Total\n€5.00", msg.Text)
+
+	_, err = ParseRawEmail([]byte("not an email"))
+	assert.ErrorIs(t, err, ErrInvalidInboundEmail)
+}
+
+func TestParseSNSEnvelope(t *testing.T) {
+	envelope := func(fields map[string]string) []byte {
+		data, _ := json.Marshal(fields)
+		return data
+	}
+
+	link, _, err := ParseSNSEnvelope(envelope(map[string]string{"Type": "SubscriptionConfirmation", "SubscribeURL": "https://sns.eu-central-1.amazonaws.com/?Action=ConfirmSubscription&Token=x"}))
+	require.NoError(t, err)
+	assert.Contains(t, link, "sns.eu-central-1.amazonaws.com")
+	_, _, err = ParseSNSEnvelope(envelope(map[string]string{"Type": "SubscriptionConfirmation", "SubscribeURL": "https://sns.example.com/confirm"}))
+	assert.ErrorIs(t, err, ErrInvalidInboundEmail)
+
+	raw := "From: Netflix <info@mailer.netflix.com>\r\nSubject: Payment\r\n\r\nAmount: 17,99 EUR\r\n"
+	notification, _ := json.Marshal(map[string]any{
+		"notificationType": "Received",
+		"receipt":          map[string]any{"action": map[string]any{"encoding": "BASE64"}},
+		"content":          base64.StdEncoding.EncodeToString([]byte(raw)),
+	})
+	link, msg, err := ParseSNSEnvelope(envelope(map[string]string{"Type": "Notification", "Message": string(notification)}))
+	require.NoError(t, err)
+	assert.Empty(t, link)
+	assert.Equal(t, "Payment", msg.Subject)
+	assert.Equal(t, "Amount: 17,99 EUR\r\n", msg.Text)
+}
+
+func TestDetectMerchant(t *testing.T) {
+	vendorID := uint(7)
+	subs := []models.Subscription{{Name: "Family Music", URL: "https://www.spotify.com/account", VendorID: &vendorID}}
+	vendors := []models.Vendor{{ID: 3, Name: "Adobe"}}
+
+	merchant, vendor := detectMerchant("Spotify <no-reply@billing.spotify.com>", subs, vendors)
+	assert.Equal(t, "Family Music", merchant)
+	assert.Equal(t, &vendorID, vendor)
+
+	merchant, vendor = detectMerchant("Apple <no_reply@email.apple.com>", subs, vendors)
+	assert.Equal(t, "Apple", merchant)
+	assert.Nil(t, vendor)
+
+	merchant, vendor = detectMerchant("Adobe Billing <mail@mail.adobesystems.com>", subs, vendors)
+	assert.Equal(t, "Adobe", merchant)
+	assert.Equal(t, uint(3), *vendor)
+
+	merchant, _ = detectMerchant("noreply@paddle.co.uk", subs, vendors)
+	assert.Equal(t, "Paddle", merchant)
+}
+
+func TestInboundEmailService_Receive(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Payment{}, &models.Vendor{}, &models.InboundEmail{}))
+	services := newTestSubscriptionService(t, db)
+	paymentRepo := repository.NewPaymentRepository(db)
+	reconcile := NewReconcileService(paymentRepo, services.subscriptions, services.currency, services.preferences)
+	inbound := NewInboundEmailService(services.settings, repository.NewInboundEmailRepository(db), reconcile, services.subscriptions, NewVendorService(repository.NewVendorRepository(db)), services.preferences)
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, -1, -3)
+	renewal := start.AddDate(0, 1, 0)
+	netflix, err := services.subscriptions.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 17.99, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", StartDate: &start})
+	require.NoError(t, err)
+
+	receipt := InboundMessage{From: "Netflix <info@mailer.netflix.com>", Subject: "Your payment", Text: "Total: 17,99 €", Date: renewal}
+	result, err := inbound.Receive(t.Context(), receipt)
+	require.NoError(t, err)
+	assert.Equal(t, InboundPaymentRecorded, result.Status)
+	assert.Equal(t, netflix.ID, result.SubscriptionID)
+	payment, err := paymentRepo.FindByDueDate(netflix.ID, renewal)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentConfirmed, payment.Status)
+	assert.Equal(t, 17.99, payment.Amount)
+
+	result, err = inbound.Receive(t.Context(), receipt)
+	require.NoError(t, err)
+	assert.Equal(t, InboundDuplicate, result.Status)
+
+	// A charge of an unknown service waits as a pending subscription
+	result, err = inbound.Receive(t.Context(), InboundMessage{From: "Fwd <me@example.org>", Subject: "Fwd: Invoice", Text: "From: CloudStore Billing <billing@cloudstore.io>\nAmount due $4.00"})
+	require.NoError(t, err)
+	assert.Equal(t, InboundPending, result.Status)
+	pending, err := inbound.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "CloudStore", pending[0].Merchant)
+	assert.Equal(t, 4.0, pending[0].Amount)
+	assert.Equal(t, "USD", pending[0].Currency)
+
+	result, err = inbound.Receive(t.Context(), InboundMessage{From: "news@example.com", Subject: "Our newsletter", Text: "Nothing to pay"})
+	require.NoError(t, err)
+	assert.Equal(t, InboundIgnored, result.Status)
+
+	require.NoError(t, inbound.Dismiss(pending[0].ID))
+	assert.Error(t, inbound.Dismiss(pending[0].ID))
+	pending, err = inbound.Pending()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// The webhook is disabled until a token is generated
+	assert.False(t, inbound.ValidToken(""))
+	token, err := inbound.GenerateToken()
+	require.NoError(t, err)
+	assert.True(t, inbound.ValidToken(token))
+	assert.False(t, inbound.ValidToken(token[1:]))
+	require.NoError(t, inbound.RevokeToken())
+	assert.False(t, inbound.ValidToken(token))
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+)
+
+var (
+	// ErrIntegrityIssueNotFound is returned when fixing an issue that no longer exists
+	ErrIntegrityIssueNotFound = errors.New("integrity issue not found")
+	// ErrIntegrityNotFixable is returned when fixing an issue that has to be corrected by hand
+	ErrIntegrityNotFixable = errors.New("integrity issue cannot be fixed automatically")
+)
+
+// IntegrityService checks the stored subscriptions for anomalies that the forms
+// and the API would not let through, like data from old versions, imports or
+// edits in the database, and fixes them
+type IntegrityService struct {
+	subscriptions *repository.SubscriptionRepository
+	categories    *repository.CategoryRepository
+	defaults      *SubscriptionDefaultsService
+	preferences   PreferencesServiceInterface
+}
+
+func NewIntegrityService(subscriptions *repository.SubscriptionRepository, categories *repository.CategoryRepository, defaults *SubscriptionDefaultsService, preferences PreferencesServiceInterface) *IntegrityService {
+	return &IntegrityService{subscriptions: subscriptions, categories: categories, defaults: defaults, preferences: preferences}
+}
+
+// Check lists the integrity issues of all subscriptions. Negative costs are
+// credits and not an issue; a cost of zero or beyond the accepted range is.
+func (s *IntegrityService) Check(ctx context.Context) (*models.IntegrityReport, error) {
+	subscriptions, err := s.subscriptions.GetAllAsStored(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categories.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[uint]bool, len(categories))
+	for _, category := range categories {
+		known[category.ID] = true
+	}
+
+	now := time.Now()
+	report := &models.IntegrityReport{CheckedAt: now, Subscriptions: len(subscriptions), Issues: []models.IntegrityIssue{}}
+	for i := range subscriptions {
+		report.Issues = append(report.Issues, integrityIssues(&subscriptions[i], known, now)...)
+	}
+	return report, nil
+}
+
+// integrityIssues returns the issues of one subscription
+func integrityIssues(sub *models.Subscription, categories map[uint]bool, now time.Time) []models.IntegrityIssue {
+	var issues []models.IntegrityIssue
+	add := func(kind, value string, fixable bool) {
+		issues = append(issues, models.IntegrityIssue{Kind: kind, SubscriptionID: sub.ID, Name: sub.Name, Value: value, Fixable: fixable})
+	}
+
+	// Compare calendar days, whatever the time of day or zone
+	if sub.Status == models.StatusActive && sub.RenewalDate != nil && sub.RenewalDate.Format(time.DateOnly) < now.Format(time.DateOnly) {
+		add(models.IntegrityPastRenewal, sub.RenewalDate.Format(time.DateOnly), true)
+	}
+	if sub.CategoryID != 0 && !categories[sub.CategoryID] {
+		add(models.IntegrityOrphanCategory, strconv.FormatUint(uint64(sub.CategoryID), 10), true)
+	}
+	if sub.RenewalReminder && sub.RenewalReminderDays <= 0 {
+		add(models.IntegrityRenewalReminderDays, strconv.Itoa(sub.RenewalReminderDays), true)
+	}
+	if sub.CancellationReminder && sub.CancellationReminderDays <= 0 {
+		add(models.IntegrityCancellationReminderDays, strconv.Itoa(sub.CancellationReminderDays), true)
+	}
+	if sub.Cost == 0 || math.Abs(sub.Cost) > models.MaxCost {
+		add(models.IntegrityInvalidCost, strconv.FormatFloat(sub.Cost, 'f', -1, 64), false)
+	}
+	if sub.OriginalCurrency != "" && !slices.Contains(SupportedCurrencies(), sub.OriginalCurrency) {
+		add(models.IntegrityUnknownCurrency, sub.OriginalCurrency, true)
+	}
+	return issues
+}
+
+// Fix corrects an issue: a passed renewal date is advanced to the next one, a
+// missing category replaced by the default category, reminder days set to
+// the defaults and an unknown currency replaced by the display currency.
+func (s *IntegrityService) Fix(ctx context.Context, kind string, subscriptionID uint) error {
+	report, err := s.Check(ctx)
+	if err != nil {
+		return err
+	}
+	for _, issue := range report.Issues {
+		if issue.Kind == kind && issue.SubscriptionID == subscriptionID {
+			return s.fix(ctx, issue)
+		}
+	}
+	return ErrIntegrityIssueNotFound
+}
+
+// FixAll corrects every issue that can be fixed automatically and returns how
+// many were fixed
+func (s *IntegrityService) FixAll(ctx context.Context) (int, error) {
+	report, err := s.Check(ctx)
+	if err != nil {
+		return 0, err
+	}
+	fixed := 0
+	for _, issue := range report.Issues {
+		if !issue.Fixable {
+			continue
+		}
+		if err := s.fix(ctx, issue); err != nil {
+			return fixed, err
+		}
+		fixed++
+	}
+	return fixed, nil
+}
+
+func (s *IntegrityService) fix(ctx context.Context, issue models.IntegrityIssue) error {
+	if !issue.Fixable {
+		return ErrIntegrityNotFixable
+	}
+
+	var columns map[string]interface{}
+	switch issue.Kind {
+	case models.IntegrityPastRenewal:
+		// Loading an active subscription advances its passed renewal date
+		sub, err := s.subscriptions.GetByID(ctx, issue.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		columns = map[string]interface{}{"renewal_date": sub.RenewalDate}
+	case models.IntegrityOrphanCategory:
+		category, err := s.categories.GetDefault()
+		if err != nil {
+			return fmt.Errorf("failed to get default category: %w", err)
+		}
+		columns = map[string]interface{}{"category_id": category.ID}
+	case models.IntegrityRenewalReminderDays:
+		columns = map[string]interface{}{"renewal_reminder_days": s.defaults.Get().RenewalReminderDays}
+	case models.IntegrityCancellationReminderDays:
+		columns = map[string]interface{}{"cancellation_reminder_days": s.defaults.Get().CancellationReminderDays}
+	case models.IntegrityUnknownCurrency:
+		columns = map[string]interface{}{"original_currency": s.preferences.GetCurrency()}
+	default:
+		return ErrIntegrityNotFixable
+	}
+
+	if err := s.subscriptions.UpdateColumns(ctx, issue.SubscriptionID, columns); err != nil {
+		return err
+	}
+	slog.Info("fixed data integrity issue", "kind", issue.Kind, "subscription_id", issue.SubscriptionID)
+	return nil
+}
+
+// LogIssues checks the data at startup and warns about the issues found, which
+// are listed under Settings > Data
+func (s *IntegrityService) LogIssues(ctx context.Context) {
+	report, err := s.Check(ctx)
+	if err != nil {
+		slog.Warn("failed to check data integrity", "error", err)
+		return
+	}
+	if len(report.Issues) == 0 {
+		return
+	}
+	kinds := map[string]int{}
+	for _, issue := range report.Issues {
+		kinds[issue.Kind]++
+	}
+	slog.Warn("data integrity issues found, see Settings > Data", "count", len(report.Issues), "kinds", kinds)
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrityService(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Vendor{}))
+	categoryRepo := repository.NewCategoryRepository(db)
+	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	categoryService := NewCategoryService(categoryRepo)
+	settingsService := NewSettingsService(repository.NewSettingsRepository(db))
+	preferencesService := NewPreferencesService(settingsService, defaultLangProvider())
+	require.NoError(t, preferencesService.SetCurrency("EUR"))
+	defaults := NewSubscriptionDefaultsService(settingsService, preferencesService, categoryService)
+	integrity := NewIntegrityService(subscriptionRepo, categoryRepo, defaults, preferencesService)
+
+	general, err := categoryRepo.Create(&models.Category{Name: "General", IsDefault: true})
+	require.NoError(t, err)
+	create := func(name string) *models.Subscription {
+		sub, err := subscriptionRepo.Create(t.Context(), &models.Subscription{Name: name, Cost: 10, Schedule: "Monthly", Status: "Active", CategoryID: general.ID, OriginalCurrency: "EUR"})
+		require.NoError(t, err)
+		return sub
+	}
+
+	healthy := create("Healthy")
+	broken := create("Broken")
+	free := create("Free")
+	past := time.Now().AddDate(0, -2, 0)
+	require.NoError(t, subscriptionRepo.UpdateColumns(t.Context(), broken.ID, map[string]interface{}{
+		"renewal_date":               past,
+		"category_id":                999,
+		"renewal_reminder":           true,
+		"renewal_reminder_days":      0,
+		"cancellation_reminder":      true,
+		"cancellation_reminder_days": -1,
+		"original_currency":          "XXX",
+	}))
+	require.NoError(t, subscriptionRepo.UpdateColumns(t.Context(), free.ID, map[string]interface{}{"cost": 0}))
+
+	report, err := integrity.Check(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Subscriptions)
+	kinds := map[string]uint{}
+	for _, issue := range report.Issues {
+		kinds[issue.Kind] = issue.SubscriptionID
+		assert.NotEqual(t, healthy.ID, issue.SubscriptionID)
+	}
+	assert.Equal(t, map[string]uint{
+		models.IntegrityPastRenewal:              broken.ID,
+		models.IntegrityOrphanCategory:           broken.ID,
+		models.IntegrityRenewalReminderDays:      broken.ID,
+		models.IntegrityCancellationReminderDays: broken.ID,
+		models.IntegrityUnknownCurrency:          broken.ID,
+		models.IntegrityInvalidCost:              free.ID,
+	}, kinds, "a passed renewal date is reported although loading would advance it")
+
+	// A cost has to be corrected by hand
+	assert.ErrorIs(t, integrity.Fix(t.Context(), models.IntegrityInvalidCost, free.ID), ErrIntegrityNotFixable)
+	assert.ErrorIs(t, integrity.Fix(t.Context(), models.IntegrityInvalidCost, healthy.ID), ErrIntegrityIssueNotFound)
+
+	require.NoError(t, integrity.Fix(t.Context(), models.IntegrityOrphanCategory, broken.ID))
+	assert.ErrorIs(t, integrity.Fix(t.Context(), models.IntegrityOrphanCategory, broken.ID), ErrIntegrityIssueNotFound)
+
+	fixed, err := integrity.FixAll(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 4, fixed)
+
+	report, err = integrity.Check(t.Context())
+	require.NoError(t, err)
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, models.IntegrityInvalidCost, report.Issues[0].Kind)
+
+	sub, err := subscriptionRepo.GetByID(t.Context(), broken.ID)
+	require.NoError(t, err)
+	assert.Equal(t, general.ID, sub.CategoryID)
+	require.NotNil(t, sub.RenewalDate)
+	assert.True(t, sub.RenewalDate.After(past))
+	assert.Equal(t, 3, sub.RenewalReminderDays)
+	assert.Equal(t, 7, sub.CancellationReminderDays)
+	assert.Equal(t, "EUR", sub.OriginalCurrency)
+}
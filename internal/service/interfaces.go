@@ -1,22 +1,45 @@
 package service
 
-import "subvault/internal/models"
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"subvault/internal/models"
+	"subvault/internal/scheduler"
+	"time"
+)
 
 // SubscriptionServiceInterface defines the contract for subscription operations.
 type SubscriptionServiceInterface interface {
-	Create(subscription *models.Subscription) (*models.Subscription, error)
-	GetAll() ([]models.Subscription, error)
-	GetAllPaginated(limit, offset int) ([]models.Subscription, int64, error)
-	GetAllSorted(sortBy, order string) ([]models.Subscription, error)
-	GetByID(id uint) (*models.Subscription, error)
-	Update(id uint, subscription *models.Subscription) (*models.Subscription, error)
-	Delete(id uint) error
-	Count() int64
-	GetStats() (*models.Stats, error)
+	Create(ctx context.Context, subscription *models.Subscription) (*models.Subscription, error)
+	CreateAll(ctx context.Context, subscriptions []*models.Subscription) error
+	GetAll(ctx context.Context) ([]models.Subscription, error)
+	GetAllPaginated(ctx context.Context, limit, offset int, purpose string) ([]models.Subscription, int64, error)
+	GetAllSorted(ctx context.Context, sortBy, order string) ([]models.Subscription, error)
+	GetByID(ctx context.Context, id uint) (*models.Subscription, error)
+	Update(ctx context.Context, id uint, subscription *models.Subscription) (*models.Subscription, error)
+	Delete(ctx context.Context, id uint) error
+	SoftDelete(ctx context.Context, id uint) error
+	SoftDeleteAll(ctx context.Context) ([]uint, error)
+	Restore(ctx context.Context, ids []uint) (int64, error)
+	PurgeDeleted(ctx context.Context, ids []uint, before time.Time) ([]uint, error)
+	Count(ctx context.Context) int64
+	GetStats(ctx context.Context) (*models.Stats, error)
+	GetStatsForPurpose(ctx context.Context, purpose string) (*models.Stats, error)
+	GetCategoryBreakdown(ctx context.Context, categoryID uint, purpose string) (*models.CategoryBreakdown, error)
+	GetTaxReport(ctx context.Context, year int, period string) (*models.TaxReport, error)
 	GetAllCategories() ([]models.Category, error)
 	GetDefaultCategory() (*models.Category, error)
-	GetSubscriptionsNeedingReminders() (map[*models.Subscription]int, error)
-	GetSubscriptionsNeedingCancellationReminders() (map[*models.Subscription]int, error)
+	GetSubscriptionsNeedingReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error)
+	GetSubscriptionsNeedingCancellationReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error)
+	GetSubscriptionsNeedingGracePeriodReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error)
+	GetSubscriptionsNeedingContractReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error)
+	GetSubscriptionsNeedingPaidThroughReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error)
+	GetSubscriptionsNeedingPromoReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error)
+	Cancel(ctx context.Context, id uint) (*models.Subscription, error)
+	Pause(ctx context.Context, id uint) (*models.Subscription, error)
+	Resume(ctx context.Context, id uint) (*models.Subscription, error)
+	SetStatus(ctx context.Context, id uint, status string) (*models.Subscription, error)
 }
 
 // SettingsServiceInterface defines the contract for base settings operations (cache + typed get/set).
@@ -30,6 +53,10 @@ type SettingsServiceInterface interface {
 	SetFloatSetting(key string, value float64) error
 	GetFloatSetting(key string, defaultValue float64) (float64, error)
 	GetFloatSettingWithDefault(key string, defaultValue float64) float64
+	GetCached(key string) (string, bool)
+	SetBudgetRollover(enabled bool) error
+	PurposeBudgets() map[string]PurposeBudget
+	SetPurposeBudget(purpose string, budget PurposeBudget) error
 }
 
 // AuthServiceInterface defines the contract for authentication operations.
@@ -41,6 +68,15 @@ type AuthServiceInterface interface {
 	HashPassword(password string) (string, error)
 	SetAuthPassword(password string) error
 	ValidatePassword(password string) error
+	Authenticate(username, password string) (string, error)
+	GetViewerUsername() (string, bool)
+	SetViewer(username, password string) error
+	RemoveViewer() error
+	GetPasswordPolicy() models.PasswordPolicy
+	SavePasswordPolicy(policy models.PasswordPolicy) error
+	EvaluatePassword(password string, userInputs ...string) PasswordCheck
+	GetSessionLifetimes() models.SessionLifetimes
+	SaveSessionLifetimes(lifetimes models.SessionLifetimes) error
 	GetOrGenerateSessionSecret() (string, error)
 	GetOrGenerateCSRFSecret() ([]byte, error)
 	SetupAuth(username, password string) error
@@ -52,7 +88,7 @@ type AuthServiceInterface interface {
 
 // APIKeyServiceInterface defines the contract for API key operations.
 type APIKeyServiceInterface interface {
-	CreateAPIKey(name, key string) (*models.APIKey, error)
+	CreateAPIKey(name string, scopes []string) (*models.APIKey, error)
 	GetAllAPIKeys() ([]models.APIKey, error)
 	DeleteAPIKey(id uint) error
 	ValidateAPIKey(key string) (*models.APIKey, error)
@@ -64,13 +100,30 @@ type PreferencesServiceInterface interface {
 	SetTheme(theme string) error
 	IsDarkModeEnabled() bool
 	SetDarkMode(enabled bool) error
+	ChartTablesEnabled() bool
+	SetChartTables(enabled bool) error
+	GetAccentColor() string
+	SetAccentColor(color string) error
+	GetCustomCSS() string
+	SetCustomCSS(css string) error
+	ValidCurrency(currency string) bool
 	SetCurrency(currency string) error
 	GetCurrency() string
 	GetCurrencySymbol() string
+	ValidLanguage(lang string) bool
 	SetLanguage(lang string) error
 	GetLanguage() string
 	SetDateFormat(format string) error
 	GetDateFormat() string
+	SetDisplayRounding(rounding string) error
+	GetDisplayRounding() string
+	SetCSVColumns(columns []string) error
+	GetCSVColumns() []string
+	SetRenewalWindowDays(days int) error
+	GetRenewalWindowDays() int
+	SetUpcomingRenewalsLimit(limit int) error
+	GetUpcomingRenewalsLimit() int
+	FormatAmount(amount float64, currency string) string
 }
 
 // NotificationConfigServiceInterface defines the contract for notification configuration operations.
@@ -80,20 +133,35 @@ type NotificationConfigServiceInterface interface {
 	SaveShoutrrrConfig(config *models.ShoutrrrConfig) error
 	GetShoutrrrConfig() (*models.ShoutrrrConfig, error)
 	MigratePushoverToShoutrrr() error
+	SaveDeliveryWindows(windows *models.DeliveryWindows) error
+	GetDeliveryWindows() *models.DeliveryWindows
+	DeliveryAllowed(channel string, t time.Time) bool
+	SaveNotificationLanguages(languages *models.NotificationLanguages) error
+	GetNotificationLanguages() *models.NotificationLanguages
+	ChannelLanguage(channel string) string
+	QueueNotification(channel, title, body string) error
+	QueuedNotifications() []models.QueuedNotification
+	FlushQueue(channel string, now time.Time, send func(title, body string) error) (int, error)
 }
 
-// CalendarServiceInterface defines the contract for calendar token operations.
+// CalendarServiceInterface defines the contract for calendar token and feed operations.
 type CalendarServiceInterface interface {
 	GenerateCalendarToken() (string, error)
 	GetCalendarToken() (string, error)
 	RevokeCalendarToken() error
+	ScopedCalendarToken(scope CalendarScope) (string, error)
+	AuthorizeCalendarFeed(token string, scope CalendarScope) bool
+	GetFeedOptions() models.CalendarFeedOptions
+	SaveFeedOptions(options models.CalendarFeedOptions) error
 }
 
 // CurrencyServiceInterface defines the contract for currency conversion operations.
 type CurrencyServiceInterface interface {
 	GetExchangeRate(fromCurrency, toCurrency string) (float64, error)
 	ConvertAmount(amount float64, fromCurrency, toCurrency string) (float64, error)
-	RefreshRates() error
+	RefreshRates(ctx context.Context) error
+	SetManualRates(rates map[string]float64) error
+	ManualRateEntries() []ExchangeRateEntry
 	GetStatus() ExchangeRateStatus
 }
 
@@ -108,38 +176,232 @@ type CategoryServiceInterface interface {
 	GetDefault() (*models.Category, error)
 }
 
-// EmailServiceInterface defines the contract for email notification operations.
-type EmailServiceInterface interface {
-	SendEmail(subject, body string) error
+// Notifier is a notification channel. Channel returns the channel name used
+// in models.NotificationChannels. Send methods return ErrChannelNotConfigured
+// when the channel is not set up.
+type Notifier interface {
+	Channel() string
 	SendHighCostAlert(subscription *models.Subscription) error
 	SendRenewalReminder(subscription *models.Subscription, daysUntilRenewal int) error
 	SendCancellationReminder(subscription *models.Subscription, daysUntilCancellation int) error
-	SendBudgetExceededAlert(totalSpend, budget float64, currencySymbol string) error
+	SendGracePeriodReminder(subscription *models.Subscription, daysUntilCutoff int) error
+	SendContractReminder(subscription *models.Subscription, daysUntilDeadline int) error
+	SendPaidThroughReminder(subscription *models.Subscription, daysUntilEnd int) error
+	SendPromoEndReminder(subscription *models.Subscription, daysUntilEnd int) error
+	SendBudgetExceededAlert(period string, totalSpend, budget float64, currencySymbol string) error
+	SendUnusedSubscriptionsNudge(nudge *UnusedNudge) error
+	SendExchangeRateAlert(alert *RateAlert) error
+	SendSettlementReport(report *SettlementReport) error
+	SendRenewalConfirmations(payments []models.Payment) error
+	SendLoginAlert(event *models.LoginEvent) error
+	SendWeeklySummary(summary *WeeklySummary) error
+	FlushQueued(now time.Time) (int, error)
+}
+
+// EmailServiceInterface defines the contract for email notification operations.
+type EmailServiceInterface interface {
+	Notifier
+	SendEmail(ctx context.Context, subject, body string, attachments ...Attachment) error
+	Preview(kind, language string, now time.Time) (*RenderedEmail, error)
 }
 
 // ShoutrrrServiceInterface defines the contract for Shoutrrr push notification operations.
 type ShoutrrrServiceInterface interface {
+	Notifier
 	SendTestNotification(urls []string) error
+}
+
+// NotificationTestServiceInterface defines the contract for sending sample
+// notifications through all channels.
+type NotificationTestServiceInterface interface {
+	TestAll(now time.Time) []NotificationTestResult
+}
+
+// ReminderSimulationServiceInterface defines the contract for simulating the
+// reminder jobs at any date without sending.
+type ReminderSimulationServiceInterface interface {
+	Simulate(ctx context.Context, at time.Time) (*ReminderSimulation, error)
+}
+
+// NotificationPreviewServiceInterface defines the contract for previewing the
+// notifications of the coming days.
+type NotificationPreviewServiceInterface interface {
+	Preview(ctx context.Context, now time.Time, days int) (*NotificationPreview, error)
+}
+
+// LoginAuditServiceInterface defines the contract for recording login
+// attempts and alerting on logins from new devices.
+type LoginAuditServiceInterface interface {
+	Record(username, role, ip, userAgent string, success bool) (*models.LoginEvent, error)
+	Recent(limit int) ([]models.LoginEvent, error)
+	AlertsEnabled() bool
+	SendAlert(event *models.LoginEvent) error
+}
+
+// NotificationDispatcherInterface defines the contract for sending
+// notifications through all registered channels.
+type NotificationDispatcherInterface interface {
+	Register(notifier Notifier)
+	Notifiers() []Notifier
+	Senders(send func(Notifier) error) map[string]func() error
 	SendHighCostAlert(subscription *models.Subscription) error
-	SendRenewalReminder(subscription *models.Subscription, daysUntilRenewal int) error
-	SendCancellationReminder(subscription *models.Subscription, daysUntilCancellation int) error
-	SendBudgetExceededAlert(totalSpend, budget float64, currencySymbol string) error
+	SendBudgetExceededAlert(period string, totalSpend, budget float64, currencySymbol string) error
+	SendUnusedSubscriptionsNudge(nudge *UnusedNudge) error
+	SendExchangeRateAlert(alert *RateAlert) error
+	SendSettlementReport(report *SettlementReport) error
+	SendRenewalConfirmations(payments []models.Payment) error
+	SendLoginAlert(event *models.LoginEvent) error
+	SendWeeklySummary(summary *WeeklySummary) error
+	FlushQueued(now time.Time) (int, error)
 }
 
 // LogoServiceInterface defines the contract for logo fetching and validation operations.
 type LogoServiceInterface interface {
-	FetchLogoFromURL(websiteURL string) (string, error)
-	GetLogoURL(iconURL, websiteURL string) string
-	ValidateLogoURL(logoURL string) bool
-	FetchAndValidateLogo(websiteURL string) (string, error)
+	FetchLogoFromURL(ctx context.Context, websiteURL string) (string, error)
+	Candidates(ctx context.Context, websiteURL string) ([]LogoCandidate, error)
+	PrivacyMode() bool
+	GetLogoURL(ctx context.Context, iconURL, websiteURL string) string
+	ValidateLogoURL(ctx context.Context, logoURL string) bool
+	FetchAndValidateLogo(ctx context.Context, websiteURL string) (string, error)
 	ExtractDomain(websiteURL string) string
-	DownloadLogo(logoURL string) ([]byte, error)
+	DownloadLogo(ctx context.Context, logoURL string) ([]byte, error)
+	LogoColor(ctx context.Context, logoURL string) (string, error)
+}
+
+// ProxyServiceInterface defines the contract for the proxy of outbound requests.
+type ProxyServiceInterface interface {
+	Get() ProxyConfig
+	Status() ProxyStatus
+	Save(config ProxyConfig) error
 }
 
 // RenewalServiceInterface defines the contract for subscription renewal date calculation.
 type RenewalServiceInterface interface {
 	InitializeRenewalDate(sub *models.Subscription)
 	RecalculateIfNeeded(existing, updated *models.Subscription)
+	Cancel(sub *models.Subscription, now time.Time)
+	Pause(sub *models.Subscription)
+	Resume(sub *models.Subscription)
+	NormalizeStatus(sub *models.Subscription, from string, now time.Time)
+}
+
+// UsageServiceInterface defines the contract for usage tracking and cost-per-use analytics.
+type UsageServiceInterface interface {
+	LogEvent(ctx context.Context, subscriptionID uint, occurredAt time.Time, note string) (*models.UsageEvent, error)
+	GetEvents(subscriptionID uint, limit int) ([]models.UsageEvent, error)
+	GetCostPerUse(ctx context.Context) ([]CostPerUse, error)
+	GetConsiderCancelling(ctx context.Context) ([]CostPerUse, error)
+	GetUnusedNudge(ctx context.Context, threshold float64) (*UnusedNudge, error)
+}
+
+// SplitServiceInterface defines the contract for shared expense splitting and settlements.
+type SplitServiceInterface interface {
+	GetShares(subscriptionID uint) ([]models.SubscriptionShare, error)
+	SetShares(ctx context.Context, subscriptionID uint, shares []models.SubscriptionShare) error
+	GetSettlement(ctx context.Context) (*SettlementReport, error)
+	WriteSettlementCSV(w io.Writer, report *SettlementReport) error
+}
+
+// ConfigServiceInterface defines the contract for exporting and importing the instance configuration.
+type ConfigServiceInterface interface {
+	Export() (*Config, error)
+	Marshal(cfg *Config, format string) ([]byte, error)
+	Parse(data []byte) (*Config, error)
+	Import(cfg *Config) (*ConfigImportResult, error)
+}
+
+// HookServiceInterface defines the contract for running user-defined hooks on events.
+type HookServiceInterface interface {
+	Has(event string) bool
+	Fire(event string, data interface{})
+}
+
+// JobServiceInterface defines the contract for inspecting and triggering background jobs.
+type JobServiceInterface interface {
+	Run(name string) error
+	Trigger(name string) error
+	List() []scheduler.Status
+	AnyRunning() bool
+}
+
+// ReminderJobsInterface defines the contract for the scheduled reminder jobs.
+type ReminderJobsInterface interface {
+	SendRenewalReminders(ctx context.Context, now time.Time) error
+	SendCancellationReminders(ctx context.Context, now time.Time) error
+	SendGracePeriodReminders(ctx context.Context, now time.Time) error
+	SendContractReminders(ctx context.Context, now time.Time) error
+	SendPaidThroughReminders(ctx context.Context, now time.Time) error
+	SendPromoReminders(ctx context.Context, now time.Time) error
+	RetryFailed(ctx context.Context, now time.Time) error
+}
+
+// ReminderRetryServiceInterface defines the contract for retrying failed reminders.
+type ReminderRetryServiceInterface interface {
+	Pending(subscriptionID uint, kind string, dueDate time.Time) bool
+	RecordResult(subscriptionID uint, kind string, dueDate time.Time, failures map[string]error, now time.Time) (*models.ReminderRetry, error)
+	Due(now time.Time) ([]models.ReminderRetry, error)
+	Drop(subscriptionID uint, kind string) error
+}
+
+// PaymentServiceInterface defines the contract for the payment ledger and renewal confirmations.
+type PaymentServiceInterface interface {
+	RecordRenewals(ctx context.Context, now time.Time) ([]models.Payment, error)
+	List(ctx context.Context, subscriptionID uint, statuses ...string) ([]models.Payment, error)
+	Unconfirmed(ctx context.Context) ([]models.Payment, error)
+	Confirm(ctx context.Context, id uint, amount *float64, paidAt *time.Time) (*models.Payment, error)
+	Reject(ctx context.Context, id uint) (*models.Payment, error)
+}
+
+// ChangeProposalServiceInterface defines the contract for the queue of subscription changes proposed by viewers.
+type ChangeProposalServiceInterface interface {
+	Propose(ctx context.Context, action string, subscriptionID uint, changes json.RawMessage, note, proposedBy string) (*models.ChangeProposal, error)
+	Get(ctx context.Context, id uint) (*ChangeProposalView, error)
+	List(ctx context.Context, limit int, statuses ...string) ([]ChangeProposalView, error)
+	CountPending() (int64, error)
+	Approve(ctx context.Context, id uint, apply func(*models.ChangeProposal) error) (*models.ChangeProposal, error)
+	Reject(ctx context.Context, id uint) (*models.ChangeProposal, error)
+}
+
+// ReconcileServiceInterface defines the contract for reconciling bank statements with subscriptions.
+type ReconcileServiceInterface interface {
+	Preview(ctx context.Context, data []byte, format string) (*ReconcilePreview, error)
+	Confirm(token string, selected []int) (*ReconcileResult, error)
+}
+
+// OpenBankingServiceInterface defines the contract for the GoCardless bank connection.
+type OpenBankingServiceInterface interface {
+	Connection() models.BankConnection
+	Candidates() []RecurringCharge
+	SaveCredentials(ctx context.Context, secretID, secretKey string) error
+	Institutions(ctx context.Context, country string) ([]BankInstitution, error)
+	Connect(ctx context.Context, institutionID, redirectURL string) (string, error)
+	RefreshAccounts(ctx context.Context) (models.BankConnection, error)
+	Sync(ctx context.Context) (*BankSyncResult, error)
+	Disconnect(ctx context.Context) error
+}
+
+// BudgetImportServiceInterface defines the contract for the YNAB and Firefly III importer.
+type BudgetImportServiceInterface interface {
+	Connection() models.BudgetConnection
+	Save(ctx context.Context, connection models.BudgetConnection) error
+	Expenses(ctx context.Context) ([]RecurringExpense, error)
+	Preview(ctx context.Context) (*BudgetPreview, error)
+	SyncAmounts(ctx context.Context) (*BudgetSyncResult, error)
+	Disconnect() error
+}
+
+// FireflyExportServiceInterface defines the contract for exporting subscriptions to Firefly III.
+type FireflyExportServiceInterface interface {
+	Accounts(ctx context.Context) ([]FireflyAccount, error)
+	Export(ctx context.Context, accountID string) (*FireflyExportResult, error)
+}
+
+// LogoQueueServiceInterface defines the contract for background logo lookups.
+type LogoQueueServiceInterface interface {
+	Enqueue(ctx context.Context, id uint) error
+	Retry(ctx context.Context, id uint) error
+	Upload(ctx context.Context, id uint, data []byte) (string, error)
+	Process(ctx context.Context) (LogoQueueResult, error)
 }
 
 // LanguageProvider defines a minimal interface for querying supported languages.
@@ -148,6 +410,85 @@ type LanguageProvider interface {
 	SupportedLanguages() []string
 }
 
+// SubscriptionDefaultsServiceInterface defines the contract for the defaults of new subscriptions.
+type SubscriptionDefaultsServiceInterface interface {
+	Get() models.SubscriptionDefaults
+	Save(defaults *models.SubscriptionDefaults) error
+	NewSubscription() *models.Subscription
+	Apply(subscription *models.Subscription)
+}
+
+// ErasureServiceInterface defines the contract for erasing all data
+type ErasureServiceInterface interface {
+	EraseAll(password, confirmation string) (*ErasureResult, error)
+}
+
+// UndoServiceInterface defines the contract for deletions that can be undone
+type UndoServiceInterface interface {
+	DeleteSubscription(ctx context.Context, id uint) (*UndoAction, error)
+	ClearSubscriptions(ctx context.Context) (*UndoAction, error)
+	Undo(ctx context.Context, token string) (*UndoAction, error)
+}
+
+// UpdateServiceInterface defines the contract for version info and update checks
+type UpdateServiceInterface interface {
+	IsEnabled() bool
+	SetEnabled(enabled bool) error
+	Check(ctx context.Context) error
+	Status() UpdateStatus
+}
+
+// StatsHistoryServiceInterface defines the contract for daily statistics snapshots
+type StatsHistoryServiceInterface interface {
+	Record(ctx context.Context, now time.Time) (*models.StatsSnapshot, error)
+	History(months int, now time.Time) ([]models.StatsSnapshot, error)
+}
+
+// CategoryRuleServiceInterface defines the contract for import category mapping rules
+type CategoryRuleServiceInterface interface {
+	List() ([]models.CategoryRule, error)
+	Set(source, match string, categoryID uint) (*models.CategoryRule, error)
+	Delete(id uint) error
+}
+
+// VendorServiceInterface defines the contract for vendors grouping subscriptions
+type VendorServiceInterface interface {
+	List() ([]models.Vendor, error)
+	GetByID(id uint) (*models.Vendor, error)
+	Create(name string) (*models.Vendor, error)
+	Rename(id uint, name string) (*models.Vendor, error)
+	Delete(id uint) error
+}
+
+// SearchServiceInterface defines the contract for the command palette search
+type SearchServiceInterface interface {
+	Search(ctx context.Context, query string) (*SearchResults, error)
+}
+
+// IntegrityServiceInterface defines the contract for the data integrity check
+type IntegrityServiceInterface interface {
+	Check(ctx context.Context) (*models.IntegrityReport, error)
+	Fix(ctx context.Context, kind string, subscriptionID uint) error
+	FixAll(ctx context.Context) (int, error)
+}
+
+// InboundEmailServiceInterface defines the contract for the inbound email webhook
+type InboundEmailServiceInterface interface {
+	GenerateToken() (string, error)
+	Token() string
+	RevokeToken() error
+	ValidToken(token string) bool
+	Receive(ctx context.Context, msg InboundMessage) (*InboundResult, error)
+	Pending() ([]models.InboundEmail, error)
+	Dismiss(id uint) error
+	ConfirmSNSSubscription(subscribeURL string) error
+}
+
+// RequestMetricsInterface defines the contract for reading the request metrics
+type RequestMetricsInterface interface {
+	Report(limit int) PerformanceReport
+}
+
 // Compile-time interface satisfaction checks.
 var _ SubscriptionServiceInterface = (*SubscriptionService)(nil)
 var _ SettingsServiceInterface = (*SettingsService)(nil)
@@ -160,5 +501,35 @@ var _ CurrencyServiceInterface = (*CurrencyService)(nil)
 var _ CategoryServiceInterface = (*CategoryService)(nil)
 var _ EmailServiceInterface = (*EmailService)(nil)
 var _ ShoutrrrServiceInterface = (*ShoutrrrService)(nil)
+var _ NotificationDispatcherInterface = (*NotificationDispatcher)(nil)
+var _ NotificationTestServiceInterface = (*NotificationTestService)(nil)
+var _ ReminderSimulationServiceInterface = (*ReminderSimulationService)(nil)
+var _ NotificationPreviewServiceInterface = (*NotificationPreviewService)(nil)
 var _ LogoServiceInterface = (*LogoService)(nil)
+var _ ProxyServiceInterface = (*ProxyService)(nil)
 var _ RenewalServiceInterface = (*RenewalService)(nil)
+var _ UsageServiceInterface = (*UsageService)(nil)
+var _ SplitServiceInterface = (*SplitService)(nil)
+var _ ConfigServiceInterface = (*ConfigService)(nil)
+var _ HookServiceInterface = (*HookService)(nil)
+var _ JobServiceInterface = (*scheduler.Scheduler)(nil)
+var _ ReminderJobsInterface = (*ReminderJobs)(nil)
+var _ ReminderRetryServiceInterface = (*ReminderRetryService)(nil)
+var _ SubscriptionDefaultsServiceInterface = (*SubscriptionDefaultsService)(nil)
+var _ PaymentServiceInterface = (*PaymentService)(nil)
+var _ ChangeProposalServiceInterface = (*ChangeProposalService)(nil)
+var _ ReconcileServiceInterface = (*ReconcileService)(nil)
+var _ OpenBankingServiceInterface = (*OpenBankingService)(nil)
+var _ BudgetImportServiceInterface = (*BudgetImportService)(nil)
+var _ FireflyExportServiceInterface = (*FireflyExportService)(nil)
+var _ LogoQueueServiceInterface = (*LogoQueueService)(nil)
+var _ ErasureServiceInterface = (*ErasureService)(nil)
+var _ UpdateServiceInterface = (*UpdateService)(nil)
+var _ StatsHistoryServiceInterface = (*StatsHistoryService)(nil)
+var _ CategoryRuleServiceInterface = (*CategoryRuleService)(nil)
+var _ VendorServiceInterface = (*VendorService)(nil)
+var _ SearchServiceInterface = (*SearchService)(nil)
+var _ IntegrityServiceInterface = (*IntegrityService)(nil)
+var _ InboundEmailServiceInterface = (*InboundEmailService)(nil)
+var _ LoginAuditServiceInterface = (*LoginAuditService)(nil)
+var _ RequestMetricsInterface = (*RequestMetrics)(nil)
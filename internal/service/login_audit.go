@@ -0,0 +1,78 @@
+package service
+
+import (
+	"log/slog"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+)
+
+// loginAuditMaxEvents is how many login attempts are kept. A device whose
+// last login dropped out of the history counts as new again.
+const loginAuditMaxEvents = 1000
+
+// LoginAuditService records login attempts and alerts through the
+// notification channels when an account logs in from a new IP or browser
+type LoginAuditService struct {
+	events   *repository.LoginEventRepository
+	settings *SettingsService
+	notifier NotificationDispatcherInterface
+}
+
+func NewLoginAuditService(events *repository.LoginEventRepository, settings *SettingsService, notifier NotificationDispatcherInterface) *LoginAuditService {
+	return &LoginAuditService{events: events, settings: settings, notifier: notifier}
+}
+
+// Record stores a login attempt. A successful login is marked as from a new
+// device when its IP and user agent never logged in successfully before; the
+// very first login has nothing to compare with and is not marked.
+func (s *LoginAuditService) Record(username, role, ip, userAgent string, success bool) (*models.LoginEvent, error) {
+	event := &models.LoginEvent{
+		Username:  truncate(username, 100),
+		Role:      role,
+		Success:   success,
+		IP:        truncate(ip, 64),
+		UserAgent: truncate(userAgent, 255),
+	}
+	if success {
+		total, err := s.events.CountSuccessful()
+		if err != nil {
+			return nil, err
+		}
+		seen, err := s.events.CountSuccessfulFrom(event.IP, event.UserAgent)
+		if err != nil {
+			return nil, err
+		}
+		event.NewDevice = total > 0 && seen == 0
+	}
+
+	if err := s.events.Create(event); err != nil {
+		return nil, err
+	}
+	if err := s.events.Trim(loginAuditMaxEvents); err != nil {
+		return nil, err
+	}
+	if !success {
+		slog.Warn("failed login attempt", "username", event.Username, "ip", event.IP)
+	}
+	return event, nil
+}
+
+// Recent returns the newest limit login attempts, newest first
+func (s *LoginAuditService) Recent(limit int) ([]models.LoginEvent, error) {
+	return s.events.Recent(limit)
+}
+
+// AlertsEnabled reports whether logins from new devices are notified
+func (s *LoginAuditService) AlertsEnabled() bool {
+	return s.settings.GetBoolSettingWithDefault("login_alerts", false)
+}
+
+// SendAlert notifies of a login from a new device if login alerts are
+// enabled. Other logins are ignored.
+func (s *LoginAuditService) SendAlert(event *models.LoginEvent) error {
+	if !event.NewDevice || !s.AlertsEnabled() {
+		return nil
+	}
+	return s.notifier.SendLoginAlert(event)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginAuditService_NewDeviceAlerts(t *testing.T) {
+	db := setupShoutrrrTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.LoginEvent{}))
+	settings := NewSettingsService(repository.NewSettingsRepository(db))
+	push := &fakeNotifier{channel: models.ChannelShoutrrr}
+	audit := NewLoginAuditService(repository.NewLoginEventRepository(db), settings, NewNotificationDispatcher(push))
+
+	const firefox = "Mozilla/5.0 Firefox/130.0"
+
+	// Failed attempts are recorded but never count as a known device
+	failed, err := audit.Record("admin", "", "203.0.113.7", firefox, false)
+	require.NoError(t, err)
+	assert.False(t, failed.NewDevice)
+
+	// The first login has nothing to compare with
+	first, err := audit.Record("admin", RoleAdmin, "192.0.2.1", firefox, true)
+	require.NoError(t, err)
+	assert.False(t, first.NewDevice)
+
+	again, err := audit.Record("admin", RoleAdmin, "192.0.2.1", firefox, true)
+	require.NoError(t, err)
+	assert.False(t, again.NewDevice)
+
+	// A new IP or a new browser is a new device
+	newIP, err := audit.Record("admin", RoleAdmin, "203.0.113.7", firefox, true)
+	require.NoError(t, err)
+	assert.True(t, newIP.NewDevice)
+	newBrowser, err := audit.Record("viewer", RoleViewer, "192.0.2.1", "curl/8.5.0", true)
+	require.NoError(t, err)
+	assert.True(t, newBrowser.NewDevice)
+
+	// Alerts are only sent when enabled and only for new devices
+	require.NoError(t, audit.SendAlert(newIP))
+	assert.Empty(t, push.sent)
+	require.NoError(t, settings.SetBoolSetting("login_alerts", true))
+	require.NoError(t, audit.SendAlert(again))
+	require.NoError(t, audit.SendAlert(newIP))
+	assert.Equal(t, []string{"login_alert"}, push.sent)
+
+	recent, err := audit.Recent(2)
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	assert.Equal(t, "viewer", recent[0].Username)
+	assert.Equal(t, RoleViewer, recent[0].Role)
+}
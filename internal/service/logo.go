@@ -1,72 +1,215 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"html"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"regexp"
+	"slices"
 	"strings"
-	"time"
 )
 
+// maxLogoSize is the largest logo that is downloaded or stored locally
+const maxLogoSize = 512 * 1024
+
+// maxLogoPageSize limits how much of a website is read to find its icons
+const maxLogoPageSize = 1 << 20
+
+// Logo sources, in the order they are tried
+const (
+	LogoSourceAppleTouchIcon = "apple-touch-icon"
+	LogoSourceFavicon        = "favicon"
+	LogoSourceGoogle         = "google"
+	LogoSourceDuckDuckGo     = "duckduckgo"
+)
+
+// Third-party favicon services; %s is the domain
+const (
+	googleFaviconURL     = "https://www.google.com/s2/favicons?domain=%s&sz=64"
+	duckDuckGoFaviconURL = "https://icons.duckduckgo.com/ip3/%s.ico"
+)
+
+var (
+	linkTagPattern = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+	attrPattern    = regexp.MustCompile(`(?is)([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+// LogoCandidate is a possible logo for a website
+type LogoCandidate struct {
+	URL    string `json:"url"`
+	Source string `json:"source"`
+}
+
 // LogoService handles fetching logos/icons for subscriptions
 type LogoService struct {
 	httpClient *http.Client
+	policy     LogoFetchPolicy
+	settings   SettingsServiceInterface
+
+	// Third-party favicon services, overridable in tests
+	googleURL     string
+	duckDuckGoURL string
 }
 
-// NewLogoService creates a new logo service
-func NewLogoService() *LogoService {
+// NewLogoService creates a new logo service. Websites and logos are fetched
+// according to the policy, which blocks internal addresses by default.
+func NewLogoService(settings SettingsServiceInterface, policy LogoFetchPolicy) *LogoService {
 	return &LogoService{
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		httpClient:    newLogoHTTPClient(policy, outboundProxy),
+		policy:        policy,
+		settings:      settings,
+		googleURL:     googleFaviconURL,
+		duckDuckGoURL: duckDuckGoFaviconURL,
 	}
 }
 
-// FetchLogoFromURL extracts the domain from a website URL and returns a favicon URL
-// Uses Google's favicon service as the primary source
-func (s *LogoService) FetchLogoFromURL(websiteURL string) (string, error) {
-	if websiteURL == "" {
-		return "", fmt.Errorf("empty URL provided")
+// PrivacyMode reports whether third-party favicon services are disabled, so
+// logos are only looked up on the subscription's own website
+func (s *LogoService) PrivacyMode() bool {
+	return s.settings.GetBoolSettingWithDefault(SettingKeyLogoPrivacyMode, false)
+}
+
+// FetchLogoFromURL returns the logo URL for a website. The candidates are tried
+// in order (the site's apple-touch-icon and favicon, then Google and DuckDuckGo
+// unless privacy mode is on) and the first one that serves an image wins.
+// If none can be verified, the Google favicon URL is returned as before and the
+// browser falls back to the initial when it does not load.
+func (s *LogoService) FetchLogoFromURL(ctx context.Context, websiteURL string) (string, error) {
+	candidates, err := s.Candidates(ctx, websiteURL)
+	if err != nil {
+		return "", err
 	}
 
-	// Normalize URL - add https:// if no protocol is specified
-	normalizedURL := strings.TrimSpace(websiteURL)
-	if !strings.HasPrefix(normalizedURL, "http://") && !strings.HasPrefix(normalizedURL, "https://") {
-		normalizedURL = "https://" + normalizedURL
+	for _, candidate := range candidates {
+		if s.isImage(ctx, candidate.URL) {
+			return candidate.URL, nil
+		}
 	}
 
-	// Parse the URL to extract domain
-	parsedURL, err := url.Parse(normalizedURL)
-	if err != nil {
-		return "", fmt.Errorf("invalid URL: %w", err)
+	if s.PrivacyMode() {
+		return "", fmt.Errorf("no logo found on %s", websiteURL)
 	}
+	return fmt.Sprintf(s.googleURL, url.QueryEscape(s.ExtractDomain(websiteURL))), nil
+}
 
-	// Get the domain (hostname without port)
-	domain := parsedURL.Hostname()
+// Candidates lists the possible logos of a website in the order they are
+// tried. The website is read for its icon links; the candidates are not checked.
+// Nothing is looked up in offline mode.
+func (s *LogoService) Candidates(ctx context.Context, websiteURL string) ([]LogoCandidate, error) {
+	if OfflineMode() {
+		return nil, ErrOfflineMode
+	}
+	domain := s.ExtractDomain(websiteURL)
 	if domain == "" {
-		// If hostname is empty, try using the path as domain (for cases like "netflix.com")
-		if parsedURL.Path != "" {
-			domain = strings.TrimPrefix(parsedURL.Path, "/")
-		} else {
-			return "", fmt.Errorf("could not extract domain from URL")
+		return nil, fmt.Errorf("could not extract domain from URL %q", websiteURL)
+	}
+
+	candidates := s.siteCandidates(ctx, siteRoot(websiteURL, domain))
+	if !s.PrivacyMode() {
+		candidates = append(candidates,
+			LogoCandidate{URL: fmt.Sprintf(s.googleURL, url.QueryEscape(domain)), Source: LogoSourceGoogle},
+			LogoCandidate{URL: fmt.Sprintf(s.duckDuckGoURL, url.PathEscape(domain)), Source: LogoSourceDuckDuckGo},
+		)
+	}
+	return candidates, nil
+}
+
+// siteCandidates returns the icons linked from a website's home page,
+// apple-touch-icons first, followed by /favicon.ico
+func (s *LogoService) siteCandidates(ctx context.Context, root *url.URL) []LogoCandidate {
+	var touchIcons, favicons []LogoCandidate
+	seen := make(map[string]bool)
+	add := func(list *[]LogoCandidate, href, source string, base *url.URL) {
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil || href == "" {
+			return
+		}
+		resolved := base.ResolveReference(ref)
+		if s.policy.checkURL(resolved) != nil {
+			return
+		}
+		if !seen[resolved.String()] {
+			seen[resolved.String()] = true
+			*list = append(*list, LogoCandidate{URL: resolved.String(), Source: source})
 		}
 	}
 
-	// Remove www. prefix for cleaner lookups
-	domain = strings.TrimPrefix(domain, "www.")
-	// Remove trailing slashes
-	domain = strings.TrimSuffix(domain, "/")
+	if resp, err := s.request(ctx, http.MethodGet, root.String()); err != nil {
+		slog.Debug("failed to read website for icons", "url", root.String(), "error", err)
+	} else {
+		page, _ := io.ReadAll(io.LimitReader(resp.Body, maxLogoPageSize))
+		resp.Body.Close()
+		if !strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "html") &&
+			!strings.HasPrefix(http.DetectContentType(page), "text/html") {
+			page = nil
+		}
+		base := resp.Request.URL
+		for _, tag := range linkTagPattern.FindAllString(string(page), -1) {
+			attrs := linkAttributes(tag)
+			rel := strings.Fields(strings.ToLower(attrs["rel"]))
+			switch {
+			case slices.Contains(rel, "apple-touch-icon"), slices.Contains(rel, "apple-touch-icon-precomposed"):
+				add(&touchIcons, attrs["href"], LogoSourceAppleTouchIcon, base)
+			case slices.Contains(rel, "icon"):
+				add(&favicons, attrs["href"], LogoSourceFavicon, base)
+			}
+		}
+	}
+	add(&favicons, "/favicon.ico", LogoSourceFavicon, root)
+
+	return append(touchIcons, favicons...)
+}
+
+// isImage reports whether a URL serves an image of at most maxLogoSize bytes
+func (s *LogoService) isImage(ctx context.Context, imageURL string) bool {
+	resp, err := s.request(ctx, http.MethodGet, imageURL)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || resp.ContentLength > maxLogoSize {
+		return false
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(resp.Body, head)
+	// Logos without a Content-Length are read up to the limit to enforce it
+	rest, _ := io.Copy(io.Discard, io.LimitReader(resp.Body, maxLogoSize))
+	if int64(n)+rest > maxLogoSize {
+		return false
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" || strings.HasPrefix(contentType, "application/octet-stream") {
+		contentType = http.DetectContentType(head[:n])
+	}
+	return n > 0 && (strings.HasPrefix(contentType, "image/") || strings.Contains(contentType, "icon"))
+}
 
-	// Try Google's favicon service first (most reliable)
-	faviconURL := fmt.Sprintf("https://www.google.com/s2/favicons?domain=%s&sz=64", url.QueryEscape(domain))
+// siteRoot returns the home page of a website, keeping an explicit http scheme and port
+func siteRoot(websiteURL, domain string) *url.URL {
+	if parsed, err := url.Parse(strings.TrimSpace(websiteURL)); err == nil && parsed.Host != "" &&
+		(parsed.Scheme == "http" || parsed.Scheme == "https") {
+		return &url.URL{Scheme: parsed.Scheme, Host: parsed.Host, Path: "/"}
+	}
+	return &url.URL{Scheme: "https", Host: domain, Path: "/"}
+}
 
-	return faviconURL, nil
+// linkAttributes returns the lower-cased attribute names and values of a <link> tag
+func linkAttributes(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range attrPattern.FindAllStringSubmatch(tag, -1) {
+		attrs[strings.ToLower(match[1])] = html.UnescapeString(match[2] + match[3] + match[4])
+	}
+	return attrs
 }
 
 // GetLogoURL returns the logo URL for a subscription
 // Returns the stored IconURL if available, otherwise tries to fetch from URL
-func (s *LogoService) GetLogoURL(iconURL, websiteURL string) string {
+func (s *LogoService) GetLogoURL(ctx context.Context, iconURL, websiteURL string) string {
 	// If icon URL is already set, return it
 	if iconURL != "" {
 		return iconURL
@@ -78,7 +221,7 @@ func (s *LogoService) GetLogoURL(iconURL, websiteURL string) string {
 	}
 
 	// Try to fetch logo from website URL
-	fetchedURL, err := s.FetchLogoFromURL(websiteURL)
+	fetchedURL, err := s.FetchLogoFromURL(ctx, websiteURL)
 	if err != nil {
 		return ""
 	}
@@ -87,12 +230,12 @@ func (s *LogoService) GetLogoURL(iconURL, websiteURL string) string {
 }
 
 // ValidateLogoURL checks if a logo URL is accessible
-func (s *LogoService) ValidateLogoURL(logoURL string) bool {
+func (s *LogoService) ValidateLogoURL(ctx context.Context, logoURL string) bool {
 	if logoURL == "" {
 		return false
 	}
 
-	resp, err := s.httpClient.Head(logoURL)
+	resp, err := s.request(ctx, http.MethodHead, logoURL)
 	if err != nil {
 		return false
 	}
@@ -103,14 +246,14 @@ func (s *LogoService) ValidateLogoURL(logoURL string) bool {
 }
 
 // FetchAndValidateLogo fetches a logo and validates it's accessible
-func (s *LogoService) FetchAndValidateLogo(websiteURL string) (string, error) {
-	logoURL, err := s.FetchLogoFromURL(websiteURL)
+func (s *LogoService) FetchAndValidateLogo(ctx context.Context, websiteURL string) (string, error) {
+	logoURL, err := s.FetchLogoFromURL(ctx, websiteURL)
 	if err != nil {
 		return "", err
 	}
 
 	// Validate the logo URL (check if it's accessible)
-	if !s.ValidateLogoURL(logoURL) {
+	if !s.ValidateLogoURL(ctx, logoURL) {
 		// Still return the URL even if validation fails
 		// The browser will handle broken images gracefully
 		return logoURL, nil
@@ -154,8 +297,8 @@ func (s *LogoService) ExtractDomain(websiteURL string) string {
 
 // DownloadLogo downloads a logo from a URL and returns the image data
 // This is for future use if we want to store logos locally
-func (s *LogoService) DownloadLogo(logoURL string) ([]byte, error) {
-	resp, err := s.httpClient.Get(logoURL)
+func (s *LogoService) DownloadLogo(ctx context.Context, logoURL string) ([]byte, error) {
+	resp, err := s.request(ctx, http.MethodGet, logoURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download logo: %w", err)
 	}
@@ -164,11 +307,37 @@ func (s *LogoService) DownloadLogo(logoURL string) ([]byte, error) {
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("failed to download logo: status %d", resp.StatusCode)
 	}
+	if resp.ContentLength > maxLogoSize {
+		return nil, fmt.Errorf("logo is larger than %d bytes", maxLogoSize)
+	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxLogoSize+1))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read logo data: %w", err)
 	}
+	if len(data) > maxLogoSize {
+		return nil, fmt.Errorf("logo is larger than %d bytes", maxLogoSize)
+	}
+	if contentType := http.DetectContentType(data); !strings.HasPrefix(contentType, "image/") &&
+		!strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
+		return nil, fmt.Errorf("logo is not an image (%s)", contentType)
+	}
 
 	return data, nil
 }
+
+// request sends a request to a user supplied URL after checking it against the policy
+func (s *LogoService) request(ctx context.Context, method, rawURL string) (*http.Response, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.checkURL(parsed); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, parsed.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.httpClient.Do(req)
+}
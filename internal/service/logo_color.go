@@ -0,0 +1,104 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // Logo formats the dominant color is read from
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// ErrNoLogoColor is returned for a logo without a distinct color, like a black
+// or grey one, or one in a format that cannot be read (SVG, BMP icons)
+var ErrNoLogoColor = errors.New("logo has no dominant color")
+
+// logoColorSamples is how many pixels per side are sampled at most
+const logoColorSamples = 64
+
+// LogoColor downloads a logo and returns its dominant color
+func (s *LogoService) LogoColor(ctx context.Context, logoURL string) (string, error) {
+	data, err := s.DownloadLogo(ctx, logoURL)
+	if err != nil {
+		return "", err
+	}
+	return DominantColor(data)
+}
+
+// DominantColor returns the most common distinct color of a PNG, JPEG, GIF or
+// ICO logo as a hex color. Transparent, near black and grey pixels like
+// backgrounds and outlines are left out, and saturated colors weigh more.
+func DominantColor(data []byte) (string, error) {
+	img, _, err := image.Decode(bytes.NewReader(icoImage(data)))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNoLogoColor, err)
+	}
+
+	type bucket struct{ r, g, b, count, weight float64 }
+	buckets := map[int]*bucket{}
+	var best *bucket
+	bounds := img.Bounds()
+	step := max(1, max(bounds.Dx(), bounds.Dy())/logoColorSamples)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			r16, g16, b16, a16 := img.At(x, y).RGBA()
+			if a16 < 0x8000 {
+				continue
+			}
+			// RGBA is premultiplied by alpha
+			r, g, b := float64(r16)*255/float64(a16), float64(g16)*255/float64(a16), float64(b16)*255/float64(a16)
+			hi, lo := max(r, g, b), min(r, g, b)
+			if hi < 40 || (hi-lo)/hi < 0.2 {
+				continue
+			}
+
+			key := int(r)>>5<<6 | int(g)>>5<<3 | int(b)>>5
+			bk := buckets[key]
+			if bk == nil {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.r, bk.g, bk.b, bk.count = bk.r+r, bk.g+g, bk.b+b, bk.count+1
+			bk.weight += 1 + (hi-lo)/hi
+			if best == nil || bk.weight > best.weight {
+				best = bk
+			}
+		}
+	}
+	if best == nil {
+		return "", ErrNoLogoColor
+	}
+	return fmt.Sprintf("#%02x%02x%02x", int(best.r/best.count+0.5), int(best.g/best.count+0.5), int(best.b/best.count+0.5)), nil
+}
+
+// icoImage returns the largest PNG stored in an ICO file, or data unchanged
+// if it is not an ICO file or holds no PNG
+func icoImage(data []byte) []byte {
+	if len(data) < 6 || binary.LittleEndian.Uint16(data[0:2]) != 0 || binary.LittleEndian.Uint16(data[2:4]) != 1 {
+		return data
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	var largest []byte
+	for i := 0; i < count; i++ {
+		entry := 6 + 16*i
+		if entry+16 > len(data) {
+			break
+		}
+		size := int(binary.LittleEndian.Uint32(data[entry+8 : entry+12]))
+		offset := int(binary.LittleEndian.Uint32(data[entry+12 : entry+16]))
+		if size <= 0 || offset < 0 || offset+size > len(data) {
+			continue
+		}
+		embedded := data[offset : offset+size]
+		if bytes.HasPrefix(embedded, []byte("\x89PNG")) && len(embedded) > len(largest) {
+			largest = embedded
+		}
+	}
+	if largest == nil {
+		return data
+	}
+	return largest
+}
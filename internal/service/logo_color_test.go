@@ -0,0 +1,101 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logoPNG encodes a 32x32 logo filled by fill
+func logoPNG(t *testing.T, fill func(x, y int) color.Color) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			img.Set(x, y, fill(x, y))
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestDominantColor(t *testing.T) {
+	green := color.NRGBA{R: 0x1d, G: 0xb9, B: 0x54, A: 0xff}
+
+	t.Run("ignores background and outline", func(t *testing.T) {
+		data := logoPNG(t, func(x, y int) color.Color {
+			switch {
+			case x < 4:
+				return color.Black
+			case y < 8:
+				return color.White
+			case y < 12:
+				return color.Transparent
+			}
+			return green
+		})
+		got, err := DominantColor(data)
+		require.NoError(t, err)
+		assert.Equal(t, "#1db954", got)
+	})
+
+	t.Run("prefers the larger area", func(t *testing.T) {
+		data := logoPNG(t, func(x, y int) color.Color {
+			if y < 10 {
+				return color.NRGBA{R: 0xe5, G: 0x09, B: 0x14, A: 0xff}
+			}
+			return green
+		})
+		got, err := DominantColor(data)
+		require.NoError(t, err)
+		assert.Equal(t, "#1db954", got)
+	})
+
+	t.Run("no distinct color", func(t *testing.T) {
+		data := logoPNG(t, func(x, y int) color.Color {
+			if x%2 == 0 {
+				return color.Gray{Y: 0x80}
+			}
+			return color.Transparent
+		})
+		_, err := DominantColor(data)
+		assert.ErrorIs(t, err, ErrNoLogoColor)
+
+		_, err = DominantColor([]byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`))
+		assert.ErrorIs(t, err, ErrNoLogoColor)
+	})
+
+	t.Run("icon file with a PNG", func(t *testing.T) {
+		embedded := logoPNG(t, func(x, y int) color.Color { return green })
+		ico := make([]byte, 22)
+		binary.LittleEndian.PutUint16(ico[2:4], 1)
+		binary.LittleEndian.PutUint16(ico[4:6], 1)
+		binary.LittleEndian.PutUint32(ico[14:18], uint32(len(embedded)))
+		binary.LittleEndian.PutUint32(ico[18:22], 22)
+		got, err := DominantColor(append(ico, embedded...))
+		require.NoError(t, err)
+		assert.Equal(t, "#1db954", got)
+	})
+}
+
+func TestLogoService_LogoColor(t *testing.T) {
+	data := logoPNG(t, func(x, y int) color.Color { return color.NRGBA{R: 0x25, G: 0x63, B: 0xeb, A: 0xff} })
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	logos, _ := setupLogoService(t, server)
+
+	got, err := logos.LogoColor(t.Context(), server.URL+"/logo.png")
+	require.NoError(t, err)
+	assert.Equal(t, "#2563eb", got)
+}
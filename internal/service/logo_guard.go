@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxLogoRedirects is how many redirects a logo request may follow
+const maxLogoRedirects = 3
+
+// logoRequestTimeout bounds a single logo request including redirects
+const logoRequestTimeout = 10 * time.Second
+
+// ErrBlockedAddress is returned when a logo request would connect to a
+// private, loopback or otherwise internal address
+var ErrBlockedAddress = errors.New("address is not publicly routable")
+
+// blockedPrefixes are special-purpose ranges not covered by the netip helpers
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, embeds IPv4 addresses
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4, embeds IPv4 addresses
+	netip.MustParsePrefix("2001::/32"),       // Teredo, embeds IPv4 addresses
+	netip.MustParsePrefix("fec0::/10"),       // deprecated site-local
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // IPv4-translated
+}
+
+// LogoFetchPolicy restricts the requests the logo service makes to user supplied URLs
+type LogoFetchPolicy struct {
+	// AllowedSchemes are the URL schemes logos and websites may use; http and
+	// https are the only ones supported. Empty allows both.
+	AllowedSchemes []string
+	// AllowPrivateNetworks permits loopback, private and link-local addresses,
+	// e.g. for logos served from the local network
+	AllowPrivateNetworks bool
+}
+
+// schemes returns the allowed schemes, defaulting to http and https
+func (p LogoFetchPolicy) schemes() []string {
+	var schemes []string
+	for _, scheme := range p.AllowedSchemes {
+		scheme = strings.ToLower(strings.TrimSpace(scheme))
+		if (scheme == "http" || scheme == "https") && !slices.Contains(schemes, scheme) {
+			schemes = append(schemes, scheme)
+		}
+	}
+	if len(schemes) == 0 {
+		return []string{"https", "http"}
+	}
+	return schemes
+}
+
+// checkURL rejects URLs with a scheme the policy does not allow
+func (p LogoFetchPolicy) checkURL(u *url.URL) error {
+	if !slices.Contains(p.schemes(), strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("URL scheme %q is not allowed", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return errors.New("URL has no host")
+	}
+	return nil
+}
+
+// isPublicAddr reports whether an address is publicly routable
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// newLogoHTTPClient returns a client that enforces the policy. The address is
+// checked in the dialer after DNS resolution, right before connecting, so a
+// host name cannot resolve to a public address for the check and to an
+// internal one for the connection. A request through the proxy is connected
+// by the proxy instead, so its host name is resolved and checked before the
+// request; the proxy itself may be on a private network.
+func newLogoHTTPClient(policy LogoFetchPolicy, proxy *OutboundProxy) *http.Client {
+	direct := &net.Dialer{Timeout: 5 * time.Second}
+	guarded := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if policy.AllowPrivateNetworks {
+				return nil
+			}
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy.Proxy,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			if proxy.isProxyAddress(address) {
+				return direct.DialContext(ctx, network, address)
+			}
+			return guarded.DialContext(ctx, network, address)
+		},
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   logoRequestTimeout,
+		Transport: &logoProxyGuard{transport: transport, policy: policy},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxLogoRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxLogoRedirects)
+			}
+			return policy.checkURL(req.URL)
+		},
+	}
+}
+
+// logoProxyGuard checks the addresses of the host of a request that goes
+// through the proxy, as the dialer only sees the proxy's address
+type logoProxyGuard struct {
+	transport *http.Transport
+	policy    LogoFetchPolicy
+}
+
+func (g *logoProxyGuard) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !g.policy.AllowPrivateNetworks {
+		proxyURL, err := g.transport.Proxy(req)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL != nil {
+			addrs, err := net.DefaultResolver.LookupNetIP(req.Context(), "ip", req.URL.Hostname())
+			if err != nil {
+				return nil, err
+			}
+			for _, addr := range addrs {
+				if !isPublicAddr(addr) {
+					return nil, fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+				}
+			}
+		}
+	}
+	return g.transport.RoundTrip(req)
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// logoQueueBatchSize is how many pending logos are loaded at a time
+const logoQueueBatchSize = 50
+
+// ErrLogoSubscriptionNotFound is returned when retrying the logo of a subscription that does not exist
+var ErrLogoSubscriptionNotFound = errors.New("subscription not found")
+
+// ErrNoWebsite is returned when a logo is requested for a subscription without a website
+var ErrNoWebsite = errors.New("subscription has no website")
+
+// ErrInvalidLogo is returned for an uploaded icon that is too large or not a
+// PNG, JPEG, GIF, WebP, ICO or SVG image
+var ErrInvalidLogo = errors.New("invalid icon, upload a PNG, JPEG, GIF, WebP, ICO or SVG image of at most 512 KB")
+
+// LogoQueueResult summarizes a run of the logo queue
+type LogoQueueResult struct {
+	Fetched int `json:"fetched"`
+	Failed  int `json:"failed"`
+}
+
+// LogoQueueService looks up subscription logos in the background, so saving a
+// subscription does not wait for a slow website. The queue is the logo status
+// stored on the subscriptions and survives restarts. Uploaded icons are
+// stored in logosDir and never looked up.
+type LogoQueueService struct {
+	repo     *repository.SubscriptionRepository
+	logos    LogoServiceInterface
+	logosDir string
+	wake     chan struct{}
+}
+
+func NewLogoQueueService(repo *repository.SubscriptionRepository, logos LogoServiceInterface, logosDir string) *LogoQueueService {
+	return &LogoQueueService{
+		repo:     repo,
+		logos:    logos,
+		logosDir: logosDir,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Enqueue marks the logo of a subscription for lookup
+func (s *LogoQueueService) Enqueue(ctx context.Context, id uint) error {
+	if err := s.repo.SetLogoStatus(ctx, id, models.LogoPending); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// Retry removes the icon of a subscription and looks up its logo again. Logos
+// are not looked up in offline mode.
+func (s *LogoQueueService) Retry(ctx context.Context, id uint) error {
+	if OfflineMode() {
+		return ErrOfflineMode
+	}
+	sub, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrLogoSubscriptionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if sub.URL == "" {
+		return ErrNoWebsite
+	}
+	if err := s.repo.QueueLogo(ctx, id); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// Upload stores an uploaded icon for a subscription and returns the URL it
+// is served under. The icon replaces a looked up logo for good.
+func (s *LogoQueueService) Upload(ctx context.Context, id uint, data []byte) (string, error) {
+	if len(data) == 0 || len(data) > maxLogoSize {
+		return "", ErrInvalidLogo
+	}
+	ext, ok := detectLogoType(data)
+	if !ok {
+		return "", ErrInvalidLogo
+	}
+	iconURL, err := storeLogo(s.logosDir, data, ext)
+	if err != nil {
+		return "", err
+	}
+	// SVG and BMP icons have no color, the status colors are used instead
+	color, _ := DominantColor(data)
+	err = s.repo.SetUploadedLogo(ctx, id, iconURL, color)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrLogoSubscriptionNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return iconURL, nil
+}
+
+// Wakeups is signalled whenever a logo was queued
+func (s *LogoQueueService) Wakeups() <-chan struct{} {
+	return s.wake
+}
+
+// Process looks up all pending logos. Subscriptions that got an icon or lost
+// their website while queued are dropped from the queue. In offline mode the
+// queue is kept until it is turned off.
+func (s *LogoQueueService) Process(ctx context.Context) (LogoQueueResult, error) {
+	var result LogoQueueResult
+	if OfflineMode() {
+		return result, nil
+	}
+	for {
+		pending, err := s.repo.GetPendingLogos(ctx, logoQueueBatchSize)
+		if err != nil {
+			return result, err
+		}
+
+		for _, sub := range pending {
+			if sub.URL == "" || sub.IconURL != "" {
+				if err := s.repo.SetLogoStatus(ctx, sub.ID, ""); err != nil {
+					return result, err
+				}
+				continue
+			}
+
+			iconURL, err := s.logos.FetchLogoFromURL(ctx, sub.URL)
+			if err != nil || iconURL == "" {
+				slog.Warn("failed to fetch logo", "subscription_id", sub.ID, "url", sub.URL, "error", err)
+				if err := s.repo.SetLogoStatus(ctx, sub.ID, models.LogoFailed); err != nil {
+					return result, err
+				}
+				result.Failed++
+				continue
+			}
+			// The color is optional, a logo without one uses the status colors
+			color, err := s.logos.LogoColor(ctx, iconURL)
+			if err != nil {
+				slog.Debug("no logo color", "subscription_id", sub.ID, "error", err)
+			}
+			if err := s.repo.SetFetchedLogo(ctx, sub.ID, iconURL, color); err != nil {
+				return result, err
+			}
+			result.Fetched++
+		}
+
+		if len(pending) < logoQueueBatchSize {
+			return result, nil
+		}
+	}
+}
+
+// notify wakes the worker without blocking; one pending signal is enough as
+// the worker processes everything that is queued
+func (s *LogoQueueService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
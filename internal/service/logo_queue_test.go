@@ -0,0 +1,121 @@
+package service
+
+import (
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogoQueueService_Process(t *testing.T) {
+	server := fakeWebsite(t, `<link rel="icon" href="/icon.png">`)
+	logos, _ := setupLogoService(t, server)
+	db := setupRenewalReminderTestDB(t)
+	repo := repository.NewSubscriptionRepository(db)
+	queue := NewLogoQueueService(repo, logos, t.TempDir())
+
+	create := func(name, url, iconURL string) *models.Subscription {
+		sub, err := repo.Create(t.Context(), &models.Subscription{Name: name, Cost: 10, Schedule: "Monthly", Status: "Active", URL: url, IconURL: iconURL})
+		require.NoError(t, err)
+		require.NoError(t, queue.Enqueue(t.Context(), sub.ID))
+		return sub
+	}
+	netflix := create("Netflix", server.URL+"/browse", "")
+	broken := create("Broken", "https://", "")
+	custom := create("Custom", server.URL, "/logos/custom.png")
+
+	select {
+	case <-queue.Wakeups():
+	default:
+		t.Fatal("enqueue did not wake the worker")
+	}
+
+	result, err := queue.Process(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, LogoQueueResult{Fetched: 1, Failed: 1}, result)
+
+	got, err := repo.GetByID(t.Context(), netflix.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.LogoFetched, got.LogoStatus)
+	assert.Equal(t, server.URL+"/icon.png", got.IconURL)
+	// The fake icon has no readable color and the status colors are used
+	assert.Empty(t, got.IconColor)
+
+	got, err = repo.GetByID(t.Context(), broken.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.LogoFailed, got.LogoStatus)
+
+	// An icon set while queued is kept and leaves the queue
+	got, err = repo.GetByID(t.Context(), custom.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "/logos/custom.png", got.IconURL)
+	assert.Empty(t, got.LogoStatus)
+
+	// Retry replaces the icon with a newly fetched logo
+	require.NoError(t, queue.Retry(t.Context(), custom.ID))
+	result, err = queue.Process(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Fetched)
+	got, err = repo.GetByID(t.Context(), custom.ID)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/icon.png", got.IconURL)
+
+	noWebsite, err := repo.Create(t.Context(), &models.Subscription{Name: "Offline", Cost: 1, Schedule: "Monthly", Status: "Active"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, queue.Retry(t.Context(), noWebsite.ID), ErrNoWebsite)
+	assert.ErrorIs(t, queue.Retry(t.Context(), 9999), ErrLogoSubscriptionNotFound)
+}
+
+func TestLogoQueueService_Upload(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	repo := repository.NewSubscriptionRepository(db)
+	logosDir := t.TempDir()
+	queue := NewLogoQueueService(repo, nil, logosDir)
+	sub, err := repo.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 10, Schedule: "Monthly", Status: "Active", URL: "https://netflix.com", LogoStatus: models.LogoFetched, IconURL: "https://netflix.com/icon.png"})
+	require.NoError(t, err)
+
+	icon := logoPNG(t, func(x, y int) color.Color { return color.RGBA{R: 229, G: 9, B: 20, A: 255} })
+	iconURL, err := queue.Upload(t.Context(), sub.ID, icon)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(iconURL, "/logos/") && strings.HasSuffix(iconURL, ".png"), iconURL)
+	assert.FileExists(t, filepath.Join(logosDir, strings.TrimPrefix(iconURL, "/logos/")))
+
+	// An uploaded icon is not looked up again when the website changes
+	got, err := repo.GetByID(t.Context(), sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, iconURL, got.IconURL)
+	assert.Equal(t, "#e50914", got.IconColor)
+	assert.Empty(t, got.LogoStatus)
+
+	_, err = queue.Upload(t.Context(), sub.ID, []byte("not an image"))
+	assert.ErrorIs(t, err, ErrInvalidLogo)
+	_, err = queue.Upload(t.Context(), sub.ID, make([]byte, maxLogoSize+1))
+	assert.ErrorIs(t, err, ErrInvalidLogo)
+	_, err = queue.Upload(t.Context(), 9999, icon)
+	assert.ErrorIs(t, err, ErrLogoSubscriptionNotFound)
+}
+
+func TestLogoQueueService_OfflineMode(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	repo := repository.NewSubscriptionRepository(db)
+	queue := NewLogoQueueService(repo, nil, t.TempDir())
+	sub, err := repo.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 10, Schedule: "Monthly", Status: "Active", URL: "https://netflix.com"})
+	require.NoError(t, err)
+	require.NoError(t, queue.Enqueue(t.Context(), sub.ID))
+	enableOfflineMode(t)
+
+	// Nothing is looked up and the queue is kept for later
+	result, err := queue.Process(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, LogoQueueResult{}, result)
+	got, err := repo.GetByID(t.Context(), sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.LogoPending, got.LogoStatus)
+	assert.ErrorIs(t, queue.Retry(t.Context(), sub.ID), ErrOfflineMode)
+}
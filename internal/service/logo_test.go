@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is enough of a PNG file for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// fakeWebsite serves a home page linking its icons and a fake favicon service
+func fakeWebsite(t *testing.T, homePage string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(homePage))
+	})
+	image := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngHeader)
+	}
+	mux.HandleFunc("/static/touch.png", image)
+	mux.HandleFunc("/icon.png", image)
+	mux.HandleFunc("/s2/favicons", image)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func setupLogoService(t *testing.T, server *httptest.Server) (*LogoService, *SettingsService) {
+	db := setupRenewalReminderTestDB(t)
+	settings := NewSettingsService(repository.NewSettingsRepository(db))
+	// The fake website listens on the loopback address
+	logos := NewLogoService(settings, LogoFetchPolicy{AllowPrivateNetworks: true})
+	logos.googleURL = server.URL + "/s2/favicons?domain=%s"
+	logos.duckDuckGoURL = server.URL + "/ip3/%s.ico"
+	return logos, settings
+}
+
+func TestLogoService_Candidates(t *testing.T) {
+	server := fakeWebsite(t, `<html><head>
+		<link rel="icon" href="/icon.png">
+		<LINK REL='apple-touch-icon' sizes="180x180" HREF='static/touch.png'>
+		<link rel="stylesheet" href="/style.css">
+		<link rel="shortcut icon" href="/icon.png">
+	</head></html>`)
+	logos, settings := setupLogoService(t, server)
+
+	candidates, err := logos.Candidates(context.Background(), server.URL+"/account")
+	require.NoError(t, err)
+	assert.Equal(t, []LogoCandidate{
+		{URL: server.URL + "/static/touch.png", Source: LogoSourceAppleTouchIcon},
+		{URL: server.URL + "/icon.png", Source: LogoSourceFavicon},
+		{URL: server.URL + "/favicon.ico", Source: LogoSourceFavicon},
+		{URL: server.URL + "/s2/favicons?domain=127.0.0.1", Source: LogoSourceGoogle},
+		{URL: server.URL + "/ip3/127.0.0.1.ico", Source: LogoSourceDuckDuckGo},
+	}, candidates)
+
+	// Privacy mode only uses the website itself
+	require.NoError(t, settings.SetBoolSetting(SettingKeyLogoPrivacyMode, true))
+	candidates, err = logos.Candidates(context.Background(), server.URL)
+	require.NoError(t, err)
+	require.Len(t, candidates, 3)
+	for _, candidate := range candidates {
+		assert.NotEqual(t, LogoSourceGoogle, candidate.Source)
+		assert.NotEqual(t, LogoSourceDuckDuckGo, candidate.Source)
+	}
+
+	_, err = logos.Candidates(context.Background(), "")
+	assert.Error(t, err)
+}
+
+func TestLogoService_FetchLogoFromURL(t *testing.T) {
+	t.Run("Prefers the apple-touch-icon", func(t *testing.T) {
+		server := fakeWebsite(t, `<link rel="icon" href="/icon.png"><link rel="apple-touch-icon" href="/static/touch.png">`)
+		logos, _ := setupLogoService(t, server)
+
+		logoURL, err := logos.FetchLogoFromURL(context.Background(), server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/static/touch.png", logoURL)
+	})
+
+	t.Run("Skips icons that do not load", func(t *testing.T) {
+		server := fakeWebsite(t, `<link rel="apple-touch-icon" href="/missing.png"><link rel="icon" href="/icon.png">`)
+		logos, _ := setupLogoService(t, server)
+
+		logoURL, err := logos.FetchLogoFromURL(context.Background(), server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/icon.png", logoURL)
+	})
+
+	t.Run("Falls back to the favicon services", func(t *testing.T) {
+		server := fakeWebsite(t, `<html></html>`)
+		logos, settings := setupLogoService(t, server)
+
+		logoURL, err := logos.FetchLogoFromURL(context.Background(), server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/s2/favicons?domain=127.0.0.1", logoURL)
+
+		require.NoError(t, settings.SetBoolSetting(SettingKeyLogoPrivacyMode, true))
+		_, err = logos.FetchLogoFromURL(context.Background(), server.URL)
+		assert.Error(t, err)
+	})
+}
+
+func TestLogoService_FetchPolicy(t *testing.T) {
+	t.Run("Blocks internal addresses", func(t *testing.T) {
+		server := fakeWebsite(t, `<link rel="icon" href="/icon.png">`)
+		db := setupRenewalReminderTestDB(t)
+		settings := NewSettingsService(repository.NewSettingsRepository(db))
+		require.NoError(t, settings.SetBoolSetting(SettingKeyLogoPrivacyMode, true))
+		logos := NewLogoService(settings, LogoFetchPolicy{})
+
+		_, err := logos.FetchLogoFromURL(context.Background(), server.URL)
+		assert.Error(t, err)
+		_, err = logos.DownloadLogo(context.Background(), server.URL+"/icon.png")
+		assert.ErrorIs(t, err, ErrBlockedAddress)
+	})
+
+	t.Run("Rejects schemes that are not allowed", func(t *testing.T) {
+		server := fakeWebsite(t, `<html></html>`)
+		db := setupRenewalReminderTestDB(t)
+		settings := NewSettingsService(repository.NewSettingsRepository(db))
+		logos := NewLogoService(settings, LogoFetchPolicy{AllowedSchemes: []string{"https"}, AllowPrivateNetworks: true})
+
+		_, err := logos.DownloadLogo(context.Background(), server.URL+"/icon.png")
+		assert.ErrorContains(t, err, "scheme")
+		_, err = logos.DownloadLogo(context.Background(), "file:///etc/passwd")
+		assert.ErrorContains(t, err, "scheme")
+	})
+
+	t.Run("Limits redirects and size", func(t *testing.T) {
+		server := fakeWebsite(t, `<html></html>`)
+		logos, _ := setupLogoService(t, server)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		})
+		mux.HandleFunc("/large.png", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(append(pngHeader, make([]byte, maxLogoSize)...))
+		})
+		mux.HandleFunc("/page.png", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<html><body>not a logo</body></html>"))
+		})
+		other := httptest.NewServer(mux)
+		t.Cleanup(other.Close)
+
+		_, err := logos.DownloadLogo(context.Background(), other.URL+"/loop")
+		assert.ErrorContains(t, err, "redirects")
+		_, err = logos.DownloadLogo(context.Background(), other.URL+"/large.png")
+		assert.ErrorContains(t, err, "larger")
+		assert.False(t, logos.isImage(context.Background(), other.URL+"/large.png"))
+		_, err = logos.DownloadLogo(context.Background(), other.URL+"/page.png")
+		assert.ErrorContains(t, err, "not an image")
+
+		data, err := logos.DownloadLogo(context.Background(), server.URL+"/icon.png")
+		require.NoError(t, err)
+		assert.Equal(t, pngHeader, data)
+	})
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":      true,
+		"2606:2800:220:1::":  true,
+		"127.0.0.1":          false,
+		"10.1.2.3":           false,
+		"172.16.0.1":         false,
+		"192.168.1.1":        false,
+		"169.254.169.254":    false,
+		"100.64.0.1":         false,
+		"0.0.0.0":            false,
+		"::1":                false,
+		"::ffff:127.0.0.1":   false,
+		"fe80::1":            false,
+		"fd00::1":            false,
+		"64:ff9b::a9fe:a9fe": false,
+		"255.255.255.255":    false,
+		"ff02::1":            false,
+	} {
+		assert.Equal(t, public, isPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// settingKeyMonthlyReportSent stores the month of the last monthly report as YYYY-MM
+const settingKeyMonthlyReportSent = "monthly_report_sent"
+
+// MonthlyReport is the CSV export emailed at the start of a month
+type MonthlyReport struct {
+	Month         time.Time // First day of the month the report is for
+	Subscriptions int
+	Filename      string
+	CSV           []byte
+}
+
+// monthlyReportMailer delivers a monthly report
+type monthlyReportMailer interface {
+	SendMonthlyReport(report *MonthlyReport) error
+}
+
+// MonthlyReportService emails the CSV export of all subscriptions as an
+// attachment once a month. Attachments cannot wait in the notification queue,
+// so while the email delivery window is closed the report is held back until
+// a later run.
+type MonthlyReportService struct {
+	export        *ExportService
+	subscriptions SubscriptionServiceInterface
+	mailer        monthlyReportMailer
+	notifConfig   NotificationConfigServiceInterface
+	settings      *SettingsService
+}
+
+func NewMonthlyReportService(export *ExportService, subscriptions SubscriptionServiceInterface, mailer monthlyReportMailer, notifConfig NotificationConfigServiceInterface, settings *SettingsService) *MonthlyReportService {
+	return &MonthlyReportService{
+		export:        export,
+		subscriptions: subscriptions,
+		mailer:        mailer,
+		notifConfig:   notifConfig,
+		settings:      settings,
+	}
+}
+
+// Send emails the report for the month of now unless it was already sent. It
+// returns the report sent, or nil when none was due or email is not set up. A
+// failed delivery is retried on the next run.
+func (s *MonthlyReportService) Send(ctx context.Context, now time.Time) (*MonthlyReport, error) {
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if sent, _ := s.settings.GetCached(settingKeyMonthlyReportSent); sent == month.Format("2006-01") {
+		return nil, nil
+	}
+	if !s.notifConfig.DeliveryAllowed(models.ChannelEmail, now) {
+		return nil, nil
+	}
+
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := s.export.WriteCSV(&buf, subscriptions, nil); err != nil {
+		return nil, err
+	}
+
+	report := &MonthlyReport{
+		Month:         month,
+		Subscriptions: len(subscriptions),
+		Filename:      "subscriptions-" + month.Format("2006-01") + ".csv",
+		CSV:           buf.Bytes(),
+	}
+	if err := s.mailer.SendMonthlyReport(report); err != nil {
+		// Without SMTP there is nobody to send the report to
+		if errors.Is(err, ErrChannelNotConfigured) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer s.settings.InvalidateCache()
+	if err := s.settings.Repo().Set(settingKeyMonthlyReportSent, month.Format("2006-01")); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// NextReport returns when the next report is due: now if this month's report
+// was not sent yet, otherwise the first of next month
+func (s *MonthlyReportService) NextReport(now time.Time) time.Time {
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if sent, _ := s.settings.GetCached(settingKeyMonthlyReportSent); sent == month.Format("2006-01") {
+		return month.AddDate(0, 1, 0)
+	}
+	return now
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReportMailer records the monthly reports instead of emailing them
+type fakeReportMailer struct {
+	reports []*MonthlyReport
+	err     error
+}
+
+func (m *fakeReportMailer) SendMonthlyReport(report *MonthlyReport) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.reports = append(m.reports, report)
+	return nil
+}
+
+func TestMonthlyReportService_Send(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.UsageEvent{}, &models.SubscriptionShare{}, &models.ReminderRetry{}, &models.Payment{}))
+	settingsRepo := repository.NewSettingsRepository(db)
+	services := newTestSubscriptionService(t, db)
+	notifConfig := NewNotificationConfigService(services.settings, settingsRepo)
+	for _, name := range []string{"Netflix", "Spotify"} {
+		_, err := services.subscriptions.Create(t.Context(), &models.Subscription{Name: name, Cost: 10, Schedule: "Monthly", Status: "Active"})
+		require.NoError(t, err)
+	}
+	require.NoError(t, services.preferences.SetCSVColumns([]string{"name", "cost"}))
+
+	mailer := &fakeReportMailer{}
+	reports := NewMonthlyReportService(NewExportService(services.subscriptions, services.preferences), services.subscriptions, mailer, notifConfig, services.settings)
+	first := time.Date(2026, time.November, 1, 9, 0, 0, 0, time.Local)
+
+	// Without SMTP nothing is sent, a failed delivery is retried on the next run
+	mailer.err = fmt.Errorf("%w: no recipient email configured", ErrChannelNotConfigured)
+	report, err := reports.Send(t.Context(), first)
+	require.NoError(t, err)
+	assert.Nil(t, report)
+	mailer.err = errors.New("smtp down")
+	_, err = reports.Send(t.Context(), first)
+	require.Error(t, err)
+	mailer.err = nil
+
+	report, err = reports.Send(t.Context(), first.Add(time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, 2, report.Subscriptions)
+	assert.Equal(t, "subscriptions-2026-11.csv", report.Filename)
+	assert.True(t, strings.HasPrefix(string(report.CSV), "Name,Cost\n"), "the saved columns apply")
+	assert.Contains(t, string(report.CSV), "Netflix,10.00\n")
+
+	// Once a month
+	report, err = reports.Send(t.Context(), first.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Nil(t, report)
+
+	// A closed delivery window holds the next report back
+	next := first.AddDate(0, 1, 0)
+	closed := models.DeliveryWindow{
+		Enabled: true,
+		Start:   next.Add(2 * time.Hour).Format("15:04"),
+		End:     next.Add(3 * time.Hour).Format("15:04"),
+	}
+	require.NoError(t, notifConfig.SaveDeliveryWindows(&models.DeliveryWindows{Email: closed}))
+	report, err = reports.Send(t.Context(), next)
+	require.NoError(t, err)
+	assert.Nil(t, report)
+
+	report, err = reports.Send(t.Context(), next.Add(150*time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, "subscriptions-2026-12.csv", report.Filename)
+	assert.Len(t, mailer.reports, 2)
+}
@@ -1,16 +1,30 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"subvault/internal/models"
 	"subvault/internal/repository"
+	"sync"
+	"time"
 )
 
+// maxQueuedNotificationAttempts is how often a deferred notification is retried
+// before it is dropped
+const maxQueuedNotificationAttempts = 5
+
+// ErrChannelNotConfigured is returned when sending through a notification
+// channel that has not been set up
+var ErrChannelNotConfigured = errors.New("notification channel not configured")
+
 type NotificationConfigService struct {
 	settings *SettingsService
 	repo     *repository.SettingsRepository
+	queueMu  sync.Mutex
 }
 
 func NewNotificationConfigService(settings *SettingsService, repo *repository.SettingsRepository) *NotificationConfigService {
@@ -115,3 +129,173 @@ func (n *NotificationConfigService) MigratePushoverToShoutrrr() error {
 
 	return nil
 }
+
+// SaveDeliveryWindows validates and saves the per-channel delivery windows
+func (n *NotificationConfigService) SaveDeliveryWindows(windows *models.DeliveryWindows) error {
+	if err := windows.Email.Validate(); err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	if err := windows.Shoutrrr.Validate(); err != nil {
+		return fmt.Errorf("shoutrrr: %w", err)
+	}
+	data, err := json.Marshal(windows)
+	if err != nil {
+		return err
+	}
+
+	defer n.settings.InvalidateCache()
+	return n.repo.Set(SettingKeyDeliveryWindows, string(data))
+}
+
+// GetDeliveryWindows returns the per-channel delivery windows. Channels
+// without a configured window deliver at any time.
+func (n *NotificationConfigService) GetDeliveryWindows() *models.DeliveryWindows {
+	windows := &models.DeliveryWindows{}
+	data, ok := n.settings.GetCached(SettingKeyDeliveryWindows)
+	if !ok {
+		return windows
+	}
+	if err := json.Unmarshal([]byte(data), windows); err != nil {
+		slog.Warn("invalid delivery windows setting", "error", err)
+		return &models.DeliveryWindows{}
+	}
+	return windows
+}
+
+// DeliveryAllowed reports whether a channel may deliver notifications at t
+func (n *NotificationConfigService) DeliveryAllowed(channel string, t time.Time) bool {
+	windows := n.GetDeliveryWindows()
+	switch channel {
+	case models.ChannelEmail:
+		return windows.Email.Allows(t)
+	case models.ChannelShoutrrr:
+		return windows.Shoutrrr.Allows(t)
+	}
+	return true
+}
+
+// SaveNotificationLanguages saves the per-channel notification languages.
+// Callers validate them against the supported languages.
+func (n *NotificationConfigService) SaveNotificationLanguages(languages *models.NotificationLanguages) error {
+	data, err := json.Marshal(languages)
+	if err != nil {
+		return err
+	}
+
+	defer n.settings.InvalidateCache()
+	return n.repo.Set(SettingKeyChannelLanguages, string(data))
+}
+
+// GetNotificationLanguages returns the per-channel notification languages
+func (n *NotificationConfigService) GetNotificationLanguages() *models.NotificationLanguages {
+	languages := &models.NotificationLanguages{}
+	data, ok := n.settings.GetCached(SettingKeyChannelLanguages)
+	if !ok {
+		return languages
+	}
+	if err := json.Unmarshal([]byte(data), languages); err != nil {
+		slog.Warn("invalid notification languages setting", "error", err)
+		return &models.NotificationLanguages{}
+	}
+	return languages
+}
+
+// ChannelLanguage returns the language a channel's notifications are written
+// in, or an empty string when the channel follows the app language
+func (n *NotificationConfigService) ChannelLanguage(channel string) string {
+	return n.GetNotificationLanguages().For(channel)
+}
+
+// QueueNotification stores a notification until its channel's delivery window opens
+func (n *NotificationConfigService) QueueNotification(channel, title, body string) error {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+
+	n.queueMu.Lock()
+	defer n.queueMu.Unlock()
+	queue := n.loadQueue()
+	queue = append(queue, models.QueuedNotification{
+		ID:       hex.EncodeToString(b),
+		Channel:  channel,
+		Title:    title,
+		Body:     body,
+		QueuedAt: time.Now(),
+	})
+	slog.Info("notification deferred until delivery window opens", "channel", channel, "title", title)
+	return n.saveQueue(queue)
+}
+
+// QueuedNotifications returns all notifications waiting for their delivery window
+func (n *NotificationConfigService) QueuedNotifications() []models.QueuedNotification {
+	n.queueMu.Lock()
+	defer n.queueMu.Unlock()
+	return n.loadQueue()
+}
+
+// FlushQueue delivers the queued notifications of a channel with send if the
+// channel's window is open at now. Failed notifications stay queued and are
+// dropped after maxQueuedNotificationAttempts. It returns the number sent.
+func (n *NotificationConfigService) FlushQueue(channel string, now time.Time, send func(title, body string) error) (int, error) {
+	if !n.DeliveryAllowed(channel, now) {
+		return 0, nil
+	}
+
+	n.queueMu.Lock()
+	defer n.queueMu.Unlock()
+	queue := n.loadQueue()
+	remaining := queue[:0]
+	sent := 0
+	var errs []error
+	for _, item := range queue {
+		if item.Channel != channel {
+			remaining = append(remaining, item)
+			continue
+		}
+		if err := send(item.Title, item.Body); err != nil {
+			item.Attempts++
+			if item.Attempts >= maxQueuedNotificationAttempts {
+				slog.Warn("dropping deferred notification after repeated failures", "channel", channel, "title", item.Title, "error", err)
+			} else {
+				remaining = append(remaining, item)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		sent++
+	}
+	if len(remaining) == len(queue) && len(errs) == 0 {
+		return 0, nil
+	}
+	if err := n.saveQueue(remaining); err != nil {
+		return sent, err
+	}
+	if sent > 0 {
+		slog.Info("delivered deferred notifications", "channel", channel, "count", sent)
+	}
+	return sent, errors.Join(errs...)
+}
+
+// loadQueue reads the notification queue; callers must hold queueMu
+func (n *NotificationConfigService) loadQueue() []models.QueuedNotification {
+	data, err := n.repo.Get(SettingKeyNotificationQueue)
+	if err != nil || data == "" {
+		return nil
+	}
+	var queue []models.QueuedNotification
+	if err := json.Unmarshal([]byte(data), &queue); err != nil {
+		slog.Warn("discarding unreadable notification queue", "error", err)
+		return nil
+	}
+	return queue
+}
+
+// saveQueue writes the notification queue; callers must hold queueMu
+func (n *NotificationConfigService) saveQueue(queue []models.QueuedNotification) error {
+	data, err := json.Marshal(queue)
+	if err != nil {
+		return err
+	}
+	return n.repo.Set(SettingKeyNotificationQueue, string(data))
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"subvault/internal/i18n"
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryWindow_Allows(t *testing.T) {
+	at := func(clock string) time.Time {
+		parsed, err := time.Parse("15:04", clock)
+		require.NoError(t, err)
+		return time.Date(2026, 3, 1, parsed.Hour(), parsed.Minute(), 0, 0, time.Local)
+	}
+
+	day := models.DeliveryWindow{Enabled: true, Start: "08:00", End: "22:00"}
+	assert.False(t, day.Allows(at("07:59")))
+	assert.True(t, day.Allows(at("08:00")))
+	assert.True(t, day.Allows(at("21:59")))
+	assert.False(t, day.Allows(at("22:00")))
+
+	night := models.DeliveryWindow{Enabled: true, Start: "22:00", End: "06:00"}
+	assert.True(t, night.Allows(at("23:30")))
+	assert.True(t, night.Allows(at("05:59")))
+	assert.False(t, night.Allows(at("12:00")))
+
+	day.Enabled = false
+	assert.True(t, day.Allows(at("03:00")))
+
+	assert.Error(t, models.DeliveryWindow{Enabled: true, Start: "8am", End: "22:00"}.Validate())
+	assert.Error(t, models.DeliveryWindow{Enabled: true, Start: "08:00", End: "08:00"}.Validate())
+}
+
+func TestNotificationConfigService_DeferredShoutrrr(t *testing.T) {
+	_, _, notifConfig, shoutrrrService := setupShoutrrrServices(t)
+	require.NoError(t, notifConfig.SaveShoutrrrConfig(&models.ShoutrrrConfig{URLs: []string{"invalid://url"}}))
+
+	// A window that excludes the current time queues instead of sending
+	now := time.Now()
+	closed := models.DeliveryWindow{
+		Enabled: true,
+		Start:   now.Add(2 * time.Hour).Format("15:04"),
+		End:     now.Add(3 * time.Hour).Format("15:04"),
+	}
+	require.NoError(t, notifConfig.SaveDeliveryWindows(&models.DeliveryWindows{Shoutrrr: closed}))
+	require.NoError(t, shoutrrrService.SendBudgetExceededAlert(models.BudgetPeriodMonthly, 120, 100, "€"))
+	queued := notifConfig.QueuedNotifications()
+	require.Len(t, queued, 1)
+	assert.Equal(t, models.ChannelShoutrrr, queued[0].Channel)
+
+	// Emails have no window and are unaffected
+	assert.True(t, notifConfig.DeliveryAllowed(models.ChannelEmail, now))
+
+	// Nothing is flushed while the window is closed
+	var delivered []string
+	send := func(title, body string) error {
+		delivered = append(delivered, title)
+		return nil
+	}
+	sent, err := notifConfig.FlushQueue(models.ChannelShoutrrr, now, send)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	// Failed deliveries stay queued until the attempt limit
+	fail := func(title, body string) error { return errors.New("gateway down") }
+	opens := now.Add(2*time.Hour + time.Minute)
+	_, err = notifConfig.FlushQueue(models.ChannelShoutrrr, opens, fail)
+	assert.Error(t, err)
+	require.Len(t, notifConfig.QueuedNotifications(), 1)
+	assert.Equal(t, 1, notifConfig.QueuedNotifications()[0].Attempts)
+
+	sent, err = notifConfig.FlushQueue(models.ChannelShoutrrr, opens, send)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []string{queued[0].Title}, delivered)
+	assert.Empty(t, notifConfig.QueuedNotifications())
+}
+
+func TestNotificationConfigService_ChannelLanguages(t *testing.T) {
+	_, preferences, notifConfig, _ := setupShoutrrrServices(t)
+	i18nService := i18n.NewI18nService("")
+	require.NoError(t, preferences.SetLanguage("de"))
+	require.NoError(t, notifConfig.SaveShoutrrrConfig(&models.ShoutrrrConfig{URLs: []string{"invalid://url"}}))
+	require.NoError(t, notifConfig.SaveSMTPConfig(&models.SMTPConfig{Host: "smtp.example.com", Port: 587, To: "me@example.com"}))
+
+	assert.Error(t, models.NotificationLanguages{Shoutrrr: "xx"}.Validate(i18nService.SupportedLanguages()))
+	languages := &models.NotificationLanguages{Shoutrrr: "en"}
+	require.NoError(t, languages.Validate(i18nService.SupportedLanguages()))
+	require.NoError(t, notifConfig.SaveNotificationLanguages(languages))
+	assert.Equal(t, "en", notifConfig.ChannelLanguage(models.ChannelShoutrrr))
+	assert.Empty(t, notifConfig.ChannelLanguage(models.ChannelEmail), "email follows the app language")
+
+	// Queue both channels to inspect the rendered text
+	now := time.Now()
+	closed := models.DeliveryWindow{
+		Enabled: true,
+		Start:   now.Add(2 * time.Hour).Format("15:04"),
+		End:     now.Add(3 * time.Hour).Format("15:04"),
+	}
+	require.NoError(t, notifConfig.SaveDeliveryWindows(&models.DeliveryWindows{Email: closed, Shoutrrr: closed}))
+	require.NoError(t, NewShoutrrrService(preferences, notifConfig, i18nService).SendBudgetExceededAlert(models.BudgetPeriodMonthly, 120, 100, "€"))
+	require.NoError(t, NewEmailService(preferences, notifConfig, i18nService).SendBudgetExceededAlert(models.BudgetPeriodMonthly, 120, 100, "€"))
+
+	queued := notifConfig.QueuedNotifications()
+	require.Len(t, queued, 2)
+	assert.Contains(t, queued[0].Body, "Your monthly subscription spending has exceeded your budget.")
+	assert.Contains(t, queued[1].Body, "Deine monatlichen Abo-Ausgaben haben dein Budget überschritten.")
+}
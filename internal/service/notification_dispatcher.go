@@ -0,0 +1,158 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// NotificationDispatcher sends notifications through every registered
+// channel, so callers do not need to know which channels exist. Email and
+// Shoutrrr are registered at startup; a new channel implements Notifier and
+// is registered next to them.
+type NotificationDispatcher struct {
+	mu        sync.RWMutex
+	notifiers []Notifier
+}
+
+func NewNotificationDispatcher(notifiers ...Notifier) *NotificationDispatcher {
+	return &NotificationDispatcher{notifiers: notifiers}
+}
+
+// Register adds a notification channel
+func (d *NotificationDispatcher) Register(notifier Notifier) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notifiers = append(d.notifiers, notifier)
+}
+
+// Notifiers returns the registered channels in registration order
+func (d *NotificationDispatcher) Notifiers() []Notifier {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return append([]Notifier(nil), d.notifiers...)
+}
+
+// Senders returns a send function per registered channel, keyed by channel
+// name, for callers that track delivery per channel
+func (d *NotificationDispatcher) Senders(send func(Notifier) error) map[string]func() error {
+	senders := make(map[string]func() error)
+	for _, notifier := range d.Notifiers() {
+		senders[notifier.Channel()] = func() error { return send(notifier) }
+	}
+	return senders
+}
+
+// broadcast sends through every channel. It succeeds when any channel
+// delivered, returns ErrChannelNotConfigured when no channel is set up and
+// otherwise the errors of the failed channels.
+func (d *NotificationDispatcher) broadcast(send func(Notifier) error) error {
+	delivered := false
+	var failures []error
+	for _, notifier := range d.Notifiers() {
+		err := send(notifier)
+		switch {
+		case err == nil:
+			delivered = true
+		case errors.Is(err, ErrChannelNotConfigured):
+		default:
+			failures = append(failures, fmt.Errorf("%s: %w", notifier.Channel(), err))
+		}
+	}
+	switch {
+	case delivered:
+		return nil
+	case len(failures) > 0:
+		return errors.Join(failures...)
+	}
+	return ErrChannelNotConfigured
+}
+
+// SendHighCostAlert sends the high-cost alert through the channels selected
+// for the subscription. Failed channels are logged.
+func (d *NotificationDispatcher) SendHighCostAlert(subscription *models.Subscription) error {
+	var failures []error
+	for _, notifier := range d.Notifiers() {
+		if !subscription.NotifiesVia(notifier.Channel()) {
+			continue
+		}
+		if err := notifier.SendHighCostAlert(subscription); err != nil && !errors.Is(err, ErrChannelNotConfigured) {
+			slog.Error("failed to send high-cost alert", "channel", notifier.Channel(), "error", err)
+			failures = append(failures, fmt.Errorf("%s: %w", notifier.Channel(), err))
+		}
+	}
+	return errors.Join(failures...)
+}
+
+// SendBudgetExceededAlert notifies that the spend of period exceeds its budget
+func (d *NotificationDispatcher) SendBudgetExceededAlert(period string, totalSpend, budget float64, currencySymbol string) error {
+	return d.broadcast(func(n Notifier) error { return n.SendBudgetExceededAlert(period, totalSpend, budget, currencySymbol) })
+}
+
+// SendUnusedSubscriptionsNudge sends the monthly unused subscription summary
+func (d *NotificationDispatcher) SendUnusedSubscriptionsNudge(nudge *UnusedNudge) error {
+	return d.broadcast(func(n Notifier) error { return n.SendUnusedSubscriptionsNudge(nudge) })
+}
+
+// SendExchangeRateAlert sends the currencies that moved beyond the alert threshold
+func (d *NotificationDispatcher) SendExchangeRateAlert(alert *RateAlert) error {
+	return d.broadcast(func(n Notifier) error { return n.SendExchangeRateAlert(alert) })
+}
+
+// SendSettlementReport sends the monthly shared expense settlement
+func (d *NotificationDispatcher) SendSettlementReport(report *SettlementReport) error {
+	return d.broadcast(func(n Notifier) error { return n.SendSettlementReport(report) })
+}
+
+// SendRenewalConfirmations asks to confirm the charges of passed renewals
+func (d *NotificationDispatcher) SendRenewalConfirmations(payments []models.Payment) error {
+	return d.broadcast(func(n Notifier) error { return n.SendRenewalConfirmations(payments) })
+}
+
+// SendLoginAlert notifies of a login from a new device
+func (d *NotificationDispatcher) SendLoginAlert(event *models.LoginEvent) error {
+	return d.broadcast(func(n Notifier) error { return n.SendLoginAlert(event) })
+}
+
+// SendWeeklySummary sends the weekly summary of changes and renewals
+func (d *NotificationDispatcher) SendWeeklySummary(summary *WeeklySummary) error {
+	return d.broadcast(func(n Notifier) error { return n.SendWeeklySummary(summary) })
+}
+
+// FlushQueued delivers the notifications queued outside each channel's
+// delivery window, returning how many were sent
+func (d *NotificationDispatcher) FlushQueued(now time.Time) (int, error) {
+	total := 0
+	var errs []error
+	for _, notifier := range d.Notifiers() {
+		sent, err := notifier.FlushQueued(now)
+		total += sent
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.Channel(), err))
+		}
+	}
+	return total, errors.Join(errs...)
+}
+
+// SendReminder sends a reminder of the given kind through one channel
+func SendReminder(notifier Notifier, kind string, subscription *models.Subscription, daysUntil int) error {
+	switch kind {
+	case models.ReminderKindRenewal:
+		return notifier.SendRenewalReminder(subscription, daysUntil)
+	case models.ReminderKindCancellation:
+		return notifier.SendCancellationReminder(subscription, daysUntil)
+	case models.ReminderKindGracePeriod:
+		return notifier.SendGracePeriodReminder(subscription, daysUntil)
+	case models.ReminderKindContract:
+		return notifier.SendContractReminder(subscription, daysUntil)
+	case models.ReminderKindPaidThrough:
+		return notifier.SendPaidThroughReminder(subscription, daysUntil)
+	case models.ReminderKindPromoEnd:
+		return notifier.SendPromoEndReminder(subscription, daysUntil)
+	}
+	return fmt.Errorf("unknown reminder kind %q", kind)
+}
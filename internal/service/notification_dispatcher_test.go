@@ -0,0 +1,116 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNotifier records the notifications sent through it and returns err
+type fakeNotifier struct {
+	channel string
+	err     error
+	sent    []string
+}
+
+func (f *fakeNotifier) send(what string) error {
+	f.sent = append(f.sent, what)
+	return f.err
+}
+
+func (f *fakeNotifier) Channel() string { return f.channel }
+func (f *fakeNotifier) SendHighCostAlert(*models.Subscription) error {
+	return f.send("high_cost")
+}
+func (f *fakeNotifier) SendRenewalReminder(*models.Subscription, int) error {
+	return f.send("renewal")
+}
+func (f *fakeNotifier) SendCancellationReminder(*models.Subscription, int) error {
+	return f.send("cancellation")
+}
+func (f *fakeNotifier) SendGracePeriodReminder(*models.Subscription, int) error {
+	return f.send("grace_period")
+}
+func (f *fakeNotifier) SendContractReminder(*models.Subscription, int) error {
+	return f.send("contract")
+}
+func (f *fakeNotifier) SendPaidThroughReminder(*models.Subscription, int) error {
+	return f.send("paid_through")
+}
+func (f *fakeNotifier) SendPromoEndReminder(*models.Subscription, int) error {
+	return f.send("promo_end")
+}
+func (f *fakeNotifier) SendBudgetExceededAlert(string, float64, float64, string) error {
+	return f.send("budget")
+}
+func (f *fakeNotifier) SendUnusedSubscriptionsNudge(*UnusedNudge) error {
+	return f.send("unused")
+}
+func (f *fakeNotifier) SendExchangeRateAlert(*RateAlert) error {
+	return f.send("rate_alert")
+}
+func (f *fakeNotifier) SendSettlementReport(*SettlementReport) error {
+	return f.send("settlement")
+}
+func (f *fakeNotifier) SendRenewalConfirmations([]models.Payment) error {
+	return f.send("renewal_confirmations")
+}
+func (f *fakeNotifier) SendLoginAlert(*models.LoginEvent) error {
+	return f.send("login_alert")
+}
+func (f *fakeNotifier) SendWeeklySummary(*WeeklySummary) error {
+	return f.send("weekly_summary")
+}
+func (f *fakeNotifier) FlushQueued(time.Time) (int, error) {
+	return len(f.sent), f.err
+}
+
+func TestNotificationDispatcher_Broadcast(t *testing.T) {
+	email := &fakeNotifier{channel: models.ChannelEmail, err: ErrChannelNotConfigured}
+	push := &fakeNotifier{channel: models.ChannelShoutrrr}
+	dispatcher := NewNotificationDispatcher(email, push)
+
+	// One delivered channel is enough
+	require.NoError(t, dispatcher.SendUnusedSubscriptionsNudge(&UnusedNudge{}))
+	assert.Equal(t, []string{"unused"}, email.sent)
+	assert.Equal(t, []string{"unused"}, push.sent)
+
+	// Nothing set up
+	push.err = ErrChannelNotConfigured
+	assert.ErrorIs(t, dispatcher.SendRenewalConfirmations(nil), ErrChannelNotConfigured)
+
+	// Real failures are reported per channel, not as unconfigured
+	push.err = errors.New("connection refused")
+	err := dispatcher.SendExchangeRateAlert(&RateAlert{})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrChannelNotConfigured)
+	assert.Contains(t, err.Error(), "shoutrrr: connection refused")
+
+	// A registered channel receives later notifications
+	extra := &fakeNotifier{channel: "telegram"}
+	dispatcher.Register(extra)
+	require.NoError(t, dispatcher.SendSettlementReport(&SettlementReport{}))
+	assert.Equal(t, []string{"settlement"}, extra.sent)
+}
+
+func TestNotificationDispatcher_SubscriptionChannels(t *testing.T) {
+	email := &fakeNotifier{channel: models.ChannelEmail}
+	push := &fakeNotifier{channel: models.ChannelShoutrrr}
+	dispatcher := NewNotificationDispatcher(email, push)
+	sub := &models.Subscription{Name: "Netflix", NotifyChannels: models.ChannelShoutrrr}
+
+	require.NoError(t, dispatcher.SendHighCostAlert(sub))
+	assert.Empty(t, email.sent)
+	assert.Equal(t, []string{"high_cost"}, push.sent)
+
+	senders := dispatcher.Senders(func(n Notifier) error { return SendReminder(n, models.ReminderKindContract, sub, 3) })
+	require.Len(t, senders, 2)
+	require.NoError(t, senders[models.ChannelEmail]())
+	assert.Equal(t, []string{"contract"}, email.sent)
+	assert.Error(t, SendReminder(email, "unknown", sub, 3))
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// NotificationPreviewDays is how far ahead the notification preview looks
+const NotificationPreviewDays = 30
+
+// Kinds of previewed notifications besides the reminder kinds
+const (
+	NotificationPreviewTrialEnd      = "trial_end"
+	NotificationPreviewWeeklySummary = NotificationTypeWeeklySummary
+	NotificationPreviewMonthlyReport = "monthly_report"
+)
+
+// NotificationPreviewEntry is a notification expected on a day. Trial ends
+// send no reminder and are listed so they are not missed.
+type NotificationPreviewEntry struct {
+	Date           time.Time           `json:"date"`
+	Kind           string              `json:"kind"`
+	SubscriptionID uint                `json:"subscription_id,omitempty"`
+	Name           string              `json:"name,omitempty"`
+	DueDate        *time.Time          `json:"due_date,omitempty"`
+	Channels       []SimulatedDelivery `json:"channels"`
+}
+
+// NotificationPreview lists the notifications expected from From until
+// before Until, in order
+type NotificationPreview struct {
+	From    time.Time                  `json:"from"`
+	Until   time.Time                  `json:"until"`
+	Entries []NotificationPreviewEntry `json:"entries"`
+}
+
+// NotificationPreviewService previews the reminders, trial ends and
+// summaries of the coming days from the current data
+type NotificationPreviewService struct {
+	simulation    *ReminderSimulationService
+	subscriptions *SubscriptionService
+	weeklySummary *WeeklySummaryService
+	monthlyReport *MonthlyReportService
+	settings      *SettingsService
+}
+
+func NewNotificationPreviewService(simulation *ReminderSimulationService, subscriptions *SubscriptionService, weeklySummary *WeeklySummaryService, monthlyReport *MonthlyReportService, settings *SettingsService) *NotificationPreviewService {
+	return &NotificationPreviewService{simulation: simulation, subscriptions: subscriptions, weeklySummary: weeklySummary, monthlyReport: monthlyReport, settings: settings}
+}
+
+// Preview returns the notifications expected on the days from now. Each
+// reminder is listed on the first day the reminder jobs would select it, which
+// is when it is sent; like the dry run it takes dates as stored.
+func (s *NotificationPreviewService) Preview(ctx context.Context, now time.Time, days int) (*NotificationPreview, error) {
+	today := dayOf(now)
+	preview := &NotificationPreview{From: today, Until: today.AddDate(0, 0, days), Entries: []NotificationPreviewEntry{}}
+
+	seen := map[string]bool{}
+	for day := 0; day < days; day++ {
+		at := now.AddDate(0, 0, day)
+		simulation, err := s.simulation.Simulate(ctx, at)
+		if err != nil {
+			return nil, err
+		}
+		for _, reminder := range simulation.Reminders {
+			key := fmt.Sprintf("%s|%d|%s", reminder.Kind, reminder.SubscriptionID, reminder.DueDate.Format(time.DateOnly))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			dueDate := reminder.DueDate
+			preview.Entries = append(preview.Entries, NotificationPreviewEntry{
+				Date:           dayOf(at),
+				Kind:           reminder.Kind,
+				SubscriptionID: reminder.SubscriptionID,
+				Name:           reminder.Name,
+				DueDate:        &dueDate,
+				Channels:       reminder.Channels,
+			})
+		}
+	}
+
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subscriptions {
+		if sub.Status != models.StatusTrial || sub.RenewalDate == nil {
+			continue
+		}
+		if end := dayOf(*sub.RenewalDate); !end.Before(today) && end.Before(preview.Until) {
+			dueDate := *sub.RenewalDate
+			preview.Entries = append(preview.Entries, NotificationPreviewEntry{
+				Date: end, Kind: NotificationPreviewTrialEnd, SubscriptionID: sub.ID, Name: sub.Name, DueDate: &dueDate, Channels: []SimulatedDelivery{},
+			})
+		}
+	}
+
+	if s.settings.GetBoolSettingWithDefault("weekly_summary", false) {
+		channels := s.simulation.channels(false)
+		for next := s.weeklySummary.NextSummary(now); next.Before(preview.Until); next = next.Add(weeklySummaryPeriod) {
+			preview.Entries = append(preview.Entries, NotificationPreviewEntry{
+				Date: dayOf(next), Kind: NotificationPreviewWeeklySummary, Channels: s.simulation.deliveriesVia(channels, next),
+			})
+		}
+	}
+	if s.settings.GetBoolSettingWithDefault("monthly_report", false) {
+		for next := s.monthlyReport.NextReport(now); next.Before(preview.Until); next = firstOfNextMonth(next) {
+			preview.Entries = append(preview.Entries, NotificationPreviewEntry{
+				Date: dayOf(next), Kind: NotificationPreviewMonthlyReport, Channels: s.simulation.deliveriesVia([]string{models.ChannelEmail}, next),
+			})
+		}
+	}
+
+	sort.SliceStable(preview.Entries, func(i, j int) bool {
+		a, b := preview.Entries[i], preview.Entries[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	return preview, nil
+}
+
+// dayOf returns the start of the day of t
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// firstOfNextMonth returns the first day of the month after t
+func firstOfNextMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+}
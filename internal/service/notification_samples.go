@@ -0,0 +1,182 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// Notification types sent by the test matrix
+const (
+	NotificationTypeRenewal              = "renewal"
+	NotificationTypeCancellation         = "cancellation"
+	NotificationTypeGracePeriod          = "grace_period"
+	NotificationTypeContract             = "contract"
+	NotificationTypePaidThrough          = "paid_through"
+	NotificationTypePromoEnd             = "promo_end"
+	NotificationTypeHighCost             = "high_cost"
+	NotificationTypeBudget               = "budget"
+	NotificationTypeUnusedDigest         = "unused_digest"
+	NotificationTypeRateAlert            = "rate_alert"
+	NotificationTypeSettlement           = "settlement"
+	NotificationTypeRenewalConfirmations = "renewal_confirmations"
+	NotificationTypeLoginAlert           = "login_alert"
+	NotificationTypeWeeklySummary        = "weekly_summary"
+)
+
+// Outcomes of a test notification on one channel
+const (
+	NotificationTestSent          = "sent"
+	NotificationTestQueued        = "queued"
+	NotificationTestFailed        = "failed"
+	NotificationTestNotConfigured = "not_configured"
+)
+
+// NotificationTestResult is the outcome of one sample notification on one channel
+type NotificationTestResult struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NotificationTestService sends a sample of every notification type through
+// every registered channel, so templates and channel settings can be checked
+// at once
+type NotificationTestService struct {
+	notifier    NotificationDispatcherInterface
+	notifConfig NotificationConfigServiceInterface
+	preferences PreferencesServiceInterface
+}
+
+func NewNotificationTestService(notifier NotificationDispatcherInterface, notifConfig NotificationConfigServiceInterface, preferences PreferencesServiceInterface) *NotificationTestService {
+	return &NotificationTestService{notifier: notifier, notifConfig: notifConfig, preferences: preferences}
+}
+
+// TestAll sends the samples and returns the outcome per type and channel, in
+// type order. Channels whose delivery window is closed queue the samples
+// instead of sending them.
+func (s *NotificationTestService) TestAll(now time.Time) []NotificationTestResult {
+	samples := sampleNotifications(s.preferences.GetCurrency(), s.preferences.GetCurrencySymbol(), now)
+	var results []NotificationTestResult
+	for _, sample := range samples {
+		for _, notifier := range s.notifier.Notifiers() {
+			result := NotificationTestResult{Type: sample.kind, Channel: notifier.Channel(), Status: NotificationTestSent}
+			err := sample.send(notifier)
+			switch {
+			case errors.Is(err, ErrChannelNotConfigured):
+				result.Status = NotificationTestNotConfigured
+			case err != nil:
+				result.Status = NotificationTestFailed
+				result.Error = err.Error()
+			case !s.notifConfig.DeliveryAllowed(notifier.Channel(), now):
+				result.Status = NotificationTestQueued
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+type sampleNotification struct {
+	kind string
+	send func(Notifier) error
+}
+
+// sampleNotifications returns one made-up notification of every type
+func sampleNotifications(currency, currencySymbol string, now time.Time) []sampleNotification {
+	day := func(days int) *time.Time {
+		date := now.AddDate(0, 0, days)
+		return &date
+	}
+	sub := &models.Subscription{
+		ID:                1,
+		Name:              "SubVault Test",
+		Cost:              12.99,
+		OriginalCurrency:  currency,
+		Schedule:          "Monthly",
+		Status:            "Active",
+		URL:               "https://example.com",
+		RenewalDate:       day(3),
+		CancellationDate:  day(7),
+		PaidThroughDate:   day(5),
+		PaymentFailedAt:   day(-2),
+		PaymentRetryDate:  day(1),
+		GracePeriodEnd:    day(3),
+		ContractNumber:    "TEST-123",
+		ContractStartDate: day(-335),
+		ContractEndDate:   day(30),
+		MinimumTermMonths: 12,
+		NoticePeriodDays:  27,
+		AutoRenew:         true,
+	}
+	// The promotional price is kept off the other samples so their costs stay regular
+	promo := *sub
+	promoCost := 4.99
+	promo.PromoCost = &promoCost
+	promo.PromoEndDate = day(7)
+	costPerUse := 6.50
+	foreign := "USD"
+	if currency == foreign {
+		foreign = "EUR"
+	}
+	return []sampleNotification{
+		{NotificationTypeRenewal, func(n Notifier) error { return n.SendRenewalReminder(sub, 3) }},
+		{NotificationTypeCancellation, func(n Notifier) error { return n.SendCancellationReminder(sub, 7) }},
+		{NotificationTypeGracePeriod, func(n Notifier) error { return n.SendGracePeriodReminder(sub, 3) }},
+		{NotificationTypeContract, func(n Notifier) error { return n.SendContractReminder(sub, 3) }},
+		{NotificationTypePaidThrough, func(n Notifier) error { return n.SendPaidThroughReminder(sub, 5) }},
+		{NotificationTypePromoEnd, func(n Notifier) error { return n.SendPromoEndReminder(&promo, 7) }},
+		{NotificationTypeHighCost, func(n Notifier) error { return n.SendHighCostAlert(sub) }},
+		{NotificationTypeBudget, func(n Notifier) error {
+			return n.SendBudgetExceededAlert("monthly", 112.40, 100, currencySymbol)
+		}},
+		{NotificationTypeUnusedDigest, func(n Notifier) error {
+			return n.SendUnusedSubscriptionsNudge(&UnusedNudge{
+				Subscriptions: []CostPerUse{{SubscriptionID: sub.ID, Name: sub.Name, Status: sub.Status, MonthlyCost: sub.Cost,
+					Currency: currency, Uses: 2, TotalUses: 9, CostPerUse: &costPerUse, LastUsed: day(-20), Tracked: true, Usage: "Low"}},
+				MonthlySavings: sub.Cost,
+				Currency:       currency,
+			})
+		}},
+		{NotificationTypeRateAlert, func(n Notifier) error {
+			return n.SendExchangeRateAlert(&RateAlert{
+				Currency: currency,
+				Since:    now.AddDate(0, -1, 0),
+				Changes: []RateChange{{Currency: foreign, OldRate: 1, NewRate: 1.08, ChangePercent: 8,
+					Subscriptions: []RateAlertSubscription{{ID: sub.ID, Name: sub.Name, MonthlyCost: sub.Cost, OldMonthlyCost: 12.99, NewMonthlyCost: 14.03}}}},
+			})
+		}},
+		{NotificationTypeSettlement, func(n Notifier) error {
+			return n.SendSettlementReport(&SettlementReport{
+				Month:    now.Format("2006-01"),
+				Currency: currency,
+				People:   []PersonSettlement{{Person: "Alex", Total: 6.50, Items: []SettlementItem{{SubscriptionID: sub.ID, Name: sub.Name, Amount: 6.50}}}},
+				Total:    6.50,
+			})
+		}},
+		{NotificationTypeRenewalConfirmations, func(n Notifier) error {
+			return n.SendRenewalConfirmations([]models.Payment{{SubscriptionID: sub.ID, Name: sub.Name, DueDate: *day(-1), Amount: sub.Cost, Currency: currency}})
+		}},
+		{NotificationTypeLoginAlert, func(n Notifier) error {
+			return n.SendLoginAlert(&models.LoginEvent{Username: "admin", Role: "admin", Success: true, IP: "203.0.113.7",
+				UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/130.0", NewDevice: true, CreatedAt: now})
+		}},
+		{NotificationTypeWeeklySummary, func(n Notifier) error {
+			item := WeeklySummaryItem{ID: sub.ID, Name: sub.Name, Status: sub.Status, Cost: sub.Cost, Currency: currency, Schedule: sub.Schedule}
+			return n.SendWeeklySummary(&WeeklySummary{
+				Since:         now.AddDate(0, 0, -7),
+				Until:         now,
+				Added:         []WeeklySummaryItem{item},
+				PriceChanges:  []WeeklyPriceChange{{ID: sub.ID, Name: sub.Name, OldCost: 12.99, OldCurrency: currency, OldSchedule: sub.Schedule, NewCost: sub.Cost, NewCurrency: currency, NewSchedule: sub.Schedule}},
+				Renewals:      []WeeklyRenewal{{ID: sub.ID, Name: sub.Name, Date: *day(3), Cost: sub.Cost, Currency: currency}},
+				RenewalsTotal: sub.Cost,
+				Currency:      currency,
+				MonthlySpend:  84.50,
+				MonthlyBudget: 100,
+				AnnualSpend:   1014,
+			})
+		}},
+	}
+}
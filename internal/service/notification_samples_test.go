@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationTestService_TestAll(t *testing.T) {
+	_, preferences, notifConfig, _ := setupShoutrrrServices(t)
+	email := &fakeNotifier{channel: models.ChannelEmail, err: ErrChannelNotConfigured}
+	push := &fakeNotifier{channel: models.ChannelShoutrrr}
+	tests := NewNotificationTestService(NewNotificationDispatcher(email, push), notifConfig, preferences)
+
+	// Every type is tried on every channel, in type order
+	now := time.Now()
+	results := tests.TestAll(now)
+	require.Len(t, results, 28)
+	assert.Equal(t, NotificationTestResult{Type: NotificationTypeRenewal, Channel: models.ChannelEmail, Status: NotificationTestNotConfigured}, results[0])
+	assert.Equal(t, NotificationTestResult{Type: NotificationTypeRenewal, Channel: models.ChannelShoutrrr, Status: NotificationTestSent}, results[1])
+	assert.Equal(t, NotificationTypeWeeklySummary, results[27].Type)
+	assert.Len(t, push.sent, 14)
+
+	// Failures carry the channel's error, closed windows queue
+	email.err = errors.New("auth failed")
+	closed := models.DeliveryWindow{
+		Enabled: true,
+		Start:   now.Add(2 * time.Hour).Format("15:04"),
+		End:     now.Add(3 * time.Hour).Format("15:04"),
+	}
+	require.NoError(t, notifConfig.SaveDeliveryWindows(&models.DeliveryWindows{Shoutrrr: closed}))
+	results = tests.TestAll(now)
+	assert.Equal(t, NotificationTestFailed, results[0].Status)
+	assert.Equal(t, "auth failed", results[0].Error)
+	assert.Equal(t, NotificationTestQueued, results[1].Status)
+}
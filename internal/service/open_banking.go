@@ -0,0 +1,431 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// gocardlessBaseURL is the GoCardless Bank Account Data API
+const gocardlessBaseURL = "https://bankaccountdata.gocardless.com/api/v2"
+
+// bankSyncDays is how many days of transactions each sync reads. Banks
+// provide at least 90 days, enough to spot quarterly charges.
+const bankSyncDays = 90
+
+// bankAutoConfirmConfidence is the match confidence at which a synced charge
+// confirms a renewal without review
+const bankAutoConfirmConfidence = 0.8
+
+// Requisition states reported by GoCardless
+const (
+	requisitionLinked   = "LN"
+	requisitionRejected = "RJ"
+	requisitionExpired  = "EX"
+)
+
+var (
+	// ErrBankNotConfigured is returned when the bank connection has no API credentials
+	ErrBankNotConfigured = errors.New("bank connection not configured")
+	// ErrBankNotLinked is returned when syncing before bank accounts are connected
+	ErrBankNotLinked = errors.New("no bank accounts linked")
+	// ErrBankAPI is returned when GoCardless rejects a request
+	ErrBankAPI = errors.New("bank data provider error")
+)
+
+// BankInstitution is a bank that can be connected
+type BankInstitution struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	BIC  string `json:"bic"`
+	Logo string `json:"logo"`
+}
+
+// BankSyncResult reports what a sync read and recorded
+type BankSyncResult struct {
+	Transactions int `json:"transactions"`
+	Recorded     int `json:"recorded"`
+	Skipped      int `json:"skipped"`
+	Candidates   int `json:"candidates"`
+}
+
+// OpenBankingService connects bank accounts through GoCardless Bank Account
+// Data, reads their transactions, confirms the renewals they pay and proposes
+// recurring charges that match no subscription
+type OpenBankingService struct {
+	settings    *SettingsService
+	reconcile   *ReconcileService
+	preferences PreferencesServiceInterface
+	httpClient  *http.Client
+	baseURL     string
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+func NewOpenBankingService(settings *SettingsService, reconcile *ReconcileService, preferences PreferencesServiceInterface) *OpenBankingService {
+	return &OpenBankingService{
+		settings:    settings,
+		reconcile:   reconcile,
+		preferences: preferences,
+		httpClient:  NewHTTPClient(30 * time.Second),
+		baseURL:     gocardlessBaseURL,
+	}
+}
+
+// Connection returns the stored bank connection
+func (s *OpenBankingService) Connection() models.BankConnection {
+	var connection models.BankConnection
+	data, ok := s.settings.GetCached(SettingKeyBankConnection)
+	if !ok || data == "" {
+		return connection
+	}
+	if err := json.Unmarshal([]byte(data), &connection); err != nil {
+		slog.Warn("failed to parse bank connection", "error", err)
+	}
+	return connection
+}
+
+// Candidates returns the recurring charges without a subscription found by the last sync
+func (s *OpenBankingService) Candidates() []RecurringCharge {
+	var candidates []RecurringCharge
+	data, ok := s.settings.GetCached(SettingKeyBankCandidates)
+	if !ok || data == "" {
+		return candidates
+	}
+	if err := json.Unmarshal([]byte(data), &candidates); err != nil {
+		slog.Warn("failed to parse bank candidates", "error", err)
+	}
+	return candidates
+}
+
+// SaveCredentials checks the GoCardless secret ID and key and stores them.
+// Changing the credentials drops the linked accounts.
+func (s *OpenBankingService) SaveCredentials(ctx context.Context, secretID, secretKey string) error {
+	secretID, secretKey = strings.TrimSpace(secretID), strings.TrimSpace(secretKey)
+	if secretID == "" || secretKey == "" {
+		return ErrBankNotConfigured
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	connection := models.BankConnection{SecretID: secretID, SecretKey: secretKey}
+	s.accessToken = ""
+	if _, err := s.tokenLocked(ctx, connection); err != nil {
+		return err
+	}
+	return s.save(connection)
+}
+
+// Institutions lists the banks available in a country (ISO 3166 code)
+func (s *OpenBankingService) Institutions(ctx context.Context, country string) ([]BankInstitution, error) {
+	var institutions []BankInstitution
+	err := s.request(ctx, http.MethodGet, "/institutions/?country="+url.QueryEscape(strings.ToLower(country)), nil, &institutions)
+	return institutions, err
+}
+
+// Connect starts linking the accounts of a bank and returns the link where the
+// user gives consent. The bank redirects back to redirectURL afterwards.
+func (s *OpenBankingService) Connect(ctx context.Context, institutionID, redirectURL string) (string, error) {
+	reference, err := generateImportToken()
+	if err != nil {
+		return "", err
+	}
+	body := map[string]string{
+		"institution_id": institutionID,
+		"redirect":       redirectURL,
+		"reference":      reference,
+		"user_language":  strings.ToUpper(s.preferences.GetLanguage()),
+	}
+	var requisition struct {
+		ID   string `json:"id"`
+		Link string `json:"link"`
+	}
+	if err := s.request(ctx, http.MethodPost, "/requisitions/", body, &requisition); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	connection := s.Connection()
+	connection.InstitutionID = institutionID
+	connection.RequisitionID = requisition.ID
+	connection.Accounts = nil
+	connection.LastError = ""
+	if err := s.save(connection); err != nil {
+		return "", err
+	}
+	return requisition.Link, nil
+}
+
+// RefreshAccounts picks up the accounts of a requisition once the user has
+// given consent at the bank. Rejected or expired requisitions are dropped.
+func (s *OpenBankingService) RefreshAccounts(ctx context.Context) (models.BankConnection, error) {
+	connection := s.Connection()
+	if connection.RequisitionID == "" || connection.Linked() {
+		return connection, nil
+	}
+
+	var requisition struct {
+		Status   string   `json:"status"`
+		Accounts []string `json:"accounts"`
+	}
+	if err := s.request(ctx, http.MethodGet, "/requisitions/"+url.PathEscape(connection.RequisitionID)+"/", nil, &requisition); err != nil {
+		return connection, err
+	}
+
+	switch requisition.Status {
+	case requisitionLinked:
+		connection.Accounts = requisition.Accounts
+	case requisitionRejected, requisitionExpired:
+		connection.RequisitionID = ""
+		connection.LastError = "bank consent was rejected or has expired, connect the bank again"
+	default:
+		return connection, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return connection, s.save(connection)
+}
+
+// Sync reads the recent transactions of the linked accounts, records the
+// charges that clearly pay a renewal in the payment ledger and stores the
+// unmatched recurring charges as proposed subscriptions
+func (s *OpenBankingService) Sync(ctx context.Context) (*BankSyncResult, error) {
+	connection, err := s.RefreshAccounts(ctx)
+	if err != nil {
+		return nil, s.recordSyncError(err)
+	}
+	if !connection.Configured() {
+		return nil, ErrBankNotConfigured
+	}
+	if !connection.Linked() {
+		return nil, ErrBankNotLinked
+	}
+
+	dateFrom := time.Now().AddDate(0, 0, -bankSyncDays).Format("2006-01-02")
+	var transactions []BankTransaction
+	for _, account := range connection.Accounts {
+		booked, err := s.accountTransactions(ctx, account, dateFrom)
+		if err != nil {
+			return nil, s.recordSyncError(err)
+		}
+		transactions = append(transactions, booked...)
+	}
+
+	recorded, candidates, err := s.reconcile.Record(ctx, transactions, bankAutoConfirmConfidence)
+	if err != nil {
+		return nil, s.recordSyncError(err)
+	}
+	data, err := json.Marshal(candidates)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.settings.Repo().Set(SettingKeyBankCandidates, string(data)); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	connection = s.Connection()
+	now := time.Now()
+	connection.LastSync = &now
+	connection.LastError = ""
+	if err := s.save(connection); err != nil {
+		return nil, err
+	}
+
+	return &BankSyncResult{
+		Transactions: len(transactions),
+		Recorded:     recorded.Recorded,
+		Skipped:      recorded.Skipped,
+		Candidates:   len(candidates),
+	}, nil
+}
+
+// Disconnect revokes the bank consent and removes the connection and credentials
+func (s *OpenBankingService) Disconnect(ctx context.Context) error {
+	connection := s.Connection()
+	if connection.RequisitionID != "" {
+		if err := s.request(ctx, http.MethodDelete, "/requisitions/"+url.PathEscape(connection.RequisitionID)+"/", nil, nil); err != nil {
+			slog.Warn("failed to revoke bank requisition", "error", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessToken = ""
+	defer s.settings.InvalidateCache()
+	return errors.Join(
+		s.settings.Repo().Delete(SettingKeyBankConnection),
+		s.settings.Repo().Delete(SettingKeyBankCandidates),
+	)
+}
+
+// gocardlessTransaction is a booked transaction as returned by GoCardless
+type gocardlessTransaction struct {
+	BookingDate       string `json:"bookingDate"`
+	ValueDate         string `json:"valueDate"`
+	TransactionAmount struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	} `json:"transactionAmount"`
+	CreditorName                      string   `json:"creditorName"`
+	DebtorName                        string   `json:"debtorName"`
+	RemittanceInformationUnstructured string   `json:"remittanceInformationUnstructured"`
+	RemittanceInformationArray        []string `json:"remittanceInformationUnstructuredArray"`
+}
+
+func (s *OpenBankingService) accountTransactions(ctx context.Context, account, dateFrom string) ([]BankTransaction, error) {
+	var response struct {
+		Transactions struct {
+			Booked []gocardlessTransaction `json:"booked"`
+		} `json:"transactions"`
+	}
+	path := "/accounts/" + url.PathEscape(account) + "/transactions/?date_from=" + dateFrom
+	if err := s.request(ctx, http.MethodGet, path, nil, &response); err != nil {
+		return nil, err
+	}
+
+	var transactions []BankTransaction
+	for _, booked := range response.Transactions.Booked {
+		date, ok := parseStatementDate(booked.BookingDate)
+		if !ok {
+			if date, ok = parseStatementDate(booked.ValueDate); !ok {
+				continue
+			}
+		}
+		amount, ok := parseStatementAmount(booked.TransactionAmount.Amount)
+		if !ok {
+			continue
+		}
+		remittance := booked.RemittanceInformationUnstructured
+		if remittance == "" {
+			remittance = strings.Join(booked.RemittanceInformationArray, " ")
+		}
+		name := booked.CreditorName
+		if name == "" {
+			name = booked.DebtorName
+		}
+		transactions = append(transactions, BankTransaction{
+			Date:        date,
+			Amount:      amount,
+			Currency:    strings.ToUpper(booked.TransactionAmount.Currency),
+			Description: strings.TrimSpace(name + " " + remittance),
+		})
+	}
+	return transactions, nil
+}
+
+// request calls the GoCardless API with an access token and decodes the JSON response into out
+func (s *OpenBankingService) request(ctx context.Context, method, path string, body, out any) error {
+	s.mu.Lock()
+	token, err := s.tokenLocked(ctx, s.Connection())
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.do(ctx, method, path, token, body, out)
+}
+
+// tokenLocked returns a cached access token or requests a new one. Caller must hold s.mu.
+func (s *OpenBankingService) tokenLocked(ctx context.Context, connection models.BankConnection) (string, error) {
+	if !connection.Configured() {
+		return "", ErrBankNotConfigured
+	}
+	if s.accessToken != "" && time.Now().Before(s.tokenExpiry) {
+		return s.accessToken, nil
+	}
+
+	var token struct {
+		Access        string `json:"access"`
+		AccessExpires int    `json:"access_expires"`
+	}
+	body := map[string]string{"secret_id": connection.SecretID, "secret_key": connection.SecretKey}
+	if err := s.do(ctx, http.MethodPost, "/token/new/", "", body, &token); err != nil {
+		return "", err
+	}
+	s.accessToken = token.Access
+	// Renew a minute early so a token never expires mid-request
+	s.tokenExpiry = time.Now().Add(time.Duration(token.AccessExpires)*time.Second - time.Minute)
+	return s.accessToken, nil
+}
+
+func (s *OpenBankingService) do(ctx context.Context, method, path, token string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach GoCardless: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Summary string `json:"summary"`
+			Detail  string `json:"detail"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&apiErr)
+		return fmt.Errorf("%w: %s (status %d)", ErrBankAPI, strings.TrimSpace(apiErr.Summary+" "+apiErr.Detail), resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode GoCardless response: %w", err)
+	}
+	return nil
+}
+
+// recordSyncError stores the error of a failed sync to show with the connection
+func (s *OpenBankingService) recordSyncError(syncErr error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	connection := s.Connection()
+	connection.LastError = syncErr.Error()
+	if err := s.save(connection); err != nil {
+		slog.Warn("failed to store bank sync error", "error", err)
+	}
+	return syncErr
+}
+
+// save stores the connection. Caller must hold s.mu.
+func (s *OpenBankingService) save(connection models.BankConnection) error {
+	data, err := json.Marshal(connection)
+	if err != nil {
+		return err
+	}
+	defer s.settings.InvalidateCache()
+	return s.settings.Repo().Set(SettingKeyBankConnection, string(data))
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGoCardless serves the GoCardless endpoints used by the bank connection
+func fakeGoCardless(t *testing.T, booked []map[string]any) *httptest.Server {
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, status int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		require.NoError(t, json.NewEncoder(w).Encode(body))
+	}
+	authorized := func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer access-token" }
+
+	mux.HandleFunc("POST /token/new/", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["secret_key"] != "secret" {
+			reply(w, http.StatusUnauthorized, map[string]any{"summary": "Authentication failed", "status_code": 401})
+			return
+		}
+		reply(w, http.StatusOK, map[string]any{"access": "access-token", "access_expires": 86400})
+	})
+	mux.HandleFunc("GET /institutions/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "de", r.URL.Query().Get("country"))
+		reply(w, http.StatusOK, []BankInstitution{{ID: "SANDBOXFINANCE_SFIN0000", Name: "Sandbox Finance"}})
+	})
+	mux.HandleFunc("POST /requisitions/", func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, authorized(r))
+		reply(w, http.StatusCreated, map[string]any{"id": "req-1", "status": "CR", "link": "https://ob.example/start/req-1"})
+	})
+	mux.HandleFunc("GET /requisitions/req-1/", func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, authorized(r))
+		reply(w, http.StatusOK, map[string]any{"id": "req-1", "status": "LN", "accounts": []string{"acc-1"}})
+	})
+	mux.HandleFunc("GET /accounts/acc-1/transactions/", func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, authorized(r))
+		assert.NotEmpty(t, r.URL.Query().Get("date_from"))
+		reply(w, http.StatusOK, map[string]any{"transactions": map[string]any{"booked": booked, "pending": []any{}}})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenBankingService_ConnectAndSync(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Payment{}))
+	services := newTestSubscriptionService(t, db)
+	paymentRepo := repository.NewPaymentRepository(db)
+	payments := NewPaymentService(paymentRepo, services.subscriptions)
+	reconcile := NewReconcileService(paymentRepo, services.subscriptions, services.currency, services.preferences)
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, -1, -3)
+	renewal := start.AddDate(0, 1, 0)
+	_, err := services.subscriptions.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 15.99, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", StartDate: &start})
+	require.NoError(t, err)
+
+	charge := func(date time.Time, amount, creditor, remittance string) map[string]any {
+		return map[string]any{
+			"bookingDate":                       date.Format("2006-01-02"),
+			"transactionAmount":                 map[string]string{"amount": amount, "currency": "EUR"},
+			"creditorName":                      creditor,
+			"remittanceInformationUnstructured": remittance,
+		}
+	}
+	server := fakeGoCardless(t, []map[string]any{
+		charge(renewal, "-15.99", "Netflix International B.V.", "Member 123"),
+		charge(renewal.AddDate(0, -2, 0), "-9.99", "Audible GmbH", ""),
+		charge(renewal.AddDate(0, -1, 0), "-9.99", "Audible GmbH", ""),
+		charge(renewal, "-9.99", "Audible GmbH", ""),
+		charge(renewal, "2500.00", "", "Salary"),
+	})
+
+	bank := NewOpenBankingService(services.settings, reconcile, services.preferences)
+	bank.baseURL = server.URL
+
+	_, err = bank.Sync(t.Context())
+	assert.ErrorIs(t, err, ErrBankNotConfigured)
+	assert.ErrorIs(t, bank.SaveCredentials(context.Background(), "id", "wrong"), ErrBankAPI)
+	assert.False(t, bank.Connection().Configured())
+	require.NoError(t, bank.SaveCredentials(context.Background(), "id", "secret"))
+
+	institutions, err := bank.Institutions(context.Background(), "DE")
+	require.NoError(t, err)
+	require.Len(t, institutions, 1)
+
+	link, err := bank.Connect(context.Background(), institutions[0].ID, "http://localhost/renewals")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ob.example/start/req-1", link)
+	assert.False(t, bank.Connection().Linked())
+
+	result, err := bank.Sync(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.Transactions)
+	assert.Equal(t, 1, result.Recorded)
+	assert.Equal(t, 1, result.Candidates)
+
+	connection := bank.Connection()
+	assert.Equal(t, []string{"acc-1"}, connection.Accounts)
+	require.NotNil(t, connection.LastSync)
+	candidates := bank.Candidates()
+	require.Len(t, candidates, 1)
+	assert.Equal(t, "Audible GmbH", candidates[0].Merchant)
+	assert.Equal(t, "Monthly", candidates[0].Schedule)
+
+	ledger, err := payments.List(t.Context(), 0, models.PaymentConfirmed)
+	require.NoError(t, err)
+	require.Len(t, ledger, 1)
+	assert.Equal(t, 15.99, ledger[0].Amount)
+	assert.True(t, ledger[0].DueDate.Equal(renewal))
+
+	// Syncing again does not record the same charge twice
+	result, err = bank.Sync(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Recorded)
+	assert.Equal(t, 1, result.Skipped)
+
+	require.NoError(t, bank.Disconnect(context.Background()))
+	assert.False(t, bank.Connection().Configured())
+	assert.Empty(t, bank.Candidates())
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Settings keys of the proxy set in the app
+const (
+	SettingKeyProxyURL = "proxy_url"
+	SettingKeyNoProxy  = "no_proxy"
+)
+
+// Where the proxy in effect comes from
+const (
+	ProxySourceNone        = "none"
+	ProxySourceSettings    = "settings"
+	ProxySourceEnvironment = "environment"
+)
+
+var (
+	// ErrInvalidProxyURL is returned for a proxy URL without a host or with an unsupported scheme
+	ErrInvalidProxyURL = errors.New("invalid proxy URL, use http://, https:// or socks5:// with a host")
+	// ErrOfflineMode is returned for outbound calls while offline mode is on
+	ErrOfflineMode = errors.New("outbound network calls are disabled in offline mode")
+)
+
+// ProxyConfig is the proxy set in the app. An empty URL leaves the proxy to
+// the environment.
+type ProxyConfig struct {
+	URL     string `json:"url"`
+	NoProxy string `json:"no_proxy"`
+}
+
+// ProxyStatus is the proxy in effect for outbound requests
+type ProxyStatus struct {
+	ProxyConfig
+	Source string `json:"source"`
+}
+
+// OutboundProxy picks the proxy of outbound HTTP requests: the proxy set in
+// the app, or else HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the environment.
+// Requests to localhost never use a proxy. In offline mode every request fails.
+type OutboundProxy struct {
+	mu      sync.RWMutex
+	config  *httpproxy.Config
+	source  string
+	proxy   func(*url.URL) (*url.URL, error)
+	offline bool
+}
+
+// outboundProxy is shared by the HTTP clients of all services, so a changed
+// proxy applies without a restart
+var outboundProxy = NewOutboundProxy()
+
+// NewOutboundProxy returns a proxy taken from the environment
+func NewOutboundProxy() *OutboundProxy {
+	p := &OutboundProxy{}
+	p.apply("", "")
+	return p
+}
+
+// EnableOfflineMode blocks all outbound HTTP requests of the services and of
+// Go's default HTTP client from now on
+func EnableOfflineMode() {
+	outboundProxy.setOffline(true)
+}
+
+// OfflineMode reports whether outbound network calls are disabled
+func OfflineMode() bool {
+	return outboundProxy.isOffline()
+}
+
+// NormalizeProxyURL checks a proxy URL. A URL without a scheme is taken as an
+// HTTP proxy, like the environment variables.
+func NormalizeProxyURL(proxyURL string) (string, error) {
+	proxyURL = strings.TrimSpace(proxyURL)
+	if proxyURL == "" {
+		return "", nil
+	}
+	if !strings.Contains(proxyURL, "://") {
+		proxyURL = "http://" + proxyURL
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil || u.Hostname() == "" || (u.Path != "" && u.Path != "/") {
+		return "", ErrInvalidProxyURL
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return "", ErrInvalidProxyURL
+	}
+	u.Path = ""
+	return u.String(), nil
+}
+
+// apply sets the proxy set in the app, or the environment's if proxyURL is
+// empty; proxyURL has to be normalized
+func (p *OutboundProxy) apply(proxyURL, noProxy string) {
+	config := httpproxy.FromEnvironment()
+	source := ProxySourceNone
+	if config.HTTPProxy != "" || config.HTTPSProxy != "" {
+		source = ProxySourceEnvironment
+	}
+	if proxyURL != "" {
+		config = &httpproxy.Config{HTTPProxy: proxyURL, HTTPSProxy: proxyURL, NoProxy: noProxy}
+		source = ProxySourceSettings
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	p.source = source
+	p.proxy = config.ProxyFunc()
+}
+
+// Proxy returns the proxy for a request, or nil to connect directly. It is
+// used as the Proxy of the transports, so it also stops requests in offline
+// mode.
+func (p *OutboundProxy) Proxy(req *http.Request) (*url.URL, error) {
+	p.mu.RLock()
+	proxy, offline := p.proxy, p.offline
+	p.mu.RUnlock()
+	if offline {
+		return nil, ErrOfflineMode
+	}
+	return proxy(req.URL)
+}
+
+func (p *OutboundProxy) setOffline(offline bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.offline = offline
+}
+
+func (p *OutboundProxy) isOffline() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.offline
+}
+
+// Status returns the proxy in effect with its password removed
+func (p *OutboundProxy) Status() ProxyStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	status := ProxyStatus{Source: p.source, ProxyConfig: ProxyConfig{NoProxy: p.config.NoProxy}}
+	proxyURL := p.config.HTTPSProxy
+	if proxyURL == "" {
+		proxyURL = p.config.HTTPProxy
+	}
+	status.URL = redactProxyURL(proxyURL)
+	return status
+}
+
+// isProxyAddress reports whether a dialed host:port is one of the proxies
+func (p *OutboundProxy) isProxyAddress(address string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, proxyURL := range []string{p.config.HTTPProxy, p.config.HTTPSProxy} {
+		if proxyURL == "" {
+			continue
+		}
+		if !strings.Contains(proxyURL, "://") {
+			proxyURL = "http://" + proxyURL
+		}
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			continue
+		}
+		port := u.Port()
+		if port == "" {
+			port = map[string]string{"https": "443", "socks5": "1080", "socks5h": "1080"}[u.Scheme]
+		}
+		if port == "" {
+			port = "80"
+		}
+		if net.JoinHostPort(u.Hostname(), port) == address {
+			return true
+		}
+	}
+	return false
+}
+
+// redactProxyURL removes the password from a proxy URL
+func redactProxyURL(proxyURL string) string {
+	if proxyURL == "" {
+		return ""
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// NewHTTPClient returns a client for outbound requests that uses the shared
+// proxy and at least TLS 1.2
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: newHTTPTransport()}
+}
+
+func newHTTPTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = outboundProxy.Proxy
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	return transport
+}
+
+// UseOutboundProxyByDefault makes Go's default HTTP client use the shared
+// proxy, for libraries like Shoutrrr that send with it
+func UseOutboundProxyByDefault() {
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport.Proxy = outboundProxy.Proxy
+	}
+}
+
+// ProxyService stores the proxy set in the app and applies it to the shared
+// outbound proxy
+type ProxyService struct {
+	settings *SettingsService
+	proxy    *OutboundProxy
+}
+
+// NewProxyService applies the stored proxy
+func NewProxyService(settings *SettingsService) *ProxyService {
+	s := &ProxyService{settings: settings, proxy: outboundProxy}
+	config := s.config()
+	proxyURL, err := NormalizeProxyURL(config.URL)
+	if err != nil {
+		slog.Warn("ignoring invalid proxy URL", "error", err)
+	}
+	s.proxy.apply(proxyURL, config.NoProxy)
+	if status := s.proxy.Status(); status.Source != ProxySourceNone {
+		slog.Info("outbound requests use a proxy", "proxy", status.URL, "source", status.Source, "no_proxy", status.NoProxy)
+	}
+	return s
+}
+
+func (s *ProxyService) config() ProxyConfig {
+	proxyURL, _ := s.settings.GetCached(SettingKeyProxyURL)
+	noProxy, _ := s.settings.GetCached(SettingKeyNoProxy)
+	return ProxyConfig{URL: proxyURL, NoProxy: noProxy}
+}
+
+// Get returns the proxy set in the app, with the password of the URL removed
+func (s *ProxyService) Get() ProxyConfig {
+	config := s.config()
+	config.URL = redactProxyURL(config.URL)
+	return config
+}
+
+// Status returns the proxy in effect, which may come from the environment
+func (s *ProxyService) Status() ProxyStatus {
+	return s.proxy.Status()
+}
+
+// Save stores and applies the proxy set in the app. A URL as returned by Get,
+// with the password removed, keeps the stored password.
+func (s *ProxyService) Save(config ProxyConfig) error {
+	current := s.config().URL
+	if current != "" && strings.TrimSpace(config.URL) == redactProxyURL(current) {
+		config.URL = current
+	}
+	proxyURL, err := NormalizeProxyURL(config.URL)
+	if err != nil {
+		return err
+	}
+	noProxy := strings.Join(strings.Fields(strings.ReplaceAll(config.NoProxy, ",", " ")), ",")
+
+	if err := s.settings.Repo().Set(SettingKeyProxyURL, proxyURL); err != nil {
+		return err
+	}
+	if err := s.settings.Repo().Set(SettingKeyNoProxy, noProxy); err != nil {
+		return err
+	}
+	s.settings.InvalidateCache()
+	s.proxy.apply(proxyURL, noProxy)
+	return nil
+}
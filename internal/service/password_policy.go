@@ -0,0 +1,237 @@
+package service
+
+import (
+	"cmp"
+	_ "embed"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	"subvault/internal/models"
+)
+
+// Password policy rules a password can fail
+const (
+	PasswordRuleMinLength = "min_length"
+	PasswordRuleMaxLength = "max_length"
+	PasswordRuleMixedCase = "mixed_case"
+	PasswordRuleDigit     = "digit"
+	PasswordRuleSymbol    = "symbol"
+	PasswordRuleBreached  = "breached"
+	PasswordRuleStrength  = "strength"
+)
+
+// PasswordMaxBytes is the longest password bcrypt accepts
+const PasswordMaxBytes = 72
+
+// PasswordPolicyError is returned when a password does not satisfy the
+// password policy. Rule is one of the PasswordRule constants.
+type PasswordPolicyError struct {
+	Rule   string
+	Policy models.PasswordPolicy
+}
+
+func (e *PasswordPolicyError) Error() string {
+	switch e.Rule {
+	case PasswordRuleMinLength:
+		return fmt.Sprintf("password must be at least %d characters long", e.Policy.MinLength)
+	case PasswordRuleMaxLength:
+		return fmt.Sprintf("password must not be longer than %d bytes", PasswordMaxBytes)
+	case PasswordRuleMixedCase:
+		return "password must contain upper and lower case letters"
+	case PasswordRuleDigit:
+		return "password must contain a digit"
+	case PasswordRuleSymbol:
+		return "password must contain a symbol"
+	case PasswordRuleBreached:
+		return "password is too common and appears in data breaches"
+	}
+	return "password is too easy to guess"
+}
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords are the passwords seen most often in data breaches
+var commonPasswords = func() map[string]bool {
+	passwords := make(map[string]bool)
+	for _, line := range strings.Split(commonPasswordsFile, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			passwords[line] = true
+		}
+	}
+	return passwords
+}()
+
+// commonWords are the common passwords that are words rather than digit
+// patterns, longest first, to find them inside longer passwords
+var commonWords = func() []string {
+	var words []string
+	for password := range commonPasswords {
+		if len(password) >= 4 && !strings.ContainsFunc(password, unicode.IsDigit) {
+			words = append(words, password)
+		}
+	}
+	sortLongestFirst(words)
+	return words
+}()
+
+func sortLongestFirst(words []string) {
+	slices.SortFunc(words, func(a, b string) int {
+		if n := cmp.Compare(len(b), len(a)); n != 0 {
+			return n
+		}
+		return strings.Compare(a, b)
+	})
+}
+
+// passwordClasses reports which character classes a password uses
+func passwordClasses(password string) (lower, upper, digit, symbol bool) {
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	return
+}
+
+// PasswordCheck is the outcome of checking a password against the policy
+type PasswordCheck struct {
+	Strength int      `json:"strength"` // 0 (trivial) to 4 (strong)
+	Failed   []string `json:"failed"`   // Rules the password does not satisfy
+}
+
+// CheckPassword checks a password against a policy. userInputs such as the
+// username make a password weaker when it contains them.
+func CheckPassword(password string, policy models.PasswordPolicy, userInputs ...string) PasswordCheck {
+	check := PasswordCheck{Strength: PasswordStrength(password, userInputs...)}
+	lower, upper, digit, symbol := passwordClasses(password)
+
+	fail := func(failed bool, rule string) {
+		if failed {
+			check.Failed = append(check.Failed, rule)
+		}
+	}
+	fail(len([]rune(password)) < policy.MinLength, PasswordRuleMinLength)
+	fail(len(password) > PasswordMaxBytes, PasswordRuleMaxLength)
+	fail(policy.RequireMixedCase && !(lower && upper), PasswordRuleMixedCase)
+	fail(policy.RequireDigit && !digit, PasswordRuleDigit)
+	fail(policy.RequireSymbol && !symbol, PasswordRuleSymbol)
+	fail(policy.RejectBreached && IsBreachedPassword(password), PasswordRuleBreached)
+	fail(check.Strength < policy.MinStrength, PasswordRuleStrength)
+	return check
+}
+
+// Err returns the first failed rule as a PasswordPolicyError, or nil
+func (c PasswordCheck) Err(policy models.PasswordPolicy) error {
+	if len(c.Failed) == 0 {
+		return nil
+	}
+	return &PasswordPolicyError{Rule: c.Failed[0], Policy: policy}
+}
+
+// IsBreachedPassword reports whether a password is one of the most common
+// breached passwords, also when written with look-alike characters or with
+// digits and symbols added before or after it ("P@ssw0rd2024!")
+func IsBreachedPassword(password string) bool {
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return true
+	}
+	core := strings.TrimFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) })
+	return core != "" && (commonPasswords[core] || commonPasswords[unleet(core)])
+}
+
+// leetReplacer maps look-alike digits and symbols to the letters they replace
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+func unleet(s string) string {
+	return leetReplacer.Replace(s)
+}
+
+// keyboardRows are the character runs people type as "sequences"
+var keyboardRows = []string{"abcdefghijklmnopqrstuvwxyz", "qwertyuiop", "asdfghjkl", "zxcvbnm", "qwertzuiop", "yxcvbnm", "azertyuiop", "01234567890"}
+
+// PasswordStrength estimates how hard a password is to guess, from 0
+// (trivial) to 4 (strong), in the spirit of zxcvbn but much simpler: the
+// brute-force entropy of the password's character classes, with repeated
+// characters, keyboard and alphabet sequences, common passwords and the
+// userInputs counting as a single character each.
+func PasswordStrength(password string, userInputs ...string) int {
+	if password == "" || IsBreachedPassword(password) {
+		return 0
+	}
+
+	lower, upper, digit, symbol := passwordClasses(password)
+	charset := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}} {
+		if class.present {
+			charset += class.size
+		}
+	}
+
+	// Words the password is built from count as one character
+	normalized := unleet(strings.ToLower(password))
+	length := float64(len([]rune(normalized)))
+	words := slices.Clone(commonWords)
+	for _, input := range userInputs {
+		if input = strings.ToLower(input); len(input) >= 3 {
+			words = append(words, input)
+		}
+	}
+	sortLongestFirst(words)
+	for _, word := range words {
+		if strings.Contains(normalized, word) {
+			normalized = strings.Replace(normalized, word, "\x00", 1)
+			length -= float64(len([]rune(word)) - 1)
+		}
+	}
+
+	// Repeats and sequences add little over the character before them
+	runes := []rune(strings.ToLower(password))
+	for i := 1; i < len(runes); i++ {
+		if runes[i] == runes[i-1] || isSequence(runes[i-1], runes[i]) {
+			length -= 0.75
+		}
+	}
+
+	bits := math.Max(length, 1) * math.Log2(float64(charset))
+	guesses := bits * math.Log10(2)
+	switch {
+	case guesses < 3:
+		return 0
+	case guesses < 6:
+		return 1
+	case guesses < 8:
+		return 2
+	case guesses < 10:
+		return 3
+	}
+	return 4
+}
+
+// isSequence reports whether b follows a on a keyboard row or in the
+// alphabet, in either direction
+func isSequence(a, b rune) bool {
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, a)
+		j := strings.IndexRune(row, b)
+		if i >= 0 && j >= 0 && (j-i == 1 || i-j == 1) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsBreachedPassword(t *testing.T) {
+	for _, password := range []string{"password", "12345678", "Password123", "P@ssw0rd2024!", "qwertyuiop", "Summer2024!", "!!letmein!!"} {
+		assert.True(t, IsBreachedPassword(password), password)
+	}
+	for _, password := range []string{"", "correct horse battery staple", "Xk9#mQ2!vL", "2024!"} {
+		assert.False(t, IsBreachedPassword(password), password)
+	}
+}
+
+func TestPasswordStrength(t *testing.T) {
+	assert.Equal(t, 0, PasswordStrength(""))
+	assert.Equal(t, 0, PasswordStrength("password1"))
+	assert.Equal(t, 4, PasswordStrength("correct horse battery staple"))
+	assert.Equal(t, 4, PasswordStrength("Xk9#mQ2!vL"))
+
+	// Repeats and sequences are weaker than random characters of the same length
+	assert.Less(t, PasswordStrength("aaaaaaaa"), PasswordStrength("qmzbxlwk"))
+	assert.Less(t, PasswordStrength("abcdefgh"), PasswordStrength("qmzbxlwk"))
+
+	// Containing the username makes a password weaker
+	assert.Less(t, PasswordStrength("jonathanfox7", "jonathanfox"), PasswordStrength("jonathanfox7"))
+}
+
+func TestCheckPassword(t *testing.T) {
+	policy := models.PasswordPolicy{MinLength: 10, RequireMixedCase: true, RequireDigit: true, RequireSymbol: true, RejectBreached: true, MinStrength: 3}
+
+	check := CheckPassword("password", policy)
+	assert.Equal(t, []string{
+		PasswordRuleMinLength, PasswordRuleMixedCase, PasswordRuleDigit,
+		PasswordRuleSymbol, PasswordRuleBreached, PasswordRuleStrength,
+	}, check.Failed)
+
+	var policyErr *PasswordPolicyError
+	require.ErrorAs(t, check.Err(policy), &policyErr)
+	assert.Equal(t, PasswordRuleMinLength, policyErr.Rule)
+	assert.Equal(t, "password must be at least 10 characters long", policyErr.Error())
+
+	check = CheckPassword("Xk9#mQ2!vLp", policy)
+	assert.Empty(t, check.Failed)
+	assert.NoError(t, check.Err(policy))
+
+	// bcrypt ignores everything past 72 bytes
+	check = CheckPassword(strings.Repeat("Xk9#mQ2!vL", 8), models.DefaultPasswordPolicy)
+	assert.Equal(t, []string{PasswordRuleMaxLength}, check.Failed)
+}
+
+func TestAuthService_PasswordPolicy(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	settingsRepo := repository.NewSettingsRepository(db)
+	authService := NewAuthService(NewSettingsService(settingsRepo), settingsRepo)
+
+	assert.Equal(t, models.DefaultPasswordPolicy, authService.GetPasswordPolicy())
+
+	// The default policy rejects short and breached passwords
+	var policyErr *PasswordPolicyError
+	require.ErrorAs(t, authService.SetupAuth("admin", "short"), &policyErr)
+	assert.Equal(t, PasswordRuleMinLength, policyErr.Rule)
+	require.ErrorAs(t, authService.SetupAuth("admin", "password123"), &policyErr)
+	assert.Equal(t, PasswordRuleBreached, policyErr.Rule)
+	assert.False(t, authService.IsAuthEnabled())
+
+	require.NoError(t, authService.SetupAuth("admin", "admin-password"))
+
+	assert.Error(t, authService.SavePasswordPolicy(models.PasswordPolicy{MinLength: 4}))
+	require.NoError(t, authService.SavePasswordPolicy(models.PasswordPolicy{MinLength: 12, RequireDigit: true, MinStrength: 3}))
+
+	require.ErrorAs(t, authService.SetAuthPassword("long-but-no-digits"), &policyErr)
+	assert.Equal(t, PasswordRuleDigit, policyErr.Rule)
+	require.ErrorAs(t, authService.SetViewer("partner", "partner12345"), &policyErr)
+	assert.Equal(t, PasswordRuleStrength, policyErr.Rule)
+	require.NoError(t, authService.SetViewer("partner", "violet-42-harbour"))
+
+	// Existing passwords keep working under a stricter policy
+	role, err := authService.Authenticate("admin", "admin-password")
+	require.NoError(t, err)
+	assert.Equal(t, RoleAdmin, role)
+}
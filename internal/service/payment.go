@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ErrPaymentNotFound is returned for an unknown payment
+var ErrPaymentNotFound = errors.New("payment not found")
+
+// ErrPaymentConfirmed is returned when confirming or rejecting a renewal that
+// was already confirmed
+var ErrPaymentConfirmed = errors.New("payment is already confirmed")
+
+// PaymentService keeps the payment ledger. With renewal confirmations enabled,
+// every renewal that passes is recorded as pending until it is confirmed as
+// charged or reported as not charged.
+type PaymentService struct {
+	repo          *repository.PaymentRepository
+	subscriptions SubscriptionServiceInterface
+}
+
+func NewPaymentService(repo *repository.PaymentRepository, subscriptions SubscriptionServiceInterface) *PaymentService {
+	return &PaymentService{repo: repo, subscriptions: subscriptions}
+}
+
+// RecordRenewals adds a pending payment for every renewal in the last
+// PaymentOverdueDays that has none yet and returns the new ones. Older renewals
+// are left out so enabling confirmations does not ask about past charges, and
+// so is the first charge on a subscription's start date.
+func (s *PaymentService) RecordRenewals(ctx context.Context, now time.Time) ([]models.Payment, error) {
+	subs, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	from := now.AddDate(0, 0, -models.PaymentOverdueDays)
+	var created []models.Payment
+	for i := range subs {
+		sub := &subs[i]
+		started := subscriptionStart(sub)
+		for _, due := range chargeDates(sub, from, now.Add(time.Nanosecond), now) {
+			if sameDay(due, started) {
+				continue
+			}
+			exists, err := s.repo.Exists(sub.ID, due)
+			if err != nil {
+				return created, err
+			}
+			if exists {
+				continue
+			}
+
+			payment := models.Payment{
+				SubscriptionID: sub.ID,
+				DueDate:        due,
+				Amount:         roundCents(sub.GrossCost()),
+				Currency:       sub.OriginalCurrency,
+				Status:         models.PaymentPending,
+			}
+			if err := s.repo.Create(&payment); err != nil {
+				return created, err
+			}
+			payment.Name = sub.Name
+			created = append(created, payment)
+		}
+	}
+	return created, nil
+}
+
+// List returns the payments of a subscription (or all with subscriptionID 0),
+// optionally limited to the given statuses
+func (s *PaymentService) List(ctx context.Context, subscriptionID uint, statuses ...string) ([]models.Payment, error) {
+	payments, err := s.repo.List(subscriptionID, statuses...)
+	if err != nil {
+		return nil, err
+	}
+	return payments, s.fillNames(ctx, payments)
+}
+
+// Unconfirmed returns the renewals awaiting confirmation and those reported as
+// not charged
+func (s *PaymentService) Unconfirmed(ctx context.Context) ([]models.Payment, error) {
+	return s.List(ctx, 0, models.PaymentPending, models.PaymentMissed)
+}
+
+// Confirm adds a renewal to the payment ledger. A non-nil amount replaces the
+// expected amount with what was actually charged; paidAt defaults to the
+// renewal date. A charge paid on or after a failed payment ends its grace
+// period.
+func (s *PaymentService) Confirm(ctx context.Context, id uint, amount *float64, paidAt *time.Time) (*models.Payment, error) {
+	payment, err := s.unconfirmed(id)
+	if err != nil {
+		return nil, err
+	}
+	if amount != nil {
+		payment.Amount = roundCents(*amount)
+	}
+	if paidAt == nil {
+		paidAt = &payment.DueDate
+	}
+	payment.PaidAt = paidAt
+	payment.Status = models.PaymentConfirmed
+	if err := s.repo.Save(payment); err != nil {
+		return nil, err
+	}
+
+	sub, err := s.subscriptions.GetByID(ctx, payment.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	payment.Name = sub.Name
+	if failed := sub.PaymentFailedAt; failed != nil && (sameDay(*paidAt, *failed) || paidAt.After(*failed)) {
+		sub.ResolvePaymentFailure()
+		if _, err := s.subscriptions.Update(ctx, sub.ID, sub); err != nil {
+			return nil, err
+		}
+	}
+	return payment, nil
+}
+
+// Reject reports a renewal as not charged, flagging a possible involuntary
+// cancellation
+func (s *PaymentService) Reject(ctx context.Context, id uint) (*models.Payment, error) {
+	payment, err := s.unconfirmed(id)
+	if err != nil {
+		return nil, err
+	}
+	payment.Status = models.PaymentMissed
+	if err := s.repo.Save(payment); err != nil {
+		return nil, err
+	}
+	return payment, s.fillName(ctx, payment)
+}
+
+func (s *PaymentService) unconfirmed(id uint) (*models.Payment, error) {
+	payment, err := s.repo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status == models.PaymentConfirmed {
+		return nil, ErrPaymentConfirmed
+	}
+	return payment, nil
+}
+
+func (s *PaymentService) fillName(ctx context.Context, payment *models.Payment) error {
+	sub, err := s.subscriptions.GetByID(ctx, payment.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	payment.Name = sub.Name
+	return nil
+}
+
+func (s *PaymentService) fillNames(ctx context.Context, payments []models.Payment) error {
+	if len(payments) == 0 {
+		return nil
+	}
+	subs, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	names := make(map[uint]string, len(subs))
+	for _, sub := range subs {
+		names[sub.ID] = sub.Name
+	}
+	for i := range payments {
+		payments[i].Name = names[payments[i].SubscriptionID]
+	}
+	return nil
+}
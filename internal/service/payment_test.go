@@ -0,0 +1,111 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPaymentService(t *testing.T) (*PaymentService, *SubscriptionService) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Payment{}))
+	services := newTestSubscriptionService(t, db)
+	return NewPaymentService(repository.NewPaymentRepository(db), services.subscriptions), services.subscriptions
+}
+
+func TestPaymentService_RecordRenewals(t *testing.T) {
+	payments, subscriptions := setupPaymentService(t)
+	now := time.Now()
+	date := func(months, days int) *time.Time {
+		d := now.AddDate(0, months, days)
+		d = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local)
+		return &d
+	}
+	for _, sub := range []models.Subscription{
+		// Renewed three days ago
+		{Name: "Netflix", Cost: 15, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", StartDate: date(-2, -3)},
+		{Name: "IDE", Cost: 10, PriceType: "net", TaxRate: 20, Schedule: "Monthly", Status: "Active", OriginalCurrency: "USD", StartDate: date(-1, -3)},
+		// Only the initial charge so far
+		{Name: "Gym", Cost: 30, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", StartDate: date(0, -1)},
+		// Last renewal is older than the confirmation window
+		{Name: "Cloud", Cost: 5, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", StartDate: date(-1, -20)},
+		{Name: "Paused", Cost: 8, Schedule: "Monthly", Status: "Paused", OriginalCurrency: "EUR", StartDate: date(-2, -3)},
+	} {
+		_, err := subscriptions.Create(t.Context(), &sub)
+		require.NoError(t, err)
+	}
+
+	created, err := payments.RecordRenewals(t.Context(), now)
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+	byName := map[string]models.Payment{}
+	for _, p := range created {
+		assert.Equal(t, models.PaymentPending, p.Status)
+		assert.True(t, p.DueDate.Before(now))
+		byName[p.Name] = p
+	}
+	assert.Equal(t, 15.0, byName["Netflix"].Amount)
+	// Net prices are charged with tax
+	assert.Equal(t, 12.0, byName["IDE"].Amount)
+	assert.Equal(t, "USD", byName["IDE"].Currency)
+
+	// Renewals are recorded once
+	created, err = payments.RecordRenewals(t.Context(), now)
+	require.NoError(t, err)
+	assert.Empty(t, created)
+}
+
+func TestPaymentService_ConfirmAndReject(t *testing.T) {
+	payments, subscriptions := setupPaymentService(t)
+	now := time.Now()
+	start := now.AddDate(0, -1, -2)
+	for _, name := range []string{"Netflix", "Spotify"} {
+		_, err := subscriptions.Create(t.Context(), &models.Subscription{Name: name, Cost: 10, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", StartDate: &start})
+		require.NoError(t, err)
+	}
+	created, err := payments.RecordRenewals(t.Context(), now)
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+
+	amount := 11.5
+	confirmed, err := payments.Confirm(t.Context(), created[0].ID, &amount, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentConfirmed, confirmed.Status)
+	assert.Equal(t, 11.5, confirmed.Amount)
+	require.NotNil(t, confirmed.PaidAt)
+	assert.True(t, confirmed.PaidAt.Equal(created[0].DueDate))
+	assert.False(t, confirmed.PossiblyCancelled(now))
+
+	_, err = payments.Confirm(t.Context(), created[0].ID, nil, nil)
+	assert.ErrorIs(t, err, ErrPaymentConfirmed)
+	_, err = payments.Reject(t.Context(), 999)
+	assert.ErrorIs(t, err, ErrPaymentNotFound)
+
+	missed, err := payments.Reject(t.Context(), created[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentMissed, missed.Status)
+	assert.True(t, missed.PossiblyCancelled(now))
+
+	ledger, err := payments.List(t.Context(), 0, models.PaymentConfirmed)
+	require.NoError(t, err)
+	require.Len(t, ledger, 1)
+	assert.Equal(t, created[0].Name, ledger[0].Name)
+
+	unconfirmed, err := payments.Unconfirmed(t.Context())
+	require.NoError(t, err)
+	require.Len(t, unconfirmed, 1)
+	assert.Equal(t, models.PaymentMissed, unconfirmed[0].Status)
+}
+
+func TestPayment_PossiblyCancelled(t *testing.T) {
+	now := time.Date(2026, 5, 20, 12, 0, 0, 0, time.UTC)
+	pending := models.Payment{Status: models.PaymentPending, DueDate: now.AddDate(0, 0, -3)}
+	assert.False(t, pending.PossiblyCancelled(now))
+	pending.DueDate = now.AddDate(0, 0, -models.PaymentOverdueDays-1)
+	assert.True(t, pending.PossiblyCancelled(now))
+}
@@ -2,6 +2,18 @@ package service
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+
+	"subvault/internal/i18n"
+)
+
+// Bounds and defaults of the dashboard's upcoming renewals preferences
+const (
+	DefaultRenewalWindowDays     = 7
+	MaxRenewalWindowDays         = 90
+	DefaultUpcomingRenewalsLimit = 5
+	MaxUpcomingRenewalsLimit     = 20
 )
 
 type PreferencesService struct {
@@ -38,17 +50,61 @@ func (p *PreferencesService) SetDarkMode(enabled bool) error {
 	return p.settings.SetBoolSetting(SettingKeyDarkMode, enabled)
 }
 
+// ChartTablesEnabled returns whether charts are shown as data tables, for
+// screen readers
+func (p *PreferencesService) ChartTablesEnabled() bool {
+	return p.settings.GetBoolSettingWithDefault(SettingKeyChartTables, false)
+}
+
+// SetChartTables saves whether charts are shown as data tables
+func (p *PreferencesService) SetChartTables(enabled bool) error {
+	return p.settings.SetBoolSetting(SettingKeyChartTables, enabled)
+}
+
+// GetAccentColor retrieves the custom accent color, or "" for the theme's own
+// accent and the accent presets
+func (p *PreferencesService) GetAccentColor() string {
+	color, _ := p.settings.GetCached(SettingKeyAccentColor)
+	if _, err := NormalizeAccentColor(color); err != nil {
+		return ""
+	}
+	return color
+}
+
+// SetAccentColor saves the custom accent color; "" removes it
+func (p *PreferencesService) SetAccentColor(color string) error {
+	color, err := NormalizeAccentColor(color)
+	if err != nil {
+		return err
+	}
+	defer p.settings.InvalidateCache()
+	return p.settings.Repo().Set(SettingKeyAccentColor, color)
+}
+
+// GetCustomCSS retrieves the custom CSS added to every page
+func (p *PreferencesService) GetCustomCSS() string {
+	css, _ := p.settings.GetCached(SettingKeyCustomCSS)
+	return css
+}
+
+// SetCustomCSS checks and saves the custom CSS; "" removes it
+func (p *PreferencesService) SetCustomCSS(css string) error {
+	css, err := SanitizeCustomCSS(css)
+	if err != nil {
+		return err
+	}
+	defer p.settings.InvalidateCache()
+	return p.settings.Repo().Set(SettingKeyCustomCSS, css)
+}
+
+// ValidCurrency reports whether currency can be the display currency
+func (p *PreferencesService) ValidCurrency(currency string) bool {
+	return slices.Contains(SupportedCurrencies(), currency)
+}
+
 // SetCurrency saves the currency preference
 func (p *PreferencesService) SetCurrency(currency string) error {
-	// Validate currency using shared constant
-	isValid := false
-	for _, c := range SupportedCurrencies {
-		if currency == c {
-			isValid = true
-			break
-		}
-	}
-	if !isValid {
+	if !p.ValidCurrency(currency) {
 		return fmt.Errorf("invalid currency: %s", currency)
 	}
 	defer p.settings.InvalidateCache()
@@ -69,16 +125,14 @@ func (p *PreferencesService) GetCurrencySymbol() string {
 	return CurrencySymbolForCode(p.GetCurrency())
 }
 
+// ValidLanguage reports whether lang is a supported language
+func (p *PreferencesService) ValidLanguage(lang string) bool {
+	return slices.Contains(p.langProvider.SupportedLanguages(), lang)
+}
+
 // SetLanguage saves the language preference
 func (p *PreferencesService) SetLanguage(lang string) error {
-	isValid := false
-	for _, l := range p.langProvider.SupportedLanguages() {
-		if lang == l {
-			isValid = true
-			break
-		}
-	}
-	if !isValid {
+	if !p.ValidLanguage(lang) {
 		return fmt.Errorf("invalid language: %s", lang)
 	}
 	defer p.settings.InvalidateCache()
@@ -109,3 +163,93 @@ func (p *PreferencesService) GetDateFormat() string {
 	}
 	return val
 }
+
+// SetDisplayRounding saves how displayed amounts are rounded, to the precision
+// of their currency or to whole units
+func (p *PreferencesService) SetDisplayRounding(rounding string) error {
+	if !i18n.ValidRounding(rounding) {
+		return fmt.Errorf("invalid display rounding: %s", rounding)
+	}
+	defer p.settings.InvalidateCache()
+	return p.settings.Repo().Set(SettingKeyDisplayRounding, rounding)
+}
+
+// GetDisplayRounding retrieves the display rounding preference, rounding to
+// the precision of the currency by default
+func (p *PreferencesService) GetDisplayRounding() string {
+	rounding, ok := p.settings.GetCached(SettingKeyDisplayRounding)
+	if !ok || !i18n.ValidRounding(rounding) {
+		return i18n.RoundingCurrency
+	}
+	return rounding
+}
+
+// SetCSVColumns saves which columns the CSV export includes and their order
+func (p *PreferencesService) SetCSVColumns(columns []string) error {
+	columns, err := ParseCSVColumns(columns)
+	if err != nil {
+		return err
+	}
+	defer p.settings.InvalidateCache()
+	return p.settings.Repo().Set(SettingKeyCSVColumns, strings.Join(columns, ","))
+}
+
+// GetCSVColumns retrieves the CSV export columns in their order, all columns
+// in the default order unless set
+func (p *PreferencesService) GetCSVColumns() []string {
+	value, ok := p.settings.GetCached(SettingKeyCSVColumns)
+	if !ok || value == "" {
+		return DefaultCSVColumns()
+	}
+	columns, err := ParseCSVColumns(strings.Split(value, ","))
+	if err != nil {
+		return DefaultCSVColumns()
+	}
+	return columns
+}
+
+// SetRenewalWindowDays saves how many days ahead the dashboard counts
+// upcoming renewals
+func (p *PreferencesService) SetRenewalWindowDays(days int) error {
+	if days < 1 || days > MaxRenewalWindowDays {
+		return fmt.Errorf("invalid renewal window: %d days", days)
+	}
+	return p.settings.SetIntSetting(SettingKeyRenewalWindowDays, days)
+}
+
+// GetRenewalWindowDays retrieves the renewal window in days, a week by default
+func (p *PreferencesService) GetRenewalWindowDays() int {
+	days := p.settings.GetIntSettingWithDefault(SettingKeyRenewalWindowDays, DefaultRenewalWindowDays)
+	if days < 1 || days > MaxRenewalWindowDays {
+		return DefaultRenewalWindowDays
+	}
+	return days
+}
+
+// SetUpcomingRenewalsLimit saves how many renewals the dashboard's upcoming
+// renewals card lists
+func (p *PreferencesService) SetUpcomingRenewalsLimit(limit int) error {
+	if limit < 1 || limit > MaxUpcomingRenewalsLimit {
+		return fmt.Errorf("invalid upcoming renewals limit: %d", limit)
+	}
+	return p.settings.SetIntSetting(SettingKeyUpcomingRenewals, limit)
+}
+
+// GetUpcomingRenewalsLimit retrieves the size of the upcoming renewals card,
+// five renewals by default
+func (p *PreferencesService) GetUpcomingRenewalsLimit() int {
+	limit := p.settings.GetIntSettingWithDefault(SettingKeyUpcomingRenewals, DefaultUpcomingRenewalsLimit)
+	if limit < 1 || limit > MaxUpcomingRenewalsLimit {
+		return DefaultUpcomingRenewalsLimit
+	}
+	return limit
+}
+
+// FormatAmount writes an amount in a currency, without symbol, rounded for
+// display. An empty currency is the display currency.
+func (p *PreferencesService) FormatAmount(amount float64, currency string) string {
+	if currency == "" {
+		currency = p.GetCurrency()
+	}
+	return i18n.FormatAmount(amount, currency, p.GetDisplayRounding())
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QuickAddDraft is a subscription parsed from free text. Fields that were not
+// found in the text are left empty.
+type QuickAddDraft struct {
+	Name        string  `json:"name"`
+	Cost        float64 `json:"cost,omitempty"`
+	Currency    string  `json:"currency,omitempty"`
+	Schedule    string  `json:"schedule,omitempty"`
+	RenewalDate string  `json:"renewal_date,omitempty"`
+}
+
+// quickCurrencySymbols maps currency symbols to codes, longest first so that
+// "HK$" is not read as "$". Ambiguous symbols map to the most common currency.
+var quickCurrencySymbols = []struct{ symbol, code string }{
+	{"HK$", "HKD"}, {"MX$", "MXN"}, {"NZ$", "NZD"}, {"A$", "AUD"}, {"C$", "CAD"}, {"R$", "BRL"},
+	{"zł", "PLN"}, {"€", "EUR"}, {"£", "GBP"}, {"₹", "INR"}, {"₽", "RUB"}, {"₪", "ILS"},
+	{"₩", "KRW"}, {"¥", "JPY"}, {"$", "USD"},
+}
+
+// quickSchedules maps schedule keywords in English and German to schedules.
+// Longer keywords come first so "vierteljährlich" is not read as "jährlich".
+var quickSchedules = []struct {
+	pattern  *regexp.Regexp
+	schedule string
+}{
+	{regexp.MustCompile(`(?i)\b(?:quarterly|(?:per|a|every|each) quarter|vierteljährlich|quartalsweise)\b`), "Quarterly"},
+	{regexp.MustCompile(`(?i)(?:\b(?:monthly|(?:per|a|every|each) month|monatlich|mtl\.?)\b|/\s?(?:mo|month|monat)\b)`), "Monthly"},
+	{regexp.MustCompile(`(?i)(?:\b(?:yearly|annually|annual|(?:per|a|every|each) year|jährlich|jaehrlich)\b|/\s?(?:yr|year|jahr)\b)`), "Annual"},
+	{regexp.MustCompile(`(?i)(?:\b(?:weekly|(?:per|a|every|each) week|wöchentlich)\b|/\s?(?:wk|week|woche)\b)`), "Weekly"},
+	{regexp.MustCompile(`(?i)(?:\b(?:daily|(?:per|a|every|each) day|täglich)\b|/\s?(?:day|tag)\b)`), "Daily"},
+}
+
+var (
+	quickISODate    = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+	quickGermanDate = regexp.MustCompile(`\b(\d{1,2})\.(\d{1,2})\.(\d{4})\b`)
+	quickMonthDay   = regexp.MustCompile(`(?i)\b(?:on\s+)?(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\.?\s+(\d{1,2})(?:st|nd|rd|th)?\b`)
+	quickDayMonth   = regexp.MustCompile(`(?i)\b(?:on\s+)?(?:the\s+)?(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\b`)
+	quickOrdinalDay = regexp.MustCompile(`(?i)\b(?:on\s+)?(?:the\s+)?(\d{1,2})(?:st|nd|rd|th)\b`)
+	quickOnDay      = regexp.MustCompile(`(?i)\b(?:on(?:\s+the)?|am|zum)\s+(\d{1,2})\.?(?:\s|$)`)
+	quickAmount     = regexp.MustCompile(`\b\d+(?:[.,]\d{1,2})?\b`)
+	quickCode       = regexp.MustCompile(`\b[A-Za-z]{3}\b`)
+	quickKeywords   = regexp.MustCompile(`(?i)\b(?:renews?|renewal|renewing|billed|costs?|due|verlängert|kostet)\b`)
+	quickFiller     = map[string]bool{
+		"on": true, "the": true, "every": true, "each": true, "per": true, "a": true, "for": true,
+		"at": true, "of": true, "is": true, "and": true, "starting": true, "next": true,
+		"am": true, "um": true, "pro": true, "für": true, "zum": true, "ab": true,
+	}
+	quickMonths = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+)
+
+// quickText is the text being parsed. Recognized parts are blanked out of
+// rest so later patterns do not match them again; first records where the
+// earliest one started, which ends the name.
+type quickText struct {
+	original string
+	rest     []byte
+	first    int
+}
+
+func (t *quickText) take(start, end int) {
+	for i := start; i < end; i++ {
+		t.rest[i] = ' '
+	}
+	if start < t.first {
+		t.first = start
+	}
+}
+
+// ParseQuickAdd reads a subscription from free text like "Netflix 17.99
+// monthly renews on the 12th". The name is the text before the first
+// recognized part, or else what is left after removing those parts. A day of
+// the month or a date without year resolves to its next occurrence from now.
+func ParseQuickAdd(text string, now time.Time) QuickAddDraft {
+	t := &quickText{original: text, rest: []byte(text), first: len(text)}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var draft QuickAddDraft
+
+	if date := t.parseDate(today); date != nil {
+		draft.RenewalDate = date.Format("2006-01-02")
+	}
+	for _, s := range quickSchedules {
+		if loc := s.pattern.FindIndex(t.rest); loc != nil {
+			draft.Schedule = s.schedule
+			t.take(loc[0], loc[1])
+			break
+		}
+	}
+	if loc := quickAmount.FindIndex(t.rest); loc != nil {
+		amount := strings.Replace(string(t.rest[loc[0]:loc[1]]), ",", ".", 1)
+		if cost, err := strconv.ParseFloat(amount, 64); err == nil && cost > 0 {
+			draft.Cost = cost
+			t.take(loc[0], loc[1])
+			draft.Currency = t.currencyNextTo(loc[0], loc[1])
+		}
+	}
+	if draft.Currency == "" {
+		draft.Currency = t.parseCurrency()
+	}
+	for _, loc := range quickKeywords.FindAllIndex(t.rest, -1) {
+		t.take(loc[0], loc[1])
+	}
+
+	draft.Name = trimName(trimFiller(strings.Fields(t.original[:t.first])))
+	if draft.Name == "" {
+		var words []string
+		for _, word := range strings.Fields(string(t.rest)) {
+			if !quickFiller[strings.ToLower(word)] {
+				words = append(words, word)
+			}
+		}
+		draft.Name = trimName(strings.Join(words, " "))
+	}
+	return draft
+}
+
+// trimFiller joins words without the filler words at their end, like "for"
+// in "Netflix for 17.99"
+func trimFiller(words []string) string {
+	for len(words) > 0 && quickFiller[strings.ToLower(words[len(words)-1])] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// parseDate finds the renewal date, trying full dates before partial ones
+func (t *quickText) parseDate(today time.Time) *time.Time {
+	if m := quickISODate.FindSubmatchIndex(t.rest); m != nil {
+		if date, ok := fullDate(t.group(m, 1), t.group(m, 2), t.group(m, 3)); ok {
+			t.take(m[0], m[1])
+			return &date
+		}
+	}
+	if m := quickGermanDate.FindSubmatchIndex(t.rest); m != nil {
+		if date, ok := fullDate(t.group(m, 3), t.group(m, 2), t.group(m, 1)); ok {
+			t.take(m[0], m[1])
+			return &date
+		}
+	}
+	for _, p := range []struct {
+		pattern    *regexp.Regexp
+		month, day int
+	}{{quickMonthDay, 1, 2}, {quickDayMonth, 2, 1}} {
+		m := p.pattern.FindSubmatchIndex(t.rest)
+		if m == nil {
+			continue
+		}
+		month := slices.Index(quickMonths, strings.ToLower(t.group(m, p.month))[:3]) + 1
+		day, _ := strconv.Atoi(t.group(m, p.day))
+		if date, ok := nextMonthDay(today, time.Month(month), day); ok {
+			t.take(m[0], m[1])
+			return &date
+		}
+	}
+	for _, pattern := range []*regexp.Regexp{quickOrdinalDay, quickOnDay} {
+		m := pattern.FindSubmatchIndex(t.rest)
+		if m == nil {
+			continue
+		}
+		day, _ := strconv.Atoi(t.group(m, 1))
+		if day >= 1 && day <= 31 {
+			t.take(m[0], m[1])
+			date := nextDayOfMonth(today, day)
+			return &date
+		}
+	}
+	return nil
+}
+
+// currencyNextTo finds a currency code written right before or after the
+// amount between start and end, in any case
+func (t *quickText) currencyNextTo(start, end int) string {
+	for _, loc := range quickCode.FindAllIndex(t.rest, -1) {
+		between := ""
+		switch {
+		case loc[0] >= end:
+			between = string(t.rest[end:loc[0]])
+		case loc[1] <= start:
+			between = string(t.rest[loc[1]:start])
+		default:
+			continue
+		}
+		code := strings.ToUpper(string(t.rest[loc[0]:loc[1]]))
+		if strings.TrimSpace(between) == "" && slices.Contains(SupportedCurrencies(), code) {
+			t.take(loc[0], loc[1])
+			return code
+		}
+	}
+	return ""
+}
+
+// parseCurrency finds a currency symbol or an upper case currency code
+func (t *quickText) parseCurrency() string {
+	for _, s := range quickCurrencySymbols {
+		if i := strings.Index(string(t.rest), s.symbol); i >= 0 {
+			t.take(i, i+len(s.symbol))
+			return s.code
+		}
+	}
+	for _, loc := range quickCode.FindAllIndex(t.rest, -1) {
+		code := string(t.rest[loc[0]:loc[1]])
+		if slices.Contains(SupportedCurrencies(), code) {
+			t.take(loc[0], loc[1])
+			return code
+		}
+	}
+	return ""
+}
+
+func (t *quickText) group(m []int, n int) string {
+	return string(t.rest[m[2*n]:m[2*n+1]])
+}
+
+func fullDate(year, month, day string) (time.Time, bool) {
+	date, err := time.Parse("2006-1-2", year+"-"+month+"-"+day)
+	return date, err == nil
+}
+
+// nextMonthDay returns the next occurrence of a day of a month, today included
+func nextMonthDay(today time.Time, month time.Month, day int) (time.Time, bool) {
+	if month < time.January || day < 1 {
+		return time.Time{}, false
+	}
+	for year := today.Year(); year <= today.Year()+4; year++ {
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		if date.Month() == month && !date.Before(today) {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextDayOfMonth returns the next date with the given day of the month, today
+// included. Months that are too short use their last day.
+func nextDayOfMonth(today time.Time, day int) time.Time {
+	date := clampedDate(today.Year(), today.Month(), day)
+	if date.Before(today) {
+		date = clampedDate(today.Year(), today.Month()+1, day)
+	}
+	return date
+}
+
+func clampedDate(year int, month time.Month, day int) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return time.Date(year, month, min(day, last), 0, 0, 0, 0, time.UTC)
+}
+
+// trimName removes separators and whitespace around a parsed name
+func trimName(name string) string {
+	return strings.TrimFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("-–—:;,.|/", r)
+	})
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	now := time.Date(2025, time.March, 20, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		text     string
+		expected QuickAddDraft
+	}{
+		{
+			text:     "Netflix 17.99 monthly renews on the 12th",
+			expected: QuickAddDraft{Name: "Netflix", Cost: 17.99, Schedule: "Monthly", RenewalDate: "2025-04-12"},
+		},
+		{
+			text:     "Spotify €9,99/mo on the 25th",
+			expected: QuickAddDraft{Name: "Spotify", Cost: 9.99, Currency: "EUR", Schedule: "Monthly", RenewalDate: "2025-03-25"},
+		},
+		{
+			text:     "Disney+ for 89.90 usd yearly, renews 2025-11-03",
+			expected: QuickAddDraft{Name: "Disney+", Cost: 89.9, Currency: "USD", Schedule: "Annual", RenewalDate: "2025-11-03"},
+		},
+		{
+			text:     "1Password 2.99 per month",
+			expected: QuickAddDraft{Name: "1Password", Cost: 2.99, Schedule: "Monthly"},
+		},
+		{
+			text:     "Marvel Unlimited $69 annually on Jan 15",
+			expected: QuickAddDraft{Name: "Marvel Unlimited", Cost: 69, Currency: "USD", Schedule: "Annual", RenewalDate: "2026-01-15"},
+		},
+		{
+			text:     "Zeitung 12,50 € vierteljährlich am 31.",
+			expected: QuickAddDraft{Name: "Zeitung", Cost: 12.5, Currency: "EUR", Schedule: "Quarterly", RenewalDate: "2025-03-31"},
+		},
+		{
+			text:     "Fitnessstudio 29 monatlich, verlängert am 01.04.2025",
+			expected: QuickAddDraft{Name: "Fitnessstudio", Cost: 29, Schedule: "Monthly", RenewalDate: "2025-04-01"},
+		},
+		{
+			text:     "4.99 weekly Meal Kit",
+			expected: QuickAddDraft{Name: "Meal Kit", Cost: 4.99, Schedule: "Weekly"},
+		},
+		{
+			text:     "Try Hulu",
+			expected: QuickAddDraft{Name: "Try Hulu"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseQuickAdd(tt.text, now))
+		})
+	}
+}
+
+func TestParseQuickAdd_DayOfMonthClampsToShortMonths(t *testing.T) {
+	now := time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "2025-01-31", ParseQuickAdd("Gym 20 on the 31st", now).RenewalDate)
+
+	now = time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "2025-02-28", ParseQuickAdd("Gym 20 on the 31st", now).RenewalDate)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+)
+
+// settingKeyRateAlertBaseline stores the exchange rates the next comparison is made against
+const settingKeyRateAlertBaseline = "rate_alert_baseline"
+
+// rateAlertPeriod is how long rates are compared over ("month-over-month")
+const rateAlertPeriod = 30 * 24 * time.Hour
+
+// DefaultRateAlertThreshold is the default minimum rate change in percent that triggers an alert
+const DefaultRateAlertThreshold = 5.0
+
+// rateBaseline is a snapshot of rates from each foreign currency to the display currency
+type rateBaseline struct {
+	Currency string             `json:"currency"`
+	Date     time.Time          `json:"date"`
+	Rates    map[string]float64 `json:"rates"`
+}
+
+// RateAlertSubscription is a subscription whose effective cost changed with the rate
+type RateAlertSubscription struct {
+	ID             uint    `json:"id"`
+	Name           string  `json:"name"`
+	MonthlyCost    float64 `json:"monthly_cost"` // in the subscription's own currency
+	OldMonthlyCost float64 `json:"old_monthly_cost"`
+	NewMonthlyCost float64 `json:"new_monthly_cost"`
+}
+
+// RateChange describes how much one foreign currency moved against the display currency
+type RateChange struct {
+	Currency      string                  `json:"currency"`
+	OldRate       float64                 `json:"old_rate"`
+	NewRate       float64                 `json:"new_rate"`
+	ChangePercent float64                 `json:"change_percent"`
+	Subscriptions []RateAlertSubscription `json:"subscriptions"`
+}
+
+// RateAlert lists the currencies that moved beyond the threshold since the last comparison
+type RateAlert struct {
+	Currency string       `json:"currency"`
+	Since    time.Time    `json:"since"`
+	Changes  []RateChange `json:"changes"`
+}
+
+// RateAlertService detects exchange rate moves that silently change the cost of
+// subscriptions billed in a foreign currency
+type RateAlertService struct {
+	subscriptions SubscriptionServiceInterface
+	currency      CurrencyServiceInterface
+	preferences   PreferencesServiceInterface
+	settings      *SettingsService
+}
+
+func NewRateAlertService(subscriptions SubscriptionServiceInterface, currency CurrencyServiceInterface, preferences PreferencesServiceInterface, settings *SettingsService) *RateAlertService {
+	return &RateAlertService{
+		subscriptions: subscriptions,
+		currency:      currency,
+		preferences:   preferences,
+		settings:      settings,
+	}
+}
+
+// Check compares current rates with the stored baseline once per period. It
+// returns nil when no comparison is due or no currency moved by at least
+// thresholdPercent. A new baseline is stored whenever a comparison was made,
+// and also when the display currency changed or no baseline exists yet.
+func (s *RateAlertService) Check(ctx context.Context, thresholdPercent float64, now time.Time) (*RateAlert, error) {
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	display := s.preferences.GetCurrency()
+	current := &rateBaseline{Currency: display, Date: now, Rates: make(map[string]float64)}
+	for _, sub := range subscriptions {
+		if sub.Status != "Active" || sub.OriginalCurrency == "" || sub.OriginalCurrency == display {
+			continue
+		}
+		if _, seen := current.Rates[sub.OriginalCurrency]; seen {
+			continue
+		}
+		rate, err := s.currency.GetExchangeRate(sub.OriginalCurrency, display)
+		if err != nil {
+			slog.Warn("no exchange rate for rate alert", "from", sub.OriginalCurrency, "to", display, "error", err)
+			continue
+		}
+		current.Rates[sub.OriginalCurrency] = rate
+	}
+
+	baseline := s.loadBaseline()
+	if baseline == nil || baseline.Currency != display {
+		return nil, s.saveBaseline(current)
+	}
+	if now.Sub(baseline.Date) < rateAlertPeriod {
+		// Currencies of new subscriptions join the current period
+		added := false
+		for currency, rate := range current.Rates {
+			if _, ok := baseline.Rates[currency]; !ok {
+				baseline.Rates[currency] = rate
+				added = true
+			}
+		}
+		if added {
+			return nil, s.saveBaseline(baseline)
+		}
+		return nil, nil
+	}
+
+	alert := &RateAlert{Currency: display, Since: baseline.Date}
+	for currency, newRate := range current.Rates {
+		oldRate, ok := baseline.Rates[currency]
+		if !ok || oldRate == 0 {
+			continue
+		}
+		change := (newRate - oldRate) / oldRate * 100
+		if math.Abs(change) < thresholdPercent {
+			continue
+		}
+
+		rc := RateChange{Currency: currency, OldRate: oldRate, NewRate: newRate, ChangePercent: change}
+		for _, sub := range subscriptions {
+			if sub.Status != "Active" || sub.OriginalCurrency != currency {
+				continue
+			}
+			monthly := sub.MonthlyCost()
+			rc.Subscriptions = append(rc.Subscriptions, RateAlertSubscription{
+				ID:             sub.ID,
+				Name:           sub.Name,
+				MonthlyCost:    monthly,
+				OldMonthlyCost: monthly * oldRate,
+				NewMonthlyCost: monthly * newRate,
+			})
+		}
+		alert.Changes = append(alert.Changes, rc)
+	}
+	sort.Slice(alert.Changes, func(i, j int) bool {
+		return math.Abs(alert.Changes[i].ChangePercent) > math.Abs(alert.Changes[j].ChangePercent)
+	})
+
+	if err := s.saveBaseline(current); err != nil {
+		return nil, err
+	}
+	if len(alert.Changes) == 0 {
+		return nil, nil
+	}
+	return alert, nil
+}
+
+func (s *RateAlertService) loadBaseline() *rateBaseline {
+	raw, ok := s.settings.GetCached(settingKeyRateAlertBaseline)
+	if !ok || raw == "" {
+		return nil
+	}
+	var baseline rateBaseline
+	if err := json.Unmarshal([]byte(raw), &baseline); err != nil {
+		slog.Warn("invalid rate alert baseline, starting over", "error", err)
+		return nil
+	}
+	return &baseline
+}
+
+func (s *RateAlertService) saveBaseline(baseline *rateBaseline) error {
+	data, err := json.Marshal(baseline)
+	if err != nil {
+		return err
+	}
+	defer s.settings.InvalidateCache()
+	return s.settings.Repo().Set(settingKeyRateAlertBaseline, string(data))
+}
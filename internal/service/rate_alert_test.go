@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRates is a CurrencyServiceInterface with fixed rates to the display currency
+type fakeRates map[string]float64
+
+func (f fakeRates) GetExchangeRate(from, to string) (float64, error) { return f[from], nil }
+func (f fakeRates) ConvertAmount(amount float64, from, to string) (float64, error) {
+	return amount * f[from], nil
+}
+func (f fakeRates) RefreshRates(context.Context) error      { return nil }
+func (f fakeRates) SetManualRates(map[string]float64) error { return nil }
+func (f fakeRates) ManualRateEntries() []ExchangeRateEntry  { return nil }
+func (f fakeRates) GetStatus() ExchangeRateStatus           { return ExchangeRateStatus{} }
+
+func TestRateAlertService_Check(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	services := newTestSubscriptionService(t, db)
+	require.NoError(t, services.preferences.SetCurrency("EUR"))
+
+	for _, sub := range []*models.Subscription{
+		{Name: "GitHub", Cost: 120, Schedule: "Annual", Status: "Active", OriginalCurrency: "USD"},
+		{Name: "Spotify", Cost: 10, Schedule: "Monthly", Status: "Active", OriginalCurrency: "GBP"},
+		{Name: "Local", Cost: 5, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR"},
+	} {
+		_, err := services.subscriptions.Create(t.Context(), sub)
+		require.NoError(t, err)
+	}
+
+	rates := fakeRates{"USD": 0.90, "GBP": 1.15}
+	alerts := NewRateAlertService(services.subscriptions, rates, services.preferences, services.settings)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The first check only records the baseline
+	alert, err := alerts.Check(t.Context(), 5, start)
+	require.NoError(t, err)
+	assert.Nil(t, alert)
+
+	// Not due before a month has passed, even with a large move
+	rates["USD"] = 1.00
+	alert, err = alerts.Check(t.Context(), 5, start.AddDate(0, 0, 10))
+	require.NoError(t, err)
+	assert.Nil(t, alert)
+
+	// USD moved by 11%, GBP by less than the threshold
+	rates["GBP"] = 1.16
+	alert, err = alerts.Check(t.Context(), 5, start.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	require.NotNil(t, alert)
+	assert.Equal(t, "EUR", alert.Currency)
+	require.Len(t, alert.Changes, 1)
+	change := alert.Changes[0]
+	assert.Equal(t, "USD", change.Currency)
+	assert.InDelta(t, 11.1, change.ChangePercent, 0.1)
+	require.Len(t, change.Subscriptions, 1)
+	assert.Equal(t, "GitHub", change.Subscriptions[0].Name)
+	assert.InDelta(t, 9.0, change.Subscriptions[0].OldMonthlyCost, 0.001)
+	assert.InDelta(t, 10.0, change.Subscriptions[0].NewMonthlyCost, 0.001)
+
+	// The comparison starts a new period from the current rates
+	alert, err = alerts.Check(t.Context(), 5, start.AddDate(0, 2, 0))
+	require.NoError(t, err)
+	assert.Nil(t, alert)
+}
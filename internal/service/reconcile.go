@@ -0,0 +1,492 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// reconcilePreviewTTL is how long a reconciled statement can be confirmed
+const reconcilePreviewTTL = 30 * time.Minute
+
+// reconcileDueWindow is how many days a charge may be booked before or after
+// the renewal it pays
+const reconcileDueWindow = 7
+
+// Matching thresholds. A charge matches a subscription when the merchant name
+// clearly matches, or when it roughly matches and the amount is close.
+const (
+	reconcileNameMatch   = 0.8
+	reconcileNamePartial = 0.5
+	reconcileAmountClose = 0.9
+	// reconcileAmountTolerance is the relative difference at which amounts no longer match at all
+	reconcileAmountTolerance = 0.2
+	// recurringAmountTolerance is how much repeated unmatched charges may differ
+	recurringAmountTolerance = 0.1
+)
+
+// ErrReconcilePreviewNotFound is returned when a reconciled statement does not exist or has expired
+var ErrReconcilePreviewNotFound = errors.New("reconciliation preview not found or expired")
+
+// ReconcileMatch is a statement charge matched to a subscription
+type ReconcileMatch struct {
+	Transaction    BankTransaction `json:"transaction"`
+	SubscriptionID uint            `json:"subscription_id"`
+	Name           string          `json:"name"`
+	Expected       float64         `json:"expected"`   // Expected charge in the statement currency
+	DueDate        time.Time       `json:"due_date"`   // Renewal the charge is recorded for
+	Confidence     float64         `json:"confidence"` // 0 to 1
+	Recorded       bool            `json:"recorded"`   // The renewal is already confirmed in the payment ledger
+}
+
+// RecurringCharge is a repeating statement charge that matches no subscription,
+// a candidate for a forgotten subscription
+type RecurringCharge struct {
+	Merchant    string    `json:"merchant"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency"`
+	Schedule    string    `json:"schedule"`
+	Occurrences int       `json:"occurrences"`
+	LastDate    time.Time `json:"last_date"`
+}
+
+// ReconcilePreview is the outcome of matching a bank statement. Nothing is
+// written until the preview is confirmed with its token.
+type ReconcilePreview struct {
+	Token      string            `json:"token"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+	Charges    int               `json:"charges"`
+	Matches    []ReconcileMatch  `json:"matches"`
+	Unmatched  int               `json:"unmatched"`
+	Candidates []RecurringCharge `json:"candidates"`
+}
+
+// ReconcileResult reports what confirming a reconciliation wrote to the payment ledger
+type ReconcileResult struct {
+	Recorded int `json:"recorded"`
+	Skipped  int `json:"skipped"`
+}
+
+type stagedReconcile struct {
+	matches   []ReconcileMatch
+	expiresAt time.Time
+}
+
+// ReconcileService matches bank statement charges to subscriptions and records
+// the matched ones in the payment ledger
+type ReconcileService struct {
+	payments      *repository.PaymentRepository
+	subscriptions SubscriptionServiceInterface
+	currency      CurrencyServiceInterface
+	preferences   PreferencesServiceInterface
+
+	mu      sync.Mutex
+	staging map[string]stagedReconcile
+}
+
+func NewReconcileService(payments *repository.PaymentRepository, subscriptions SubscriptionServiceInterface, currency CurrencyServiceInterface, preferences PreferencesServiceInterface) *ReconcileService {
+	return &ReconcileService{
+		payments:      payments,
+		subscriptions: subscriptions,
+		currency:      currency,
+		preferences:   preferences,
+		staging:       make(map[string]stagedReconcile),
+	}
+}
+
+// Preview parses a CSV or OFX bank statement (empty format is detected),
+// matches its charges to active and cancelled subscriptions and lists unmatched
+// charges that repeat like a subscription. Statements without a currency are
+// taken to be in the display currency.
+func (s *ReconcileService) Preview(ctx context.Context, data []byte, format string) (*ReconcilePreview, error) {
+	transactions, err := ParseBankStatement(data, format)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	preview, err := s.analyze(ctx, transactions, now)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := generateImportToken()
+	if err != nil {
+		return nil, err
+	}
+	preview.Token = token
+	preview.ExpiresAt = now.Add(reconcilePreviewTTL)
+
+	s.mu.Lock()
+	s.pruneExpiredLocked()
+	s.staging[token] = stagedReconcile{matches: preview.Matches, expiresAt: preview.ExpiresAt}
+	s.mu.Unlock()
+
+	return preview, nil
+}
+
+// Record matches transactions to subscriptions and records the matches with at
+// least minConfidence in the payment ledger right away. It returns what was
+// recorded and the unmatched recurring charges.
+func (s *ReconcileService) Record(ctx context.Context, transactions []BankTransaction, minConfidence float64) (*ReconcileResult, []RecurringCharge, error) {
+	preview, err := s.analyze(ctx, transactions, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var matches []ReconcileMatch
+	for _, match := range preview.Matches {
+		if match.Confidence >= minConfidence {
+			matches = append(matches, match)
+		}
+	}
+	result, err := s.record(matches)
+	return result, preview.Candidates, err
+}
+
+// analyze matches the charges among transactions to subscriptions and collects
+// the unmatched recurring charges
+func (s *ReconcileService) analyze(ctx context.Context, transactions []BankTransaction, now time.Time) (*ReconcilePreview, error) {
+	subs, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Statements with both signs list charges as negative amounts
+	hasDebits := false
+	for _, tx := range transactions {
+		if tx.Amount < 0 {
+			hasDebits = true
+			break
+		}
+	}
+
+	display := s.preferences.GetCurrency()
+	preview := &ReconcilePreview{}
+	var unmatched []BankTransaction
+	for _, tx := range transactions {
+		if tx.Amount == 0 || (hasDebits && tx.Amount > 0) {
+			continue
+		}
+		tx.Amount = math.Abs(tx.Amount)
+		if tx.Currency == "" {
+			tx.Currency = display
+		}
+		preview.Charges++
+
+		match, ok := s.match(tx, subs, now)
+		if !ok {
+			unmatched = append(unmatched, tx)
+			continue
+		}
+		if payment, err := s.payments.FindByDueDate(match.SubscriptionID, match.DueDate); err == nil {
+			match.Recorded = payment.Status == models.PaymentConfirmed
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		preview.Matches = append(preview.Matches, match)
+	}
+	preview.Unmatched = len(unmatched)
+	preview.Candidates = recurringCharges(unmatched)
+	return preview, nil
+}
+
+// Confirm records the matches of a previewed statement in the payment ledger
+// with the charged amount and booking date. selected lists the indexes of the
+// matches to record; nil records all. Pending and missed renewals are
+// confirmed, renewals without a payment are added and confirmed ones skipped.
+func (s *ReconcileService) Confirm(token string, selected []int) (*ReconcileResult, error) {
+	s.mu.Lock()
+	staged, ok := s.staging[token]
+	delete(s.staging, token)
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(staged.expiresAt) {
+		return nil, ErrReconcilePreviewNotFound
+	}
+
+	matches := staged.matches
+	if selected != nil {
+		matches = nil
+		for _, i := range selected {
+			if i >= 0 && i < len(staged.matches) {
+				matches = append(matches, staged.matches[i])
+			}
+		}
+	}
+	return s.record(matches)
+}
+
+// record adds matches to the payment ledger, skipping renewals already confirmed
+func (s *ReconcileService) record(matches []ReconcileMatch) (*ReconcileResult, error) {
+	result := &ReconcileResult{}
+	for _, match := range matches {
+		payment, err := s.payments.FindByDueDate(match.SubscriptionID, match.DueDate)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			payment = &models.Payment{SubscriptionID: match.SubscriptionID, DueDate: match.DueDate}
+		case err != nil:
+			return result, err
+		case payment.Status == models.PaymentConfirmed:
+			result.Skipped++
+			continue
+		}
+
+		paidAt := match.Transaction.Date
+		payment.Amount = roundCents(match.Transaction.Amount)
+		payment.Currency = match.Transaction.Currency
+		payment.PaidAt = &paidAt
+		payment.Status = models.PaymentConfirmed
+		if err := s.payments.Save(payment); err != nil {
+			return result, err
+		}
+		result.Recorded++
+	}
+	return result, nil
+}
+
+// match finds the subscription a charge most likely pays
+func (s *ReconcileService) match(tx BankTransaction, subs []models.Subscription, now time.Time) (ReconcileMatch, bool) {
+	var best ReconcileMatch
+	found := false
+	for i := range subs {
+		sub := &subs[i]
+		if sub.Status != "Active" && sub.Status != "Cancelled" {
+			continue
+		}
+
+		expected := roundCents(s.convert(sub.GrossCost(), sub.OriginalCurrency, tx.Currency))
+		name := merchantNameScore(sub.Name, tx.Description)
+		amount := amountScore(tx.Amount, expected)
+		if name < reconcileNameMatch && (name < reconcileNamePartial || amount < reconcileAmountClose) {
+			continue
+		}
+
+		confidence := 0.7*name + 0.3*amount
+		if found && confidence <= best.Confidence {
+			continue
+		}
+		best = ReconcileMatch{
+			Transaction:    tx,
+			SubscriptionID: sub.ID,
+			Name:           sub.Name,
+			Expected:       expected,
+			DueDate:        nearestChargeDate(sub, tx.Date, now),
+			Confidence:     math.Round(confidence*100) / 100,
+		}
+		found = true
+	}
+	return best, found
+}
+
+// convert converts an amount between currencies, keeping it unchanged if no rate is available
+func (s *ReconcileService) convert(amount float64, from, to string) float64 {
+	if from == "" || from == to {
+		return amount
+	}
+	converted, err := s.currency.ConvertAmount(amount, from, to)
+	if err != nil {
+		return amount
+	}
+	return converted
+}
+
+// pruneExpiredLocked removes expired staged statements. Caller must hold s.mu.
+func (s *ReconcileService) pruneExpiredLocked() {
+	now := time.Now()
+	for token, staged := range s.staging {
+		if now.After(staged.expiresAt) {
+			delete(s.staging, token)
+		}
+	}
+}
+
+// nearestChargeDate returns the renewal of a subscription closest to a booking
+// date, or the booking date itself when no renewal is within reconcileDueWindow
+func nearestChargeDate(sub *models.Subscription, booked, now time.Time) time.Time {
+	from := booked.AddDate(0, 0, -reconcileDueWindow)
+	to := booked.AddDate(0, 0, reconcileDueWindow+1)
+	if to.After(now) {
+		now = to
+	}
+
+	nearest, found := booked, false
+	for _, due := range chargeDates(sub, from, to, now) {
+		if !found || math.Abs(due.Sub(booked).Hours()) < math.Abs(nearest.Sub(booked).Hours()) {
+			nearest, found = due, true
+		}
+	}
+	return nearest
+}
+
+// merchantNameScore rates from 0 to 1 how well a subscription name matches the
+// text of a bank transaction. Names contained in the text score 1; otherwise
+// the share of name words found in the text, or the best fuzzy match of the
+// name against runs of words in the text, scaled down.
+func merchantNameScore(name, description string) float64 {
+	nameWords := normalizedWords(name)
+	words := normalizedWords(description)
+	compactName := strings.Join(nameWords, "")
+	if len(compactName) < 3 || len(words) == 0 {
+		return 0
+	}
+	if strings.Contains(strings.Join(words, ""), compactName) {
+		return 1
+	}
+
+	found := 0
+	for _, nameWord := range nameWords {
+		for _, word := range words {
+			if len(nameWord) >= 3 && (strings.HasPrefix(word, nameWord) || similarity(nameWord, word) >= 0.8) {
+				found++
+				break
+			}
+		}
+	}
+	best := float64(found) / float64(len(nameWords))
+
+	for size := 1; size <= len(nameWords)+1; size++ {
+		for i := 0; i+size <= len(words); i++ {
+			best = max(best, similarity(compactName, strings.Join(words[i:i+size], "")))
+		}
+	}
+	return 0.9 * best
+}
+
+// normalizedWords lowercases text and splits it into letter and digit runs
+func normalizedWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// similarity is 1 minus the Levenshtein distance relative to the longer string
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(max(len(ra), len(rb)))
+}
+
+// amountScore rates from 0 to 1 how close a charge is to the expected amount
+func amountScore(charged, expected float64) float64 {
+	if expected <= 0 {
+		return 0
+	}
+	diff := math.Abs(charged-expected) / expected
+	return math.Max(0, 1-diff/reconcileAmountTolerance)
+}
+
+// recurringCharges groups charges by merchant and returns the groups that
+// repeat at a regular schedule with similar amounts
+func recurringCharges(charges []BankTransaction) []RecurringCharge {
+	groups := make(map[string][]BankTransaction)
+	var keys []string
+	for _, tx := range charges {
+		key := merchantKey(tx.Description)
+		if key == "" {
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], tx)
+	}
+
+	var candidates []RecurringCharge
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].Date.Before(group[j].Date) })
+		last := group[len(group)-1]
+
+		schedule := ""
+		similar := true
+		for i := 1; i < len(group); i++ {
+			if math.Abs(group[i].Amount-last.Amount) > recurringAmountTolerance*last.Amount {
+				similar = false
+				break
+			}
+			gap := scheduleForGap(int(math.Round(group[i].Date.Sub(group[i-1].Date).Hours() / 24)))
+			if gap == "" || (schedule != "" && gap != schedule) {
+				similar = false
+				break
+			}
+			schedule = gap
+		}
+		if !similar {
+			continue
+		}
+
+		candidates = append(candidates, RecurringCharge{
+			Merchant:    last.Description,
+			Amount:      roundCents(last.Amount),
+			Currency:    last.Currency,
+			Schedule:    schedule,
+			Occurrences: len(group),
+			LastDate:    last.Date,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Amount > candidates[j].Amount })
+	return candidates
+}
+
+// merchantKey identifies the merchant of a transaction by its first words,
+// ignoring words with digits such as references and card numbers
+func merchantKey(description string) string {
+	var words []string
+	for _, word := range normalizedWords(description) {
+		if strings.IndexFunc(word, unicode.IsDigit) >= 0 {
+			continue
+		}
+		words = append(words, word)
+		if len(words) == 3 {
+			break
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// scheduleForGap returns the subscription schedule a number of days between two
+// charges fits, or "" if none
+func scheduleForGap(days int) string {
+	switch {
+	case days >= 6 && days <= 8:
+		return "Weekly"
+	case days >= 26 && days <= 33:
+		return "Monthly"
+	case days >= 85 && days <= 95:
+		return "Quarterly"
+	case days >= 358 && days <= 372:
+		return "Annual"
+	}
+	return ""
+}
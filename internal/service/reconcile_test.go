@@ -0,0 +1,160 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupReconcileService(t *testing.T) (*ReconcileService, *PaymentService, *SubscriptionService) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Payment{}))
+	services := newTestSubscriptionService(t, db)
+	paymentRepo := repository.NewPaymentRepository(db)
+	return NewReconcileService(paymentRepo, services.subscriptions, services.currency, services.preferences),
+		NewPaymentService(paymentRepo, services.subscriptions), services.subscriptions
+}
+
+func TestParseBankStatement_CSV(t *testing.T) {
+	data := "\xef\xbb\xbfKonto;DE123\n\nBuchungstag;Empfänger;Verwendungszweck;Betrag;Währung\n" +
+		"03.05.2026;NETFLIX.COM;Abo 123;-12,99;EUR\n" +
+		"04.05.2026;Arbeitgeber;Gehalt;2.500,00;EUR\n" +
+		"Summe;;;;\n"
+
+	transactions, err := ParseBankStatement([]byte(data), "")
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "NETFLIX.COM Abo 123", transactions[0].Description)
+	assert.Equal(t, -12.99, transactions[0].Amount)
+	assert.Equal(t, "EUR", transactions[0].Currency)
+	assert.Equal(t, time.May, transactions[0].Date.Month())
+	assert.Equal(t, 2500.0, transactions[1].Amount)
+
+	_, err = ParseBankStatement([]byte("just some text"), "")
+	assert.ErrorIs(t, err, ErrUnknownStatementFormat)
+	_, err = ParseBankStatement([]byte("a,b\n1,2\n"), StatementCSV)
+	assert.ErrorIs(t, err, ErrNoTransactions)
+}
+
+func TestParseBankStatement_OFX(t *testing.T) {
+	data := `OFXHEADER:100
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>USD
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260503120000<TRNAMT>-9.99<NAME>SPOTIFY USA<MEMO>Premium</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260510<TRNAMT>-4.50<NAME>Coffee</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+	transactions, err := ParseBankStatement([]byte(data), "")
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "SPOTIFY USA Premium", transactions[0].Description)
+	assert.Equal(t, -9.99, transactions[0].Amount)
+	assert.Equal(t, "USD", transactions[0].Currency)
+	assert.Equal(t, 3, transactions[0].Date.Day())
+}
+
+func TestParseStatementAmount(t *testing.T) {
+	for value, expected := range map[string]float64{
+		"-12.99":    -12.99,
+		"1.234,56":  1234.56,
+		"1,234.56":  1234.56,
+		"12,99 €":   12.99,
+		"(12.99)":   -12.99,
+		"12.99-":    -12.99,
+		"1,000":     1000,
+		"$ -100.00": -100,
+	} {
+		amount, ok := parseStatementAmount(value)
+		assert.True(t, ok, value)
+		assert.InDelta(t, expected, amount, 0.001, value)
+	}
+	_, ok := parseStatementAmount("")
+	assert.False(t, ok)
+}
+
+func TestReconcileService_PreviewAndConfirm(t *testing.T) {
+	reconcile, payments, subscriptions := setupReconcileService(t)
+	now := time.Now()
+	day := func(d time.Time) time.Time { return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local) }
+	start := day(now.AddDate(0, -1, -3))
+	renewal := start.AddDate(0, 1, 0)
+
+	netflix, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 15.99, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", StartDate: &start})
+	require.NoError(t, err)
+	gym, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Fitness First", Cost: 30, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", StartDate: &start})
+	require.NoError(t, err)
+	_, err = subscriptions.Create(t.Context(), &models.Subscription{Name: "Paused Box", Cost: 20, Schedule: "Monthly", Status: "Paused", OriginalCurrency: "EUR", StartDate: &start})
+	require.NoError(t, err)
+
+	// The gym renewal is already pending confirmation
+	created, err := payments.RecordRenewals(t.Context(), now)
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+
+	csvDate := func(d time.Time) string { return d.Format("2006-01-02") }
+	statement := "Date,Description,Amount,Currency\n" +
+		fmt.Sprintf("%s,NETFLIX.COM 866-579-7172,-15.99,EUR\n", csvDate(renewal.AddDate(0, 0, 1))) +
+		fmt.Sprintf("%s,FITNESS FIRST GMBH,-30.00,EUR\n", csvDate(renewal)) +
+		fmt.Sprintf("%s,PAUSED BOX,-20.00,EUR\n", csvDate(renewal)) +
+		fmt.Sprintf("%s,Salary,3000.00,EUR\n", csvDate(renewal)) +
+		// An unknown charge repeating every month is a forgotten subscription
+		fmt.Sprintf("%s,CLOUDSTORE *REF1111,-2.99,EUR\n", csvDate(renewal.AddDate(0, -2, 0))) +
+		fmt.Sprintf("%s,CLOUDSTORE *REF2222,-2.99,EUR\n", csvDate(renewal.AddDate(0, -1, 0))) +
+		fmt.Sprintf("%s,CLOUDSTORE *REF3333,-2.99,EUR\n", csvDate(renewal)) +
+		fmt.Sprintf("%s,Bakery,-3.40,EUR\n", csvDate(renewal))
+
+	preview, err := reconcile.Preview(t.Context(), []byte(statement), "")
+	require.NoError(t, err)
+	assert.Equal(t, 7, preview.Charges)
+	require.Len(t, preview.Matches, 2)
+	assert.Equal(t, 5, preview.Unmatched)
+	for _, match := range preview.Matches {
+		assert.True(t, match.DueDate.Equal(renewal), match.Name)
+		assert.False(t, match.Recorded)
+	}
+	assert.Equal(t, netflix.ID, preview.Matches[0].SubscriptionID)
+	assert.Equal(t, gym.ID, preview.Matches[1].SubscriptionID)
+
+	require.Len(t, preview.Candidates, 1)
+	assert.Equal(t, "Monthly", preview.Candidates[0].Schedule)
+	assert.Equal(t, 3, preview.Candidates[0].Occurrences)
+	assert.Equal(t, 2.99, preview.Candidates[0].Amount)
+
+	result, err := reconcile.Confirm(preview.Token, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Recorded)
+
+	ledger, err := payments.List(t.Context(), 0, models.PaymentConfirmed)
+	require.NoError(t, err)
+	require.Len(t, ledger, 2)
+	unconfirmed, err := payments.Unconfirmed(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, unconfirmed)
+
+	// Tokens are single use and recorded charges are recognised
+	_, err = reconcile.Confirm(preview.Token, nil)
+	assert.ErrorIs(t, err, ErrReconcilePreviewNotFound)
+	preview, err = reconcile.Preview(t.Context(), []byte(statement), StatementCSV)
+	require.NoError(t, err)
+	for _, match := range preview.Matches {
+		assert.True(t, match.Recorded, match.Name)
+	}
+	result, err = reconcile.Confirm(preview.Token, []int{0})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Recorded)
+	assert.Equal(t, 1, result.Skipped)
+}
+
+func TestMerchantNameScore(t *testing.T) {
+	assert.Equal(t, 1.0, merchantNameScore("Netflix", "NETFLIX.COM Los Gatos"))
+	assert.Equal(t, 1.0, merchantNameScore("Disney+", "PAYPAL *DISNEYPLUS"))
+	assert.Greater(t, merchantNameScore("Spotify", "SPOTIFI AB"), reconcileNamePartial)
+	assert.Less(t, merchantNameScore("Netflix", "Bakery Miller"), reconcileNamePartial)
+	assert.Zero(t, merchantNameScore("TV", "TV licence"))
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// ReminderJobs sends renewal, cancellation, grace period, contract,
+// paid-through and promotional price reminders through the channels selected for each subscription
+// and records failed channels for retry. Its methods are the scheduler's
+// reminder jobs and take the current time so they can be tested.
+type ReminderJobs struct {
+	subscriptions *SubscriptionService
+	notifier      NotificationDispatcherInterface
+	hooks         HookServiceInterface
+	retries       *ReminderRetryService
+}
+
+func NewReminderJobs(subscriptions *SubscriptionService, notifier NotificationDispatcherInterface, hooks HookServiceInterface, retries *ReminderRetryService) *ReminderJobs {
+	return &ReminderJobs{subscriptions: subscriptions, notifier: notifier, hooks: hooks, retries: retries}
+}
+
+// reminderSelection finds the subscriptions due for one kind of reminder as
+// of now and the date each reminder is about
+type reminderSelection struct {
+	kind    string
+	label   string
+	find    func(ctx context.Context, now time.Time) (map[*models.Subscription]int, error)
+	dueDate func(sub *models.Subscription, now time.Time) time.Time
+}
+
+// reminderSelections lists the reminder kinds in the order they are sent
+func reminderSelections(subscriptions *SubscriptionService) []reminderSelection {
+	return []reminderSelection{
+		{models.ReminderKindRenewal, "renewal", subscriptions.GetSubscriptionsNeedingReminders,
+			func(sub *models.Subscription, _ time.Time) time.Time { return *sub.RenewalDate }},
+		{models.ReminderKindCancellation, "cancellation", subscriptions.GetSubscriptionsNeedingCancellationReminders,
+			func(sub *models.Subscription, _ time.Time) time.Time { return *sub.CancellationDate }},
+		{models.ReminderKindGracePeriod, "grace period", subscriptions.GetSubscriptionsNeedingGracePeriodReminders,
+			func(sub *models.Subscription, _ time.Time) time.Time { return *sub.GracePeriodEnd }},
+		{models.ReminderKindContract, "contract", subscriptions.GetSubscriptionsNeedingContractReminders,
+			func(sub *models.Subscription, now time.Time) time.Time { return *sub.ContractDecideBy(now) }},
+		{models.ReminderKindPaidThrough, "paid-through", subscriptions.GetSubscriptionsNeedingPaidThroughReminders,
+			func(sub *models.Subscription, _ time.Time) time.Time { return *sub.PaidThroughDate }},
+		{models.ReminderKindPromoEnd, "promo", subscriptions.GetSubscriptionsNeedingPromoReminders,
+			func(sub *models.Subscription, _ time.Time) time.Time { return *sub.PromoEndDate }},
+	}
+}
+
+// SendRenewalReminders sends the due renewal reminders through each subscription's channels: email,
+// Shoutrrr and hooks listening to renewal.imminent. Reminders with a pending retry are left to
+// RetryFailed.
+func (r *ReminderJobs) SendRenewalReminders(ctx context.Context, now time.Time) error {
+	return r.sendKind(ctx, models.ReminderKindRenewal, now)
+}
+
+// SendCancellationReminders sends the due cancellation reminders
+func (r *ReminderJobs) SendCancellationReminders(ctx context.Context, now time.Time) error {
+	return r.sendKind(ctx, models.ReminderKindCancellation, now)
+}
+
+// SendGracePeriodReminders reminds of failed payments whose service cutoff is near
+func (r *ReminderJobs) SendGracePeriodReminders(ctx context.Context, now time.Time) error {
+	return r.sendKind(ctx, models.ReminderKindGracePeriod, now)
+}
+
+// SendContractReminders reminds of contracts whose decision deadline is near
+func (r *ReminderJobs) SendContractReminders(ctx context.Context, now time.Time) error {
+	return r.sendKind(ctx, models.ReminderKindContract, now)
+}
+
+// SendPaidThroughReminders reminds of cancelled subscriptions whose paid period ends soon
+func (r *ReminderJobs) SendPaidThroughReminders(ctx context.Context, now time.Time) error {
+	return r.sendKind(ctx, models.ReminderKindPaidThrough, now)
+}
+
+// SendPromoReminders reminds of promotional prices that end soon
+func (r *ReminderJobs) SendPromoReminders(ctx context.Context, now time.Time) error {
+	return r.sendKind(ctx, models.ReminderKindPromoEnd, now)
+}
+
+// sendKind selects and sends the due reminders of one kind
+func (r *ReminderJobs) sendKind(ctx context.Context, kind string, now time.Time) error {
+	for _, selection := range reminderSelections(r.subscriptions) {
+		if selection.kind != kind {
+			continue
+		}
+		subscriptions, err := selection.find(ctx, now)
+		if err != nil {
+			slog.Error("failed to get subscriptions for "+selection.label+" reminders", "error", err)
+			return err
+		}
+		return r.sendDue(ctx, selection.kind, selection.label, subscriptions, now, func(sub *models.Subscription) time.Time { return selection.dueDate(sub, now) })
+	}
+	return fmt.Errorf("unknown reminder kind %q", kind)
+}
+
+// sendDue sends the reminders of one kind, skipping those with a pending retry
+func (r *ReminderJobs) sendDue(ctx context.Context, kind, label string, subscriptions map[*models.Subscription]int, now time.Time, dueDate func(*models.Subscription) time.Time) error {
+	if len(subscriptions) == 0 {
+		slog.Info("no subscriptions need " + label + " reminders today")
+		return nil
+	}
+
+	sentCount := 0
+	failedCount := 0
+	for sub, daysUntil := range subscriptions {
+		date := dueDate(sub)
+		if r.retries.Pending(sub.ID, kind, date) {
+			continue
+		}
+		if r.send(ctx, kind, sub, date, daysUntil, "", now) {
+			sentCount++
+		} else {
+			failedCount++
+		}
+	}
+
+	slog.Info(label+" reminder check complete", "sent", sentCount, "failed", failedCount)
+	if failedCount > 0 {
+		return fmt.Errorf("%d of %d %s reminders failed", failedCount, len(subscriptions), label)
+	}
+	return nil
+}
+
+// RetryFailed retries the channels of reminders that failed earlier and whose backoff has elapsed.
+// Retries for reminders that were disabled, moved to another date or whose date has passed are
+// dropped.
+func (r *ReminderJobs) RetryFailed(ctx context.Context, now time.Time) error {
+	due, err := r.retries.Due(now)
+	if err != nil {
+		return err
+	}
+
+	failedCount := 0
+	for _, retry := range due {
+		sub, err := r.subscriptions.GetByID(ctx, retry.SubscriptionID)
+		if err != nil {
+			r.retries.Drop(retry.SubscriptionID, retry.Kind)
+			continue
+		}
+
+		enabled, date := sub.RenewalReminder, sub.RenewalDate
+		switch retry.Kind {
+		case models.ReminderKindCancellation:
+			enabled, date = sub.CancellationReminder, sub.CancellationDate
+		case models.ReminderKindGracePeriod:
+			enabled, date = sub.PaymentFailedAt != nil, sub.GracePeriodEnd
+		case models.ReminderKindContract:
+			enabled, date = sub.Status != "Cancelled", sub.ContractDecideBy(now)
+		case models.ReminderKindPaidThrough:
+			enabled, date = sub.Status == "Cancelled", sub.PaidThroughDate
+		case models.ReminderKindPromoEnd:
+			enabled, date = sub.PromoEndReminder && sub.PromoCost != nil && sub.Status != "Cancelled", sub.PromoEndDate
+		}
+		if !enabled || date == nil || date.Format("2006-01-02") != retry.DueDate.Format("2006-01-02") || daysUntilDate(*date, now) < 0 {
+			slog.Info("dropping reminder retry that no longer applies", "subscription", sub.Name, "kind", retry.Kind)
+			r.retries.Drop(retry.SubscriptionID, retry.Kind)
+			continue
+		}
+
+		slog.Info("retrying reminder", "subscription", sub.Name, "kind", retry.Kind, "attempt", retry.Attempts+1, "channels", retry.Channels)
+		if !r.send(ctx, retry.Kind, sub, *date, daysUntilDate(*date, now), retry.Channels, now) {
+			failedCount++
+		}
+	}
+
+	if failedCount > 0 {
+		return fmt.Errorf("%d of %d reminder retries failed", failedCount, len(due))
+	}
+	return nil
+}
+
+// send delivers a reminder of the given kind. only restricts delivery to a
+// comma-separated list of channels when retrying. The reminder is marked as
+// sent once any channel delivered; failed channels are scheduled for retry.
+// It reports whether every attempted channel succeeded.
+func (r *ReminderJobs) send(ctx context.Context, kind string, sub *models.Subscription, dueDate time.Time, daysUntil int, only string, now time.Time) bool {
+	senders := r.notifier.Senders(func(n Notifier) error { return SendReminder(n, kind, sub, daysUntil) })
+	if kind == models.ReminderKindRenewal {
+		senders[models.ChannelWebhook] = func() error {
+			if !r.hooks.Has(EventRenewalImminent) {
+				return ErrChannelNotConfigured
+			}
+			r.hooks.Fire(EventRenewalImminent, map[string]interface{}{"subscription": sub, "days_until": daysUntil})
+			return nil
+		}
+	}
+	if only != "" {
+		for channel := range senders {
+			if !slices.Contains(strings.Split(only, ","), channel) {
+				delete(senders, channel)
+			}
+		}
+	}
+
+	delivered, failures := notifySubscription(sub, senders)
+	if len(delivered) > 0 {
+		r.markSent(ctx, kind, sub, now)
+		slog.Info("sent reminder", "kind", kind, "subscription", sub.Name, "daysUntil", daysUntil, "channels", delivered)
+	}
+
+	retry, err := r.retries.RecordResult(sub.ID, kind, dueDate, failures, now)
+	if err != nil {
+		slog.Warn("failed to record reminder retry", "subscription", sub.Name, "kind", kind, "error", err)
+	} else if retry != nil && retry.Attempts < MaxReminderAttempts {
+		slog.Info("reminder scheduled for retry", "subscription", sub.Name, "kind", kind, "channels", retry.Channels, "next_attempt", retry.NextAttemptAt)
+	}
+
+	if len(failures) > 0 {
+		slog.Error("failed to send reminder", "kind", kind, "subscription", sub.Name, "id", sub.ID, "failures", failures)
+		return false
+	}
+	return true
+}
+
+// markSent records that the reminder for the subscription's current date was sent
+func (r *ReminderJobs) markSent(ctx context.Context, kind string, sub *models.Subscription, now time.Time) {
+	switch kind {
+	case models.ReminderKindRenewal:
+		sub.LastReminderSent = &now
+		if sub.RenewalDate != nil {
+			renewalDateCopy := *sub.RenewalDate
+			sub.LastReminderRenewalDate = &renewalDateCopy
+		}
+	case models.ReminderKindCancellation:
+		sub.LastCancellationReminderSent = &now
+		if sub.CancellationDate != nil {
+			cancellationDateCopy := *sub.CancellationDate
+			sub.LastCancellationReminderDate = &cancellationDateCopy
+		}
+	case models.ReminderKindGracePeriod:
+		if sub.GracePeriodEnd != nil {
+			gracePeriodEndCopy := *sub.GracePeriodEnd
+			sub.LastGraceReminderDate = &gracePeriodEndCopy
+		}
+	case models.ReminderKindContract:
+		sub.LastContractReminderDate = sub.ContractDecideBy(now)
+	case models.ReminderKindPaidThrough:
+		if sub.PaidThroughDate != nil {
+			paidThroughCopy := *sub.PaidThroughDate
+			sub.LastPaidThroughReminderDate = &paidThroughCopy
+		}
+	case models.ReminderKindPromoEnd:
+		if sub.PromoEndDate != nil {
+			promoEndCopy := *sub.PromoEndDate
+			sub.LastPromoReminderDate = &promoEndCopy
+		}
+	}
+
+	if _, err := r.subscriptions.Update(ctx, sub.ID, sub); err != nil {
+		slog.Warn("failed to update last reminder sent", "kind", kind, "subscription", sub.Name, "id", sub.ID, "error", err)
+	}
+}
+
+// notifySubscription sends a notification through the channels selected for a
+// subscription. send maps channels to their sender; channels that are not
+// configured are skipped. It returns the channels that delivered and the
+// errors of selected channels that failed.
+func notifySubscription(sub *models.Subscription, send map[string]func() error) ([]string, map[string]error) {
+	var delivered []string
+	failures := make(map[string]error)
+	for _, channel := range models.NotificationChannels {
+		fn, ok := send[channel]
+		if !ok || !sub.NotifiesVia(channel) {
+			continue
+		}
+		err := fn()
+		switch {
+		case err == nil:
+			delivered = append(delivered, channel)
+		case errors.Is(err, ErrChannelNotConfigured):
+			slog.Debug("skipping unconfigured notification channel", "subscription", sub.Name, "channel", channel)
+		default:
+			failures[channel] = err
+		}
+	}
+	if len(delivered) == 0 && len(failures) == 0 {
+		failures["none"] = errors.New("no selected notification channel is configured")
+	}
+	return delivered, failures
+}
+
+// daysUntilDate returns the number of calendar days from now until date
+func daysUntilDate(date, now time.Time) int {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	return int(day.Sub(today).Hours() / 24)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReminderJobs_SendAndRetry(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ReminderRetry{}))
+	services := newTestSubscriptionService(t, db)
+
+	email := &fakeNotifier{channel: models.ChannelEmail}
+	push := &fakeNotifier{channel: models.ChannelShoutrrr, err: errors.New("connection refused")}
+	jobs := NewReminderJobs(services.subscriptions, NewNotificationDispatcher(email, push), NewHookService(),
+		NewReminderRetryService(repository.NewReminderRetryRepository(db)))
+
+	now := time.Now()
+	sub, err := services.subscriptions.Create(t.Context(), &models.Subscription{
+		Name: "Netflix", Cost: 15, Schedule: "Monthly", Status: "Active",
+		RenewalDate: timePtr(now.AddDate(0, 0, 3)), RenewalReminder: true, RenewalReminderDays: 7,
+	})
+	require.NoError(t, err)
+
+	// Email delivers, Shoutrrr fails and is queued for retry
+	assert.Error(t, jobs.SendRenewalReminders(t.Context(), now))
+	assert.Equal(t, []string{"renewal"}, email.sent)
+	assert.Equal(t, []string{"renewal"}, push.sent)
+	saved, err := services.subscriptions.GetByID(t.Context(), sub.ID)
+	require.NoError(t, err)
+	require.NotNil(t, saved.LastReminderSent)
+	assert.Equal(t, now.Unix(), saved.LastReminderSent.Unix())
+
+	// Already sent for this renewal date
+	require.NoError(t, jobs.SendRenewalReminders(t.Context(), now))
+	assert.Len(t, email.sent, 1)
+
+	// Nothing is due before the backoff elapsed, afterwards only Shoutrrr is retried
+	require.NoError(t, jobs.RetryFailed(t.Context(), now))
+	assert.Len(t, push.sent, 1)
+	push.err = nil
+	require.NoError(t, jobs.RetryFailed(t.Context(), now.Add(time.Hour)))
+	assert.Len(t, email.sent, 1)
+	assert.Equal(t, []string{"renewal", "renewal"}, push.sent)
+	require.NoError(t, jobs.RetryFailed(t.Context(), now.Add(2*time.Hour)))
+	assert.Len(t, push.sent, 2)
+}
+
+func TestDaysUntilDate(t *testing.T) {
+	now := time.Date(2026, 3, 10, 23, 30, 0, 0, time.UTC)
+	assert.Equal(t, 0, daysUntilDate(time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), now))
+	assert.Equal(t, 1, daysUntilDate(time.Date(2026, 3, 11, 8, 0, 0, 0, time.UTC), now))
+	assert.Equal(t, -1, daysUntilDate(time.Date(2026, 3, 9, 8, 0, 0, 0, time.UTC), now))
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// MaxReminderAttempts is how often a reminder is attempted on a channel before
+// the scheduler gives up on it
+const MaxReminderAttempts = 6
+
+// reminderRetryBaseDelay is the wait before the first retry; it doubles with
+// every further failed attempt up to reminderRetryMaxDelay
+const (
+	reminderRetryBaseDelay = 30 * time.Minute
+	reminderRetryMaxDelay  = 12 * time.Hour
+)
+
+// ReminderRetryService keeps track of reminders that failed on some of their
+// channels. Failed channels are retried with exponential backoff on later
+// scheduler runs, carrying over to the next days until the reminder's date has
+// passed or MaxReminderAttempts is reached.
+type ReminderRetryService struct {
+	repo *repository.ReminderRetryRepository
+}
+
+func NewReminderRetryService(repo *repository.ReminderRetryRepository) *ReminderRetryService {
+	return &ReminderRetryService{repo: repo}
+}
+
+// Pending reports whether the reminder for dueDate is handled by a retry,
+// either waiting for its next attempt or given up after too many failures.
+// Regular reminder runs skip pending reminders so delivered channels are not
+// repeated.
+func (s *ReminderRetryService) Pending(subscriptionID uint, kind string, dueDate time.Time) bool {
+	retry, err := s.repo.Get(subscriptionID, kind)
+	if err != nil {
+		return false
+	}
+	return sameDay(retry.DueDate, dueDate)
+}
+
+// RecordResult stores the outcome of a reminder attempt. Failed channels are
+// scheduled for another attempt; once nothing is left to retry the record is
+// removed. Failures that are not a notification channel (e.g. "no channel
+// configured") are not retried.
+func (s *ReminderRetryService) RecordResult(subscriptionID uint, kind string, dueDate time.Time, failures map[string]error, now time.Time) (*models.ReminderRetry, error) {
+	var channels, messages []string
+	for channel, err := range failures {
+		if !slices.Contains(models.NotificationChannels, channel) {
+			continue
+		}
+		channels = append(channels, channel)
+		messages = append(messages, fmt.Sprintf("%s: %v", channel, err))
+	}
+	if len(channels) == 0 {
+		return nil, s.Drop(subscriptionID, kind)
+	}
+	sort.Strings(channels)
+	sort.Strings(messages)
+
+	retry, err := s.repo.Get(subscriptionID, kind)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if retry == nil {
+		retry = &models.ReminderRetry{SubscriptionID: subscriptionID, Kind: kind}
+	}
+	if !sameDay(retry.DueDate, dueDate) {
+		// A retry left over from an earlier date starts from scratch
+		retry.DueDate = dueDate
+		retry.Attempts = 0
+	}
+
+	retry.Channels = strings.Join(channels, ",")
+	retry.Attempts++
+	retry.NextAttemptAt = now.Add(retryDelay(retry.Attempts))
+	retry.LastError = strings.Join(messages, "; ")
+	if err := s.repo.Save(retry); err != nil {
+		return nil, err
+	}
+
+	if retry.Attempts >= MaxReminderAttempts {
+		slog.Error("giving up on reminder after repeated failures", "subscription_id", subscriptionID, "kind", kind, "attempts", retry.Attempts, "channels", retry.Channels, "error", retry.LastError)
+	}
+	return retry, nil
+}
+
+// Due returns the retries whose next attempt is due at now
+func (s *ReminderRetryService) Due(now time.Time) ([]models.ReminderRetry, error) {
+	return s.repo.GetDue(now, MaxReminderAttempts)
+}
+
+// Drop removes the retry of a subscription's reminder kind
+func (s *ReminderRetryService) Drop(subscriptionID uint, kind string) error {
+	return s.repo.Delete(subscriptionID, kind)
+}
+
+// retryDelay returns the backoff before the next attempt after the given
+// number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := reminderRetryBaseDelay
+	for i := 1; i < attempts && delay < reminderRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, reminderRetryMaxDelay)
+}
+
+// sameDay reports whether two times fall on the same calendar day
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupReminderRetryService(t *testing.T) *ReminderRetryService {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.ReminderRetry{}))
+	return NewReminderRetryService(repository.NewReminderRetryRepository(db))
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Minute, retryDelay(1))
+	assert.Equal(t, time.Hour, retryDelay(2))
+	assert.Equal(t, 2*time.Hour, retryDelay(3))
+	assert.Equal(t, 12*time.Hour, retryDelay(10))
+}
+
+func TestReminderRetryService_RecordResult(t *testing.T) {
+	service := setupReminderRetryService(t)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.Local)
+	due := time.Date(2026, 3, 4, 0, 0, 0, 0, time.Local)
+	failures := map[string]error{
+		models.ChannelShoutrrr: errors.New("gateway down"),
+		models.ChannelEmail:    errors.New("smtp timeout"),
+		"none":                 errors.New("no channel delivered"),
+	}
+
+	retry, err := service.RecordResult(1, models.ReminderKindRenewal, due, failures, now)
+	require.NoError(t, err)
+	assert.Equal(t, "email,shoutrrr", retry.Channels)
+	assert.Equal(t, 1, retry.Attempts)
+	assert.True(t, service.Pending(1, models.ReminderKindRenewal, due))
+	assert.False(t, service.Pending(1, models.ReminderKindCancellation, due))
+
+	// Not due before the backoff has elapsed
+	pending, err := service.Due(now.Add(10 * time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	pending, err = service.Due(now.Add(31 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+
+	// Only the channel that still fails is kept, with a longer delay
+	retry, err = service.RecordResult(1, models.ReminderKindRenewal, due, map[string]error{models.ChannelEmail: errors.New("smtp timeout")}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "email", retry.Channels)
+	assert.Equal(t, 2, retry.Attempts)
+	assert.Equal(t, now.Add(time.Hour), retry.NextAttemptAt)
+
+	// A new due date starts over
+	next := due.AddDate(0, 1, 0)
+	retry, err = service.RecordResult(1, models.ReminderKindRenewal, next, map[string]error{models.ChannelEmail: errors.New("smtp timeout")}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, retry.Attempts)
+	assert.False(t, service.Pending(1, models.ReminderKindRenewal, due))
+
+	// Success removes the retry
+	retry, err = service.RecordResult(1, models.ReminderKindRenewal, next, nil, now)
+	require.NoError(t, err)
+	assert.Nil(t, retry)
+	assert.False(t, service.Pending(1, models.ReminderKindRenewal, next))
+}
+
+func TestReminderRetryService_GivesUp(t *testing.T) {
+	service := setupReminderRetryService(t)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.Local)
+	due := now.AddDate(0, 0, 3)
+	failures := map[string]error{models.ChannelEmail: errors.New("smtp timeout")}
+
+	for range MaxReminderAttempts {
+		_, err := service.RecordResult(2, models.ReminderKindCancellation, due, failures, now)
+		require.NoError(t, err)
+	}
+
+	// Exhausted retries are no longer due but still block a duplicate reminder
+	pending, err := service.Due(now.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	assert.True(t, service.Pending(2, models.ReminderKindCancellation, due))
+}
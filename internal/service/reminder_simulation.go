@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// ErrInvalidSimulationDate is returned for a simulation date that is neither
+// a date nor a date and time
+var ErrInvalidSimulationDate = errors.New("invalid simulation date, use YYYY-MM-DD or YYYY-MM-DDTHH:MM")
+
+// Outcomes of a simulated reminder on one channel
+const (
+	ReminderSimulationSend          = "send"
+	ReminderSimulationQueued        = "queued"
+	ReminderSimulationNotConfigured = "not_configured"
+)
+
+// SimulatedDelivery is how a simulated reminder would go out on one channel.
+// Recipients are the email addresses, or the services of the Shoutrrr URLs
+// without their credentials.
+type SimulatedDelivery struct {
+	Channel    string   `json:"channel"`
+	Status     string   `json:"status"`
+	Recipients []string `json:"recipients,omitempty"`
+}
+
+// SimulatedReminder is a reminder the reminder jobs would send. A reminder
+// with a pending retry is left to the retry job and not sent again.
+type SimulatedReminder struct {
+	Kind           string              `json:"kind"`
+	SubscriptionID uint                `json:"subscription_id"`
+	Name           string              `json:"name"`
+	DueDate        time.Time           `json:"due_date"`
+	DaysUntil      int                 `json:"days_until"`
+	PendingRetry   bool                `json:"pending_retry"`
+	Channels       []SimulatedDelivery `json:"channels"`
+}
+
+// ReminderSimulation lists the reminders that would be sent at a time
+type ReminderSimulation struct {
+	At        time.Time           `json:"at"`
+	Reminders []SimulatedReminder `json:"reminders"`
+}
+
+// ReminderSimulationService runs the reminder selection for any date without
+// sending anything, to check the reminder setup after changing dates
+type ReminderSimulationService struct {
+	subscriptions *SubscriptionService
+	notifier      NotificationDispatcherInterface
+	notifConfig   NotificationConfigServiceInterface
+	hooks         HookServiceInterface
+	retries       *ReminderRetryService
+}
+
+func NewReminderSimulationService(subscriptions *SubscriptionService, notifier NotificationDispatcherInterface, notifConfig NotificationConfigServiceInterface, hooks HookServiceInterface, retries *ReminderRetryService) *ReminderSimulationService {
+	return &ReminderSimulationService{subscriptions: subscriptions, notifier: notifier, notifConfig: notifConfig, hooks: hooks, retries: retries}
+}
+
+// ParseSimulationTime parses a simulation date. A date without a time is
+// taken at the time of day of now, in now's location.
+func ParseSimulationTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return now, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04", value, now.Location()); err == nil {
+		return t, nil
+	}
+	date, err := time.ParseInLocation(time.DateOnly, value, now.Location())
+	if err != nil {
+		return time.Time{}, ErrInvalidSimulationDate
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), now.Hour(), now.Minute(), 0, 0, now.Location()), nil
+}
+
+// Simulate returns the reminders the reminder jobs would send at, soonest
+// first. Dates are taken as stored: a renewal date passed by then is not
+// advanced, so simulating far ahead misses the reminders of later renewals.
+func (s *ReminderSimulationService) Simulate(ctx context.Context, at time.Time) (*ReminderSimulation, error) {
+	simulation := &ReminderSimulation{At: at, Reminders: []SimulatedReminder{}}
+	for _, selection := range reminderSelections(s.subscriptions) {
+		subscriptions, err := selection.find(ctx, at)
+		if err != nil {
+			return nil, err
+		}
+		for sub, daysUntil := range subscriptions {
+			dueDate := selection.dueDate(sub, at)
+			simulation.Reminders = append(simulation.Reminders, SimulatedReminder{
+				Kind:           selection.kind,
+				SubscriptionID: sub.ID,
+				Name:           sub.Name,
+				DueDate:        dueDate,
+				DaysUntil:      daysUntil,
+				PendingRetry:   s.retries.Pending(sub.ID, selection.kind, dueDate),
+				Channels:       s.deliveries(selection.kind, sub, at),
+			})
+		}
+	}
+
+	sort.SliceStable(simulation.Reminders, func(i, j int) bool {
+		a, b := simulation.Reminders[i], simulation.Reminders[j]
+		if a.DaysUntil != b.DaysUntil {
+			return a.DaysUntil < b.DaysUntil
+		}
+		if a.Name != b.Name {
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+		return a.Kind < b.Kind
+	})
+	return simulation, nil
+}
+
+// deliveries returns how a reminder would go out on each channel selected for
+// the subscription, like ReminderJobs.send would deliver it
+func (s *ReminderSimulationService) deliveries(kind string, sub *models.Subscription, at time.Time) []SimulatedDelivery {
+	// Hooks only listen to renewals
+	var channels []string
+	for _, channel := range s.channels(kind == models.ReminderKindRenewal) {
+		if sub.NotifiesVia(channel) {
+			channels = append(channels, channel)
+		}
+	}
+	return s.deliveriesVia(channels, at)
+}
+
+// channels returns the registered notification channels in their usual order,
+// and the webhook channel if withHooks is set
+func (s *ReminderSimulationService) channels(withHooks bool) []string {
+	registered := map[string]bool{models.ChannelWebhook: withHooks}
+	for _, notifier := range s.notifier.Notifiers() {
+		registered[notifier.Channel()] = true
+	}
+	var channels []string
+	for _, channel := range models.NotificationChannels {
+		if registered[channel] {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// deliveriesVia returns how a notification would go out on each channel
+func (s *ReminderSimulationService) deliveriesVia(channels []string, at time.Time) []SimulatedDelivery {
+	deliveries := []SimulatedDelivery{}
+	for _, channel := range channels {
+		delivery := SimulatedDelivery{Channel: channel, Status: ReminderSimulationSend, Recipients: s.recipients(channel)}
+		switch {
+		case channel == models.ChannelWebhook && !s.hooks.Has(EventRenewalImminent):
+			delivery.Status = ReminderSimulationNotConfigured
+		case channel != models.ChannelWebhook && len(delivery.Recipients) == 0:
+			delivery.Status = ReminderSimulationNotConfigured
+		case channel == models.ChannelShoutrrr && OfflineMode():
+			delivery.Status = ReminderSimulationNotConfigured
+		case !s.notifConfig.DeliveryAllowed(channel, at):
+			delivery.Status = ReminderSimulationQueued
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries
+}
+
+// recipients returns who a channel notifies
+func (s *ReminderSimulationService) recipients(channel string) []string {
+	switch channel {
+	case models.ChannelEmail:
+		config, err := s.notifConfig.GetSMTPConfig()
+		if err != nil || config.Host == "" || len(models.SplitAddresses(config.To)) == 0 {
+			return nil
+		}
+		recipients := models.SplitAddresses(config.To)
+		recipients = append(recipients, models.SplitAddresses(config.CC)...)
+		return append(recipients, models.SplitAddresses(config.BCC)...)
+	case models.ChannelShoutrrr:
+		config, err := s.notifConfig.GetShoutrrrConfig()
+		if err != nil {
+			return nil
+		}
+		var services []string
+		for _, raw := range config.URLs {
+			service := "shoutrrr"
+			if u, err := url.Parse(raw); err == nil && u.Scheme != "" {
+				service = u.Scheme
+			}
+			if !slices.Contains(services, service) {
+				services = append(services, service)
+			}
+		}
+		return services
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReminderSimulationService_Simulate(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ReminderRetry{}))
+	settingsRepo := repository.NewSettingsRepository(db)
+	services := newTestSubscriptionService(t, db)
+	notifConfig := NewNotificationConfigService(services.settings, settingsRepo)
+	require.NoError(t, notifConfig.SaveSMTPConfig(&models.SMTPConfig{Host: "smtp.example.com", Port: 587, From: "vault@example.com", To: "me@example.com", CC: "partner@example.com"}))
+
+	email := &fakeNotifier{channel: models.ChannelEmail}
+	push := &fakeNotifier{channel: models.ChannelShoutrrr}
+	simulation := NewReminderSimulationService(services.subscriptions, NewNotificationDispatcher(email, push), notifConfig, NewHookService(),
+		NewReminderRetryService(repository.NewReminderRetryRepository(db)))
+
+	now := time.Now()
+	renewal := now.AddDate(0, 0, 10)
+	sub, err := services.subscriptions.Create(t.Context(), &models.Subscription{
+		Name: "Netflix", Cost: 15, Schedule: "Monthly", Status: "Active",
+		RenewalDate: &renewal, RenewalReminder: true, RenewalReminderDays: 3,
+	})
+	require.NoError(t, err)
+
+	result, err := simulation.Simulate(t.Context(), now)
+	require.NoError(t, err)
+	assert.Empty(t, result.Reminders, "the reminder is not due yet")
+
+	result, err = simulation.Simulate(t.Context(), now.AddDate(0, 0, 8))
+	require.NoError(t, err)
+	require.Len(t, result.Reminders, 1)
+	reminder := result.Reminders[0]
+	assert.Equal(t, models.ReminderKindRenewal, reminder.Kind)
+	assert.Equal(t, sub.ID, reminder.SubscriptionID)
+	assert.Equal(t, 2, reminder.DaysUntil)
+	assert.False(t, reminder.PendingRetry)
+	assert.Equal(t, []SimulatedDelivery{
+		{Channel: models.ChannelEmail, Status: ReminderSimulationSend, Recipients: []string{"me@example.com", "partner@example.com"}},
+		{Channel: models.ChannelShoutrrr, Status: ReminderSimulationNotConfigured},
+		{Channel: models.ChannelWebhook, Status: ReminderSimulationNotConfigured},
+	}, reminder.Channels)
+
+	// Nothing was sent or marked as sent
+	assert.Empty(t, email.sent)
+	assert.Empty(t, push.sent)
+	saved, err := services.subscriptions.GetByID(t.Context(), sub.ID)
+	require.NoError(t, err)
+	assert.Nil(t, saved.LastReminderSent)
+}
+
+func TestParseSimulationTime(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 30, 0, 0, time.UTC)
+
+	at, err := ParseSimulationTime("", now)
+	require.NoError(t, err)
+	assert.Equal(t, now, at)
+
+	at, err = ParseSimulationTime("2026-04-01", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 4, 1, 9, 30, 0, 0, time.UTC), at, "a date keeps the time of day")
+
+	at, err = ParseSimulationTime("2026-04-01T22:15", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 4, 1, 22, 15, 0, 0, time.UTC), at)
+
+	_, err = ParseSimulationTime("next week", now)
+	assert.ErrorIs(t, err, ErrInvalidSimulationDate)
+}
+
+func TestNotificationPreviewService_Preview(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ReminderRetry{}))
+	settingsRepo := repository.NewSettingsRepository(db)
+	services := newTestSubscriptionService(t, db)
+	notifConfig := NewNotificationConfigService(services.settings, settingsRepo)
+	simulation := NewReminderSimulationService(services.subscriptions, NewNotificationDispatcher(&fakeNotifier{channel: models.ChannelEmail}), notifConfig, NewHookService(),
+		NewReminderRetryService(repository.NewReminderRetryRepository(db)))
+	preview := NewNotificationPreviewService(simulation, services.subscriptions,
+		NewWeeklySummaryService(services.subscriptions, fakeRates{}, services.preferences, services.settings),
+		NewMonthlyReportService(NewExportService(services.subscriptions, services.preferences), services.subscriptions, nil, notifConfig, services.settings), services.settings)
+
+	now := time.Now()
+	renewal := now.AddDate(0, 0, 10)
+	trialEnd := now.AddDate(0, 0, 5)
+	later := now.AddDate(0, 0, 45)
+	for _, sub := range []*models.Subscription{
+		{Name: "Netflix", Cost: 15, Schedule: "Monthly", Status: "Active", RenewalDate: &renewal, RenewalReminder: true, RenewalReminderDays: 3},
+		{Name: "Figma", Cost: 12, Schedule: "Monthly", Status: "Trial", RenewalDate: &trialEnd},
+		{Name: "Domain", Cost: 20, Schedule: "Annual", Status: "Active", RenewalDate: &later, RenewalReminder: true, RenewalReminderDays: 7},
+	} {
+		_, err := services.subscriptions.Create(t.Context(), sub)
+		require.NoError(t, err)
+	}
+	services.settings.SetBoolSetting("weekly_summary", true)
+
+	result, err := preview.Preview(t.Context(), now, NotificationPreviewDays)
+	require.NoError(t, err)
+	var entries []string
+	for _, entry := range result.Entries {
+		entries = append(entries, fmt.Sprintf("%d %s %s", daysUntilDate(entry.Date, now), entry.Kind, entry.Name))
+	}
+	assert.Equal(t, []string{
+		"0 weekly_summary ",
+		"5 trial_end Figma",
+		"7 weekly_summary ",
+		"7 renewal Netflix",
+		"14 weekly_summary ",
+		"21 weekly_summary ",
+		"28 weekly_summary ",
+	}, entries, "a reminder shows once, on the day it is sent; renewals beyond the window are left out")
+}
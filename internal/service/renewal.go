@@ -50,6 +50,60 @@ func (r *RenewalService) RecalculateIfNeeded(existing, updated *models.Subscript
 	}
 }
 
+// Cancel marks the subscription as cancelled on now. The cancellation date is
+// kept if one was planned, and an upcoming renewal date becomes the paid-through
+// date, since access lasts until the end of the period already paid for.
+func (r *RenewalService) Cancel(sub *models.Subscription, now time.Time) {
+	sub.Status = "Cancelled"
+	if sub.CancellationDate == nil {
+		sub.CancellationDate = &now
+	}
+	if sub.PaidThroughDate == nil && sub.RenewalDate != nil && sub.RenewalDate.After(now) {
+		paidThrough := *sub.RenewalDate
+		sub.PaidThroughDate = &paidThrough
+	}
+	sub.RenewalDate = nil
+}
+
+// Pause marks the subscription as paused. A paused subscription is not
+// charged, so it has no renewal date.
+func (r *RenewalService) Pause(sub *models.Subscription) {
+	sub.Status = "Paused"
+	sub.RenewalDate = nil
+}
+
+// Resume makes a paused or cancelled subscription active again, clearing the
+// cancellation and calculating the next renewal date
+func (r *RenewalService) Resume(sub *models.Subscription) {
+	sub.Status = "Active"
+	sub.CancellationDate = nil
+	sub.PaidThroughDate = nil
+	sub.RenewalDate = nil
+	sub.CalculateNextRenewalDate()
+}
+
+// NormalizeStatus makes the dates of the subscription consistent with its
+// status after a change from the given status, which is empty for a new
+// subscription. Cancelled and paused subscriptions have no renewal date, a
+// cancelled one always has a cancellation date, and resuming a paused or
+// cancelled subscription drops a cancellation that already took effect.
+func (r *RenewalService) NormalizeStatus(sub *models.Subscription, from string, now time.Time) {
+	switch sub.Status {
+	case models.StatusCancelled:
+		r.Cancel(sub, now)
+	case models.StatusPaused:
+		r.Pause(sub)
+	case models.StatusActive, models.StatusTrial:
+		if from != models.StatusPaused && from != models.StatusCancelled {
+			return
+		}
+		if sub.CancellationDate != nil && !sub.CancellationDate.After(now) {
+			sub.CancellationDate = nil
+		}
+		sub.PaidThroughDate = nil
+	}
+}
+
 func startDateChanged(old, new *time.Time) bool {
 	if old == nil && new != nil {
 		return true
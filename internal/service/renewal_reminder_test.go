@@ -209,7 +209,7 @@ func TestSubscriptionService_GetSubscriptionsNeedingReminders(t *testing.T) {
 				assert.NoError(t, err, "Failed to create test subscription")
 			}
 
-			result, err := subscriptionService.GetSubscriptionsNeedingReminders()
+			result, err := subscriptionService.GetSubscriptionsNeedingReminders(t.Context(), time.Now())
 			assert.NoError(t, err, "GetSubscriptionsNeedingReminders should not return error")
 			assert.Equal(t, tt.expectedCount, len(result), tt.description)
 
@@ -309,7 +309,7 @@ func TestSubscriptionService_GetSubscriptionsNeedingReminders_DaysCalculation(t
 	err := db.Create(sub).Error
 	assert.NoError(t, err)
 
-	result, err := subscriptionService.GetSubscriptionsNeedingReminders()
+	result, err := subscriptionService.GetSubscriptionsNeedingReminders(t.Context(), time.Now())
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(result), "Should find one subscription")
 
@@ -387,7 +387,7 @@ func TestSubscriptionService_GetSubscriptionsNeedingReminders_BoundaryCases(t *t
 			err := db.Create(sub).Error
 			assert.NoError(t, err)
 
-			result, err := subscriptionService.GetSubscriptionsNeedingReminders()
+			result, err := subscriptionService.GetSubscriptionsNeedingReminders(t.Context(), time.Now())
 			assert.NoError(t, err)
 
 			if tt.shouldFind {
@@ -431,7 +431,7 @@ func TestSubscriptionService_GetSubscriptionsNeedingReminders_DuplicatePreventio
 	err := db.Create(sub).Error
 	assert.NoError(t, err)
 
-	result, err := subscriptionService.GetSubscriptionsNeedingReminders()
+	result, err := subscriptionService.GetSubscriptionsNeedingReminders(t.Context(), time.Now())
 	assert.NoError(t, err)
 	assert.Equal(t, 0, len(result), "Should not find subscription that already has reminder sent for this renewal date")
 
@@ -442,7 +442,7 @@ func TestSubscriptionService_GetSubscriptionsNeedingReminders_DuplicatePreventio
 	assert.NoError(t, err)
 
 	// Should find it now because renewal date changed
-	result, err = subscriptionService.GetSubscriptionsNeedingReminders()
+	result, err = subscriptionService.GetSubscriptionsNeedingReminders(t.Context(), time.Now())
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(result), "Should find subscription when renewal date changes")
 }
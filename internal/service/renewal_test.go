@@ -234,3 +234,44 @@ func TestRenewalService_RecalculateIfNeeded_InactiveStatus(t *testing.T) {
 
 	assert.Nil(t, updated.RenewalDate)
 }
+
+func TestRenewalService_Cancel(t *testing.T) {
+	rs := NewRenewalService()
+	now := time.Now()
+
+	t.Run("upcoming renewal becomes the paid-through date", func(t *testing.T) {
+		renewal := now.AddDate(0, 0, 10)
+		sub := &models.Subscription{Schedule: "Monthly", Status: "Active", RenewalDate: &renewal}
+		rs.Cancel(sub, now)
+		assert.Equal(t, "Cancelled", sub.Status)
+		assert.Nil(t, sub.RenewalDate)
+		assert.Equal(t, &renewal, sub.PaidThroughDate)
+		assert.Equal(t, &now, sub.CancellationDate)
+	})
+
+	t.Run("planned cancellation date and paid-through date are kept", func(t *testing.T) {
+		planned := now.AddDate(0, 0, 5)
+		paidThrough := now.AddDate(0, 0, 20)
+		renewal := now.AddDate(0, 0, 10)
+		sub := &models.Subscription{Status: "Paused", RenewalDate: &renewal, CancellationDate: &planned, PaidThroughDate: &paidThrough}
+		rs.Cancel(sub, now)
+		assert.Equal(t, &planned, sub.CancellationDate)
+		assert.Equal(t, &paidThrough, sub.PaidThroughDate)
+	})
+}
+
+func TestRenewalService_PauseAndResume(t *testing.T) {
+	rs := NewRenewalService()
+	renewal := time.Now().AddDate(0, 0, 10)
+	sub := &models.Subscription{Schedule: "Monthly", Status: "Active", RenewalDate: &renewal}
+
+	rs.Pause(sub)
+	assert.Equal(t, "Paused", sub.Status)
+	assert.Nil(t, sub.RenewalDate)
+
+	rs.Resume(sub)
+	assert.Equal(t, "Active", sub.Status)
+	if assert.NotNil(t, sub.RenewalDate) {
+		assert.True(t, sub.RenewalDate.After(time.Now()))
+	}
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"expvar"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// requestMetricsRecent is how many of the latest requests are kept to
+	// pick the slowest from
+	requestMetricsRecent = 500
+	// requestMetricsSlowQueries is how many of the latest slow queries are kept
+	requestMetricsSlowQueries = 50
+)
+
+// RequestTiming is where the time of a single request went
+type RequestTiming struct {
+	Method     string        `json:"method"`
+	Route      string        `json:"route"`
+	Path       string        `json:"path"`
+	Status     int           `json:"status"`
+	Duration   time.Duration `json:"duration_ns"`
+	DBTime     time.Duration `json:"db_ns"`
+	Queries    int           `json:"queries"`
+	RenderTime time.Duration `json:"render_ns"`
+	At         time.Time     `json:"at"`
+}
+
+// RouteStats sums up the requests to one route since startup
+type RouteStats struct {
+	Method  string        `json:"method"`
+	Route   string        `json:"route"`
+	Count   int64         `json:"count"`
+	Errors  int64         `json:"errors"` // Responses with a 5xx status
+	Total   time.Duration `json:"total_ns"`
+	Max     time.Duration `json:"max_ns"`
+	DBTime  time.Duration `json:"db_ns"`
+	Queries int64         `json:"queries"`
+}
+
+// Avg is the mean duration of the route's requests
+func (s RouteStats) Avg() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// QueryTiming is a database query that took longer than the slow query threshold
+type QueryTiming struct {
+	SQL      string        `json:"sql"`
+	Duration time.Duration `json:"duration_ns"`
+	Rows     int           `json:"rows"`
+	Route    string        `json:"route,omitempty"` // Empty for queries outside a request
+	At       time.Time     `json:"at"`
+}
+
+// PerformanceReport is a snapshot of the request metrics
+type PerformanceReport struct {
+	Routes      []RouteStats    `json:"routes"`       // Most total time first
+	Slowest     []RequestTiming `json:"slowest"`      // Slowest of the recent requests
+	SlowQueries []QueryTiming   `json:"slow_queries"` // Newest first
+	Queries     int64           `json:"queries"`      // All queries since startup
+	DBTime      time.Duration   `json:"db_ns"`
+	Since       time.Time       `json:"since"`
+	SlowQueryMs int             `json:"slow_query_ms"`
+}
+
+// RequestTrace collects the database and template time of one request. It
+// travels in the request context so that GORM callbacks can find it.
+type RequestTrace struct {
+	mu         sync.Mutex
+	route      string
+	dbTime     time.Duration
+	queries    int
+	renderTime time.Duration
+}
+
+type requestTraceKey struct{}
+
+// WithRequestTrace returns a context carrying a new trace for a request to route
+func WithRequestTrace(ctx context.Context, route string) (context.Context, *RequestTrace) {
+	trace := &RequestTrace{route: route}
+	return context.WithValue(ctx, requestTraceKey{}, trace), trace
+}
+
+// RequestTraceFrom returns the trace of the request ctx belongs to, or nil
+func RequestTraceFrom(ctx context.Context) *RequestTrace {
+	if ctx == nil {
+		return nil
+	}
+	trace, _ := ctx.Value(requestTraceKey{}).(*RequestTrace)
+	return trace
+}
+
+// AddQuery adds the time of a database query made for the request
+func (t *RequestTrace) AddQuery(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dbTime += d
+	t.queries++
+}
+
+// AddRender adds the time spent rendering templates for the request
+func (t *RequestTrace) AddRender(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.renderTime += d
+}
+
+// RequestMetrics records request latencies per route, the slowest recent
+// requests and slow database queries. Only queries that carry the request
+// context (the subscription queries do) count towards a request's database
+// time; all queries count towards the totals and the slow query log.
+type RequestMetrics struct {
+	mu          sync.Mutex
+	routes      map[string]*RouteStats
+	recent      []RequestTiming
+	next        int
+	slowQueries []QueryTiming
+	queries     int64
+	dbTime      time.Duration
+	since       time.Time
+	slowQuery   time.Duration
+}
+
+// NewRequestMetrics creates the metrics store. Queries taking at least
+// slowQuery are logged; 0 disables the slow query log.
+func NewRequestMetrics(slowQuery time.Duration) *RequestMetrics {
+	return &RequestMetrics{
+		routes:    make(map[string]*RouteStats),
+		since:     time.Now(),
+		slowQuery: slowQuery,
+	}
+}
+
+// Var returns the per-route statistics for publishing with expvar
+func (m *RequestMetrics) Var() expvar.Var {
+	return expvar.Func(func() any { return m.Report(0).Routes })
+}
+
+// RecordRequest adds a finished request
+func (m *RequestMetrics) RecordRequest(timing RequestTiming, trace *RequestTrace) {
+	if trace != nil {
+		trace.mu.Lock()
+		timing.DBTime, timing.Queries, timing.RenderTime = trace.dbTime, trace.queries, trace.renderTime
+		trace.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := timing.Method + " " + timing.Route
+	stats, ok := m.routes[key]
+	if !ok {
+		stats = &RouteStats{Method: timing.Method, Route: timing.Route}
+		m.routes[key] = stats
+	}
+	stats.Count++
+	stats.Total += timing.Duration
+	stats.Max = max(stats.Max, timing.Duration)
+	stats.DBTime += timing.DBTime
+	stats.Queries += int64(timing.Queries)
+	if timing.Status >= 500 {
+		stats.Errors++
+	}
+
+	if len(m.recent) < requestMetricsRecent {
+		m.recent = append(m.recent, timing)
+	} else {
+		m.recent[m.next] = timing
+	}
+	m.next = (m.next + 1) % requestMetricsRecent
+}
+
+// RecordQuery adds a finished database query and logs it when it is slow
+func (m *RequestMetrics) RecordQuery(ctx context.Context, sql string, d time.Duration, rows int64) {
+	trace := RequestTraceFrom(ctx)
+	if trace != nil {
+		trace.AddQuery(d)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queries++
+	m.dbTime += d
+	if m.slowQuery <= 0 || d < m.slowQuery {
+		return
+	}
+
+	query := QueryTiming{SQL: truncate(sql, 500), Duration: d, Rows: int(rows), At: time.Now()}
+	if trace != nil {
+		query.Route = trace.route
+	}
+	slog.Warn("slow query", "duration_ms", d.Milliseconds(), "rows", rows, "route", query.Route, "sql", query.SQL)
+	m.slowQueries = append(m.slowQueries, query)
+	if len(m.slowQueries) > requestMetricsSlowQueries {
+		m.slowQueries = m.slowQueries[1:]
+	}
+}
+
+// Report returns the routes by total time, the slowest recent requests and
+// the recent slow queries. A positive limit caps the routes and requests.
+func (m *RequestMetrics) Report(limit int) PerformanceReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := PerformanceReport{
+		Routes:      make([]RouteStats, 0, len(m.routes)),
+		Slowest:     slices.Clone(m.recent),
+		SlowQueries: slices.Clone(m.slowQueries),
+		Queries:     m.queries,
+		DBTime:      m.dbTime,
+		Since:       m.since,
+		SlowQueryMs: int(m.slowQuery.Milliseconds()),
+	}
+	for _, stats := range m.routes {
+		report.Routes = append(report.Routes, *stats)
+	}
+	slices.SortFunc(report.Routes, func(a, b RouteStats) int {
+		return cmp.Or(cmp.Compare(b.Total, a.Total), cmp.Compare(a.Route, b.Route), cmp.Compare(a.Method, b.Method))
+	})
+	slices.SortFunc(report.Slowest, func(a, b RequestTiming) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	slices.Reverse(report.SlowQueries)
+
+	if limit > 0 {
+		report.Routes = report.Routes[:min(len(report.Routes), limit)]
+		report.Slowest = report.Slowest[:min(len(report.Slowest), limit)]
+	}
+	return report
+}
+
+// requestMetricsStartKey is where the query start time is kept on the statement
+const requestMetricsStartKey = "request_metrics:start"
+
+// InstrumentDB times every query made through db
+func (m *RequestMetrics) InstrumentDB(db *gorm.DB) error {
+	before := func(tx *gorm.DB) {
+		tx.InstanceSet(requestMetricsStartKey, time.Now())
+	}
+	after := func(tx *gorm.DB) {
+		start, ok := tx.InstanceGet(requestMetricsStartKey)
+		if !ok {
+			return
+		}
+		m.RecordQuery(tx.Statement.Context, tx.Statement.SQL.String(), time.Since(start.(time.Time)), tx.Statement.RowsAffected)
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("request_metrics:before_create", before),
+		callbacks.Create().After("gorm:create").Register("request_metrics:after_create", after),
+		callbacks.Query().Before("gorm:query").Register("request_metrics:before_query", before),
+		callbacks.Query().After("gorm:query").Register("request_metrics:after_query", after),
+		callbacks.Update().Before("gorm:update").Register("request_metrics:before_update", before),
+		callbacks.Update().After("gorm:update").Register("request_metrics:after_update", after),
+		callbacks.Delete().Before("gorm:delete").Register("request_metrics:before_delete", before),
+		callbacks.Delete().After("gorm:delete").Register("request_metrics:after_delete", after),
+		callbacks.Row().Before("gorm:row").Register("request_metrics:before_row", before),
+		callbacks.Row().After("gorm:row").Register("request_metrics:after_row", after),
+		callbacks.Raw().Before("gorm:raw").Register("request_metrics:before_raw", before),
+		callbacks.Raw().After("gorm:raw").Register("request_metrics:after_raw", after),
+	)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestMetrics_Report(t *testing.T) {
+	metrics := NewRequestMetrics(100 * time.Millisecond)
+
+	_, fast := WithRequestTrace(context.Background(), "/")
+	fast.AddQuery(5 * time.Millisecond)
+	fast.AddRender(2 * time.Millisecond)
+	metrics.RecordRequest(RequestTiming{Method: "GET", Route: "/", Path: "/", Status: 200, Duration: 20 * time.Millisecond}, fast)
+	metrics.RecordRequest(RequestTiming{Method: "GET", Route: "/", Path: "/", Status: 200, Duration: 40 * time.Millisecond}, nil)
+	metrics.RecordRequest(RequestTiming{Method: "GET", Route: "/analytics", Path: "/analytics", Status: 500, Duration: 300 * time.Millisecond}, nil)
+	metrics.RecordRequest(RequestTiming{Method: "POST", Route: "/api/subscriptions", Path: "/api/subscriptions", Status: 201, Duration: 10 * time.Millisecond}, nil)
+
+	report := metrics.Report(2)
+	require.Len(t, report.Routes, 2)
+	assert.Equal(t, "/analytics", report.Routes[0].Route)
+	assert.Equal(t, int64(1), report.Routes[0].Errors)
+	assert.Equal(t, "/", report.Routes[1].Route)
+	assert.Equal(t, int64(2), report.Routes[1].Count)
+	assert.Equal(t, 30*time.Millisecond, report.Routes[1].Avg())
+	assert.Equal(t, 40*time.Millisecond, report.Routes[1].Max)
+	assert.Equal(t, int64(1), report.Routes[1].Queries)
+
+	require.Len(t, report.Slowest, 2)
+	assert.Equal(t, "/analytics", report.Slowest[0].Path)
+	assert.Equal(t, "/", report.Slowest[1].Path)
+
+	assert.Len(t, metrics.Report(0).Routes, 3)
+	assert.Len(t, metrics.Report(0).Slowest, 4)
+}
+
+func TestRequestMetrics_InstrumentDB(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	// Every query counts as slow
+	metrics := NewRequestMetrics(time.Nanosecond)
+	require.NoError(t, metrics.InstrumentDB(db))
+
+	ctx, trace := WithRequestTrace(context.Background(), "/subscriptions")
+	require.NoError(t, db.WithContext(ctx).Create(&models.Category{Name: "Streaming"}).Error)
+	var categories []models.Category
+	require.NoError(t, db.WithContext(ctx).Find(&categories).Error)
+	// Queries without the request context only count towards the totals
+	require.NoError(t, db.Find(&categories).Error)
+
+	assert.Equal(t, 2, trace.queries)
+	assert.Positive(t, trace.dbTime)
+
+	report := metrics.Report(0)
+	assert.Equal(t, int64(3), report.Queries)
+	require.Len(t, report.SlowQueries, 3)
+	assert.Empty(t, report.SlowQueries[0].Route)
+	assert.Contains(t, report.SlowQueries[1].SQL, "SELECT * FROM `categories`")
+	assert.Equal(t, "/subscriptions", report.SlowQueries[1].Route)
+	assert.Contains(t, report.SlowQueries[2].SQL, "INSERT INTO `categories`")
+}
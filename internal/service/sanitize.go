@@ -0,0 +1,53 @@
+package service
+
+import (
+	"html"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"subvault/internal/models"
+)
+
+// htmlTagPattern matches HTML tags, comments and doctypes
+var htmlTagPattern = regexp.MustCompile(`(?s)<[!/?]?[a-zA-Z][^>]*>|<!--.*?-->`)
+
+// plainText turns a user supplied field into a single line of plain text for
+// notifications: entities are decoded, HTML tags removed, control and bidi
+// override characters dropped and whitespace collapsed. Notification services
+// that render HTML or Markdown then show the text, and a field cannot add
+// lines or headers to a message.
+func plainText(s string) string {
+	s = htmlTagPattern.ReplaceAllString(html.UnescapeString(s), "")
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return ' '
+		case unicode.IsControl(r), unicode.Is(unicode.Bidi_Control, r):
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// plainSubscription returns a copy of a subscription whose user supplied
+// fields that appear in notifications are plain text
+func plainSubscription(sub *models.Subscription) *models.Subscription {
+	plain := *sub
+	plain.Name = plainText(sub.Name)
+	plain.URL = plainText(sub.URL)
+	plain.CancelURL = plainText(sub.CancelURL)
+	plain.CancelNotes = plainText(sub.CancelNotes)
+	plain.PaymentMethod = plainText(sub.PaymentMethod)
+	plain.ContractNumber = plainText(sub.ContractNumber)
+	plain.Category.Name = plainText(sub.Category.Name)
+	return &plain
+}
+
+// encodeHeader makes a value safe for an email header: it is reduced to plain
+// text, so it cannot end the header, and encoded when it is not ASCII
+func encodeHeader(value string) string {
+	return mime.QEncoding.Encode("UTF-8", plainText(value))
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maliciousSubscription carries markup and line breaks in every free text field
+func maliciousSubscription() *models.Subscription {
+	renewal := time.Now().AddDate(0, 0, 3)
+	promoCost, promoEnd := 5.0, time.Now().AddDate(0, 0, 7)
+	return &models.Subscription{
+		Name:           "Netflix<img src=x onerror=alert(1)>\r\nBcc: victim@example.com",
+		Cost:           15,
+		Schedule:       "Monthly",
+		Status:         "Active",
+		URL:            "javascript:alert(1)",
+		PaymentMethod:  "<b>Visa</b> &lt;script&gt;alert(1)&lt;/script&gt;",
+		ContractNumber: "<script>alert(1)</script>K-42",
+		RenewalDate:    &renewal,
+		Category:       models.Category{Name: "<a href=\"https://evil.example\">Streaming</a>"},
+		PromoCost:      &promoCost,
+		PromoEndDate:   &promoEnd,
+		CancelURL:      "javascript:alert(2)",
+		CancelNotes:    "<b>Account</b> → <script>alert(3)</script>Cancel",
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := map[string]string{
+		"Netflix":                                 "Netflix",
+		"<b>Net</b>flix":                          "Netflix",
+		"Tom &amp; Jerry":                         "Tom & Jerry",
+		"&lt;script&gt;alert(1)&lt;/script&gt;":   "alert(1)",
+		"Line one\r\nSubject: injected":           "Line one Subject: injected",
+		"  spaced \t out  ":                       "spaced out",
+		"Plan <!-- hidden -->Pro":                 "Plan Pro",
+		"evil\u202egnp.exe":                       "evilgnp.exe",
+		"bell\a and null\x00":                     "bell and null",
+		"2 < 3 and 5 > 4":                         "2 < 3 and 5 > 4",
+		"Disney+ (Premium) – 4K":                  "Disney+ (Premium) – 4K",
+		"<script>alert(1)</script><p>Spotify</p>": "alert(1)Spotify",
+	}
+	for input, expected := range tests {
+		assert.Equal(t, expected, plainText(input), input)
+	}
+}
+
+func TestComposeMessage_HeaderInjection(t *testing.T) {
+	config := &models.SMTPConfig{
+		From:     "subvault@example.com",
+		FromName: "SubVault\r\nBcc: victim@example.com",
+		To:       "me@example.com\r\nBcc: victim@example.com",
+	}
+	message := composeMessage(config, "Reminder: Netflix\r\nBcc: victim@example.com", "<p>body</p>")
+
+	headers, body, found := strings.Cut(message, "\r\n\r\n")
+	require.True(t, found)
+	assert.Equal(t, "<p>body</p>", body)
+	lines := strings.Split(headers, "\r\n")
+	require.Len(t, lines, 5)
+	for _, line := range lines {
+		assert.False(t, strings.HasPrefix(line, "Bcc:"), line)
+	}
+	assert.Equal(t, "Subject: Reminder: Netflix Bcc: victim@example.com", lines[2])
+
+	// Non-ASCII subjects are MIME encoded
+	message = composeMessage(config, "Erinnerung: Süddeutsche", "")
+	assert.Contains(t, message, "Subject: =?UTF-8?q?Erinnerung:_S=C3=BCddeutsche?=\r\n")
+}
+
+func TestComposeMessage_Recipients(t *testing.T) {
+	config := &models.SMTPConfig{
+		From:    "subvault@example.com",
+		To:      "me@example.com, partner@example.com",
+		CC:      "kid@example.com",
+		BCC:     "archive@example.com",
+		ReplyTo: "me@example.com",
+	}
+	message := composeMessage(config, "Reminder", "")
+	assert.Contains(t, message, "To: me@example.com, partner@example.com\r\n")
+	assert.Contains(t, message, "Cc: kid@example.com\r\n")
+	assert.Contains(t, message, "Reply-To: me@example.com\r\n")
+	assert.NotContains(t, message, "archive@example.com", "BCC recipients stay out of the headers")
+}
+
+func TestComposeMessage_Attachments(t *testing.T) {
+	config := &models.SMTPConfig{From: "subvault@example.com", To: "me@example.com"}
+	data := []byte(strings.Repeat("Name,Cost\nNetflix,15.99\n", 10))
+	message := composeMessage(config, "Report", "<p>body</p>", Attachment{Filename: "report\r\n.csv", ContentType: "text/csv", Data: data})
+
+	msg, err := mail.ReadMessage(strings.NewReader(message))
+	require.NoError(t, err)
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	part, err := reader.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "text/html; charset=UTF-8", part.Header.Get("Content-Type"))
+	body, err := io.ReadAll(part)
+	require.NoError(t, err)
+	assert.Equal(t, "<p>body</p>", string(body))
+
+	part, err = reader.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "report .csv", part.FileName())
+	encoded, err := io.ReadAll(part)
+	require.NoError(t, err)
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		assert.LessOrEqual(t, len(line), 76)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	require.NoError(t, err)
+	assert.Equal(t, data, decoded)
+
+	_, err = reader.NextPart()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestNotifications_SanitizeSubscriptionFields(t *testing.T) {
+	_, _, notifConfig, shoutrrrService := setupShoutrrrServices(t)
+	preferences := shoutrrrService.preferences
+	require.NoError(t, notifConfig.SaveShoutrrrConfig(&models.ShoutrrrConfig{URLs: []string{"invalid://url"}}))
+	require.NoError(t, notifConfig.SaveSMTPConfig(&models.SMTPConfig{Host: "smtp.example.com", Port: 587, To: "me@example.com"}))
+
+	// Close both delivery windows so the rendered notifications are queued
+	now := time.Now()
+	closed := models.DeliveryWindow{
+		Enabled: true,
+		Start:   now.Add(2 * time.Hour).Format("15:04"),
+		End:     now.Add(3 * time.Hour).Format("15:04"),
+	}
+	require.NoError(t, notifConfig.SaveDeliveryWindows(&models.DeliveryWindows{Email: closed, Shoutrrr: closed}))
+
+	emailService := NewEmailService(preferences, notifConfig)
+	sub := maliciousSubscription()
+	require.NoError(t, shoutrrrService.SendRenewalReminder(sub, 3))
+	require.NoError(t, shoutrrrService.SendGracePeriodReminder(sub, 3))
+	require.NoError(t, emailService.SendHighCostAlert(sub))
+	require.NoError(t, emailService.SendBudgetExceededAlert(models.BudgetPeriodMonthly, 120, 100, "<b>€</b>"))
+	require.NoError(t, emailService.SendContractReminder(sub, 10))
+	require.NoError(t, emailService.SendPaidThroughReminder(sub, 2))
+	require.NoError(t, emailService.SendPromoEndReminder(sub, 7))
+	require.NoError(t, emailService.SendCancellationReminder(sub, 5))
+
+	// The caller's subscription is not modified
+	assert.Equal(t, maliciousSubscription().Name, sub.Name)
+
+	queued := notifConfig.QueuedNotifications()
+	require.Len(t, queued, 8)
+	for _, notification := range queued {
+		assert.NotContains(t, notification.Title, "\n")
+		assert.NotContains(t, notification.Body, "<img")
+		assert.NotContains(t, notification.Body, "<script")
+		assert.NotContains(t, notification.Body, "evil.example")
+		assert.NotContains(t, notification.Body, "<b>")
+		assert.NotContains(t, notification.Body, "\nBcc:")
+	}
+	assert.Contains(t, queued[1].Body, "Visa alert(1)")
+	assert.Contains(t, queued[2].Body, "Netflix Bcc: victim@example.com")
+	assert.NotContains(t, queued[2].Body, `href="javascript:`)
+	assert.Contains(t, queued[3].Body, "&lt;b&gt;€&lt;/b&gt;")
+	assert.Contains(t, queued[4].Body, "alert(1)K-42")
+	assert.Contains(t, queued[5].Body, "Netflix Bcc: victim@example.com")
+	assert.Contains(t, queued[0].Body, "Account → alert(3)Cancel")
+	assert.Contains(t, queued[7].Body, "Account → alert(3)Cancel")
+	assert.NotContains(t, queued[7].Body, `href="javascript:`)
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+)
+
+// SearchLimit caps the number of matches returned per kind of result
+const SearchLimit = 8
+
+// Subscription fields the search looks at
+const (
+	SearchFieldName           = "name"
+	SearchFieldCategory       = "category"
+	SearchFieldCustomerNumber = "customer_number"
+	SearchFieldContractNumber = "contract_number"
+	SearchFieldURL            = "url"
+	SearchFieldNotes          = "notes"
+)
+
+// searchFieldWeights ranks a match in a subscription's name above one in its
+// numbers, category, website or notes. Weights are in tenths.
+var searchFieldWeights = map[string]int{
+	SearchFieldName:           10,
+	SearchFieldCustomerNumber: 8,
+	SearchFieldContractNumber: 8,
+	SearchFieldCategory:       6,
+	SearchFieldURL:            5,
+	SearchFieldNotes:          4,
+}
+
+// SubscriptionMatch is a subscription found by a search and the field that
+// matched the query best
+type SubscriptionMatch struct {
+	models.Subscription
+	Field string
+	score int
+}
+
+// SearchResults holds the subscriptions and categories matching a query, best
+// matches first
+type SearchResults struct {
+	Subscriptions []SubscriptionMatch
+	Categories    []models.Category
+}
+
+// SearchService finds subscriptions and categories for the command palette and
+// the search box
+type SearchService struct {
+	subscriptions *repository.SubscriptionRepository
+	categories    *repository.CategoryRepository
+}
+
+// NewSearchService creates a search service
+func NewSearchService(subscriptions *repository.SubscriptionRepository, categories *repository.CategoryRepository) *SearchService {
+	return &SearchService{subscriptions: subscriptions, categories: categories}
+}
+
+// Search returns the subscriptions whose name, category, customer or contract
+// number, website or notes fuzzily match query, and the categories whose name
+// does, ranked by how well they match. An empty query matches nothing.
+func (s *SearchService) Search(ctx context.Context, query string) (*SearchResults, error) {
+	query = strings.TrimSpace(query)
+	results := &SearchResults{Subscriptions: []SubscriptionMatch{}, Categories: []models.Category{}}
+	if query == "" {
+		return results, nil
+	}
+
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categories.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sub := range subscriptions {
+		// Fields in order of their weight, so the heavier field wins a tie
+		fields := []struct{ name, text string }{
+			{SearchFieldName, sub.Name},
+			{SearchFieldCustomerNumber, sub.CustomerNumber},
+			{SearchFieldContractNumber, sub.ContractNumber},
+			{SearchFieldCategory, sub.Category.Name},
+			{SearchFieldURL, sub.URL},
+			{SearchFieldNotes, sub.Notes},
+		}
+		best := SubscriptionMatch{Subscription: sub}
+		for _, field := range fields {
+			if score := FuzzyScore(query, field.text) * searchFieldWeights[field.name] / 10; score > best.score {
+				best.Field, best.score = field.name, score
+			}
+		}
+		if best.score > 0 {
+			results.Subscriptions = append(results.Subscriptions, best)
+		}
+	}
+	sort.SliceStable(results.Subscriptions, func(i, j int) bool {
+		a, b := results.Subscriptions[i], results.Subscriptions[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	if len(results.Subscriptions) > SearchLimit {
+		results.Subscriptions = results.Subscriptions[:SearchLimit]
+	}
+
+	scores := map[uint]int{}
+	for _, category := range categories {
+		if score := FuzzyScore(query, category.Name); score > 0 {
+			scores[category.ID] = score
+			results.Categories = append(results.Categories, category)
+		}
+	}
+	sort.SliceStable(results.Categories, func(i, j int) bool {
+		return scores[results.Categories[i].ID] > scores[results.Categories[j].ID]
+	})
+	if len(results.Categories) > SearchLimit {
+		results.Categories = results.Categories[:SearchLimit]
+	}
+	return results, nil
+}
+
+// FuzzyScore rates how well query matches text, ignoring case; 0 means it does
+// not match. Containing the query scores highest, more so at the start of the
+// text or of a word. Next come texts holding the query's characters in order
+// with few gaps, like "nflx" in "Netflix", and last words or texts a typo
+// away from the query, like "spotfiy" for "Spotify".
+func FuzzyScore(query, text string) int {
+	q := []rune(strings.ToLower(strings.TrimSpace(query)))
+	t := []rune(strings.ToLower(text))
+	if len(q) == 0 || len(t) == 0 {
+		return 0
+	}
+
+	if idx := runeIndex(t, q); idx >= 0 {
+		score := 1000 - min(idx, 100)
+		switch {
+		case len(t) == len(q):
+			score += 300
+		case idx == 0:
+			score += 200
+		case !isWordRune(t[idx-1]):
+			score += 100
+		}
+		return score
+	}
+
+	if len(q) >= 2 {
+		if start, span, ok := subsequenceSpan(t, q); ok && span <= 2*len(q)+2 {
+			score := 600 - 20*(span-len(q)) - min(start, 50)
+			if start == 0 || !isWordRune(t[start-1]) {
+				score += 50
+			}
+			return score
+		}
+	}
+
+	if len(q) >= 4 {
+		allowed := 1
+		if len(q) >= 8 {
+			allowed = 2
+		}
+		best := 0
+		words := strings.FieldsFunc(string(t), func(r rune) bool { return !isWordRune(r) })
+		for _, word := range append(words, string(t)) {
+			if d := editDistance(q, []rune(word)); d <= allowed {
+				best = max(best, 300-100*d)
+			}
+		}
+		return best
+	}
+	return 0
+}
+
+// isWordRune reports whether r is part of a word rather than a separator
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// runeIndex returns the index of the first occurrence of sub in s, or -1
+func runeIndex(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// subsequenceSpan finds the shortest stretch of s holding the characters of
+// sub in order and returns where it starts and how long it is
+func subsequenceSpan(s, sub []rune) (start, span int, ok bool) {
+	span = len(s) + 1
+	for i := range s {
+		if s[i] != sub[0] {
+			continue
+		}
+		j, k := i, 0
+		for ; j < len(s) && k < len(sub); j++ {
+			if s[j] == sub[k] {
+				k++
+			}
+		}
+		if k == len(sub) && j-i < span {
+			start, span, ok = i, j-i, true
+		}
+	}
+	return start, span, ok
+}
+
+// editDistance is the number of inserted, deleted, replaced or swapped
+// adjacent characters that turn a into b
+func editDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchService_Search(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Vendor{}))
+	categoryRepo := repository.NewCategoryRepository(db)
+	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	search := NewSearchService(subscriptionRepo, categoryRepo)
+
+	streaming, err := categoryRepo.Create(&models.Category{Name: "Streaming"})
+	require.NoError(t, err)
+	_, err = categoryRepo.Create(&models.Category{Name: "100% Cloud"})
+	require.NoError(t, err)
+	for _, name := range []string{"Netflix", "Spotify", "Disney+", "net_work"} {
+		_, err := subscriptionRepo.Create(t.Context(), &models.Subscription{Name: name, Cost: 10, Schedule: "Monthly", Status: "Active", CategoryID: streaming.ID})
+		require.NoError(t, err)
+	}
+	_, err = subscriptionRepo.Create(t.Context(), &models.Subscription{
+		Name: "Mobile Plan", Cost: 20, Schedule: "Monthly", Status: "Active", CategoryID: streaming.ID,
+		CustomerNumber: "KD-884213", URL: "https://telco.example", Notes: "Shared with the family, includes roaming",
+	})
+	require.NoError(t, err)
+
+	names := func(results *SearchResults) (subs, categories []string) {
+		subs, categories = []string{}, []string{}
+		for _, s := range results.Subscriptions {
+			subs = append(subs, s.Name)
+		}
+		for _, c := range results.Categories {
+			categories = append(categories, c.Name)
+		}
+		return subs, categories
+	}
+
+	results, err := search.Search(t.Context(), "NET")
+	require.NoError(t, err)
+	subs, categories := names(results)
+	assert.Equal(t, []string{"net_work", "Netflix"}, subs, "matches anywhere in the name, ignoring case")
+	assert.Empty(t, categories)
+
+	results, err = search.Search(t.Context(), "nflx")
+	require.NoError(t, err)
+	subs, _ = names(results)
+	assert.Equal(t, []string{"Netflix"}, subs, "characters in order match")
+
+	results, err = search.Search(t.Context(), "spotfiy")
+	require.NoError(t, err)
+	subs, _ = names(results)
+	assert.Equal(t, []string{"Spotify"}, subs, "a typo still matches")
+
+	results, err = search.Search(t.Context(), "884213")
+	require.NoError(t, err)
+	require.Len(t, results.Subscriptions, 1)
+	assert.Equal(t, "Mobile Plan", results.Subscriptions[0].Name)
+	assert.Equal(t, SearchFieldCustomerNumber, results.Subscriptions[0].Field)
+
+	results, err = search.Search(t.Context(), "roaming")
+	require.NoError(t, err)
+	require.Len(t, results.Subscriptions, 1)
+	assert.Equal(t, SearchFieldNotes, results.Subscriptions[0].Field)
+
+	results, err = search.Search(t.Context(), "stream")
+	require.NoError(t, err)
+	subs, categories = names(results)
+	assert.Len(t, subs, 5, "subscriptions match by category")
+	assert.Equal(t, []string{"Streaming"}, categories)
+
+	results, err = search.Search(t.Context(), "t_w")
+	require.NoError(t, err)
+	subs, _ = names(results)
+	assert.Equal(t, []string{"net_work"}, subs, "underscore is not a wildcard")
+
+	results, err = search.Search(t.Context(), "0%")
+	require.NoError(t, err)
+	_, categories = names(results)
+	assert.Equal(t, []string{"100% Cloud"}, categories, "percent sign is not a wildcard")
+
+	results, err = search.Search(t.Context(), "  ")
+	require.NoError(t, err)
+	assert.Empty(t, results.Subscriptions)
+	assert.Empty(t, results.Categories)
+}
+
+func TestFuzzyScore(t *testing.T) {
+	assert.Greater(t, FuzzyScore("netflix", "Netflix"), FuzzyScore("net", "Netflix"), "an exact match ranks first")
+	assert.Greater(t, FuzzyScore("net", "Netflix"), FuzzyScore("net", "Cloud Network"), "a match at the start beats one at a word start")
+	assert.Greater(t, FuzzyScore("net", "Cloud Network"), FuzzyScore("net", "Magnetic"), "a word start beats the middle of a word")
+	assert.Greater(t, FuzzyScore("net", "Magnetic"), FuzzyScore("nflx", "Netflix"), "containing the query beats characters in order")
+	assert.Greater(t, FuzzyScore("nflx", "Netflix"), FuzzyScore("netflxi", "Netflix"), "characters in order beat a typo")
+	assert.Positive(t, FuzzyScore("netflxi", "Netflix"))
+	assert.Positive(t, FuzzyScore("dropbxo business", "Dropbox Business"), "longer queries allow two typos")
+
+	assert.Zero(t, FuzzyScore("nx", "Netflix"), "characters spread too far apart do not match")
+	assert.Zero(t, FuzzyScore("xyz", "Netflix"))
+	assert.Zero(t, FuzzyScore("abc", "acb"), "short queries do not match with typos")
+	assert.Zero(t, FuzzyScore("", "Netflix"))
+	assert.Zero(t, FuzzyScore("net", ""))
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+)
+
+// SeedFormat is the import batch format of seeded subscriptions, so they can
+// be removed again from the import history
+const SeedFormat = "seed"
+
+// SeedMaxCount bounds how many subscriptions one seed run creates
+const SeedMaxCount = 100000
+
+// ErrSeedNotEmpty is returned when seeding a database that already has
+// subscriptions without forcing it
+var ErrSeedNotEmpty = errors.New("database already contains subscriptions")
+
+// seedServices are the names synthetic subscriptions are built from
+var seedServices = []string{
+	"Netflix", "Spotify", "Disney+", "YouTube Premium", "Apple One", "iCloud+", "Google One",
+	"Dropbox", "1Password", "Bitwarden", "GitHub", "JetBrains", "Adobe Creative Cloud",
+	"Microsoft 365", "Notion", "Slack", "Zoom", "Figma", "Canva", "Audible", "Kindle Unlimited",
+	"The New York Times", "Duolingo", "Headspace", "Strava", "Peloton", "Gym", "Mobile plan",
+	"Internet", "Electricity", "Car insurance", "Domain", "VPS", "Backblaze", "Proton",
+}
+
+// seedCurrencies are the currencies synthetic subscriptions are billed in;
+// the first is used most often
+var seedCurrencies = []string{"EUR", "EUR", "EUR", "USD", "USD", "GBP", "CHF", "SEK", "JPY"}
+
+// seedSchedules and seedStatuses are weighted towards the common cases
+var (
+	seedSchedules = []string{"Monthly", "Monthly", "Monthly", "Monthly", "Annual", "Annual", "Quarterly", "Weekly", "Daily"}
+	seedStatuses  = []string{"Active", "Active", "Active", "Active", "Active", "Active", "Cancelled", "Paused", "Trial"}
+	seedPurposes  = []string{"personal", "personal", "personal", "business", "shared"}
+	seedUsage     = []string{"", "High", "Medium", "Low", "None"}
+)
+
+// SyntheticSubscriptions generates n plausible subscriptions spread over the
+// categories, currencies, schedules and statuses, with start dates up to five
+// years before now. The same rng seed gives the same subscriptions. Reminders
+// and alerts stay off so a seeded instance sends no notifications.
+func SyntheticSubscriptions(n int, categoryIDs []uint, rng *rand.Rand, now time.Time) []*models.Subscription {
+	pick := func(values []string) string { return values[rng.IntN(len(values))] }
+
+	subscriptions := make([]*models.Subscription, n)
+	for i := range subscriptions {
+		start := now.AddDate(0, 0, -rng.IntN(5*365))
+		sub := &models.Subscription{
+			Name:             fmt.Sprintf("%s #%d", pick(seedServices), i+1),
+			Cost:             float64(100+rng.IntN(9900)) / 100,
+			OriginalCurrency: pick(seedCurrencies),
+			Schedule:         pick(seedSchedules),
+			Status:           pick(seedStatuses),
+			PaymentMethod:    pick([]string{"", "Credit card", "PayPal", "Direct debit"}),
+			Purpose:          pick(seedPurposes),
+			Usage:            pick(seedUsage),
+			StartDate:        &start,
+			PriceType:        "gross",
+			Notes:            "Synthetic subscription created by subvault seed",
+		}
+		if len(categoryIDs) > 0 {
+			sub.CategoryID = categoryIDs[rng.IntN(len(categoryIDs))]
+		}
+		if sub.Schedule == "Annual" {
+			sub.Cost *= 10
+		}
+		if sub.Status == "Cancelled" {
+			cancelled := now.AddDate(0, 0, -rng.IntN(365))
+			sub.CancellationDate = &cancelled
+		}
+		subscriptions[i] = sub
+	}
+	return subscriptions
+}
+
+// SeedService fills a database with synthetic subscriptions to try out and
+// benchmark SubVault with realistic amounts of data
+type SeedService struct {
+	subscriptions SubscriptionServiceInterface
+	categories    CategoryServiceInterface
+	renewal       RenewalServiceInterface
+	batches       *repository.ImportBatchRepository
+}
+
+func NewSeedService(subscriptions SubscriptionServiceInterface, categories CategoryServiceInterface, renewal RenewalServiceInterface, batches *repository.ImportBatchRepository) *SeedService {
+	return &SeedService{subscriptions: subscriptions, categories: categories, renewal: renewal, batches: batches}
+}
+
+// Seed creates count synthetic subscriptions in the existing categories as
+// one import batch, which undoes the seeding when deleted. A database that
+// already has subscriptions is only seeded with force.
+func (s *SeedService) Seed(ctx context.Context, count int, seed uint64, force bool) (*models.ImportBatch, error) {
+	if count < 1 || count > SeedMaxCount {
+		return nil, fmt.Errorf("count must be between 1 and %d", SeedMaxCount)
+	}
+	if !force && s.subscriptions.Count(ctx) > 0 {
+		return nil, ErrSeedNotEmpty
+	}
+
+	categories, err := s.categories.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := make([]uint, len(categories))
+	for i, category := range categories {
+		categoryIDs[i] = category.ID
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed))
+	subscriptions := SyntheticSubscriptions(count, categoryIDs, rng, time.Now())
+	entries := make([]repository.ImportBatchEntry, len(subscriptions))
+	for i, sub := range subscriptions {
+		s.renewal.InitializeRenewalDate(sub)
+		entries[i] = repository.ImportBatchEntry{Subscription: sub}
+	}
+
+	batch := &models.ImportBatch{Format: SeedFormat}
+	if err := s.batches.Create(batch, entries); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
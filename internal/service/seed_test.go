@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSyntheticSubscriptions(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	subs := SyntheticSubscriptions(200, []uint{1, 2, 3}, rand.New(rand.NewPCG(7, 7)), now)
+	require.Len(t, subs, 200)
+
+	again := SyntheticSubscriptions(200, []uint{1, 2, 3}, rand.New(rand.NewPCG(7, 7)), now)
+	assert.Equal(t, subs[42].Name, again[42].Name)
+	assert.Equal(t, subs[42].Cost, again[42].Cost)
+
+	statuses := map[string]int{}
+	for _, sub := range subs {
+		statuses[sub.Status]++
+		assert.Positive(t, sub.Cost)
+		assert.Contains(t, []uint{1, 2, 3}, sub.CategoryID)
+		assert.False(t, sub.StartDate.After(now))
+		assert.False(t, sub.RenewalReminder)
+		assert.Equal(t, sub.Status == "Cancelled", sub.CancellationDate != nil)
+	}
+	assert.Greater(t, statuses["Active"], statuses["Cancelled"])
+}
+
+func TestSeedService_Seed(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ImportBatch{}))
+	subscriptionService, seedService := setupSeedServices(t, db)
+
+	batch, err := seedService.Seed(t.Context(), 50, 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, SeedFormat, batch.Format)
+	assert.Equal(t, 50, batch.SubscriptionCount)
+	assert.Equal(t, int64(50), subscriptionService.Count(t.Context()))
+
+	// Active subscriptions get a renewal date like any new subscription
+	subs, err := subscriptionService.GetAll(t.Context())
+	require.NoError(t, err)
+	for _, sub := range subs {
+		if sub.Status == "Active" {
+			assert.NotNil(t, sub.RenewalDate, sub.Name)
+		}
+	}
+
+	_, err = seedService.Seed(t.Context(), 10, 2, false)
+	assert.ErrorIs(t, err, ErrSeedNotEmpty)
+	_, err = seedService.Seed(t.Context(), 10, 2, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), subscriptionService.Count(t.Context()))
+
+	_, err = seedService.Seed(t.Context(), 0, 1, true)
+	assert.Error(t, err)
+}
+
+// setupSeedServices builds the subscription and seed services over db
+func setupSeedServices(t testing.TB, db *gorm.DB) (*SubscriptionService, *SeedService) {
+	services := newTestSubscriptionService(t, db)
+	seedService := NewSeedService(services.subscriptions, services.categories, NewRenewalService(), repository.NewImportBatchRepository(db))
+	return services.subscriptions, seedService
+}
+
+// setupBenchmarkService returns a subscription service over n seeded
+// subscriptions in a quiet on-disk database, with exchange rates for the
+// seeded currencies so conversions do not reach out to the ECB
+func setupBenchmarkService(b *testing.B, n int) *SubscriptionService {
+	b.Helper()
+	db, err := gorm.Open(sqlite.Open(b.TempDir()+"/bench.db"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(b, err)
+	require.NoError(b, db.AutoMigrate(&models.Subscription{}, &models.Category{}, &models.Settings{}, &models.ExchangeRate{}, &models.ImportBatch{}))
+
+	var rates []models.ExchangeRate
+	for currency, rate := range map[string]float64{"USD": 1.08, "GBP": 0.86, "CHF": 0.95, "SEK": 11.4, "JPY": 162} {
+		rates = append(rates, models.ExchangeRate{BaseCurrency: "EUR", Currency: currency, Rate: rate, Date: time.Now()})
+	}
+	require.NoError(b, repository.NewExchangeRateRepository(db).SaveRates(rates))
+	for _, name := range []string{"Streaming", "Software", "Utilities", "Insurance", "Fitness"} {
+		require.NoError(b, db.Create(&models.Category{Name: name}).Error)
+	}
+
+	subscriptionService, seedService := setupSeedServices(b, db)
+	_, err = seedService.Seed(context.Background(), n, 1, false)
+	require.NoError(b, err)
+	return subscriptionService
+}
+
+// benchmarkSizes are the subscription counts the benchmarks run with
+var benchmarkSizes = []int{100, 1000, 5000}
+
+func BenchmarkSubscriptionService_GetStats(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("subs=%d", n), func(b *testing.B) {
+			subscriptionService := setupBenchmarkService(b, n)
+			for b.Loop() {
+				if _, err := subscriptionService.GetStats(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSubscriptionService_GetAllSorted(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("subs=%d", n), func(b *testing.B) {
+			subscriptionService := setupBenchmarkService(b, n)
+			for b.Loop() {
+				if _, err := subscriptionService.GetAllSorted(context.Background(), "cost", "desc"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
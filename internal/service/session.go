@@ -3,70 +3,122 @@ package service
 import (
 	"net/http"
 	"os"
+	"sync"
+	"time"
+
+	"subvault/internal/models"
 
 	"github.com/gorilla/sessions"
 )
 
 const (
-	SessionName      = "subvault_session"
-	SessionUserKey   = "user_authenticated"
-	SessionMaxAge    = 24 * 60 * 60      // 24 hours in seconds
-	RememberMeMaxAge = 30 * 24 * 60 * 60 // 30 days in seconds
+	SessionName        = "subvault_session"
+	SessionUserKey     = "user_authenticated"
+	SessionRoleKey     = "user_role"
+	SessionCreatedKey  = "created_at" // Unix time of the login
+	SessionRenewedKey  = "renewed_at" // Unix time the idle lifetime last started
+	SessionRememberKey = "remember_me"
 )
 
+// sessionRenewInterval limits how often sliding expiration rewrites the
+// session cookie
+const sessionRenewInterval = time.Minute
+
+// SessionService manages the signed session cookies. Lifetimes are stored in
+// the cookie as login and renewal times and checked against the configured
+// lifetimes on every request, so changed lifetimes apply to existing sessions.
 type SessionService struct {
-	store *sessions.CookieStore
+	mu        sync.RWMutex
+	store     *sessions.CookieStore
+	lifetimes func() models.SessionLifetimes
+	now       func() time.Time
+}
+
+// NewSessionService creates a new session service. lifetimes returns the
+// current session lifetimes; nil uses the defaults.
+func NewSessionService(secretKey string, lifetimes func() models.SessionLifetimes) *SessionService {
+	if lifetimes == nil {
+		lifetimes = func() models.SessionLifetimes { return models.DefaultSessionLifetimes }
+	}
+	return &SessionService{store: newCookieStore(secretKey), lifetimes: lifetimes, now: time.Now}
+}
+
+// Rotate replaces the session secret, which ends all existing sessions
+func (s *SessionService) Rotate(secretKey string) {
+	store := newCookieStore(secretKey)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	store.Options.MaxAge = s.store.Options.MaxAge
+	s.store = store
 }
 
-// NewSessionService creates a new session service
-func NewSessionService(secretKey string) *SessionService {
+// cookieStore returns the current session store
+func (s *SessionService) cookieStore() *sessions.CookieStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.store
+}
+
+func newCookieStore(secretKey string) *sessions.CookieStore {
 	store := sessions.NewCookieStore([]byte(secretKey))
 
 	// Configure session options
 	store.Options = &sessions.Options{
 		Path:     "/",
-		MaxAge:   SessionMaxAge,
+		MaxAge:   int(models.DefaultSessionLifetimes.Session(false).Seconds()),
 		HttpOnly: true,
 		Secure:   os.Getenv("HTTPS_ENABLED") == "true",
 		SameSite: http.SameSiteStrictMode,
 	}
-
-	return &SessionService{store: store}
+	return store
 }
 
-// CreateSession creates a new authenticated session
-func (s *SessionService) CreateSession(w http.ResponseWriter, r *http.Request, rememberMe bool) error {
-	session, err := s.store.Get(r, SessionName)
+// CreateSession creates a new authenticated session for the given role
+func (s *SessionService) CreateSession(w http.ResponseWriter, r *http.Request, rememberMe bool, role string) error {
+	session, err := s.cookieStore().Get(r, SessionName)
 	if err != nil {
 		return err
 	}
 
+	now := s.now()
 	session.Values[SessionUserKey] = true
-
-	// Extend session if "remember me" is checked
-	if rememberMe {
-		session.Options.MaxAge = RememberMeMaxAge
-	} else {
-		session.Options.MaxAge = SessionMaxAge
-	}
+	session.Values[SessionRoleKey] = role
+	session.Values[SessionCreatedKey] = now.Unix()
+	session.Values[SessionRenewedKey] = now.Unix()
+	session.Values[SessionRememberKey] = rememberMe
+	session.Options.MaxAge = s.maxAge(session, now)
 
 	return session.Save(r, w)
 }
 
-// IsAuthenticated checks if the user is authenticated
+// IsAuthenticated checks if the user is authenticated and the session has
+// neither been idle too long nor reached the absolute lifetime
 func (s *SessionService) IsAuthenticated(r *http.Request) bool {
-	session, err := s.store.Get(r, SessionName)
+	session, err := s.cookieStore().Get(r, SessionName)
 	if err != nil {
 		return false
 	}
 
 	auth, ok := session.Values[SessionUserKey].(bool)
-	return ok && auth
+	return ok && auth && s.maxAge(session, s.now()) > 0
+}
+
+// GetRole returns the role of an authenticated session. Sessions created
+// before roles existed belong to the admin.
+func (s *SessionService) GetRole(r *http.Request) string {
+	session, err := s.cookieStore().Get(r, SessionName)
+	if err != nil {
+		return ""
+	}
+	if role, ok := session.Values[SessionRoleKey].(string); ok && role != "" {
+		return role
+	}
+	return RoleAdmin
 }
 
 // DestroySession destroys the user session
 func (s *SessionService) DestroySession(w http.ResponseWriter, r *http.Request) error {
-	session, err := s.store.Get(r, SessionName)
+	session, err := s.cookieStore().Get(r, SessionName)
 	if err != nil {
 		return err
 	}
@@ -74,36 +126,65 @@ func (s *SessionService) DestroySession(w http.ResponseWriter, r *http.Request)
 	// Mark session as expired
 	session.Options.MaxAge = -1
 	delete(session.Values, SessionUserKey)
+	delete(session.Values, SessionRoleKey)
+	delete(session.Values, SessionCreatedKey)
+	delete(session.Values, SessionRenewedKey)
+	delete(session.Values, SessionRememberKey)
 
 	return session.Save(r, w)
 }
 
-// RefreshSession extends the session expiration
+// RefreshSession renews the idle lifetime of an authenticated session when
+// sliding expiration is enabled, never beyond the absolute lifetime. The
+// cookie is rewritten at most once per sessionRenewInterval.
 func (s *SessionService) RefreshSession(w http.ResponseWriter, r *http.Request) error {
-	session, err := s.store.Get(r, SessionName)
+	if !s.lifetimes().Sliding {
+		return nil
+	}
+	session, err := s.cookieStore().Get(r, SessionName)
 	if err != nil {
 		return err
 	}
-
-	// Only refresh if authenticated
-	if auth, ok := session.Values[SessionUserKey].(bool); ok && auth {
-		// Extend the max age
-		currentMaxAge := session.Options.MaxAge
-		if currentMaxAge > 0 {
-			session.Options.MaxAge = currentMaxAge
-		}
-		return session.Save(r, w)
+	if auth, ok := session.Values[SessionUserKey].(bool); !ok || !auth {
+		return nil
 	}
 
-	return nil
+	now := s.now()
+	renewed, _ := session.Values[SessionRenewedKey].(int64)
+	if now.Sub(time.Unix(renewed, 0)) < sessionRenewInterval {
+		return nil
+	}
+	// Sessions from before lifetimes were tracked start counting now
+	if _, ok := session.Values[SessionCreatedKey].(int64); !ok {
+		session.Values[SessionCreatedKey] = now.Unix()
+	}
+	session.Values[SessionRenewedKey] = now.Unix()
+	session.Options.MaxAge = s.maxAge(session, now)
+	return session.Save(r, w)
 }
 
-// UpdateSessionExpiry updates the session secret (useful when secret changes)
-func (s *SessionService) UpdateSessionExpiry(maxAge int) {
-	s.store.Options.MaxAge = maxAge
+// maxAge returns the seconds a session has left at now: the rest of its idle
+// lifetime, capped by its absolute lifetime. Zero or less means it expired.
+func (s *SessionService) maxAge(session *sessions.Session, now time.Time) int {
+	lifetimes := s.lifetimes()
+	rememberMe, _ := session.Values[SessionRememberKey].(bool)
+	created, ok := session.Values[SessionCreatedKey].(int64)
+	if !ok {
+		created = now.Unix()
+	}
+	renewed, ok := session.Values[SessionRenewedKey].(int64)
+	if !ok {
+		renewed = created
+	}
+
+	expires := time.Unix(renewed, 0).Add(lifetimes.Session(rememberMe))
+	if limit := time.Unix(created, 0).Add(lifetimes.Absolute()); limit.Before(expires) {
+		expires = limit
+	}
+	return int(expires.Sub(now).Seconds())
 }
 
 // GetSession retrieves the current session
 func (s *SessionService) GetSession(r *http.Request) (*sessions.Session, error) {
-	return s.store.Get(r, SessionName)
+	return s.cookieStore().Get(r, SessionName)
 }
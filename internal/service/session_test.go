@@ -0,0 +1,83 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionService_Lifetimes(t *testing.T) {
+	lifetimes := models.SessionLifetimes{SessionHours: 2, RememberMeDays: 7, AbsoluteDays: 10, Sliding: true}
+	sessions := NewSessionService("secret", func() models.SessionLifetimes { return lifetimes })
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	sessions.now = func() time.Time { return now }
+
+	var cookies []*http.Cookie
+	request := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range cookies {
+			r.AddCookie(cookie)
+		}
+		return r
+	}
+	// refresh makes a request and keeps the cookie if it was rewritten
+	refresh := func() {
+		recorder := httptest.NewRecorder()
+		require.NoError(t, sessions.RefreshSession(recorder, request()))
+		if rewritten := recorder.Result().Cookies(); len(rewritten) > 0 {
+			cookies = rewritten
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	require.NoError(t, sessions.CreateSession(recorder, request(), false, RoleAdmin))
+	cookies = recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, 2*60*60, cookies[0].MaxAge)
+
+	// Activity renews the idle lifetime
+	now = now.Add(90 * time.Minute)
+	refresh()
+	now = now.Add(90 * time.Minute)
+	assert.True(t, sessions.IsAuthenticated(request()))
+
+	// Idle for longer than the lifetime
+	now = now.Add(3 * time.Hour)
+	assert.False(t, sessions.IsAuthenticated(request()))
+
+	// Remember me sessions are capped by the absolute lifetime however active
+	now = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	recorder = httptest.NewRecorder()
+	require.NoError(t, sessions.CreateSession(recorder, request(), true, RoleAdmin))
+	cookies = recorder.Result().Cookies()
+	for day := 0; day < 9; day++ {
+		now = now.Add(24 * time.Hour)
+		refresh()
+		require.True(t, sessions.IsAuthenticated(request()))
+	}
+	assert.Equal(t, 24*60*60, cookies[0].MaxAge)
+	now = now.Add(24 * time.Hour)
+	assert.False(t, sessions.IsAuthenticated(request()))
+
+	// Without sliding expiration sessions end a fixed time after login, and
+	// shorter lifetimes apply to existing sessions
+	now = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	lifetimes.Sliding = false
+	recorder = httptest.NewRecorder()
+	require.NoError(t, sessions.CreateSession(recorder, request(), true, RoleAdmin))
+	cookies = recorder.Result().Cookies()
+	now = now.Add(2 * 24 * time.Hour)
+	refresh()
+	assert.True(t, sessions.IsAuthenticated(request()))
+	lifetimes.RememberMeDays = 1
+	assert.False(t, sessions.IsAuthenticated(request()))
+
+	assert.Error(t, models.SessionLifetimes{SessionHours: 48, RememberMeDays: 30, AbsoluteDays: 1}.Validate())
+	assert.NoError(t, models.DefaultSessionLifetimes.Validate())
+}
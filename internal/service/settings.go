@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"subvault/internal/i18n"
 	"subvault/internal/repository"
 	"sync"
 	"time"
@@ -23,6 +24,8 @@ const (
 	SettingKeyAuthEnabled       = "auth_enabled"
 	SettingKeyAuthUsername      = "auth_username"
 	SettingKeyAuthPasswordHash  = "auth_password_hash"
+	SettingKeyViewerUsername    = "auth_viewer_username"
+	SettingKeyViewerPassword    = "auth_viewer_password_hash"
 	SettingKeyAuthSessionSecret = "auth_session_secret"
 	SettingKeyCSRFSecret        = "csrf_secret"
 	SettingKeyAuthResetToken    = "auth_reset_token"
@@ -30,6 +33,27 @@ const (
 	SettingKeyShoutrrrConfig    = "shoutrrr_config"
 	SettingKeyPushoverConfig       = "pushover_config"
 	SettingKeyCurrencyRefreshHours = "currency_refresh_hours"
+	SettingKeyDeliveryWindows      = "delivery_windows"
+	SettingKeyChannelLanguages     = "notification_languages"
+	SettingKeyNotificationQueue    = "notification_queue"
+	SettingKeyBudgetRolloverSince  = "budget_rollover_since"
+	SettingKeySubscriptionDefaults = "subscription_defaults"
+	SettingKeyBankConnection       = "bank_connection"
+	SettingKeyBankCandidates       = "bank_candidates"
+	SettingKeyBudgetConnection     = "budget_connection"
+	SettingKeyInboundEmailToken    = "inbound_email_token"
+	SettingKeyDisplayRounding      = "display_rounding"
+	SettingKeyLogoPrivacyMode      = "logo_privacy_mode"
+	SettingKeyUpdateCheck          = "update_check_enabled"
+	SettingKeySessionLifetimes     = "session_lifetimes"
+	SettingKeyPasswordPolicy       = "password_policy"
+	SettingKeyCalendarFeed         = "calendar_feed"
+	SettingKeyCSVColumns           = "csv_export_columns"
+	SettingKeyRenewalWindowDays    = "renewal_window_days"
+	SettingKeyUpcomingRenewals     = "upcoming_renewals_limit"
+	SettingKeyChartTables          = "chart_tables"
+	SettingKeyAccentColor          = "accent_color"
+	SettingKeyCustomCSS            = "custom_css"
 )
 
 type SettingsService struct {
@@ -177,73 +201,6 @@ func (s *SettingsService) GetFloatSettingWithDefault(key string, defaultValue fl
 
 // CurrencySymbolForCode returns the symbol for a given currency code
 func CurrencySymbolForCode(code string) string {
-	switch code {
-	case "EUR":
-		return "€"
-	case "PLN":
-		return "zł"
-	case "GBP":
-		return "£"
-	case "RUB":
-		return "₽"
-	case "JPY":
-		return "¥"
-	case "SEK":
-		return "kr"
-	case "INR":
-		return "₹"
-	case "CHF":
-		return "Fr."
-	case "BRL":
-		return "R$"
-	case "BDT":
-		return "৳"
-	case "AUD":
-		return "A$"
-	case "CAD":
-		return "C$"
-	case "CNY":
-		return "¥"
-	case "CZK":
-		return "Kč"
-	case "DKK":
-		return "kr"
-	case "HKD":
-		return "HK$"
-	case "HUF":
-		return "Ft"
-	case "IDR":
-		return "Rp"
-	case "ILS":
-		return "₪"
-	case "ISK":
-		return "kr"
-	case "KRW":
-		return "₩"
-	case "MXN":
-		return "MX$"
-	case "MYR":
-		return "RM"
-	case "NOK":
-		return "kr"
-	case "NZD":
-		return "NZ$"
-	case "PHP":
-		return "₱"
-	case "RON":
-		return "lei"
-	case "SGD":
-		return "S$"
-	case "THB":
-		return "฿"
-	case "TRY":
-		return "₺"
-	case "ZAR":
-		return "R"
-	case "COP":
-		return "COL$"
-	default:
-		return "$"
-	}
+	return i18n.CurrencySymbol(code)
 }
 
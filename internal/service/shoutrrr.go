@@ -6,6 +6,7 @@ import (
 	"strings"
 	"subvault/internal/i18n"
 	"subvault/internal/models"
+	"time"
 
 	"github.com/containrrr/shoutrrr"
 	t "github.com/containrrr/shoutrrr/pkg/types"
@@ -28,12 +29,25 @@ func NewShoutrrrService(preferences PreferencesServiceInterface, notifConfig Not
 	return svc
 }
 
+// Channel returns the notification channel name
+func (s *ShoutrrrService) Channel() string {
+	return models.ChannelShoutrrr
+}
+
+// language returns the language of push notifications, the channel's
+// override or else the app language
+func (s *ShoutrrrService) language() string {
+	if lang := s.notifConfig.ChannelLanguage(models.ChannelShoutrrr); lang != "" {
+		return lang
+	}
+	return s.preferences.GetLanguage()
+}
+
 func (s *ShoutrrrService) tr(messageID string) string {
 	if s.i18nService == nil {
 		return messageID
 	}
-	lang := s.preferences.GetLanguage()
-	localizer := s.i18nService.NewLocalizer(lang)
+	localizer := s.i18nService.NewLocalizer(s.language())
 	return s.i18nService.T(localizer, messageID)
 }
 
@@ -41,22 +55,58 @@ func (s *ShoutrrrService) tPlural(messageID string, count int, data map[string]i
 	if s.i18nService == nil {
 		return messageID
 	}
-	lang := s.preferences.GetLanguage()
-	localizer := s.i18nService.NewLocalizer(lang)
+	localizer := s.i18nService.NewLocalizer(s.language())
 	return s.i18nService.TPluralCount(localizer, messageID, count, data)
 }
 
+// amount formats an amount of the display currency with the display rounding
+func (s *ShoutrrrService) amount(v float64) string {
+	return s.preferences.FormatAmount(v, "")
+}
+
+// sendToAll sends a notification to all configured URLs, or queues it when the
+// push delivery window is closed. In offline mode Shoutrrr counts as not
+// configured, so nothing is queued or retried.
 func (s *ShoutrrrService) sendToAll(title, message string) error {
+	if OfflineMode() {
+		return fmt.Errorf("%w: %w", ErrChannelNotConfigured, ErrOfflineMode)
+	}
 	config, err := s.notifConfig.GetShoutrrrConfig()
 	if err != nil {
-		return fmt.Errorf("failed to get Shoutrrr config: %w", err)
+		return fmt.Errorf("%w: failed to get Shoutrrr config: %v", ErrChannelNotConfigured, err)
 	}
 
 	if len(config.URLs) == 0 {
-		return fmt.Errorf("Shoutrrr not configured: no notification URLs defined")
+		return fmt.Errorf("%w: no Shoutrrr URLs defined", ErrChannelNotConfigured)
+	}
+
+	if !s.notifConfig.DeliveryAllowed(models.ChannelShoutrrr, time.Now()) {
+		return s.notifConfig.QueueNotification(models.ChannelShoutrrr, title, message)
+	}
+	return s.deliver(config.URLs, title, message)
+}
+
+// FlushQueued sends the notifications deferred by the delivery window if it is open
+func (s *ShoutrrrService) FlushQueued(now time.Time) (int, error) {
+	return s.notifConfig.FlushQueue(models.ChannelShoutrrr, now, func(title, message string) error {
+		config, err := s.notifConfig.GetShoutrrrConfig()
+		if err != nil {
+			return fmt.Errorf("%w: failed to get Shoutrrr config: %v", ErrChannelNotConfigured, err)
+		}
+		return s.deliver(config.URLs, title, message)
+	})
+}
+
+// deliver sends a notification to the given URLs immediately
+func (s *ShoutrrrService) deliver(urls []string, title, message string) error {
+	if len(urls) == 0 {
+		return fmt.Errorf("%w: no Shoutrrr URLs defined", ErrChannelNotConfigured)
+	}
+	if OfflineMode() {
+		return fmt.Errorf("%w: %w", ErrChannelNotConfigured, ErrOfflineMode)
 	}
 
-	sender, err := shoutrrr.CreateSender(config.URLs...)
+	sender, err := shoutrrr.CreateSender(urls...)
 	if err != nil {
 		return fmt.Errorf("failed to create Shoutrrr sender: %w", err)
 	}
@@ -87,6 +137,9 @@ func (s *ShoutrrrService) SendTestNotification(urls []string) error {
 	if len(urls) == 0 {
 		return fmt.Errorf("no notification URLs provided")
 	}
+	if OfflineMode() {
+		return ErrOfflineMode
+	}
 
 	sender, err := shoutrrr.CreateSender(urls...)
 	if err != nil {
@@ -113,12 +166,13 @@ func (s *ShoutrrrService) SendTestNotification(urls []string) error {
 }
 
 func (s *ShoutrrrService) SendHighCostAlert(subscription *models.Subscription) error {
+	subscription = plainSubscription(subscription)
 	currencySymbol := s.preferences.GetCurrencySymbol()
 
 	message := fmt.Sprintf("\u26a0\ufe0f %s\n\n", s.tr("shoutrrr_high_cost_alert"))
 	message += fmt.Sprintf("%s %s\n", s.tr("email_name"), subscription.Name)
-	message += fmt.Sprintf("%s %s%.2f %s\n", s.tr("shoutrrr_cost"), currencySymbol, subscription.Cost, subscription.Schedule)
-	message += fmt.Sprintf("%s %s%.2f\n", s.tr("shoutrrr_monthly_cost"), currencySymbol, subscription.MonthlyCost())
+	message += fmt.Sprintf("%s %s%s %s\n", s.tr("shoutrrr_cost"), currencySymbol, s.amount(subscription.Cost), subscription.Schedule)
+	message += fmt.Sprintf("%s %s%s\n", s.tr("shoutrrr_monthly_cost"), currencySymbol, s.amount(subscription.MonthlyCost()))
 	if subscription.Category.Name != "" {
 		message += fmt.Sprintf("%s %s\n", s.tr("shoutrrr_category"), subscription.Category.Name)
 	}
@@ -139,14 +193,16 @@ func (s *ShoutrrrService) SendHighCostAlert(subscription *models.Subscription) e
 }
 
 func (s *ShoutrrrService) SendRenewalReminder(subscription *models.Subscription, daysUntilRenewal int) error {
+	subscription = plainSubscription(subscription)
 	currencySymbol := s.preferences.GetCurrencySymbol()
 	renewalText := s.tPlural("email_renewal_reminder", daysUntilRenewal, map[string]interface{}{"Name": subscription.Name})
 
 	message := fmt.Sprintf("\U0001f514 %s\n\n", s.tr("shoutrrr_renewal_reminder"))
 	message += renewalText + "\n\n"
+	message += s.cancelInstructions(subscription)
 	message += s.tr("shoutrrr_sub_details") + "\n"
-	message += fmt.Sprintf("%s %s%.2f %s\n", s.tr("shoutrrr_cost"), currencySymbol, subscription.Cost, subscription.Schedule)
-	message += fmt.Sprintf("%s %s%.2f\n", s.tr("shoutrrr_monthly_cost"), currencySymbol, subscription.MonthlyCost())
+	message += fmt.Sprintf("%s %s%s %s\n", s.tr("shoutrrr_cost"), currencySymbol, s.amount(subscription.Cost), subscription.Schedule)
+	message += fmt.Sprintf("%s %s%s\n", s.tr("shoutrrr_monthly_cost"), currencySymbol, s.amount(subscription.MonthlyCost()))
 	if subscription.Category.Name != "" {
 		message += fmt.Sprintf("%s %s\n", s.tr("shoutrrr_category"), subscription.Category.Name)
 	}
@@ -167,14 +223,16 @@ func (s *ShoutrrrService) SendRenewalReminder(subscription *models.Subscription,
 }
 
 func (s *ShoutrrrService) SendCancellationReminder(subscription *models.Subscription, daysUntilCancellation int) error {
+	subscription = plainSubscription(subscription)
 	currencySymbol := s.preferences.GetCurrencySymbol()
 	cancellationText := s.tPlural("email_cancellation_reminder", daysUntilCancellation, map[string]interface{}{"Name": subscription.Name})
 
 	message := fmt.Sprintf("\u26a0\ufe0f %s\n\n", s.tr("shoutrrr_cancellation_reminder"))
 	message += cancellationText + "\n\n"
+	message += s.cancelInstructions(subscription)
 	message += s.tr("shoutrrr_sub_details") + "\n"
-	message += fmt.Sprintf("%s %s%.2f %s\n", s.tr("shoutrrr_cost"), currencySymbol, subscription.Cost, subscription.Schedule)
-	message += fmt.Sprintf("%s %s%.2f\n", s.tr("shoutrrr_monthly_cost"), currencySymbol, subscription.MonthlyCost())
+	message += fmt.Sprintf("%s %s%s %s\n", s.tr("shoutrrr_cost"), currencySymbol, s.amount(subscription.Cost), subscription.Schedule)
+	message += fmt.Sprintf("%s %s%s\n", s.tr("shoutrrr_monthly_cost"), currencySymbol, s.amount(subscription.MonthlyCost()))
 	if subscription.Category.Name != "" {
 		message += fmt.Sprintf("%s %s\n", s.tr("shoutrrr_category"), subscription.Category.Name)
 	}
@@ -194,12 +252,154 @@ func (s *ShoutrrrService) SendCancellationReminder(subscription *models.Subscrip
 	return nil
 }
 
-func (s *ShoutrrrService) SendBudgetExceededAlert(totalSpend, budget float64, currencySymbol string) error {
-	message := fmt.Sprintf("%s\n%s: %s%.2f\n%s: %s%.2f\n%s: %s%.2f",
-		s.tr("budget_exceeded_alert"),
-		s.tr("dashboard_budget"), currencySymbol, budget,
-		s.tr("analytics_monthly_cost"), currencySymbol, totalSpend,
-		s.tr("dashboard_budget_exceeded"), currencySymbol, totalSpend-budget,
+// cancelInstructions is the message paragraph telling how to cancel a
+// subscription, empty when neither a cancellation page nor steps are known
+func (s *ShoutrrrService) cancelInstructions(subscription *models.Subscription) string {
+	url := CancelURL(subscription)
+	if url == "" && subscription.CancelNotes == "" {
+		return ""
+	}
+	text := s.tr("shoutrrr_cancel_how")
+	if url != "" {
+		text += " " + url
+	}
+	if subscription.CancelNotes != "" {
+		text += "\n" + subscription.CancelNotes
+	}
+	return text + "\n\n"
+}
+
+// SendGracePeriodReminder notifies that the service of a subscription with a failed payment will be
+// cut off
+func (s *ShoutrrrService) SendGracePeriodReminder(subscription *models.Subscription, daysUntilCutoff int) error {
+	subscription = plainSubscription(subscription)
+	currencySymbol := s.preferences.GetCurrencySymbol()
+	reminderText := s.tPlural("email_grace_period_reminder", daysUntilCutoff, map[string]interface{}{"Name": subscription.Name})
+
+	message := fmt.Sprintf("\u26a0\ufe0f %s\n\n", s.tr("shoutrrr_grace_period_reminder"))
+	message += reminderText + "\n\n"
+	message += s.tr("shoutrrr_sub_details") + "\n"
+	message += fmt.Sprintf("%s %s%s %s\n", s.tr("shoutrrr_cost"), currencySymbol, s.amount(subscription.Cost), subscription.Schedule)
+	if subscription.PaymentMethod != "" {
+		message += fmt.Sprintf("%s %s\n", s.tr("email_payment_method"), subscription.PaymentMethod)
+	}
+	if subscription.PaymentRetryDate != nil {
+		message += fmt.Sprintf("%s %s\n", s.tr("email_payment_retry_date"), subscription.PaymentRetryDate.Format("January 2, 2006"))
+	}
+	if subscription.GracePeriodEnd != nil {
+		message += fmt.Sprintf("%s %s\n", s.tr("email_grace_period_end"), subscription.GracePeriodEnd.Format("January 2, 2006"))
+	}
+	if subscription.URL != "" {
+		message += fmt.Sprintf("%s %s", s.tr("shoutrrr_url"), subscription.URL)
+	}
+
+	title := fmt.Sprintf("%s: %s", s.tr("shoutrrr_grace_period_reminder"), subscription.Name)
+
+	if err := s.sendToAll(title, message); err != nil {
+		slog.Error("failed to send grace period reminder via Shoutrrr", "error", err)
+		return err
+	}
+	return nil
+}
+
+// SendContractReminder notifies that the decision deadline of a contract is near: the last day to
+// cancel an auto-renewing contract, or the end of a fixed term
+func (s *ShoutrrrService) SendContractReminder(subscription *models.Subscription, daysUntilDeadline int) error {
+	subscription = plainSubscription(subscription)
+	currencySymbol := s.preferences.GetCurrencySymbol()
+	reminderText := s.tPlural(contractReminderKey(subscription), daysUntilDeadline, map[string]interface{}{"Name": subscription.Name})
+	now := time.Now()
+
+	message := fmt.Sprintf("\U0001f4dd %s\n\n", s.tr("shoutrrr_contract_reminder"))
+	message += reminderText + "\n\n"
+	message += s.tr("shoutrrr_sub_details") + "\n"
+	message += fmt.Sprintf("%s %s%s %s\n", s.tr("shoutrrr_cost"), currencySymbol, s.amount(subscription.Cost), subscription.Schedule)
+	if subscription.ContractNumber != "" {
+		message += fmt.Sprintf("%s %s\n", s.tr("email_contract_number"), subscription.ContractNumber)
+	}
+	if decideBy := subscription.ContractDecideBy(now); decideBy != nil {
+		message += fmt.Sprintf("%s %s\n", s.tr("email_contract_decide_by"), decideBy.Format("January 2, 2006"))
+	}
+	if exit := subscription.EarliestExitDate(now); exit != nil {
+		message += fmt.Sprintf("%s %s\n", s.tr("email_contract_exit_date"), exit.Format("January 2, 2006"))
+	}
+	if subscription.URL != "" {
+		message += fmt.Sprintf("%s %s", s.tr("shoutrrr_url"), subscription.URL)
+	}
+
+	title := fmt.Sprintf("%s: %s", s.tr("shoutrrr_contract_reminder"), subscription.Name)
+
+	if err := s.sendToAll(title, message); err != nil {
+		slog.Error("failed to send contract reminder via Shoutrrr", "error", err)
+		return err
+	}
+	return nil
+}
+
+// SendPromoEndReminder sends a reminder via Shoutrrr that a promotional price ends soon
+func (s *ShoutrrrService) SendPromoEndReminder(subscription *models.Subscription, daysUntilEnd int) error {
+	subscription = plainSubscription(subscription)
+	currencySymbol := CurrencySymbolForCode(subscription.OriginalCurrency)
+	reminderText := s.tPlural("email_promo_end_reminder", daysUntilEnd, map[string]interface{}{"Name": subscription.Name})
+
+	message := fmt.Sprintf("\U0001f4c8 %s\n\n", s.tr("shoutrrr_promo_end_reminder"))
+	message += reminderText + "\n\n"
+	message += s.tr("shoutrrr_sub_details") + "\n"
+	if subscription.PromoCost != nil {
+		message += fmt.Sprintf("%s %s%s\n", s.tr("email_promo_cost"), currencySymbol, s.preferences.FormatAmount(*subscription.PromoCost, subscription.OriginalCurrency))
+	}
+	message += fmt.Sprintf("%s %s%s %s\n", s.tr("email_regular_cost"), currencySymbol, s.preferences.FormatAmount(subscription.Cost, subscription.OriginalCurrency), subscription.Schedule)
+	if subscription.PromoEndDate != nil {
+		message += fmt.Sprintf("%s %s\n", s.tr("email_promo_end_date"), subscription.PromoEndDate.Format("January 2, 2006"))
+	}
+	if subscription.URL != "" {
+		message += fmt.Sprintf("%s %s", s.tr("shoutrrr_url"), subscription.URL)
+	}
+
+	title := fmt.Sprintf("%s: %s", s.tr("shoutrrr_promo_end_reminder"), subscription.Name)
+
+	if err := s.sendToAll(title, message); err != nil {
+		slog.Error("failed to send promo end reminder via Shoutrrr", "error", err)
+		return err
+	}
+	return nil
+}
+
+// SendPaidThroughReminder sends a reminder via Shoutrrr that access to a cancelled subscription ends soon
+func (s *ShoutrrrService) SendPaidThroughReminder(subscription *models.Subscription, daysUntilEnd int) error {
+	subscription = plainSubscription(subscription)
+	reminderText := s.tPlural("email_paid_through_reminder", daysUntilEnd, map[string]interface{}{"Name": subscription.Name})
+
+	message := fmt.Sprintf("\U0001f6aa %s\n\n", s.tr("shoutrrr_paid_through_reminder"))
+	message += reminderText + "\n\n"
+	message += s.tr("shoutrrr_sub_details") + "\n"
+	if subscription.PaidThroughDate != nil {
+		message += fmt.Sprintf("%s %s\n", s.tr("email_paid_through_date"), subscription.PaidThroughDate.Format("January 2, 2006"))
+	}
+	if subscription.PaymentMethod != "" {
+		message += fmt.Sprintf("%s %s\n", s.tr("email_payment_method"), subscription.PaymentMethod)
+	}
+	if subscription.URL != "" {
+		message += fmt.Sprintf("%s %s", s.tr("shoutrrr_url"), subscription.URL)
+	}
+
+	title := fmt.Sprintf("%s: %s", s.tr("shoutrrr_paid_through_reminder"), subscription.Name)
+
+	if err := s.sendToAll(title, message); err != nil {
+		slog.Error("failed to send paid-through reminder via Shoutrrr", "error", err)
+		return err
+	}
+	return nil
+}
+
+// SendBudgetExceededAlert notifies that the spend of period (monthly or annual) exceeds its budget
+func (s *ShoutrrrService) SendBudgetExceededAlert(period string, totalSpend, budget float64, currencySymbol string) error {
+	alert, budgetLabel, spendLabel := budgetAlertKeys(period)
+	message := fmt.Sprintf("%s\n%s: %s%s\n%s: %s%s\n%s: %s%s",
+		s.tr(alert),
+		s.tr(budgetLabel), currencySymbol, s.amount(budget),
+		s.tr(spendLabel), currencySymbol, s.amount(totalSpend),
+		s.tr("dashboard_budget_exceeded"), currencySymbol, s.amount(totalSpend-budget),
 	)
 
 	title := s.tr("shoutrrr_budget_exceeded")
@@ -210,3 +410,157 @@ func (s *ShoutrrrService) SendBudgetExceededAlert(totalSpend, budget float64, cu
 	}
 	return nil
 }
+
+func (s *ShoutrrrService) SendUnusedSubscriptionsNudge(nudge *UnusedNudge) error {
+	currencySymbol := s.preferences.GetCurrencySymbol()
+
+	message := s.tr("email_unused_intro") + "\n\n"
+	for _, sub := range nudge.Subscriptions {
+		message += fmt.Sprintf("• %s: %s%s\n", plainText(sub.Name), currencySymbol, s.amount(sub.MonthlyCost))
+	}
+	message += fmt.Sprintf("\n%s %s%s", s.tr("email_unused_savings"), currencySymbol, s.amount(nudge.MonthlySavings))
+
+	title := s.tr("shoutrrr_unused_nudge")
+
+	if err := s.sendToAll(title, message); err != nil {
+		slog.Error("failed to send unused subscriptions nudge via Shoutrrr", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *ShoutrrrService) SendExchangeRateAlert(alert *RateAlert) error {
+	currencySymbol := CurrencySymbolForCode(alert.Currency)
+
+	message := s.tr("email_rate_alert_intro") + "\n"
+	for _, change := range alert.Changes {
+		message += fmt.Sprintf("\n%s → %s: %+.1f%%\n", change.Currency, alert.Currency, change.ChangePercent)
+		for _, sub := range change.Subscriptions {
+			message += fmt.Sprintf("• %s: %s%s → %s%s\n", plainText(sub.Name), currencySymbol, s.preferences.FormatAmount(sub.OldMonthlyCost, alert.Currency), currencySymbol, s.preferences.FormatAmount(sub.NewMonthlyCost, alert.Currency))
+		}
+	}
+
+	title := s.tr("shoutrrr_rate_alert")
+
+	if err := s.sendToAll(title, strings.TrimRight(message, "\n")); err != nil {
+		slog.Error("failed to send exchange rate alert via Shoutrrr", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *ShoutrrrService) SendSettlementReport(report *SettlementReport) error {
+	currencySymbol := s.preferences.GetCurrencySymbol()
+
+	var message string
+	for _, person := range report.People {
+		message += fmt.Sprintf("%s: %s%s\n", plainText(person.Person), currencySymbol, s.amount(person.Total))
+		for _, item := range person.Items {
+			message += fmt.Sprintf("  • %s: %s%s\n", plainText(item.Name), currencySymbol, s.amount(item.Amount))
+		}
+	}
+
+	title := fmt.Sprintf("%s %s", s.tr("split_settlement_title"), report.Month)
+
+	if err := s.sendToAll(title, strings.TrimRight(message, "\n")); err != nil {
+		slog.Error("failed to send settlement report via Shoutrrr", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *ShoutrrrService) SendRenewalConfirmations(payments []models.Payment) error {
+	message := s.tr("email_renewal_confirm_intro") + "\n\n"
+	for _, p := range payments {
+		message += fmt.Sprintf("• %s (%s): %s%s\n", plainText(p.Name), p.DueDate.Format("January 2, 2006"), CurrencySymbolForCode(p.Currency), s.preferences.FormatAmount(p.Amount, p.Currency))
+	}
+	message += "\n" + s.tr("email_renewal_confirm_hint")
+
+	title := s.tr("email_renewal_confirm_title")
+
+	if err := s.sendToAll(title, message); err != nil {
+		slog.Error("failed to send renewal confirmations via Shoutrrr", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *ShoutrrrService) SendLoginAlert(event *models.LoginEvent) error {
+	message := s.tr("email_login_alert_intro") + "\n\n"
+	message += fmt.Sprintf("%s %s\n", s.tr("login_history_username"), plainText(event.Username))
+	message += fmt.Sprintf("%s %s\n", s.tr("login_history_time"), event.CreatedAt.Format("January 2, 2006 15:04 MST"))
+	message += fmt.Sprintf("%s %s\n", s.tr("login_history_ip"), event.IP)
+	message += fmt.Sprintf("%s %s\n\n", s.tr("login_history_device"), plainText(event.UserAgent))
+	message += s.tr("email_login_alert_hint")
+
+	title := s.tr("email_login_alert_title")
+
+	if err := s.sendToAll(title, message); err != nil {
+		slog.Error("failed to send login alert via Shoutrrr", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *ShoutrrrService) SendWeeklySummary(summary *WeeklySummary) error {
+	message := fmt.Sprintf("%s – %s\n", summary.Since.Format("January 2, 2006"), summary.Until.Format("January 2, 2006"))
+	if !summary.HasChanges() {
+		message += s.tr("email_weekly_summary_no_changes") + "\n"
+	}
+	sections := []struct {
+		label string
+		items []WeeklySummaryItem
+	}{
+		{"email_weekly_summary_added", summary.Added},
+		{"email_weekly_summary_edited", summary.Edited},
+		{"email_weekly_summary_cancelled", summary.Cancelled},
+		{"email_weekly_summary_deleted", summary.Deleted},
+	}
+	for _, section := range sections {
+		if len(section.items) == 0 {
+			continue
+		}
+		message += "\n" + s.tr(section.label) + "\n"
+		for _, item := range section.items {
+			message += fmt.Sprintf("• %s: %s%s\n", plainText(item.Name), CurrencySymbolForCode(item.Currency), s.preferences.FormatAmount(item.Cost, item.Currency))
+		}
+	}
+	if len(summary.PriceChanges) > 0 {
+		message += "\n" + s.tr("email_weekly_summary_price_changes") + "\n"
+		for _, change := range summary.PriceChanges {
+			message += fmt.Sprintf("• %s: %s%s → %s%s\n", plainText(change.Name),
+				CurrencySymbolForCode(change.OldCurrency), s.preferences.FormatAmount(change.OldCost, change.OldCurrency),
+				CurrencySymbolForCode(change.NewCurrency), s.preferences.FormatAmount(change.NewCost, change.NewCurrency))
+		}
+	}
+
+	symbol := CurrencySymbolForCode(summary.Currency)
+	message += "\n" + s.tr("email_weekly_summary_renewals") + "\n"
+	if len(summary.Renewals) == 0 {
+		message += s.tr("email_weekly_summary_no_renewals") + "\n"
+	}
+	for _, renewal := range summary.Renewals {
+		message += fmt.Sprintf("• %s (%s): %s%s\n", plainText(renewal.Name), renewal.Date.Format("January 2, 2006"), CurrencySymbolForCode(renewal.Currency), s.preferences.FormatAmount(renewal.Cost, renewal.Currency))
+	}
+	if len(summary.Renewals) > 0 {
+		message += fmt.Sprintf("%s: %s%s\n", s.tr("email_weekly_summary_renewals_total"), symbol, s.preferences.FormatAmount(summary.RenewalsTotal, ""))
+	}
+
+	message += "\n" + s.tr("email_weekly_summary_budget") + "\n"
+	message += fmt.Sprintf("%s: %s%s", s.tr("analytics_monthly_cost"), symbol, s.preferences.FormatAmount(summary.MonthlySpend, ""))
+	if summary.MonthlyBudget > 0 {
+		message += fmt.Sprintf(" / %s%s", symbol, s.preferences.FormatAmount(summary.MonthlyBudget, ""))
+	}
+	message += fmt.Sprintf("\n%s: %s%s", s.tr("dashboard_annual_spend"), symbol, s.preferences.FormatAmount(summary.AnnualSpend, ""))
+	if summary.AnnualBudget > 0 {
+		message += fmt.Sprintf(" / %s%s", symbol, s.preferences.FormatAmount(summary.AnnualBudget, ""))
+	}
+
+	title := s.tr("email_weekly_summary_title")
+
+	if err := s.sendToAll(title, message); err != nil {
+		slog.Error("failed to send weekly summary via Shoutrrr", "error", err)
+		return err
+	}
+	return nil
+}
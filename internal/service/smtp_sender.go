@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// Sender warning codes returned by CheckSender
+const (
+	// SenderWarningFreemailRelay: the From address belongs to a mail provider
+	// with a strict DMARC policy, but mail is sent through another server
+	SenderWarningFreemailRelay = "freemail_relay"
+	// SenderWarningDMARCRelay: the From domain enforces DMARC and mail is
+	// relayed by a server of another domain, which must sign with DKIM for it
+	SenderWarningDMARCRelay = "dmarc_relay"
+	// SenderWarningNoDMARC: the From domain publishes no DMARC policy, which
+	// makes large providers more likely to file reminders as spam
+	SenderWarningNoDMARC = "no_dmarc"
+)
+
+// SenderWarning is a likely deliverability problem of the SMTP sender setup
+type SenderWarning struct {
+	Code   string `json:"code"`
+	Domain string `json:"domain"`
+	Host   string `json:"host"`
+	Policy string `json:"policy,omitempty"`
+}
+
+// freemailProviders maps the domains of mail providers that publish a
+// quarantine or reject DMARC policy to the domains of their SMTP servers
+var freemailProviders = map[string][]string{
+	"gmail.com":      {"gmail.com", "google.com", "googlemail.com"},
+	"googlemail.com": {"gmail.com", "google.com", "googlemail.com"},
+	"yahoo.com":      {"yahoo.com"},
+	"ymail.com":      {"yahoo.com"},
+	"aol.com":        {"aol.com", "yahoo.com"},
+	"outlook.com":    {"outlook.com", "office365.com", "live.com", "hotmail.com"},
+	"hotmail.com":    {"outlook.com", "office365.com", "live.com", "hotmail.com"},
+	"live.com":       {"outlook.com", "office365.com", "live.com", "hotmail.com"},
+	"icloud.com":     {"icloud.com", "me.com"},
+	"me.com":         {"icloud.com", "me.com"},
+	"mac.com":        {"icloud.com", "me.com"},
+	"gmx.de":         {"gmx.net", "gmx.com", "gmx.de"},
+	"gmx.net":        {"gmx.net", "gmx.com", "gmx.de"},
+	"web.de":         {"web.de"},
+	"proton.me":      {"proton.me", "protonmail.ch", "protonmail.com"},
+	"protonmail.com": {"proton.me", "protonmail.ch", "protonmail.com"},
+}
+
+// dmarcTimeout bounds the DMARC lookup of CheckSender
+const dmarcTimeout = 3 * time.Second
+
+// lookupTXT resolves TXT records; tests replace it
+var lookupTXT = net.DefaultResolver.LookupTXT
+
+// CheckSender looks for sender setups whose mail is likely to fail SPF or
+// DMARC checks at the recipient: a freemail From address sent through another
+// provider's relay, or a From domain that enforces DMARC while a relay of
+// another domain sends for it. With lookup set, the From domain's DMARC
+// record is resolved; otherwise only the provider list is consulted.
+func CheckSender(ctx context.Context, config *models.SMTPConfig, lookup bool) []SenderWarning {
+	domain := addressDomain(config.From)
+	host := strings.ToLower(strings.TrimSuffix(config.Host, "."))
+	if domain == "" || host == "" {
+		return nil
+	}
+
+	if relays, ok := freemailProviders[domain]; ok {
+		for _, relay := range relays {
+			if hostInDomain(host, relay) {
+				return nil
+			}
+		}
+		return []SenderWarning{{Code: SenderWarningFreemailRelay, Domain: domain, Host: host}}
+	}
+	if !lookup || hostInDomain(host, domain) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dmarcTimeout)
+	defer cancel()
+	records, err := lookupTXT(ctx, "_dmarc."+domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return []SenderWarning{{Code: SenderWarningNoDMARC, Domain: domain, Host: host}}
+		}
+		slog.Debug("DMARC lookup failed", "domain", domain, "error", err)
+		return nil
+	}
+	policy := dmarcPolicy(records)
+	switch policy {
+	case "":
+		return []SenderWarning{{Code: SenderWarningNoDMARC, Domain: domain, Host: host}}
+	case "quarantine", "reject":
+		return []SenderWarning{{Code: SenderWarningDMARCRelay, Domain: domain, Host: host, Policy: policy}}
+	}
+	return nil
+}
+
+// senderWarned remembers the sender setups already warned about
+var senderWarned sync.Map
+
+// WarnSender logs the warnings of CheckSender, once per From address and host.
+// It runs at startup with the DMARC lookup and before each send without it.
+func WarnSender(ctx context.Context, config *models.SMTPConfig, lookup bool) {
+	key := strings.ToLower(config.From + "|" + config.Host)
+	if _, seen := senderWarned.LoadOrStore(key, true); seen {
+		return
+	}
+	for _, warning := range CheckSender(ctx, config, lookup) {
+		slog.Warn("notification emails are likely to fail SPF/DMARC checks", "code", warning.Code, "from_domain", warning.Domain, "smtp_host", warning.Host, "dmarc_policy", warning.Policy)
+	}
+}
+
+// addressDomain returns the lower-cased domain of an email address
+func addressDomain(address string) string {
+	_, domain, found := strings.Cut(strings.TrimSpace(address), "@")
+	if !found {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
+
+// hostInDomain reports whether host is domain or one of its subdomains
+func hostInDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// dmarcPolicy returns the p= tag of the DMARC record among records, empty
+// without a DMARC record
+func dmarcPolicy(records []string) string {
+	for _, record := range records {
+		if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(record)), "v=dmarc1") {
+			continue
+		}
+		for _, tag := range strings.Split(record, ";") {
+			name, value, found := strings.Cut(strings.TrimSpace(tag), "=")
+			if found && strings.EqualFold(strings.TrimSpace(name), "p") {
+				return strings.ToLower(strings.TrimSpace(value))
+			}
+		}
+		return "none"
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSender(t *testing.T) {
+	dmarc := map[string][]string{
+		"_dmarc.strict.example": {"v=spf1 -all", "v=DMARC1; p=reject; rua=mailto:dmarc@strict.example"},
+		"_dmarc.lax.example":    {"v=DMARC1; p=none"},
+	}
+	lookups := 0
+	original := lookupTXT
+	lookupTXT = func(_ context.Context, name string) ([]string, error) {
+		lookups++
+		if records, ok := dmarc[name]; ok {
+			return records, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	t.Cleanup(func() { lookupTXT = original })
+
+	check := func(from, host string) []SenderWarning {
+		return CheckSender(t.Context(), &models.SMTPConfig{From: from, Host: host}, true)
+	}
+
+	// Freemail addresses only pass through their own provider
+	assert.Empty(t, check("me@gmail.com", "smtp.gmail.com"))
+	assert.Empty(t, check("me@outlook.com", "smtp.office365.com"))
+	assert.Equal(t, []SenderWarning{{Code: SenderWarningFreemailRelay, Domain: "gmail.com", Host: "smtp.sendgrid.net"}}, check("Me@Gmail.com", "smtp.sendgrid.net"))
+
+	// Own domains are checked against their DMARC policy when relayed
+	assert.Equal(t, []SenderWarning{{Code: SenderWarningDMARCRelay, Domain: "strict.example", Host: "smtp.sendgrid.net", Policy: "reject"}}, check("noreply@strict.example", "smtp.sendgrid.net"))
+	assert.Empty(t, check("noreply@lax.example", "smtp.sendgrid.net"))
+	assert.Equal(t, SenderWarningNoDMARC, check("noreply@bare.example", "smtp.sendgrid.net")[0].Code)
+
+	// The domain's own server needs no lookup
+	lookups = 0
+	assert.Empty(t, check("noreply@strict.example", "mail.strict.example"))
+	assert.Zero(t, lookups)
+
+	// Lookup failures other than a missing record are not reported
+	lookupTXT = func(context.Context, string) ([]string, error) { return nil, errors.New("timeout") }
+	assert.Empty(t, check("noreply@strict.example", "smtp.sendgrid.net"))
+
+	// Without lookup only the provider list is used
+	assert.Empty(t, CheckSender(t.Context(), &models.SMTPConfig{From: "noreply@bare.example", Host: "smtp.sendgrid.net"}, false))
+	assert.Empty(t, check("", "smtp.sendgrid.net"))
+}
+
+func TestDMARCPolicy(t *testing.T) {
+	assert.Equal(t, "quarantine", dmarcPolicy([]string{"v=DMARC1;p=Quarantine;pct=100"}))
+	assert.Equal(t, "none", dmarcPolicy([]string{"v=DMARC1; rua=mailto:x@example.com"}))
+	assert.Empty(t, dmarcPolicy([]string{"google-site-verification=abc"}))
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"subvault/internal/i18n"
+	"subvault/internal/models"
+	"subvault/internal/repository"
+)
+
+// ErrSplitSubscriptionNotFound is returned when configuring shares of an unknown subscription
+var ErrSplitSubscriptionNotFound = errors.New("subscription not found")
+
+// ErrInvalidShare is returned when a split configuration is inconsistent
+var ErrInvalidShare = errors.New("invalid share")
+
+// SettlementItem is one person's monthly part of a single subscription
+type SettlementItem struct {
+	SubscriptionID uint    `json:"subscription_id"`
+	Name           string  `json:"name"`
+	Amount         float64 `json:"amount"`
+}
+
+// PersonSettlement sums up what one person owes per month
+type PersonSettlement struct {
+	Person string           `json:"person"`
+	Total  float64          `json:"total"`
+	Items  []SettlementItem `json:"items"`
+}
+
+// SettlementReport lists who owes what for the current month, in the display currency
+type SettlementReport struct {
+	Month    string             `json:"month"`
+	Currency string             `json:"currency"`
+	People   []PersonSettlement `json:"people"`
+	Total    float64            `json:"total"`
+}
+
+type SplitService struct {
+	repo          *repository.SubscriptionShareRepository
+	subscriptions SubscriptionServiceInterface
+	currency      CurrencyServiceInterface
+	preferences   PreferencesServiceInterface
+}
+
+func NewSplitService(repo *repository.SubscriptionShareRepository, subscriptions SubscriptionServiceInterface, currency CurrencyServiceInterface, preferences PreferencesServiceInterface) *SplitService {
+	return &SplitService{
+		repo:          repo,
+		subscriptions: subscriptions,
+		currency:      currency,
+		preferences:   preferences,
+	}
+}
+
+// GetShares returns the split configuration of a subscription
+func (s *SplitService) GetShares(subscriptionID uint) ([]models.SubscriptionShare, error) {
+	return s.repo.GetBySubscription(subscriptionID)
+}
+
+// SetShares replaces the split configuration of a subscription. An empty list
+// removes the split. Shares may not add up to more than the subscription's cost,
+// and seat shares need a plan with more than one seat.
+func (s *SplitService) SetShares(ctx context.Context, subscriptionID uint, shares []models.SubscriptionShare) error {
+	sub, err := s.subscriptions.GetByID(ctx, subscriptionID)
+	if err != nil {
+		return ErrSplitSubscriptionNotFound
+	}
+
+	monthly := sub.MonthlyCost()
+	seen := make(map[string]bool, len(shares))
+	var portion float64
+	for i := range shares {
+		share := &shares[i]
+		share.Person = strings.TrimSpace(share.Person)
+		if share.Person == "" {
+			return fmt.Errorf("%w: person is required", ErrInvalidShare)
+		}
+		key := strings.ToLower(share.Person)
+		if seen[key] {
+			return fmt.Errorf("%w: %s is listed twice", ErrInvalidShare, share.Person)
+		}
+		seen[key] = true
+
+		switch share.ShareType {
+		case models.ShareTypePercent:
+			if share.Value <= 0 || share.Value > 100 {
+				return fmt.Errorf("%w: percentage for %s must be between 0 and 100", ErrInvalidShare, share.Person)
+			}
+			portion += share.Value / 100
+		case models.ShareTypeFixed:
+			if share.Value <= 0 {
+				return fmt.Errorf("%w: amount for %s must be positive", ErrInvalidShare, share.Person)
+			}
+			if monthly > 0 {
+				portion += share.Value / monthly
+			}
+		case models.ShareTypeSeats:
+			if !sub.IsSeatPlan() {
+				return fmt.Errorf("%w: set the number of seats of %s before assigning seats", ErrInvalidShare, sub.Name)
+			}
+			if share.Value < 1 || share.Value != math.Trunc(share.Value) {
+				return fmt.Errorf("%w: seats for %s must be a whole number of at least 1", ErrInvalidShare, share.Person)
+			}
+			portion += share.Value / float64(sub.Seats)
+		default:
+			return fmt.Errorf("%w: unknown share type %q", ErrInvalidShare, share.ShareType)
+		}
+	}
+	// Allow for rounding of fixed amounts
+	if portion > 1.0001 {
+		return fmt.Errorf("%w: shares exceed the monthly cost", ErrInvalidShare)
+	}
+
+	return s.repo.Replace(subscriptionID, shares)
+}
+
+// GetSettlement builds the monthly settlement for all active subscriptions with shares
+func (s *SplitService) GetSettlement(ctx context.Context) (*SettlementReport, error) {
+	shares, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SettlementReport{
+		Month:    time.Now().Format("2006-01"),
+		Currency: s.preferences.GetCurrency(),
+	}
+	if len(shares) == 0 {
+		return report, nil
+	}
+
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Subscription, len(subscriptions))
+	for i := range subscriptions {
+		byID[subscriptions[i].ID] = &subscriptions[i]
+	}
+
+	people := make(map[string]*PersonSettlement)
+	var order []string
+	for _, share := range shares {
+		sub, ok := byID[share.SubscriptionID]
+		if !ok || sub.Status != "Active" {
+			continue
+		}
+
+		var amount float64
+		switch share.ShareType {
+		case models.ShareTypeFixed:
+			amount = share.Value
+		case models.ShareTypeSeats:
+			// Seats no longer count once the plan's seat count was removed
+			if !sub.IsSeatPlan() {
+				continue
+			}
+			amount = sub.MonthlyCostPerSeat() * share.Value
+		default:
+			amount = sub.MonthlyCost() * share.Value / 100
+		}
+		amount = roundCents(s.convert(amount, sub.OriginalCurrency, report.Currency))
+
+		key := strings.ToLower(share.Person)
+		person, ok := people[key]
+		if !ok {
+			person = &PersonSettlement{Person: share.Person}
+			people[key] = person
+			order = append(order, key)
+		}
+		person.Items = append(person.Items, SettlementItem{SubscriptionID: sub.ID, Name: sub.Name, Amount: amount})
+		person.Total = roundCents(person.Total + amount)
+		report.Total = roundCents(report.Total + amount)
+	}
+
+	sort.Strings(order)
+	for _, key := range order {
+		report.People = append(report.People, *people[key])
+	}
+	return report, nil
+}
+
+// WriteSettlementCSV writes one row per person and subscription, followed by per-person totals
+func (s *SplitService) WriteSettlementCSV(w io.Writer, report *SettlementReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Month", "Person", "Subscription", "Amount", "Currency"}); err != nil {
+		return err
+	}
+	for _, person := range report.People {
+		for _, item := range person.Items {
+			if err := writer.Write([]string{report.Month, csvText(person.Person), csvText(item.Name), i18n.FormatAmount(item.Amount, report.Currency, i18n.RoundingCurrency), report.Currency}); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write([]string{report.Month, csvText(person.Person), "Total", i18n.FormatAmount(person.Total, report.Currency, i18n.RoundingCurrency), report.Currency}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvText keeps a user-entered value from being read as a formula when the CSV
+// is opened in a spreadsheet, by prefixing values that start like one with '
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// convert converts an amount between currencies, keeping it unchanged if no rate is available
+func (s *SplitService) convert(amount float64, from, to string) float64 {
+	if from == "" || from == to {
+		return amount
+	}
+	converted, err := s.currency.ConvertAmount(amount, from, to)
+	if err != nil {
+		return amount
+	}
+	return converted
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
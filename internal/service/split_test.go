@@ -0,0 +1,138 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSplitService(t *testing.T) (*SubscriptionService, *SplitService) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.SubscriptionShare{}, &models.UsageEvent{}, &models.ReminderRetry{}, &models.Payment{}))
+	services := newTestSubscriptionService(t, db)
+
+	return services.subscriptions, NewSplitService(repository.NewSubscriptionShareRepository(db), services.subscriptions, services.currency, services.preferences)
+}
+
+func createSplitTestSubscription(t *testing.T, subscriptionService *SubscriptionService, name string, cost float64, status string) *models.Subscription {
+	sub, err := subscriptionService.Create(t.Context(), &models.Subscription{
+		Name:             name,
+		Cost:             cost,
+		Schedule:         "Monthly",
+		Status:           status,
+		OriginalCurrency: "USD",
+	})
+	require.NoError(t, err)
+	return sub
+}
+
+func TestSplitService_SetSharesValidation(t *testing.T) {
+	subscriptionService, splitService := setupSplitService(t)
+	sub := createSplitTestSubscription(t, subscriptionService, "Family Plan", 20, "Active")
+
+	tests := []struct {
+		name   string
+		shares []models.SubscriptionShare
+	}{
+		{"missing person", []models.SubscriptionShare{{Person: " ", ShareType: models.ShareTypePercent, Value: 10}}},
+		{"duplicate person", []models.SubscriptionShare{
+			{Person: "Alice", ShareType: models.ShareTypePercent, Value: 10},
+			{Person: "alice", ShareType: models.ShareTypeFixed, Value: 2},
+		}},
+		{"percent out of range", []models.SubscriptionShare{{Person: "Bob", ShareType: models.ShareTypePercent, Value: 120}}},
+		{"unknown type", []models.SubscriptionShare{{Person: "Bob", ShareType: "half", Value: 1}}},
+		{"exceeds cost", []models.SubscriptionShare{
+			{Person: "Alice", ShareType: models.ShareTypePercent, Value: 60},
+			{Person: "Bob", ShareType: models.ShareTypeFixed, Value: 10},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, splitService.SetShares(t.Context(), sub.ID, tt.shares), ErrInvalidShare)
+		})
+	}
+
+	assert.ErrorIs(t, splitService.SetShares(t.Context(), 9999, nil), ErrSplitSubscriptionNotFound)
+}
+
+func TestSplitService_Settlement(t *testing.T) {
+	subscriptionService, splitService := setupSplitService(t)
+	family := createSplitTestSubscription(t, subscriptionService, "Family Plan", 20, "Active")
+	music := createSplitTestSubscription(t, subscriptionService, "Music", 15, "Active")
+	paused := createSplitTestSubscription(t, subscriptionService, "Paused", 30, "Paused")
+
+	require.NoError(t, splitService.SetShares(t.Context(), family.ID, []models.SubscriptionShare{
+		{Person: "Bob", ShareType: models.ShareTypePercent, Value: 25},
+		{Person: "Alice", ShareType: models.ShareTypeFixed, Value: 5},
+	}))
+	require.NoError(t, splitService.SetShares(t.Context(), music.ID, []models.SubscriptionShare{
+		{Person: "alice", ShareType: models.ShareTypePercent, Value: 50},
+	}))
+	require.NoError(t, splitService.SetShares(t.Context(), paused.ID, []models.SubscriptionShare{
+		{Person: "Carol", ShareType: models.ShareTypePercent, Value: 50},
+	}))
+
+	report, err := splitService.GetSettlement(t.Context())
+	require.NoError(t, err)
+	require.Len(t, report.People, 2)
+	assert.Equal(t, "Alice", report.People[0].Person)
+	assert.InDelta(t, 12.5, report.People[0].Total, 0.001)
+	assert.Len(t, report.People[0].Items, 2)
+	assert.Equal(t, "Bob", report.People[1].Person)
+	assert.InDelta(t, 5, report.People[1].Total, 0.001)
+	assert.InDelta(t, 17.5, report.Total, 0.001)
+
+	var buf bytes.Buffer
+	require.NoError(t, splitService.WriteSettlementCSV(&buf, report))
+	assert.Contains(t, buf.String(), "Alice,Music,7.50,USD")
+	assert.Contains(t, buf.String(), "Bob,Total,5.00,USD")
+
+	report.People[1].Person = "=HYPERLINK(\"http://evil\")"
+	report.People[0].Items[0].Name = "@SUM(A1)"
+	buf.Reset()
+	require.NoError(t, splitService.WriteSettlementCSV(&buf, report))
+	assert.Contains(t, buf.String(), `"'=HYPERLINK(""http://evil"")",Total`)
+	assert.Contains(t, buf.String(), ",'@SUM(A1),")
+
+	// Replacing with an empty list stops sharing; deleting a subscription removes its shares
+	require.NoError(t, splitService.SetShares(t.Context(), music.ID, nil))
+	require.NoError(t, subscriptionService.Delete(t.Context(), family.ID))
+	report, err = splitService.GetSettlement(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, report.People)
+}
+
+func TestSplitService_SeatShares(t *testing.T) {
+	subscriptionService, splitService := setupSplitService(t)
+	single := createSplitTestSubscription(t, subscriptionService, "Single", 10, "Active")
+	family := createSplitTestSubscription(t, subscriptionService, "Family Plan", 24, "Active")
+	family.Seats = 6
+	_, err := subscriptionService.Update(t.Context(), family.ID, family)
+	require.NoError(t, err)
+
+	// Seats need a plan with seats, whole numbers and no more than the plan has
+	seats := func(person string, value float64) models.SubscriptionShare {
+		return models.SubscriptionShare{Person: person, ShareType: models.ShareTypeSeats, Value: value}
+	}
+	assert.ErrorIs(t, splitService.SetShares(t.Context(), single.ID, []models.SubscriptionShare{seats("Bob", 1)}), ErrInvalidShare)
+	assert.ErrorIs(t, splitService.SetShares(t.Context(), family.ID, []models.SubscriptionShare{seats("Bob", 1.5)}), ErrInvalidShare)
+	assert.ErrorIs(t, splitService.SetShares(t.Context(), family.ID, []models.SubscriptionShare{seats("Bob", 4), seats("Carol", 3)}), ErrInvalidShare)
+
+	require.NoError(t, splitService.SetShares(t.Context(), family.ID, []models.SubscriptionShare{seats("Bob", 2), seats("Carol", 1)}))
+	report, err := splitService.GetSettlement(t.Context())
+	require.NoError(t, err)
+	require.Len(t, report.People, 2)
+	assert.InDelta(t, 8, report.People[0].Total, 0.001)
+	assert.InDelta(t, 4, report.People[1].Total, 0.001)
+
+	// The dashboard shows the effective cost per person
+	stats, err := subscriptionService.GetStats(t.Context())
+	require.NoError(t, err)
+	require.Len(t, stats.SeatPlans, 1)
+	assert.Equal(t, models.SeatSpend{SubscriptionID: family.ID, Name: "Family Plan", Seats: 6, MonthlySpend: 24, PerSeat: 4}, stats.SeatPlans[0])
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"subvault/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// uncategorized is the name shown for subscriptions without a category
+const uncategorized = "Uncategorized"
+
+// ErrCategoryNotFound is returned when drilling into a category that does not exist
+var ErrCategoryNotFound = errors.New("category not found")
+
+// subscriptionCategory returns the category a subscription's spend is counted
+// under; subscriptions without a category are counted under ID 0
+func subscriptionCategory(sub *models.Subscription) (uint, string) {
+	if sub.Category.Name == "" {
+		return 0, uncategorized
+	}
+	return sub.CategoryID, sub.Category.Name
+}
+
+// categorySpends groups the monthly spend of the active subscriptions by
+// category, highest spend first
+func (s *SubscriptionService) categorySpends(subs []models.Subscription, total float64, displayCurrency string) []models.CategorySpend {
+	index := make(map[uint]int)
+	categories := []models.CategorySpend{}
+	for i := range subs {
+		sub := &subs[i]
+		if sub.Status != "Active" {
+			continue
+		}
+		id, name := subscriptionCategory(sub)
+		pos, ok := index[id]
+		if !ok {
+			pos = len(categories)
+			index[id] = pos
+			categories = append(categories, models.CategorySpend{ID: id, Name: name})
+		}
+		categories[pos].MonthlySpend += s.convertAmount(sub.MonthlyCost(), sub.OriginalCurrency, displayCurrency)
+		categories[pos].Count++
+	}
+
+	for i := range categories {
+		if total > 0 {
+			categories[i].Share = roundCents(categories[i].MonthlySpend / total * 100)
+		}
+		categories[i].MonthlySpend = roundCents(categories[i].MonthlySpend)
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].MonthlySpend != categories[j].MonthlySpend {
+			return categories[i].MonthlySpend > categories[j].MonthlySpend
+		}
+		return categories[i].Name < categories[j].Name
+	})
+	return categories
+}
+
+// GetCategoryBreakdown returns the active subscriptions contributing to the
+// monthly spend of a category, optionally limited to one purpose. Category ID 0
+// drills into the subscriptions without a category.
+func (s *SubscriptionService) GetCategoryBreakdown(ctx context.Context, categoryID uint, purpose string) (*models.CategoryBreakdown, error) {
+	breakdown := &models.CategoryBreakdown{
+		ID:            categoryID,
+		Name:          uncategorized,
+		Currency:      s.preferences.GetCurrency(),
+		Purpose:       purpose,
+		Subscriptions: []models.CategorySubscription{},
+	}
+	if categoryID != 0 {
+		category, err := s.categoryService.GetByID(categoryID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		breakdown.Name = category.Name
+	}
+
+	subs, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var total float64
+	for i := range subs {
+		sub := &subs[i]
+		if sub.Status != "Active" || (purpose != "" && sub.EffectivePurpose() != purpose) {
+			continue
+		}
+		if id, _ := subscriptionCategory(sub); id != categoryID {
+			continue
+		}
+		converted := s.convertAmount(sub.MonthlyCost(), sub.OriginalCurrency, breakdown.Currency)
+		total += converted
+		breakdown.Subscriptions = append(breakdown.Subscriptions, models.CategorySubscription{
+			ID:                   sub.ID,
+			Name:                 sub.Name,
+			IconURL:              sub.IconURL,
+			Cost:                 sub.Cost,
+			Schedule:             sub.Schedule,
+			Currency:             sub.OriginalCurrency,
+			MonthlyCost:          roundCents(sub.MonthlyCost()),
+			ConvertedMonthlyCost: converted,
+		})
+	}
+
+	for i := range breakdown.Subscriptions {
+		item := &breakdown.Subscriptions[i]
+		if total > 0 {
+			item.Share = roundCents(item.ConvertedMonthlyCost / total * 100)
+		}
+		item.ConvertedMonthlyCost = roundCents(item.ConvertedMonthlyCost)
+	}
+	sort.SliceStable(breakdown.Subscriptions, func(i, j int) bool {
+		return breakdown.Subscriptions[i].ConvertedMonthlyCost > breakdown.Subscriptions[j].ConvertedMonthlyCost
+	})
+	breakdown.MonthlySpend = roundCents(total)
+	return breakdown, nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"sort"
+
+	"subvault/internal/models"
+)
+
+// currencySpends groups the spend of the active subscriptions by the currency
+// they are billed in, without conversion, highest converted spend first.
+// Subscriptions without a currency count as the display currency.
+func (s *SubscriptionService) currencySpends(subs []models.Subscription, total float64, displayCurrency string) []models.CurrencySpend {
+	index := make(map[string]int)
+	currencies := []models.CurrencySpend{}
+	for i := range subs {
+		sub := &subs[i]
+		if sub.Status != "Active" {
+			continue
+		}
+		currency := sub.OriginalCurrency
+		if currency == "" {
+			currency = displayCurrency
+		}
+		pos, ok := index[currency]
+		if !ok {
+			pos = len(currencies)
+			index[currency] = pos
+			currencies = append(currencies, models.CurrencySpend{Currency: currency})
+		}
+		currencies[pos].MonthlySpend += sub.MonthlyCost()
+		currencies[pos].AnnualSpend += sub.AnnualCost()
+		currencies[pos].Count++
+	}
+
+	for i := range currencies {
+		spend := &currencies[i]
+		spend.ConvertedMonthlySpend = spend.MonthlySpend
+		if spend.Currency != displayCurrency {
+			converted, err := s.currencyService.ConvertAmount(spend.MonthlySpend, spend.Currency, displayCurrency)
+			if err == nil {
+				spend.ConvertedMonthlySpend = converted
+			} else {
+				spend.RateMissing = true
+			}
+		}
+		if total > 0 {
+			spend.Share = roundCents(spend.ConvertedMonthlySpend / total * 100)
+		}
+		spend.MonthlySpend = roundCents(spend.MonthlySpend)
+		spend.AnnualSpend = roundCents(spend.AnnualSpend)
+		spend.ConvertedMonthlySpend = roundCents(spend.ConvertedMonthlySpend)
+	}
+	sort.SliceStable(currencies, func(i, j int) bool {
+		if currencies[i].ConvertedMonthlySpend != currencies[j].ConvertedMonthlySpend {
+			return currencies[i].ConvertedMonthlySpend > currencies[j].ConvertedMonthlySpend
+		}
+		return currencies[i].Currency < currencies[j].Currency
+	})
+	return currencies
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionService_CurrencySpends(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	services := newTestSubscriptionService(t, db)
+	rates := repository.NewExchangeRateRepository(db)
+	require.NoError(t, rates.SaveRates([]models.ExchangeRate{
+		{BaseCurrency: "EUR", Currency: "EUR", Rate: 1.0, Date: time.Now()},
+		{BaseCurrency: "EUR", Currency: "USD", Rate: 2.0, Date: time.Now()},
+	}))
+	require.NoError(t, services.preferences.SetCurrency("EUR"))
+
+	for _, sub := range []models.Subscription{
+		{Name: "Netflix", Cost: 15, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR"},
+		{Name: "GitHub", Cost: 40, Schedule: "Monthly", Status: "Active", OriginalCurrency: "USD"},
+		{Name: "Domain", Cost: 24, Schedule: "Annual", Status: "Active", OriginalCurrency: "USD"},
+		{Name: "Hosting", Cost: 5, Schedule: "Monthly", Status: "Active", OriginalCurrency: "XYZ"},
+		{Name: "Hulu", Cost: 8, Schedule: "Monthly", Status: "Cancelled", OriginalCurrency: "USD"},
+	} {
+		_, err := services.subscriptions.Create(t.Context(), &sub)
+		require.NoError(t, err)
+	}
+
+	stats, err := services.subscriptions.GetStats(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 41.0, stats.TotalMonthlySpend)
+	assert.Equal(t, []models.CurrencySpend{
+		{Currency: "USD", MonthlySpend: 42, AnnualSpend: 504, ConvertedMonthlySpend: 21, Count: 2, Share: 51.22},
+		{Currency: "EUR", MonthlySpend: 15, AnnualSpend: 180, ConvertedMonthlySpend: 15, Count: 1, Share: 36.59},
+		{Currency: "XYZ", MonthlySpend: 5, AnnualSpend: 60, ConvertedMonthlySpend: 5, Count: 1, Share: 12.2, RateMissing: true},
+	}, stats.Currencies)
+}
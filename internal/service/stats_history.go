@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+)
+
+const (
+	// DefaultStatsHistoryMonths is how far back the history goes unless asked otherwise
+	DefaultStatsHistoryMonths = 24
+	// MaxStatsHistoryMonths limits how far back the history can be requested
+	MaxStatsHistoryMonths = 120
+)
+
+// StatsHistoryService records a snapshot of the statistics once a day, for
+// trend graphs that do not change when subscriptions are edited later
+type StatsHistoryService struct {
+	repo          *repository.StatsHistoryRepository
+	subscriptions SubscriptionServiceInterface
+	preferences   PreferencesServiceInterface
+}
+
+// NewStatsHistoryService creates a stats history service
+func NewStatsHistoryService(repo *repository.StatsHistoryRepository, subscriptions SubscriptionServiceInterface, preferences PreferencesServiceInterface) *StatsHistoryService {
+	return &StatsHistoryService{repo: repo, subscriptions: subscriptions, preferences: preferences}
+}
+
+// Record stores the snapshot of the day of now. Recording again on the same
+// day replaces that day's snapshot.
+func (s *StatsHistoryService) Record(ctx context.Context, now time.Time) (*models.StatsSnapshot, error) {
+	stats, err := s.subscriptions.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	displayCurrency := s.preferences.GetCurrency()
+	currencySpend := make(map[string]float64)
+	for i := range stats.AllSubscriptions {
+		sub := &stats.AllSubscriptions[i]
+		if sub.Status != "Active" {
+			continue
+		}
+		currency := sub.OriginalCurrency
+		if currency == "" {
+			currency = displayCurrency
+		}
+		currencySpend[currency] += sub.MonthlyCost()
+	}
+	for currency, spend := range currencySpend {
+		currencySpend[currency] = roundCents(spend)
+	}
+
+	categories := stats.Categories
+	if categories == nil {
+		categories = []models.CategorySpend{}
+	}
+	snapshot := &models.StatsSnapshot{
+		Date:          time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+		ActiveCount:   stats.ActiveSubscriptions,
+		Currency:      displayCurrency,
+		MonthlySpend:  roundCents(stats.TotalMonthlySpend),
+		CurrencySpend: currencySpend,
+		Categories:    categories,
+	}
+	if err := s.repo.Save(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// History returns the snapshots of the last months before now, oldest first.
+// months is clamped to 1..MaxStatsHistoryMonths.
+func (s *StatsHistoryService) History(months int, now time.Time) ([]models.StatsSnapshot, error) {
+	months = max(1, min(months, MaxStatsHistoryMonths))
+	snapshots, err := s.repo.Since(now.AddDate(0, -months, 0))
+	if err != nil {
+		return nil, err
+	}
+	if snapshots == nil {
+		snapshots = []models.StatsSnapshot{}
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHistoryService_Record(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.StatsSnapshot{}))
+	services := newTestSubscriptionService(t, db)
+	history := NewStatsHistoryService(repository.NewStatsHistoryRepository(db), services.subscriptions, services.preferences)
+	currency := services.preferences.GetCurrency()
+
+	streaming, err := services.subscriptions.categoryService.Create(&models.Category{Name: "Streaming"})
+	require.NoError(t, err)
+	netflix, err := services.subscriptions.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 15, Schedule: "Monthly", Status: "Active", OriginalCurrency: currency, CategoryID: streaming.ID})
+	require.NoError(t, err)
+	_, err = services.subscriptions.Create(t.Context(), &models.Subscription{Name: "Hosting", Cost: 120, Schedule: "Annual", Status: "Active", OriginalCurrency: "XYZ"})
+	require.NoError(t, err)
+	_, err = services.subscriptions.Create(t.Context(), &models.Subscription{Name: "Hulu", Cost: 8, Schedule: "Monthly", Status: "Cancelled", OriginalCurrency: currency})
+	require.NoError(t, err)
+
+	now := time.Date(2026, 3, 15, 23, 30, 0, 0, time.Local)
+	lastYear := now.AddDate(-1, 0, 0)
+	snapshot, err := history.Record(t.Context(), lastYear)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 15, 0, 0, 0, 0, time.Local), snapshot.Date)
+	assert.Equal(t, 2, snapshot.ActiveCount)
+	assert.Equal(t, currency, snapshot.Currency)
+	assert.Equal(t, 25.0, snapshot.MonthlySpend) // No rate for XYZ, counted 1:1
+	assert.Equal(t, map[string]float64{currency: 15, "XYZ": 10}, snapshot.CurrencySpend)
+	require.Len(t, snapshot.Categories, 2)
+	assert.Equal(t, "Streaming", snapshot.Categories[0].Name)
+
+	// A price change does not rewrite the recorded history
+	netflix.Cost = 20
+	_, err = services.subscriptions.Update(t.Context(), netflix.ID, netflix)
+	require.NoError(t, err)
+	_, err = history.Record(t.Context(), now.Add(-time.Hour))
+	require.NoError(t, err)
+	snapshot, err = history.Record(t.Context(), now) // Same day: replaces the earlier snapshot
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, snapshot.MonthlySpend)
+
+	snapshots, err := history.History(24, now)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, 25.0, snapshots[0].MonthlySpend)
+	assert.Equal(t, map[string]float64{currency: 15, "XYZ": 10}, snapshots[0].CurrencySpend)
+	assert.Equal(t, 30.0, snapshots[1].MonthlySpend)
+	assert.Equal(t, map[string]float64{currency: 20, "XYZ": 10}, snapshots[1].CurrencySpend)
+
+	snapshots, err = history.History(6, now)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.True(t, snapshots[0].Date.Equal(time.Date(2026, 3, 15, 0, 0, 0, 0, time.Local)))
+}
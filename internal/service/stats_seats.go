@@ -0,0 +1,35 @@
+package service
+
+import (
+	"sort"
+
+	"subvault/internal/models"
+)
+
+// seatSpends lists the active plans with more than one seat and their
+// effective monthly cost per person, highest cost per seat first
+func (s *SubscriptionService) seatSpends(subs []models.Subscription, displayCurrency string) []models.SeatSpend {
+	plans := []models.SeatSpend{}
+	for i := range subs {
+		sub := &subs[i]
+		if sub.Status != "Active" || !sub.IsSeatPlan() {
+			continue
+		}
+		monthly := s.convertAmount(sub.MonthlyCost(), sub.OriginalCurrency, displayCurrency)
+		plans = append(plans, models.SeatSpend{
+			SubscriptionID: sub.ID,
+			Name:           sub.Name,
+			Seats:          sub.Seats,
+			MonthlySpend:   roundCents(monthly),
+			PerSeat:        roundCents(monthly / float64(sub.Seats)),
+		})
+	}
+
+	sort.SliceStable(plans, func(i, j int) bool {
+		if plans[i].PerSeat != plans[j].PerSeat {
+			return plans[i].PerSeat > plans[j].PerSeat
+		}
+		return plans[i].Name < plans[j].Name
+	})
+	return plans
+}
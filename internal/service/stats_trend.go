@@ -0,0 +1,138 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// maxTopMovers limits the number of subscriptions listed as top movers
+const maxTopMovers = 5
+
+// monthOverMonth compares the monthly spend of active subscriptions with the
+// spend one month before now. Without a price history the comparison uses
+// today's prices: a subscription counted a month ago if it had started by then
+// and was not yet cancelled.
+func (s *SubscriptionService) monthOverMonth(subs []models.Subscription, now time.Time, displayCurrency string) *models.StatsTrend {
+	monthAgo := now.AddDate(0, -1, 0)
+	trend := &models.StatsTrend{
+		NewlyAdded:     []models.TrendItem{},
+		NewlyCancelled: []models.TrendItem{},
+		TopMovers:      []models.TrendItem{},
+		Categories:     []models.CategoryTrend{},
+	}
+
+	current := make(map[string]float64)
+	previous := make(map[string]float64)
+	var currentTotal float64
+	for _, sub := range subs {
+		activeNow := sub.Status == "Active"
+		activeBefore := countedAt(&sub, monthAgo, now)
+		if !activeNow && !activeBefore {
+			continue
+		}
+
+		monthly := s.convertAmount(sub.MonthlyCost(), sub.OriginalCurrency, displayCurrency)
+		category := "Uncategorized"
+		if sub.Category.Name != "" {
+			category = sub.Category.Name
+		}
+		item := models.TrendItem{ID: sub.ID, Name: sub.Name, Category: category, MonthlyCost: roundCents(monthly)}
+
+		if activeNow {
+			current[category] += monthly
+			currentTotal += monthly
+		}
+		if activeBefore {
+			previous[category] += monthly
+			trend.PreviousMonthlySpend += monthly
+		}
+
+		switch {
+		case activeNow && !activeBefore:
+			item.Change = item.MonthlyCost
+			trend.NewlyAdded = append(trend.NewlyAdded, item)
+			trend.TopMovers = append(trend.TopMovers, item)
+		case !activeNow && activeBefore:
+			item.Change = -item.MonthlyCost
+			trend.NewlyCancelled = append(trend.NewlyCancelled, item)
+			trend.TopMovers = append(trend.TopMovers, item)
+		}
+	}
+
+	trend.MonthlySpendChange = roundCents(currentTotal - trend.PreviousMonthlySpend)
+	if trend.PreviousMonthlySpend > 0 {
+		trend.ChangePercent = math.Round((currentTotal-trend.PreviousMonthlySpend)/trend.PreviousMonthlySpend*1000) / 10
+	}
+	trend.PreviousMonthlySpend = roundCents(trend.PreviousMonthlySpend)
+
+	sort.SliceStable(trend.TopMovers, func(i, j int) bool {
+		return math.Abs(trend.TopMovers[i].Change) > math.Abs(trend.TopMovers[j].Change)
+	})
+	if len(trend.TopMovers) > maxTopMovers {
+		trend.TopMovers = trend.TopMovers[:maxTopMovers]
+	}
+
+	for category := range current {
+		if _, ok := previous[category]; !ok {
+			previous[category] = 0
+		}
+	}
+	for category, before := range previous {
+		spend := current[category]
+		trend.Categories = append(trend.Categories, models.CategoryTrend{
+			Category: category,
+			Current:  roundCents(spend),
+			Previous: roundCents(before),
+			Change:   roundCents(spend - before),
+		})
+	}
+	sort.Slice(trend.Categories, func(i, j int) bool {
+		if trend.Categories[i].Current != trend.Categories[j].Current {
+			return trend.Categories[i].Current > trend.Categories[j].Current
+		}
+		return trend.Categories[i].Category < trend.Categories[j].Category
+	})
+
+	return trend
+}
+
+// countedAt reports whether a subscription counted towards the monthly spend
+// at t: it had started by then and is active, or was cancelled after t
+func countedAt(sub *models.Subscription, t, now time.Time) bool {
+	if subscriptionStart(sub).After(t) {
+		return false
+	}
+	switch sub.Status {
+	case "Active":
+		return true
+	case "Cancelled":
+		return subscriptionCancelledAt(sub, now).After(t)
+	}
+	return false
+}
+
+// subscriptionStart returns when a subscription started, falling back to
+// when it was added
+func subscriptionStart(sub *models.Subscription) time.Time {
+	if sub.StartDate != nil {
+		return *sub.StartDate
+	}
+	return sub.CreatedAt
+}
+
+// subscriptionCancelledAt returns when a cancelled subscription stopped
+// counting: its cancellation date, or the last change if none is set. Dates in
+// the future are treated as now since the subscription no longer counts.
+func subscriptionCancelledAt(sub *models.Subscription, now time.Time) time.Time {
+	at := sub.UpdatedAt
+	if sub.CancellationDate != nil {
+		at = *sub.CancellationDate
+	}
+	if at.After(now) {
+		return now
+	}
+	return at
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionService_MonthOverMonth(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	date := func(months, days int) *time.Time {
+		d := now.AddDate(0, months, days)
+		return &d
+	}
+	streaming := models.Category{Name: "Streaming"}
+	software := models.Category{Name: "Software"}
+
+	subs := []models.Subscription{
+		{ID: 1, Name: "Netflix", Cost: 10, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", Category: streaming, StartDate: date(-6, 0)},
+		{ID: 2, Name: "Disney+", Cost: 20, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", Category: streaming, StartDate: date(0, -5)},
+		{ID: 3, Name: "IDE", Cost: 96, Schedule: "Annual", Status: "Cancelled", OriginalCurrency: "EUR", Category: software, StartDate: date(-12, 0), CancellationDate: date(0, -3)},
+		{ID: 4, Name: "Old", Cost: 5, Schedule: "Monthly", Status: "Cancelled", OriginalCurrency: "EUR", Category: software, StartDate: date(-12, 0), CancellationDate: date(-3, 0)},
+		{ID: 5, Name: "Paused", Cost: 7, Schedule: "Monthly", Status: "Paused", OriginalCurrency: "EUR", StartDate: date(-6, 0)},
+	}
+
+	s := &SubscriptionService{}
+	trend := s.monthOverMonth(subs, now, "EUR")
+
+	assert.Equal(t, 18.0, trend.PreviousMonthlySpend)
+	assert.Equal(t, 12.0, trend.MonthlySpendChange)
+	assert.InDelta(t, 66.7, trend.ChangePercent, 0.01)
+
+	require.Len(t, trend.NewlyAdded, 1)
+	assert.Equal(t, "Disney+", trend.NewlyAdded[0].Name)
+	require.Len(t, trend.NewlyCancelled, 1)
+	assert.Equal(t, "IDE", trend.NewlyCancelled[0].Name)
+	assert.Equal(t, -8.0, trend.NewlyCancelled[0].Change)
+
+	require.Len(t, trend.TopMovers, 2)
+	assert.Equal(t, "Disney+", trend.TopMovers[0].Name)
+	assert.Equal(t, "IDE", trend.TopMovers[1].Name)
+
+	assert.Equal(t, []models.CategoryTrend{
+		{Category: "Streaming", Current: 30, Previous: 10, Change: 20},
+		{Category: "Software", Current: 0, Previous: 8, Change: -8},
+	}, trend.Categories)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"sort"
+
+	"subvault/internal/models"
+)
+
+// vendorSpends groups the monthly spend of the active subscriptions by vendor,
+// highest spend first. Subscriptions without a vendor are left out.
+func (s *SubscriptionService) vendorSpends(subs []models.Subscription, total float64, displayCurrency string) []models.VendorSpend {
+	index := make(map[uint]int)
+	vendors := []models.VendorSpend{}
+	for i := range subs {
+		sub := &subs[i]
+		if sub.Status != "Active" || sub.VendorID == nil || sub.Vendor == nil {
+			continue
+		}
+		pos, ok := index[*sub.VendorID]
+		if !ok {
+			pos = len(vendors)
+			index[*sub.VendorID] = pos
+			vendors = append(vendors, models.VendorSpend{ID: *sub.VendorID, Name: sub.Vendor.Name})
+		}
+		vendors[pos].MonthlySpend += s.convertAmount(sub.MonthlyCost(), sub.OriginalCurrency, displayCurrency)
+		vendors[pos].Count++
+	}
+
+	for i := range vendors {
+		if total > 0 {
+			vendors[i].Share = roundCents(vendors[i].MonthlySpend / total * 100)
+		}
+		vendors[i].MonthlySpend = roundCents(vendors[i].MonthlySpend)
+	}
+	sort.SliceStable(vendors, func(i, j int) bool {
+		if vendors[i].MonthlySpend != vendors[j].MonthlySpend {
+			return vendors[i].MonthlySpend > vendors[j].MonthlySpend
+		}
+		return vendors[i].Name < vendors[j].Name
+	})
+	return vendors
+}
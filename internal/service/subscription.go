@@ -1,12 +1,27 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"slices"
 	"subvault/internal/models"
 	"subvault/internal/repository"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// ErrSubscriptionNotFound is returned for an unknown subscription
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+// ErrInvalidStatusChange is returned when a quick action does not apply to
+// the subscription's current status, e.g. pausing a cancelled subscription
+var ErrInvalidStatusChange = errors.New("status change not allowed")
+
+// ErrInvalidStatus is returned for a status that is not one of models.Statuses
+var ErrInvalidStatus = errors.New("invalid subscription status")
+
 type SubscriptionService struct {
 	repo            *repository.SubscriptionRepository
 	categoryService *CategoryService
@@ -27,42 +42,146 @@ func NewSubscriptionService(repo *repository.SubscriptionRepository, categorySer
 	}
 }
 
-func (s *SubscriptionService) Create(subscription *models.Subscription) (*models.Subscription, error) {
+// Create stores a new subscription. Its dates are normalized for its status,
+// e.g. a cancelled subscription gets a cancellation date and no renewal date.
+func (s *SubscriptionService) Create(ctx context.Context, subscription *models.Subscription) (*models.Subscription, error) {
+	if !models.IsValidStatus(subscription.Status) {
+		return nil, ErrInvalidStatus
+	}
+	s.renewalService.NormalizeStatus(subscription, "", time.Now())
 	s.renewalService.InitializeRenewalDate(subscription)
-	return s.repo.Create(subscription)
+	return s.repo.Create(ctx, subscription)
+}
+
+// CreateAll creates all subscriptions in one transaction, so either all or none are stored
+func (s *SubscriptionService) CreateAll(ctx context.Context, subscriptions []*models.Subscription) error {
+	now := time.Now()
+	for _, subscription := range subscriptions {
+		if !models.IsValidStatus(subscription.Status) {
+			return ErrInvalidStatus
+		}
+		s.renewalService.NormalizeStatus(subscription, "", now)
+		s.renewalService.InitializeRenewalDate(subscription)
+	}
+	return s.repo.CreateAll(ctx, subscriptions)
 }
 
-func (s *SubscriptionService) GetAll() ([]models.Subscription, error) {
-	return s.repo.GetAll()
+func (s *SubscriptionService) GetAll(ctx context.Context) ([]models.Subscription, error) {
+	return s.repo.GetAll(ctx)
 }
 
-func (s *SubscriptionService) GetAllPaginated(limit, offset int) ([]models.Subscription, int64, error) {
-	return s.repo.GetAllPaginated(limit, offset)
+func (s *SubscriptionService) GetAllPaginated(ctx context.Context, limit, offset int, purpose string) ([]models.Subscription, int64, error) {
+	return s.repo.GetAllPaginated(ctx, limit, offset, purpose)
 }
 
-func (s *SubscriptionService) GetAllSorted(sortBy, order string) ([]models.Subscription, error) {
-	return s.repo.GetAllSorted(sortBy, order)
+func (s *SubscriptionService) GetAllSorted(ctx context.Context, sortBy, order string) ([]models.Subscription, error) {
+	return s.repo.GetAllSorted(ctx, sortBy, order)
 }
 
-func (s *SubscriptionService) GetByID(id uint) (*models.Subscription, error) {
-	return s.repo.GetByID(id)
+func (s *SubscriptionService) GetByID(ctx context.Context, id uint) (*models.Subscription, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
-func (s *SubscriptionService) Update(id uint, subscription *models.Subscription) (*models.Subscription, error) {
-	existing, err := s.repo.GetByID(id)
+// Update saves the subscription. A status change must be allowed by
+// models.CanChangeStatus and normalizes the dates like the quick actions do.
+func (s *SubscriptionService) Update(ctx context.Context, id uint, subscription *models.Subscription) (*models.Subscription, error) {
+	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if !models.IsValidStatus(subscription.Status) {
+		return nil, ErrInvalidStatus
+	}
+	if !models.CanChangeStatus(existing.Status, subscription.Status) {
+		return nil, ErrInvalidStatusChange
+	}
+	s.renewalService.NormalizeStatus(subscription, existing.Status, time.Now())
 	s.renewalService.RecalculateIfNeeded(existing, subscription)
-	return s.repo.Update(id, subscription)
+	return s.repo.Update(ctx, id, subscription)
+}
+
+// Cancel marks an active, trial or paused subscription as cancelled
+func (s *SubscriptionService) Cancel(ctx context.Context, id uint) (*models.Subscription, error) {
+	return s.changeStatus(ctx, id, []string{"Active", "Trial", "Paused"}, func(sub *models.Subscription) {
+		s.renewalService.Cancel(sub, time.Now())
+	})
+}
+
+// Pause pauses an active or trial subscription
+func (s *SubscriptionService) Pause(ctx context.Context, id uint) (*models.Subscription, error) {
+	return s.changeStatus(ctx, id, []string{"Active", "Trial"}, s.renewalService.Pause)
+}
+
+// Resume reactivates a paused or cancelled subscription
+func (s *SubscriptionService) Resume(ctx context.Context, id uint) (*models.Subscription, error) {
+	return s.changeStatus(ctx, id, []string{"Paused", "Cancelled"}, s.renewalService.Resume)
+}
+
+// SetStatus changes the status of a subscription with the same rules as the
+// quick actions. Making a paused or cancelled subscription active or a trial
+// again resumes it first.
+func (s *SubscriptionService) SetStatus(ctx context.Context, id uint, status string) (*models.Subscription, error) {
+	switch status {
+	case "Cancelled":
+		return s.Cancel(ctx, id)
+	case "Paused":
+		return s.Pause(ctx, id)
+	case "Active", "Trial":
+		return s.changeStatus(ctx, id, []string{"Active", "Trial", "Paused", "Cancelled"}, func(sub *models.Subscription) {
+			if sub.Status == "Paused" || sub.Status == "Cancelled" {
+				s.renewalService.Resume(sub)
+			}
+			sub.Status = status
+		})
+	}
+	return nil, ErrInvalidStatusChange
+}
+
+// changeStatus applies a status change to the subscription if its current
+// status is one of from
+func (s *SubscriptionService) changeStatus(ctx context.Context, id uint, from []string, change func(*models.Subscription)) (*models.Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(from, sub.Status) {
+		return nil, ErrInvalidStatusChange
+	}
+	change(sub)
+	return s.repo.Update(ctx, id, sub)
+}
+
+func (s *SubscriptionService) Delete(ctx context.Context, id uint) error {
+	return s.repo.Delete(ctx, id)
 }
 
-func (s *SubscriptionService) Delete(id uint) error {
-	return s.repo.Delete(id)
+// SoftDelete hides a subscription so that its deletion can be undone
+func (s *SubscriptionService) SoftDelete(ctx context.Context, id uint) error {
+	return s.repo.SoftDelete(ctx, id)
 }
 
-func (s *SubscriptionService) Count() int64 {
-	return s.repo.Count()
+// SoftDeleteAll hides every subscription and returns their IDs
+func (s *SubscriptionService) SoftDeleteAll(ctx context.Context) ([]uint, error) {
+	return s.repo.SoftDeleteAll(ctx)
+}
+
+// Restore brings back soft deleted subscriptions
+func (s *SubscriptionService) Restore(ctx context.Context, ids []uint) (int64, error) {
+	return s.repo.Restore(ctx, ids)
+}
+
+// PurgeDeleted permanently deletes soft deleted subscriptions with their usage,
+// shares, reminder retries and payments: those in ids, or without ids all
+// deleted before the given time
+func (s *SubscriptionService) PurgeDeleted(ctx context.Context, ids []uint, before time.Time) ([]uint, error) {
+	return s.repo.PurgeDeleted(ctx, ids, before)
+}
+
+func (s *SubscriptionService) Count(ctx context.Context) int64 {
+	return s.repo.Count(ctx)
 }
 
 // convertAmount converts an amount from one currency to the display currency.
@@ -79,23 +198,48 @@ func (s *SubscriptionService) convertAmount(amount float64, fromCurrency, toCurr
 	return converted
 }
 
-func (s *SubscriptionService) GetStats() (*models.Stats, error) {
+func (s *SubscriptionService) GetStats(ctx context.Context) (*models.Stats, error) {
+	return s.GetStatsForPurpose(ctx, "")
+}
+
+// GetStatsForPurpose returns the statistics of the subscriptions with the given
+// purpose, or of all subscriptions if purpose is empty. Budgets come from the
+// purpose's own budget settings.
+func (s *SubscriptionService) GetStatsForPurpose(ctx context.Context, purpose string) (*models.Stats, error) {
 	displayCurrency := s.preferences.GetCurrency()
 
 	// Single query: load all subscriptions with categories
-	allSubs, err := s.repo.GetAll()
+	allSubs, err := s.repo.GetAll(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// Partition in-memory
 	now := time.Now()
-	renewalCutoff := now.AddDate(0, 0, 7)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	windowDays := s.preferences.GetRenewalWindowDays()
+	windowEnd := today.AddDate(0, 0, windowDays+1)
 
 	stats := &models.Stats{
-		CategorySpending: make(map[string]float64),
-		AllSubscriptions: allSubs,
+		RenewalWindowDays: windowDays,
+		CategorySpending:  make(map[string]float64),
+		Purpose:           purpose,
+		PurposeSpending:   make(map[string]float64, len(models.Purposes)),
+	}
+	for _, p := range models.Purposes {
+		stats.PurposeSpending[p] = 0
+	}
+	for _, sub := range allSubs {
+		if sub.Status == "Active" {
+			stats.PurposeSpending[sub.EffectivePurpose()] += s.convertAmount(sub.MonthlyCost(), sub.OriginalCurrency, displayCurrency)
+		}
+	}
+	if purpose != "" {
+		allSubs = slices.DeleteFunc(allSubs, func(sub models.Subscription) bool {
+			return sub.EffectivePurpose() != purpose
+		})
 	}
+	stats.AllSubscriptions = allSubs
 
 	for _, sub := range allSubs {
 		switch sub.Status {
@@ -106,15 +250,18 @@ func (s *SubscriptionService) GetStats() (*models.Stats, error) {
 			stats.TotalMonthlySpend += monthly
 			stats.TotalAnnualSpend += annual
 
-			categoryName := "Uncategorized"
-			if sub.Category.Name != "" {
-				categoryName = sub.Category.Name
-			}
+			_, categoryName := subscriptionCategory(&sub)
 			stats.CategorySpending[categoryName] += monthly
 
-			// Check upcoming renewals
-			if sub.RenewalDate != nil && !sub.RenewalDate.Before(now) && !sub.RenewalDate.After(renewalCutoff) {
-				stats.UpcomingRenewals++
+			// Count every renewal in the window, projected from the schedule
+			// so that short schedules and passed stored dates are included
+			if sub.RenewalDate != nil {
+				stats.UpcomingRenewals += len(models.ProjectRenewalDates(*sub.RenewalDate, sub.Schedule, today, windowEnd))
+			}
+
+			// A failed payment being retried is still spend, not savings
+			if sub.PaymentFailedAt != nil {
+				stats.FailedPayments++
 			}
 
 			if sub.OriginalCurrency != displayCurrency && !HasECBRate(sub.OriginalCurrency) {
@@ -127,12 +274,12 @@ func (s *SubscriptionService) GetStats() (*models.Stats, error) {
 		}
 	}
 
-	// Budget calculation
-	budget := s.settings.GetFloatSettingWithDefault("monthly_budget", 0)
-	stats.MonthlyBudget = budget
-	if budget > 0 {
-		stats.BudgetUtilization = stats.TotalMonthlySpend / budget * 100
-	}
+	stats.Categories = s.categorySpends(allSubs, stats.TotalMonthlySpend, displayCurrency)
+	stats.Vendors = s.vendorSpends(allSubs, stats.TotalMonthlySpend, displayCurrency)
+	stats.Currencies = s.currencySpends(allSubs, stats.TotalMonthlySpend, displayCurrency)
+	stats.SeatPlans = s.seatSpends(allSubs, displayCurrency)
+	s.applyBudgets(stats, allSubs, now, displayCurrency)
+	stats.Trend = s.monthOverMonth(allSubs, now, displayCurrency)
 
 	return stats, nil
 }
@@ -146,15 +293,14 @@ func (s *SubscriptionService) GetDefaultCategory() (*models.Category, error) {
 }
 
 // GetSubscriptionsNeedingReminders returns subscriptions that need renewal reminders
-// based on per-subscription settings. It returns a map of subscription to days until renewal.
-func (s *SubscriptionService) GetSubscriptionsNeedingReminders() (map[*models.Subscription]int, error) {
-	subscriptions, err := s.repo.GetSubscriptionsWithRenewalReminder()
+// based on per-subscription settings as of now. It returns a map of subscription to days until renewal.
+func (s *SubscriptionService) GetSubscriptionsNeedingReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error) {
+	subscriptions, err := s.repo.GetSubscriptionsWithRenewalReminder(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	result := make(map[*models.Subscription]int)
-	now := time.Now()
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
 	for i := range subscriptions {
@@ -181,15 +327,14 @@ func (s *SubscriptionService) GetSubscriptionsNeedingReminders() (map[*models.Su
 }
 
 // GetSubscriptionsNeedingCancellationReminders returns subscriptions that need cancellation reminders
-// based on per-subscription settings. It returns a map of subscription to days until cancellation.
-func (s *SubscriptionService) GetSubscriptionsNeedingCancellationReminders() (map[*models.Subscription]int, error) {
-	subscriptions, err := s.repo.GetSubscriptionsWithCancellationReminder()
+// based on per-subscription settings as of now. It returns a map of subscription to days until cancellation.
+func (s *SubscriptionService) GetSubscriptionsNeedingCancellationReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error) {
+	subscriptions, err := s.repo.GetSubscriptionsWithCancellationReminder(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	result := make(map[*models.Subscription]int)
-	now := time.Now()
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
 	for i := range subscriptions {
@@ -214,3 +359,111 @@ func (s *SubscriptionService) GetSubscriptionsNeedingCancellationReminders() (ma
 
 	return result, nil
 }
+
+// GetSubscriptionsNeedingGracePeriodReminders returns active subscriptions with a failed payment
+// whose service cutoff is at most GracePeriodReminderDays away and was not reminded of yet. It
+// returns a map of subscription to days until the cutoff.
+func (s *SubscriptionService) GetSubscriptionsNeedingGracePeriodReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error) {
+	subscriptions, err := s.repo.GetSubscriptionsInGracePeriod(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[*models.Subscription]int)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		cutoffDay := time.Date(sub.GracePeriodEnd.Year(), sub.GracePeriodEnd.Month(), sub.GracePeriodEnd.Day(), 0, 0, 0, 0, sub.GracePeriodEnd.Location())
+		daysUntil := int(cutoffDay.Sub(today).Hours() / 24)
+
+		if daysUntil >= 0 && daysUntil <= models.GracePeriodReminderDays {
+			if sub.LastGraceReminderDate != nil && sub.LastGraceReminderDate.Equal(*sub.GracePeriodEnd) {
+				continue
+			}
+
+			result[sub] = daysUntil
+		}
+	}
+
+	return result, nil
+}
+
+// GetSubscriptionsNeedingPaidThroughReminders returns cancelled subscriptions whose paid period
+// ends at most PaidThroughReminderDays away and was not reminded of yet. It returns a map of
+// subscription to days until access ends.
+func (s *SubscriptionService) GetSubscriptionsNeedingPaidThroughReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error) {
+	subscriptions, err := s.repo.GetCancelledSubscriptionsWithPaidThrough(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[*models.Subscription]int)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		endDay := time.Date(sub.PaidThroughDate.Year(), sub.PaidThroughDate.Month(), sub.PaidThroughDate.Day(), 0, 0, 0, 0, sub.PaidThroughDate.Location())
+		daysUntil := int(endDay.Sub(today).Hours() / 24)
+
+		if daysUntil >= 0 && daysUntil <= models.PaidThroughReminderDays {
+			if sub.LastPaidThroughReminderDate != nil && sub.LastPaidThroughReminderDate.Equal(*sub.PaidThroughDate) {
+				continue
+			}
+
+			result[sub] = daysUntil
+		}
+	}
+
+	return result, nil
+}
+
+// GetSubscriptionsNeedingPromoReminders returns subscriptions whose promotional price ends at
+// most PromoReminderDays away and was not reminded of yet. It returns a map of subscription to
+// days until the regular cost applies.
+func (s *SubscriptionService) GetSubscriptionsNeedingPromoReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error) {
+	subscriptions, err := s.repo.GetSubscriptionsWithPromoEndReminder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[*models.Subscription]int)
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		daysUntil := daysUntilDate(*sub.PromoEndDate, now)
+		if daysUntil < 0 || daysUntil > models.PromoReminderDays {
+			continue
+		}
+		if sub.LastPromoReminderDate != nil && sub.LastPromoReminderDate.Equal(*sub.PromoEndDate) {
+			continue
+		}
+		result[sub] = daysUntil
+	}
+
+	return result, nil
+}
+
+// GetSubscriptionsNeedingContractReminders returns the subscriptions whose contract decision
+// deadline is at most ContractDecisionWindowDays away and was not reminded of yet. It returns a
+// map of subscription to days until the deadline.
+func (s *SubscriptionService) GetSubscriptionsNeedingContractReminders(ctx context.Context, now time.Time) (map[*models.Subscription]int, error) {
+	subscriptions, err := s.repo.GetSubscriptionsWithContract(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[*models.Subscription]int)
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		decision, ok := sub.ContractDecision(now)
+		if !ok {
+			continue
+		}
+		if sub.LastContractReminderDate != nil && sub.LastContractReminderDate.Equal(decision.DecideBy) {
+			continue
+		}
+		result[sub] = decision.DaysLeft
+	}
+
+	return result, nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionService_CreateAll(t *testing.T) {
+	_, subscriptions := setupPaymentService(t)
+	start := time.Now().AddDate(0, -2, 0)
+
+	batch := []*models.Subscription{
+		{Name: "Netflix", Cost: 15.99, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", StartDate: &start},
+		{Name: "Spotify", Cost: 9.99, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR"},
+	}
+	require.NoError(t, subscriptions.CreateAll(t.Context(), batch))
+	assert.NotZero(t, batch[0].ID)
+	assert.NotZero(t, batch[1].ID)
+	require.NotNil(t, batch[0].RenewalDate, "renewal date is initialized like on Create")
+	assert.True(t, batch[0].RenewalDate.After(time.Now()))
+	assert.Equal(t, int64(2), subscriptions.Count(t.Context()))
+
+	// A failing entry rolls back the whole batch
+	err := subscriptions.CreateAll(t.Context(), []*models.Subscription{
+		{Name: "Disney+", Cost: 8.99, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR"},
+		{ID: batch[0].ID, Name: "Duplicate", Cost: 1, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR"},
+	})
+	require.Error(t, err)
+	assert.Equal(t, int64(2), subscriptions.Count(t.Context()))
+}
+
+func TestSubscriptionService_CategoryBreakdown(t *testing.T) {
+	_, subscriptions := setupPaymentService(t)
+	currency := subscriptions.preferences.GetCurrency()
+	streaming, err := subscriptions.categoryService.Create(&models.Category{Name: "Streaming"})
+	require.NoError(t, err)
+	software, err := subscriptions.categoryService.Create(&models.Category{Name: "Software"})
+	require.NoError(t, err)
+
+	for _, sub := range []*models.Subscription{
+		{Name: "Netflix", Cost: 15, Schedule: "Monthly", Status: "Active", OriginalCurrency: currency, CategoryID: streaming.ID},
+		{Name: "Disney+", Cost: 60, Schedule: "Annual", Status: "Active", OriginalCurrency: currency, CategoryID: streaming.ID},
+		{Name: "Hulu", Cost: 8, Schedule: "Monthly", Status: "Cancelled", OriginalCurrency: currency, CategoryID: streaming.ID},
+		{Name: "Figma", Cost: 10, Schedule: "Monthly", Status: "Active", OriginalCurrency: currency, CategoryID: software.ID, Purpose: models.PurposeBusiness},
+		{Name: "Gym", Cost: 30, Schedule: "Monthly", Status: "Active", OriginalCurrency: currency},
+	} {
+		_, err := subscriptions.Create(t.Context(), sub)
+		require.NoError(t, err)
+	}
+
+	stats, err := subscriptions.GetStats(t.Context())
+	require.NoError(t, err)
+	require.Len(t, stats.Categories, 3)
+	assert.Equal(t, models.CategorySpend{ID: 0, Name: "Uncategorized", MonthlySpend: 30, Count: 1, Share: 50}, stats.Categories[0])
+	assert.Equal(t, models.CategorySpend{ID: streaming.ID, Name: "Streaming", MonthlySpend: 20, Count: 2, Share: 33.33}, stats.Categories[1])
+	assert.Equal(t, software.ID, stats.Categories[2].ID)
+
+	breakdown, err := subscriptions.GetCategoryBreakdown(t.Context(), streaming.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Streaming", breakdown.Name)
+	assert.Equal(t, currency, breakdown.Currency)
+	assert.Equal(t, 20.0, breakdown.MonthlySpend)
+	require.Len(t, breakdown.Subscriptions, 2, "cancelled subscriptions do not count")
+	assert.Equal(t, "Netflix", breakdown.Subscriptions[0].Name)
+	assert.Equal(t, 75.0, breakdown.Subscriptions[0].Share)
+	assert.Equal(t, 5.0, breakdown.Subscriptions[1].ConvertedMonthlyCost)
+	assert.Equal(t, 60.0, breakdown.Subscriptions[1].Cost)
+
+	uncategorized, err := subscriptions.GetCategoryBreakdown(t.Context(), 0, "")
+	require.NoError(t, err)
+	require.Len(t, uncategorized.Subscriptions, 1)
+	assert.Equal(t, "Gym", uncategorized.Subscriptions[0].Name)
+
+	personal, err := subscriptions.GetCategoryBreakdown(t.Context(), software.ID, models.PurposePersonal)
+	require.NoError(t, err)
+	assert.Empty(t, personal.Subscriptions)
+
+	_, err = subscriptions.GetCategoryBreakdown(t.Context(), 9999, "")
+	assert.ErrorIs(t, err, ErrCategoryNotFound)
+}
+
+func TestSubscriptionService_QuickActions(t *testing.T) {
+	_, subscriptions := setupPaymentService(t)
+	sub, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Netflix", Cost: 10, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR"})
+	require.NoError(t, err)
+	require.NotNil(t, sub.RenewalDate)
+
+	paused, err := subscriptions.Pause(t.Context(), sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Paused", paused.Status)
+	assert.Nil(t, paused.RenewalDate)
+
+	_, err = subscriptions.Pause(t.Context(), sub.ID)
+	assert.ErrorIs(t, err, ErrInvalidStatusChange)
+
+	cancelled, err := subscriptions.Cancel(t.Context(), sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Cancelled", cancelled.Status)
+	assert.NotNil(t, cancelled.CancellationDate)
+
+	resumed, err := subscriptions.Resume(t.Context(), sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Active", resumed.Status)
+	assert.Nil(t, resumed.CancellationDate)
+	assert.NotNil(t, resumed.RenewalDate)
+
+	_, err = subscriptions.Resume(t.Context(), sub.ID)
+	assert.ErrorIs(t, err, ErrInvalidStatusChange)
+
+	_, err = subscriptions.Cancel(t.Context(), 9999)
+	assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+}
+
+func TestSubscriptionService_SetStatus(t *testing.T) {
+	_, subscriptions := setupPaymentService(t)
+	sub, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Spotify", Cost: 5, Schedule: "Monthly", Status: "Trial", OriginalCurrency: "EUR"})
+	require.NoError(t, err)
+
+	cancelled, err := subscriptions.SetStatus(t.Context(), sub.ID, "Cancelled")
+	require.NoError(t, err)
+	assert.Equal(t, "Cancelled", cancelled.Status)
+	assert.NotNil(t, cancelled.CancellationDate)
+
+	// Making a cancelled subscription a trial again resumes it first
+	trial, err := subscriptions.SetStatus(t.Context(), sub.ID, "Trial")
+	require.NoError(t, err)
+	assert.Equal(t, "Trial", trial.Status)
+	assert.Nil(t, trial.CancellationDate)
+	assert.NotNil(t, trial.RenewalDate)
+
+	_, err = subscriptions.SetStatus(t.Context(), sub.ID, "Expired")
+	assert.ErrorIs(t, err, ErrInvalidStatusChange)
+}
+
+func TestSubscriptionService_StatusStateMachine(t *testing.T) {
+	_, subscriptions := setupPaymentService(t)
+
+	// A cancelled subscription is stored without renewal date but with a cancellation date
+	renewal := time.Now().AddDate(0, 0, 10)
+	sub, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "Hulu", Cost: 8, Schedule: "Monthly", Status: "Cancelled", OriginalCurrency: "EUR", RenewalDate: &renewal})
+	require.NoError(t, err)
+	assert.Nil(t, sub.RenewalDate)
+	assert.NotNil(t, sub.CancellationDate)
+	require.NotNil(t, sub.PaidThroughDate)
+	assert.True(t, sub.PaidThroughDate.Equal(renewal))
+
+	// Cancelled subscriptions have to be resumed before they can be paused
+	update := *sub
+	update.Status = "Paused"
+	_, err = subscriptions.Update(t.Context(), sub.ID, &update)
+	assert.ErrorIs(t, err, ErrInvalidStatusChange)
+
+	// Editing a cancelled subscription keeps it consistent
+	update = *sub
+	update.RenewalDate = &renewal
+	updated, err := subscriptions.Update(t.Context(), sub.ID, &update)
+	require.NoError(t, err)
+	assert.Nil(t, updated.RenewalDate)
+
+	// Making it active again drops the cancellation and calculates a renewal date
+	update = *updated
+	update.Status = "Active"
+	updated, err = subscriptions.Update(t.Context(), sub.ID, &update)
+	require.NoError(t, err)
+	assert.Nil(t, updated.CancellationDate)
+	assert.Nil(t, updated.PaidThroughDate)
+	assert.NotNil(t, updated.RenewalDate)
+
+	update = *updated
+	update.Status = "Expired"
+	_, err = subscriptions.Update(t.Context(), sub.ID, &update)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+	_, err = subscriptions.Create(t.Context(), &models.Subscription{Name: "Max", Cost: 10, Schedule: "Monthly", Status: "cancelled"})
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func TestSubscriptionService_StatsCountRenewalsInWindow(t *testing.T) {
+	_, subscriptions := setupPaymentService(t)
+	now := time.Now()
+	in := func(days int) *time.Time {
+		d := time.Date(now.Year(), now.Month(), now.Day()+days, 0, 0, 0, 0, time.Local)
+		return &d
+	}
+	for _, sub := range []models.Subscription{
+		{Name: "Newsletter", Schedule: "Weekly", Status: "Active", RenewalDate: in(2)},
+		{Name: "Netflix", Schedule: "Monthly", Status: "Active", RenewalDate: in(20)},
+		{Name: "Gym", Schedule: "Monthly", Status: "Cancelled", RenewalDate: in(3)},
+	} {
+		sub.Cost, sub.OriginalCurrency = 10, "EUR"
+		_, err := subscriptions.Create(t.Context(), &sub)
+		require.NoError(t, err)
+	}
+
+	stats, err := subscriptions.GetStats(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, DefaultRenewalWindowDays, stats.RenewalWindowDays)
+	assert.Equal(t, 1, stats.UpcomingRenewals)
+
+	// A longer window counts every weekly renewal in it: days 2, 9, 16, 23 and 30
+	require.NoError(t, subscriptions.preferences.SetRenewalWindowDays(30))
+	stats, err = subscriptions.GetStats(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 30, stats.RenewalWindowDays)
+	assert.Equal(t, 6, stats.UpcomingRenewals)
+
+	assert.Error(t, subscriptions.preferences.SetRenewalWindowDays(0))
+	assert.Error(t, subscriptions.preferences.SetRenewalWindowDays(MaxRenewalWindowDays+1))
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// ErrInvalidTaxPeriod is returned for a tax report period other than month or quarter
+var ErrInvalidTaxPeriod = errors.New("period must be month or quarter")
+
+// maxChargeSteps bounds the schedule steps walked per subscription, enough for
+// a daily subscription renewing for several decades
+const maxChargeSteps = 20000
+
+// GetTaxReport sums the net, gross and tax amounts of the charges made in the
+// given year, grouped per month or quarter and category
+func (s *SubscriptionService) GetTaxReport(ctx context.Context, year int, period string) (*models.TaxReport, error) {
+	if period == "" {
+		period = models.TaxPeriodMonth
+	}
+	if period != models.TaxPeriodMonth && period != models.TaxPeriodQuarter {
+		return nil, ErrInvalidTaxPeriod
+	}
+
+	subs, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.taxReport(subs, year, period, time.Now(), s.preferences.GetCurrency()), nil
+}
+
+// taxReport builds the report from the charges of each subscription. Without a
+// payment history a charge is assumed on every renewal date between the start
+// of the subscription and now, or its cancellation.
+func (s *SubscriptionService) taxReport(subs []models.Subscription, year int, period string, now time.Time, displayCurrency string) *models.TaxReport {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+
+	report := &models.TaxReport{
+		Year:     year,
+		Period:   period,
+		Currency: displayCurrency,
+		Periods:  []models.TaxReportPeriod{},
+	}
+
+	// Periods that have started, so a running year has no empty future rows
+	index := make(map[string]int)
+	months := 1
+	if period == models.TaxPeriodQuarter {
+		months = 3
+	}
+	for start := from; start.Before(to) && !start.After(now); start = start.AddDate(0, months, 0) {
+		label := taxPeriodLabel(start, period)
+		index[label] = len(report.Periods)
+		report.Periods = append(report.Periods, models.TaxReportPeriod{Label: label})
+	}
+
+	categories := make([]map[string]*models.TaxReportCategory, len(report.Periods))
+	for i := range categories {
+		categories[i] = make(map[string]*models.TaxReportCategory)
+	}
+
+	for _, sub := range subs {
+		dates := chargeDates(&sub, from, to, now)
+		if len(dates) == 0 {
+			continue
+		}
+		net := s.convertAmount(sub.NetCost(), sub.OriginalCurrency, displayCurrency)
+		gross := s.convertAmount(sub.GrossCost(), sub.OriginalCurrency, displayCurrency)
+		category := "Uncategorized"
+		if sub.Category.Name != "" {
+			category = sub.Category.Name
+		}
+
+		for _, d := range dates {
+			i, ok := index[taxPeriodLabel(d, period)]
+			if !ok {
+				continue
+			}
+			entry, ok := categories[i][category]
+			if !ok {
+				entry = &models.TaxReportCategory{Category: category}
+				categories[i][category] = entry
+			}
+			entry.Net += net
+			entry.Gross += gross
+			entry.Tax += gross - net
+			entry.Charges++
+		}
+	}
+
+	for i := range report.Periods {
+		p := &report.Periods[i]
+		p.Categories = []models.TaxReportCategory{}
+		for _, entry := range categories[i] {
+			p.Net += entry.Net
+			p.Gross += entry.Gross
+			p.Tax += entry.Tax
+			entry.Net = roundCents(entry.Net)
+			entry.Gross = roundCents(entry.Gross)
+			entry.Tax = roundCents(entry.Tax)
+			p.Categories = append(p.Categories, *entry)
+		}
+		sort.Slice(p.Categories, func(a, b int) bool {
+			return p.Categories[a].Category < p.Categories[b].Category
+		})
+
+		report.Net += p.Net
+		report.Gross += p.Gross
+		report.Tax += p.Tax
+		p.Net = roundCents(p.Net)
+		p.Gross = roundCents(p.Gross)
+		p.Tax = roundCents(p.Tax)
+	}
+	report.Net = roundCents(report.Net)
+	report.Gross = roundCents(report.Gross)
+	report.Tax = roundCents(report.Tax)
+
+	return report
+}
+
+// taxPeriodLabel returns the month (2026-01) or quarter (2026-Q1) containing t
+func taxPeriodLabel(t time.Time, period string) string {
+	if period == models.TaxPeriodQuarter {
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	}
+	return t.Format("2006-01")
+}
+
+// chargeDates returns the renewal dates within [from, to) on which an active or
+// cancelled subscription was charged, projected from its renewal date. Charges
+// before the start, after a cancellation or after now are left out.
+func chargeDates(sub *models.Subscription, from, to, now time.Time) []time.Time {
+	step := scheduleStep(sub.Schedule)
+	if step == nil {
+		return nil
+	}
+
+	last := now
+	switch sub.Status {
+	case "Active":
+	case "Cancelled":
+		last = subscriptionCancelledAt(sub, now)
+	default:
+		return nil
+	}
+	started := subscriptionStart(sub)
+	first := time.Date(started.Year(), started.Month(), started.Day(), 0, 0, 0, 0, started.Location())
+
+	anchor := first
+	if sub.RenewalDate != nil {
+		anchor = *sub.RenewalDate
+	}
+
+	// Walk back to the last renewal before the range, then forward through it
+	n := 0
+	for i := 0; i < maxChargeSteps && !step(anchor, n).Before(from); i++ {
+		n--
+	}
+	var dates []time.Time
+	for i := 0; i < maxChargeSteps; i++ {
+		n++
+		d := step(anchor, n)
+		if !d.Before(to) || d.After(last) {
+			break
+		}
+		if d.Before(from) || d.Before(first) {
+			continue
+		}
+		dates = append(dates, d)
+	}
+	return dates
+}
+
+// scheduleStep returns a function advancing a date by n renewal periods, or
+// nil for an unknown schedule
+func scheduleStep(schedule string) func(t time.Time, n int) time.Time {
+	switch schedule {
+	case "Daily":
+		return func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) }
+	case "Weekly":
+		return func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) }
+	case "Monthly":
+		return func(t time.Time, n int) time.Time { return t.AddDate(0, n, 0) }
+	case "Quarterly":
+		return func(t time.Time, n int) time.Time { return t.AddDate(0, 3*n, 0) }
+	case "Annual":
+		return func(t time.Time, n int) time.Time { return t.AddDate(n, 0, 0) }
+	}
+	return nil
+}
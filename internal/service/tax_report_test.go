@@ -0,0 +1,103 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionService_TaxReport(t *testing.T) {
+	now := time.Date(2026, 5, 20, 12, 0, 0, 0, time.UTC)
+	day := func(year int, month time.Month, d int) *time.Time {
+		t := time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	software := models.Category{Name: "Software"}
+	hosting := models.Category{Name: "Hosting"}
+
+	subs := []models.Subscription{
+		// Net price plus 19% VAT, charged on the 10th since February
+		{Name: "IDE", Cost: 100, PriceType: "net", TaxRate: 19, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR",
+			Category: software, StartDate: day(2026, 2, 10), RenewalDate: day(2026, 6, 10)},
+		// Gross price including 19% VAT, charged once in March
+		{Name: "Server", Cost: 238, PriceType: "gross", TaxRate: 19, Schedule: "Annual", Status: "Active", OriginalCurrency: "EUR",
+			Category: hosting, StartDate: day(2025, 3, 1), RenewalDate: day(2027, 3, 1)},
+		// Cancelled in mid-April, so the last charge was on April 1st
+		{Name: "CDN", Cost: 10, Schedule: "Monthly", Status: "Cancelled", OriginalCurrency: "EUR",
+			Category: hosting, StartDate: day(2025, 12, 1), RenewalDate: day(2026, 5, 1), CancellationDate: day(2026, 4, 15)},
+		{Name: "Paused", Cost: 50, Schedule: "Monthly", Status: "Paused", OriginalCurrency: "EUR", StartDate: day(2025, 1, 1)},
+	}
+
+	s := &SubscriptionService{}
+
+	t.Run("monthly", func(t *testing.T) {
+		report := s.taxReport(subs, 2026, models.TaxPeriodMonth, now, "EUR")
+
+		// January through May have started
+		require.Len(t, report.Periods, 5)
+		assert.Equal(t, "2026-01", report.Periods[0].Label)
+		assert.Equal(t, []models.TaxReportCategory{{Category: "Hosting", Net: 10, Gross: 10, Charges: 1}}, report.Periods[0].Categories)
+
+		march := report.Periods[2]
+		assert.Equal(t, "2026-03", march.Label)
+		assert.Equal(t, []models.TaxReportCategory{
+			{Category: "Hosting", Net: 210, Gross: 248, Tax: 38, Charges: 2},
+			{Category: "Software", Net: 100, Gross: 119, Tax: 19, Charges: 1},
+		}, march.Categories)
+		assert.Equal(t, 367.0, march.Gross)
+
+		// May: only the IDE, the CDN was cancelled in April
+		assert.Equal(t, []models.TaxReportCategory{{Category: "Software", Net: 100, Gross: 119, Tax: 19, Charges: 1}}, report.Periods[4].Categories)
+
+		// IDE 4x119, server 238, CDN 4x10
+		assert.Equal(t, 754.0, report.Gross)
+		assert.Equal(t, 400.0+200+40, report.Net)
+		assert.Equal(t, 114.0, report.Tax)
+	})
+
+	t.Run("quarterly", func(t *testing.T) {
+		report := s.taxReport(subs, 2026, models.TaxPeriodQuarter, now, "EUR")
+
+		require.Len(t, report.Periods, 2)
+		assert.Equal(t, "2026-Q1", report.Periods[0].Label)
+		assert.Equal(t, 30.0+238+238, report.Periods[0].Gross)
+		assert.Equal(t, "2026-Q2", report.Periods[1].Label)
+		assert.Equal(t, 10.0+238, report.Periods[1].Gross)
+	})
+
+	t.Run("past year", func(t *testing.T) {
+		report := s.taxReport(subs, 2025, models.TaxPeriodQuarter, now, "EUR")
+
+		require.Len(t, report.Periods, 4)
+		assert.Equal(t, 238.0, report.Periods[0].Gross)
+		assert.Equal(t, 10.0, report.Periods[3].Gross)
+	})
+}
+
+func TestSubscriptionService_GetTaxReportInvalidPeriod(t *testing.T) {
+	s := &SubscriptionService{}
+	_, err := s.GetTaxReport(t.Context(), 2026, "week")
+	assert.ErrorIs(t, err, ErrInvalidTaxPeriod)
+}
+
+func TestExportService_WriteTaxReportCSV(t *testing.T) {
+	report := &models.TaxReport{
+		Year: 2026, Period: models.TaxPeriodMonth, Currency: "EUR", Net: 100, Gross: 119, Tax: 19,
+		Periods: []models.TaxReportPeriod{
+			{Label: "2026-01", Net: 100, Gross: 119, Tax: 19, Categories: []models.TaxReportCategory{
+				{Category: "Software", Net: 100, Gross: 119, Tax: 19, Charges: 1},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, (&ExportService{}).WriteTaxReportCSV(&buf, report))
+	assert.Equal(t, "Period,Category,Charges,Net,Tax,Gross,Currency\n"+
+		"2026-01,Software,1,100.00,19.00,119.00,EUR\n"+
+		"2026,Total,,100.00,19.00,119.00,EUR\n", buf.String())
+}
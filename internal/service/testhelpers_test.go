@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+
+	"subvault/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// testServices are the services most service tests build on, all on one database
+type testServices struct {
+	categories    *CategoryService
+	settings      *SettingsService
+	currency      *CurrencyService
+	preferences   *PreferencesService
+	subscriptions *SubscriptionService
+}
+
+// newTestSubscriptionService wires a subscription service and the services it
+// depends on to db
+func newTestSubscriptionService(t testing.TB, db *gorm.DB) testServices {
+	t.Helper()
+	settings := NewSettingsService(repository.NewSettingsRepository(db))
+	services := testServices{
+		categories:  NewCategoryService(repository.NewCategoryRepository(db)),
+		settings:    settings,
+		currency:    NewCurrencyService(repository.NewExchangeRateRepository(db), settings),
+		preferences: NewPreferencesService(settings, defaultLangProvider()),
+	}
+	services.subscriptions = NewSubscriptionService(repository.NewSubscriptionRepository(db), services.categories, services.currency, services.preferences, services.settings, NewRenewalService())
+	return services
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxCustomCSSLength caps the size of the custom CSS snippet in bytes
+const MaxCustomCSSLength = 20000
+
+var (
+	// ErrInvalidAccentColor is returned for an accent color that is not a hex color like #1a2b3c
+	ErrInvalidAccentColor = errors.New("invalid accent color, use a hex color like #2563eb")
+	// ErrInvalidCustomCSS is returned for a custom CSS snippet that is too long or uses blocked features
+	ErrInvalidCustomCSS = errors.New("invalid custom CSS")
+)
+
+var accentColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// blockedCSS lists what custom CSS may not contain: markup that could end a
+// style element, escapes that could hide the other entries, imports, the old
+// ways of running scripts from CSS and the functions besides url() that load
+// an image from a plain string (image-set() also covers -webkit-image-set())
+var blockedCSS = []string{"<", "\\", "@import", "expression(", "javascript:", "vbscript:", "behavior:", "-moz-binding", "image-set(", "src("}
+
+var cssCommentPattern = regexp.MustCompile(`(?s)/\*.*?\*/`)
+var cssURLPattern = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")\s]*)`)
+
+// cssAbsoluteURLPattern finds a quoted string holding an absolute URL, which
+// outside url() a browser may still load
+var cssAbsoluteURLPattern = regexp.MustCompile(`['"]\s*([a-z][a-z0-9+.-]*:)?//`)
+
+// NormalizeAccentColor lowercases a hex accent color. An empty color is kept
+// and means the theme's own accent.
+func NormalizeAccentColor(color string) (string, error) {
+	color = strings.TrimSpace(color)
+	if color == "" {
+		return "", nil
+	}
+	if !accentColorPattern.MatchString(color) {
+		return "", ErrInvalidAccentColor
+	}
+	return strings.ToLower(color), nil
+}
+
+// SanitizeCustomCSS checks a custom CSS snippet before it is saved. Besides
+// the blocked features, url() may only point to this server or embed an
+// image and no string may hold an absolute URL, so that the pages load
+// nothing from elsewhere.
+func SanitizeCustomCSS(css string) (string, error) {
+	css = strings.TrimSpace(strings.ReplaceAll(css, "\r\n", "\n"))
+	if len(css) > MaxCustomCSSLength {
+		return "", fmt.Errorf("%w: at most %d characters", ErrInvalidCustomCSS, MaxCustomCSSLength)
+	}
+
+	code := strings.ToLower(cssCommentPattern.ReplaceAllString(css, ""))
+	code = strings.Join(strings.Fields(code), " ")
+	for _, blocked := range blockedCSS {
+		if strings.Contains(code, blocked) || strings.Contains(strings.ReplaceAll(code, " ", ""), blocked) {
+			return "", fmt.Errorf("%w: %q is not allowed", ErrInvalidCustomCSS, blocked)
+		}
+	}
+	for _, match := range cssURLPattern.FindAllStringSubmatch(code, -1) {
+		target := match[1]
+		local := strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//")
+		if !local && !strings.HasPrefix(target, "data:image/") {
+			return "", fmt.Errorf("%w: url() may only point to this server or a data:image", ErrInvalidCustomCSS)
+		}
+	}
+	if cssAbsoluteURLPattern.MatchString(code) {
+		return "", fmt.Errorf("%w: strings may not hold an absolute URL", ErrInvalidCustomCSS)
+	}
+	return css, nil
+}
+
+// ThemeStylesheet returns the stylesheet that applies a custom accent color
+// over the themes and accent presets, followed by the custom CSS
+func ThemeStylesheet(accent, customCSS string) string {
+	var b strings.Builder
+	if accent != "" {
+		// The attribute selectors outrank the themes and the presets
+		fmt.Fprintf(&b, "html[data-mode=\"light\"][data-accent] {\n    --accent: %s; --accent-hover: %s; --accent-light: %s; --accent-surface: %s;\n}\n",
+			accent, mixColor(accent, "#000000", 0.15), mixColor(accent, "#ffffff", 0.7), mixColor(accent, "#ffffff", 0.94))
+		fmt.Fprintf(&b, "html[data-mode=\"dark\"][data-accent] {\n    --accent: %s; --accent-hover: %s; --accent-light: %s; --accent-surface: %s;\n}\n",
+			accent, mixColor(accent, "#ffffff", 0.2), mixColor(accent, "#000000", 0.7), mixColor(accent, "#000000", 0.88))
+	}
+	if customCSS != "" {
+		b.WriteString("\n/* Custom CSS */\n")
+		b.WriteString(customCSS)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// mixColor mixes weight of the hex color with into the hex color base
+func mixColor(base, with string, weight float64) string {
+	channel := func(color string, i int) float64 {
+		v, _ := strconv.ParseUint(color[1+2*i:3+2*i], 16, 8)
+		return float64(v)
+	}
+	mixed := "#"
+	for i := 0; i < 3; i++ {
+		v := channel(base, i)*(1-weight) + channel(with, i)*weight
+		mixed += fmt.Sprintf("%02x", int(v+0.5))
+	}
+	return mixed
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeAccentColor(t *testing.T) {
+	color, err := NormalizeAccentColor(" #2563EB ")
+	require.NoError(t, err)
+	assert.Equal(t, "#2563eb", color)
+
+	color, err = NormalizeAccentColor("")
+	require.NoError(t, err)
+	assert.Empty(t, color)
+
+	for _, invalid := range []string{"blue", "#fff", "#12345g", "#2563eb; color: red"} {
+		_, err := NormalizeAccentColor(invalid)
+		assert.ErrorIs(t, err, ErrInvalidAccentColor, invalid)
+	}
+}
+
+func TestSanitizeCustomCSS(t *testing.T) {
+	allowed := []string{
+		"",
+		".card { border-radius: 4px; }",
+		"body { background: url('/static/images/bg.png') }",
+		".logo { background-image: url(data:image/png;base64,AAAA) }",
+		".card > .card-header { font-weight: 700 }",
+		`.badge::after { content: "note: see /settings" }`,
+		"@font-face { font-family: Inter; src: url('/static/fonts/inter.woff2') format('woff2') }",
+	}
+	for _, css := range allowed {
+		_, err := SanitizeCustomCSS(css)
+		assert.NoError(t, err, css)
+	}
+
+	css, err := SanitizeCustomCSS("  .a { color: red }\r\n.b { color: blue }  ")
+	require.NoError(t, err)
+	assert.Equal(t, ".a { color: red }\n.b { color: blue }", css)
+
+	blocked := []string{
+		"</style><script>alert(1)</script>",
+		"@import url('/static/x.css');",
+		"@IMPORT 'x.css';",
+		"@im/**/port 'x.css';",
+		"body { width: expression(alert(1)) }",
+		"body { background: url(javascript:alert(1)) }",
+		"body { behavior : url(/x.htc) }",
+		"body { -moz-binding: url(/x.xml) }",
+		`body { background: url(\68ttps://tracker.example/p.png) }`,
+		"body { background: url(https://tracker.example/p.png) }",
+		"body { background: url( '//tracker.example/p.png' ) }",
+		`body { background-image: image-set("https://tracker.example/p.png" 1x) }`,
+		`body { background-image: -webkit-image-set('//tracker.example/p.png' 1x) }`,
+		`body { background-image: IMAGE-SET ("/static/a.png" 1x) }`,
+		`body { background-image: src("https://tracker.example/p.png") }`,
+		`body { cursor: "https://tracker.example/c.png", auto }`,
+		`@font-face { font-family: x; src: 'HTTPS://tracker.example/f.woff' }`,
+		strings.Repeat("a", MaxCustomCSSLength+1),
+	}
+	for _, css := range blocked {
+		_, err := SanitizeCustomCSS(css)
+		assert.ErrorIs(t, err, ErrInvalidCustomCSS, css)
+	}
+}
+
+func TestThemeStylesheet(t *testing.T) {
+	assert.Empty(t, ThemeStylesheet("", ""))
+
+	css := ThemeStylesheet("#2563eb", ".card { border-radius: 4px; }")
+	assert.Contains(t, css, `html[data-mode="light"][data-accent] {`)
+	assert.Contains(t, css, `html[data-mode="dark"][data-accent] {`)
+	assert.Contains(t, css, "--accent: #2563eb; --accent-hover: #1f54c8;")
+	assert.True(t, strings.HasSuffix(css, ".card { border-radius: 4px; }\n"))
+
+	assert.Equal(t, "#808080", mixColor("#000000", "#ffffff", 0.5))
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// UndoWindow is how long a deletion can be undone before it becomes permanent
+const UndoWindow = 30 * time.Second
+
+// Kinds of undoable deletions
+const (
+	UndoDelete = "delete" // a single subscription was deleted
+	UndoClear  = "clear"  // all subscriptions were cleared
+)
+
+// ErrUndoExpired is returned for an undo token that does not exist or has expired
+var ErrUndoExpired = errors.New("undo token not found or expired")
+
+// UndoAction is a deletion that can be undone with its token until it expires
+type UndoAction struct {
+	Token     string    `json:"undo_token,omitempty"`
+	Kind      string    `json:"kind"`
+	Count     int       `json:"count"`
+	ExpiresAt time.Time `json:"expires_at"`
+	IDs       []uint    `json:"-"`
+}
+
+// pendingUndo is a deletion waiting for its undo window to pass
+type pendingUndo struct {
+	action UndoAction
+	timer  *time.Timer
+}
+
+// UndoService deletes subscriptions so that the deletion can be undone for
+// UndoWindow. Subscriptions are soft deleted and kept with their usage, shares
+// and payments until the window passes, then purged. Tokens live in memory, so
+// subscriptions whose token was lost to a restart are purged by PurgeExpired.
+type UndoService struct {
+	subscriptions  SubscriptionServiceInterface
+	logosDir       string
+	attachmentsDir string
+	window         time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*pendingUndo
+}
+
+func NewUndoService(subscriptions SubscriptionServiceInterface, logosDir, attachmentsDir string) *UndoService {
+	return &UndoService{
+		subscriptions:  subscriptions,
+		logosDir:       logosDir,
+		attachmentsDir: attachmentsDir,
+		window:         UndoWindow,
+		pending:        make(map[string]*pendingUndo),
+	}
+}
+
+// DeleteSubscription deletes a subscription and returns the action that undoes it
+func (s *UndoService) DeleteSubscription(ctx context.Context, id uint) (*UndoAction, error) {
+	if err := s.subscriptions.SoftDelete(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.register(UndoDelete, []uint{id})
+}
+
+// ClearSubscriptions deletes all subscriptions and returns the action that
+// undoes it. Once the window has passed and no subscriptions are left, cached
+// logos and attachments are removed as well. Without subscriptions to clear
+// the action has no token.
+func (s *UndoService) ClearSubscriptions(ctx context.Context) (*UndoAction, error) {
+	ids, err := s.subscriptions.SoftDeleteAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return &UndoAction{Kind: UndoClear}, nil
+	}
+	return s.register(UndoClear, ids)
+}
+
+// Undo restores the subscriptions deleted by the action with the given token
+func (s *UndoService) Undo(ctx context.Context, token string) (*UndoAction, error) {
+	s.mu.Lock()
+	pending, ok := s.pending[token]
+	if ok {
+		delete(s.pending, token)
+	}
+	s.mu.Unlock()
+
+	// A timer that already fired is purging the subscriptions
+	if !ok || !pending.timer.Stop() {
+		return nil, ErrUndoExpired
+	}
+
+	restored, err := s.subscriptions.Restore(ctx, pending.action.IDs)
+	if err != nil {
+		// The subscriptions stay deleted and are purged by PurgeExpired
+		return nil, err
+	}
+	action := pending.action
+	action.Count = int(restored)
+	slog.Info("deletion undone", "kind", action.Kind, "subscriptions", restored)
+	return &action, nil
+}
+
+// PurgeExpired permanently deletes subscriptions deleted longer than the undo
+// window ago and returns how many were purged. It runs at startup for
+// deletions whose undo token was lost.
+func (s *UndoService) PurgeExpired(ctx context.Context) (int, error) {
+	purged, err := s.subscriptions.PurgeDeleted(ctx, nil, time.Now().Add(-s.window))
+	if err != nil {
+		return 0, err
+	}
+	if len(purged) > 0 {
+		slog.Info("purged deleted subscriptions", "subscriptions", len(purged))
+	}
+	return len(purged), nil
+}
+
+// register keeps a deletion undoable for the undo window
+func (s *UndoService) register(kind string, ids []uint) (*UndoAction, error) {
+	token, err := generateImportToken()
+	if err != nil {
+		return nil, err
+	}
+	action := UndoAction{Token: token, Kind: kind, Count: len(ids), ExpiresAt: time.Now().Add(s.window), IDs: ids}
+
+	s.mu.Lock()
+	s.pending[token] = &pendingUndo{action: action, timer: time.AfterFunc(s.window, func() { s.expire(token) })}
+	s.mu.Unlock()
+	return &action, nil
+}
+
+// expire makes a deletion permanent once its undo window has passed
+func (s *UndoService) expire(token string) {
+	s.mu.Lock()
+	pending, ok := s.pending[token]
+	delete(s.pending, token)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	purged, err := s.subscriptions.PurgeDeleted(ctx, pending.action.IDs, time.Time{})
+	if err != nil {
+		slog.Error("failed to purge deleted subscriptions", "kind", pending.action.Kind, "error", err)
+		return
+	}
+	if pending.action.Kind == UndoClear && s.subscriptions.Count(ctx) == 0 {
+		for _, dir := range []string{s.logosDir, s.attachmentsDir} {
+			if _, err := clearDir(dir); err != nil {
+				slog.Error("clearing data left files behind", "error", err)
+			}
+		}
+	}
+	slog.Info("deletion made permanent", "kind", pending.action.Kind, "subscriptions", len(purged))
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupUndoService(t *testing.T) (*UndoService, *SubscriptionService, *gorm.DB, []uint) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.UsageEvent{}, &models.SubscriptionShare{}, &models.ReminderRetry{}, &models.Payment{}))
+	services := newTestSubscriptionService(t, db)
+
+	var ids []uint
+	for _, name := range []string{"Netflix", "Spotify", "iCloud"} {
+		sub, err := services.subscriptions.Create(t.Context(), &models.Subscription{Name: name, Cost: 10, Schedule: "Monthly", Status: "Active"})
+		require.NoError(t, err)
+		require.NoError(t, db.Create(&models.UsageEvent{SubscriptionID: sub.ID, OccurredAt: time.Now()}).Error)
+		require.NoError(t, db.Create(&models.Payment{SubscriptionID: sub.ID, DueDate: time.Now(), Amount: 10}).Error)
+		ids = append(ids, sub.ID)
+	}
+	return NewUndoService(services.subscriptions, t.TempDir(), t.TempDir()), services.subscriptions, db, ids
+}
+
+func TestUndoService_DeleteAndUndo(t *testing.T) {
+	undo, subscriptions, _, ids := setupUndoService(t)
+
+	action, err := undo.DeleteSubscription(t.Context(), ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, UndoDelete, action.Kind)
+	assert.Equal(t, 1, action.Count)
+	assert.NotEmpty(t, action.Token)
+
+	_, err = subscriptions.GetByID(t.Context(), ids[0])
+	assert.Error(t, err)
+	assert.Equal(t, int64(2), subscriptions.Count(t.Context()))
+
+	restored, err := undo.Undo(t.Context(), action.Token)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored.Count)
+	assert.Equal(t, []uint{ids[0]}, restored.IDs)
+
+	sub, err := subscriptions.GetByID(t.Context(), ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, "Netflix", sub.Name)
+
+	// A token works once
+	_, err = undo.Undo(t.Context(), action.Token)
+	assert.ErrorIs(t, err, ErrUndoExpired)
+	_, err = undo.Undo(t.Context(), "unknown")
+	assert.ErrorIs(t, err, ErrUndoExpired)
+}
+
+func TestUndoService_ExpiryPurges(t *testing.T) {
+	undo, _, db, ids := setupUndoService(t)
+
+	action, err := undo.DeleteSubscription(t.Context(), ids[0])
+	require.NoError(t, err)
+
+	// The window passes before the undo arrives
+	undo.expire(action.Token)
+	_, err = undo.Undo(t.Context(), action.Token)
+	assert.ErrorIs(t, err, ErrUndoExpired)
+
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&models.Subscription{}).Where("id = ?", ids[0]).Count(&count).Error)
+	assert.Zero(t, count)
+	for _, model := range []any{&models.UsageEvent{}, &models.Payment{}} {
+		require.NoError(t, db.Model(model).Where("subscription_id = ?", ids[0]).Count(&count).Error)
+		assert.Zero(t, count)
+	}
+
+	// The other subscriptions keep their data
+	require.NoError(t, db.Model(&models.Payment{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestUndoService_ClearSubscriptions(t *testing.T) {
+	undo, subscriptions, db, _ := setupUndoService(t)
+	require.NoError(t, os.WriteFile(filepath.Join(undo.logosDir, "netflix.png"), []byte("png"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(undo.attachmentsDir, "1"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(undo.attachmentsDir, "1", "invoice.pdf"), []byte("pdf"), 0o644))
+
+	action, err := undo.ClearSubscriptions(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, UndoClear, action.Kind)
+	assert.Equal(t, 3, action.Count)
+	assert.Zero(t, subscriptions.Count(t.Context()))
+
+	// Undoing brings back the subscriptions with their files
+	_, err = undo.Undo(t.Context(), action.Token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), subscriptions.Count(t.Context()))
+
+	// Once the window has passed the data and files are gone for good
+	action, err = undo.ClearSubscriptions(t.Context())
+	require.NoError(t, err)
+	undo.expire(action.Token)
+
+	for _, model := range []any{&models.UsageEvent{}, &models.Payment{}} {
+		var count int64
+		require.NoError(t, db.Model(model).Count(&count).Error)
+		assert.Zero(t, count)
+	}
+	for _, dir := range []string{undo.logosDir, undo.attachmentsDir} {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	}
+
+	// Nothing left to clear
+	action, err = undo.ClearSubscriptions(t.Context())
+	require.NoError(t, err)
+	assert.Zero(t, action.Count)
+	assert.Empty(t, action.Token)
+}
+
+func TestUndoService_PurgeExpired(t *testing.T) {
+	undo, subscriptions, db, ids := setupUndoService(t)
+
+	// A deletion whose token was lost to a restart
+	require.NoError(t, subscriptions.SoftDelete(t.Context(), ids[0]))
+	require.NoError(t, db.Unscoped().Model(&models.Subscription{}).Where("id = ?", ids[0]).
+		Update("deleted_at", time.Now().Add(-time.Hour)).Error)
+	// A deletion that can still be undone
+	_, err := undo.DeleteSubscription(t.Context(), ids[1])
+	require.NoError(t, err)
+
+	purged, err := undo.PurgeExpired(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&models.Subscription{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"subvault/internal/version"
+)
+
+const (
+	latestReleaseURL = "https://api.github.com/repos/YakGravity/subvault/releases/latest"
+	// maxReleaseSize bounds the GitHub response, which includes the release notes
+	maxReleaseSize = 1 << 20
+)
+
+// UpdateStatus describes the running build and, when update checks are
+// enabled, the latest published release
+type UpdateStatus struct {
+	Version         string     `json:"version"`
+	Commit          string     `json:"commit"`
+	GoVersion       string     `json:"go_version"`
+	CheckEnabled    bool       `json:"update_check_enabled"`
+	UpdateAvailable bool       `json:"update_available"`
+	LatestVersion   string     `json:"latest_version,omitempty"`
+	ReleaseURL      string     `json:"release_url,omitempty"`
+	ReleaseNotes    string     `json:"release_notes,omitempty"`
+	CheckedAt       *time.Time `json:"checked_at,omitempty"`
+}
+
+// githubRelease is the part of the GitHub releases API response that is used
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+	Body    string `json:"body"`
+}
+
+// UpdateService checks GitHub for newer releases. Checks are off by default;
+// nothing is sent unless they are enabled in the settings.
+type UpdateService struct {
+	settings   *SettingsService
+	client     *http.Client
+	releaseURL string
+
+	mu        sync.RWMutex
+	latest    *githubRelease
+	checkedAt time.Time
+}
+
+// NewUpdateService creates an update service
+func NewUpdateService(settings *SettingsService) *UpdateService {
+	return &UpdateService{
+		settings:   settings,
+		client:     NewHTTPClient(10 * time.Second),
+		releaseURL: latestReleaseURL,
+	}
+}
+
+// IsEnabled reports whether update checks are enabled. They are always off
+// in offline mode.
+func (s *UpdateService) IsEnabled() bool {
+	return !OfflineMode() && s.settings.GetBoolSettingWithDefault(SettingKeyUpdateCheck, false)
+}
+
+// SetEnabled turns update checks on or off. Turning them off forgets the last
+// result; turning them on fails in offline mode.
+func (s *UpdateService) SetEnabled(enabled bool) error {
+	if enabled && OfflineMode() {
+		return ErrOfflineMode
+	}
+	if err := s.settings.SetBoolSetting(SettingKeyUpdateCheck, enabled); err != nil {
+		return err
+	}
+	if !enabled {
+		s.mu.Lock()
+		s.latest = nil
+		s.checkedAt = time.Time{}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// Check fetches the latest release. It does nothing while checks are disabled.
+func (s *UpdateService) Check(ctx context.Context) error {
+	if !s.IsEnabled() {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.releaseURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "SubVault/"+version.GetVersion())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxReleaseSize)).Decode(&release); err != nil {
+		return fmt.Errorf("failed to decode latest release: %w", err)
+	}
+	if _, ok := parseVersion(release.TagName); !ok {
+		return fmt.Errorf("latest release has an invalid tag %q", release.TagName)
+	}
+
+	s.mu.Lock()
+	s.latest = &release
+	s.checkedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// Status returns the running version and the result of the last check
+func (s *UpdateService) Status() UpdateStatus {
+	status := UpdateStatus{
+		Version:      version.GetVersion(),
+		Commit:       version.GitCommit,
+		GoVersion:    runtime.Version(),
+		CheckEnabled: s.IsEnabled(),
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !status.CheckEnabled || s.latest == nil {
+		return status
+	}
+	checkedAt := s.checkedAt
+	status.CheckedAt = &checkedAt
+	status.LatestVersion = s.latest.TagName
+	status.UpdateAvailable = isNewerVersion(s.latest.TagName, status.Version)
+	if status.UpdateAvailable {
+		status.ReleaseURL = s.latest.HTMLURL
+		status.ReleaseNotes = s.latest.Body
+	}
+	return status
+}
+
+// parseVersion parses a "v1.2.3" style version; pre-release and build suffixes are ignored
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "-")
+	v, _, _ = strings.Cut(v, "+")
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// isNewerVersion reports whether latest is a higher version than current.
+// Development builds, whose version is not a release tag, never report updates.
+func isNewerVersion(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
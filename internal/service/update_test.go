@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"subvault/internal/repository"
+	"subvault/internal/version"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsNewerVersion(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		newer           bool
+	}{
+		{"v1.6.0", "v1.5.1", true},
+		{"v1.5.10", "v1.5.9", true},
+		{"v2.0", "v1.9.9", true},
+		{"1.5.2", "v1.5.1", true},
+		{"v1.5.1", "v1.5.1", false},
+		{"v1.5.0", "v1.5.1", false},
+		{"v1.6.0-rc1", "v1.5.1", true},
+		{"v1.6.0", "dev", false},
+		{"v1.6.0", "a1b2c3d", false},
+		{"nightly", "v1.5.1", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.newer, isNewerVersion(tt.latest, tt.current), "%s vs %s", tt.latest, tt.current)
+	}
+}
+
+func TestUpdateService_Check(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Contains(t, r.Header.Get("User-Agent"), "SubVault/")
+		w.Write([]byte(`{"tag_name":"v99.0.0","html_url":"https://github.com/YakGravity/subvault/releases/tag/v99.0.0","body":"- New things"}`))
+	}))
+	defer server.Close()
+
+	originalVersion := version.Version
+	version.Version = "v1.5.1"
+	t.Cleanup(func() { version.Version = originalVersion })
+
+	db := setupRenewalReminderTestDB(t)
+	updates := NewUpdateService(NewSettingsService(repository.NewSettingsRepository(db)))
+	updates.releaseURL = server.URL
+
+	// Off by default: nothing is requested
+	require.NoError(t, updates.Check(context.Background()))
+	assert.Zero(t, requests)
+	status := updates.Status()
+	assert.False(t, status.CheckEnabled)
+	assert.Equal(t, "v1.5.1", status.Version)
+	assert.NotEmpty(t, status.GoVersion)
+
+	require.NoError(t, updates.SetEnabled(true))
+	require.NoError(t, updates.Check(context.Background()))
+	assert.Equal(t, 1, requests)
+	status = updates.Status()
+	assert.True(t, status.UpdateAvailable)
+	assert.Equal(t, "v99.0.0", status.LatestVersion)
+	assert.Equal(t, "https://github.com/YakGravity/subvault/releases/tag/v99.0.0", status.ReleaseURL)
+	assert.Equal(t, "- New things", status.ReleaseNotes)
+	assert.NotNil(t, status.CheckedAt)
+
+	// Disabling forgets the result
+	require.NoError(t, updates.SetEnabled(false))
+	status = updates.Status()
+	assert.False(t, status.UpdateAvailable)
+	assert.Empty(t, status.LatestVersion)
+}
+
+func TestUpdateService_CheckErrors(t *testing.T) {
+	body := `{"tag_name":"latest"}`
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	db := setupRenewalReminderTestDB(t)
+	updates := NewUpdateService(NewSettingsService(repository.NewSettingsRepository(db)))
+	updates.releaseURL = server.URL
+	require.NoError(t, updates.SetEnabled(true))
+
+	assert.Error(t, updates.Check(context.Background()), "invalid tag")
+	status = http.StatusForbidden
+	assert.Error(t, updates.Check(context.Background()), "rate limited")
+	assert.Empty(t, updates.Status().LatestVersion)
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+)
+
+// UsageWindowDays is the period used for cost-per-use and unused-subscription analysis
+const UsageWindowDays = 30
+
+// maxConsiderCancelling limits the "consider cancelling" list on the dashboard
+const maxConsiderCancelling = 5
+
+// ErrUsageSubscriptionNotFound is returned when logging usage for an unknown subscription
+var ErrUsageSubscriptionNotFound = errors.New("subscription not found")
+
+// ErrUsageInFuture is returned when a usage event is dated in the future
+var ErrUsageInFuture = errors.New("usage event cannot be in the future")
+
+// CostPerUse describes how much a subscription costs per recorded use
+// within the analysis window, in the display currency.
+type CostPerUse struct {
+	SubscriptionID uint       `json:"subscription_id"`
+	Name           string     `json:"name"`
+	Status         string     `json:"status"`
+	MonthlyCost    float64    `json:"monthly_cost"`
+	Currency       string     `json:"currency"`
+	Uses           int64      `json:"uses"`
+	TotalUses      int64      `json:"total_uses"`
+	CostPerUse     *float64   `json:"cost_per_use"` // nil when not used in the window
+	LastUsed       *time.Time `json:"last_used"`
+	Tracked        bool       `json:"tracked"` // at least one usage event was ever recorded
+	Usage          string     `json:"usage"`   // static usage rating (High/Medium/Low/None)
+}
+
+type UsageService struct {
+	repo          *repository.UsageEventRepository
+	subscriptions SubscriptionServiceInterface
+	currency      CurrencyServiceInterface
+	preferences   PreferencesServiceInterface
+}
+
+func NewUsageService(repo *repository.UsageEventRepository, subscriptions SubscriptionServiceInterface, currency CurrencyServiceInterface, preferences PreferencesServiceInterface) *UsageService {
+	return &UsageService{
+		repo:          repo,
+		subscriptions: subscriptions,
+		currency:      currency,
+		preferences:   preferences,
+	}
+}
+
+// LogEvent records a use of the subscription. A zero occurredAt means now.
+func (s *UsageService) LogEvent(ctx context.Context, subscriptionID uint, occurredAt time.Time, note string) (*models.UsageEvent, error) {
+	if _, err := s.subscriptions.GetByID(ctx, subscriptionID); err != nil {
+		return nil, ErrUsageSubscriptionNotFound
+	}
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+	if occurredAt.After(time.Now().Add(time.Minute)) {
+		return nil, ErrUsageInFuture
+	}
+	return s.repo.Create(&models.UsageEvent{
+		SubscriptionID: subscriptionID,
+		OccurredAt:     occurredAt,
+		Note:           note,
+	})
+}
+
+// GetEvents returns the most recent usage events of a subscription
+func (s *UsageService) GetEvents(subscriptionID uint, limit int) ([]models.UsageEvent, error) {
+	return s.repo.GetBySubscription(subscriptionID, limit)
+}
+
+// GetCostPerUse returns cost-per-use figures for all active and trial subscriptions,
+// most expensive per use first. Subscriptions not used in the window come first.
+func (s *UsageService) GetCostPerUse(ctx context.Context) ([]CostPerUse, error) {
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	windowStart := time.Now().AddDate(0, 0, -UsageWindowDays)
+	summaries, err := s.repo.GetSummaries(windowStart)
+	if err != nil {
+		return nil, err
+	}
+
+	displayCurrency := s.preferences.GetCurrency()
+	result := make([]CostPerUse, 0, len(subscriptions))
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		if sub.Status != "Active" && sub.Status != "Trial" {
+			continue
+		}
+
+		entry := CostPerUse{
+			SubscriptionID: sub.ID,
+			Name:           sub.Name,
+			Status:         sub.Status,
+			MonthlyCost:    s.monthlyCostIn(sub, displayCurrency),
+			Currency:       displayCurrency,
+			Usage:          sub.Usage,
+		}
+		if summary, ok := summaries[sub.ID]; ok {
+			entry.Tracked = true
+			entry.Uses = summary.RecentCount
+			entry.TotalUses = summary.TotalCount
+			entry.LastUsed = summary.LastUsed
+			if summary.RecentCount > 0 {
+				perUse := entry.MonthlyCost * UsageWindowDays / 30.44 / float64(summary.RecentCount)
+				entry.CostPerUse = &perUse
+			}
+		}
+		result = append(result, entry)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if (a.CostPerUse == nil) != (b.CostPerUse == nil) {
+			return a.CostPerUse == nil
+		}
+		if a.CostPerUse != nil && *a.CostPerUse != *b.CostPerUse {
+			return *a.CostPerUse > *b.CostPerUse
+		}
+		return a.MonthlyCost > b.MonthlyCost
+	})
+
+	return result, nil
+}
+
+// GetConsiderCancelling returns expensive subscriptions that appear unused: tracked
+// subscriptions without any use in the window, or untracked ones rated "None".
+// Subscriptions younger than the window are ignored.
+func (s *UsageService) GetConsiderCancelling(ctx context.Context) ([]CostPerUse, error) {
+	all, err := s.GetCostPerUse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	windowStart := time.Now().AddDate(0, 0, -UsageWindowDays)
+	established := make(map[uint]bool, len(subscriptions))
+	for _, sub := range subscriptions {
+		established[sub.ID] = sub.CreatedAt.Before(windowStart)
+	}
+
+	var candidates []CostPerUse
+	for _, entry := range all {
+		if entry.Status != "Active" || entry.MonthlyCost <= 0 || !established[entry.SubscriptionID] {
+			continue
+		}
+		unusedTracked := entry.Tracked && entry.Uses == 0
+		unusedRated := !entry.Tracked && entry.Usage == "None"
+		if unusedTracked || unusedRated {
+			candidates = append(candidates, entry)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].MonthlyCost > candidates[j].MonthlyCost
+	})
+	if len(candidates) > maxConsiderCancelling {
+		candidates = candidates[:maxConsiderCancelling]
+	}
+	return candidates, nil
+}
+
+// monthlyCostIn returns the subscription's monthly cost converted to the given currency.
+// Falls back to the unconverted amount if no exchange rate is available.
+func (s *UsageService) monthlyCostIn(sub *models.Subscription, currency string) float64 {
+	monthly := sub.MonthlyCost()
+	if sub.OriginalCurrency == "" || sub.OriginalCurrency == currency {
+		return monthly
+	}
+	converted, err := s.currency.ConvertAmount(monthly, sub.OriginalCurrency, currency)
+	if err != nil {
+		return monthly
+	}
+	return converted
+}
+
+// UnusedNudge lists active subscriptions that are rarely or never used,
+// together with the monthly amount that cancelling them would save.
+type UnusedNudge struct {
+	Subscriptions  []CostPerUse `json:"subscriptions"`
+	MonthlySavings float64      `json:"monthly_savings"`
+	Currency       string       `json:"currency"`
+}
+
+// GetUnusedNudge returns active subscriptions whose monthly cost exceeds threshold and
+// that look unused. Subscriptions with usage events are judged by their events in the
+// window; all others by their Low/None usage rating.
+func (s *UsageService) GetUnusedNudge(ctx context.Context, threshold float64) (*UnusedNudge, error) {
+	all, err := s.GetCostPerUse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nudge := &UnusedNudge{Currency: s.preferences.GetCurrency()}
+	for _, entry := range all {
+		if entry.Status != "Active" || entry.MonthlyCost <= threshold {
+			continue
+		}
+		unused := entry.Usage == "Low" || entry.Usage == "None"
+		if entry.Tracked {
+			unused = entry.Uses == 0
+		}
+		if unused {
+			nudge.Subscriptions = append(nudge.Subscriptions, entry)
+			nudge.MonthlySavings += entry.MonthlyCost
+		}
+	}
+
+	sort.SliceStable(nudge.Subscriptions, func(i, j int) bool {
+		return nudge.Subscriptions[i].MonthlyCost > nudge.Subscriptions[j].MonthlyCost
+	})
+	return nudge, nil
+}
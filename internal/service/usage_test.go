@@ -0,0 +1,121 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupUsageService(t *testing.T) (*gorm.DB, *UsageService) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.UsageEvent{}))
+	services := newTestSubscriptionService(t, db)
+
+	return db, NewUsageService(repository.NewUsageEventRepository(db), services.subscriptions, services.currency, services.preferences)
+}
+
+func createUsageTestSubscription(t *testing.T, db *gorm.DB, name string, cost float64, usage string, createdAt time.Time) *models.Subscription {
+	sub := &models.Subscription{
+		Name:             name,
+		Cost:             cost,
+		Schedule:         "Monthly",
+		Status:           "Active",
+		Usage:            usage,
+		OriginalCurrency: "USD",
+	}
+	require.NoError(t, db.Create(sub).Error)
+	require.NoError(t, db.Model(sub).Update("created_at", createdAt).Error)
+	return sub
+}
+
+func TestUsageService_LogEvent(t *testing.T) {
+	db, usageService := setupUsageService(t)
+	sub := createUsageTestSubscription(t, db, "Netflix", 15, "High", time.Now())
+
+	event, err := usageService.LogEvent(t.Context(), sub.ID, time.Time{}, "movie night")
+	require.NoError(t, err)
+	assert.NotZero(t, event.ID)
+	assert.WithinDuration(t, time.Now(), event.OccurredAt, time.Minute)
+
+	_, err = usageService.LogEvent(t.Context(), sub.ID, time.Now().Add(time.Hour), "")
+	assert.ErrorIs(t, err, ErrUsageInFuture)
+
+	_, err = usageService.LogEvent(t.Context(), 9999, time.Time{}, "")
+	assert.ErrorIs(t, err, ErrUsageSubscriptionNotFound)
+
+	events, err := usageService.GetEvents(sub.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "movie night", events[0].Note)
+}
+
+func TestUsageService_CostPerUseAndConsiderCancelling(t *testing.T) {
+	db, usageService := setupUsageService(t)
+	old := time.Now().AddDate(0, -3, 0)
+
+	used := createUsageTestSubscription(t, db, "Gym", 30, "", old)
+	stale := createUsageTestSubscription(t, db, "Magazine", 10, "", old)
+	rated := createUsageTestSubscription(t, db, "Cloud", 20, "None", old)
+	createUsageTestSubscription(t, db, "New", 50, "None", time.Now())
+
+	for i := 0; i < 3; i++ {
+		_, err := usageService.LogEvent(t.Context(), used.ID, time.Now().AddDate(0, 0, -i), "")
+		require.NoError(t, err)
+	}
+	// Only used before the window
+	_, err := usageService.LogEvent(t.Context(), stale.ID, time.Now().AddDate(0, 0, -UsageWindowDays-5), "")
+	require.NoError(t, err)
+
+	costPerUse, err := usageService.GetCostPerUse(t.Context())
+	require.NoError(t, err)
+	require.Len(t, costPerUse, 4)
+
+	var gym CostPerUse
+	for _, entry := range costPerUse {
+		if entry.SubscriptionID == used.ID {
+			gym = entry
+		}
+	}
+	assert.Equal(t, int64(3), gym.Uses)
+	require.NotNil(t, gym.CostPerUse)
+	assert.InDelta(t, 30*UsageWindowDays/30.44/3, *gym.CostPerUse, 0.01)
+
+	candidates, err := usageService.GetConsiderCancelling(t.Context())
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	// Sorted by monthly cost; the brand-new subscription is not flagged yet
+	assert.Equal(t, rated.ID, candidates[0].SubscriptionID)
+	assert.Equal(t, stale.ID, candidates[1].SubscriptionID)
+	assert.NotNil(t, candidates[1].LastUsed)
+}
+
+func TestUsageService_GetUnusedNudge(t *testing.T) {
+	db, usageService := setupUsageService(t)
+	old := time.Now().AddDate(0, -3, 0)
+
+	createUsageTestSubscription(t, db, "Cheap", 5, "None", old)
+	rarely := createUsageTestSubscription(t, db, "Streaming", 15, "Low", old)
+	createUsageTestSubscription(t, db, "Daily", 40, "High", old)
+	tracked := createUsageTestSubscription(t, db, "Gym", 30, "Low", old)
+
+	// A recently logged use overrides the static rating
+	_, err := usageService.LogEvent(t.Context(), tracked.ID, time.Now(), "")
+	require.NoError(t, err)
+
+	nudge, err := usageService.GetUnusedNudge(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, nudge.Subscriptions, 1)
+	assert.Equal(t, rarely.ID, nudge.Subscriptions[0].SubscriptionID)
+	assert.InDelta(t, 15, nudge.MonthlySavings, 0.001)
+
+	nudge, err = usageService.GetUnusedNudge(t.Context(), 0)
+	require.NoError(t, err)
+	assert.Len(t, nudge.Subscriptions, 2)
+	assert.InDelta(t, 20, nudge.MonthlySavings, 0.001)
+}
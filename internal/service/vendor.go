@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidVendor is returned when a vendor has no name
+var ErrInvalidVendor = errors.New("vendor name is required")
+
+// ErrVendorExists is returned when a vendor with the same name already exists
+var ErrVendorExists = errors.New("vendor already exists")
+
+// ErrVendorNotFound is returned when a vendor does not exist
+var ErrVendorNotFound = errors.New("vendor not found")
+
+// VendorService manages the vendors that group related subscriptions
+type VendorService struct {
+	repo *repository.VendorRepository
+}
+
+// NewVendorService creates a vendor service
+func NewVendorService(repo *repository.VendorRepository) *VendorService {
+	return &VendorService{repo: repo}
+}
+
+// List returns all vendors sorted by name
+func (s *VendorService) List() ([]models.Vendor, error) {
+	return s.repo.GetAll()
+}
+
+// GetByID returns a vendor
+func (s *VendorService) GetByID(id uint) (*models.Vendor, error) {
+	vendor, err := s.repo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrVendorNotFound
+	}
+	return vendor, err
+}
+
+// Create adds a vendor. Names are unique ignoring case.
+func (s *VendorService) Create(name string) (*models.Vendor, error) {
+	name = strings.TrimSpace(name)
+	if err := s.checkName(0, name); err != nil {
+		return nil, err
+	}
+	vendor := &models.Vendor{Name: name}
+	if err := s.repo.Create(vendor); err != nil {
+		return nil, err
+	}
+	return vendor, nil
+}
+
+// Rename changes the name of a vendor
+func (s *VendorService) Rename(id uint, name string) (*models.Vendor, error) {
+	name = strings.TrimSpace(name)
+	if _, err := s.GetByID(id); err != nil {
+		return nil, err
+	}
+	if err := s.checkName(id, name); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Rename(id, name); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+// Delete removes a vendor. Its subscriptions are kept without a vendor.
+func (s *VendorService) Delete(id uint) error {
+	err := s.repo.Delete(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrVendorNotFound
+	}
+	return err
+}
+
+// checkName rejects empty names and names used by another vendor
+func (s *VendorService) checkName(id uint, name string) error {
+	if name == "" {
+		return ErrInvalidVendor
+	}
+	vendors, err := s.repo.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, vendor := range vendors {
+		if vendor.ID != id && strings.EqualFold(vendor.Name, name) {
+			return ErrVendorExists
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+
+	"subvault/internal/models"
+	"subvault/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupVendorServices(t *testing.T) (*VendorService, *SubscriptionService) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Vendor{}, &models.UsageEvent{}, &models.SubscriptionShare{}, &models.ReminderRetry{}, &models.Payment{}))
+	services := newTestSubscriptionService(t, db)
+	return NewVendorService(repository.NewVendorRepository(db)), services.subscriptions
+}
+
+func TestVendorService(t *testing.T) {
+	vendors, subscriptions := setupVendorServices(t)
+
+	aws, err := vendors.Create(" AWS ")
+	require.NoError(t, err)
+	assert.Equal(t, "AWS", aws.Name)
+	google, err := vendors.Create("Google Workspace")
+	require.NoError(t, err)
+
+	_, err = vendors.Create("aws")
+	assert.ErrorIs(t, err, ErrVendorExists)
+	_, err = vendors.Create("  ")
+	assert.ErrorIs(t, err, ErrInvalidVendor)
+
+	// Renaming may change the case of the own name but not take another's
+	renamed, err := vendors.Rename(aws.ID, "Amazon Web Services")
+	require.NoError(t, err)
+	assert.Equal(t, "Amazon Web Services", renamed.Name)
+	_, err = vendors.Rename(google.ID, "amazon web services")
+	assert.ErrorIs(t, err, ErrVendorExists)
+	_, err = vendors.Rename(999, "Azure")
+	assert.ErrorIs(t, err, ErrVendorNotFound)
+
+	list, err := vendors.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "Amazon Web Services", list[0].Name)
+
+	// Deleting a vendor keeps its subscriptions
+	sub, err := subscriptions.Create(t.Context(), &models.Subscription{Name: "AWS prod", Cost: 40, Schedule: "Monthly", Status: "Active", VendorID: &aws.ID})
+	require.NoError(t, err)
+	sub, err = subscriptions.GetByID(t.Context(), sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Amazon Web Services", sub.VendorName())
+
+	require.NoError(t, vendors.Delete(aws.ID))
+	assert.ErrorIs(t, vendors.Delete(aws.ID), ErrVendorNotFound)
+	sub, err = subscriptions.GetByID(t.Context(), sub.ID)
+	require.NoError(t, err)
+	assert.Nil(t, sub.VendorID)
+	assert.Empty(t, sub.VendorName())
+}
+
+func TestSubscriptionService_VendorSpends(t *testing.T) {
+	_, s := setupVendorServices(t)
+	aws, google := uint(1), uint(2)
+	subs := []models.Subscription{
+		{Name: "AWS prod", Cost: 40, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", VendorID: &aws, Vendor: &models.Vendor{ID: aws, Name: "AWS"}},
+		{Name: "AWS dev", Cost: 120, Schedule: "Annual", Status: "Active", OriginalCurrency: "EUR", VendorID: &aws, Vendor: &models.Vendor{ID: aws, Name: "AWS"}},
+		{Name: "AWS old", Cost: 99, Schedule: "Monthly", Status: "Cancelled", OriginalCurrency: "EUR", VendorID: &aws, Vendor: &models.Vendor{ID: aws, Name: "AWS"}},
+		{Name: "Workspace", Cost: 60, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", VendorID: &google, Vendor: &models.Vendor{ID: google, Name: "Google"}},
+		{Name: "Netflix", Cost: 50, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR"},
+	}
+
+	vendors := s.vendorSpends(subs, 160, "EUR")
+	require.Len(t, vendors, 2)
+	assert.Equal(t, models.VendorSpend{ID: google, Name: "Google", MonthlySpend: 60, Count: 1, Share: 37.5}, vendors[0])
+	assert.Equal(t, models.VendorSpend{ID: aws, Name: "AWS", MonthlySpend: 50, Count: 2, Share: 31.25}, vendors[1])
+}
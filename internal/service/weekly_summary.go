@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"time"
+
+	"subvault/internal/models"
+)
+
+// settingKeyWeeklySummaryBaseline stores the subscriptions as of the last summary
+const settingKeyWeeklySummaryBaseline = "weekly_summary_baseline"
+
+// weeklySummaryPeriod is how often the summary is sent
+const weeklySummaryPeriod = 7 * 24 * time.Hour
+
+// summaryEntry is what the baseline remembers about one subscription
+type summaryEntry struct {
+	Name        string  `json:"name"`
+	Cost        float64 `json:"cost"`
+	Currency    string  `json:"currency"`
+	Schedule    string  `json:"schedule"`
+	Status      string  `json:"status"`
+	Fingerprint uint64  `json:"fingerprint"`
+}
+
+// summaryBaseline is a snapshot of all subscriptions taken with each summary
+type summaryBaseline struct {
+	Date          time.Time             `json:"date"`
+	Subscriptions map[uint]summaryEntry `json:"subscriptions"`
+}
+
+// WeeklySummaryItem is a subscription that was added, edited, cancelled or
+// deleted during the week
+type WeeklySummaryItem struct {
+	ID       uint    `json:"id"`
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Cost     float64 `json:"cost"`
+	Currency string  `json:"currency"`
+	Schedule string  `json:"schedule"`
+}
+
+// WeeklyPriceChange is a subscription whose price or billing schedule changed
+type WeeklyPriceChange struct {
+	ID          uint    `json:"id"`
+	Name        string  `json:"name"`
+	OldCost     float64 `json:"old_cost"`
+	OldCurrency string  `json:"old_currency"`
+	OldSchedule string  `json:"old_schedule"`
+	NewCost     float64 `json:"new_cost"`
+	NewCurrency string  `json:"new_currency"`
+	NewSchedule string  `json:"new_schedule"`
+}
+
+// WeeklyRenewal is a renewal due in the coming week
+type WeeklyRenewal struct {
+	ID       uint      `json:"id"`
+	Name     string    `json:"name"`
+	Date     time.Time `json:"date"`
+	Cost     float64   `json:"cost"`
+	Currency string    `json:"currency"`
+}
+
+// WeeklySummary sums up the changes since the last summary, the renewals of
+// the coming week and where the spend stands against the budgets. Amounts
+// outside the items are in Currency, the display currency.
+type WeeklySummary struct {
+	Since         time.Time           `json:"since"`
+	Until         time.Time           `json:"until"`
+	Added         []WeeklySummaryItem `json:"added"`
+	Edited        []WeeklySummaryItem `json:"edited"`
+	Cancelled     []WeeklySummaryItem `json:"cancelled"`
+	Deleted       []WeeklySummaryItem `json:"deleted"`
+	PriceChanges  []WeeklyPriceChange `json:"price_changes"`
+	Renewals      []WeeklyRenewal     `json:"renewals"`
+	RenewalsTotal float64             `json:"renewals_total"`
+	Currency      string              `json:"currency"`
+	MonthlySpend  float64             `json:"monthly_spend"`
+	MonthlyBudget float64             `json:"monthly_budget"` // Including rollover, 0 without a budget
+	AnnualSpend   float64             `json:"annual_spend"`
+	AnnualBudget  float64             `json:"annual_budget"`
+}
+
+// HasChanges reports whether any subscription changed during the week
+func (s *WeeklySummary) HasChanges() bool {
+	return len(s.Added)+len(s.Edited)+len(s.Cancelled)+len(s.Deleted)+len(s.PriceChanges) > 0
+}
+
+// WeeklySummaryService builds the opt-in weekly summary by comparing the
+// subscriptions with a snapshot taken at the previous summary
+type WeeklySummaryService struct {
+	subscriptions SubscriptionServiceInterface
+	currency      CurrencyServiceInterface
+	preferences   PreferencesServiceInterface
+	settings      *SettingsService
+}
+
+func NewWeeklySummaryService(subscriptions SubscriptionServiceInterface, currency CurrencyServiceInterface, preferences PreferencesServiceInterface, settings *SettingsService) *WeeklySummaryService {
+	return &WeeklySummaryService{
+		subscriptions: subscriptions,
+		currency:      currency,
+		preferences:   preferences,
+		settings:      settings,
+	}
+}
+
+// Check returns the summary once a week after the previous one, and nil in
+// between. Without a previous snapshot, which is the case for the first
+// summary, the week's changes are taken from creation and cancellation dates
+// and edits and price changes are not known. Each summary stores a new
+// snapshot.
+func (s *WeeklySummaryService) Check(ctx context.Context, now time.Time) (*WeeklySummary, error) {
+	baseline := s.loadBaseline()
+	if baseline != nil && now.Sub(baseline.Date) < weeklySummaryPeriod {
+		return nil, nil
+	}
+
+	subscriptions, err := s.subscriptions.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.subscriptions.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := s.build(subscriptions, baseline, now)
+	summary.MonthlySpend = stats.TotalMonthlySpend
+	summary.MonthlyBudget = stats.EffectiveMonthlyBudget
+	summary.AnnualSpend = stats.TotalAnnualSpend
+	summary.AnnualBudget = stats.AnnualBudget
+
+	current := &summaryBaseline{Date: now, Subscriptions: make(map[uint]summaryEntry, len(subscriptions))}
+	for i := range subscriptions {
+		current.Subscriptions[subscriptions[i].ID] = newSummaryEntry(&subscriptions[i])
+	}
+	if err := s.saveBaseline(current); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// NextSummary returns when the next summary is due: a week after the previous
+// one, or now if that has passed or there was none
+func (s *WeeklySummaryService) NextSummary(now time.Time) time.Time {
+	baseline := s.loadBaseline()
+	if baseline == nil || now.Sub(baseline.Date) >= weeklySummaryPeriod {
+		return now
+	}
+	return baseline.Date.Add(weeklySummaryPeriod)
+}
+
+// build compares the subscriptions with the baseline and collects the
+// renewals of the seven days from now
+func (s *WeeklySummaryService) build(subscriptions []models.Subscription, baseline *summaryBaseline, now time.Time) *WeeklySummary {
+	summary := &WeeklySummary{
+		Since:    now.Add(-weeklySummaryPeriod),
+		Until:    now,
+		Currency: s.preferences.GetCurrency(),
+	}
+	if baseline != nil {
+		summary.Since = baseline.Date
+	}
+
+	seen := make(map[uint]bool, len(subscriptions))
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		seen[sub.ID] = true
+		item := WeeklySummaryItem{ID: sub.ID, Name: sub.Name, Status: sub.Status, Cost: sub.Cost, Currency: sub.OriginalCurrency, Schedule: sub.Schedule}
+
+		if baseline == nil {
+			switch {
+			case !sub.CreatedAt.Before(summary.Since):
+				summary.Added = append(summary.Added, item)
+			case sub.Status == "Cancelled" && sub.CancellationDate != nil && !sub.CancellationDate.Before(summary.Since) && !sub.CancellationDate.After(now):
+				summary.Cancelled = append(summary.Cancelled, item)
+			}
+			continue
+		}
+
+		previous, ok := baseline.Subscriptions[sub.ID]
+		current := newSummaryEntry(sub)
+		switch {
+		case !ok:
+			summary.Added = append(summary.Added, item)
+		case sub.Status == "Cancelled" && previous.Status != "Cancelled":
+			summary.Cancelled = append(summary.Cancelled, item)
+		case previous.Cost != current.Cost || previous.Currency != current.Currency || previous.Schedule != current.Schedule:
+			summary.PriceChanges = append(summary.PriceChanges, WeeklyPriceChange{
+				ID:          sub.ID,
+				Name:        sub.Name,
+				OldCost:     previous.Cost,
+				OldCurrency: previous.Currency,
+				OldSchedule: previous.Schedule,
+				NewCost:     current.Cost,
+				NewCurrency: current.Currency,
+				NewSchedule: current.Schedule,
+			})
+		case previous.Fingerprint != current.Fingerprint || previous.Status != current.Status:
+			summary.Edited = append(summary.Edited, item)
+		}
+	}
+	if baseline != nil {
+		for id, previous := range baseline.Subscriptions {
+			if !seen[id] {
+				summary.Deleted = append(summary.Deleted, WeeklySummaryItem{ID: id, Name: previous.Name, Status: previous.Status,
+					Cost: previous.Cost, Currency: previous.Currency, Schedule: previous.Schedule})
+			}
+		}
+		sort.Slice(summary.Deleted, func(i, j int) bool { return summary.Deleted[i].Name < summary.Deleted[j].Name })
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	nextWeek := today.AddDate(0, 0, 7)
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		if sub.RenewalDate == nil || (sub.Status != "Active" && sub.Status != "Trial") ||
+			sub.RenewalDate.Before(today) || !sub.RenewalDate.Before(nextWeek) {
+			continue
+		}
+		cost := sub.CostAt(*sub.RenewalDate)
+		summary.Renewals = append(summary.Renewals, WeeklyRenewal{ID: sub.ID, Name: sub.Name, Date: *sub.RenewalDate, Cost: cost, Currency: sub.OriginalCurrency})
+		summary.RenewalsTotal += s.convert(cost, sub.OriginalCurrency, summary.Currency)
+	}
+	sort.SliceStable(summary.Renewals, func(i, j int) bool { return summary.Renewals[i].Date.Before(summary.Renewals[j].Date) })
+
+	return summary
+}
+
+// convert converts an amount to the display currency, leaving it unchanged
+// when no rate is available
+func (s *WeeklySummaryService) convert(amount float64, from, to string) float64 {
+	if from == "" || from == to {
+		return amount
+	}
+	converted, err := s.currency.ConvertAmount(amount, from, to)
+	if err != nil {
+		slog.Warn("no exchange rate for weekly summary", "from", from, "to", to, "error", err)
+		return amount
+	}
+	return converted
+}
+
+// newSummaryEntry remembers a subscription's price and a fingerprint of the
+// fields a user edits. Renewal dates and reminder bookkeeping are left out,
+// since the scheduler changes them.
+func newSummaryEntry(sub *models.Subscription) summaryEntry {
+	h := fnv.New64a()
+	date := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	}
+	fmt.Fprintf(h, "%s|%d|%s|%s|%s|%s|%s|%s|%s|%s|%t|%d|%t|%d|%.2f|%s",
+		sub.Name, sub.CategoryID, sub.PaymentMethod, sub.Account, sub.URL, sub.Notes, sub.Usage, sub.Purpose,
+		date(sub.StartDate), date(sub.CancellationDate),
+		sub.RenewalReminder, sub.RenewalReminderDays, sub.CancellationReminder, sub.CancellationReminderDays,
+		sub.TaxRate, sub.PriceType)
+	return summaryEntry{
+		Name:        sub.Name,
+		Cost:        sub.Cost,
+		Currency:    sub.OriginalCurrency,
+		Schedule:    sub.Schedule,
+		Status:      sub.Status,
+		Fingerprint: h.Sum64(),
+	}
+}
+
+func (s *WeeklySummaryService) loadBaseline() *summaryBaseline {
+	raw, ok := s.settings.GetCached(settingKeyWeeklySummaryBaseline)
+	if !ok || raw == "" {
+		return nil
+	}
+	var baseline summaryBaseline
+	if err := json.Unmarshal([]byte(raw), &baseline); err != nil {
+		slog.Warn("invalid weekly summary baseline, starting over", "error", err)
+		return nil
+	}
+	return &baseline
+}
+
+func (s *WeeklySummaryService) saveBaseline(baseline *summaryBaseline) error {
+	data, err := json.Marshal(baseline)
+	if err != nil {
+		return err
+	}
+	defer s.settings.InvalidateCache()
+	return s.settings.Repo().Set(settingKeyWeeklySummaryBaseline, string(data))
+}
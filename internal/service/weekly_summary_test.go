@@ -0,0 +1,133 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"subvault/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeeklySummaryService_Check(t *testing.T) {
+	db := setupRenewalReminderTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.UsageEvent{}, &models.SubscriptionShare{}, &models.ReminderRetry{}, &models.Payment{}))
+	services := newTestSubscriptionService(t, db)
+	require.NoError(t, services.preferences.SetCurrency("EUR"))
+
+	now := time.Now()
+	soon := now.AddDate(0, 0, 3)
+	later := now.AddDate(0, 1, 0)
+	promo, promoEnd := 8.0, now.AddDate(0, 0, 10)
+	created := map[string]*models.Subscription{}
+	for _, sub := range []*models.Subscription{
+		{Name: "Netflix", Cost: 15, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", RenewalDate: &soon, PromoCost: &promo, PromoEndDate: &promoEnd},
+		{Name: "GitHub", Cost: 10, Schedule: "Monthly", Status: "Active", OriginalCurrency: "USD", RenewalDate: &soon},
+		{Name: "Gym", Cost: 30, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", RenewalDate: &later},
+		{Name: "Magazine", Cost: 5, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR", RenewalDate: &later},
+	} {
+		sub, err := services.subscriptions.Create(t.Context(), sub)
+		require.NoError(t, err)
+		created[sub.Name] = sub
+	}
+
+	summaries := NewWeeklySummaryService(services.subscriptions, fakeRates{"USD": 0.9}, services.preferences, services.settings)
+
+	// Without a baseline the week's additions come from the creation dates
+	summary, err := summaries.Check(t.Context(), now)
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+	assert.Len(t, summary.Added, 4)
+	assert.Empty(t, summary.PriceChanges)
+	require.Len(t, summary.Renewals, 2)
+	// Netflix renews at its promotional price
+	assert.InDelta(t, 17.0, summary.RenewalsTotal, 0.001)
+	assert.Equal(t, "EUR", summary.Currency)
+
+	// Nothing until a week has passed
+	summary, err = summaries.Check(t.Context(), now.AddDate(0, 0, 3))
+	require.NoError(t, err)
+	assert.Nil(t, summary)
+
+	netflix := created["Netflix"]
+	netflix.Cost = 18
+	_, err = services.subscriptions.Update(t.Context(), netflix.ID, netflix)
+	require.NoError(t, err)
+	github := created["GitHub"]
+	github.Notes = "Team plan"
+	_, err = services.subscriptions.Update(t.Context(), github.ID, github)
+	require.NoError(t, err)
+	gym := created["Gym"]
+	gym.Status = "Cancelled"
+	_, err = services.subscriptions.Update(t.Context(), gym.ID, gym)
+	require.NoError(t, err)
+	require.NoError(t, services.subscriptions.Delete(t.Context(), created["Magazine"].ID))
+	_, err = services.subscriptions.Create(t.Context(), &models.Subscription{Name: "Spotify", Cost: 11, Schedule: "Monthly", Status: "Active", OriginalCurrency: "EUR"})
+	require.NoError(t, err)
+
+	summary, err = summaries.Check(t.Context(), now.AddDate(0, 0, 7))
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+	assert.True(t, summary.HasChanges())
+	assert.Equal(t, now.Unix(), summary.Since.Unix())
+	require.Len(t, summary.Added, 1)
+	assert.Equal(t, "Spotify", summary.Added[0].Name)
+	require.Len(t, summary.Edited, 1)
+	assert.Equal(t, "GitHub", summary.Edited[0].Name)
+	require.Len(t, summary.Cancelled, 1)
+	assert.Equal(t, "Gym", summary.Cancelled[0].Name)
+	require.Len(t, summary.Deleted, 1)
+	assert.Equal(t, "Magazine", summary.Deleted[0].Name)
+	require.Len(t, summary.PriceChanges, 1)
+	assert.Equal(t, WeeklyPriceChange{ID: netflix.ID, Name: "Netflix", OldCost: 15, OldCurrency: "EUR", OldSchedule: "Monthly",
+		NewCost: 18, NewCurrency: "EUR", NewSchedule: "Monthly"}, summary.PriceChanges[0])
+
+	// A quiet week still summarises renewals and budget
+	summary, err = summaries.Check(t.Context(), now.AddDate(0, 0, 14))
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+	assert.False(t, summary.HasChanges())
+}
+
+func TestWeeklySummary_Render(t *testing.T) {
+	_, _, notifConfig, shoutrrrService := setupShoutrrrServices(t)
+	require.NoError(t, notifConfig.SaveShoutrrrConfig(&models.ShoutrrrConfig{URLs: []string{"invalid://url"}}))
+	require.NoError(t, notifConfig.SaveSMTPConfig(&models.SMTPConfig{Host: "smtp.example.com", Port: 587, To: "me@example.com"}))
+
+	// Close both delivery windows so the rendered notifications are queued
+	now := time.Now()
+	closed := models.DeliveryWindow{
+		Enabled: true,
+		Start:   now.Add(2 * time.Hour).Format("15:04"),
+		End:     now.Add(3 * time.Hour).Format("15:04"),
+	}
+	require.NoError(t, notifConfig.SaveDeliveryWindows(&models.DeliveryWindows{Email: closed, Shoutrrr: closed}))
+
+	summary := &WeeklySummary{
+		Since:         now.AddDate(0, 0, -7),
+		Until:         now,
+		Added:         []WeeklySummaryItem{{ID: 1, Name: "<b>Netflix</b>", Cost: 15, Currency: "EUR", Schedule: "Monthly"}},
+		PriceChanges:  []WeeklyPriceChange{{ID: 2, Name: "Spotify", OldCost: 10, OldCurrency: "EUR", OldSchedule: "Monthly", NewCost: 12, NewCurrency: "EUR", NewSchedule: "Monthly"}},
+		Renewals:      []WeeklyRenewal{{ID: 2, Name: "Spotify", Date: now.AddDate(0, 0, 2), Cost: 12, Currency: "EUR"}},
+		RenewalsTotal: 12,
+		Currency:      "EUR",
+		MonthlySpend:  120,
+		MonthlyBudget: 100,
+	}
+	require.NoError(t, NewEmailService(shoutrrrService.preferences, notifConfig).SendWeeklySummary(summary))
+	require.NoError(t, shoutrrrService.SendWeeklySummary(summary))
+
+	queued := notifConfig.QueuedNotifications()
+	require.Len(t, queued, 2)
+	email := queued[0].Body
+	assert.Contains(t, email, "&lt;b&gt;Netflix&lt;/b&gt;")
+	assert.Contains(t, email, "email_weekly_summary_price_changes")
+	assert.Contains(t, email, `<span class="over">€120.00 / €100.00</span>`, "spend over the budget is highlighted")
+	assert.NotContains(t, email, "email_weekly_summary_no_changes")
+	assert.NotContains(t, email, "email_weekly_summary_deleted", "empty sections are left out")
+
+	push := queued[1].Body
+	assert.Contains(t, push, "• Spotify: €10.00 → €12.00")
+	assert.Contains(t, push, "email_weekly_summary_renewals_total: €12.00")
+}
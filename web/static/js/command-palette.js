@@ -0,0 +1,123 @@
+// SubVault Command Palette
+// Opens with Ctrl+K (Cmd+K on macOS) or by typing into the sidebar search box
+// and searches subscriptions, categories and pages through /api/search.
+// Arrow keys select a result, Enter opens it.
+
+(function() {
+    var overlay, input, list, empty;
+    var results = [];
+    var selected = 0;
+    var timer = null;
+    var requestId = 0;
+
+    function init() {
+        overlay = document.getElementById('command-palette');
+        input = document.getElementById('command-palette-input');
+        list = document.getElementById('command-palette-results');
+        empty = document.getElementById('command-palette-empty');
+        if (!overlay) return;
+
+        input.addEventListener('input', function() {
+            clearTimeout(timer);
+            timer = setTimeout(search, 150);
+        });
+        input.addEventListener('keydown', function(e) {
+            if (e.key === 'ArrowDown') {
+                e.preventDefault();
+                select(selected + 1);
+            } else if (e.key === 'ArrowUp') {
+                e.preventDefault();
+                select(selected - 1);
+            } else if (e.key === 'Enter') {
+                e.preventDefault();
+                open(results[selected]);
+            }
+        });
+    }
+
+    function search() {
+        var id = ++requestId;
+        fetch('/api/search?q=' + encodeURIComponent(input.value.trim()), { credentials: 'same-origin' })
+            .then(function(r) { return r.ok ? r.json() : { data: [] }; })
+            .then(function(body) {
+                if (id !== requestId) return;
+                results = body.data || [];
+                render();
+            })
+            .catch(function(err) { console.error('Command palette search failed:', err); });
+    }
+
+    function render() {
+        list.innerHTML = '';
+        results.forEach(function(result, i) {
+            var item = document.createElement('li');
+            item.className = 'command-palette-item';
+            item.setAttribute('role', 'option');
+            item.dataset.type = result.type;
+
+            var title = document.createElement('span');
+            title.className = 'command-palette-title';
+            title.textContent = result.title;
+            item.appendChild(title);
+            if (result.subtitle) {
+                var subtitle = document.createElement('span');
+                subtitle.className = 'command-palette-subtitle';
+                subtitle.textContent = result.subtitle;
+                item.appendChild(subtitle);
+            }
+
+            item.addEventListener('mousemove', function() { select(i); });
+            item.addEventListener('click', function() { open(result); });
+            list.appendChild(item);
+        });
+        empty.style.display = results.length ? 'none' : '';
+        select(0);
+    }
+
+    function select(index) {
+        var items = list.children;
+        if (!items.length) return;
+        selected = (index + items.length) % items.length;
+        for (var i = 0; i < items.length; i++) {
+            items[i].classList.toggle('active', i === selected);
+            items[i].setAttribute('aria-selected', i === selected ? 'true' : 'false');
+        }
+        items[selected].scrollIntoView({ block: 'nearest' });
+    }
+
+    function open(result) {
+        if (result) window.location.href = result.url;
+    }
+
+    window.openCommandPalette = function(query) {
+        if (!overlay) return;
+        overlay.classList.add('active');
+        input.value = query || '';
+        search();
+        input.focus();
+        input.setSelectionRange(input.value.length, input.value.length);
+    };
+
+    window.closeCommandPalette = function() {
+        if (overlay) overlay.classList.remove('active');
+    };
+
+    document.addEventListener('keydown', function(e) {
+        if ((e.ctrlKey || e.metaKey) && e.key.toLowerCase() === 'k') {
+            e.preventDefault();
+            if (overlay && overlay.classList.contains('active')) {
+                closeCommandPalette();
+            } else {
+                openCommandPalette();
+            }
+        } else if (e.key === 'Escape' && overlay && overlay.classList.contains('active')) {
+            closeCommandPalette();
+        }
+    });
+
+    if (document.readyState === 'loading') {
+        document.addEventListener('DOMContentLoaded', init);
+    } else {
+        init();
+    }
+})();